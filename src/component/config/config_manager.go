@@ -174,6 +174,34 @@ func (configManagerInst *ConfigManager) GetLogicId() uint32 {
 	return configManagerInst.GetCurrentConfigGroup().GetSectionConfig().GetLogicId()
 }
 
+// GetServedZoneIds 本进程服务的所有大区ID，第一个是本区，其后是合服并入的原大区
+func (configManagerInst *ConfigManager) GetServedZoneIds() []uint32 {
+	localZoneId := configManagerInst.GetLogicId()
+	mergedZoneIds := configManagerInst.GetCurrentConfigGroup().GetSectionConfig().GetZoneMerge().GetMergedZoneIds()
+	ret := make([]uint32, 0, 1+len(mergedZoneIds))
+	ret = append(ret, localZoneId)
+	for _, zoneId := range mergedZoneIds {
+		if zoneId == 0 || zoneId == localZoneId {
+			continue
+		}
+		ret = append(ret, zoneId)
+	}
+	return ret
+}
+
+// IsServedZoneId 检查大区是否由本进程服务
+func (configManagerInst *ConfigManager) IsServedZoneId(zoneId uint32) bool {
+	if zoneId == 0 {
+		return false
+	}
+	for _, servedZoneId := range configManagerInst.GetServedZoneIds() {
+		if servedZoneId == zoneId {
+			return true
+		}
+	}
+	return false
+}
+
 func (configManagerInst *ConfigManager) GetApp() libatapp.AppImpl {
 	return configManagerInst.app
 }
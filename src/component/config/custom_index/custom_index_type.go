@@ -15,6 +15,7 @@ type ExcelConfigCustomIndex struct {
 	MallRandomSheetIndex []int32
 	MailGlobalMajorTypes []int32
 	MailUserMajorTypes   []int32
+	AchievementIndex     ExcelConfigAchievementIndex
 }
 
 type ExcelConfigCustomIndexLastBuildTime struct {
@@ -85,6 +86,7 @@ func (i *ExcelConfigCustomIndex) GetRandomPool(typeId int32) *ExcelConfigRandomP
 
 type ExcelConfigMallIndex struct {
 	MallSheetMallIndex map[int32]*public_protocol_config.Readonly_ExcelMall // map[mallSheetId]mallId
+	DiscountProducts   []*public_protocol_config.Readonly_ExcelMallProduct  // 配置了折扣或阶梯价格的商品
 }
 
 func (i *ExcelConfigCustomIndex) GetMallByMallSheet(mallSheetId int32) *public_protocol_config.Readonly_ExcelMall {
@@ -98,3 +100,23 @@ func (i *ExcelConfigCustomIndex) GetMallByMallSheet(mallSheetId int32) *public_p
 
 	return i.MallIndex.MallSheetMallIndex[mallSheetId]
 }
+
+func (i *ExcelConfigCustomIndex) GetMallDiscountProducts() []*public_protocol_config.Readonly_ExcelMallProduct {
+	if i == nil {
+		return nil
+	}
+
+	return i.MallIndex.DiscountProducts
+}
+
+type ExcelConfigAchievementIndex struct {
+	ProgressTypeIndex map[int32][]*public_protocol_config.Readonly_ExcelAchievement // map[progressType]achievements
+}
+
+func (i *ExcelConfigCustomIndex) GetAchievementsByProgressType(progressType int32) []*public_protocol_config.Readonly_ExcelAchievement {
+	if i == nil {
+		return nil
+	}
+
+	return i.AchievementIndex.ProgressTypeIndex[progressType]
+}
@@ -0,0 +1,63 @@
+package atframework_component_config
+
+import (
+	"fmt"
+
+	generate_config "github.com/atframework/atsf4g-go/component/config/generate_config"
+	public_protocol_common "github.com/atframework/atsf4g-go/component/protocol/public/common/protocol/common"
+	public_protocol_config "github.com/atframework/atsf4g-go/component/protocol/public/config/protocol/config"
+)
+
+// initExcelAchievementConfigIndex 检查成就档位配置，并按进度类型建立索引
+func initExcelAchievementConfigIndex(group *generate_config.ConfigGroup) error {
+	if group.GetExcelAchievementAllOfAchievementId() == nil {
+		return nil
+	}
+
+	index := make(map[int32][]*public_protocol_config.Readonly_ExcelAchievement)
+	for _, v := range *group.GetExcelAchievementAllOfAchievementId() {
+		if err := checkExcelAchievement(group, v); err != nil {
+			return err
+		}
+
+		progressType := int32(v.GetProgress().GetProgressParamOneofCase())
+		index[progressType] = append(index[progressType], v)
+	}
+
+	group.GetCustomIndex().AchievementIndex.ProgressTypeIndex = index
+	return nil
+}
+
+func checkExcelAchievement(group *generate_config.ConfigGroup, achievement *public_protocol_config.Readonly_ExcelAchievement) error {
+	progressType := int32(achievement.GetProgress().GetProgressParamOneofCase())
+	if progressType == 0 {
+		return fmt.Errorf("achievement %d progress type not set", achievement.GetAchievementId())
+	}
+
+	// 档位按阈值递增完成，只支持进度值越大越接近完成的进度类型
+	progressTypeCfg := group.GetExcelQuestProgressTypeById(progressType)
+	if progressTypeCfg == nil {
+		return fmt.Errorf("achievement %d progress type %d not found", achievement.GetAchievementId(), progressType)
+	}
+	if progressTypeCfg.GetValueCompareType() != public_protocol_common.EnQuestProgressValueCompareType_EN_QUEST_PROGRESS_VALUE_COMPARE_TYPE_GREATER_OR_EQUAL {
+		return fmt.Errorf("achievement %d progress type %d compare type %d not supported",
+			achievement.GetAchievementId(), progressType, progressTypeCfg.GetValueCompareType())
+	}
+
+	if len(achievement.GetTiers()) == 0 {
+		return fmt.Errorf("achievement %d has no tier", achievement.GetAchievementId())
+	}
+
+	var lastThreshold int64
+	for i, tier := range achievement.GetTiers() {
+		if tier.GetThreshold() <= lastThreshold {
+			return fmt.Errorf("achievement %d tier %d threshold %d must be greater than previous tier %d",
+				achievement.GetAchievementId(), i+1, tier.GetThreshold(), lastThreshold)
+		}
+		if tier.GetPoints() < 0 {
+			return fmt.Errorf("achievement %d tier %d points %d invalid", achievement.GetAchievementId(), i+1, tier.GetPoints())
+		}
+		lastThreshold = tier.GetThreshold()
+	}
+	return nil
+}
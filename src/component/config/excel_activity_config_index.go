@@ -0,0 +1,74 @@
+package atframework_component_config
+
+import (
+	"fmt"
+	"time"
+
+	generate_config "github.com/atframework/atsf4g-go/component/config/generate_config"
+	public_protocol_common "github.com/atframework/atsf4g-go/component/protocol/public/common/protocol/common"
+	public_protocol_config "github.com/atframework/atsf4g-go/component/protocol/public/config/protocol/config"
+)
+
+// initExcelActivityConfigIndex 检查活动排期和登入活动奖励配置
+func initExcelActivityConfigIndex(group *generate_config.ConfigGroup) error {
+	if group.GetExcelActivityAllOfActivityId() == nil {
+		return nil
+	}
+
+	for _, v := range *group.GetExcelActivityAllOfActivityId() {
+		if err := checkExcelActivity(group, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetActivitySchedulePeriod 每日和每周活动一期的最大时长，固定时间活动返回 0
+func GetActivitySchedulePeriod(activity *public_protocol_config.Readonly_ExcelActivity) time.Duration {
+	switch activity.GetScheduleType() {
+	case public_protocol_common.EnActivityScheduleType_EN_ACTIVITY_SCHEDULE_TYPE_DAILY:
+		return 24 * time.Hour
+	case public_protocol_common.EnActivityScheduleType_EN_ACTIVITY_SCHEDULE_TYPE_WEEKLY:
+		return 7 * 24 * time.Hour
+	default:
+		return 0
+	}
+}
+
+func checkExcelActivity(group *generate_config.ConfigGroup, activity *public_protocol_config.Readonly_ExcelActivity) error {
+	if activity.GetActivityType() == public_protocol_common.EnActivityType_EN_ACTIVITY_TYPE_INVALID {
+		return fmt.Errorf("activity %d type not set", activity.GetActivityId())
+	}
+
+	if activity.GetEndTime().GetSeconds() > 0 && activity.GetEndTime().GetSeconds() <= activity.GetStartTime().GetSeconds() {
+		return fmt.Errorf("activity %d end time %d must be greater than start time %d",
+			activity.GetActivityId(), activity.GetEndTime().GetSeconds(), activity.GetStartTime().GetSeconds())
+	}
+
+	switch activity.GetScheduleType() {
+	case public_protocol_common.EnActivityScheduleType_EN_ACTIVITY_SCHEDULE_TYPE_FIXED:
+		if activity.GetEndTime().GetSeconds() <= 0 {
+			return fmt.Errorf("activity %d is fixed schedule but end time not set", activity.GetActivityId())
+		}
+	case public_protocol_common.EnActivityScheduleType_EN_ACTIVITY_SCHEDULE_TYPE_DAILY,
+		public_protocol_common.EnActivityScheduleType_EN_ACTIVITY_SCHEDULE_TYPE_WEEKLY:
+		period := GetActivitySchedulePeriod(activity)
+		openOffset := activity.GetOpenOffset().AsDuration()
+		duration := activity.GetDuration().AsDuration()
+		if openOffset < 0 || openOffset >= period {
+			return fmt.Errorf("activity %d open offset %v out of range [0, %v)", activity.GetActivityId(), openOffset, period)
+		}
+		if duration <= 0 || duration > period {
+			return fmt.Errorf("activity %d duration %v out of range (0, %v]", activity.GetActivityId(), duration, period)
+		}
+	default:
+		return fmt.Errorf("activity %d schedule type %d invalid", activity.GetActivityId(), activity.GetScheduleType())
+	}
+
+	for _, reward := range group.GetExcelActivityLoginRewardByActivityId(activity.GetActivityId()) {
+		if reward.GetDay() <= 0 {
+			return fmt.Errorf("activity %d login reward day %d invalid", activity.GetActivityId(), reward.GetDay())
+		}
+	}
+	return nil
+}
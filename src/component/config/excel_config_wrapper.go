@@ -37,6 +37,26 @@ func ExcelConfigCallbackOnLoad(group *generate_config.ConfigGroup, logger *log.L
 		return
 	}
 
+	err = initExcelGachaConfigIndex(group)
+	if err != nil {
+		return
+	}
+
+	err = initExcelSignInConfigIndex(group)
+	if err != nil {
+		return
+	}
+
+	err = initExcelAchievementConfigIndex(group)
+	if err != nil {
+		return
+	}
+
+	err = initExcelActivityConfigIndex(group)
+	if err != nil {
+		return
+	}
+
 	err = initExcelMailConfigIndex(group)
 	if err != nil {
 		return
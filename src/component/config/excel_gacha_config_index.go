@@ -0,0 +1,54 @@
+package atframework_component_config
+
+import (
+	"fmt"
+
+	generate_config "github.com/atframework/atsf4g-go/component/config/generate_config"
+	public_protocol_config "github.com/atframework/atsf4g-go/component/protocol/public/config/protocol/config"
+)
+
+// gachaRateBase 抽卡概率的分母(万分比)
+const gachaRateBase = 10000
+
+// initExcelGachaConfigIndex 检查卡池配置，需要在随机池索引之后初始化
+func initExcelGachaConfigIndex(group *generate_config.ConfigGroup) error {
+	if group.GetExcelGachaBannerAllOfBannerId() == nil {
+		return nil
+	}
+
+	for _, v := range *group.GetExcelGachaBannerAllOfBannerId() {
+		if err := checkExcelGachaBanner(group, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkExcelGachaBanner(group *generate_config.ConfigGroup, banner *public_protocol_config.Readonly_ExcelGachaBanner) error {
+	for _, poolId := range []int32{banner.GetNormalPoolId(), banner.GetRarePoolId()} {
+		if group.GetCustomIndex().GetRandomPool(poolId) == nil {
+			return fmt.Errorf("gacha banner %d random pool %d not found", banner.GetBannerId(), poolId)
+		}
+	}
+	if banner.GetFeaturedPoolId() != 0 && group.GetCustomIndex().GetRandomPool(banner.GetFeaturedPoolId()) == nil {
+		return fmt.Errorf("gacha banner %d featured random pool %d not found", banner.GetBannerId(), banner.GetFeaturedPoolId())
+	}
+
+	pity := banner.GetPity()
+	if pity.GetBaseRate() < 0 || pity.GetBaseRate() > gachaRateBase || pity.GetSoftPityRateStep() < 0 {
+		return fmt.Errorf("gacha banner %d pity rate invalid", banner.GetBannerId())
+	}
+	if pity.GetHardPity() > 0 && pity.GetSoftPityStart() > pity.GetHardPity() {
+		return fmt.Errorf("gacha banner %d soft pity start %d is greater than hard pity %d",
+			banner.GetBannerId(), pity.GetSoftPityStart(), pity.GetHardPity())
+	}
+
+	featuredRate := banner.GetFeatured().GetFeaturedRate()
+	if featuredRate < 0 || featuredRate > gachaRateBase {
+		return fmt.Errorf("gacha banner %d featured rate %d invalid", banner.GetBannerId(), featuredRate)
+	}
+	if banner.GetFeaturedPoolId() == 0 && (featuredRate > 0 || banner.GetFeatured().GetGuaranteeAfterMiss()) {
+		return fmt.Errorf("gacha banner %d has featured rule but no featured random pool", banner.GetBannerId())
+	}
+	return nil
+}
@@ -31,7 +31,19 @@ func initExcelMallConfigIndex(group *generate_config.ConfigGroup) error {
 	}
 
 	randomSheetIndex := make([]int32, 0)
+	discountProducts := make([]*public_protocol_config.Readonly_ExcelMallProduct, 0)
 	for _, v := range *group.GetExcelMallSheetAllOfMallSheetId() {
+		for _, product := range group.GetExcelMallProductByMallSheetId(v.GetMallSheetId()) {
+			if err := checkExcelMallBundleProducts(group, product); err != nil {
+				return err
+			}
+
+			if product.GetDiscount().GetTimeLimitRatio() > 0 || product.GetDiscount().GetFirstPurchaseRatio() > 0 ||
+				len(product.GetDiscount().GetTieredPrice()) > 0 {
+				discountProducts = append(discountProducts, product)
+			}
+		}
+
 		if v.GetRandomCfg().GetIsRandom() == false {
 			continue
 		}
@@ -39,6 +51,24 @@ func initExcelMallConfigIndex(group *generate_config.ConfigGroup) error {
 	}
 
 	group.GetCustomIndex().MallIndex.MallSheetMallIndex = index
+	group.GetCustomIndex().MallIndex.DiscountProducts = discountProducts
 	group.GetCustomIndex().MallRandomSheetIndex = randomSheetIndex
 	return nil
 }
+
+// checkExcelMallBundleProducts 捆绑包内的商品必须存在，且不允许嵌套捆绑包
+func checkExcelMallBundleProducts(group *generate_config.ConfigGroup, product *public_protocol_config.Readonly_ExcelMallProduct) error {
+	for _, bundle := range product.GetBundleProducts() {
+		bundleRow := group.GetExcelMallProductByProductIdPurchasePriority(bundle.GetProductId(), bundle.GetPurchasePriority())
+		if bundleRow == nil {
+			return fmt.Errorf("mall bundle product not found, product %d, bundle product %d-%d",
+				product.GetProductId(), bundle.GetProductId(), bundle.GetPurchasePriority())
+		}
+
+		if len(bundleRow.GetBundleProducts()) > 0 {
+			return fmt.Errorf("mall bundle product can not be nested, product %d, bundle product %d",
+				product.GetProductId(), bundle.GetProductId())
+		}
+	}
+	return nil
+}
@@ -15,6 +15,12 @@ import (
 // ---------------- 接口 ----------------
 
 func RandomWithPool(poolID int32, count int64, customIndex []int32) (ret public_protocol_pbdesc.EnErrorCode, result []*public_protocol_common.DItemOffset) {
+	return RandomWithPoolSource(nil, poolID, count, customIndex)
+}
+
+// RandomWithPoolSource 使用指定的随机数生成器抽取随机池，相同种子的生成器得到相同的结果
+// rng 为 nil 时使用全局随机数
+func RandomWithPoolSource(rng *rand.Rand, poolID int32, count int64, customIndex []int32) (ret public_protocol_pbdesc.EnErrorCode, result []*public_protocol_common.DItemOffset) {
 	if !IsRandomPool(poolID) {
 		ret = public_protocol_pbdesc.EnErrorCode_EN_ERR_RANDOM_POOL_NOT_RANDOM_POOL
 		return
@@ -22,7 +28,7 @@ func RandomWithPool(poolID int32, count int64, customIndex []int32) (ret public_
 
 	if count == 1 {
 		used := make(map[int32]struct{})
-		ret, result = randomWithPoolInternal(poolID, used, customIndex)
+		ret, result = randomWithPoolInternal(rng, poolID, used, customIndex)
 		if ret != 0 {
 			return
 		}
@@ -30,7 +36,7 @@ func RandomWithPool(poolID int32, count int64, customIndex []int32) (ret public_
 		for i := int64(0); i < count; i++ {
 			used := make(map[int32]struct{})
 			var onceResult []*public_protocol_common.DItemOffset
-			ret, onceResult = randomWithPoolInternal(poolID, used, customIndex)
+			ret, onceResult = randomWithPoolInternal(rng, poolID, used, customIndex)
 			if ret != 0 {
 				return
 			}
@@ -149,7 +155,7 @@ func IsRandomPool(id int32) bool {
 	return id >= int32(public_protocol_common.EnItemTypeRange_EN_ITEM_TYPE_RANGE_RANDOM_POOL_BEGIN) && id < int32(public_protocol_common.EnItemTypeRange_EN_ITEM_TYPE_RANGE_RANDOM_POOL_END)
 }
 
-func randomWithPoolInternal(poolID int32, used map[int32]struct{},
+func randomWithPoolInternal(rng *rand.Rand, poolID int32, used map[int32]struct{},
 	customIndex []int32,
 ) (ret public_protocol_pbdesc.EnErrorCode, result []*public_protocol_common.DItemOffset) {
 	row := GetConfigManager().GetCurrentConfigGroup().GetCustomIndex().GetRandomPool(poolID)
@@ -171,13 +177,13 @@ func randomWithPoolInternal(poolID int32, used map[int32]struct{},
 
 	switch row.RandomType {
 	case public_protocol_config.EnRandomPoolType_EN_RANDOM_POOL_TYPE_ALL:
-		ret, result = handleAll(row.Elements, row.Times, used)
+		ret, result = handleAll(rng, row.Elements, row.Times, used)
 	case public_protocol_config.EnRandomPoolType_EN_RANDOM_POOL_TYPE_INDEPENDENT:
-		ret, result = handleIndependent(row.Elements, row.Times, used)
+		ret, result = handleIndependent(rng, row.Elements, row.Times, used)
 	case public_protocol_config.EnRandomPoolType_EN_RANDOM_POOL_TYPE_EXCLUSIVE:
-		ret, result = handleExclusive(row.Elements, row.Times, used)
+		ret, result = handleExclusive(rng, row.Elements, row.Times, used)
 	case public_protocol_config.EnRandomPoolType_EN_RANDOM_POOL_TYPE_CUSTOM:
-		ret, result = handleCustom(row.Elements, row.Times, used, customIndex)
+		ret, result = handleCustom(rng, row.Elements, row.Times, used, customIndex)
 	default:
 		ret = public_protocol_pbdesc.EnErrorCode_EN_ERR_RANDOM_POOL_TYPE_NOT_SUPPORT
 		return
@@ -187,7 +193,7 @@ func randomWithPoolInternal(poolID int32, used map[int32]struct{},
 }
 
 // N选1, 执行M次
-func handleIndependent(elements []*public_protocol_config.Readonly_DRandomPoolElement, times int32, used map[int32]struct{}) (ret public_protocol_pbdesc.EnErrorCode, result []*public_protocol_common.DItemOffset) {
+func handleIndependent(rng *rand.Rand, elements []*public_protocol_config.Readonly_DRandomPoolElement, times int32, used map[int32]struct{}) (ret public_protocol_pbdesc.EnErrorCode, result []*public_protocol_common.DItemOffset) {
 	var sumWeight int32
 
 	for _, e := range elements {
@@ -201,14 +207,14 @@ func handleIndependent(elements []*public_protocol_config.Readonly_DRandomPoolEl
 	}
 
 	for i := 0; i < int(times); i++ {
-		selectWeight := rand.Int32N(sumWeight)
+		selectWeight := randomInt32N(rng, sumWeight)
 		for _, e := range elements {
 			if !validElement(e) {
 				continue
 			}
 			if selectWeight < e.GetWeight() {
 				var currentResult []*public_protocol_common.DItemOffset
-				ret, currentResult = addRandomResult(e, used)
+				ret, currentResult = addRandomResult(rng, e, used)
 				if ret != 0 {
 					return
 				}
@@ -222,7 +228,7 @@ func handleIndependent(elements []*public_protocol_config.Readonly_DRandomPoolEl
 }
 
 // N选M 不重复
-func handleExclusive(elements []*public_protocol_config.Readonly_DRandomPoolElement, times int32, used map[int32]struct{}) (ret public_protocol_pbdesc.EnErrorCode, result []*public_protocol_common.DItemOffset) {
+func handleExclusive(rng *rand.Rand, elements []*public_protocol_config.Readonly_DRandomPoolElement, times int32, used map[int32]struct{}) (ret public_protocol_pbdesc.EnErrorCode, result []*public_protocol_common.DItemOffset) {
 	valid := make([]*public_protocol_config.Readonly_DRandomPoolElement, 0)
 	var sumWeight int32
 	for _, e := range elements {
@@ -239,7 +245,7 @@ func handleExclusive(elements []*public_protocol_config.Readonly_DRandomPoolElem
 	if int(times) >= len(valid) {
 		for _, e := range valid {
 			var currentResult []*public_protocol_common.DItemOffset
-			ret, currentResult = addRandomResult(e, used)
+			ret, currentResult = addRandomResult(rng, e, used)
 			if ret != 0 {
 				return
 			}
@@ -249,7 +255,7 @@ func handleExclusive(elements []*public_protocol_config.Readonly_DRandomPoolElem
 	}
 
 	for i := 0; i < int(times) && len(valid) > 0; i++ {
-		selectWeight := rand.Int32N(sumWeight)
+		selectWeight := randomInt32N(rng, sumWeight)
 		idx := 0
 		for ; idx < len(valid); idx++ {
 			if selectWeight < valid[idx].GetWeight() {
@@ -264,7 +270,7 @@ func handleExclusive(elements []*public_protocol_config.Readonly_DRandomPoolElem
 		opt := valid[idx]
 
 		var currentResult []*public_protocol_common.DItemOffset
-		ret, currentResult = addRandomResult(opt, used)
+		ret, currentResult = addRandomResult(rng, opt, used)
 		if ret != 0 {
 			return
 		}
@@ -278,7 +284,7 @@ func handleExclusive(elements []*public_protocol_config.Readonly_DRandomPoolElem
 }
 
 // 自选池
-func handleCustom(elements []*public_protocol_config.Readonly_DRandomPoolElement, times int32, used map[int32]struct{},
+func handleCustom(rng *rand.Rand, elements []*public_protocol_config.Readonly_DRandomPoolElement, times int32, used map[int32]struct{},
 	customIndex []int32,
 ) (ret public_protocol_pbdesc.EnErrorCode, result []*public_protocol_common.DItemOffset) {
 	if len(customIndex) != int(times) {
@@ -304,7 +310,7 @@ func handleCustom(elements []*public_protocol_config.Readonly_DRandomPoolElement
 		}
 		if validElement(elements[customIndex[i]]) {
 			var currentResult []*public_protocol_common.DItemOffset
-			ret, currentResult = addRandomResult(elements[customIndex[i]], used)
+			ret, currentResult = addRandomResult(rng, elements[customIndex[i]], used)
 			if ret != 0 {
 				return
 			}
@@ -315,12 +321,12 @@ func handleCustom(elements []*public_protocol_config.Readonly_DRandomPoolElement
 }
 
 // 下发所有道具
-func handleAll(elements []*public_protocol_config.Readonly_DRandomPoolElement, times int32, used map[int32]struct{}) (ret public_protocol_pbdesc.EnErrorCode, result []*public_protocol_common.DItemOffset) {
+func handleAll(rng *rand.Rand, elements []*public_protocol_config.Readonly_DRandomPoolElement, times int32, used map[int32]struct{}) (ret public_protocol_pbdesc.EnErrorCode, result []*public_protocol_common.DItemOffset) {
 	for i := 0; i < int(times); i++ {
 		for _, e := range elements {
 			if validElement(e) {
 				var currentResult []*public_protocol_common.DItemOffset
-				ret, currentResult = addRandomResult(e, used)
+				ret, currentResult = addRandomResult(rng, e, used)
 				if ret != 0 {
 					return
 				}
@@ -333,7 +339,7 @@ func handleAll(elements []*public_protocol_config.Readonly_DRandomPoolElement, t
 
 // ---------------- 工具函数 ----------------
 
-func addRandomResult(item *public_protocol_config.Readonly_DRandomPoolElement, used map[int32]struct{}) (ret public_protocol_pbdesc.EnErrorCode, result []*public_protocol_common.DItemOffset) {
+func addRandomResult(rng *rand.Rand, item *public_protocol_config.Readonly_DRandomPoolElement, used map[int32]struct{}) (ret public_protocol_pbdesc.EnErrorCode, result []*public_protocol_common.DItemOffset) {
 	minCount := item.GetCount().GetMinCount()
 	maxCount := item.GetCount().GetMaxCount()
 	if minCount > maxCount {
@@ -344,12 +350,12 @@ func addRandomResult(item *public_protocol_config.Readonly_DRandomPoolElement, u
 	if minCount == maxCount {
 		count = minCount
 	} else {
-		count = rand.Int64N(maxCount-minCount+1) + minCount
+		count = randomInt64N(rng, maxCount-minCount+1) + minCount
 	}
 	if IsRandomPool(item.GetTypeId()) {
 		for i := int32(0); i < int32(count); i++ {
 			var currentResult []*public_protocol_common.DItemOffset
-			ret, currentResult = randomWithPoolInternal(item.GetTypeId(), used, nil)
+			ret, currentResult = randomWithPoolInternal(rng, item.GetTypeId(), used, nil)
 			if ret != 0 {
 				return
 			}
@@ -364,6 +370,20 @@ func addRandomResult(item *public_protocol_config.Readonly_DRandomPoolElement, u
 	return
 }
 
+func randomInt32N(rng *rand.Rand, n int32) int32 {
+	if rng == nil {
+		return rand.Int32N(n)
+	}
+	return rng.Int32N(n)
+}
+
+func randomInt64N(rng *rand.Rand, n int64) int64 {
+	if rng == nil {
+		return rand.Int64N(n)
+	}
+	return rng.Int64N(n)
+}
+
 type itemCountExpire struct {
 	count        int64
 	expireOffset int64
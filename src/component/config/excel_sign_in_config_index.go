@@ -0,0 +1,58 @@
+package atframework_component_config
+
+import (
+	"fmt"
+
+	generate_config "github.com/atframework/atsf4g-go/component/config/generate_config"
+	public_protocol_common "github.com/atframework/atsf4g-go/component/protocol/public/common/protocol/common"
+	public_protocol_config "github.com/atframework/atsf4g-go/component/protocol/public/config/protocol/config"
+)
+
+// signInMonthlyMaxDays 月签最多 31 天
+const signInMonthlyMaxDays = 31
+
+// initExcelSignInConfigIndex 检查签到日历和奖励配置
+func initExcelSignInConfigIndex(group *generate_config.ConfigGroup) error {
+	if group.GetExcelSignInCalendarAllOfCalendarId() == nil {
+		return nil
+	}
+
+	for _, v := range *group.GetExcelSignInCalendarAllOfCalendarId() {
+		if err := checkExcelSignInCalendar(group, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetSignInCalendarPeriodDays 签到日历一个周期的最大天数
+func GetSignInCalendarPeriodDays(calendar *public_protocol_config.Readonly_ExcelSignInCalendar) int32 {
+	switch calendar.GetCalendarType() {
+	case public_protocol_common.EnSignInCalendarType_EN_SIGN_IN_CALENDAR_TYPE_MONTHLY:
+		return signInMonthlyMaxDays
+	case public_protocol_common.EnSignInCalendarType_EN_SIGN_IN_CALENDAR_TYPE_CYCLE:
+		return calendar.GetCycleDays()
+	default:
+		return 0
+	}
+}
+
+func checkExcelSignInCalendar(group *generate_config.ConfigGroup, calendar *public_protocol_config.Readonly_ExcelSignInCalendar) error {
+	periodDays := GetSignInCalendarPeriodDays(calendar)
+	if periodDays <= 0 {
+		return fmt.Errorf("sign in calendar %d type %d or cycle days %d invalid",
+			calendar.GetCalendarId(), calendar.GetCalendarType(), calendar.GetCycleDays())
+	}
+
+	if calendar.GetMakeUpMaxCount() < 0 {
+		return fmt.Errorf("sign in calendar %d make up max count %d invalid", calendar.GetCalendarId(), calendar.GetMakeUpMaxCount())
+	}
+
+	for _, reward := range group.GetExcelSignInRewardByCalendarId(calendar.GetCalendarId()) {
+		if reward.GetDay() <= 0 || reward.GetDay() > periodDays {
+			return fmt.Errorf("sign in calendar %d reward day %d out of range [1, %d]",
+				calendar.GetCalendarId(), reward.GetDay(), periodDays)
+		}
+	}
+	return nil
+}
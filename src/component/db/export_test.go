@@ -0,0 +1,25 @@
+package atframework_component_db
+
+import (
+	"time"
+
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+)
+
+// 只在测试中导出的内部接口，TableCacheManager 的回写任务和订阅回调需要完整的 app 运行环境
+
+func (c *TableCache[K, V]) Flush(ctx cd.AwaitableContext, batchCount int) ([]string, cd.RpcResult) {
+	return c.flush(ctx, batchCount)
+}
+
+func (c *TableCache[K, V]) EvictExpired(now time.Time) int {
+	return c.evictExpired(now)
+}
+
+func (m *TableCacheManager) ReceiveInvalidateMessage(channel string, payload []byte) {
+	m.receiveInvalidateMessage(channel, payload)
+}
+
+func (m *TableCacheManager) StopAt(now time.Time) bool {
+	return m.stopAt(now)
+}
@@ -10,6 +10,8 @@ import (
 	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
 	"github.com/atframework/libatapp-go"
 	"google.golang.org/protobuf/proto"
+
+	storage "github.com/atframework/atsf4g-go/component/db/storage"
 )
 
 func HashTableLoadListAll(ctx cd.AwaitableContext, index string, tableName string,
@@ -56,7 +58,7 @@ func HashTableLoadListAll(ctx cd.AwaitableContext, index string, tableName strin
 					}
 					return redisError
 				}
-				if len(result) == 0 {
+				if isListRecordEmpty(result) {
 					ctx.GetApp().GetLogger(2).LogInfo("HashTableLoadListAll HGetAll Record Not Found", "TableName", tableName, "Seq", awaitOption.Sequence)
 					resumeData.Result = cd.CreateRpcResultError(fmt.Errorf("record not found"), public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND)
 					resumeError := cd.ResumeTaskAction(ctx, currentAction, resumeData)
@@ -172,7 +174,7 @@ func HashTableLoadListIndex(ctx cd.AwaitableContext, index string, tableName str
 					}
 					return redisError
 				}
-				if len(result) == 0 {
+				if isListIndexAllMissing(result) {
 					ctx.GetApp().GetLogger(2).LogInfo("HashTableLoadListIndex HMGet Record Not Found", "TableName", tableName, "Seq", awaitOption.Sequence)
 					resumeData.Result = cd.CreateRpcResultError(fmt.Errorf("record not found"), public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND)
 					resumeError := cd.ResumeTaskAction(ctx, currentAction, resumeData)
@@ -478,3 +480,23 @@ func HashTableAddList(ctx cd.AwaitableContext, index string, tableName string,
 	newListIndex = *newListIndexPtr
 	return
 }
+
+// isListRecordEmpty KL 表删除所有数据后仍然保留自增下标字段，没有数据时等同于记录不存在
+func isListRecordEmpty(result map[string]string) bool {
+	for field := range result {
+		if field != storage.ListIndexField {
+			return false
+		}
+	}
+	return true
+}
+
+// isListIndexAllMissing HMGet 总是按字段数返回，所有下标都不存在时才是记录不存在
+func isListIndexAllMissing(result []interface{}) bool {
+	for _, value := range result {
+		if value != nil {
+			return false
+		}
+	}
+	return true
+}
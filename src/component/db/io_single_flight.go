@@ -0,0 +1,120 @@
+package atframework_component_db
+
+import (
+	"container/list"
+	"sync"
+	"unsafe"
+
+	lu "github.com/atframework/atframe-utils-go/lang_utility"
+	config "github.com/atframework/atsf4g-go/component/config"
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+)
+
+// IOSingleFlight 同一个 key 同一时间只有一个任务执行 IO，其他任务挂起等待它完成后共享结果
+// 零值可直接使用
+type IOSingleFlight[K comparable] struct {
+	mu    sync.Mutex
+	calls map[K]*ioSingleFlightCall
+}
+
+type ioSingleFlightCall struct {
+	ioTask          cd.TaskActionImpl
+	awaitIOTaskList list.List
+	result          cd.RpcResult
+}
+
+// Do 没有进行中的 IO 时在当前任务执行 fn，否则挂起等待进行中的 IO 完成
+// shared 为 true 时 result 是其他任务的执行结果，调用方通常需要重新检查自己的缓存
+func (g *IOSingleFlight[K]) Do(ctx cd.AwaitableContext, key K,
+	fn func(ctx cd.AwaitableContext) cd.RpcResult,
+) (shared bool, result cd.RpcResult) {
+	currentTask := ctx.GetAction()
+	if lu.IsNil(currentTask) || currentTask.GetTaskId() == 0 {
+		ctx.LogError("should in task")
+		return false, cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_RPC_NO_TASK)
+	}
+
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[K]*ioSingleFlightCall)
+	}
+	call := g.calls[key]
+	if call != nil && call.ioTask == currentTask {
+		// 重入时直接执行
+		g.mu.Unlock()
+		return false, fn(ctx)
+	}
+
+	if call != nil && !call.ioTask.IsExiting() {
+		// 等待当前任务完成IO
+		resumeElement := &struct {
+			ele *list.Element
+		}{
+			ele: nil,
+		}
+		_, result = cd.YieldTaskAction(ctx, currentTask, &cd.DispatcherAwaitOptions{
+			Type:     uint64(uintptr(unsafe.Pointer(call))),
+			Sequence: currentTask.GetTaskId(),
+			Timeout:  config.GetConfigManager().GetCurrentConfigGroup().GetSectionConfig().GetTask().GetCsmsg().GetTimeout().AsDuration(),
+		}, &cd.YieldTaskHookSet{
+			PreYield: func(ctx cd.RpcContext) cd.RpcResult {
+				resumeElement.ele = call.awaitIOTaskList.PushBack(currentTask)
+				return cd.CreateRpcResultOk()
+			},
+			PreYieldCleanup: func(_ cd.RpcContext) {
+				g.mu.Unlock()
+			},
+			PostYield: func(ctx cd.RpcContext, _resume *cd.DispatcherResumeData, result cd.RpcResult) cd.RpcResult {
+				if resumeElement.ele != nil {
+					g.mu.Lock()
+					call.awaitIOTaskList.Remove(resumeElement.ele)
+					g.mu.Unlock()
+				}
+				return result
+			},
+		})
+		if result.IsError() {
+			return true, result
+		}
+
+		g.mu.Lock()
+		result = call.result
+		g.mu.Unlock()
+		return true, result
+	}
+
+	// 没有进行中的IO，或者执行IO的任务已经退出
+	call = &ioSingleFlightCall{ioTask: currentTask}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	result = fn(ctx)
+
+	g.mu.Lock()
+	call.result = result
+	if g.calls[key] == call {
+		delete(g.calls, key)
+	}
+	awaitTasks := make([]cd.TaskActionImpl, 0, call.awaitIOTaskList.Len())
+	for call.awaitIOTaskList.Len() > 0 {
+		elem := call.awaitIOTaskList.Front()
+		awaitTasks = append(awaitTasks, elem.Value.(cd.TaskActionImpl))
+		call.awaitIOTaskList.Remove(elem)
+	}
+	g.mu.Unlock()
+
+	// 唤醒等待的任务
+	for _, task := range awaitTasks {
+		if task.IsExiting() {
+			continue
+		}
+		cd.ResumeTaskAction(ctx, task, &cd.DispatcherResumeData{
+			Message: &cd.DispatcherRawMessage{
+				Type: uint64(uintptr(unsafe.Pointer(call))),
+			},
+			Sequence: task.GetTaskId(),
+		})
+	}
+	return false, result
+}
@@ -0,0 +1,730 @@
+package atframework_component_db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	lu "github.com/atframework/atframe-utils-go/lang_utility"
+	pu "github.com/atframework/atframe-utils-go/proto_utility"
+	storage "github.com/atframework/atsf4g-go/component/db/storage"
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	"github.com/atframework/libatapp-go"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/protobuf/proto"
+)
+
+// Pipeline 收集多个数据库操作，Execute 时通过一次 Redis pipeline 发送，任务只切出一次
+// transaction 为 false 时各操作互不影响，CAS 检查失败只影响对应的操作，其他操作照常写入
+// transaction 为 true 时先 WATCH 所有 CAS 操作的 key 并检查版本，任何一个不匹配则所有操作都不执行，
+// 结果均为 EN_ERR_DB_CAS_CHECK_FAILED；检查通过后用 MULTI/EXEC 写入，期间 key 被修改时同样整体放弃
+// 集群模式下 transaction 的所有 key 必须在同一个 slot 内；命令本身的运行错误（如类型不匹配）Redis 不会回滚
+// 非 Redis 后端按添加顺序逐个执行，本地后端不会切出任务，所以同样不会被其他任务打断
+// 所有操作都走主节点，不受 replica_read 配置影响
+type Pipeline struct {
+	ctx         cd.AwaitableContext
+	transaction bool
+	operations  []*pipelineOperation
+	executed    bool
+}
+
+// PipelineResult 只关心成功与否的操作结果，Execute 之后才有效
+type PipelineResult struct {
+	Result cd.RpcResult
+}
+
+// PipelineExpireResult 设置过期时间的结果，Execute 之后才有效
+type PipelineExpireResult struct {
+	// key 不存在或者不满足条件时为 false
+	Success bool
+	Result  cd.RpcResult
+}
+
+// PipelineListLoadResult KL 表读取结果，Execute 之后才有效
+type PipelineListLoadResult struct {
+	IndexMessage []pu.RedisListIndexMessage
+	Result       cd.RpcResult
+}
+
+type pipelineOperation struct {
+	tableName string
+	index     string
+	// CAS 写操作的版本信息，其他操作为 nil
+	cas *pipelineCASCheck
+	// Redis 后端：把命令加入 pipeline，返回 Exec 之后解析结果的函数
+	queue func(ctx context.Context, pipe redis.Pipeliner, instance cd.RedisClientWrapper, dispatcher *cd.RedisMessageDispatcher) func()
+	// 其他后端：直接调用 StorageBackend
+	call func(ctx cd.AwaitableContext, backend StorageBackend)
+	// transaction 整体放弃时设置结果
+	abort func(result cd.RpcResult)
+	// 回到任务后按添加顺序调用
+	complete func()
+}
+
+// pipelineCASCheck CAS 写操作的版本信息，和 CAS 脚本的判断一致：
+// 数据库中没有版本、强制写入或者版本相同时才写入，写入后版本加一
+type pipelineCASCheck struct {
+	table         proto.Message
+	versionField  string
+	expectVersion uint64
+	forceUpdate   bool
+	// 数据库中的版本，检查失败时带回给调用方
+	realVersion uint64
+	// transaction 模式下已经在 WATCH 之后检查过版本，直接用 HSET 写入
+	checked bool
+}
+
+func (c *pipelineCASCheck) match(realVersion uint64) bool {
+	return c.forceUpdate || realVersion == 0 || realVersion == c.expectVersion
+}
+
+// CreatePipeline 创建数据库批量操作
+func CreatePipeline(ctx cd.AwaitableContext, transaction bool) *Pipeline {
+	return &Pipeline{
+		ctx:         ctx,
+		transaction: transaction,
+	}
+}
+
+func (p *Pipeline) GetContext() cd.AwaitableContext {
+	return p.ctx
+}
+
+func (p *Pipeline) IsTransaction() bool {
+	return p.transaction
+}
+
+// Len 已添加的操作数量
+func (p *Pipeline) Len() int {
+	return len(p.operations)
+}
+
+// HashTableLoad 读取 KV 表，onComplete 在 Execute 返回前调用
+func (p *Pipeline) HashTableLoad(index string, tableName string, messageCreate func() proto.Message,
+	onComplete func(table proto.Message, CASVersion uint64, result cd.RpcResult),
+) {
+	var table proto.Message
+	var casVersion uint64
+	result := createPipelineNotExecutedResult()
+	p.operations = append(p.operations, &pipelineOperation{
+		tableName: tableName,
+		index:     index,
+		queue: func(ctx context.Context, pipe redis.Pipeliner, _ cd.RedisClientWrapper, _ *cd.RedisMessageDispatcher) func() {
+			cmd := pipe.HGetAll(ctx, index)
+			return func() {
+				data, redisError := cmd.Result()
+				if redisError != nil {
+					result = cd.CreateRpcResultError(redisError, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+					return
+				}
+				if len(data) == 0 {
+					result = cd.CreateRpcResultError(fmt.Errorf("record not found"), public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND)
+					return
+				}
+				pbResult := messageCreate()
+				version, err := pu.RedisKVMapToPB(data, pbResult)
+				if err != nil {
+					result = cd.CreateRpcResultError(err, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM_BAD_PACKAGE)
+					return
+				}
+				table = pbResult
+				casVersion = version
+				result = cd.CreateRpcResultOk()
+			}
+		},
+		call: func(ctx cd.AwaitableContext, backend StorageBackend) {
+			table, casVersion, result = backend.HashTableLoad(ctx, index, tableName, messageCreate)
+		},
+		abort: func(abortResult cd.RpcResult) {
+			result = abortResult
+		},
+		complete: func() {
+			if onComplete != nil {
+				onComplete(table, casVersion, result)
+			}
+		},
+	})
+}
+
+// HashTablePartlyGet 读取 KV 表的部分字段，onComplete 在 Execute 返回前调用
+func (p *Pipeline) HashTablePartlyGet(index string, tableName string, messageCreate func() proto.Message,
+	partlyGetField []string, onComplete func(table proto.Message, CASVersion uint64, result cd.RpcResult),
+) {
+	var table proto.Message
+	var casVersion uint64
+	result := createPipelineNotExecutedResult()
+	p.operations = append(p.operations, &pipelineOperation{
+		tableName: tableName,
+		index:     index,
+		queue: func(ctx context.Context, pipe redis.Pipeliner, _ cd.RedisClientWrapper, _ *cd.RedisMessageDispatcher) func() {
+			cmd := pipe.HMGet(ctx, index, partlyGetField...)
+			return func() {
+				data, redisError := cmd.Result()
+				if redisError != nil {
+					result = cd.CreateRpcResultError(redisError, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+					return
+				}
+				if len(data) == 0 {
+					result = cd.CreateRpcResultError(fmt.Errorf("record not found"), public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND)
+					return
+				}
+				pbResult := messageCreate()
+				version, recordExist, err := pu.RedisSliceKVMapToPB(partlyGetField, data, pbResult)
+				if err != nil {
+					result = cd.CreateRpcResultError(err, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM_BAD_PACKAGE)
+					return
+				}
+				if !recordExist {
+					result = cd.CreateRpcResultError(fmt.Errorf("record not found"), public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND)
+					return
+				}
+				table = pbResult
+				casVersion = version
+				result = cd.CreateRpcResultOk()
+			}
+		},
+		call: func(ctx cd.AwaitableContext, backend StorageBackend) {
+			table, casVersion, result = backend.HashTablePartlyGet(ctx, index, tableName, messageCreate, partlyGetField)
+		},
+		abort: func(abortResult cd.RpcResult) {
+			result = abortResult
+		},
+		complete: func() {
+			if onComplete != nil {
+				onComplete(table, casVersion, result)
+			}
+		},
+	})
+}
+
+// HashTableLoadListAll 读取 KL 表的全部元素，onComplete 在 Execute 返回前调用
+func (p *Pipeline) HashTableLoadListAll(index string, tableName string, messageCreate func() proto.Message,
+	onComplete func(indexMessage []pu.RedisListIndexMessage, result cd.RpcResult),
+) {
+	var indexMessage []pu.RedisListIndexMessage
+	result := createPipelineNotExecutedResult()
+	p.operations = append(p.operations, &pipelineOperation{
+		tableName: tableName,
+		index:     index,
+		queue: func(ctx context.Context, pipe redis.Pipeliner, _ cd.RedisClientWrapper, _ *cd.RedisMessageDispatcher) func() {
+			cmd := pipe.HGetAll(ctx, index)
+			return func() {
+				data, redisError := cmd.Result()
+				if redisError != nil {
+					result = cd.CreateRpcResultError(redisError, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+					return
+				}
+				if len(data) == 0 {
+					result = cd.CreateRpcResultError(fmt.Errorf("record not found"), public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND)
+					return
+				}
+				messages, err := pu.RedisKLMapToPB(data, messageCreate)
+				if err != nil {
+					result = cd.CreateRpcResultError(err, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM_BAD_PACKAGE)
+					return
+				}
+				indexMessage = messages
+				result = cd.CreateRpcResultOk()
+			}
+		},
+		call: func(ctx cd.AwaitableContext, backend StorageBackend) {
+			indexMessage, result = backend.HashTableLoadListAll(ctx, index, tableName, messageCreate)
+		},
+		abort: func(abortResult cd.RpcResult) {
+			result = abortResult
+		},
+		complete: func() {
+			if onComplete != nil {
+				onComplete(indexMessage, result)
+			}
+		},
+	})
+}
+
+// HashTableUpdate 覆盖写 KV 表，添加时即序列化 table，之后的修改不会写入
+func (p *Pipeline) HashTableUpdate(index string, tableName string, table proto.Message,
+	onComplete func(result cd.RpcResult),
+) {
+	redisData := pu.PBMapToRedisKV(table, nil, false)
+	result := createPipelineNotExecutedResult()
+	p.operations = append(p.operations, &pipelineOperation{
+		tableName: tableName,
+		index:     index,
+		queue: func(ctx context.Context, pipe redis.Pipeliner, _ cd.RedisClientWrapper, _ *cd.RedisMessageDispatcher) func() {
+			cmd := pipe.HSet(ctx, index, redisData)
+			return func() {
+				if redisError := cmd.Err(); redisError != nil {
+					result = cd.CreateRpcResultError(redisError, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+					return
+				}
+				result = cd.CreateRpcResultOk()
+			}
+		},
+		call: func(ctx cd.AwaitableContext, backend StorageBackend) {
+			result = backend.HashTableUpdate(ctx, index, tableName, table)
+		},
+		abort: func(abortResult cd.RpcResult) {
+			result = abortResult
+		},
+		complete: func() {
+			if onComplete != nil {
+				onComplete(result)
+			}
+		},
+	})
+}
+
+// HashTableUpdateCAS 带版本检查写 KV 表，Execute 返回后 currentCASVersion 更新为数据库中的版本
+// 添加时即序列化 table，之后的修改不会写入
+func (p *Pipeline) HashTableUpdateCAS(index string, tableName string, table proto.Message,
+	currentCASVersion *uint64, forceUpdate bool, onComplete func(result cd.RpcResult),
+) {
+	if forceUpdate {
+		*currentCASVersion = 0
+	}
+	oldCASVersion := *currentCASVersion
+	redisData := pu.PBMapToRedisKV(table, &oldCASVersion, forceUpdate)
+	// 前两个参数是版本字段名和期望的版本
+	redisArgs := storage.FlattenArgs(redisData)
+	cas := &pipelineCASCheck{
+		table:         table,
+		versionField:  redisArgs[0],
+		expectVersion: oldCASVersion,
+		forceUpdate:   forceUpdate,
+	}
+	result := createPipelineNotExecutedResult()
+	p.operations = append(p.operations, &pipelineOperation{
+		tableName: tableName,
+		index:     index,
+		cas:       cas,
+		queue: func(ctx context.Context, pipe redis.Pipeliner, instance cd.RedisClientWrapper, dispatcher *cd.RedisMessageDispatcher) func() {
+			if cas.checked {
+				// 版本已经在 WATCH 之后检查过，EXEC 成功即写入成功
+				newVersion := cas.realVersion + 1
+				args := append([]string{}, redisArgs...)
+				args[1] = strconv.FormatUint(newVersion, 10)
+				cmd := pipe.HSet(ctx, index, args)
+				return func() {
+					if redisError := cmd.Err(); redisError != nil {
+						result = cd.CreateRpcResultError(redisError, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+						return
+					}
+					cas.realVersion = newVersion
+					result = cd.CreateRpcResultOk()
+				}
+			}
+
+			cmd := pipe.EvalSha(ctx, dispatcher.GetCASLuaSHA(), []string{index}, redisData)
+			return func() {
+				cmdResult, redisError := cmd.Result()
+				if redisError != nil && redis.HasErrorPrefix(redisError, "NOSCRIPT") {
+					// Redis 重启或者 SCRIPT FLUSH 之后脚本缓存丢失，单独用 EVAL 重试一次，EVAL 会重新缓存脚本
+					// 这时这个写操作会晚于 pipeline 中后面的操作执行
+					cmdResult, redisError = instance.Eval(ctx, cd.CASLuaScript, []string{index}, redisData).Result()
+				}
+				var realVersion uint64
+				if redisError == nil {
+					switch val := cmdResult.(type) {
+					case string:
+						realVersion, redisError = strconv.ParseUint(val, 10, 64)
+					case []byte:
+						realVersion, redisError = strconv.ParseUint(string(val), 10, 64)
+					default:
+						redisError = fmt.Errorf("unsupport cmd result")
+					}
+				}
+				if redisError != nil {
+					result = cd.CreateRpcResultError(redisError, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+					return
+				}
+				cas.realVersion = realVersion
+				if !forceUpdate && oldCASVersion+1 != realVersion {
+					result = cd.CreateRpcResultError(fmt.Errorf("cas check failed"), public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_CAS_CHECK_FAILED)
+					return
+				}
+				result = cd.CreateRpcResultOk()
+			}
+		},
+		call: func(ctx cd.AwaitableContext, backend StorageBackend) {
+			realVersion := oldCASVersion
+			result = backend.HashTableUpdateCAS(ctx, index, tableName, table, &realVersion, forceUpdate)
+			cas.realVersion = realVersion
+		},
+		abort: func(abortResult cd.RpcResult) {
+			result = abortResult
+		},
+		complete: func() {
+			// 和单次调用一样，CAS 检查失败时也带回数据库中的版本
+			if cas.realVersion != 0 {
+				*currentCASVersion = cas.realVersion
+			}
+			if onComplete != nil {
+				onComplete(result)
+			}
+		},
+	})
+}
+
+// HashTableUpdateList 覆盖写 KL 表的一个元素，添加时即序列化 table
+func (p *Pipeline) HashTableUpdateList(index string, tableName string, table proto.Message, listIndex uint64,
+	onComplete func(result cd.RpcResult),
+) {
+	redisData := pu.PBMapToRedisKLUpdate(table, listIndex)
+	result := createPipelineNotExecutedResult()
+	p.operations = append(p.operations, &pipelineOperation{
+		tableName: tableName,
+		index:     index,
+		queue: func(ctx context.Context, pipe redis.Pipeliner, _ cd.RedisClientWrapper, _ *cd.RedisMessageDispatcher) func() {
+			cmd := pipe.HSet(ctx, index, redisData)
+			return func() {
+				if redisError := cmd.Err(); redisError != nil {
+					result = cd.CreateRpcResultError(redisError, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+					return
+				}
+				result = cd.CreateRpcResultOk()
+			}
+		},
+		call: func(ctx cd.AwaitableContext, backend StorageBackend) {
+			result = backend.HashTableUpdateList(ctx, index, tableName, table, listIndex)
+		},
+		abort: func(abortResult cd.RpcResult) {
+			result = abortResult
+		},
+		complete: func() {
+			if onComplete != nil {
+				onComplete(result)
+			}
+		},
+	})
+}
+
+// TableDel 删除整条记录
+func (p *Pipeline) TableDel(index string, tableName string, onComplete func(result cd.RpcResult)) {
+	result := createPipelineNotExecutedResult()
+	p.operations = append(p.operations, &pipelineOperation{
+		tableName: tableName,
+		index:     index,
+		queue: func(ctx context.Context, pipe redis.Pipeliner, _ cd.RedisClientWrapper, _ *cd.RedisMessageDispatcher) func() {
+			cmd := pipe.Del(ctx, index)
+			return func() {
+				if redisError := cmd.Err(); redisError != nil {
+					result = cd.CreateRpcResultError(redisError, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+					return
+				}
+				result = cd.CreateRpcResultOk()
+			}
+		},
+		call: func(ctx cd.AwaitableContext, backend StorageBackend) {
+			result = backend.TableDel(ctx, index, tableName)
+		},
+		abort: func(abortResult cd.RpcResult) {
+			result = abortResult
+		},
+		complete: func() {
+			if onComplete != nil {
+				onComplete(result)
+			}
+		},
+	})
+}
+
+// TableExpire 设置整条记录的过期时间，condition 和 Redis EXPIRE 的 NX/XX/GT/LT 一致
+func (p *Pipeline) TableExpire(index string, tableName string, expiration time.Duration, condition ExpireConditionOption,
+	onComplete func(success bool, result cd.RpcResult),
+) {
+	var success bool
+	result := createPipelineNotExecutedResult()
+	p.operations = append(p.operations, &pipelineOperation{
+		tableName: tableName,
+		index:     index,
+		queue: func(ctx context.Context, pipe redis.Pipeliner, _ cd.RedisClientWrapper, _ *cd.RedisMessageDispatcher) func() {
+			var cmd *redis.BoolCmd
+			switch condition {
+			case ExpireConditionNX:
+				cmd = pipe.ExpireNX(ctx, index, expiration)
+			case ExpireConditionXX:
+				cmd = pipe.ExpireXX(ctx, index, expiration)
+			case ExpireConditionGT:
+				cmd = pipe.ExpireGT(ctx, index, expiration)
+			case ExpireConditionLT:
+				cmd = pipe.ExpireLT(ctx, index, expiration)
+			default:
+				cmd = pipe.Expire(ctx, index, expiration)
+			}
+			return func() {
+				cmdResult, redisError := cmd.Result()
+				if redisError != nil {
+					result = cd.CreateRpcResultError(redisError, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+					return
+				}
+				success = cmdResult
+				result = cd.CreateRpcResultOk()
+			}
+		},
+		call: func(ctx cd.AwaitableContext, backend StorageBackend) {
+			success, result = backend.TableExpire(ctx, index, tableName, expiration, condition)
+		},
+		abort: func(abortResult cd.RpcResult) {
+			result = abortResult
+		},
+		complete: func() {
+			if onComplete != nil {
+				onComplete(success, result)
+			}
+		},
+	})
+}
+
+// Execute 发送所有操作，每个 Pipeline 只能执行一次
+// 返回值表示发送是否成功，transaction 模式下整体放弃时返回放弃的原因
+// 各操作的结果通过各自的 onComplete 或结果对象获取
+func (p *Pipeline) Execute() (retResult cd.RpcResult) {
+	ctx := p.ctx
+	if p.executed {
+		ctx.LogError("db pipeline already executed")
+		return cd.CreateRpcResultError(fmt.Errorf("pipeline already executed"), public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+	}
+	p.executed = true
+	if len(p.operations) == 0 {
+		return cd.CreateRpcResultOk()
+	}
+
+	backend := GetStorageBackend(ctx.GetApp())
+	if backend == nil {
+		ctx.LogError("get db storage backend failed")
+		return cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+	}
+
+	redisBackend, ok := backend.(*redisStorageBackend)
+	if !ok {
+		retResult = p.executeBackend(backend)
+		p.complete()
+		return
+	}
+
+	instance, retResult := redisBackend.getInstance(ctx)
+	if retResult.IsError() {
+		return
+	}
+	var abortResult cd.RpcResult
+	abortResult, retResult = executeRedisPipeline(ctx, redisBackend.dispatcher, instance, p.operations, p.transaction)
+	if retResult.IsError() {
+		return
+	}
+	p.complete()
+	return abortResult
+}
+
+// executeBackend 非 Redis 后端按顺序调用，transaction 模式下先检查所有 CAS 操作的版本
+func (p *Pipeline) executeBackend(backend StorageBackend) cd.RpcResult {
+	ctx := p.ctx
+	if p.transaction {
+		casMatched := true
+		for _, op := range p.operations {
+			if op.cas == nil {
+				continue
+			}
+			cas := op.cas
+			_, realVersion, result := backend.HashTableLoad(ctx, op.index, op.tableName, func() proto.Message {
+				return cas.table.ProtoReflect().New().Interface()
+			})
+			if result.IsError() && result.GetResponseCode() != int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND) {
+				abortPipelineOperations(p.operations, result)
+				return result
+			}
+			cas.realVersion = realVersion
+			if !cas.match(realVersion) {
+				casMatched = false
+			}
+		}
+		if !casMatched {
+			ctx.LogInfo("db pipeline transaction cas check failed", "Count", len(p.operations))
+			result := createPipelineCASCheckFailedResult()
+			abortPipelineOperations(p.operations, result)
+			return result
+		}
+	}
+
+	for _, op := range p.operations {
+		op.call(ctx, backend)
+	}
+	return cd.CreateRpcResultOk()
+}
+
+func (p *Pipeline) complete() {
+	for _, op := range p.operations {
+		op.complete()
+	}
+}
+
+func abortPipelineOperations(operations []*pipelineOperation, result cd.RpcResult) {
+	for _, op := range operations {
+		op.abort(result)
+	}
+}
+
+func createPipelineNotExecutedResult() cd.RpcResult {
+	return cd.CreateRpcResultError(fmt.Errorf("pipeline not executed"), public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+}
+
+func createPipelineCASCheckFailedResult() cd.RpcResult {
+	return cd.CreateRpcResultError(errPipelineCASCheckFailed, public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_CAS_CHECK_FAILED)
+}
+
+var errPipelineCASCheckFailed = errors.New("pipeline transaction cas check failed")
+
+// runRedisPipeline 发送命令并解析结果，返回 transaction 整体放弃的原因和需要记录的 Redis 错误
+// 单个命令的错误由各自的解析函数处理
+func runRedisPipeline(ctx context.Context, dispatcher *cd.RedisMessageDispatcher, instance cd.RedisClientWrapper,
+	operations []*pipelineOperation, transaction bool,
+) (abortResult cd.RpcResult, redisError error) {
+	queueAll := func(pipe redis.Pipeliner) []func() {
+		parsers := make([]func(), 0, len(operations))
+		for _, op := range operations {
+			parsers = append(parsers, op.queue(ctx, pipe, instance, dispatcher))
+		}
+		return parsers
+	}
+
+	var casOperations []*pipelineOperation
+	if transaction {
+		for _, op := range operations {
+			if op.cas != nil {
+				casOperations = append(casOperations, op)
+			}
+		}
+	}
+
+	if len(casOperations) == 0 {
+		var pipe redis.Pipeliner
+		if transaction {
+			pipe = instance.TxPipeline()
+		} else {
+			pipe = instance.Pipeline()
+		}
+		parsers := queueAll(pipe)
+		_, redisError = pipe.Exec(ctx)
+		for _, parser := range parsers {
+			parser()
+		}
+		return
+	}
+
+	// transaction 中有 CAS 操作时先 WATCH 再检查版本，EXEC 之前 key 被修改会返回 TxFailedErr
+	keys := make([]string, 0, len(casOperations))
+	for _, op := range casOperations {
+		keys = append(keys, op.index)
+	}
+	var parsers []func()
+	redisError = instance.Watch(ctx, func(tx *redis.Tx) error {
+		casMatched := true
+		for _, op := range casOperations {
+			realVersion, err := tx.HGet(ctx, op.index, op.cas.versionField).Uint64()
+			if errors.Is(err, redis.Nil) {
+				realVersion, err = 0, nil
+			}
+			if err != nil {
+				return err
+			}
+			op.cas.realVersion = realVersion
+			if !op.cas.match(realVersion) {
+				casMatched = false
+			}
+		}
+		if !casMatched {
+			return errPipelineCASCheckFailed
+		}
+		for _, op := range casOperations {
+			op.cas.checked = true
+		}
+		_, err := tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			parsers = queueAll(pipe)
+			return nil
+		})
+		return err
+	}, keys...)
+
+	if errors.Is(redisError, errPipelineCASCheckFailed) || errors.Is(redisError, redis.TxFailedErr) {
+		abortResult = createPipelineCASCheckFailedResult()
+		abortPipelineOperations(operations, abortResult)
+		return
+	}
+	if parsers == nil {
+		// WATCH 或者读取版本失败，命令还没有发送
+		abortResult = cd.CreateRpcResultError(redisError, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+		abortPipelineOperations(operations, abortResult)
+		return
+	}
+	for _, parser := range parsers {
+		parser()
+	}
+	return
+}
+
+func executeRedisPipeline(ctx cd.AwaitableContext, dispatcher *cd.RedisMessageDispatcher, instance cd.RedisClientWrapper,
+	operations []*pipelineOperation, transaction bool,
+) (abortResult cd.RpcResult, retResult cd.RpcResult) {
+	awaitOption := dispatcher.CreateDispatcherAwaitOptions()
+	currentAction := ctx.GetAction()
+	if lu.IsNil(currentAction) {
+		ctx.LogError("not in context action")
+		retResult = cd.CreateRpcResultError(fmt.Errorf("action not found"), public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+		return
+	}
+	if currentAction.GetRpcContext() == nil || lu.IsNil(currentAction.GetRpcContext().GetContext()) {
+		ctx.LogError("not found context")
+		retResult = cd.CreateRpcResultError(fmt.Errorf("context not found"), public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+		return
+	}
+
+	hooks := &cd.YieldTaskHookSet{
+		PreYield: func(ctx cd.RpcContext) cd.RpcResult {
+			err := ctx.GetApp().PushAction(func(app_action *libatapp.AppActionData) error {
+				ctx.GetApp().GetLogger(2).LogDebug("Pipeline Exec Send", "Seq", awaitOption.Sequence, "Count", len(operations), "Transaction", transaction)
+				pipelineAbortResult, redisError := runRedisPipeline(ctx.GetContext(), dispatcher, instance, operations, transaction)
+				if redisError != nil {
+					ctx.GetApp().GetLogger(2).LogWarn("Pipeline Exec Recv Error", "Seq", awaitOption.Sequence, "redisError", redisError)
+				}
+				for _, op := range operations {
+					ctx.GetApp().GetLogger(2).LogDebug("Pipeline Exec Recv", "Seq", awaitOption.Sequence, "TableName", op.tableName,
+						"index", op.index)
+				}
+
+				resumeData := &cd.DispatcherResumeData{
+					Message: &cd.DispatcherRawMessage{
+						Type: awaitOption.Type,
+					},
+					Sequence:    awaitOption.Sequence,
+					PrivateData: &pipelineAbortResult,
+					Result:      cd.CreateRpcResultOk(),
+				}
+				resumeError := cd.ResumeTaskAction(ctx, currentAction, resumeData)
+				if resumeError != nil {
+					ctx.LogError("pipeline resume error", "err", resumeError)
+					return resumeError
+				}
+				return nil
+			}, nil, nil)
+			if err != nil {
+				return cd.CreateRpcResultError(err, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+			}
+			return cd.CreateRpcResultOk()
+		},
+	}
+	var resumeData *cd.DispatcherResumeData
+	resumeData, retResult = cd.YieldTaskAction(ctx, currentAction, awaitOption, hooks)
+	if retResult.IsError() {
+		return
+	}
+	if resumeData.Result.IsError() {
+		retResult = resumeData.Result
+		return
+	}
+	if pipelineAbortResult, ok := resumeData.PrivateData.(*cd.RpcResult); ok {
+		abortResult = *pipelineAbortResult
+	}
+	return
+}
@@ -0,0 +1,165 @@
+package atframework_component_db_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	db "github.com/atframework/atsf4g-go/component/db"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	test_utility "github.com/atframework/atsf4g-go/component/test_utility"
+)
+
+const (
+	testPipelineZoneId uint32 = 1
+	testPipelineUserId uint64 = 50001
+	testPipelineOpenId        = "pipeline-open-id"
+)
+
+func createPipelineTestContext(t *testing.T) (*test_utility.MockRpcContext, uint64) {
+	app, _ := test_utility.CreateMemoryStorageApp()
+	ctx := test_utility.NewMockRpcContext(app)
+	var casVersion uint64
+	assert.False(t, db.DatabaseTableUserReplaceZoneIdUserId(ctx, &private_protocol_pbdesc.DatabaseTableUser{
+		ZoneId: testPipelineZoneId,
+		UserId: testPipelineUserId,
+		OpenId: testPipelineOpenId,
+	}, &casVersion, false).IsError())
+	return ctx, casVersion
+}
+
+func createPipelineTestAccess(loginCode string) *private_protocol_pbdesc.DatabaseTableAccess {
+	return &private_protocol_pbdesc.DatabaseTableAccess{
+		OpenId:    testPipelineOpenId,
+		UserId:    testPipelineUserId,
+		ZoneId:    testPipelineZoneId,
+		LoginCode: loginCode,
+	}
+}
+
+// TestPipelineLoadUpdateExpire 测试非 Redis 后端的批量读写
+// Scenario: 按添加顺序执行，读取带回 CAS 版本，对刚写入的记录设置过期时间成功，对不存在的记录设置失败
+func TestPipelineLoadUpdateExpire(t *testing.T) {
+	// Arrange
+	ctx, casVersion := createPipelineTestContext(t)
+	pipeline := db.CreatePipeline(ctx, false)
+	userResult := db.DatabaseTableUserPipelineLoadWithZoneIdUserId(pipeline, testPipelineZoneId, testPipelineUserId)
+	updateResult := db.DatabaseTableAccessPipelineUpdateOpenId(pipeline, createPipelineTestAccess("code"))
+	expireResult := db.DatabaseTableAccessPipelineExpireWithOpenId(pipeline, testPipelineOpenId, time.Hour, db.ExpireConditionNone)
+	missingExpireResult := db.DatabaseTableAccessPipelineExpireWithOpenId(pipeline, "missing-open-id", time.Hour, db.ExpireConditionNone)
+
+	// Act
+	result := pipeline.Execute()
+
+	// Assert
+	assert.False(t, result.IsError())
+	assert.False(t, userResult.Result.IsError())
+	assert.Equal(t, testPipelineOpenId, userResult.Table.GetOpenId())
+	assert.Equal(t, casVersion, userResult.CASVersion)
+	assert.False(t, updateResult.Result.IsError())
+	assert.False(t, expireResult.Result.IsError())
+	assert.True(t, expireResult.Success)
+	assert.False(t, missingExpireResult.Result.IsError())
+	assert.False(t, missingExpireResult.Success)
+
+	accessTb, accessResult := db.DatabaseTableAccessLoadWithOpenId(ctx, testPipelineOpenId)
+	assert.False(t, accessResult.IsError())
+	assert.Equal(t, "code", accessTb.GetLoginCode())
+
+	assert.True(t, pipeline.Execute().IsError())
+}
+
+// TestPipelineCASMismatchWithoutTransaction 测试非 transaction 模式下 CAS 检查失败
+// Scenario: 只有版本不匹配的操作失败并带回数据库中的版本，其他操作照常写入
+func TestPipelineCASMismatchWithoutTransaction(t *testing.T) {
+	// Arrange
+	ctx, casVersion := createPipelineTestContext(t)
+	staleVersion := casVersion + 5
+	pipeline := db.CreatePipeline(ctx, false)
+	replaceResult := db.DatabaseTableUserPipelineReplaceZoneIdUserId(pipeline, &private_protocol_pbdesc.DatabaseTableUser{
+		ZoneId: testPipelineZoneId,
+		UserId: testPipelineUserId,
+		OpenId: "changed",
+	}, &staleVersion, false)
+	updateResult := db.DatabaseTableAccessPipelineUpdateOpenId(pipeline, createPipelineTestAccess("code"))
+
+	// Act
+	result := pipeline.Execute()
+
+	// Assert
+	assert.False(t, result.IsError())
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_CAS_CHECK_FAILED), replaceResult.Result.GetResponseCode())
+	assert.Equal(t, casVersion, staleVersion)
+	assert.False(t, updateResult.Result.IsError())
+
+	userTb, _, userResult := db.DatabaseTableUserLoadWithZoneIdUserId(ctx, testPipelineZoneId, testPipelineUserId)
+	assert.False(t, userResult.IsError())
+	assert.Equal(t, testPipelineOpenId, userTb.GetOpenId())
+	_, accessResult := db.DatabaseTableAccessLoadWithOpenId(ctx, testPipelineOpenId)
+	assert.False(t, accessResult.IsError())
+}
+
+// TestPipelineTransactionCASMismatch 测试 transaction 模式下 CAS 检查失败
+// Scenario: 任何一个 CAS 操作版本不匹配时所有操作都不写入，结果均为 EN_ERR_DB_CAS_CHECK_FAILED
+func TestPipelineTransactionCASMismatch(t *testing.T) {
+	// Arrange
+	ctx, casVersion := createPipelineTestContext(t)
+	staleVersion := casVersion + 5
+	pipeline := db.CreatePipeline(ctx, true)
+	replaceResult := db.DatabaseTableUserPipelineReplaceZoneIdUserId(pipeline, &private_protocol_pbdesc.DatabaseTableUser{
+		ZoneId: testPipelineZoneId,
+		UserId: testPipelineUserId,
+		OpenId: "changed",
+	}, &staleVersion, false)
+	updateResult := db.DatabaseTableAccessPipelineUpdateOpenId(pipeline, createPipelineTestAccess("code"))
+
+	// Act
+	result := pipeline.Execute()
+
+	// Assert
+	casCheckFailed := int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_CAS_CHECK_FAILED)
+	assert.Equal(t, casCheckFailed, result.GetResponseCode())
+	assert.Equal(t, casCheckFailed, replaceResult.Result.GetResponseCode())
+	assert.Equal(t, casCheckFailed, updateResult.Result.GetResponseCode())
+	assert.Equal(t, casVersion, staleVersion)
+
+	userTb, realVersion, userResult := db.DatabaseTableUserLoadWithZoneIdUserId(ctx, testPipelineZoneId, testPipelineUserId)
+	assert.False(t, userResult.IsError())
+	assert.Equal(t, testPipelineOpenId, userTb.GetOpenId())
+	assert.Equal(t, casVersion, realVersion)
+	_, accessResult := db.DatabaseTableAccessLoadWithOpenId(ctx, testPipelineOpenId)
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND), accessResult.GetResponseCode())
+}
+
+// TestPipelineTransactionCASSuccess 测试 transaction 模式下 CAS 检查通过
+// Scenario: 所有操作都写入，CAS 版本加一
+func TestPipelineTransactionCASSuccess(t *testing.T) {
+	// Arrange
+	ctx, casVersion := createPipelineTestContext(t)
+	currentVersion := casVersion
+	pipeline := db.CreatePipeline(ctx, true)
+	replaceResult := db.DatabaseTableUserPipelineReplaceZoneIdUserId(pipeline, &private_protocol_pbdesc.DatabaseTableUser{
+		ZoneId: testPipelineZoneId,
+		UserId: testPipelineUserId,
+		OpenId: "changed",
+	}, &currentVersion, false)
+	updateResult := db.DatabaseTableAccessPipelineUpdateOpenId(pipeline, createPipelineTestAccess("code"))
+
+	// Act
+	result := pipeline.Execute()
+
+	// Assert
+	assert.False(t, result.IsError())
+	assert.False(t, replaceResult.Result.IsError())
+	assert.False(t, updateResult.Result.IsError())
+	assert.Equal(t, casVersion+1, currentVersion)
+
+	userTb, realVersion, userResult := db.DatabaseTableUserLoadWithZoneIdUserId(ctx, testPipelineZoneId, testPipelineUserId)
+	assert.False(t, userResult.IsError())
+	assert.Equal(t, "changed", userTb.GetOpenId())
+	assert.Equal(t, currentVersion, realVersion)
+	_, accessResult := db.DatabaseTableAccessLoadWithOpenId(ctx, testPipelineOpenId)
+	assert.False(t, accessResult.IsError())
+}
@@ -248,6 +248,91 @@ func SortedSetZAddIncr(ctx cd.AwaitableContext, index string, tableName string,
 	return
 }
 
+// SortedSetZRem 从有序集合删除成员，返回实际删除的成员数
+func SortedSetZRem(ctx cd.AwaitableContext, index string, tableName string,
+	dispatcher *cd.RedisMessageDispatcher, instance cd.RedisClientWrapper,
+	members []string,
+) (removed int64, retResult cd.RpcResult) {
+	awaitOption := dispatcher.CreateDispatcherAwaitOptions()
+	currentAction := ctx.GetAction()
+	if lu.IsNil(currentAction) {
+		ctx.LogError("not in context action")
+		retResult = cd.CreateRpcResultError(fmt.Errorf("action not found"), public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+		return
+	}
+	if currentAction.GetRpcContext() == nil || lu.IsNil(currentAction.GetRpcContext().GetContext()) {
+		ctx.LogError("not found context")
+		retResult = cd.CreateRpcResultError(fmt.Errorf("context not found"), public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+		return
+	}
+
+	redisMembers := make([]interface{}, 0, len(members))
+	for _, m := range members {
+		redisMembers = append(redisMembers, m)
+	}
+
+	type innerPrivateData struct {
+		Removed int64
+	}
+
+	hooks := &cd.YieldTaskHookSet{
+		PreYield: func(ctx cd.RpcContext) cd.RpcResult {
+			err := ctx.GetApp().PushAction(func(app_action *libatapp.AppActionData) error {
+				ctx.GetApp().GetLogger(2).LogDebug("SortedSetZRem Send", "TableName", tableName, "Seq", awaitOption.Sequence, "index", index, "memberCount", len(members))
+
+				cmdResult, redisError := instance.ZRem(ctx.GetContext(), index, redisMembers...).Result()
+				resumeData := &cd.DispatcherResumeData{
+					Message: &cd.DispatcherRawMessage{
+						Type: awaitOption.Type,
+					},
+					Sequence:    awaitOption.Sequence,
+					PrivateData: nil,
+				}
+				if redisError != nil {
+					ctx.GetApp().GetLogger(2).LogError("SortedSetZRem Recv Error", "TableName", tableName, "Seq", awaitOption.Sequence, "redisError", redisError)
+					resumeData.Result = cd.CreateRpcResultError(redisError, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+					resumeError := cd.ResumeTaskAction(ctx, currentAction, resumeData)
+					if resumeError != nil {
+						ctx.LogError("zrem failed resume error", "TableName", tableName, "err", resumeError)
+						return resumeError
+					}
+					return redisError
+				}
+				ctx.GetApp().GetLogger(2).LogDebug("SortedSetZRem Recv Success", "TableName", tableName, "Seq", awaitOption.Sequence, "removed", cmdResult)
+				resumeData.PrivateData = &innerPrivateData{Removed: cmdResult}
+				resumeData.Result = cd.CreateRpcResultOk()
+				resumeError := cd.ResumeTaskAction(ctx, currentAction, resumeData)
+				if resumeError != nil {
+					ctx.LogError("zrem failed resume error", "TableName", tableName, "err", resumeError)
+					return resumeError
+				}
+				return nil
+			}, nil, nil)
+			if err != nil {
+				return cd.CreateRpcResultError(err, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+			}
+			return cd.CreateRpcResultOk()
+		},
+	}
+	var resumeData *cd.DispatcherResumeData
+	resumeData, retResult = cd.YieldTaskAction(ctx, currentAction, awaitOption, hooks)
+	if retResult.IsError() {
+		return
+	}
+	if resumeData.Result.IsError() {
+		retResult = resumeData.Result
+		return
+	}
+	privateData, ok := resumeData.PrivateData.(*innerPrivateData)
+	if !ok {
+		ctx.LogError("zrem PrivateData failed not innerPrivateData")
+		retResult = cd.CreateRpcResultError(fmt.Errorf("private data not innerPrivateData"), public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+		return
+	}
+	removed = privateData.Removed
+	return
+}
+
 // ============================================================================
 // ZRange 相关结构与接口
 // ============================================================================
@@ -0,0 +1,742 @@
+// Copyright 2026 atframework
+// @brief 本地存储引擎，在进程内模拟 Redis 的 Hash/SortedSet 语义，供非 Redis 存储后端使用
+
+package atframework_component_db_storage
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrWrongType 对 Hash 执行 SortedSet 操作或反之
+	ErrWrongType = errors.New("operation against a key holding the wrong kind of value")
+	// ErrNotInteger 对非整数字段执行 HIncrBy
+	ErrNotInteger = errors.New("hash value is not an integer")
+	// ErrInvalidArgs 脚本参数不合法
+	ErrInvalidArgs = errors.New("invalid arguments")
+)
+
+// ExpireCondition 过期时间设置条件，和 Redis EXPIRE 的 NX/XX/GT/LT 一致
+type ExpireCondition int32
+
+const (
+	ExpireConditionNone ExpireCondition = 0
+	ExpireConditionNX   ExpireCondition = 1
+	ExpireConditionXX   ExpireCondition = 2
+	ExpireConditionGT   ExpireCondition = 3
+	ExpireConditionLT   ExpireCondition = 4
+)
+
+// ZAddExistence ZADD 的 NX/XX 选项
+type ZAddExistence int32
+
+const (
+	ZAddExistenceNone ZAddExistence = 0
+	ZAddExistenceNX   ZAddExistence = 1
+	ZAddExistenceXX   ZAddExistence = 2
+)
+
+// ZAddComparison ZADD 的 GT/LT 选项
+type ZAddComparison int32
+
+const (
+	ZAddComparisonNone ZAddComparison = 0
+	ZAddComparisonGT   ZAddComparison = 1
+	ZAddComparisonLT   ZAddComparison = 2
+)
+
+// ZMember 有序集合成员
+type ZMember struct {
+	Member string
+	Score  float64
+}
+
+// ScoreBound 分数范围边界
+type ScoreBound struct {
+	Score   float64
+	Exclude bool
+}
+
+// ListIndexField KL 表中记录自增下标的字段，和 Redis 的 list add 脚本保持一致
+const ListIndexField = "index_number"
+
+type record struct {
+	hash     map[string]string
+	zset     map[string]float64
+	expireAt int64 // UnixNano，0 表示不过期
+}
+
+func (r *record) empty() bool {
+	return len(r.hash) == 0 && len(r.zset) == 0
+}
+
+// Engine 本地存储引擎
+// 所有接口都是同步的并且线程安全，journal 不为空时每次修改都会追加写入磁盘
+type Engine struct {
+	mu      sync.Mutex
+	records map[string]*record
+	journal *fileJournal
+	now     func() time.Time
+}
+
+// CreateMemoryEngine 创建纯内存存储引擎，进程退出后数据丢失
+func CreateMemoryEngine() *Engine {
+	return &Engine{
+		records: make(map[string]*record),
+		now:     time.Now,
+	}
+}
+
+// SetTimeSource 替换过期判定使用的时间源，主要用于测试
+func (e *Engine) SetTimeSource(now func() time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if now == nil {
+		now = time.Now
+	}
+	e.now = now
+}
+
+// Close 关闭引擎，文件存储会刷新并关闭日志文件
+func (e *Engine) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.journal == nil {
+		return nil
+	}
+	err := e.journal.close()
+	e.journal = nil
+	return err
+}
+
+// ============================================================================
+// 内部工具
+// ============================================================================
+
+func (e *Engine) getRecord(key string) *record {
+	r, ok := e.records[key]
+	if !ok {
+		return nil
+	}
+	if r.expireAt > 0 && r.expireAt <= e.now().UnixNano() {
+		delete(e.records, key)
+		e.persist(key, nil)
+		return nil
+	}
+	return r
+}
+
+func (e *Engine) getHash(key string, create bool) (*record, error) {
+	r := e.getRecord(key)
+	if r != nil && r.zset != nil {
+		return nil, ErrWrongType
+	}
+	if r == nil && create {
+		r = &record{hash: make(map[string]string)}
+		e.records[key] = r
+	}
+	return r, nil
+}
+
+func (e *Engine) getZSet(key string, create bool) (*record, error) {
+	r := e.getRecord(key)
+	if r != nil && r.hash != nil {
+		return nil, ErrWrongType
+	}
+	if r == nil && create {
+		r = &record{zset: make(map[string]float64)}
+		e.records[key] = r
+	}
+	return r, nil
+}
+
+// commit 写回修改，空记录会被删除(和 Redis 一致)
+func (e *Engine) commit(key string, r *record) {
+	if r == nil || r.empty() {
+		delete(e.records, key)
+		e.persist(key, nil)
+		return
+	}
+	e.persist(key, r)
+}
+
+func (e *Engine) persist(key string, r *record) {
+	if e.journal == nil {
+		return
+	}
+	e.journal.append(key, r, e.records)
+}
+
+// ============================================================================
+// Hash
+// ============================================================================
+
+// HGetAll 获取整个 Hash，不存在时返回空 map
+func (e *Engine) HGetAll(key string) (map[string]string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	r, err := e.getHash(key, false)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[string]string)
+	if r == nil {
+		return ret, nil
+	}
+	for k, v := range r.hash {
+		ret[k] = v
+	}
+	return ret, nil
+}
+
+// HMGet 获取多个字段，返回值和 go-redis 一致: 字段存在为 string，不存在为 nil
+func (e *Engine) HMGet(key string, fields ...string) ([]interface{}, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	r, err := e.getHash(key, false)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]interface{}, len(fields))
+	if r == nil {
+		return ret, nil
+	}
+	for i, field := range fields {
+		if v, ok := r.hash[field]; ok {
+			ret[i] = v
+		}
+	}
+	return ret, nil
+}
+
+// HSet 设置多个字段，参数格式和 go-redis HSet 一致，返回新增字段数
+func (e *Engine) HSet(key string, values ...interface{}) (int64, error) {
+	args := FlattenArgs(values...)
+	if len(args) == 0 || len(args)%2 != 0 {
+		return 0, ErrInvalidArgs
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	r, err := e.getHash(key, true)
+	if err != nil {
+		return 0, err
+	}
+	added := hashSetPairs(r, args)
+	e.commit(key, r)
+	return added, nil
+}
+
+func hashSetPairs(r *record, args []string) int64 {
+	var added int64
+	for i := 0; i+1 < len(args); i += 2 {
+		if _, ok := r.hash[args[i]]; !ok {
+			added++
+		}
+		r.hash[args[i]] = args[i+1]
+	}
+	return added
+}
+
+// HDel 删除多个字段，返回删除的字段数
+func (e *Engine) HDel(key string, fields ...string) (int64, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	r, err := e.getHash(key, false)
+	if err != nil || r == nil {
+		return 0, err
+	}
+	var removed int64
+	for _, field := range fields {
+		if _, ok := r.hash[field]; ok {
+			delete(r.hash, field)
+			removed++
+		}
+	}
+	if removed > 0 {
+		e.commit(key, r)
+	}
+	return removed, nil
+}
+
+// HIncrBy 字段原子自增，不存在时从0开始
+func (e *Engine) HIncrBy(key string, field string, incr int64) (int64, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	r, err := e.getHash(key, true)
+	if err != nil {
+		return 0, err
+	}
+	var current int64
+	if v, ok := r.hash[field]; ok {
+		current, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			e.commit(key, r)
+			return 0, ErrNotInteger
+		}
+	}
+	current += incr
+	r.hash[field] = strconv.FormatInt(current, 10)
+	e.commit(key, r)
+	return current, nil
+}
+
+// EvalCAS 和 RedisMessageDispatcher 的 CAS 脚本语义一致
+// args[0] 为版本号字段名，args[1] 为期望版本号(-1 表示强制写入)，之后为字段和值
+// 版本号匹配或者记录不存在时写入并返回新版本号，否则不写入并返回当前版本号
+func (e *Engine) EvalCAS(key string, values ...interface{}) (uint64, error) {
+	args := FlattenArgs(values...)
+	if len(args) < 2 || len(args)%2 != 0 {
+		return 0, ErrInvalidArgs
+	}
+	expectVersion, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return 0, ErrInvalidArgs
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	r, err := e.getHash(key, false)
+	if err != nil {
+		return 0, err
+	}
+	var realVersion uint64
+	if r != nil {
+		if v, ok := r.hash[args[0]]; ok {
+			realVersion, _ = strconv.ParseUint(v, 10, 64)
+		}
+	}
+
+	if realVersion != 0 && expectVersion != -1 && uint64(expectVersion) != realVersion {
+		return realVersion, nil
+	}
+
+	if r == nil {
+		r = &record{hash: make(map[string]string)}
+		e.records[key] = r
+	}
+	args[1] = strconv.FormatUint(realVersion+1, 10)
+	hashSetPairs(r, args)
+	e.commit(key, r)
+	return realVersion + 1, nil
+}
+
+// EvalListAdd 和 RedisMessageDispatcher 的 list add 脚本语义一致
+// args[0] 为最大长度，args[1] 为数据，超过最大长度时删除下标最小的一条
+func (e *Engine) EvalListAdd(key string, values ...interface{}) (uint64, error) {
+	args := FlattenArgs(values...)
+	if len(args) != 2 {
+		return 0, ErrInvalidArgs
+	}
+	maxLen, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return 0, ErrInvalidArgs
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	r, err := e.getHash(key, true)
+	if err != nil {
+		return 0, err
+	}
+
+	if int64(len(r.hash)) >= maxLen+1 {
+		minIndex := int64(-1)
+		for field := range r.hash {
+			if field == ListIndexField {
+				continue
+			}
+			num, perr := strconv.ParseInt(field, 10, 64)
+			if perr != nil {
+				continue
+			}
+			if minIndex < 0 || num < minIndex {
+				minIndex = num
+			}
+		}
+		if minIndex >= 0 {
+			delete(r.hash, strconv.FormatInt(minIndex, 10))
+		}
+	}
+
+	var newIndex uint64
+	if v, ok := r.hash[ListIndexField]; ok {
+		newIndex, err = strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			e.commit(key, r)
+			return 0, ErrNotInteger
+		}
+	}
+	newIndex++
+	r.hash[ListIndexField] = strconv.FormatUint(newIndex, 10)
+	r.hash[strconv.FormatUint(newIndex, 10)] = args[1]
+	e.commit(key, r)
+	return newIndex, nil
+}
+
+// ============================================================================
+// 通用
+// ============================================================================
+
+// Del 删除 key，返回是否存在
+func (e *Engine) Del(key string) int64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.getRecord(key) == nil {
+		return 0
+	}
+	delete(e.records, key)
+	e.persist(key, nil)
+	return 1
+}
+
+// Expire 设置相对过期时间，expiration <= 0 时直接删除
+func (e *Engine) Expire(key string, expiration time.Duration, condition ExpireCondition) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.expireAt(key, e.now().Add(expiration), condition)
+}
+
+// ExpireAt 设置绝对过期时间点
+func (e *Engine) ExpireAt(key string, expireAt time.Time) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.expireAt(key, expireAt, ExpireConditionNone)
+}
+
+func (e *Engine) expireAt(key string, expireAt time.Time, condition ExpireCondition) bool {
+	r := e.getRecord(key)
+	if r == nil {
+		return false
+	}
+
+	newExpire := expireAt.UnixNano()
+	// 没有过期时间的 key 在 GT/LT 比较时视为无限大
+	switch condition {
+	case ExpireConditionNX:
+		if r.expireAt != 0 {
+			return false
+		}
+	case ExpireConditionXX:
+		if r.expireAt == 0 {
+			return false
+		}
+	case ExpireConditionGT:
+		if r.expireAt == 0 || newExpire <= r.expireAt {
+			return false
+		}
+	case ExpireConditionLT:
+		if r.expireAt != 0 && newExpire >= r.expireAt {
+			return false
+		}
+	}
+
+	if newExpire <= e.now().UnixNano() {
+		delete(e.records, key)
+		e.persist(key, nil)
+		return true
+	}
+	r.expireAt = newExpire
+	e.persist(key, r)
+	return true
+}
+
+// ============================================================================
+// SortedSet
+// ============================================================================
+
+// ZAdd 添加或更新成员，返回新增成员数
+func (e *Engine) ZAdd(key string, existence ZAddExistence, comparison ZAddComparison, members ...ZMember) (int64, error) {
+	if existence == ZAddExistenceNX && comparison != ZAddComparisonNone {
+		return 0, ErrInvalidArgs
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	r, err := e.getZSet(key, true)
+	if err != nil {
+		return 0, err
+	}
+
+	var added int64
+	for _, m := range members {
+		if math.IsNaN(m.Score) {
+			e.commit(key, r)
+			return added, ErrInvalidArgs
+		}
+		current, exists := r.zset[m.Member]
+		if exists {
+			if existence == ZAddExistenceNX {
+				continue
+			}
+			if comparison == ZAddComparisonGT && m.Score <= current {
+				continue
+			}
+			if comparison == ZAddComparisonLT && m.Score >= current {
+				continue
+			}
+		} else {
+			if existence == ZAddExistenceXX {
+				continue
+			}
+			added++
+		}
+		r.zset[m.Member] = m.Score
+	}
+	e.commit(key, r)
+	return added, nil
+}
+
+// ZAddIncr ZADD INCR，返回新分数
+func (e *Engine) ZAddIncr(key string, member ZMember) (float64, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	r, err := e.getZSet(key, true)
+	if err != nil {
+		return 0, err
+	}
+	newScore := r.zset[member.Member] + member.Score
+	if math.IsNaN(newScore) {
+		e.commit(key, r)
+		return 0, ErrInvalidArgs
+	}
+	r.zset[member.Member] = newScore
+	e.commit(key, r)
+	return newScore, nil
+}
+
+// ZRem 删除成员，返回删除的成员数
+func (e *Engine) ZRem(key string, members ...string) (int64, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	r, err := e.getZSet(key, false)
+	if err != nil || r == nil {
+		return 0, err
+	}
+	var removed int64
+	for _, member := range members {
+		if _, ok := r.zset[member]; ok {
+			delete(r.zset, member)
+			removed++
+		}
+	}
+	if removed > 0 {
+		e.commit(key, r)
+	}
+	return removed, nil
+}
+
+func sortedMembers(r *record, rev bool) []ZMember {
+	if r == nil {
+		return nil
+	}
+	ret := make([]ZMember, 0, len(r.zset))
+	for member, score := range r.zset {
+		ret = append(ret, ZMember{Member: member, Score: score})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Score != ret[j].Score {
+			return ret[i].Score < ret[j].Score
+		}
+		return ret[i].Member < ret[j].Member
+	})
+	if rev {
+		for i, j := 0, len(ret)-1; i < j; i, j = i+1, j-1 {
+			ret[i], ret[j] = ret[j], ret[i]
+		}
+	}
+	return ret
+}
+
+// ZRangeByRank 按排名查询，start/stop 支持负数
+func (e *Engine) ZRangeByRank(key string, start int64, stop int64, rev bool) ([]ZMember, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	r, err := e.getZSet(key, false)
+	if err != nil {
+		return nil, err
+	}
+	members := sortedMembers(r, rev)
+	size := int64(len(members))
+	if start < 0 {
+		start += size
+	}
+	if stop < 0 {
+		stop += size
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= size {
+		stop = size - 1
+	}
+	if start > stop || start >= size {
+		return []ZMember{}, nil
+	}
+	return append([]ZMember{}, members[start:stop+1]...), nil
+}
+
+func (b ScoreBound) lessOrEqual(score float64) bool {
+	if b.Exclude {
+		return b.Score < score
+	}
+	return b.Score <= score
+}
+
+func (b ScoreBound) greaterOrEqual(score float64) bool {
+	if b.Exclude {
+		return b.Score > score
+	}
+	return b.Score >= score
+}
+
+// ZRangeByScore 按分数查询，min/max 不随 rev 交换
+// offset 和 count 都为0时不限制数量，count 小于0表示返回 offset 之后的全部
+func (e *Engine) ZRangeByScore(key string, min ScoreBound, max ScoreBound, offset int64, count int64, rev bool) ([]ZMember, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	r, err := e.getZSet(key, false)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := []ZMember{}
+	limit := offset != 0 || count != 0
+	if limit && offset < 0 {
+		return ret, nil
+	}
+	var skipped int64
+	for _, m := range sortedMembers(r, rev) {
+		if !min.lessOrEqual(m.Score) || !max.greaterOrEqual(m.Score) {
+			continue
+		}
+		if limit {
+			if skipped < offset {
+				skipped++
+				continue
+			}
+			if count >= 0 && int64(len(ret)) >= count {
+				break
+			}
+		}
+		ret = append(ret, m)
+	}
+	return ret, nil
+}
+
+// ZRank 获取成员排名和分数
+func (e *Engine) ZRank(key string, member string, rev bool) (int64, float64, bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	r, err := e.getZSet(key, false)
+	if err != nil || r == nil {
+		return 0, 0, false, err
+	}
+	if _, ok := r.zset[member]; !ok {
+		return 0, 0, false, nil
+	}
+	for i, m := range sortedMembers(r, rev) {
+		if m.Member == member {
+			return int64(i), m.Score, true, nil
+		}
+	}
+	return 0, 0, false, nil
+}
+
+// ============================================================================
+// 参数转换
+// ============================================================================
+
+// FlattenArgs 把 go-redis 风格的参数展开为字符串列表，规则和 go-redis 的参数编码一致
+func FlattenArgs(values ...interface{}) []string {
+	ret := make([]string, 0, len(values))
+	for _, v := range values {
+		switch val := v.(type) {
+		case []interface{}:
+			ret = append(ret, FlattenArgs(val...)...)
+		case []string:
+			ret = append(ret, val...)
+		case map[string]interface{}:
+			for k, item := range val {
+				ret = append(ret, k, formatArg(item))
+			}
+		case map[string]string:
+			for k, item := range val {
+				ret = append(ret, k, item)
+			}
+		default:
+			ret = append(ret, formatArg(v))
+		}
+	}
+	return ret
+}
+
+func formatArg(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case []byte:
+		return string(val)
+	case int:
+		return strconv.FormatInt(int64(val), 10)
+	case int8:
+		return strconv.FormatInt(int64(val), 10)
+	case int16:
+		return strconv.FormatInt(int64(val), 10)
+	case int32:
+		return strconv.FormatInt(int64(val), 10)
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case uint:
+		return strconv.FormatUint(uint64(val), 10)
+	case uint8:
+		return strconv.FormatUint(uint64(val), 10)
+	case uint16:
+		return strconv.FormatUint(uint64(val), 10)
+	case uint32:
+		return strconv.FormatUint(uint64(val), 10)
+	case uint64:
+		return strconv.FormatUint(val, 10)
+	case float32:
+		return strconv.FormatFloat(float64(val), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case bool:
+		if val {
+			return "1"
+		}
+		return "0"
+	case time.Time:
+		return val.Format(time.RFC3339Nano)
+	case time.Duration:
+		return strconv.FormatInt(val.Nanoseconds(), 10)
+	case fmt.Stringer:
+		return val.String()
+	default:
+		return fmt.Sprint(val)
+	}
+}
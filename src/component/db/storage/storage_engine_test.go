@@ -0,0 +1,171 @@
+package atframework_component_db_storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestEngineEvalCAS 测试CAS写入的版本号语义
+// Scenario: 新记录写入版本号为1，旧版本号写入失败并返回当前版本号，-1强制写入
+func TestEngineEvalCAS(t *testing.T) {
+	// Arrange
+	e := CreateMemoryEngine()
+
+	// Act & Assert
+	version, err := e.EvalCAS("user", []interface{}{"_cas", uint64(0), "name", "a"})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), version)
+
+	version, err = e.EvalCAS("user", []interface{}{"_cas", uint64(1), "name", "b"})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), version)
+
+	version, err = e.EvalCAS("user", []interface{}{"_cas", uint64(1), "name", "c"})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), version)
+
+	version, err = e.EvalCAS("user", []interface{}{"_cas", int64(-1), "name", "d"})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), version)
+
+	data, err := e.HGetAll("user")
+	assert.NoError(t, err)
+	assert.Equal(t, "d", data["name"])
+	assert.Equal(t, "3", data["_cas"])
+}
+
+// TestEngineListAddMaxLength 测试KL表追加时的最大长度淘汰
+// Scenario: 最大长度为2时追加3条，最早的一条被删除，下标持续递增
+func TestEngineListAddMaxLength(t *testing.T) {
+	// Arrange
+	e := CreateMemoryEngine()
+
+	// Act
+	for i := 0; i < 3; i++ {
+		index, err := e.EvalListAdd("mail", uint32(2), []byte{byte('a' + i)})
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(i+1), index)
+	}
+
+	// Assert
+	values, err := e.HMGet("mail", "1", "2", "3")
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{nil, "b", "c"}, values)
+}
+
+// TestEngineSortedSet 测试有序集合的添加、排名和分数范围查询
+// Scenario: GT 只提高分数，排名按分数和成员排序，rev 逆序
+func TestEngineSortedSet(t *testing.T) {
+	// Arrange
+	e := CreateMemoryEngine()
+	_, err := e.ZAdd("rank", ZAddExistenceNone, ZAddComparisonNone,
+		ZMember{Member: "a", Score: 10}, ZMember{Member: "b", Score: 20}, ZMember{Member: "c", Score: 20})
+	assert.NoError(t, err)
+
+	// Act
+	_, err = e.ZAdd("rank", ZAddExistenceNone, ZAddComparisonGT,
+		ZMember{Member: "a", Score: 5}, ZMember{Member: "b", Score: 30})
+	assert.NoError(t, err)
+
+	// Assert
+	members, err := e.ZRangeByRank("rank", 0, -1, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []ZMember{{"b", 30}, {"c", 20}, {"a", 10}}, members)
+
+	rank, score, found, err := e.ZRank("rank", "c", false)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, int64(1), rank)
+	assert.Equal(t, float64(20), score)
+
+	members, err = e.ZRangeByScore("rank", ScoreBound{Score: 10, Exclude: true}, ScoreBound{Score: 30}, 0, 1, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []ZMember{{"c", 20}}, members)
+}
+
+// TestEngineSortedSetZRem 测试删除有序集合成员
+// Scenario: 只统计实际删除的成员，删除全部成员后 key 也被删除
+func TestEngineSortedSetZRem(t *testing.T) {
+	// Arrange
+	e := CreateMemoryEngine()
+	_, err := e.ZAdd("queue", ZAddExistenceNone, ZAddComparisonNone,
+		ZMember{Member: "a", Score: 1}, ZMember{Member: "b", Score: 2})
+	assert.NoError(t, err)
+
+	// Act
+	removed, err := e.ZRem("queue", "a", "not_exists")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+	_, _, found, err := e.ZRank("queue", "a", false)
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	removed, err = e.ZRem("queue", "b")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+	members, err := e.ZRangeByRank("queue", 0, -1, false)
+	assert.NoError(t, err)
+	assert.Empty(t, members)
+}
+
+// TestEngineExpire 测试过期时间和 NX/GT 条件
+// Scenario: NX 只在没有过期时间时生效，GT 对无过期时间的 key 不生效，到期后 key 被删除
+func TestEngineExpire(t *testing.T) {
+	// Arrange
+	now := time.Unix(1000, 0)
+	e := CreateMemoryEngine()
+	e.SetTimeSource(func() time.Time { return now })
+	_, err := e.HSet("session", "id", 1)
+	assert.NoError(t, err)
+
+	// Act & Assert
+	assert.False(t, e.Expire("session", time.Minute, ExpireConditionGT))
+	assert.True(t, e.Expire("session", time.Minute, ExpireConditionNX))
+	assert.False(t, e.Expire("session", 2*time.Minute, ExpireConditionNX))
+
+	now = now.Add(2 * time.Minute)
+	data, err := e.HGetAll("session")
+	assert.NoError(t, err)
+	assert.Empty(t, data)
+}
+
+// TestFileEngineReopen 测试磁盘存储重启后恢复数据
+// Scenario: 写入 Hash 和有序集合并删除一个 key，重新打开后数据和删除都被保留
+func TestFileEngineReopen(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	e, err := OpenFileEngine(dir)
+	assert.NoError(t, err)
+	_, err = e.EvalCAS("user", "_cas", 0, "data", string([]byte{0, 1, 0xff}))
+	assert.NoError(t, err)
+	_, err = e.ZAddIncr("rank", ZMember{Member: "a", Score: 1.5})
+	assert.NoError(t, err)
+	_, err = e.HSet("tmp", "k", "v")
+	assert.NoError(t, err)
+	e.Del("tmp")
+	assert.NoError(t, e.Close())
+
+	// Act
+	e, err = OpenFileEngine(dir)
+	assert.NoError(t, err)
+	defer e.Close()
+
+	// Assert
+	data, err := e.HGetAll("user")
+	assert.NoError(t, err)
+	assert.Equal(t, string([]byte{0, 1, 0xff}), data["data"])
+	assert.Equal(t, "1", data["_cas"])
+
+	_, score, found, err := e.ZRank("rank", "a", false)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, 1.5, score)
+
+	data, err = e.HGetAll("tmp")
+	assert.NoError(t, err)
+	assert.Empty(t, data)
+}
@@ -0,0 +1,268 @@
+// Copyright 2026 atframework
+// @brief 本地存储引擎的磁盘日志，每次修改追加记录 key 的完整状态，启动时回放并压缩
+
+package atframework_component_db_storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+const (
+	journalFileName = "storage.journal"
+	// 日志条目超过存活 key 数的倍数后压缩
+	journalCompactRatio = 4
+	// 日志条目少于该值时不压缩
+	journalCompactMinEntries = 4096
+)
+
+type journalEntry struct {
+	Key      string            `json:"k"`
+	Deleted  bool              `json:"d,omitempty"`
+	Hash     map[string][]byte `json:"h,omitempty"`
+	ZSet     map[string]string `json:"z,omitempty"`
+	ExpireAt int64             `json:"e,omitempty"`
+}
+
+type fileJournal struct {
+	path    string
+	file    *os.File
+	writer  *bufio.Writer
+	entries int
+	err     error
+}
+
+// OpenFileEngine 打开磁盘存储引擎，数据保存在 dir 目录下
+// 数据全部常驻内存，磁盘只用于重启恢复，适合开发、测试和单机部署
+// 每次修改都会 fsync，写入吞吐受磁盘同步延迟限制
+func OpenFileEngine(dir string) (*Engine, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	e := CreateMemoryEngine()
+	path := filepath.Join(dir, journalFileName)
+	if err := replayJournal(path, e.records); err != nil {
+		return nil, err
+	}
+
+	now := e.now().UnixNano()
+	for key, r := range e.records {
+		if r.expireAt > 0 && r.expireAt <= now {
+			delete(e.records, key)
+		}
+	}
+
+	e.journal = &fileJournal{path: path}
+	if err := e.journal.compact(e.records); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// JournalError 返回磁盘日志写入的第一个错误，内存存储始终返回 nil
+func (e *Engine) JournalError() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.journal == nil {
+		return nil
+	}
+	return e.journal.err
+}
+
+func replayJournal(path string, records map[string]*record) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	for {
+		line, rerr := reader.ReadBytes('\n')
+		if len(line) > 0 && line[len(line)-1] == '\n' {
+			var entry journalEntry
+			// 最后一条可能因为进程崩溃写了一半，解析失败时直接丢弃
+			if jerr := json.Unmarshal(line, &entry); jerr == nil {
+				applyJournalEntry(records, &entry)
+			}
+		}
+		if rerr == io.EOF {
+			return nil
+		}
+		if rerr != nil {
+			return rerr
+		}
+	}
+}
+
+func applyJournalEntry(records map[string]*record, entry *journalEntry) {
+	if entry.Deleted {
+		delete(records, entry.Key)
+		return
+	}
+
+	r := &record{expireAt: entry.ExpireAt}
+	if entry.Hash != nil {
+		r.hash = make(map[string]string, len(entry.Hash))
+		for k, v := range entry.Hash {
+			r.hash[k] = string(v)
+		}
+	} else {
+		r.zset = make(map[string]float64, len(entry.ZSet))
+		for k, v := range entry.ZSet {
+			score, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			r.zset[k] = score
+		}
+	}
+	records[entry.Key] = r
+}
+
+func makeJournalEntry(key string, r *record) *journalEntry {
+	entry := &journalEntry{Key: key}
+	if r == nil {
+		entry.Deleted = true
+		return entry
+	}
+
+	entry.ExpireAt = r.expireAt
+	if r.hash != nil {
+		entry.Hash = make(map[string][]byte, len(r.hash))
+		for k, v := range r.hash {
+			entry.Hash[k] = []byte(v)
+		}
+	} else {
+		entry.ZSet = make(map[string]string, len(r.zset))
+		for k, v := range r.zset {
+			entry.ZSet[k] = strconv.FormatFloat(v, 'g', -1, 64)
+		}
+	}
+	return entry
+}
+
+func (j *fileJournal) writeEntry(writer *bufio.Writer, entry *journalEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err = writer.Write(data); err != nil {
+		return err
+	}
+	return writer.WriteByte('\n')
+}
+
+func (j *fileJournal) append(key string, r *record, records map[string]*record) {
+	if j.err != nil || j.writer == nil {
+		return
+	}
+
+	if err := j.writeEntry(j.writer, makeJournalEntry(key, r)); err != nil {
+		j.err = err
+		return
+	}
+	if err := j.writer.Flush(); err != nil {
+		j.err = err
+		return
+	}
+	// 每次修改都落盘，返回成功后进程或机器崩溃都不会丢失这次修改
+	if err := j.file.Sync(); err != nil {
+		j.err = err
+		return
+	}
+	j.entries++
+
+	if j.entries > journalCompactMinEntries && j.entries > journalCompactRatio*len(records) {
+		j.err = j.compact(records)
+	}
+}
+
+// compact 把存活数据写入临时文件后替换日志文件
+func (j *fileJournal) compact(records map[string]*record) error {
+	if j.file != nil {
+		j.writer.Flush()
+		j.file.Close()
+		j.file = nil
+		j.writer = nil
+	}
+
+	tmpPath := j.path + ".tmp"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(tmp)
+	for key, r := range records {
+		if err = j.writeEntry(writer, makeJournalEntry(key, r)); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err = writer.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmpPath, j.path); err != nil {
+		return err
+	}
+	if err = syncJournalDir(filepath.Dir(j.path)); err != nil {
+		return err
+	}
+
+	j.file, err = os.OpenFile(j.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	j.writer = bufio.NewWriter(j.file)
+	j.entries = len(records)
+	return nil
+}
+
+func (j *fileJournal) close() error {
+	if j.file == nil {
+		return j.err
+	}
+	err := j.writer.Flush()
+	if err == nil {
+		err = j.file.Sync()
+	}
+	if cerr := j.file.Close(); err == nil {
+		err = cerr
+	}
+	j.file = nil
+	j.writer = nil
+	if err == nil {
+		err = j.err
+	}
+	return err
+}
+
+// syncJournalDir 替换日志文件后同步目录项，保证重启后能看到新文件
+func syncJournalDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if cerr := d.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
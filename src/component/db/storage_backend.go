@@ -0,0 +1,509 @@
+package atframework_component_db
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	pu "github.com/atframework/atframe-utils-go/proto_utility"
+	config "github.com/atframework/atsf4g-go/component/config"
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	private_protocol_config "github.com/atframework/atsf4g-go/component/protocol/private/config/protocol/config"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	"github.com/atframework/libatapp-go"
+	"google.golang.org/protobuf/proto"
+)
+
+// StorageBackendRedis 默认存储后端名称
+const StorageBackendRedis = "redis"
+
+// StorageBackend 数据库存储后端，生成的 DatabaseTable* 接口都通过它访问数据
+// 所有后端都需要保证和 Redis 实现一致的 CAS、KL 表 max_list_length 以及有序集合语义
+type StorageBackend interface {
+	Name() string
+	GetRecordPrefix() string
+	Close() error
+
+	// KV 表
+	HashTableLoad(ctx cd.AwaitableContext, index string, tableName string,
+		messageCreate func() proto.Message) (proto.Message, uint64, cd.RpcResult)
+	HashTableBatchLoad(ctx cd.AwaitableContext, index []string, tableName string,
+		messageCreate func() proto.Message) ([]*RedisSetIndexMessage, cd.RpcResult)
+	HashTablePartlyGet(ctx cd.AwaitableContext, index string, tableName string,
+		messageCreate func() proto.Message, partlyGetField []string) (proto.Message, uint64, cd.RpcResult)
+	HashTableBatchPartlyGet(ctx cd.AwaitableContext, index []string, tableName string,
+		messageCreate func() proto.Message, partlyGetField []string) ([]*RedisSetIndexMessage, cd.RpcResult)
+	HashTableUpdate(ctx cd.AwaitableContext, index string, tableName string, table proto.Message) cd.RpcResult
+	HashTableUpdateCAS(ctx cd.AwaitableContext, index string, tableName string,
+		table proto.Message, currentCASVersion *uint64, forceUpdate bool) cd.RpcResult
+	HashTableAtomicInc(ctx cd.AwaitableContext, index string, tableName string,
+		incField string, incValue uint64) (uint64, cd.RpcResult)
+
+	// KL 表
+	HashTableLoadListAll(ctx cd.AwaitableContext, index string, tableName string,
+		messageCreate func() proto.Message) ([]pu.RedisListIndexMessage, cd.RpcResult)
+	HashTableLoadListIndex(ctx cd.AwaitableContext, index string, tableName string,
+		messageCreate func() proto.Message, listIndex []uint64) ([]pu.RedisListIndexMessage, cd.RpcResult)
+	HashTableDelListIndex(ctx cd.AwaitableContext, index string, tableName string, listIndex []uint64) cd.RpcResult
+	HashTableUpdateList(ctx cd.AwaitableContext, index string, tableName string,
+		table proto.Message, listIndex uint64) cd.RpcResult
+	HashTableAddList(ctx cd.AwaitableContext, index string, tableName string,
+		table proto.Message, maxListLen uint32) (cd.RpcResult, uint64)
+
+	// 通用
+	TableDel(ctx cd.AwaitableContext, index string, tableName string) cd.RpcResult
+	TableExpire(ctx cd.AwaitableContext, index string, tableName string,
+		expiration time.Duration, condition ExpireConditionOption) (bool, cd.RpcResult)
+	TableExpireAt(ctx cd.AwaitableContext, index string, tableName string, expireAt time.Time) (bool, cd.RpcResult)
+
+	// 有序集合
+	SortedSetZAdd(ctx cd.AwaitableContext, index string, tableName string,
+		members []SortedSetMember, existence ZAddExistenceOption, comparison ZAddComparisonOption) cd.RpcResult
+	SortedSetZAddIncr(ctx cd.AwaitableContext, index string, tableName string,
+		member SortedSetMember) (ZAddIncrResult, cd.RpcResult)
+	SortedSetZRem(ctx cd.AwaitableContext, index string, tableName string,
+		members []string) (int64, cd.RpcResult)
+	SortedSetZRangeByRank(ctx cd.AwaitableContext, index string, tableName string,
+		start int64, stop int64, rev bool) ([]SortedSetRangeMember, cd.RpcResult)
+	SortedSetZRangeByScore(ctx cd.AwaitableContext, index string, tableName string,
+		min SortedSetScoreBound, max SortedSetScoreBound, offset int64, count int64, rev bool) ([]SortedSetRangeMember, cd.RpcResult)
+	SortedSetZRank(ctx cd.AwaitableContext, index string, tableName string,
+		member string, rev bool) (SortedSetZRankResult, bool, cd.RpcResult)
+
+	// 发布订阅，Redis 后端跨进程广播，本地后端只在进程内广播
+	// Publish 会阻塞当前线程，返回收到消息的订阅数
+	Publish(channel string, payload []byte) (int64, error)
+	Subscribe(channels []string, handler StorageMessageHandler) (StorageSubscription, error)
+}
+
+// StorageMessageHandler 订阅消息回调，不在主线程调用，访问业务数据前需要 PushAction 回到主线程
+type StorageMessageHandler func(channel string, payload []byte)
+
+// StorageSubscription 订阅句柄，Close 返回后不会再收到回调
+type StorageSubscription interface {
+	Close() error
+}
+
+// StorageBackendCreator 根据配置创建存储后端
+type StorageBackendCreator func(app libatapp.AppImpl, cfg *private_protocol_config.Readonly_LogicDbCfg) (StorageBackend, error)
+
+var (
+	storageBackendCreatorLock sync.RWMutex
+	storageBackendCreators    = map[string]StorageBackendCreator{}
+)
+
+func init() {
+	var _ libatapp.AppModuleImpl = (*StorageBackendModule)(nil)
+	var _ StorageBackend = (*redisStorageBackend)(nil)
+
+	RegisterStorageBackendCreator(StorageBackendRedis, func(app libatapp.AppImpl, _ *private_protocol_config.Readonly_LogicDbCfg) (StorageBackend, error) {
+		dispatcher := libatapp.AtappGetModule[*cd.RedisMessageDispatcher](app)
+		if dispatcher == nil {
+			return nil, fmt.Errorf("RedisMessageDispatcher not found")
+		}
+		return &redisStorageBackend{dispatcher: dispatcher}, nil
+	})
+}
+
+// RegisterStorageBackendCreator 注册存储后端，同名覆盖
+func RegisterStorageBackendCreator(name string, creator StorageBackendCreator) {
+	storageBackendCreatorLock.Lock()
+	defer storageBackendCreatorLock.Unlock()
+
+	storageBackendCreators[name] = creator
+}
+
+func getStorageBackendCreator(name string) StorageBackendCreator {
+	storageBackendCreatorLock.RLock()
+	defer storageBackendCreatorLock.RUnlock()
+
+	return storageBackendCreators[name]
+}
+
+// StorageBackendModule 根据 logic.db.backend 配置创建存储后端
+// 使用 redis 后端时需要在它之前注册 RedisMessageDispatcher
+type StorageBackendModule struct {
+	libatapp.AppModuleBase
+
+	backend StorageBackend
+}
+
+// CreateStorageBackendModule 创建存储后端模块，后端在 Init 时根据配置创建
+func CreateStorageBackendModule(owner libatapp.AppImpl) *StorageBackendModule {
+	return &StorageBackendModule{
+		AppModuleBase: libatapp.CreateAppModuleBase(owner),
+	}
+}
+
+// CreateStorageBackendModuleWithBackend 使用指定的存储后端创建模块，不读取配置，主要用于测试
+func CreateStorageBackendModuleWithBackend(owner libatapp.AppImpl, backend StorageBackend) *StorageBackendModule {
+	return &StorageBackendModule{
+		AppModuleBase: libatapp.CreateAppModuleBase(owner),
+		backend:       backend,
+	}
+}
+
+func (m *StorageBackendModule) Name() string {
+	return "StorageBackendModule"
+}
+
+func (m *StorageBackendModule) Init(parent context.Context) error {
+	if m.backend != nil {
+		return nil
+	}
+
+	dbCfg := config.GetConfigManager().GetCurrentConfigGroup().GetSectionConfig().GetDb()
+	backendName := dbCfg.GetBackend()
+	if backendName == "" {
+		backendName = StorageBackendRedis
+	}
+
+	creator := getStorageBackendCreator(backendName)
+	if creator == nil {
+		m.GetApp().GetDefaultLogger().LogError("db storage backend not found", "backend", backendName)
+		return fmt.Errorf("db storage backend %s not found", backendName)
+	}
+
+	backend, err := creator(m.GetApp(), dbCfg)
+	if err != nil {
+		m.GetApp().GetDefaultLogger().LogError("create db storage backend failed", "backend", backendName, "error", err)
+		return err
+	}
+
+	m.backend = backend
+	m.GetApp().GetDefaultLogger().LogInfo("db storage backend created", "backend", backend.Name(), "prefix", backend.GetRecordPrefix())
+	return nil
+}
+
+func (m *StorageBackendModule) Cleanup() {
+	if m.backend == nil {
+		return
+	}
+
+	if err := m.backend.Close(); err != nil {
+		m.GetApp().GetDefaultLogger().LogError("close db storage backend failed", "backend", m.backend.Name(), "error", err)
+	}
+	m.backend = nil
+}
+
+func (m *StorageBackendModule) GetBackend() StorageBackend {
+	if m == nil {
+		return nil
+	}
+	return m.backend
+}
+
+// GetStorageBackend 获取当前存储后端
+// 没有注册 StorageBackendModule 时兼容直接使用 RedisMessageDispatcher
+func GetStorageBackend(app libatapp.AppImpl) StorageBackend {
+	if module := libatapp.AtappGetModule[*StorageBackendModule](app); module != nil && module.GetBackend() != nil {
+		return module.GetBackend()
+	}
+
+	dispatcher := libatapp.AtappGetModule[*cd.RedisMessageDispatcher](app)
+	if dispatcher == nil || dispatcher.GetRedisInstance() == nil {
+		return nil
+	}
+	return &redisStorageBackend{dispatcher: dispatcher}
+}
+
+// GetStorageRecordPrefix 获取当前存储后端的 key 前缀
+func GetStorageRecordPrefix(app libatapp.AppImpl) string {
+	backend := GetStorageBackend(app)
+	if backend == nil {
+		return "default"
+	}
+	return backend.GetRecordPrefix()
+}
+
+// ============================================================================
+// Redis 后端
+// ============================================================================
+
+type redisStorageBackend struct {
+	dispatcher *cd.RedisMessageDispatcher
+}
+
+func (b *redisStorageBackend) Name() string {
+	return StorageBackendRedis
+}
+
+func (b *redisStorageBackend) GetRecordPrefix() string {
+	return b.dispatcher.GetRecordPrefix()
+}
+
+func (b *redisStorageBackend) Close() error {
+	// redis 连接由 RedisMessageDispatcher 管理
+	return nil
+}
+
+func (b *redisStorageBackend) getInstance(ctx cd.AwaitableContext) (cd.RedisClientWrapper, cd.RpcResult) {
+	instance := b.dispatcher.GetRedisInstance()
+	if instance == nil {
+		ctx.LogError("get redis instance failed")
+		return nil, cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+	}
+	return instance, cd.CreateRpcResultOk()
+}
+
+// getReadInstance 只读操作使用，开启从节点读取时可能读到稍旧的数据，CAS 等写操作必须走 getInstance
+func (b *redisStorageBackend) getReadInstance(ctx cd.AwaitableContext, tableName string) (cd.RedisClientWrapper, cd.RpcResult) {
+	instance := b.dispatcher.GetRedisReadInstance(tableName)
+	if instance == nil {
+		ctx.LogError("get redis read instance failed")
+		return nil, cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+	}
+	return instance, cd.CreateRpcResultOk()
+}
+
+func (b *redisStorageBackend) HashTableLoad(ctx cd.AwaitableContext, index string, tableName string,
+	messageCreate func() proto.Message,
+) (proto.Message, uint64, cd.RpcResult) {
+	instance, result := b.getReadInstance(ctx, tableName)
+	if result.IsError() {
+		return nil, 0, result
+	}
+	return HashTableLoad(ctx, index, tableName, b.dispatcher, instance, messageCreate)
+}
+
+func (b *redisStorageBackend) HashTableBatchLoad(ctx cd.AwaitableContext, index []string, tableName string,
+	messageCreate func() proto.Message,
+) ([]*RedisSetIndexMessage, cd.RpcResult) {
+	instance, result := b.getReadInstance(ctx, tableName)
+	if result.IsError() {
+		return nil, result
+	}
+	return HashTableBatchLoad(ctx, index, tableName, b.dispatcher, instance, messageCreate)
+}
+
+func (b *redisStorageBackend) HashTablePartlyGet(ctx cd.AwaitableContext, index string, tableName string,
+	messageCreate func() proto.Message, partlyGetField []string,
+) (proto.Message, uint64, cd.RpcResult) {
+	instance, result := b.getReadInstance(ctx, tableName)
+	if result.IsError() {
+		return nil, 0, result
+	}
+	return HashTablePartlyGet(ctx, index, tableName, b.dispatcher, instance, messageCreate, partlyGetField)
+}
+
+func (b *redisStorageBackend) HashTableBatchPartlyGet(ctx cd.AwaitableContext, index []string, tableName string,
+	messageCreate func() proto.Message, partlyGetField []string,
+) ([]*RedisSetIndexMessage, cd.RpcResult) {
+	instance, result := b.getReadInstance(ctx, tableName)
+	if result.IsError() {
+		return nil, result
+	}
+	return HashTableBatchPartlyGet(ctx, index, tableName, b.dispatcher, instance, messageCreate, partlyGetField)
+}
+
+func (b *redisStorageBackend) HashTableUpdate(ctx cd.AwaitableContext, index string, tableName string,
+	table proto.Message,
+) cd.RpcResult {
+	instance, result := b.getInstance(ctx)
+	if result.IsError() {
+		return result
+	}
+	return HashTableUpdate(ctx, index, tableName, b.dispatcher, instance, table)
+}
+
+func (b *redisStorageBackend) HashTableUpdateCAS(ctx cd.AwaitableContext, index string, tableName string,
+	table proto.Message, currentCASVersion *uint64, forceUpdate bool,
+) cd.RpcResult {
+	instance, result := b.getInstance(ctx)
+	if result.IsError() {
+		return result
+	}
+	return HashTableUpdateCAS(ctx, index, tableName, b.dispatcher, instance, table, currentCASVersion, forceUpdate)
+}
+
+func (b *redisStorageBackend) HashTableAtomicInc(ctx cd.AwaitableContext, index string, tableName string,
+	incField string, incValue uint64,
+) (uint64, cd.RpcResult) {
+	instance, result := b.getInstance(ctx)
+	if result.IsError() {
+		return 0, result
+	}
+	return HashTableAtomicInc(ctx, index, tableName, b.dispatcher, instance, incField, incValue)
+}
+
+func (b *redisStorageBackend) HashTableLoadListAll(ctx cd.AwaitableContext, index string, tableName string,
+	messageCreate func() proto.Message,
+) ([]pu.RedisListIndexMessage, cd.RpcResult) {
+	instance, result := b.getInstance(ctx)
+	if result.IsError() {
+		return nil, result
+	}
+	return HashTableLoadListAll(ctx, index, tableName, b.dispatcher, instance, messageCreate)
+}
+
+func (b *redisStorageBackend) HashTableLoadListIndex(ctx cd.AwaitableContext, index string, tableName string,
+	messageCreate func() proto.Message, listIndex []uint64,
+) ([]pu.RedisListIndexMessage, cd.RpcResult) {
+	instance, result := b.getInstance(ctx)
+	if result.IsError() {
+		return nil, result
+	}
+	return HashTableLoadListIndex(ctx, index, tableName, b.dispatcher, instance, messageCreate, listIndex)
+}
+
+func (b *redisStorageBackend) HashTableDelListIndex(ctx cd.AwaitableContext, index string, tableName string,
+	listIndex []uint64,
+) cd.RpcResult {
+	instance, result := b.getInstance(ctx)
+	if result.IsError() {
+		return result
+	}
+	return HashTableDelListIndex(ctx, index, tableName, b.dispatcher, instance, listIndex)
+}
+
+func (b *redisStorageBackend) HashTableUpdateList(ctx cd.AwaitableContext, index string, tableName string,
+	table proto.Message, listIndex uint64,
+) cd.RpcResult {
+	instance, result := b.getInstance(ctx)
+	if result.IsError() {
+		return result
+	}
+	return HashTableUpdateList(ctx, index, tableName, b.dispatcher, instance, table, listIndex)
+}
+
+func (b *redisStorageBackend) HashTableAddList(ctx cd.AwaitableContext, index string, tableName string,
+	table proto.Message, maxListLen uint32,
+) (cd.RpcResult, uint64) {
+	instance, result := b.getInstance(ctx)
+	if result.IsError() {
+		return result, 0
+	}
+	return HashTableAddList(ctx, index, tableName, b.dispatcher, instance, table, maxListLen)
+}
+
+func (b *redisStorageBackend) TableDel(ctx cd.AwaitableContext, index string, tableName string) cd.RpcResult {
+	instance, result := b.getInstance(ctx)
+	if result.IsError() {
+		return result
+	}
+	return TableDel(ctx, index, tableName, b.dispatcher, instance)
+}
+
+func (b *redisStorageBackend) TableExpire(ctx cd.AwaitableContext, index string, tableName string,
+	expiration time.Duration, condition ExpireConditionOption,
+) (bool, cd.RpcResult) {
+	instance, result := b.getInstance(ctx)
+	if result.IsError() {
+		return false, result
+	}
+	return TableExpire(ctx, index, tableName, b.dispatcher, instance, expiration, condition)
+}
+
+func (b *redisStorageBackend) TableExpireAt(ctx cd.AwaitableContext, index string, tableName string,
+	expireAt time.Time,
+) (bool, cd.RpcResult) {
+	instance, result := b.getInstance(ctx)
+	if result.IsError() {
+		return false, result
+	}
+	return TableExpireAt(ctx, index, tableName, b.dispatcher, instance, expireAt)
+}
+
+func (b *redisStorageBackend) SortedSetZAdd(ctx cd.AwaitableContext, index string, tableName string,
+	members []SortedSetMember, existence ZAddExistenceOption, comparison ZAddComparisonOption,
+) cd.RpcResult {
+	instance, result := b.getInstance(ctx)
+	if result.IsError() {
+		return result
+	}
+	return SortedSetZAdd(ctx, index, tableName, b.dispatcher, instance, members, existence, comparison)
+}
+
+func (b *redisStorageBackend) SortedSetZAddIncr(ctx cd.AwaitableContext, index string, tableName string,
+	member SortedSetMember,
+) (ZAddIncrResult, cd.RpcResult) {
+	instance, result := b.getInstance(ctx)
+	if result.IsError() {
+		return ZAddIncrResult{}, result
+	}
+	return SortedSetZAddIncr(ctx, index, tableName, b.dispatcher, instance, member)
+}
+
+func (b *redisStorageBackend) SortedSetZRem(ctx cd.AwaitableContext, index string, tableName string,
+	members []string,
+) (int64, cd.RpcResult) {
+	instance, result := b.getInstance(ctx)
+	if result.IsError() {
+		return 0, result
+	}
+	return SortedSetZRem(ctx, index, tableName, b.dispatcher, instance, members)
+}
+
+func (b *redisStorageBackend) SortedSetZRangeByRank(ctx cd.AwaitableContext, index string, tableName string,
+	start int64, stop int64, rev bool,
+) ([]SortedSetRangeMember, cd.RpcResult) {
+	instance, result := b.getReadInstance(ctx, tableName)
+	if result.IsError() {
+		return nil, result
+	}
+	return SortedSetZRangeByRank(ctx, index, tableName, b.dispatcher, instance, start, stop, rev)
+}
+
+func (b *redisStorageBackend) SortedSetZRangeByScore(ctx cd.AwaitableContext, index string, tableName string,
+	min SortedSetScoreBound, max SortedSetScoreBound, offset int64, count int64, rev bool,
+) ([]SortedSetRangeMember, cd.RpcResult) {
+	instance, result := b.getReadInstance(ctx, tableName)
+	if result.IsError() {
+		return nil, result
+	}
+	return SortedSetZRangeByScore(ctx, index, tableName, b.dispatcher, instance, min, max, offset, count, rev)
+}
+
+func (b *redisStorageBackend) SortedSetZRank(ctx cd.AwaitableContext, index string, tableName string,
+	member string, rev bool,
+) (SortedSetZRankResult, bool, cd.RpcResult) {
+	instance, result := b.getInstance(ctx)
+	if result.IsError() {
+		return SortedSetZRankResult{}, false, result
+	}
+	return SortedSetZRank(ctx, index, tableName, b.dispatcher, instance, member, rev)
+}
+
+func (b *redisStorageBackend) Publish(channel string, payload []byte) (int64, error) {
+	instance := b.dispatcher.GetRedisInstance()
+	if instance == nil {
+		return 0, fmt.Errorf("redis instance not found")
+	}
+	return instance.Publish(context.Background(), channel, payload).Result()
+}
+
+func (b *redisStorageBackend) Subscribe(channels []string, handler StorageMessageHandler) (StorageSubscription, error) {
+	instance := b.dispatcher.GetRedisInstance()
+	if instance == nil {
+		return nil, fmt.Errorf("redis instance not found")
+	}
+
+	ret := &redisStorageSubscription{
+		pubsub: instance.Subscribe(context.Background(), channels...),
+	}
+	ret.waitGroup.Add(1)
+	go ret.receive(handler)
+	return ret, nil
+}
+
+type redisStorageSubscription struct {
+	pubsub    *redis.PubSub
+	waitGroup sync.WaitGroup
+}
+
+func (s *redisStorageSubscription) receive(handler StorageMessageHandler) {
+	defer s.waitGroup.Done()
+
+	for msg := range s.pubsub.Channel() {
+		handler(msg.Channel, []byte(msg.Payload))
+	}
+}
+
+func (s *redisStorageSubscription) Close() error {
+	err := s.pubsub.Close()
+	s.waitGroup.Wait()
+	return err
+}
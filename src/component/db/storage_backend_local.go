@@ -0,0 +1,447 @@
+package atframework_component_db
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	pu "github.com/atframework/atframe-utils-go/proto_utility"
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	private_protocol_config "github.com/atframework/atsf4g-go/component/protocol/private/config/protocol/config"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	"github.com/atframework/libatapp-go"
+	"google.golang.org/protobuf/proto"
+
+	storage "github.com/atframework/atsf4g-go/component/db/storage"
+)
+
+const (
+	// StorageBackendMemory 纯内存存储，进程退出后数据丢失
+	StorageBackendMemory = "memory"
+	// StorageBackendFile 本地磁盘存储，数据常驻内存并追加写日志
+	StorageBackendFile = "file"
+)
+
+func init() {
+	var _ StorageBackend = (*localStorageBackend)(nil)
+
+	RegisterStorageBackendCreator(StorageBackendMemory, func(_ libatapp.AppImpl, cfg *private_protocol_config.Readonly_LogicDbCfg) (StorageBackend, error) {
+		return CreateMemoryStorageBackend(cfg.GetRecordPrefix()), nil
+	})
+
+	RegisterStorageBackendCreator(StorageBackendFile, func(_ libatapp.AppImpl, cfg *private_protocol_config.Readonly_LogicDbCfg) (StorageBackend, error) {
+		if cfg.GetPath() == "" {
+			return nil, fmt.Errorf("db.path is required by file storage backend")
+		}
+		engine, err := storage.OpenFileEngine(cfg.GetPath())
+		if err != nil {
+			return nil, err
+		}
+		return createLocalStorageBackend(StorageBackendFile, cfg.GetRecordPrefix(), engine), nil
+	})
+}
+
+// localStorageBackend 基于本地存储引擎的后端
+// 和 Redis 后端共用 proto 和 Hash 的转换规则，所有操作同步完成，不会切出当前任务
+type localStorageBackend struct {
+	name         string
+	recordPrefix string
+	engine       *storage.Engine
+
+	// 进程内的发布订阅
+	subscriptionLock sync.RWMutex
+	subscriptions    map[string]map[*localStorageSubscription]struct{}
+}
+
+// CreateMemoryStorageBackend 创建纯内存存储后端，主要用于单元测试和本地开发
+func CreateMemoryStorageBackend(recordPrefix string) StorageBackend {
+	return createLocalStorageBackend(StorageBackendMemory, recordPrefix, storage.CreateMemoryEngine())
+}
+
+func createLocalStorageBackend(name string, recordPrefix string, engine *storage.Engine) *localStorageBackend {
+	if recordPrefix == "" {
+		recordPrefix = "default"
+	}
+	return &localStorageBackend{
+		name:          name,
+		recordPrefix:  recordPrefix,
+		engine:        engine,
+		subscriptions: make(map[string]map[*localStorageSubscription]struct{}),
+	}
+}
+
+func (b *localStorageBackend) Name() string {
+	return b.name
+}
+
+func (b *localStorageBackend) GetRecordPrefix() string {
+	return b.recordPrefix
+}
+
+func (b *localStorageBackend) Close() error {
+	return b.engine.Close()
+}
+
+func (b *localStorageBackend) makeError(ctx cd.AwaitableContext, tableName string, op string, err error) cd.RpcResult {
+	ctx.LogError("local storage operation failed", "Backend", b.name, "TableName", tableName, "Operation", op, "err", err)
+	return cd.CreateRpcResultError(err, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+}
+
+// checkWrite 检查磁盘日志是否写入成功
+func (b *localStorageBackend) checkWrite(ctx cd.AwaitableContext, tableName string, op string) cd.RpcResult {
+	if err := b.engine.JournalError(); err != nil {
+		return b.makeError(ctx, tableName, op, err)
+	}
+	return cd.CreateRpcResultOk()
+}
+
+func (b *localStorageBackend) HashTableLoad(ctx cd.AwaitableContext, index string, tableName string,
+	messageCreate func() proto.Message,
+) (proto.Message, uint64, cd.RpcResult) {
+	result, err := b.engine.HGetAll(index)
+	if err != nil {
+		return nil, 0, b.makeError(ctx, tableName, "HGetAll", err)
+	}
+	if len(result) == 0 {
+		ctx.LogInfo("HashTableLoad Record Not Found", "Backend", b.name, "TableName", tableName)
+		return nil, 0, cd.CreateRpcResultError(fmt.Errorf("record not found"), public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND)
+	}
+	table := messageCreate()
+	casVersion, err := pu.RedisKVMapToPB(result, table)
+	if err != nil {
+		ctx.LogError("HashTableLoad Parse Failed", "Backend", b.name, "TableName", tableName, "err", err)
+		return nil, 0, cd.CreateRpcResultError(err, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM_BAD_PACKAGE)
+	}
+	return table, casVersion, cd.CreateRpcResultOk()
+}
+
+func (b *localStorageBackend) HashTableBatchLoad(ctx cd.AwaitableContext, index []string, tableName string,
+	messageCreate func() proto.Message,
+) ([]*RedisSetIndexMessage, cd.RpcResult) {
+	setMessage := make([]*RedisSetIndexMessage, len(index))
+	for i, idx := range index {
+		message, casVersion, result := b.HashTableLoad(ctx, idx, tableName, messageCreate)
+		setMessage[i] = &RedisSetIndexMessage{
+			Result:     result,
+			Message:    message,
+			CASVersion: casVersion,
+		}
+	}
+	return setMessage, cd.CreateRpcResultOk()
+}
+
+func (b *localStorageBackend) HashTablePartlyGet(ctx cd.AwaitableContext, index string, tableName string,
+	messageCreate func() proto.Message, partlyGetField []string,
+) (proto.Message, uint64, cd.RpcResult) {
+	result, err := b.engine.HMGet(index, partlyGetField...)
+	if err != nil {
+		return nil, 0, b.makeError(ctx, tableName, "HMGet", err)
+	}
+	table := messageCreate()
+	casVersion, recordExist, err := pu.RedisSliceKVMapToPB(partlyGetField, result, table)
+	if err != nil {
+		ctx.LogError("HashTablePartlyGet Parse Failed", "Backend", b.name, "TableName", tableName, "err", err)
+		return nil, 0, cd.CreateRpcResultError(err, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM_BAD_PACKAGE)
+	}
+	if !recordExist {
+		ctx.LogInfo("HashTablePartlyGet Record Not Found", "Backend", b.name, "TableName", tableName)
+		return nil, 0, cd.CreateRpcResultError(fmt.Errorf("record not found"), public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND)
+	}
+	return table, casVersion, cd.CreateRpcResultOk()
+}
+
+func (b *localStorageBackend) HashTableBatchPartlyGet(ctx cd.AwaitableContext, index []string, tableName string,
+	messageCreate func() proto.Message, partlyGetField []string,
+) ([]*RedisSetIndexMessage, cd.RpcResult) {
+	setMessage := make([]*RedisSetIndexMessage, len(index))
+	for i, idx := range index {
+		message, casVersion, result := b.HashTablePartlyGet(ctx, idx, tableName, messageCreate, partlyGetField)
+		setMessage[i] = &RedisSetIndexMessage{
+			Result:     result,
+			Message:    message,
+			CASVersion: casVersion,
+		}
+	}
+	return setMessage, cd.CreateRpcResultOk()
+}
+
+func (b *localStorageBackend) HashTableUpdate(ctx cd.AwaitableContext, index string, tableName string,
+	table proto.Message,
+) cd.RpcResult {
+	if _, err := b.engine.HSet(index, pu.PBMapToRedisKV(table, nil, false)); err != nil {
+		return b.makeError(ctx, tableName, "HSet", err)
+	}
+	return b.checkWrite(ctx, tableName, "HSet")
+}
+
+func (b *localStorageBackend) HashTableUpdateCAS(ctx cd.AwaitableContext, index string, tableName string,
+	table proto.Message, currentCASVersion *uint64, forceUpdate bool,
+) cd.RpcResult {
+	if forceUpdate {
+		*currentCASVersion = 0
+	}
+	oldCASVersion := *currentCASVersion
+	realVersion, err := b.engine.EvalCAS(index, pu.PBMapToRedisKV(table, &oldCASVersion, forceUpdate))
+	if err != nil {
+		return b.makeError(ctx, tableName, "EvalCAS", err)
+	}
+	if result := b.checkWrite(ctx, tableName, "EvalCAS"); result.IsError() {
+		return result
+	}
+
+	*currentCASVersion = realVersion
+	if !forceUpdate && oldCASVersion+1 != realVersion {
+		ctx.LogInfo("HashTableUpdateCAS CAS Check Failed", "Backend", b.name, "TableName", tableName,
+			"currentCASVersion+1", oldCASVersion+1, "RealCASVersion", realVersion)
+		return cd.CreateRpcResultError(fmt.Errorf("cas check failed"), public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_CAS_CHECK_FAILED)
+	}
+	return cd.CreateRpcResultOk()
+}
+
+func (b *localStorageBackend) HashTableAtomicInc(ctx cd.AwaitableContext, index string, tableName string,
+	incField string, incValue uint64,
+) (uint64, cd.RpcResult) {
+	newValue, err := b.engine.HIncrBy(index, incField, int64(incValue))
+	if err != nil {
+		return 0, b.makeError(ctx, tableName, "HIncrBy", err)
+	}
+	return uint64(newValue), b.checkWrite(ctx, tableName, "HIncrBy")
+}
+
+func (b *localStorageBackend) HashTableLoadListAll(ctx cd.AwaitableContext, index string, tableName string,
+	messageCreate func() proto.Message,
+) ([]pu.RedisListIndexMessage, cd.RpcResult) {
+	result, err := b.engine.HGetAll(index)
+	if err != nil {
+		return nil, b.makeError(ctx, tableName, "HGetAll", err)
+	}
+	if isListRecordEmpty(result) {
+		ctx.LogInfo("HashTableLoadListAll Record Not Found", "Backend", b.name, "TableName", tableName)
+		return nil, cd.CreateRpcResultError(fmt.Errorf("record not found"), public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND)
+	}
+	indexMessages, err := pu.RedisKLMapToPB(result, messageCreate)
+	if err != nil {
+		ctx.LogError("HashTableLoadListAll Parse Failed", "Backend", b.name, "TableName", tableName, "err", err)
+		return nil, cd.CreateRpcResultError(err, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM_BAD_PACKAGE)
+	}
+	return indexMessages, cd.CreateRpcResultOk()
+}
+
+func (b *localStorageBackend) HashTableLoadListIndex(ctx cd.AwaitableContext, index string, tableName string,
+	messageCreate func() proto.Message, listIndex []uint64,
+) ([]pu.RedisListIndexMessage, cd.RpcResult) {
+	indexGetField := make([]string, 0, len(listIndex))
+	sliceKey := make([]pu.RedisSliceKey, 0, len(listIndex))
+	for _, listIndexId := range listIndex {
+		indexGetField = append(indexGetField, fmt.Sprintf("%d", listIndexId))
+		sliceKey = append(sliceKey, pu.RedisSliceKey{
+			Index: listIndexId,
+		})
+	}
+
+	result, err := b.engine.HMGet(index, indexGetField...)
+	if err != nil {
+		return nil, b.makeError(ctx, tableName, "HMGet", err)
+	}
+	if isListIndexAllMissing(result) {
+		ctx.LogInfo("HashTableLoadListIndex Record Not Found", "Backend", b.name, "TableName", tableName)
+		return nil, cd.CreateRpcResultError(fmt.Errorf("record not found"), public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND)
+	}
+	indexMessages, err := pu.RedisSliceKLMapToPB(sliceKey, result, messageCreate)
+	if err != nil {
+		ctx.LogError("HashTableLoadListIndex Parse Failed", "Backend", b.name, "TableName", tableName, "err", err)
+		return nil, cd.CreateRpcResultError(err, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM_BAD_PACKAGE)
+	}
+	return indexMessages, cd.CreateRpcResultOk()
+}
+
+func (b *localStorageBackend) HashTableDelListIndex(ctx cd.AwaitableContext, index string, tableName string,
+	listIndex []uint64,
+) cd.RpcResult {
+	delField := make([]string, 0, len(listIndex))
+	for _, listIndexId := range listIndex {
+		delField = append(delField, fmt.Sprintf("%d", listIndexId))
+	}
+	if _, err := b.engine.HDel(index, delField...); err != nil {
+		return b.makeError(ctx, tableName, "HDel", err)
+	}
+	return b.checkWrite(ctx, tableName, "HDel")
+}
+
+func (b *localStorageBackend) HashTableUpdateList(ctx cd.AwaitableContext, index string, tableName string,
+	table proto.Message, listIndex uint64,
+) cd.RpcResult {
+	if _, err := b.engine.HSet(index, pu.PBMapToRedisKLUpdate(table, listIndex)); err != nil {
+		return b.makeError(ctx, tableName, "HSet", err)
+	}
+	return b.checkWrite(ctx, tableName, "HSet")
+}
+
+func (b *localStorageBackend) HashTableAddList(ctx cd.AwaitableContext, index string, tableName string,
+	table proto.Message, maxListLen uint32,
+) (cd.RpcResult, uint64) {
+	if maxListLen == 0 {
+		ctx.LogError("maxListLen is zero")
+		return cd.CreateRpcResultError(fmt.Errorf("maxListLen is zero"), public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM), 0
+	}
+
+	newIndex, err := b.engine.EvalListAdd(index, pu.PBMapToRedisKLAdd(table, maxListLen))
+	if err != nil {
+		return b.makeError(ctx, tableName, "EvalListAdd", err), 0
+	}
+	return b.checkWrite(ctx, tableName, "EvalListAdd"), newIndex
+}
+
+func (b *localStorageBackend) TableDel(ctx cd.AwaitableContext, index string, tableName string) cd.RpcResult {
+	b.engine.Del(index)
+	return b.checkWrite(ctx, tableName, "Del")
+}
+
+func (b *localStorageBackend) TableExpire(ctx cd.AwaitableContext, index string, tableName string,
+	expiration time.Duration, condition ExpireConditionOption,
+) (bool, cd.RpcResult) {
+	success := b.engine.Expire(index, expiration, storage.ExpireCondition(condition))
+	return success, b.checkWrite(ctx, tableName, "Expire")
+}
+
+func (b *localStorageBackend) TableExpireAt(ctx cd.AwaitableContext, index string, tableName string,
+	expireAt time.Time,
+) (bool, cd.RpcResult) {
+	success := b.engine.ExpireAt(index, expireAt)
+	return success, b.checkWrite(ctx, tableName, "ExpireAt")
+}
+
+func (b *localStorageBackend) SortedSetZAdd(ctx cd.AwaitableContext, index string, tableName string,
+	members []SortedSetMember, existence ZAddExistenceOption, comparison ZAddComparisonOption,
+) cd.RpcResult {
+	zMembers := make([]storage.ZMember, 0, len(members))
+	for _, m := range members {
+		zMembers = append(zMembers, storage.ZMember{Member: m.Member, Score: m.Score})
+	}
+	if _, err := b.engine.ZAdd(index, storage.ZAddExistence(existence), storage.ZAddComparison(comparison), zMembers...); err != nil {
+		return b.makeError(ctx, tableName, "ZAdd", err)
+	}
+	return b.checkWrite(ctx, tableName, "ZAdd")
+}
+
+func (b *localStorageBackend) SortedSetZAddIncr(ctx cd.AwaitableContext, index string, tableName string,
+	member SortedSetMember,
+) (ZAddIncrResult, cd.RpcResult) {
+	newScore, err := b.engine.ZAddIncr(index, storage.ZMember{Member: member.Member, Score: member.Score})
+	if err != nil {
+		return ZAddIncrResult{}, b.makeError(ctx, tableName, "ZAddIncr", err)
+	}
+	return ZAddIncrResult{NewScore: newScore, Exists: true}, b.checkWrite(ctx, tableName, "ZAddIncr")
+}
+
+func (b *localStorageBackend) SortedSetZRem(ctx cd.AwaitableContext, index string, tableName string,
+	members []string,
+) (int64, cd.RpcResult) {
+	removed, err := b.engine.ZRem(index, members...)
+	if err != nil {
+		return 0, b.makeError(ctx, tableName, "ZRem", err)
+	}
+	return removed, b.checkWrite(ctx, tableName, "ZRem")
+}
+
+func convertStorageRangeMembers(members []storage.ZMember) []SortedSetRangeMember {
+	ret := make([]SortedSetRangeMember, 0, len(members))
+	for _, m := range members {
+		ret = append(ret, SortedSetRangeMember{Member: m.Member, Score: m.Score})
+	}
+	return ret
+}
+
+func convertStorageScoreBound(bound SortedSetScoreBound) storage.ScoreBound {
+	switch bound.Type {
+	case ScoreBoundNegativeInfinity:
+		return storage.ScoreBound{Score: math.Inf(-1)}
+	case ScoreBoundPositiveInfinity:
+		return storage.ScoreBound{Score: math.Inf(1)}
+	}
+	return storage.ScoreBound{Score: bound.Score, Exclude: bound.Exclude}
+}
+
+func (b *localStorageBackend) SortedSetZRangeByRank(ctx cd.AwaitableContext, index string, tableName string,
+	start int64, stop int64, rev bool,
+) ([]SortedSetRangeMember, cd.RpcResult) {
+	members, err := b.engine.ZRangeByRank(index, start, stop, rev)
+	if err != nil {
+		return nil, b.makeError(ctx, tableName, "ZRangeByRank", err)
+	}
+	return convertStorageRangeMembers(members), cd.CreateRpcResultOk()
+}
+
+func (b *localStorageBackend) SortedSetZRangeByScore(ctx cd.AwaitableContext, index string, tableName string,
+	min SortedSetScoreBound, max SortedSetScoreBound, offset int64, count int64, rev bool,
+) ([]SortedSetRangeMember, cd.RpcResult) {
+	members, err := b.engine.ZRangeByScore(index, convertStorageScoreBound(min), convertStorageScoreBound(max), offset, count, rev)
+	if err != nil {
+		return nil, b.makeError(ctx, tableName, "ZRangeByScore", err)
+	}
+	return convertStorageRangeMembers(members), cd.CreateRpcResultOk()
+}
+
+func (b *localStorageBackend) SortedSetZRank(ctx cd.AwaitableContext, index string, tableName string,
+	member string, rev bool,
+) (SortedSetZRankResult, bool, cd.RpcResult) {
+	rank, score, found, err := b.engine.ZRank(index, member, rev)
+	if err != nil {
+		return SortedSetZRankResult{}, false, b.makeError(ctx, tableName, "ZRank", err)
+	}
+	return SortedSetZRankResult{Rank: rank, Score: score}, found, cd.CreateRpcResultOk()
+}
+
+// Publish 同步调用本进程内的订阅回调，返回收到消息的订阅数
+func (b *localStorageBackend) Publish(channel string, payload []byte) (int64, error) {
+	b.subscriptionLock.RLock()
+	receivers := make([]*localStorageSubscription, 0, len(b.subscriptions[channel]))
+	for subscription := range b.subscriptions[channel] {
+		receivers = append(receivers, subscription)
+	}
+	b.subscriptionLock.RUnlock()
+
+	for _, subscription := range receivers {
+		subscription.handler(channel, payload)
+	}
+	return int64(len(receivers)), nil
+}
+
+func (b *localStorageBackend) Subscribe(channels []string, handler StorageMessageHandler) (StorageSubscription, error) {
+	ret := &localStorageSubscription{
+		backend:  b,
+		channels: channels,
+		handler:  handler,
+	}
+
+	b.subscriptionLock.Lock()
+	defer b.subscriptionLock.Unlock()
+	for _, channel := range channels {
+		subscriptions, ok := b.subscriptions[channel]
+		if !ok {
+			subscriptions = make(map[*localStorageSubscription]struct{})
+			b.subscriptions[channel] = subscriptions
+		}
+		subscriptions[ret] = struct{}{}
+	}
+	return ret, nil
+}
+
+type localStorageSubscription struct {
+	backend  *localStorageBackend
+	channels []string
+	handler  StorageMessageHandler
+}
+
+func (s *localStorageSubscription) Close() error {
+	s.backend.subscriptionLock.Lock()
+	defer s.backend.subscriptionLock.Unlock()
+	for _, channel := range s.channels {
+		delete(s.backend.subscriptions[channel], s)
+		if len(s.backend.subscriptions[channel]) == 0 {
+			delete(s.backend.subscriptions, channel)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package atframework_component_db_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+
+	db "github.com/atframework/atsf4g-go/component/db"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	test_utility "github.com/atframework/atsf4g-go/component/test_utility"
+)
+
+func createTestListMessage() proto.Message {
+	return &private_protocol_pbdesc.DatabaseTableChatOfflineMessage{}
+}
+
+// TestLocalStorageListNotFound 测试本地后端 KL 表记录不存在的返回值
+// Scenario: 从未写入、数据全部删除后以及请求的下标都不存在时返回 EN_ERR_DB_RECORD_NOT_FOUND
+func TestLocalStorageListNotFound(t *testing.T) {
+	// Arrange
+	backend := db.CreateMemoryStorageBackend("test")
+	ctx := test_utility.NewMockRpcContext(nil)
+	notFound := int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND)
+
+	// Act & Assert
+	_, result := backend.HashTableLoadListAll(ctx, "list", "chat", createTestListMessage)
+	assert.Equal(t, notFound, result.GetResponseCode())
+
+	result, listIndex := backend.HashTableAddList(ctx, "list", "chat", &private_protocol_pbdesc.DatabaseTableChatOfflineMessage{UserId: 1}, 10)
+	assert.False(t, result.IsError())
+
+	messages, result := backend.HashTableLoadListIndex(ctx, "list", "chat", createTestListMessage, []uint64{listIndex, listIndex + 1})
+	assert.False(t, result.IsError())
+	assert.Len(t, messages, 1)
+
+	_, result = backend.HashTableLoadListIndex(ctx, "list", "chat", createTestListMessage, []uint64{listIndex + 1})
+	assert.Equal(t, notFound, result.GetResponseCode())
+
+	result = backend.HashTableDelListIndex(ctx, "list", "chat", []uint64{listIndex})
+	assert.False(t, result.IsError())
+	_, result = backend.HashTableLoadListAll(ctx, "list", "chat", createTestListMessage)
+	assert.Equal(t, notFound, result.GetResponseCode())
+}
+
+// TestLocalStoragePubSub 测试本地后端的进程内发布订阅
+// Scenario: 只有订阅了对应频道的回调收到消息，Close 后不再收到，返回值为收到的订阅数
+func TestLocalStoragePubSub(t *testing.T) {
+	// Arrange
+	backend := db.CreateMemoryStorageBackend("test")
+	var lock sync.Mutex
+	received := map[string][]string{}
+	subscribe := func(name string, channels ...string) db.StorageSubscription {
+		subscription, err := backend.Subscribe(channels, func(channel string, payload []byte) {
+			lock.Lock()
+			defer lock.Unlock()
+			received[name] = append(received[name], channel+":"+string(payload))
+		})
+		assert.NoError(t, err)
+		return subscription
+	}
+	first := subscribe("first", "a", "b")
+	subscribe("second", "b")
+
+	// Act
+	receiversA, errA := backend.Publish("a", []byte("1"))
+	receiversB, errB := backend.Publish("b", []byte("2"))
+	first.Close()
+	receiversAfterClose, _ := backend.Publish("b", []byte("3"))
+	receiversNone, _ := backend.Publish("c", []byte("4"))
+
+	// Assert
+	assert.NoError(t, errA)
+	assert.NoError(t, errB)
+	assert.Equal(t, int64(1), receiversA)
+	assert.Equal(t, int64(2), receiversB)
+	assert.Equal(t, int64(1), receiversAfterClose)
+	assert.Equal(t, int64(0), receiversNone)
+	assert.Equal(t, []string{"a:1", "b:2"}, received["first"])
+	assert.Equal(t, []string{"b:2", "b:3"}, received["second"])
+}
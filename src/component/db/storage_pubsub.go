@@ -0,0 +1,107 @@
+package atframework_component_db
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/atframework/libatapp-go"
+)
+
+// PubSubMessageHandler 订阅消息回调，已经通过 PushAction 回到主线程，可以直接访问业务数据
+type PubSubMessageHandler func(channel string, payload []byte)
+
+// PubSubPublishCallback 发送完成回调，和发送一样在 PushAction 中执行
+type PubSubPublishCallback func(receivers int64, err error)
+
+// PubSubChannel 基于存储后端发布订阅的跨进程转发，负责订阅、回到主线程分发和异步发送
+// 频道名统一通过 GetPubSubChannelName 加上存储后端的 record_prefix 前缀
+type PubSubChannel struct {
+	app          libatapp.AppImpl
+	owner        string
+	subscription StorageSubscription
+}
+
+// GetPubSubChannelName 生成带 record_prefix 前缀的频道名，parts 用 "-" 连接
+func GetPubSubChannelName(app libatapp.AppImpl, parts ...string) string {
+	return fmt.Sprintf("%s-%s", GetStorageRecordPrefix(app), strings.Join(parts, "-"))
+}
+
+// GetPubSubServerChannelName 生成只有指定进程订阅的频道名
+func GetPubSubServerChannelName(app libatapp.AppImpl, channel string, serverId uint64) string {
+	return GetPubSubChannelName(app, channel, "server", fmt.Sprintf("%d", serverId))
+}
+
+// SubscribePubSubChannel 订阅频道，owner 用于日志，需要在 StorageBackendModule 之后调用
+func SubscribePubSubChannel(app libatapp.AppImpl, owner string, channels []string, handler PubSubMessageHandler) (*PubSubChannel, error) {
+	backend := GetStorageBackend(app)
+	if backend == nil {
+		app.GetDefaultLogger().LogError("need StorageBackendModule to subscribe channel", "owner", owner)
+		return nil, fmt.Errorf("storage backend not found")
+	}
+
+	ret := &PubSubChannel{
+		app:   app,
+		owner: owner,
+	}
+	subscription, err := backend.Subscribe(channels, func(channel string, payload []byte) {
+		// 回到主线程处理，和任务访问业务数据的线程保持一致
+		app.PushAction(func(app_action *libatapp.AppActionData) error {
+			handler(channel, payload)
+			return nil
+		}, nil, nil)
+	})
+	if err != nil {
+		app.GetDefaultLogger().LogError("subscribe channel failed", "owner", owner, "channels", channels, "error", err)
+		return nil, err
+	}
+	ret.subscription = subscription
+
+	app.GetDefaultLogger().LogInfo("subscribe channel", "owner", owner, "channels", channels)
+	return ret, nil
+}
+
+// IsSubscribed 没有订阅时不向其他进程发送，对方也无法回复
+func (c *PubSubChannel) IsSubscribed() bool {
+	return c != nil && c.subscription != nil
+}
+
+// Close 取消订阅，返回后不会再收到回调
+func (c *PubSubChannel) Close() {
+	if c == nil || c.subscription == nil {
+		return
+	}
+	c.subscription.Close()
+	c.subscription = nil
+}
+
+// Publish 在 PushAction 中发送，不阻塞当前任务，onPublished 可以为 nil
+// 返回的错误只表示没能提交发送，发送结果通过 onPublished 获取
+func (c *PubSubChannel) Publish(channel string, payload []byte, onPublished PubSubPublishCallback) error {
+	if !c.IsSubscribed() {
+		return fmt.Errorf("channel not subscribed")
+	}
+	return PublishPubSubChannel(c.app, c.owner, channel, payload, onPublished)
+}
+
+// PublishPubSubChannel 不需要订阅的单向通知，规则和 PubSubChannel.Publish 一致
+func PublishPubSubChannel(app libatapp.AppImpl, owner string, channel string, payload []byte, onPublished PubSubPublishCallback) error {
+	if channel == "" {
+		return fmt.Errorf("channel is empty")
+	}
+
+	backend := GetStorageBackend(app)
+	if backend == nil {
+		return fmt.Errorf("storage backend not found")
+	}
+
+	return app.PushAction(func(app_action *libatapp.AppActionData) error {
+		receivers, err := backend.Publish(channel, payload)
+		if err != nil {
+			app.GetLogger(2).LogWarn("publish channel failed", "owner", owner, "channel", channel, "error", err)
+		}
+		if onPublished != nil {
+			onPublished(receivers, err)
+		}
+		return err
+	}, nil, nil)
+}
@@ -0,0 +1,312 @@
+package atframework_component_db
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+)
+
+// TableCacheOptions 共享表本地缓存配置
+type TableCacheOptions[K comparable, V any] struct {
+	// Name 缓存名称，用于日志和跨进程失效通知，需要全局唯一
+	Name string
+	// TTL 未修改的记录在本地保留的时间，0 表示不过期，只依赖失效通知
+	TTL time.Duration
+	// Load 从数据库加载一条记录和 CAS 版本，非 CAS 表返回 0，失败的结果不会缓存
+	Load func(ctx cd.AwaitableContext, key K) (V, uint64, cd.RpcResult)
+	// Save 把一条脏数据加入回写的 pipeline，为 nil 时缓存只读
+	// CAS 表使用 currentCASVersion 做版本检查，Execute 之后会更新为数据库中的版本
+	Save func(pipeline *Pipeline, key K, value V, currentCASVersion *uint64) *PipelineResult
+	// KeyString 把 key 转换成失效通知中使用的字符串，为 nil 时使用 fmt.Sprint
+	KeyString func(key K) string
+}
+
+// TableCache 共享表的本地缓存
+// 读取时同一个 key 的并发加载只会有一次 IO，写入先修改本地数据，由 TableCacheManager 定期批量回写，
+// 回写成功后通过 Redis pub/sub 通知其他进程丢弃本地数据
+// Get 返回的是缓存中的对象，修改后需要调用 Set 才会回写
+// CAS 表回写时使用最后一次加载或者回写的版本，所以 Set 之前应该先 Get，版本冲突时丢弃本地修改
+type TableCache[K comparable, V any] struct {
+	options TableCacheOptions[K, V]
+
+	mu         sync.Mutex
+	entries    map[string]*tableCacheEntry[K, V]
+	loadFlight IOSingleFlight[string]
+}
+
+type tableCacheEntry[K comparable, V any] struct {
+	key      K
+	value    V
+	expireAt time.Time
+	dirty    bool
+	// 每次 Set 递增，回写完成时版本没变才清除脏标记
+	version uint64
+	// 数据库中的 CAS 版本，非 CAS 表始终为 0
+	casVersion uint64
+}
+
+// tableCacheImpl TableCacheManager 使用的无类型接口
+type tableCacheImpl interface {
+	Name() string
+	hasDirty() bool
+	flush(ctx cd.AwaitableContext, batchCount int) (savedKeys []string, result cd.RpcResult)
+	evictExpired(now time.Time) int
+	invalidateKeys(keys []string)
+	dropDirty() []string
+}
+
+// CreateTableCache 创建共享表缓存，需要注册到 TableCacheManager 后才会回写和接收失效通知
+func CreateTableCache[K comparable, V any](options TableCacheOptions[K, V]) *TableCache[K, V] {
+	return &TableCache[K, V]{
+		options: options,
+		entries: make(map[string]*tableCacheEntry[K, V]),
+	}
+}
+
+func (c *TableCache[K, V]) Name() string {
+	return c.options.Name
+}
+
+func (c *TableCache[K, V]) keyString(key K) string {
+	if c.options.KeyString != nil {
+		return c.options.KeyString(key)
+	}
+	return fmt.Sprint(key)
+}
+
+func (c *TableCache[K, V]) lookup(keyString string, now time.Time) (value V, found bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := c.entries[keyString]
+	if entry == nil {
+		return
+	}
+	if !entry.dirty && !entry.expireAt.IsZero() && !now.Before(entry.expireAt) {
+		delete(c.entries, keyString)
+		return
+	}
+	return entry.value, true
+}
+
+// store 保存加载结果，本地有未回写的修改时以本地数据为准
+func (c *TableCache[K, V]) store(keyString string, key K, value V, casVersion uint64, now time.Time) V {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry := c.entries[keyString]; entry != nil && entry.dirty {
+		return entry.value
+	}
+	entry := &tableCacheEntry[K, V]{
+		key:        key,
+		value:      value,
+		casVersion: casVersion,
+	}
+	if c.options.TTL > 0 {
+		entry.expireAt = now.Add(c.options.TTL)
+	}
+	c.entries[keyString] = entry
+	return value
+}
+
+// Get 优先返回本地数据，未命中或过期时从数据库加载
+func (c *TableCache[K, V]) Get(ctx cd.AwaitableContext, key K) (value V, result cd.RpcResult) {
+	keyString := c.keyString(key)
+	if value, found := c.lookup(keyString, ctx.GetSysNow()); found {
+		return value, cd.CreateRpcResultOk()
+	}
+
+	var loaded V
+	shared, result := c.loadFlight.Do(ctx, keyString, func(childCtx cd.AwaitableContext) cd.RpcResult {
+		var casVersion uint64
+		var loadResult cd.RpcResult
+		loaded, casVersion, loadResult = c.options.Load(childCtx, key)
+		if loadResult.IsError() {
+			return loadResult
+		}
+		loaded = c.store(keyString, key, loaded, casVersion, childCtx.GetSysNow())
+		return cd.CreateRpcResultOk()
+	})
+	if result.IsError() {
+		return
+	}
+	if !shared {
+		return loaded, cd.CreateRpcResultOk()
+	}
+	if value, found := c.lookup(keyString, ctx.GetSysNow()); found {
+		return value, cd.CreateRpcResultOk()
+	}
+
+	// 共享的加载结果已经被淘汰，自己再加载一次
+	var casVersion uint64
+	value, casVersion, result = c.options.Load(ctx, key)
+	if result.IsError() {
+		return
+	}
+	return c.store(keyString, key, value, casVersion, ctx.GetSysNow()), cd.CreateRpcResultOk()
+}
+
+// Set 修改本地数据并标记为待回写
+func (c *TableCache[K, V]) Set(ctx cd.RpcContext, key K, value V) cd.RpcResult {
+	if c.options.Save == nil {
+		ctx.LogError("table cache is readonly", "cache", c.options.Name)
+		return cd.CreateRpcResultError(fmt.Errorf("table cache %s is readonly", c.options.Name), public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+	}
+
+	keyString := c.keyString(key)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := c.entries[keyString]
+	if entry == nil {
+		entry = &tableCacheEntry[K, V]{key: key}
+		c.entries[keyString] = entry
+	}
+	entry.value = value
+	entry.dirty = true
+	entry.version++
+	return cd.CreateRpcResultOk()
+}
+
+// Invalidate 丢弃本地数据，未回写的修改不会丢弃
+func (c *TableCache[K, V]) Invalidate(key K) {
+	c.invalidateKeys([]string{c.keyString(key)})
+}
+
+func (c *TableCache[K, V]) invalidateKeys(keys []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, keyString := range keys {
+		if entry := c.entries[keyString]; entry != nil && !entry.dirty {
+			delete(c.entries, keyString)
+		}
+	}
+}
+
+// dropDirty 丢弃所有未回写的数据，返回丢弃的 key
+func (c *TableCache[K, V]) dropDirty() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ret := make([]string, 0)
+	for keyString, entry := range c.entries {
+		if entry.dirty {
+			delete(c.entries, keyString)
+			ret = append(ret, keyString)
+		}
+	}
+	return ret
+}
+
+func (c *TableCache[K, V]) hasDirty() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, entry := range c.entries {
+		if entry.dirty {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *TableCache[K, V]) evictExpired(now time.Time) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ret := 0
+	for keyString, entry := range c.entries {
+		if !entry.dirty && !entry.expireAt.IsZero() && !now.Before(entry.expireAt) {
+			delete(c.entries, keyString)
+			ret++
+		}
+	}
+	return ret
+}
+
+// flush 把最多 batchCount 条脏数据放在一个 pipeline 中回写
+// CAS 冲突说明其他进程已经修改过，本地修改基于旧数据，直接丢弃，下次读取时重新加载
+func (c *TableCache[K, V]) flush(ctx cd.AwaitableContext, batchCount int) (savedKeys []string, result cd.RpcResult) {
+	if c.options.Save == nil {
+		return nil, cd.CreateRpcResultOk()
+	}
+
+	type flushItem struct {
+		keyString  string
+		entry      *tableCacheEntry[K, V]
+		version    uint64
+		casVersion uint64
+		result     *PipelineResult
+	}
+
+	pipeline := CreatePipeline(ctx, false)
+	items := make([]*flushItem, 0, batchCount)
+	c.mu.Lock()
+	for keyString, entry := range c.entries {
+		if !entry.dirty {
+			continue
+		}
+		if len(items) >= batchCount {
+			break
+		}
+		item := &flushItem{
+			keyString:  keyString,
+			entry:      entry,
+			version:    entry.version,
+			casVersion: entry.casVersion,
+		}
+		// Save 添加时即序列化，不会受之后修改的影响
+		item.result = c.options.Save(pipeline, entry.key, entry.value, &item.casVersion)
+		items = append(items, item)
+	}
+	c.mu.Unlock()
+	if len(items) == 0 {
+		return nil, cd.CreateRpcResultOk()
+	}
+
+	result = pipeline.Execute()
+	if result.IsError() {
+		return nil, result
+	}
+
+	now := ctx.GetSysNow()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, item := range items {
+		// 回写期间被丢弃或者重新创建的记录不再更新
+		entry := item.entry
+		if c.entries[item.keyString] != entry {
+			entry = nil
+		}
+		if item.result == nil || item.result.Result.IsError() {
+			if item.result != nil && item.result.Result.GetResponseCode() == int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_CAS_CHECK_FAILED) {
+				ctx.LogError("table cache flush cas check failed, drop local modification", "cache", c.options.Name, "key", item.keyString,
+					"cas_version", item.entry.casVersion, "real_cas_version", item.casVersion)
+				if entry != nil {
+					delete(c.entries, item.keyString)
+				}
+				continue
+			}
+			ctx.LogWarn("table cache flush failed, will retry later", "cache", c.options.Name, "key", item.keyString)
+			continue
+		}
+
+		savedKeys = append(savedKeys, item.keyString)
+		if entry == nil {
+			continue
+		}
+		// 回写期间又修改过的记录仍然是脏数据，下次回写使用新的版本
+		entry.casVersion = item.casVersion
+		if entry.version == item.version {
+			entry.dirty = false
+			if c.options.TTL > 0 {
+				entry.expireAt = now.Add(c.options.TTL)
+			}
+		}
+	}
+	return savedKeys, cd.CreateRpcResultOk()
+}
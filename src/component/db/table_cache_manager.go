@@ -0,0 +1,317 @@
+package atframework_component_db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	lu "github.com/atframework/atframe-utils-go/lang_utility"
+	config "github.com/atframework/atsf4g-go/component/config"
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	private_protocol_config "github.com/atframework/atsf4g-go/component/protocol/private/config/protocol/config"
+	"github.com/atframework/libatapp-go"
+)
+
+// TableCacheManager 管理所有共享表缓存的回写、淘汰和跨进程失效通知
+// 需要在 StorageBackendModule 之后注册，停服时会等待脏数据回写完成，最多等待 stop_timeout
+type TableCacheManager struct {
+	libatapp.AppModuleBase
+
+	cacheLock sync.RWMutex
+	caches    map[string]tableCacheImpl
+
+	flushTask          lu.AtomicInterface[cd.TaskActionImpl]
+	nextFlushTimepoint time.Time
+	stopDeadline       time.Time
+
+	pubsub *PubSubChannel
+}
+
+// tableCacheInvalidateMessage 失效通知内容
+type tableCacheInvalidateMessage struct {
+	Cache  string   `json:"cache"`
+	Sender uint64   `json:"sender"`
+	Keys   []string `json:"keys"`
+}
+
+func init() {
+	var _ libatapp.AppModuleImpl = (*TableCacheManager)(nil)
+	var _ tableCacheImpl = (*TableCache[string, any])(nil)
+}
+
+// CreateTableCacheManager 创建共享表缓存管理模块
+func CreateTableCacheManager(owner libatapp.AppImpl) *TableCacheManager {
+	return &TableCacheManager{
+		AppModuleBase: libatapp.CreateAppModuleBase(owner),
+		caches:        make(map[string]tableCacheImpl),
+	}
+}
+
+// RegisterTableCache 注册到当前 app 的 TableCacheManager，没有注册模块时缓存只在本地生效，不会回写
+func RegisterTableCache[K comparable, V any](app libatapp.AppImpl, cache *TableCache[K, V]) error {
+	manager := libatapp.AtappGetModule[*TableCacheManager](app)
+	if manager == nil {
+		return fmt.Errorf("TableCacheManager not found")
+	}
+	return manager.Register(cache)
+}
+
+func getTableCacheConfig() *private_protocol_config.Readonly_LogicTableCacheCfg {
+	return config.GetConfigManager().GetCurrentConfigGroup().GetSectionConfig().GetDb().GetTableCache()
+}
+
+func (m *TableCacheManager) Name() string {
+	return "TableCacheManager"
+}
+
+func (m *TableCacheManager) Init(parent context.Context) error {
+	channel := getTableCacheConfig().GetInvalidateChannel()
+	if channel == "" {
+		return nil
+	}
+
+	pubsub, err := SubscribePubSubChannel(m.GetApp(), m.Name(), []string{GetPubSubChannelName(m.GetApp(), channel)}, m.receiveInvalidateMessage)
+	if err != nil {
+		return err
+	}
+	m.pubsub = pubsub
+	return nil
+}
+
+func (m *TableCacheManager) Cleanup() {
+	m.pubsub.Close()
+	m.pubsub = nil
+}
+
+func (m *TableCacheManager) Tick(parent context.Context) bool {
+	m.tryToStartFlushTask(false)
+	return false
+}
+
+// Stop 等待所有脏数据回写完成，数据库一直不可用时超时后丢弃
+func (m *TableCacheManager) Stop() (bool, error) {
+	return m.stopAt(m.GetApp().GetSysNow()), nil
+}
+
+func (m *TableCacheManager) stopAt(now time.Time) bool {
+	if m.stopDeadline.IsZero() {
+		timeout := getTableCacheConfig().GetStopTimeout().AsDuration()
+		if timeout <= 0 {
+			timeout = 30 * time.Second
+		}
+		m.stopDeadline = now.Add(timeout)
+	}
+
+	if !now.Before(m.stopDeadline) {
+		m.dropDirty()
+		return true
+	}
+	if m.isFlushTaskRunning() {
+		return false
+	}
+	if !m.hasDirty() {
+		return true
+	}
+
+	m.tryToStartFlushTask(true)
+	return false
+}
+
+// dropDirty 停服超时后丢弃未回写的数据，记录下丢弃的 key 便于人工恢复
+func (m *TableCacheManager) dropDirty() {
+	for _, cache := range m.getAllCaches() {
+		if keys := cache.dropDirty(); len(keys) > 0 {
+			m.GetApp().GetDefaultLogger().LogError("TableCacheManager stop timeout, drop dirty data not written back",
+				"cache", cache.Name(), "count", len(keys), "keys", keys)
+		}
+	}
+}
+
+// Register 注册缓存，同名缓存不能重复注册
+func (m *TableCacheManager) Register(cache tableCacheImpl) error {
+	if lu.IsNil(cache) || cache.Name() == "" {
+		return fmt.Errorf("table cache name is required")
+	}
+
+	m.cacheLock.Lock()
+	defer m.cacheLock.Unlock()
+
+	if _, exists := m.caches[cache.Name()]; exists {
+		return fmt.Errorf("table cache %s already registered", cache.Name())
+	}
+	m.caches[cache.Name()] = cache
+	return nil
+}
+
+func (m *TableCacheManager) getCache(name string) tableCacheImpl {
+	m.cacheLock.RLock()
+	defer m.cacheLock.RUnlock()
+
+	return m.caches[name]
+}
+
+func (m *TableCacheManager) getAllCaches() []tableCacheImpl {
+	m.cacheLock.RLock()
+	defer m.cacheLock.RUnlock()
+
+	ret := make([]tableCacheImpl, 0, len(m.caches))
+	for _, cache := range m.caches {
+		ret = append(ret, cache)
+	}
+	return ret
+}
+
+func (m *TableCacheManager) hasDirty() bool {
+	for _, cache := range m.getAllCaches() {
+		if cache.hasDirty() {
+			return true
+		}
+	}
+	return false
+}
+
+// isFlushTaskRunning 检查回写任务是否正在运行
+func (m *TableCacheManager) isFlushTaskRunning() bool {
+	if lu.IsNil(m.flushTask.Load()) {
+		return false
+	}
+	if m.flushTask.Load().IsExiting() {
+		m.flushTask.Store(nil)
+		return false
+	}
+	return true
+}
+
+// tryToStartFlushTask 到达回写间隔后启动回写任务，force 时忽略间隔
+func (m *TableCacheManager) tryToStartFlushTask(force bool) {
+	now := m.GetApp().GetSysNow()
+	if !force && now.Before(m.nextFlushTimepoint) {
+		return
+	}
+
+	if m.isFlushTaskRunning() {
+		return
+	}
+
+	interval := getTableCacheConfig().GetFlushInterval().AsDuration()
+	if interval <= 0 {
+		interval = time.Second
+	}
+	m.nextFlushTimepoint = now.Add(interval)
+
+	// 只在真正启动任务时才创建上下文，Tick 中不创建
+	d := libatapp.AtappGetModule[*cd.NoMessageDispatcher](m.GetApp())
+	ctx := d.CreateRpcContext()
+	flushTask, startData := cd.CreateNoMessageTaskAction(
+		d, ctx, nil,
+		func(rd cd.DispatcherImpl, actor *cd.ActorExecutor, timeout time.Duration) *taskActionTableCacheFlush {
+			return &taskActionTableCacheFlush{
+				TaskActionNoMessageBase: cd.CreateNoMessageTaskActionBase(rd, actor, timeout),
+				manager:                 m,
+			}
+		},
+	)
+
+	err := libatapp.AtappGetModule[*cd.TaskManager](ctx.GetApp()).StartTaskAction(ctx, flushTask, &startData)
+	if err != nil {
+		m.GetApp().GetDefaultLogger().LogError("TableCacheManager StartTaskAction failed", "error", err)
+	} else {
+		m.flushTask.Store(flushTask)
+	}
+}
+
+// InvalidateTableCache 不经过缓存直接写数据库之后调用，丢弃本进程的本地数据并通知其他进程
+// 没有注册 TableCacheManager 的进程(比如只写数据库的工具服)也会通知其他进程
+// keys 和 TableCacheOptions.KeyString 的结果一致
+func InvalidateTableCache(ctx cd.RpcContext, cacheName string, keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+	if manager := libatapp.AtappGetModule[*TableCacheManager](ctx.GetApp()); manager != nil {
+		if cache := manager.getCache(cacheName); !lu.IsNil(cache) {
+			cache.invalidateKeys(keys)
+		}
+	}
+	publishTableCacheInvalidate(ctx, cacheName, keys)
+}
+
+func (m *TableCacheManager) publishInvalidate(ctx cd.RpcContext, cacheName string, keys []string) {
+	if !m.pubsub.IsSubscribed() {
+		return
+	}
+	publishTableCacheInvalidate(ctx, cacheName, keys)
+}
+
+// publishTableCacheInvalidate 通知其他进程丢弃这些 key 的本地数据，发送失败只影响其他进程的缓存时效
+func publishTableCacheInvalidate(ctx cd.RpcContext, cacheName string, keys []string) {
+	channel := getTableCacheConfig().GetInvalidateChannel()
+	if channel == "" || len(keys) == 0 {
+		return
+	}
+
+	data, err := json.Marshal(&tableCacheInvalidateMessage{
+		Cache:  cacheName,
+		Sender: uint64(config.GetConfigManager().GetLogicId()),
+		Keys:   keys,
+	})
+	if err != nil {
+		ctx.LogError("marshal table cache invalidate message failed", "cache", cacheName, "error", err)
+		return
+	}
+
+	if err := PublishPubSubChannel(ctx.GetApp(), "TableCacheManager", GetPubSubChannelName(ctx.GetApp(), channel), data, nil); err != nil {
+		ctx.LogWarn("publish table cache invalidate message failed", "cache", cacheName, "error", err)
+	}
+}
+
+func (m *TableCacheManager) receiveInvalidateMessage(channel string, payload []byte) {
+	invalidate := &tableCacheInvalidateMessage{}
+	if err := json.Unmarshal(payload, invalidate); err != nil {
+		m.GetApp().GetDefaultLogger().LogWarn("TableCacheManager bad invalidate message", "channel", channel, "payload", string(payload), "error", err)
+		return
+	}
+	if invalidate.Sender == uint64(config.GetConfigManager().GetLogicId()) {
+		return
+	}
+
+	cache := m.getCache(invalidate.Cache)
+	if lu.IsNil(cache) {
+		return
+	}
+	cache.invalidateKeys(invalidate.Keys)
+}
+
+// taskActionTableCacheFlush 回写所有缓存的脏数据并淘汰过期数据
+type taskActionTableCacheFlush struct {
+	cd.TaskActionNoMessageBase
+	manager *TableCacheManager
+}
+
+func (t *taskActionTableCacheFlush) Name() string {
+	return "TaskActionTableCacheFlush"
+}
+
+func (t *taskActionTableCacheFlush) Run(_ *cd.DispatcherStartData) error {
+	ctx := t.GetAwaitableContext()
+	batchCount := int(getTableCacheConfig().GetFlushBatchCount())
+	if batchCount <= 0 {
+		batchCount = 1
+	}
+
+	for _, cache := range t.manager.getAllCaches() {
+		savedKeys, result := cache.flush(ctx, batchCount)
+		if result.IsError() {
+			result.LogWarn(ctx, "table cache flush failed, will retry later", "cache", cache.Name())
+		} else if len(savedKeys) > 0 {
+			ctx.LogDebug("table cache flush success", "cache", cache.Name(), "count", len(savedKeys))
+			t.manager.publishInvalidate(ctx, cache.Name(), savedKeys)
+		}
+
+		if evicted := cache.evictExpired(ctx.GetSysNow()); evicted > 0 {
+			ctx.LogDebug("table cache evict expired", "cache", cache.Name(), "count", evicted)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,283 @@
+package atframework_component_db_test
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/atframework/libatapp-go"
+
+	db "github.com/atframework/atsf4g-go/component/db"
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	test_utility "github.com/atframework/atsf4g-go/component/test_utility"
+)
+
+const (
+	testTableCacheName          = "test_user"
+	testTableCacheZoneId uint32 = 1
+	testTableCacheTTL           = time.Minute
+)
+
+var testTableCacheNow = time.Unix(1700000000, 0)
+
+// createTestUserTableCache 以 DatabaseTableUser 为例创建缓存，loadCount 记录实际从数据库加载的次数
+func createTestUserTableCache(loadCount *atomic.Int32, beforeLoad func()) *db.TableCache[uint64, *private_protocol_pbdesc.DatabaseTableUser] {
+	return db.CreateTableCache(db.TableCacheOptions[uint64, *private_protocol_pbdesc.DatabaseTableUser]{
+		Name: testTableCacheName,
+		TTL:  testTableCacheTTL,
+		Load: func(ctx cd.AwaitableContext, userId uint64) (*private_protocol_pbdesc.DatabaseTableUser, uint64, cd.RpcResult) {
+			loadCount.Add(1)
+			if beforeLoad != nil {
+				beforeLoad()
+			}
+			return db.DatabaseTableUserLoadWithZoneIdUserId(ctx, testTableCacheZoneId, userId)
+		},
+		Save: func(pipeline *db.Pipeline, _ uint64, value *private_protocol_pbdesc.DatabaseTableUser, currentCASVersion *uint64) *db.PipelineResult {
+			return db.DatabaseTableUserPipelineReplaceZoneIdUserId(pipeline, value, currentCASVersion, false)
+		},
+	})
+}
+
+func putTestTableCacheUser(t *testing.T, ctx cd.AwaitableContext, userId uint64, openId string) uint64 {
+	var casVersion uint64
+	assert.False(t, db.DatabaseTableUserReplaceZoneIdUserId(ctx, &private_protocol_pbdesc.DatabaseTableUser{
+		ZoneId: testTableCacheZoneId,
+		UserId: userId,
+		OpenId: openId,
+	}, &casVersion, true).IsError())
+	return casVersion
+}
+
+// TestTableCacheTTLExpire 测试未修改的记录过期
+// Scenario: TTL 内读取命中本地数据，过期后重新加载，有未回写修改的记录不会过期淘汰
+func TestTableCacheTTLExpire(t *testing.T) {
+	// Arrange
+	app, _ := test_utility.CreateMemoryStorageApp()
+	ctx := test_utility.NewMockTaskRpcContext(app, testTableCacheNow)
+	putTestTableCacheUser(t, ctx, 1, "open-1")
+	var loadCount atomic.Int32
+	cache := createTestUserTableCache(&loadCount, nil)
+
+	// Act & Assert
+	_, result := cache.Get(ctx, 1)
+	assert.False(t, result.IsError())
+	ctx.SetNow(testTableCacheNow.Add(testTableCacheTTL - time.Second))
+	_, result = cache.Get(ctx, 1)
+	assert.False(t, result.IsError())
+	assert.Equal(t, int32(1), loadCount.Load())
+
+	ctx.SetNow(testTableCacheNow.Add(testTableCacheTTL))
+	value, result := cache.Get(ctx, 1)
+	assert.False(t, result.IsError())
+	assert.Equal(t, "open-1", value.GetOpenId())
+	assert.Equal(t, int32(2), loadCount.Load())
+
+	assert.False(t, cache.Set(ctx, 2, &private_protocol_pbdesc.DatabaseTableUser{ZoneId: testTableCacheZoneId, UserId: 2}).IsError())
+	assert.Equal(t, 1, cache.EvictExpired(testTableCacheNow.Add(3*testTableCacheTTL)))
+	_, result = cache.Get(ctx, 2)
+	assert.False(t, result.IsError())
+	assert.Equal(t, int32(2), loadCount.Load())
+}
+
+// TestTableCacheSingleFlight 测试并发读取同一条记录
+// Scenario: 一个任务正在加载时，其他任务挂起等待并共享加载结果，只有一次 IO
+func TestTableCacheSingleFlight(t *testing.T) {
+	// Arrange
+	app, _ := test_utility.CreateMemoryStorageApp()
+	firstCtx := test_utility.NewMockTaskRpcContext(app, testTableCacheNow)
+	secondCtx := test_utility.NewMockTaskRpcContext(app, testTableCacheNow)
+	putTestTableCacheUser(t, firstCtx, 1, "open-1")
+	loadStarted := make(chan struct{})
+	releaseLoad := make(chan struct{})
+	var startOnce sync.Once
+	var loadCount atomic.Int32
+	cache := createTestUserTableCache(&loadCount, func() {
+		startOnce.Do(func() { close(loadStarted) })
+		<-releaseLoad
+	})
+
+	// Act
+	var wg sync.WaitGroup
+	values := make([]*private_protocol_pbdesc.DatabaseTableUser, 2)
+	results := make([]cd.RpcResult, 2)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		values[0], results[0] = cache.Get(firstCtx, 1)
+	}()
+	<-loadStarted
+	go func() {
+		defer wg.Done()
+		values[1], results[1] = cache.Get(secondCtx, 1)
+	}()
+	// 等待第二个任务进入挂起状态
+	time.Sleep(100 * time.Millisecond)
+	close(releaseLoad)
+	wg.Wait()
+
+	// Assert
+	assert.Equal(t, int32(1), loadCount.Load())
+	for i := range values {
+		assert.False(t, results[i].IsError())
+		assert.Equal(t, "open-1", values[i].GetOpenId())
+	}
+	assert.Same(t, values[0], values[1])
+}
+
+// TestTableCacheWriteBehindFlush 测试回写
+// Scenario: Set 之后数据库不变，回写后写入数据库并清除脏标记，CAS 版本使用回写后的版本，连续回写都成功
+func TestTableCacheWriteBehindFlush(t *testing.T) {
+	// Arrange
+	app, _ := test_utility.CreateMemoryStorageApp()
+	ctx := test_utility.NewMockTaskRpcContext(app, testTableCacheNow)
+	casVersion := putTestTableCacheUser(t, ctx, 1, "open-1")
+	var loadCount atomic.Int32
+	cache := createTestUserTableCache(&loadCount, nil)
+	value, result := cache.Get(ctx, 1)
+	assert.False(t, result.IsError())
+
+	// Act & Assert
+	value.OpenId = "changed-1"
+	assert.False(t, cache.Set(ctx, 1, value).IsError())
+	savedKeys, result := cache.Flush(ctx, 10)
+	assert.False(t, result.IsError())
+	assert.Equal(t, []string{"1"}, savedKeys)
+
+	dbValue, dbCASVersion, result := db.DatabaseTableUserLoadWithZoneIdUserId(ctx, testTableCacheZoneId, 1)
+	assert.False(t, result.IsError())
+	assert.Equal(t, "changed-1", dbValue.GetOpenId())
+	assert.Equal(t, casVersion+1, dbCASVersion)
+
+	value.OpenId = "changed-2"
+	assert.False(t, cache.Set(ctx, 1, value).IsError())
+	savedKeys, result = cache.Flush(ctx, 10)
+	assert.False(t, result.IsError())
+	assert.Equal(t, []string{"1"}, savedKeys)
+
+	dbValue, dbCASVersion, result = db.DatabaseTableUserLoadWithZoneIdUserId(ctx, testTableCacheZoneId, 1)
+	assert.False(t, result.IsError())
+	assert.Equal(t, "changed-2", dbValue.GetOpenId())
+	assert.Equal(t, casVersion+2, dbCASVersion)
+
+	savedKeys, result = cache.Flush(ctx, 10)
+	assert.False(t, result.IsError())
+	assert.Empty(t, savedKeys)
+	assert.Equal(t, int32(1), loadCount.Load())
+}
+
+// TestTableCacheFlushCASConflict 测试回写时 CAS 冲突
+// Scenario: 其他进程修改过数据库后本地修改被丢弃，数据库保留其他进程的数据，下次读取重新加载
+func TestTableCacheFlushCASConflict(t *testing.T) {
+	// Arrange
+	app, _ := test_utility.CreateMemoryStorageApp()
+	ctx := test_utility.NewMockTaskRpcContext(app, testTableCacheNow)
+	putTestTableCacheUser(t, ctx, 1, "open-1")
+	var loadCount atomic.Int32
+	cache := createTestUserTableCache(&loadCount, nil)
+	value, result := cache.Get(ctx, 1)
+	assert.False(t, result.IsError())
+	putTestTableCacheUser(t, ctx, 1, "other-process")
+
+	// Act
+	value.OpenId = "local"
+	assert.False(t, cache.Set(ctx, 1, value).IsError())
+	savedKeys, result := cache.Flush(ctx, 10)
+
+	// Assert
+	assert.False(t, result.IsError())
+	assert.Empty(t, savedKeys)
+
+	value, result = cache.Get(ctx, 1)
+	assert.False(t, result.IsError())
+	assert.Equal(t, "other-process", value.GetOpenId())
+	assert.Equal(t, int32(2), loadCount.Load())
+}
+
+// TestTableCacheInvalidate 测试失效通知
+// Scenario: 本进程 InvalidateTableCache 和收到其他进程的通知都会丢弃本地数据，自己发出的通知和未回写的修改不受影响
+func TestTableCacheInvalidate(t *testing.T) {
+	// Arrange
+	app, _ := test_utility.CreateMemoryStorageApp()
+	manager := db.CreateTableCacheManager(app)
+	libatapp.AtappAddModule(app, manager)
+	ctx := test_utility.NewMockTaskRpcContext(app, testTableCacheNow)
+	putTestTableCacheUser(t, ctx, 1, "open-1")
+	putTestTableCacheUser(t, ctx, 2, "open-2")
+	var loadCount atomic.Int32
+	cache := createTestUserTableCache(&loadCount, nil)
+	assert.NoError(t, db.RegisterTableCache(app, cache))
+	assert.Error(t, db.RegisterTableCache(app, cache))
+	createMessage := func(sender uint64, keys ...string) []byte {
+		payload, err := json.Marshal(map[string]any{"cache": testTableCacheName, "sender": sender, "keys": keys})
+		assert.NoError(t, err)
+		return payload
+	}
+	getAll := func() {
+		for _, userId := range []uint64{1, 2} {
+			_, result := cache.Get(ctx, userId)
+			assert.False(t, result.IsError())
+		}
+	}
+	getAll()
+	assert.Equal(t, int32(2), loadCount.Load())
+
+	// Act & Assert
+	db.InvalidateTableCache(ctx, testTableCacheName, "1")
+	getAll()
+	assert.Equal(t, int32(3), loadCount.Load())
+
+	// 测试环境的 logic id 为 0
+	manager.ReceiveInvalidateMessage("channel", createMessage(0, "1", "2"))
+	getAll()
+	assert.Equal(t, int32(3), loadCount.Load())
+
+	manager.ReceiveInvalidateMessage("channel", createMessage(1, "2"))
+	getAll()
+	assert.Equal(t, int32(4), loadCount.Load())
+
+	assert.False(t, cache.Set(ctx, 1, &private_protocol_pbdesc.DatabaseTableUser{ZoneId: testTableCacheZoneId, UserId: 1, OpenId: "local"}).IsError())
+	manager.ReceiveInvalidateMessage("channel", createMessage(1, "1"))
+	value, result := cache.Get(ctx, 1)
+	assert.False(t, result.IsError())
+	assert.Equal(t, "local", value.GetOpenId())
+	assert.Equal(t, int32(4), loadCount.Load())
+
+	manager.ReceiveInvalidateMessage("channel", []byte("bad payload"))
+	_, result = cache.Get(ctx, 2)
+	assert.False(t, result.IsError())
+	assert.Equal(t, int32(4), loadCount.Load())
+}
+
+// TestTableCacheManagerStopTimeout 测试停服等待回写超时
+// Scenario: 没有脏数据时直接完成，超过等待时间后丢弃未回写的数据并完成停服，数据库保持原样
+func TestTableCacheManagerStopTimeout(t *testing.T) {
+	// Arrange
+	app, _ := test_utility.CreateMemoryStorageApp()
+	manager := db.CreateTableCacheManager(app)
+	libatapp.AtappAddModule(app, manager)
+	ctx := test_utility.NewMockTaskRpcContext(app, testTableCacheNow)
+	putTestTableCacheUser(t, ctx, 1, "open-1")
+	var loadCount atomic.Int32
+	cache := createTestUserTableCache(&loadCount, nil)
+	assert.NoError(t, db.RegisterTableCache(app, cache))
+
+	// Act
+	stoppedWithoutDirty := manager.StopAt(testTableCacheNow)
+	assert.False(t, cache.Set(ctx, 1, &private_protocol_pbdesc.DatabaseTableUser{ZoneId: testTableCacheZoneId, UserId: 1, OpenId: "lost"}).IsError())
+	stoppedAfterTimeout := manager.StopAt(testTableCacheNow.Add(time.Hour))
+
+	// Assert
+	assert.True(t, stoppedWithoutDirty)
+	assert.True(t, stoppedAfterTimeout)
+	savedKeys, result := cache.Flush(ctx, 10)
+	assert.False(t, result.IsError())
+	assert.Empty(t, savedKeys)
+	dbValue, _, result := db.DatabaseTableUserLoadWithZoneIdUserId(ctx, testTableCacheZoneId, 1)
+	assert.False(t, result.IsError())
+	assert.Equal(t, "open-1", dbValue.GetOpenId())
+}
@@ -2,14 +2,19 @@ package atframework_component_dispatcher
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"log/slog"
+	"math/rand"
+	"os"
 	"sync/atomic"
 	"time"
 
 	host "github.com/atframework/atframe-utils-go/host"
 	log "github.com/atframework/atframe-utils-go/log"
 	config "github.com/atframework/atsf4g-go/component/config"
+	private_protocol_config "github.com/atframework/atsf4g-go/component/protocol/private/config/protocol/config"
 	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
 	libatapp "github.com/atframework/libatapp-go"
 	"github.com/redis/go-redis/v9"
@@ -34,6 +39,11 @@ type RedisClientWrapper interface {
 	redis.GenericCmdable
 	Close() error
 	ScriptLoad(ctx context.Context, script string) *redis.StringCmd
+	Pipeline() redis.Pipeliner
+	TxPipeline() redis.Pipeliner
+	Watch(ctx context.Context, fn func(*redis.Tx) error, keys ...string) error
+	Publish(ctx context.Context, channel string, message interface{}) *redis.IntCmd
+	Subscribe(ctx context.Context, channels ...string) *redis.PubSub
 }
 
 type RedisMessageDispatcher struct {
@@ -45,6 +55,10 @@ type RedisMessageDispatcher struct {
 	recordPrefix  string
 	casLuaSHA     string
 	listAddLuaSHA string
+
+	// 从节点读取
+	redisReadInstance RedisClientWrapper
+	replicaReadTables map[string]struct{}
 }
 
 const (
@@ -121,6 +135,13 @@ func (d *RedisMessageDispatcher) Init(initCtx context.Context) error {
 		return err
 	}
 
+	// 使用本地存储后端时不连接 Redis
+	dbBackend := config.GetConfigManager().GetCurrentConfigGroup().GetSectionConfig().GetDb().GetBackend()
+	if dbBackend != "" && dbBackend != "redis" {
+		d.DispatcherBase.GetLogger().LogInfo("Skip init redis, db storage backend is not redis", "Backend", dbBackend)
+		return nil
+	}
+
 	redisCfg := config.GetConfigManager().GetCurrentConfigGroup().GetSectionConfig().GetRedis()
 	if len(redisCfg.GetAddrs()) == 0 {
 		d.DispatcherBase.GetLogger().LogError("redis config error empty")
@@ -137,28 +158,34 @@ func (d *RedisMessageDispatcher) Init(initCtx context.Context) error {
 
 	d.DispatcherBase.GetLogger().LogInfo("Redis Prefix", "Prefix", d.recordPrefix)
 
-	redis.SetLogger(&d.log)
-	if redisCfg.GetClusterMode() {
-		d.GetLogger().LogInfo("Init Redis Cluster Client")
-		d.redisInstance = redis.NewClusterClient(&redis.ClusterOptions{
-			Addrs:    redisCfg.GetAddrs(),
-			Password: redisCfg.GetPassword(),
-			PoolSize: int(redisCfg.GetPoolSize()),
-		})
-	} else {
-		d.GetLogger().LogInfo("Init Redis Client")
-		d.redisInstance = redis.NewClient(&redis.Options{
-			Addr:     redisCfg.GetAddrs()[0],
-			Password: redisCfg.GetPassword(),
-			PoolSize: int(redisCfg.GetPoolSize()),
-		})
+	tlsConfig, err := CreateRedisTLSConfig(redisCfg.GetTls())
+	if err != nil {
+		d.GetLogger().LogError("load redis tls config failed", "err", err)
+		return err
 	}
 
+	redis.SetLogger(&d.log)
+	d.redisInstance = d.createRedisClient(redisCfg, tlsConfig, false)
 	if d.redisInstance == nil {
 		d.DispatcherBase.GetLogger().LogError("Create Redis Cluster Client Failed")
 		return fmt.Errorf("create redis cluster client failed")
 	}
 
+	if redisCfg.GetReplicaRead().GetEnable() {
+		d.redisReadInstance = d.createRedisClient(redisCfg, tlsConfig, true)
+		if d.redisReadInstance == nil {
+			d.GetLogger().LogError("Create Redis replica client failed, replica_read.addrs is required without cluster or sentinel")
+			return fmt.Errorf("create redis replica client failed")
+		}
+		d.replicaReadTables = make(map[string]struct{}, len(redisCfg.GetReplicaRead().GetTables()))
+		for _, tableName := range redisCfg.GetReplicaRead().GetTables() {
+			d.replicaReadTables[tableName] = struct{}{}
+		}
+		if len(d.replicaReadTables) == 0 {
+			d.GetLogger().LogWarn("replica_read is enabled but replica_read.tables is empty, all reads will use the primary")
+		}
+	}
+
 	newCtx, _ := context.WithTimeout(initCtx, time.Duration(3)*time.Second)
 	sha, err := d.redisInstance.ScriptLoad(newCtx, CASLuaScript).Result()
 	if err != nil {
@@ -179,11 +206,114 @@ func (d *RedisMessageDispatcher) Init(initCtx context.Context) error {
 	return nil
 }
 
+// createRedisClient 按配置创建客户端，优先级 Sentinel > 集群 > 单节点
+// replica 为 true 时创建只读客户端，只用于不需要强一致的读取
+func (d *RedisMessageDispatcher) createRedisClient(redisCfg *private_protocol_config.Readonly_LogicRedisCfg, tlsConfig *tls.Config, replica bool) RedisClientWrapper {
+	if masterName := redisCfg.GetSentinel().GetMasterName(); masterName != "" {
+		d.GetLogger().LogInfo("Init Redis Sentinel Client", "MasterName", masterName, "Replica", replica)
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       masterName,
+			SentinelAddrs:    redisCfg.GetAddrs(),
+			SentinelUsername: redisCfg.GetSentinel().GetUsername(),
+			SentinelPassword: redisCfg.GetSentinel().GetPassword(),
+			ReplicaOnly:      replica,
+			Username:         redisCfg.GetUsername(),
+			Password:         redisCfg.GetPassword(),
+			PoolSize:         int(redisCfg.GetPoolSize()),
+			DialTimeout:      redisCfg.GetDialTimeout().AsDuration(),
+			ReadTimeout:      redisCfg.GetReadTimeout().AsDuration(),
+			WriteTimeout:     redisCfg.GetWriteTimeout().AsDuration(),
+			MaxRetries:       int(redisCfg.GetMaxRetries()),
+			MinRetryBackoff:  redisCfg.GetMinRetryBackoff().AsDuration(),
+			MaxRetryBackoff:  redisCfg.GetMaxRetryBackoff().AsDuration(),
+			TLSConfig:        tlsConfig,
+		})
+	}
+
+	if redisCfg.GetClusterMode() {
+		d.GetLogger().LogInfo("Init Redis Cluster Client", "Replica", replica)
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs: redisCfg.GetAddrs(),
+			// 只读客户端的只读命令随机发往主从节点
+			ReadOnly:        replica,
+			RouteRandomly:   replica,
+			Username:        redisCfg.GetUsername(),
+			Password:        redisCfg.GetPassword(),
+			PoolSize:        int(redisCfg.GetPoolSize()),
+			DialTimeout:     redisCfg.GetDialTimeout().AsDuration(),
+			ReadTimeout:     redisCfg.GetReadTimeout().AsDuration(),
+			WriteTimeout:    redisCfg.GetWriteTimeout().AsDuration(),
+			MaxRetries:      int(redisCfg.GetMaxRetries()),
+			MinRetryBackoff: redisCfg.GetMinRetryBackoff().AsDuration(),
+			MaxRetryBackoff: redisCfg.GetMaxRetryBackoff().AsDuration(),
+			TLSConfig:       tlsConfig,
+		})
+	}
+
+	addr := redisCfg.GetAddrs()[0]
+	if replica {
+		replicaAddrs := redisCfg.GetReplicaRead().GetAddrs()
+		if len(replicaAddrs) == 0 {
+			return nil
+		}
+		addr = replicaAddrs[rand.Intn(len(replicaAddrs))]
+	}
+	d.GetLogger().LogInfo("Init Redis Client", "Addr", addr, "Replica", replica)
+	return redis.NewClient(&redis.Options{
+		Addr:            addr,
+		Username:        redisCfg.GetUsername(),
+		Password:        redisCfg.GetPassword(),
+		PoolSize:        int(redisCfg.GetPoolSize()),
+		DialTimeout:     redisCfg.GetDialTimeout().AsDuration(),
+		ReadTimeout:     redisCfg.GetReadTimeout().AsDuration(),
+		WriteTimeout:    redisCfg.GetWriteTimeout().AsDuration(),
+		MaxRetries:      int(redisCfg.GetMaxRetries()),
+		MinRetryBackoff: redisCfg.GetMinRetryBackoff().AsDuration(),
+		MaxRetryBackoff: redisCfg.GetMaxRetryBackoff().AsDuration(),
+		TLSConfig:       tlsConfig,
+	})
+}
+
+// CreateRedisTLSConfig 未开启 TLS 时返回 nil，工具侧连接 Redis 时也复用这里的规则
+func CreateRedisTLSConfig(tlsCfg *private_protocol_config.Readonly_LogicRedisTlsCfg) (*tls.Config, error) {
+	if !tlsCfg.GetEnable() {
+		return nil, nil
+	}
+
+	ret := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         tlsCfg.GetServerName(),
+		InsecureSkipVerify: tlsCfg.GetInsecureSkipVerify(),
+	}
+	if tlsCfg.GetCaFile() != "" {
+		caData, err := os.ReadFile(tlsCfg.GetCaFile())
+		if err != nil {
+			return nil, fmt.Errorf("read redis tls ca file failed: %w", err)
+		}
+		ret.RootCAs = x509.NewCertPool()
+		if !ret.RootCAs.AppendCertsFromPEM(caData) {
+			return nil, fmt.Errorf("no certificate found in redis tls ca file %s", tlsCfg.GetCaFile())
+		}
+	}
+	if tlsCfg.GetCertFile() != "" || tlsCfg.GetKeyFile() != "" {
+		cert, err := tls.LoadX509KeyPair(tlsCfg.GetCertFile(), tlsCfg.GetKeyFile())
+		if err != nil {
+			return nil, fmt.Errorf("load redis tls client certificate failed: %w", err)
+		}
+		ret.Certificates = []tls.Certificate{cert}
+	}
+	return ret, nil
+}
+
 func (d *RedisMessageDispatcher) Cleanup() {
 	if d.redisInstance != nil {
 		d.redisInstance.Close()
 	}
 	d.redisInstance = nil
+	if d.redisReadInstance != nil {
+		d.redisReadInstance.Close()
+	}
+	d.redisReadInstance = nil
 }
 
 func (d *RedisMessageDispatcher) GetRedisInstance() RedisClientWrapper {
@@ -193,6 +323,21 @@ func (d *RedisMessageDispatcher) GetRedisInstance() RedisClientWrapper {
 	return d.redisInstance
 }
 
+// GetRedisReadInstance 获取只读操作使用的客户端，未开启从节点读取或表不在 replica_read.tables 中时返回主节点客户端
+func (d *RedisMessageDispatcher) GetRedisReadInstance(tableName string) RedisClientWrapper {
+	if d == nil {
+		return nil
+	}
+	if d.redisReadInstance == nil {
+		return d.redisInstance
+	}
+	// 只有显式列出的表才走从节点，避免读取后需要 CAS 写回的表读到旧版本
+	if _, ok := d.replicaReadTables[tableName]; !ok {
+		return d.redisInstance
+	}
+	return d.redisReadInstance
+}
+
 func (d *RedisMessageDispatcher) CreateDispatcherAwaitOptions() *DispatcherAwaitOptions {
 	return &DispatcherAwaitOptions{
 		Type:     d.GetInstanceIdent(),
@@ -0,0 +1,55 @@
+package atframework_component_dispatcher
+
+import (
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGetRedisReadInstance 测试只读操作的节点选择
+// Scenario: 只有 replica_read.tables 中列出的表走从节点，列表为空或者未开启从节点读取时都走主节点
+func TestGetRedisReadInstance(t *testing.T) {
+	primary := redis.NewClient(&redis.Options{Addr: "127.0.0.1:0"})
+	replica := redis.NewClient(&redis.Options{Addr: "127.0.0.1:0"})
+	defer primary.Close()
+	defer replica.Close()
+
+	testCases := []struct {
+		name          string
+		replica       RedisClientWrapper
+		tables        []string
+		tableName     string
+		expectReplica bool
+	}{
+		{name: "replica disabled", replica: nil, tables: []string{"DatabaseTableRank"}, tableName: "DatabaseTableRank"},
+		{name: "empty tables", replica: replica, tables: nil, tableName: "DatabaseTableRank"},
+		{name: "empty tables with cas table", replica: replica, tables: nil, tableName: "DatabaseTableUser"},
+		{name: "listed table", replica: replica, tables: []string{"DatabaseTableRank"}, tableName: "DatabaseTableRank", expectReplica: true},
+		{name: "unlisted table", replica: replica, tables: []string{"DatabaseTableRank"}, tableName: "DatabaseTableUser"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			d := &RedisMessageDispatcher{
+				redisInstance:     primary,
+				redisReadInstance: tc.replica,
+				replicaReadTables: make(map[string]struct{}),
+			}
+			for _, tableName := range tc.tables {
+				d.replicaReadTables[tableName] = struct{}{}
+			}
+
+			// Act
+			instance := d.GetRedisReadInstance(tc.tableName)
+
+			// Assert
+			if tc.expectReplica {
+				assert.Same(t, replica, instance)
+			} else {
+				assert.Same(t, primary, instance)
+			}
+		})
+	}
+}
@@ -72,6 +72,7 @@ type WebSocketMessageDispatcher struct {
 	webServerHandle   *http.ServeMux
 	webServerInstance *http.Server
 	webServerAddress  string
+	httpHandlers      sync.Map // path -> http.HandlerFunc
 
 	stopping bool
 
@@ -139,6 +140,11 @@ func (d *WebSocketMessageDispatcher) setupListen() error {
 	if d.webServerHandle == nil {
 		d.webServerHandle = http.NewServeMux()
 		d.webServerHandle.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			if handler, ok := d.httpHandlers.Load(r.URL.Path); ok {
+				handler.(http.HandlerFunc)(w, r)
+				return
+			}
+
 			if !strings.HasPrefix(r.URL.Path, d.wsConfig.GetPath()) {
 				http.NotFound(w, r)
 				return
@@ -641,6 +647,17 @@ func (d *WebSocketMessageDispatcher) AllocateSessionId() uint64 {
 	return d.sessionIdAllocator.Add(1)
 }
 
+// SetHttpHandler 在 webserver 上挂载额外的HTTP处理函数(按路径精确匹配，如平台支付回调)，handler 为 nil 时移除
+// 可以在运行中调用，用于Reload后更换路径
+func (d *WebSocketMessageDispatcher) SetHttpHandler(path string, handler http.HandlerFunc) {
+	if handler == nil {
+		d.httpHandlers.Delete(path)
+		return
+	}
+
+	d.httpHandlers.Store(path, handler)
+}
+
 func (d *WebSocketMessageDispatcher) SetOnNewSession(callback WebSocketCallbackOnNewSession) {
 	if callback == nil {
 		d.onNewSession.Store(nil)
@@ -4,6 +4,8 @@
 package atframework_component_mail
 
 import (
+	"fmt"
+
 	config "github.com/atframework/atsf4g-go/component/config"
 	db "github.com/atframework/atsf4g-go/component/db"
 	cd "github.com/atframework/atsf4g-go/component/dispatcher"
@@ -21,8 +23,21 @@ const (
 	EN_MAIL_GLOBAL_LEAK_CHECK_TIMEOUT = 7 * 24 * 3600 // 7天（秒）
 	// EN_MAIL_DEFAULT_MAX_RETRY 默认最大重试次数
 	EN_MAIL_DEFAULT_MAX_RETRY = 5
+
+	// GlobalMailTableCacheName lobbysvr 中全服邮件表的共享缓存名称
+	GlobalMailTableCacheName = "global_mail"
 )
 
+// GlobalMailTableCacheKey 全服邮件表在共享缓存中的 key
+type GlobalMailTableCacheKey struct {
+	ZoneId    uint32
+	MajorType int32
+}
+
+func (k GlobalMailTableCacheKey) String() string {
+	return fmt.Sprintf("%d-%d", k.ZoneId, k.MajorType)
+}
+
 // compactMails 淘汰老邮件，保持邮件数量在限制内
 func compactMails(_ctx cd.AwaitableContext, dbData *private_protocol_pbdesc.DatabaseGlobalMailBlobData) {
 	configGroup := config.GetConfigManager().GetCurrentConfigGroup()
@@ -257,7 +272,7 @@ func addGlobalMailInner(
 	// DB: retry and add mail record
 	var lastErr cd.RpcResult
 	for leftRetry := EN_MAIL_DEFAULT_MAX_RETRY; leftRetry > 0; leftRetry-- {
-		dbTable, loadResult := db.DatabaseTableGlobalMailLoadWithZoneIdMajorType(ctx, zoneId, mail.GetMajorType())
+		dbTable, casVersion, loadResult := db.DatabaseTableGlobalMailLoadWithZoneIdMajorType(ctx, zoneId, mail.GetMajorType())
 		if loadResult.IsError() &&
 			loadResult.GetResponseCode() != int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND) {
 			ctx.LogError("get_global_mail failed",
@@ -299,7 +314,7 @@ func addGlobalMailInner(
 		// 如果邮件太多需要淘汰老的
 		compactMails(ctx, dbTable.GetJobData())
 
-		updateResult := db.DatabaseTableGlobalMailUpdateZoneIdMajorType(ctx, dbTable)
+		updateResult := db.DatabaseTableGlobalMailReplaceZoneIdMajorType(ctx, dbTable, &casVersion, false)
 		if updateResult.IsError() {
 			ctx.LogError("replace_global_mail failed",
 				"zone_id", zoneId,
@@ -309,6 +324,7 @@ func addGlobalMailInner(
 			lastErr = updateResult
 			continue
 		}
+		db.InvalidateTableCache(ctx, GlobalMailTableCacheName, GlobalMailTableCacheKey{ZoneId: zoneId, MajorType: mail.GetMajorType()}.String())
 
 		// 成功
 		ctx.LogInfo("add_global_mail success",
@@ -369,7 +385,7 @@ func RemoveGlobalMail(
 			majorType = mailContent.GetJobData().GetMailContent().GetMajorType()
 		}
 
-		dbTable, loadResult := db.DatabaseTableGlobalMailLoadWithZoneIdMajorType(ctx, zoneId, majorType)
+		dbTable, casVersion, loadResult := db.DatabaseTableGlobalMailLoadWithZoneIdMajorType(ctx, zoneId, majorType)
 		if loadResult.GetResponseCode() == int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND) {
 			return cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_MAIL_NOT_FOUND)
 		}
@@ -415,7 +431,7 @@ func RemoveGlobalMail(
 		// 如果邮件太多需要淘汰老的
 		compactMails(ctx, dbData)
 
-		updateResult := db.DatabaseTableGlobalMailUpdateZoneIdMajorType(ctx, dbTable)
+		updateResult := db.DatabaseTableGlobalMailReplaceZoneIdMajorType(ctx, dbTable, &casVersion, false)
 		if updateResult.IsError() {
 			ctx.LogError("replace_global_mail failed",
 				"zone_id", zoneId,
@@ -425,6 +441,7 @@ func RemoveGlobalMail(
 			lastErr = updateResult
 			continue
 		}
+		db.InvalidateTableCache(ctx, GlobalMailTableCacheName, GlobalMailTableCacheKey{ZoneId: zoneId, MajorType: majorType}.String())
 
 		// 成功
 		ctx.LogInfo("remove_global_mail success",
@@ -0,0 +1,123 @@
+// Copyright 2026 atframework
+// @brief 邮件多语言工具函数
+
+package atframework_component_mail
+
+import (
+	"strings"
+
+	config "github.com/atframework/atsf4g-go/component/config"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+)
+
+// MailParseLanguage 解析语言标识（BCP 47 或 locale 格式，如 zh-Hans-CN, zh_TW, en-US, ja），无法识别返回 EN_MUTL_LANGUAGE_NONE
+func MailParseLanguage(lang string) public_protocol_pbdesc.EnMutlLanguageType {
+	lang = strings.ToLower(strings.TrimSpace(strings.ReplaceAll(lang, "_", "-")))
+	if lang == "" {
+		return public_protocol_pbdesc.EnMutlLanguageType_EN_MUTL_LANGUAGE_NONE
+	}
+
+	segments := strings.Split(lang, "-")
+	switch segments[0] {
+	case "en":
+		return public_protocol_pbdesc.EnMutlLanguageType_EN_MUTL_LANGUAGE_EN
+	case "ja":
+		return public_protocol_pbdesc.EnMutlLanguageType_EN_MUTL_LANGUAGE_JA
+	case "zh":
+		// 无脚本标记时按地区区分简繁
+		for _, segment := range segments[1:] {
+			switch segment {
+			case "hant", "tw", "hk", "mo":
+				return public_protocol_pbdesc.EnMutlLanguageType_EN_MUTL_LANGUAGE_ZH_HANT
+			case "hans", "cn", "sg":
+				return public_protocol_pbdesc.EnMutlLanguageType_EN_MUTL_LANGUAGE_ZH_HANS
+			}
+		}
+		return public_protocol_pbdesc.EnMutlLanguageType_EN_MUTL_LANGUAGE_ZH_HANS
+	}
+
+	// 允许直接填写枚举名或枚举值
+	if v, ok := public_protocol_pbdesc.EnMutlLanguageType_value[strings.ToUpper(strings.ReplaceAll(lang, "-", "_"))]; ok {
+		return public_protocol_pbdesc.EnMutlLanguageType(v)
+	}
+
+	return public_protocol_pbdesc.EnMutlLanguageType_EN_MUTL_LANGUAGE_NONE
+}
+
+// MailGetServerLanguage 获取服务器默认语言（logic_localization_cfg.lang）
+func MailGetServerLanguage() public_protocol_pbdesc.EnMutlLanguageType {
+	configGroup := config.GetConfigManager().GetCurrentConfigGroup()
+	if configGroup == nil {
+		return public_protocol_pbdesc.EnMutlLanguageType_EN_MUTL_LANGUAGE_NONE
+	}
+
+	return MailParseLanguage(configGroup.GetSectionConfig().GetLocalization().GetLang())
+}
+
+// mailLanguageFallbackChain 构造语言回退链: 偏好语言 -> 同语系的另一书写形式 -> 服务器默认语言 -> 英语
+func mailLanguageFallbackChain(preferred, serverDefault public_protocol_pbdesc.EnMutlLanguageType) []public_protocol_pbdesc.EnMutlLanguageType {
+	ret := make([]public_protocol_pbdesc.EnMutlLanguageType, 0, 4)
+	appendUnique := func(lang public_protocol_pbdesc.EnMutlLanguageType) {
+		if lang == public_protocol_pbdesc.EnMutlLanguageType_EN_MUTL_LANGUAGE_NONE {
+			return
+		}
+		for _, exists := range ret {
+			if exists == lang {
+				return
+			}
+		}
+		ret = append(ret, lang)
+	}
+
+	appendUnique(preferred)
+	switch preferred {
+	case public_protocol_pbdesc.EnMutlLanguageType_EN_MUTL_LANGUAGE_ZH_HANS:
+		appendUnique(public_protocol_pbdesc.EnMutlLanguageType_EN_MUTL_LANGUAGE_ZH_HANT)
+	case public_protocol_pbdesc.EnMutlLanguageType_EN_MUTL_LANGUAGE_ZH_HANT:
+		appendUnique(public_protocol_pbdesc.EnMutlLanguageType_EN_MUTL_LANGUAGE_ZH_HANS)
+	}
+	appendUnique(serverDefault)
+	appendUnique(public_protocol_pbdesc.EnMutlLanguageType_EN_MUTL_LANGUAGE_EN)
+	return ret
+}
+
+// MailSelectLanguage 按回退链选择邮件的标题和正文
+// 依次查找默认语言(language)和 language_data，都找不到时使用默认的 title/content
+// 默认语言未标记时视为服务器默认语言
+func MailSelectLanguage(mail *public_protocol_pbdesc.DMailContent,
+	preferred, serverDefault public_protocol_pbdesc.EnMutlLanguageType,
+) (title string, content string, language int32) {
+	if mail == nil {
+		return "", "", 0
+	}
+
+	defaultLanguage := mail.GetLanguage()
+	if defaultLanguage == int32(public_protocol_pbdesc.EnMutlLanguageType_EN_MUTL_LANGUAGE_NONE) {
+		defaultLanguage = int32(serverDefault)
+	}
+
+	for _, lang := range mailLanguageFallbackChain(preferred, serverDefault) {
+		if defaultLanguage == int32(lang) {
+			return mail.GetTitle(), mail.GetContent(), defaultLanguage
+		}
+
+		for _, data := range mail.GetLanguageData() {
+			if data.GetLanguage() == int32(lang) {
+				return data.GetTitle(), data.GetContent(), data.GetLanguage()
+			}
+		}
+	}
+
+	return mail.GetTitle(), mail.GetContent(), defaultLanguage
+}
+
+// MailApplyLanguage 将邮件的标题和正文替换为选中的语言，并移除其他语言数据以减少下发量
+// 只能用于下发给客户端的副本
+func MailApplyLanguage(mail *public_protocol_pbdesc.DMailContent, preferred public_protocol_pbdesc.EnMutlLanguageType) {
+	if mail == nil || len(mail.GetLanguageData()) == 0 {
+		return
+	}
+
+	mail.Title, mail.Content, mail.Language = MailSelectLanguage(mail, preferred, MailGetServerLanguage())
+	mail.LanguageData = nil
+}
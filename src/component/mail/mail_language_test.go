@@ -0,0 +1,54 @@
+package atframework_component_mail
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+)
+
+func TestMailParseLanguage(t *testing.T) {
+	assert.Equal(t, public_protocol_pbdesc.EnMutlLanguageType_EN_MUTL_LANGUAGE_EN, MailParseLanguage("en-US"))
+	assert.Equal(t, public_protocol_pbdesc.EnMutlLanguageType_EN_MUTL_LANGUAGE_JA, MailParseLanguage("ja_JP"))
+	assert.Equal(t, public_protocol_pbdesc.EnMutlLanguageType_EN_MUTL_LANGUAGE_ZH_HANS, MailParseLanguage("zh-Hans-CN"))
+	assert.Equal(t, public_protocol_pbdesc.EnMutlLanguageType_EN_MUTL_LANGUAGE_ZH_HANS, MailParseLanguage("zh"))
+	assert.Equal(t, public_protocol_pbdesc.EnMutlLanguageType_EN_MUTL_LANGUAGE_ZH_HANT, MailParseLanguage("zh_TW"))
+	assert.Equal(t, public_protocol_pbdesc.EnMutlLanguageType_EN_MUTL_LANGUAGE_ZH_HANT, MailParseLanguage("zh-Hant-HK"))
+	assert.Equal(t, public_protocol_pbdesc.EnMutlLanguageType_EN_MUTL_LANGUAGE_NONE, MailParseLanguage(""))
+	assert.Equal(t, public_protocol_pbdesc.EnMutlLanguageType_EN_MUTL_LANGUAGE_NONE, MailParseLanguage("fr-FR"))
+}
+
+func TestMailSelectLanguage(t *testing.T) {
+	mail := &public_protocol_pbdesc.DMailContent{
+		Title:    "标题",
+		Content:  "正文",
+		Language: int32(public_protocol_pbdesc.EnMutlLanguageType_EN_MUTL_LANGUAGE_ZH_HANS),
+		LanguageData: []*public_protocol_pbdesc.DMailMultLanguage{
+			{Language: int32(public_protocol_pbdesc.EnMutlLanguageType_EN_MUTL_LANGUAGE_EN), Title: "Title", Content: "Content"},
+			{Language: int32(public_protocol_pbdesc.EnMutlLanguageType_EN_MUTL_LANGUAGE_ZH_HANT), Title: "標題", Content: "正文"},
+		},
+	}
+
+	// 命中偏好语言
+	title, _, language := MailSelectLanguage(mail, public_protocol_pbdesc.EnMutlLanguageType_EN_MUTL_LANGUAGE_EN,
+		public_protocol_pbdesc.EnMutlLanguageType_EN_MUTL_LANGUAGE_ZH_HANS)
+	assert.Equal(t, "Title", title)
+	assert.Equal(t, int32(public_protocol_pbdesc.EnMutlLanguageType_EN_MUTL_LANGUAGE_EN), language)
+
+	// 命中默认语言
+	title, _, _ = MailSelectLanguage(mail, public_protocol_pbdesc.EnMutlLanguageType_EN_MUTL_LANGUAGE_ZH_HANS,
+		public_protocol_pbdesc.EnMutlLanguageType_EN_MUTL_LANGUAGE_EN)
+	assert.Equal(t, "标题", title)
+
+	// 缺少日语时回退到服务器默认语言
+	title, _, _ = MailSelectLanguage(mail, public_protocol_pbdesc.EnMutlLanguageType_EN_MUTL_LANGUAGE_JA,
+		public_protocol_pbdesc.EnMutlLanguageType_EN_MUTL_LANGUAGE_ZH_HANT)
+	assert.Equal(t, "標題", title)
+
+	// 缺少繁体时优先回退到简体
+	mail.LanguageData = mail.LanguageData[:1]
+	title, _, _ = MailSelectLanguage(mail, public_protocol_pbdesc.EnMutlLanguageType_EN_MUTL_LANGUAGE_ZH_HANT,
+		public_protocol_pbdesc.EnMutlLanguageType_EN_MUTL_LANGUAGE_EN)
+	assert.Equal(t, "标题", title)
+}
@@ -98,6 +98,21 @@ func MailRenderTemplate(
 
 	mail.Title = templateConfig.GetTitle()
 	mail.Content = templateConfig.GetContent()
+	mail.Language = int32(MailGetServerLanguage())
+	mail.LanguageData = nil
+	for _, languageData := range templateConfig.GetLanguageData() {
+		if languageData.GetLanguage() == mail.Language {
+			mail.Title = languageData.GetTitle()
+			mail.Content = languageData.GetContent()
+			continue
+		}
+
+		mail.LanguageData = append(mail.LanguageData, &public_protocol_pbdesc.DMailMultLanguage{
+			Language: languageData.GetLanguage(),
+			Title:    languageData.GetTitle(),
+			Content:  languageData.GetContent(),
+		})
+	}
 
 	if extensions != nil {
 		if mail.MailTemplateExtensions == nil {
@@ -0,0 +1,16 @@
+package atframework_component_test_utility
+
+import (
+	"github.com/atframework/libatapp-go"
+
+	db "github.com/atframework/atsf4g-go/component/db"
+)
+
+// CreateMemoryStorageApp 创建挂载了内存存储后端的 app，不需要 Redis
+// 配合 MockRpcContext 使用时生成的 DatabaseTable* 接口直接读写内存
+func CreateMemoryStorageApp() (libatapp.AppImpl, db.StorageBackend) {
+	app := libatapp.CreateAppInstance()
+	backend := db.CreateMemoryStorageBackend("test")
+	libatapp.AtappAddModule(app, db.CreateStorageBackendModuleWithBackend(app, backend))
+	return app, backend
+}
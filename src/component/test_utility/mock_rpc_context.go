@@ -0,0 +1,123 @@
+// Copyright 2026 atframework
+// @brief 单元测试公共工具，只在 _test.go 中使用
+
+package atframework_component_test_utility
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/atframework/libatapp-go"
+
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	logical_time "github.com/atframework/atsf4g-go/component/logical_time"
+)
+
+// MockRpcContext 单元测试用的 RpcContext，同时实现 AwaitableContext
+// 内存存储后端的操作都是同步完成的，可以直接用它调用生成的 DatabaseTable* 接口
+type MockRpcContext struct {
+	app    libatapp.AppImpl
+	now    time.Time
+	action cd.TaskActionImpl
+}
+
+func init() {
+	var _ cd.AwaitableContext = (*MockRpcContext)(nil)
+}
+
+// NewMockRpcContext 创建测试用的 RpcContext，app 可以为 nil
+func NewMockRpcContext(app libatapp.AppImpl) *MockRpcContext {
+	return &MockRpcContext{
+		app: app,
+	}
+}
+
+// NewMockRpcContextWithNow 创建固定时间的 RpcContext
+func NewMockRpcContextWithNow(app libatapp.AppImpl, now time.Time) *MockRpcContext {
+	return &MockRpcContext{
+		app: app,
+		now: now,
+	}
+}
+
+// NewMockTaskRpcContext 创建绑定了任务的 RpcContext，用于测试需要挂起和恢复任务的接口（如 IOSingleFlight）
+// 每个 ctx 对应一个独立的任务，在不同的 goroutine 中使用可以模拟并发的任务，任务本身不会被调度执行
+// app 中没有 TaskManager 和 NoMessageDispatcher 时会自动注册，不能和其他 goroutine 并发调用
+func NewMockTaskRpcContext(app libatapp.AppImpl, now time.Time) *MockRpcContext {
+	if libatapp.AtappGetModule[*cd.TaskManager](app) == nil {
+		libatapp.AtappAddModule(app, cd.CreateTaskManager(app))
+	}
+	d := libatapp.AtappGetModule[*cd.NoMessageDispatcher](app)
+	if d == nil {
+		d = cd.CreateNoMessageDispatcher(app)
+		libatapp.AtappAddModule(app, d)
+	}
+
+	ctx := NewMockRpcContextWithNow(app, now)
+	task, startData := cd.CreateNoMessageTaskAction(d, ctx, nil,
+		func(rd cd.DispatcherImpl, actor *cd.ActorExecutor, timeout time.Duration) *mockTaskAction {
+			return &mockTaskAction{
+				TaskActionNoMessageBase: cd.CreateNoMessageTaskActionBase(rd, actor, timeout),
+			}
+		},
+	)
+	task.PrepareHookRun(&startData)
+	ctx.BindAction(task)
+	return ctx
+}
+
+// mockTaskAction NewMockTaskRpcContext 使用的任务，只提供任务ID和挂起恢复的能力
+type mockTaskAction struct {
+	cd.TaskActionNoMessageBase
+}
+
+func (t *mockTaskAction) Name() string {
+	return "MockTaskAction"
+}
+
+func (t *mockTaskAction) Run(_ *cd.DispatcherStartData) error {
+	return nil
+}
+
+// SetNow 设置固定时间，传入零值时恢复使用逻辑时间
+func (m *MockRpcContext) SetNow(now time.Time) {
+	m.now = now
+}
+
+// GetNow 没有设置固定时间时使用逻辑时间，和任务中的 ctx.GetNow() 一致受 GM 时间偏移影响
+func (m *MockRpcContext) GetNow() time.Time {
+	if !m.now.IsZero() {
+		return m.now
+	}
+	return logical_time.GetLogicalNow()
+}
+
+func (m *MockRpcContext) GetSysNow() time.Time {
+	if !m.now.IsZero() {
+		return m.now
+	}
+	return logical_time.GetSysNow()
+}
+
+func (m *MockRpcContext) Awaitable()                                                 {}
+func (m *MockRpcContext) GetApp() libatapp.AppImpl                                   { return m.app }
+func (m *MockRpcContext) GetAction() cd.TaskActionImpl                               { return m.action }
+func (m *MockRpcContext) BindAction(action cd.TaskActionImpl)                        { m.action = action }
+func (m *MockRpcContext) GetContext() context.Context                                { return context.Background() }
+func (m *MockRpcContext) GetCancelFn() context.CancelFunc                            { return nil }
+func (m *MockRpcContext) SetContext(_ context.Context)                               {}
+func (m *MockRpcContext) SetCancelFn(_ context.CancelFunc)                           {}
+func (m *MockRpcContext) SetContextCancelFn(_ context.Context, _ context.CancelFunc) {}
+
+func (m *MockRpcContext) LogWithLevelContextWithCaller(_ uintptr, _ context.Context, _ slog.Level, _ string, _ ...any) {
+}
+func (m *MockRpcContext) LogWithLevelWithCaller(_ uintptr, _ slog.Level, _ string, _ ...any) {}
+func (m *MockRpcContext) LogErrorContext(_ context.Context, _ string, _ ...any)              {}
+func (m *MockRpcContext) LogError(_ string, _ ...any)                                        {}
+func (m *MockRpcContext) LogWarnContext(_ context.Context, _ string, _ ...any)               {}
+func (m *MockRpcContext) LogWarn(_ string, _ ...any)                                         {}
+func (m *MockRpcContext) LogInfoContext(_ context.Context, _ string, _ ...any)               {}
+func (m *MockRpcContext) LogInfo(_ string, _ ...any)                                         {}
+func (m *MockRpcContext) LogDebugContext(_ context.Context, _ string, _ ...any)              {}
+func (m *MockRpcContext) LogDebug(_ string, _ ...any)                                        {}
@@ -262,6 +262,11 @@ func (p *UserRouterCache) SaveObject(ctx cd.AwaitableContext, _ router.RouterPri
 	}
 
 	// 锁处理完毕
+	// 到达快照间隔时随本次存盘生成快照
+	// 内存中的 LastSnapshotTime 等存盘成功后再更新，失败时下次存盘仍会生成快照
+	needSnapshot := shouldTakeAutoUserSnapshot(p.obj, ctx.GetSysNow())
+	snapshotTime := ctx.GetSysNow().Unix()
+
 	dstTb := &private_protocol_pbdesc.DatabaseTableUser{}
 	result := p.obj.DumpToDB(ctx, dstTb)
 	if result.IsError() {
@@ -269,6 +274,9 @@ func (p *UserRouterCache) SaveObject(ctx cd.AwaitableContext, _ router.RouterPri
 		result.LogError(ctx, "dump user to db failed")
 		return result
 	}
+	if needSnapshot {
+		dstTb.LastSnapshotTime = snapshotTime
+	}
 
 	userCASVersion := p.obj.GetUserCASVersion()
 	ctx.LogInfo("save user to db start", "cas_version", userCASVersion)
@@ -284,6 +292,9 @@ func (p *UserRouterCache) SaveObject(ctx cd.AwaitableContext, _ router.RouterPri
 			result.LogError(ctx, "dump user to db failed")
 			return result
 		}
+		if needSnapshot {
+			dstTb.LastSnapshotTime = snapshotTime
+		}
 		ctx.LogInfo("retry save user to db start ", "cas_version", userCASVersion)
 		userCASVersion = p.obj.GetUserCASVersion()
 		err = db.DatabaseTableUserReplaceZoneIdUserId(ctx, dstTb, &userCASVersion, false)
@@ -293,6 +304,12 @@ func (p *UserRouterCache) SaveObject(ctx cd.AwaitableContext, _ router.RouterPri
 	if !err.IsError() {
 		p.obj.OnSaved(ctx, userCASVersion)
 		result.LogInfo(ctx, "save user to db success")
+
+		if needSnapshot {
+			p.obj.SetLastSnapshotTime(snapshotTime)
+			// 快照失败不影响存盘结果
+			AddUserSnapshot(ctx, dstTb, userCASVersion, UserSnapshotReasonAuto)
+		}
 	} else {
 		err.LogError(ctx, "save user to db failed", "err", err)
 	}
@@ -121,3 +121,26 @@ func (sm *SessionManager) RemoveSession(ctx cd.RpcContext, key *SessionKey, reas
 
 	// TODO: 移除session超时检查
 }
+
+// ForeachSession 遍历所有连接，回调返回 false 时停止遍历
+// 遍历的是调用时的快照，回调中可以创建或移除连接
+func (sm *SessionManager) ForeachSession(fn func(session *Session) bool) {
+	if fn == nil {
+		return
+	}
+
+	sm.sessionLock.RLock()
+	sessions := make([]*Session, 0, len(sm.sessions))
+	for _, nodeSessions := range sm.sessions {
+		for _, session := range nodeSessions {
+			sessions = append(sessions, session)
+		}
+	}
+	sm.sessionLock.RUnlock()
+
+	for _, session := range sessions {
+		if !fn(session) {
+			return
+		}
+	}
+}
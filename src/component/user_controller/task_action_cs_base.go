@@ -74,6 +74,20 @@ func CreateCSTaskActionBase[RequestType proto.Message, ResponseType proto.Messag
 	}
 }
 
+// CreateCSTaskActionBaseWithUser 不经过派发器和会话直接绑定玩家创建，用于单元测试直接调用 Run
+func CreateCSTaskActionBaseWithUser[RequestType proto.Message, ResponseType proto.Message](
+	user UserImpl,
+	requestBody RequestType,
+	responseFactory func() ResponseType,
+) TaskActionCSBase[RequestType, ResponseType] {
+	ret := TaskActionCSBase[RequestType, ResponseType]{
+		requestBody:     requestBody,
+		responseFactory: responseFactory,
+	}
+	ret.SetUser(user)
+	return ret
+}
+
 func (t *TaskActionCSBase[RequestType, ResponseType]) SetUser(user UserImpl) {
 	if lu.IsNil(user) {
 		t.user = nil
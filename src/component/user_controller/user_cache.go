@@ -69,6 +69,9 @@ type UserImpl interface {
 	GetLoginLockCASVersion() uint64
 	SetLoginLockCASVersion(CASVersion uint64)
 
+	GetLastSnapshotTime() int64
+	SetLastSnapshotTime(t int64)
+
 	SendUserOssLog(ctx cd.RpcContext, ossLog *private_protocol_log.OperationSupportSystemLog)
 
 	OnCsTaskActionStart(ctx cd.RpcContext, taskId uint64)
@@ -147,6 +150,8 @@ type UserCache struct {
 	loginLockCASVersion uint64
 	userCASVersion      uint64
 
+	lastSnapshotTime int64
+
 	csProtocolFrequencyLimit map[string]*CSProtocolFrequencyRingBuffer
 	runningCsTask            map[uint64]struct{}
 }
@@ -367,8 +372,7 @@ func (u *UserCache) InitFromDB(_ctx cd.RpcContext, srcTb *private_protocol_pbdes
 	}
 
 	u.hasCreateInit = srcTb.GetCreateInit()
-
-	// TODO: 数据版本升级 u.dataVersion -> UserDataCurrentVersion
+	u.lastSnapshotTime = srcTb.GetLastSnapshotTime()
 
 	return cd.CreateRpcResultOk()
 }
@@ -395,6 +399,7 @@ func (u *UserCache) DumpToDB(ctx cd.RpcContext, dstTb *private_protocol_pbdesc.D
 	dstTb.LoginData = u.loginData.Get()
 	dstTb.MutableUserData().SessionSequence = u.sessionSequence
 	dstTb.CreateInit = u.hasCreateInit
+	dstTb.LastSnapshotTime = u.lastSnapshotTime
 
 	return cd.CreateRpcResultOk()
 }
@@ -604,6 +609,22 @@ func (u *UserCache) SetUserCASVersion(version uint64) {
 	u.userCASVersion = version
 }
 
+func (u *UserCache) GetLastSnapshotTime() int64 {
+	if u == nil {
+		return 0
+	}
+
+	return u.lastSnapshotTime
+}
+
+func (u *UserCache) SetLastSnapshotTime(t int64) {
+	if u == nil {
+		return
+	}
+
+	u.lastSnapshotTime = t
+}
+
 func (u *UserCache) IsNewUser() bool {
 	if u == nil {
 		return false
@@ -161,6 +161,23 @@ func (um *UserManager) Save(ctx cd.AwaitableContext, checkUser UserImpl) cd.RpcR
 	return cache.Save(ctx)
 }
 
+// MarkFastSave 不在 CS 请求中修改了玩家数据时调用，尽快保存而不是等到定时保存
+func (um *UserManager) MarkFastSave(ctx cd.RpcContext, checkUser UserImpl) {
+	if lu.IsNil(checkUser) {
+		return
+	}
+	routerManager := GetUserRouterManager(um.GetApp())
+	cache := routerManager.GetCache(router.RouterObjectKey{
+		TypeID:   uint32(public_protocol_pbdesc.EnRouterObjectType_EN_ROT_PLAYER),
+		ZoneID:   checkUser.GetZoneId(),
+		ObjectID: checkUser.GetUserId(),
+	})
+	if lu.IsNil(cache) || !cache.IsWritable() || cache.GetUserImpl() != checkUser {
+		return
+	}
+	libatapp.AtappGetModule[*router.RouterManagerSet](um.GetApp()).MarkFastSave(ctx, routerManager, cache)
+}
+
 func UserManagerFindUserAs[T UserImpl](ctx cd.RpcContext, app libatapp.AppImpl, zoneID uint32, userID uint64) T {
 	um := libatapp.AtappGetModule[*UserManager](app)
 
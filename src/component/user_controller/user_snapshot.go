@@ -0,0 +1,447 @@
+package atframework_component_user_controller
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	config "github.com/atframework/atsf4g-go/component/config"
+	db "github.com/atframework/atsf4g-go/component/db"
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	libatapp "github.com/atframework/libatapp-go"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// UserSnapshotReasonAuto 保存玩家数据时自动生成
+	UserSnapshotReasonAuto = "auto"
+	// UserSnapshotReasonGM GM 手动生成
+	UserSnapshotReasonGM = "gm"
+	// UserSnapshotReasonBeforeRestore 回档前备份当前数据
+	UserSnapshotReasonBeforeRestore = "before_restore"
+
+	// 和 database_table_user_snapshot 的 max_list_length 保持一致
+	userSnapshotMaxListLength = 64
+)
+
+// UserSnapshotRecord 快照列表中的一条记录
+type UserSnapshotRecord struct {
+	ListIndex uint64
+	Data      *private_protocol_pbdesc.UserSnapshotBlobData
+}
+
+// EncodeUserSnapshot 压缩玩家数据生成快照
+func EncodeUserSnapshot(userTb *private_protocol_pbdesc.DatabaseTableUser, casVersion uint64, reason string, now time.Time) (*private_protocol_pbdesc.UserSnapshotBlobData, error) {
+	raw, err := proto.Marshal(userTb)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err = writer.Write(raw); err != nil {
+		return nil, err
+	}
+	if err = writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return &private_protocol_pbdesc.UserSnapshotBlobData{
+		SnapshotTime:   now.Unix(),
+		UserCasVersion: casVersion,
+		Reason:         reason,
+		RawSize:        uint64(len(raw)),
+		CompressedData: buf.Bytes(),
+	}, nil
+}
+
+// DecodeUserSnapshot 解压快照中的玩家数据
+func DecodeUserSnapshot(snapshot *private_protocol_pbdesc.UserSnapshotBlobData) (*private_protocol_pbdesc.DatabaseTableUser, error) {
+	if snapshot == nil || len(snapshot.GetCompressedData()) == 0 {
+		return nil, fmt.Errorf("empty snapshot")
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(snapshot.GetCompressedData()))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	raw, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &private_protocol_pbdesc.DatabaseTableUser{}
+	if err = proto.Unmarshal(raw, ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// DiffUserSnapshot 按 database_table_user 的顶层字段对比两份数据，返回有差异的字段描述
+func DiffUserSnapshot(from *private_protocol_pbdesc.DatabaseTableUser, to *private_protocol_pbdesc.DatabaseTableUser) []string {
+	fromReflect := from.ProtoReflect()
+	toReflect := to.ProtoReflect()
+	fields := fromReflect.Descriptor().Fields()
+
+	ret := make([]string, 0)
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		hasFrom := fromReflect.Has(fd)
+		hasTo := toReflect.Has(fd)
+		if !hasFrom && !hasTo {
+			continue
+		}
+		if hasFrom && hasTo && fromReflect.Get(fd).Equal(toReflect.Get(fd)) {
+			continue
+		}
+
+		if fd.Message() != nil && !fd.IsList() && !fd.IsMap() {
+			fromSize := 0
+			toSize := 0
+			if hasFrom {
+				fromSize = proto.Size(fromReflect.Get(fd).Message().Interface())
+			}
+			if hasTo {
+				toSize = proto.Size(toReflect.Get(fd).Message().Interface())
+			}
+			ret = append(ret, fmt.Sprintf("%s: changed, size %d => %d", fd.Name(), fromSize, toSize))
+		} else {
+			ret = append(ret, fmt.Sprintf("%s: %v => %v", fd.Name(), fromReflect.Get(fd), toReflect.Get(fd)))
+		}
+	}
+	return ret
+}
+
+func getUserSnapshotMaxCount() int {
+	maxCount := int(config.GetConfigManager().GetCurrentConfigGroup().GetSectionConfig().GetUser().GetSnapshot().GetMaxCount())
+	if maxCount <= 0 || maxCount > userSnapshotMaxListLength {
+		maxCount = userSnapshotMaxListLength
+	}
+	return maxCount
+}
+
+// shouldTakeAutoUserSnapshot 检查保存时是否需要生成自动快照
+func shouldTakeAutoUserSnapshot(user UserImpl, now time.Time) bool {
+	snapshotCfg := config.GetConfigManager().GetCurrentConfigGroup().GetSectionConfig().GetUser().GetSnapshot()
+	if !snapshotCfg.GetEnable() || !user.HasCreateInit() {
+		return false
+	}
+
+	return now.Unix()-user.GetLastSnapshotTime() >= int64(snapshotCfg.GetInterval().AsDuration().Seconds())
+}
+
+// AddUserSnapshot 写入一条快照，并按数量和时间清理旧快照
+func AddUserSnapshot(ctx cd.AwaitableContext, userTb *private_protocol_pbdesc.DatabaseTableUser, casVersion uint64, reason string) cd.RpcResult {
+	snapshot, err := EncodeUserSnapshot(userTb, casVersion, reason, ctx.GetSysNow())
+	if err != nil {
+		ctx.LogError("encode user snapshot failed", "zone_id", userTb.GetZoneId(), "user_id", userTb.GetUserId(), "error", err)
+		return cd.CreateRpcResultError(err, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM_BAD_PACKAGE)
+	}
+
+	return ImportUserSnapshot(ctx, userTb.GetZoneId(), userTb.GetUserId(), snapshot)
+}
+
+// ImportUserSnapshot 写入一条已经编码好的快照（转区搬迁等），并按数量和时间清理旧快照
+func ImportUserSnapshot(ctx cd.AwaitableContext, zoneId uint32, userId uint64, snapshot *private_protocol_pbdesc.UserSnapshotBlobData) cd.RpcResult {
+	result, listIndex := db.DatabaseTableUserSnapshotAddZoneIdUserId(ctx, &private_protocol_pbdesc.DatabaseTableUserSnapshot{
+		ZoneId:       zoneId,
+		UserId:       userId,
+		SnapshotData: snapshot,
+	})
+	if result.IsError() {
+		result.LogError(ctx, "add user snapshot failed", "zone_id", zoneId, "user_id", userId)
+		return result
+	}
+
+	ctx.LogInfo("add user snapshot success", "zone_id", zoneId, "user_id", userId,
+		"list_index", listIndex, "reason", snapshot.GetReason(), "cas_version", snapshot.GetUserCasVersion(),
+		"raw_size", snapshot.GetRawSize(), "compressed_size", len(snapshot.GetCompressedData()))
+
+	// 索引写入失败时快照本身已经落地，不影响结果，多出来的快照会在超过 max_list_length 时被自动淘汰
+	cleanupUserSnapshots(ctx, zoneId, userId, &private_protocol_pbdesc.UserSnapshotIndexItem{
+		ListIndex:    listIndex,
+		SnapshotTime: snapshot.GetSnapshotTime(),
+	})
+	return cd.CreateRpcResultOk()
+}
+
+// loadUserSnapshotIndex 读取快照时间索引，索引不存在时（旧数据）从完整的快照列表重建
+func loadUserSnapshotIndex(ctx cd.AwaitableContext, zoneId uint32, userId uint64) (*private_protocol_pbdesc.DatabaseTableUserSnapshotIndex, uint64, cd.RpcResult) {
+	indexTb, casVersion, result := db.DatabaseTableUserSnapshotIndexLoadWithZoneIdUserId(ctx, zoneId, userId)
+	if result.IsOK() {
+		return indexTb, casVersion, result
+	}
+	if result.GetResponseCode() != int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND) {
+		return nil, 0, result
+	}
+
+	records, result := ListUserSnapshots(ctx, zoneId, userId)
+	if result.IsError() {
+		return nil, 0, result
+	}
+	indexTb = &private_protocol_pbdesc.DatabaseTableUserSnapshotIndex{
+		ZoneId: zoneId,
+		UserId: userId,
+		Items:  make([]*private_protocol_pbdesc.UserSnapshotIndexItem, 0, len(records)),
+	}
+	for _, record := range records {
+		indexTb.Items = append(indexTb.Items, &private_protocol_pbdesc.UserSnapshotIndexItem{
+			ListIndex:    record.ListIndex,
+			SnapshotTime: record.Data.GetSnapshotTime(),
+		})
+	}
+	return indexTb, 0, cd.CreateRpcResultOk()
+}
+
+// selectRemovedUserSnapshots 按 list_index 从旧到新排序后，返回需要保留和需要删除的快照
+// 最新的一条始终保留
+func selectRemovedUserSnapshots(items []*private_protocol_pbdesc.UserSnapshotIndexItem, maxCount int,
+	expiredBefore int64,
+) ([]*private_protocol_pbdesc.UserSnapshotIndexItem, []uint64) {
+	slices.SortFunc(items, func(a, b *private_protocol_pbdesc.UserSnapshotIndexItem) int {
+		if a.GetListIndex() < b.GetListIndex() {
+			return -1
+		}
+		if a.GetListIndex() > b.GetListIndex() {
+			return 1
+		}
+		return 0
+	})
+
+	keep := make([]*private_protocol_pbdesc.UserSnapshotIndexItem, 0, len(items))
+	removeIndexes := make([]uint64, 0)
+	for i, item := range items {
+		if i != len(items)-1 && (len(items)-i > maxCount || item.GetSnapshotTime() < expiredBefore) {
+			removeIndexes = append(removeIndexes, item.GetListIndex())
+		} else {
+			keep = append(keep, item)
+		}
+	}
+	return keep, removeIndexes
+}
+
+// cleanupUserSnapshots 只读写时间索引来决定要清理哪些快照，不加载完整的快照数据
+func cleanupUserSnapshots(ctx cd.AwaitableContext, zoneId uint32, userId uint64, added *private_protocol_pbdesc.UserSnapshotIndexItem) {
+	maxCount := getUserSnapshotMaxCount()
+	maxAge := config.GetConfigManager().GetCurrentConfigGroup().GetSectionConfig().GetUser().GetSnapshot().GetMaxAge().AsDuration()
+	expiredBefore := int64(0)
+	if maxAge > 0 {
+		expiredBefore = ctx.GetSysNow().Add(-maxAge).Unix()
+	}
+
+	var removeIndexes []uint64
+	result := cd.CreateRpcResultOk()
+	// CAS 冲突时重试一次
+	for retry := 0; retry < 2; retry++ {
+		var indexTb *private_protocol_pbdesc.DatabaseTableUserSnapshotIndex
+		var casVersion uint64
+		indexTb, casVersion, result = loadUserSnapshotIndex(ctx, zoneId, userId)
+		if result.IsError() {
+			break
+		}
+
+		if added != nil && !slices.ContainsFunc(indexTb.GetItems(), func(item *private_protocol_pbdesc.UserSnapshotIndexItem) bool {
+			return item.GetListIndex() == added.GetListIndex()
+		}) {
+			indexTb.Items = append(indexTb.Items, added)
+		}
+		indexTb.Items, removeIndexes = selectRemovedUserSnapshots(indexTb.GetItems(), maxCount, expiredBefore)
+
+		result = db.DatabaseTableUserSnapshotIndexReplaceZoneIdUserId(ctx, indexTb, &casVersion, false)
+		if result.GetResponseCode() != int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_CAS_CHECK_FAILED) {
+			break
+		}
+	}
+	if result.IsError() {
+		result.LogError(ctx, "update user snapshot index failed", "zone_id", zoneId, "user_id", userId)
+		return
+	}
+
+	if len(removeIndexes) == 0 {
+		return
+	}
+
+	result = db.DatabaseTableUserSnapshotDelIndexWithZoneIdUserId(ctx, removeIndexes, zoneId, userId)
+	if result.IsError() {
+		result.LogError(ctx, "cleanup user snapshot failed", "zone_id", zoneId, "user_id", userId)
+	}
+}
+
+// RemoveAllUserSnapshots 删除玩家的全部快照和时间索引，返回删除的快照数量
+func RemoveAllUserSnapshots(ctx cd.AwaitableContext, zoneId uint32, userId uint64) (int32, cd.RpcResult) {
+	indexTb, _, result := loadUserSnapshotIndex(ctx, zoneId, userId)
+	if result.IsError() {
+		return 0, result
+	}
+
+	result = db.DatabaseTableUserSnapshotDelWithZoneIdUserId(ctx, zoneId, userId)
+	if result.IsError() && result.GetResponseCode() != int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND) {
+		return 0, result
+	}
+	result = db.DatabaseTableUserSnapshotIndexDelWithZoneIdUserId(ctx, zoneId, userId)
+	if result.IsError() && result.GetResponseCode() != int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND) {
+		return 0, result
+	}
+	return int32(len(indexTb.GetItems())), cd.CreateRpcResultOk()
+}
+
+// ListUserSnapshots 按时间从旧到新列出玩家的快照
+func ListUserSnapshots(ctx cd.AwaitableContext, zoneId uint32, userId uint64) ([]*UserSnapshotRecord, cd.RpcResult) {
+	indexMessages, result := db.DatabaseTableUserSnapshotLoadAllWithZoneIdUserId(ctx, zoneId, userId)
+	if result.IsError() {
+		if result.GetResponseCode() == int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND) {
+			return nil, cd.CreateRpcResultOk()
+		}
+		return nil, result
+	}
+
+	ret := make([]*UserSnapshotRecord, 0, len(indexMessages))
+	for _, indexMessage := range indexMessages {
+		table, ok := indexMessage.Table.(*private_protocol_pbdesc.DatabaseTableUserSnapshot)
+		if !ok || table.GetSnapshotData() == nil {
+			continue
+		}
+		ret = append(ret, &UserSnapshotRecord{
+			ListIndex: indexMessage.ListIndex,
+			Data:      table.GetSnapshotData(),
+		})
+	}
+
+	slices.SortFunc(ret, func(a, b *UserSnapshotRecord) int {
+		if a.ListIndex < b.ListIndex {
+			return -1
+		}
+		if a.ListIndex > b.ListIndex {
+			return 1
+		}
+		return 0
+	})
+	return ret, cd.CreateRpcResultOk()
+}
+
+// LoadUserSnapshot 读取并解压指定快照
+func LoadUserSnapshot(ctx cd.AwaitableContext, zoneId uint32, userId uint64, listIndex uint64) (*private_protocol_pbdesc.DatabaseTableUser, *private_protocol_pbdesc.UserSnapshotBlobData, cd.RpcResult) {
+	indexMessages, result := db.DatabaseTableUserSnapshotLoadIndexWithZoneIdUserId(ctx, []uint64{listIndex}, zoneId, userId)
+	if result.IsError() {
+		return nil, nil, result
+	}
+
+	for _, indexMessage := range indexMessages {
+		table, ok := indexMessage.Table.(*private_protocol_pbdesc.DatabaseTableUserSnapshot)
+		if !ok || indexMessage.ListIndex != listIndex || table.GetSnapshotData() == nil {
+			continue
+		}
+
+		userTb, err := DecodeUserSnapshot(table.GetSnapshotData())
+		if err != nil {
+			ctx.LogError("decode user snapshot failed", "zone_id", zoneId, "user_id", userId, "list_index", listIndex, "error", err)
+			return nil, nil, cd.CreateRpcResultError(err, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM_BAD_PACKAGE)
+		}
+		return userTb, table.GetSnapshotData(), cd.CreateRpcResultOk()
+	}
+
+	return nil, nil, cd.CreateRpcResultError(fmt.Errorf("snapshot %d not found", listIndex), public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND)
+}
+
+// RestoreUserSnapshot 把玩家数据回档到指定快照
+// 先踢掉本进程内的玩家，再占用登入锁后覆盖 user 表，回档前会把当前数据备份成一条新快照
+// 玩家在其他进程在线时返回 EN_ERR_ALREADY_LOGIN
+// 需要在目标玩家的 ActorExecutor 中执行
+func RestoreUserSnapshot(ctx cd.AwaitableContext, zoneId uint32, userId uint64, listIndex uint64) cd.RpcResult {
+	restoreTb, snapshot, result := LoadUserSnapshot(ctx, zoneId, userId, listIndex)
+	if result.IsError() {
+		return result
+	}
+
+	// 踢下线，会先走一次正常的存盘和登出流程
+	result = libatapp.AtappGetModule[*UserManager](ctx.GetApp()).Remove(ctx, zoneId, userId, nil, true)
+	if result.IsError() {
+		result.LogError(ctx, "kickoff user before restore snapshot failed", "zone_id", zoneId, "user_id", userId)
+		return result
+	}
+
+	// 占用登入锁，防止回档期间登入或者旧进程继续存盘
+	loginLockTb, loginLockCASVersion, result := db.DatabaseTableLoginLockLoadWithUserId(ctx, userId)
+	if result.IsError() {
+		if result.GetResponseCode() != int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND) {
+			return result
+		}
+		loginLockTb = &private_protocol_pbdesc.DatabaseTableLoginLock{UserId: userId}
+		loginLockCASVersion = 0
+	}
+
+	selfServerId := uint64(config.GetConfigManager().GetLogicId())
+	if loginLockTb.GetRouterServerId() != 0 && loginLockTb.GetRouterServerId() != selfServerId &&
+		ctx.GetSysNow().Unix() < loginLockTb.GetLoginExpired() {
+		ctx.LogWarn("user is online on other server, can not restore snapshot", "zone_id", zoneId, "user_id", userId,
+			"router_server_id", loginLockTb.GetRouterServerId())
+		return cd.CreateRpcResultError(fmt.Errorf("user is online on other server"), public_protocol_pbdesc.EnErrorCode_EN_ERR_ALREADY_LOGIN)
+	}
+
+	loginLockTb.RouterServerId = selfServerId
+	loginLockTb.RouterVersion = loginLockTb.GetRouterVersion() + 1
+	loginLockTb.LoginZoneId = zoneId
+	loginLockTb.LoginExpired = ctx.GetSysNow().Unix() +
+		config.GetConfigManager().GetCurrentConfigGroup().GetSectionConfig().GetSession().GetLoginCodeValidSec().GetSeconds()
+	result = db.DatabaseTableLoginLockReplaceUserId(ctx, loginLockTb, &loginLockCASVersion, false)
+	if result.IsError() {
+		result.LogError(ctx, "lock login lock before restore snapshot failed", "zone_id", zoneId, "user_id", userId)
+		return result
+	}
+
+	result = restoreUserTable(ctx, zoneId, userId, restoreTb)
+
+	// 释放登入锁
+	loginLockTb.RouterServerId = 0
+	loginLockTb.RouterVersion = loginLockTb.GetRouterVersion() + 1
+	loginLockTb.LoginExpired = 0
+	loginLockTb.ExpectTableUserDbVersion = 0
+	// 回档已经完成，释放失败时登入锁会在 LoginExpired 后过期，不影响回档结果
+	unlockResult := db.DatabaseTableLoginLockReplaceUserId(ctx, loginLockTb, &loginLockCASVersion, false)
+	if unlockResult.IsError() {
+		unlockResult.LogError(ctx, "unlock login lock after restore snapshot failed", "zone_id", zoneId, "user_id", userId)
+	}
+
+	if result.IsError() {
+		return result
+	}
+
+	ctx.LogInfo("restore user snapshot success", "zone_id", zoneId, "user_id", userId, "list_index", listIndex,
+		"snapshot_time", snapshot.GetSnapshotTime(), "snapshot_cas_version", snapshot.GetUserCasVersion())
+	return cd.CreateRpcResultOk()
+}
+
+func restoreUserTable(ctx cd.AwaitableContext, zoneId uint32, userId uint64, restoreTb *private_protocol_pbdesc.DatabaseTableUser) cd.RpcResult {
+	currentTb, userCASVersion, result := db.DatabaseTableUserLoadWithZoneIdUserId(ctx, zoneId, userId)
+	if result.IsError() {
+		if result.GetResponseCode() != int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND) {
+			return result
+		}
+		currentTb = nil
+		userCASVersion = 0
+	}
+
+	if currentTb != nil {
+		result = AddUserSnapshot(ctx, currentTb, userCASVersion, UserSnapshotReasonBeforeRestore)
+		if result.IsError() {
+			return result
+		}
+		restoreTb.OpenId = currentTb.GetOpenId()
+	}
+
+	restoreTb.ZoneId = zoneId
+	restoreTb.UserId = userId
+	restoreTb.LastSnapshotTime = ctx.GetSysNow().Unix()
+	result = db.DatabaseTableUserReplaceZoneIdUserId(ctx, restoreTb, &userCASVersion, false)
+	if result.IsError() {
+		result.LogError(ctx, "replace user table with snapshot failed", "zone_id", zoneId, "user_id", userId)
+	}
+	return result
+}
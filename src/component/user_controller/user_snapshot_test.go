@@ -0,0 +1,239 @@
+package atframework_component_user_controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	db "github.com/atframework/atsf4g-go/component/db"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	test_utility "github.com/atframework/atsf4g-go/component/test_utility"
+)
+
+const (
+	testSnapshotZoneId uint32 = 1
+	testSnapshotUserId uint64 = 20001
+)
+
+func createTestSnapshotUserTable(level uint32) *private_protocol_pbdesc.DatabaseTableUser {
+	return &private_protocol_pbdesc.DatabaseTableUser{
+		ZoneId: testSnapshotZoneId,
+		UserId: testSnapshotUserId,
+		OpenId: "snapshot-open-id",
+		UserData: &private_protocol_pbdesc.UserData{
+			UserLevel: level,
+		},
+	}
+}
+
+func loadTestSnapshotIndex(t *testing.T, ctx *test_utility.MockRpcContext) []*private_protocol_pbdesc.UserSnapshotIndexItem {
+	indexTb, _, result := db.DatabaseTableUserSnapshotIndexLoadWithZoneIdUserId(ctx, testSnapshotZoneId, testSnapshotUserId)
+	assert.False(t, result.IsError())
+	return indexTb.GetItems()
+}
+
+// TestAddUserSnapshotAndLoad 测试写入快照后可以按 list_index 读回
+// Scenario: 写入一条快照后，列表、时间索引和解压后的数据都和写入时一致
+func TestAddUserSnapshotAndLoad(t *testing.T) {
+	// Arrange
+	app, _ := test_utility.CreateMemoryStorageApp()
+	now := time.Unix(1700000000, 0)
+	ctx := test_utility.NewMockRpcContextWithNow(app, now)
+	userTb := createTestSnapshotUserTable(10)
+
+	// Act
+	addResult := AddUserSnapshot(ctx, userTb, 3, UserSnapshotReasonGM)
+	records, listResult := ListUserSnapshots(ctx, testSnapshotZoneId, testSnapshotUserId)
+	indexItems := loadTestSnapshotIndex(t, ctx)
+
+	// Assert
+	assert.False(t, addResult.IsError())
+	assert.False(t, listResult.IsError())
+	assert.Len(t, records, 1)
+	assert.Len(t, indexItems, 1)
+	assert.Equal(t, records[0].ListIndex, indexItems[0].GetListIndex())
+	assert.Equal(t, now.Unix(), indexItems[0].GetSnapshotTime())
+
+	loadedTb, snapshot, loadResult := LoadUserSnapshot(ctx, testSnapshotZoneId, testSnapshotUserId, records[0].ListIndex)
+	assert.False(t, loadResult.IsError())
+	assert.Equal(t, uint64(3), snapshot.GetUserCasVersion())
+	assert.Equal(t, UserSnapshotReasonGM, snapshot.GetReason())
+	assert.Equal(t, uint32(10), loadedTb.GetUserData().GetUserLevel())
+}
+
+// TestLoadUserSnapshotNotFound 测试读取不存在的快照
+// Scenario: list_index 不存在时返回 EN_ERR_DB_RECORD_NOT_FOUND
+func TestLoadUserSnapshotNotFound(t *testing.T) {
+	// Arrange
+	app, _ := test_utility.CreateMemoryStorageApp()
+	ctx := test_utility.NewMockRpcContext(app)
+	AddUserSnapshot(ctx, createTestSnapshotUserTable(1), 1, UserSnapshotReasonAuto)
+
+	// Act
+	_, _, result := LoadUserSnapshot(ctx, testSnapshotZoneId, testSnapshotUserId, 999)
+
+	// Assert
+	assert.True(t, result.IsError())
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND), result.GetResponseCode())
+}
+
+// TestAddUserSnapshotRetentionByCount 测试按数量清理旧快照
+// Scenario: 写入超过上限的快照后，只保留最新的 userSnapshotMaxListLength 条，索引和快照列表保持一致
+func TestAddUserSnapshotRetentionByCount(t *testing.T) {
+	// Arrange
+	app, _ := test_utility.CreateMemoryStorageApp()
+	now := time.Unix(1700000000, 0)
+	ctx := test_utility.NewMockRpcContextWithNow(app, now)
+	total := userSnapshotMaxListLength + 3
+
+	// Act
+	for i := 0; i < total; i++ {
+		ctx.SetNow(now.Add(time.Duration(i) * time.Minute))
+		AddUserSnapshot(ctx, createTestSnapshotUserTable(uint32(i+1)), uint64(i+1), UserSnapshotReasonAuto)
+	}
+	records, listResult := ListUserSnapshots(ctx, testSnapshotZoneId, testSnapshotUserId)
+	indexItems := loadTestSnapshotIndex(t, ctx)
+
+	// Assert
+	assert.False(t, listResult.IsError())
+	assert.Len(t, records, userSnapshotMaxListLength)
+	assert.Len(t, indexItems, userSnapshotMaxListLength)
+	assert.Equal(t, uint64(4), records[0].Data.GetUserCasVersion())
+	assert.Equal(t, uint64(total), records[len(records)-1].Data.GetUserCasVersion())
+	for i, record := range records {
+		assert.Equal(t, record.ListIndex, indexItems[i].GetListIndex())
+	}
+}
+
+// TestSelectRemovedUserSnapshots 测试清理规则
+// Scenario: 超过数量上限或者过期的快照被删除，最新的一条即使过期也保留
+func TestSelectRemovedUserSnapshots(t *testing.T) {
+	testCases := []struct {
+		name          string
+		items         []*private_protocol_pbdesc.UserSnapshotIndexItem
+		maxCount      int
+		expiredBefore int64
+		expectKeep    []uint64
+		expectRemove  []uint64
+	}{
+		{
+			name: "under limit",
+			items: []*private_protocol_pbdesc.UserSnapshotIndexItem{
+				{ListIndex: 1, SnapshotTime: 100},
+				{ListIndex: 2, SnapshotTime: 200},
+			},
+			maxCount:     3,
+			expectKeep:   []uint64{1, 2},
+			expectRemove: []uint64{},
+		},
+		{
+			name: "over count, unordered input",
+			items: []*private_protocol_pbdesc.UserSnapshotIndexItem{
+				{ListIndex: 3, SnapshotTime: 300},
+				{ListIndex: 1, SnapshotTime: 100},
+				{ListIndex: 2, SnapshotTime: 200},
+			},
+			maxCount:     2,
+			expectKeep:   []uint64{2, 3},
+			expectRemove: []uint64{1},
+		},
+		{
+			name: "expired",
+			items: []*private_protocol_pbdesc.UserSnapshotIndexItem{
+				{ListIndex: 1, SnapshotTime: 100},
+				{ListIndex: 2, SnapshotTime: 200},
+				{ListIndex: 3, SnapshotTime: 300},
+			},
+			maxCount:      10,
+			expiredBefore: 250,
+			expectKeep:    []uint64{3},
+			expectRemove:  []uint64{1, 2},
+		},
+		{
+			name: "latest kept even if expired",
+			items: []*private_protocol_pbdesc.UserSnapshotIndexItem{
+				{ListIndex: 1, SnapshotTime: 100},
+				{ListIndex: 2, SnapshotTime: 200},
+			},
+			maxCount:      10,
+			expiredBefore: 1000,
+			expectKeep:    []uint64{2},
+			expectRemove:  []uint64{1},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			keep, remove := selectRemovedUserSnapshots(tc.items, tc.maxCount, tc.expiredBefore)
+
+			// Assert
+			keepIndexes := make([]uint64, 0, len(keep))
+			for _, item := range keep {
+				keepIndexes = append(keepIndexes, item.GetListIndex())
+			}
+			assert.Equal(t, tc.expectKeep, keepIndexes)
+			assert.Equal(t, tc.expectRemove, remove)
+		})
+	}
+}
+
+// TestCleanupUserSnapshotsRebuildIndex 测试旧数据没有时间索引时重建
+// Scenario: 只有快照列表没有索引时，清理会从快照列表重建索引
+func TestCleanupUserSnapshotsRebuildIndex(t *testing.T) {
+	// Arrange
+	app, _ := test_utility.CreateMemoryStorageApp()
+	now := time.Unix(1700000000, 0)
+	ctx := test_utility.NewMockRpcContextWithNow(app, now)
+	snapshot, err := EncodeUserSnapshot(createTestSnapshotUserTable(5), 7, UserSnapshotReasonAuto, now)
+	assert.NoError(t, err)
+	addResult, listIndex := db.DatabaseTableUserSnapshotAddZoneIdUserId(ctx, &private_protocol_pbdesc.DatabaseTableUserSnapshot{
+		ZoneId:       testSnapshotZoneId,
+		UserId:       testSnapshotUserId,
+		SnapshotData: snapshot,
+	})
+	assert.False(t, addResult.IsError())
+
+	// Act
+	cleanupUserSnapshots(ctx, testSnapshotZoneId, testSnapshotUserId, nil)
+	indexItems := loadTestSnapshotIndex(t, ctx)
+
+	// Assert
+	assert.Len(t, indexItems, 1)
+	assert.Equal(t, listIndex, indexItems[0].GetListIndex())
+	assert.Equal(t, now.Unix(), indexItems[0].GetSnapshotTime())
+}
+
+// TestRestoreUserTable 测试回档覆盖 user 表
+// Scenario: 回档前当前数据被备份成一条新快照，user 表被快照数据覆盖并保留 open_id
+func TestRestoreUserTable(t *testing.T) {
+	// Arrange
+	app, _ := test_utility.CreateMemoryStorageApp()
+	now := time.Unix(1700000000, 0)
+	ctx := test_utility.NewMockRpcContextWithNow(app, now)
+	var casVersion uint64
+	saveResult := db.DatabaseTableUserReplaceZoneIdUserId(ctx, createTestSnapshotUserTable(20), &casVersion, false)
+	assert.False(t, saveResult.IsError())
+	restoreTb := createTestSnapshotUserTable(8)
+	restoreTb.OpenId = ""
+
+	// Act
+	result := restoreUserTable(ctx, testSnapshotZoneId, testSnapshotUserId, restoreTb)
+	currentTb, _, loadResult := db.DatabaseTableUserLoadWithZoneIdUserId(ctx, testSnapshotZoneId, testSnapshotUserId)
+	records, listResult := ListUserSnapshots(ctx, testSnapshotZoneId, testSnapshotUserId)
+
+	// Assert
+	assert.False(t, result.IsError())
+	assert.False(t, loadResult.IsError())
+	assert.False(t, listResult.IsError())
+	assert.Equal(t, uint32(8), currentTb.GetUserData().GetUserLevel())
+	assert.Equal(t, "snapshot-open-id", currentTb.GetOpenId())
+	assert.Equal(t, now.Unix(), currentTb.GetLastSnapshotTime())
+	assert.Len(t, records, 1)
+	assert.Equal(t, UserSnapshotReasonBeforeRestore, records[0].Data.GetReason())
+	backupTb, err := DecodeUserSnapshot(records[0].Data)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(20), backupTb.GetUserData().GetUserLevel())
+}
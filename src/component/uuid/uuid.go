@@ -1,10 +1,8 @@
 package atframework_component_uuid
 
 import (
-	"container/list"
 	"fmt"
 	"sync"
-	"unsafe"
 
 	lu "github.com/atframework/atframe-utils-go/lang_utility"
 	config "github.com/atframework/atsf4g-go/component/config"
@@ -25,17 +23,27 @@ type uniqueIDPool struct {
 
 	base  uint64 // DB Key
 	index uint64 // Local Index
-
-	ioTask          cd.TaskActionImpl
-	awaitIOTaskList list.List
 }
 
 var (
 	uniqueIDPools = map[uniqueIDKey]*uniqueIDPool{}
 	uniqueIDMu    sync.RWMutex
+
+	// 同一个池同时只有一个任务向数据库申请新区块
+	uniqueIDIOFlight db.IOSingleFlight[uniqueIDKey]
+)
+
+const (
+	// GlobalUniqueIDOffset 分配出的ID都加上这个偏移: uuid = ((base << bitsOff) | index) + GlobalUniqueIDOffset
+	GlobalUniqueIDOffset = 1000000
+	// GlobalUniqueIDShortBitsOff 短ID模式每个区块的位数
+	GlobalUniqueIDShortBitsOff = 5
+	// GlobalUniqueIDBitsOff 默认每个区块的位数
+	GlobalUniqueIDBitsOff = 13
 )
 
-func bitsOffForMajor(majorType private_protocol_pbdesc.EnGlobalUUIDMajorType) int64 {
+// GlobalUniqueIDBitsOffForMajor 每个区块包含 1 << bitsOff 个ID，base 存储在 uuid_allocator 表中
+func GlobalUniqueIDBitsOffForMajor(majorType private_protocol_pbdesc.EnGlobalUUIDMajorType) int64 {
 	switch majorType {
 	case private_protocol_pbdesc.EnGlobalUUIDMajorType_EN_GLOBAL_UUID_MAT_ACCOUNT_ID,
 		private_protocol_pbdesc.EnGlobalUUIDMajorType_EN_GLOBAL_UUID_MAT_GUILD_ID:
@@ -43,10 +51,10 @@ func bitsOffForMajor(majorType private_protocol_pbdesc.EnGlobalUUIDMajorType) in
 		// EN_GLOBAL_UUID_MAT_ACCOUNT_ID:  [1 | 55 | 5] | 3
 		// EN_GLOBAL_UUID_MAT_GUILD_ID:    [1 | 55 | 5] | 3
 		// 公会和玩家账号分配采用短ID模式
-		return 5
+		return GlobalUniqueIDShortBitsOff
 	default:
 		// POOL => 1 | 50 | 13
-		return 13
+		return GlobalUniqueIDBitsOff
 	}
 }
 
@@ -73,8 +81,9 @@ func GenerateGlobalUniqueID(ctx cd.AwaitableContext,
 	minorType private_protocol_pbdesc.EnGlobalUUIDMinorType,
 	pathType private_protocol_pbdesc.EnGlobalUUIDPatchType,
 ) (uuid uint64, result cd.RpcResult) {
-	pool := getUniqueIDPool(uniqueIDKey{majorType: uint32(majorType), minorType: uint32(minorType), pathType: uint32(pathType)})
-	bitsOff := bitsOffForMajor(majorType)
+	key := uniqueIDKey{majorType: uint32(majorType), minorType: uint32(minorType), pathType: uint32(pathType)}
+	pool := getUniqueIDPool(key)
+	bitsOff := GlobalUniqueIDBitsOffForMajor(majorType)
 	bitsRange := uint64(1) << bitsOff
 
 	currentTask := ctx.GetAction()
@@ -84,7 +93,6 @@ func GenerateGlobalUniqueID(ctx cd.AwaitableContext,
 	}
 
 	const maxRetry = 5
-	shouldWake := false
 	for range maxRetry {
 		if currentTask.IsExiting() {
 			result = cd.CreateRpcResultError(fmt.Errorf("task exiting"), public_protocol_pbdesc.EnErrorCode_EN_ERR_TIMEOUT)
@@ -92,81 +100,37 @@ func GenerateGlobalUniqueID(ctx cd.AwaitableContext,
 		}
 
 		pool.mu.Lock()
-		if !lu.IsNil(pool.ioTask) && !pool.ioTask.IsExiting() && pool.ioTask != currentTask {
-			// 等待当前任务完成IO
-			resumeElement := &struct {
-				ele *list.Element
-			}{
-				ele: nil,
-			}
-			cd.YieldTaskAction(ctx, currentTask, &cd.DispatcherAwaitOptions{
-				Type:     uint64(uintptr(unsafe.Pointer(pool))),
-				Sequence: currentTask.GetTaskId(),
-				Timeout:  config.GetConfigManager().GetCurrentConfigGroup().GetSectionConfig().GetTask().GetCsmsg().GetTimeout().AsDuration(),
-			}, &cd.YieldTaskHookSet{
-				PreYield: func(ctx cd.RpcContext) cd.RpcResult {
-					resumeElement.ele = pool.awaitIOTaskList.PushBack(currentTask)
-					return cd.CreateRpcResultOk()
-				},
-				PreYieldCleanup: func(_ cd.RpcContext) {
-					pool.mu.Unlock()
-				},
-				PostYield: func(ctx cd.RpcContext, _resume *cd.DispatcherResumeData, result cd.RpcResult) cd.RpcResult {
-					if resumeElement.ele != nil {
-						pool.awaitIOTaskList.Remove(resumeElement.ele)
-					}
-					return result
-				},
-			})
-			continue
+		if pool.base != 0 && pool.index < bitsRange {
+			uuid = ((pool.base << bitsOff) | pool.index) + GlobalUniqueIDOffset
+			pool.index++
+			pool.mu.Unlock()
+			result = cd.CreateRpcResultOk()
+			break
 		}
+		pool.mu.Unlock()
 
-		// 这里开始没有任务在跑IO 并且拿到了锁
-		if pool.base == 0 || pool.index >= bitsRange {
-			// 需要从数据库分配新的区块
-			pool.ioTask = currentTask
-			shouldWake = true
+		// 需要从数据库分配新的区块，其他任务在分配时等待它完成后重试
+		_, result = uniqueIDIOFlight.Do(ctx, key, func(ctx cd.AwaitableContext) cd.RpcResult {
+			pool.mu.Lock()
+			exhausted := pool.base == 0 || pool.index >= bitsRange
 			pool.mu.Unlock()
+			if !exhausted {
+				// 其他任务刚分配完
+				return cd.CreateRpcResultOk()
+			}
 
 			val, ret := db.DatabaseTableUuidAllocatorAtomicIncMajorTypeMinorTypePathTypeAutoIncId(ctx,
 				uint32(majorType), uint32(minorType), uint32(pathType), 1)
 			if ret.IsError() {
-				result = ret
-				continue
+				return ret
 			}
 
 			pool.mu.Lock()
 			pool.base = val
 			pool.index = 0
-		}
-
-		uuid = ((pool.base << bitsOff) | pool.index) + 1000000
-		pool.index++
-		pool.mu.Unlock()
-		break
-	}
-
-	if shouldWake {
-		// 唤醒等待的任务
-		pool.mu.Lock()
-		if pool.ioTask == currentTask {
-			pool.ioTask = nil
-			for pool.awaitIOTaskList.Len() > 0 {
-				elem := pool.awaitIOTaskList.Front()
-				task := elem.Value.(cd.TaskActionImpl)
-				pool.awaitIOTaskList.Remove(elem)
-				if task.IsExiting() {
-					continue
-				}
-				cd.ResumeTaskAction(ctx, task, &cd.DispatcherResumeData{
-					Message: &cd.DispatcherRawMessage{
-						Type: uint64(uintptr(unsafe.Pointer(pool))),
-					},
-					Sequence: task.GetTaskId(),
-				})
-			}
-		}
-		pool.mu.Unlock()
+			pool.mu.Unlock()
+			return cd.CreateRpcResultOk()
+		})
 	}
 
 	if uuid == 0 && result.IsOK() {
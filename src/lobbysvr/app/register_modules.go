@@ -2,14 +2,21 @@ package lobbysvr_app
 
 import (
 	// nolint:blank-imports
+	_ "github.com/atframework/atsf4g-go/service-lobbysvr/logic/achievement/impl"
+	_ "github.com/atframework/atsf4g-go/service-lobbysvr/logic/activity/impl"
 	_ "github.com/atframework/atsf4g-go/service-lobbysvr/logic/condition/impl"
+	_ "github.com/atframework/atsf4g-go/service-lobbysvr/logic/friend/impl"
+	_ "github.com/atframework/atsf4g-go/service-lobbysvr/logic/gacha/impl"
+	_ "github.com/atframework/atsf4g-go/service-lobbysvr/logic/guild/impl"
 	_ "github.com/atframework/atsf4g-go/service-lobbysvr/logic/inventory/impl"
 	_ "github.com/atframework/atsf4g-go/service-lobbysvr/logic/mail/impl"
 	_ "github.com/atframework/atsf4g-go/service-lobbysvr/logic/mall/impl"
 	_ "github.com/atframework/atsf4g-go/service-lobbysvr/logic/module_unlock/impl"
 	_ "github.com/atframework/atsf4g-go/service-lobbysvr/logic/open_platform/impl"
+	_ "github.com/atframework/atsf4g-go/service-lobbysvr/logic/payment/impl"
 	_ "github.com/atframework/atsf4g-go/service-lobbysvr/logic/quest/impl"
 	_ "github.com/atframework/atsf4g-go/service-lobbysvr/logic/random_pool/impl"
+	_ "github.com/atframework/atsf4g-go/service-lobbysvr/logic/sign_in/impl"
 	_ "github.com/atframework/atsf4g-go/service-lobbysvr/logic/timer/impl"
 	_ "github.com/atframework/atsf4g-go/service-lobbysvr/logic/trigger/impl"
 	_ "github.com/atframework/atsf4g-go/service-lobbysvr/logic/unlock/impl"
@@ -84,6 +84,7 @@ type User struct {
 
 	moduleManagerMap map[lu.TypeID]UserModuleManagerImpl
 	itemManagerList  []userItemManagerWrapper
+	dataMigration    *private_protocol_pbdesc.UserDataMigrationData
 
 	dirtyHandles                   map[unsafe.Pointer]userDirtyHandles
 	lazyEvalationHandles           map[UserLazyEvalationPriority]map[string]UserLazyEvalationData
@@ -224,7 +225,14 @@ func (u *User) RefreshLimit(ctx cd.RpcContext, now time.Time) {
 }
 
 func (u *User) InitFromDB(ctx cd.RpcContext, srcTb *private_protocol_pbdesc.DatabaseTableUser) cd.RpcResult {
-	result := u.UserCache.InitFromDB(ctx, srcTb)
+	// 数据迁移必须在所有模块读取数据之前执行
+	result := MigrateUserData(ctx, srcTb)
+	if result.IsError() {
+		return result
+	}
+	u.dataMigration = srcTb.GetMigrationData().Clone()
+
+	result = u.UserCache.InitFromDB(ctx, srcTb)
 	if result.IsError() {
 		return result
 	}
@@ -248,6 +256,11 @@ func (u *User) DumpToDB(ctx cd.RpcContext, dstDb *private_protocol_pbdesc.Databa
 		return result
 	}
 
+	if u.dataMigration == nil {
+		u.dataMigration = createCurrentUserDataMigrationData()
+	}
+	dstDb.MigrationData = u.dataMigration.Clone()
+
 	for _, mgr := range u.moduleManagerMap {
 		result = mgr.DumpToDB(ctx, dstDb)
 		if result.IsError() {
@@ -0,0 +1,125 @@
+package lobbysvr_data
+
+import (
+	"fmt"
+	"slices"
+
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+)
+
+// UserDataMigrationFunc 数据迁移步骤，直接修改从DB读取的原始数据，在各模块 InitFromDB 之前执行
+type UserDataMigrationFunc func(ctx cd.RpcContext, dbUser *private_protocol_pbdesc.DatabaseTableUser) error
+
+type userDataMigrationStep struct {
+	version uint64
+	name    string
+	fn      UserDataMigrationFunc
+}
+
+// 模块名 => 按版本号递增排列的迁移步骤
+var userDataMigrations = make(map[string][]userDataMigrationStep)
+
+// RegisterUserDataMigration 注册模块的数据迁移步骤，需要在 init 中调用
+// 同一个模块的 version 从1开始且必须严格递增，已经上线的步骤不允许修改或删除
+func RegisterUserDataMigration(module string, version uint64, name string, fn UserDataMigrationFunc) {
+	if module == "" {
+		panic("empty user data migration module name")
+	}
+	if fn == nil {
+		panic("nil user data migration function for module: " + module)
+	}
+	if version == 0 {
+		panic("user data migration version must be greater than 0 for module: " + module)
+	}
+
+	steps := userDataMigrations[module]
+	if len(steps) > 0 && steps[len(steps)-1].version >= version {
+		panic(fmt.Sprintf("user data migration version must be increasing for module: %s, last: %d, current: %d",
+			module, steps[len(steps)-1].version, version))
+	}
+
+	userDataMigrations[module] = append(steps, userDataMigrationStep{
+		version: version,
+		name:    name,
+		fn:      fn,
+	})
+}
+
+// GetUserDataMigrationVersion 获取模块当前的数据版本号，没有注册迁移步骤时为0
+func GetUserDataMigrationVersion(module string) uint64 {
+	steps := userDataMigrations[module]
+	if len(steps) == 0 {
+		return 0
+	}
+	return steps[len(steps)-1].version
+}
+
+func createCurrentUserDataMigrationData() *private_protocol_pbdesc.UserDataMigrationData {
+	ret := &private_protocol_pbdesc.UserDataMigrationData{
+		ModuleVersions: make(map[string]uint64, len(userDataMigrations)),
+	}
+	for module := range userDataMigrations {
+		ret.ModuleVersions[module] = GetUserDataMigrationVersion(module)
+	}
+	return ret
+}
+
+// MigrateUserData 把DB数据升级到当前版本
+// 未执行过创建初始化的新账号没有需要迁移的数据，直接标记为当前版本
+// 任意一步失败或者数据版本高于当前服务器版本时返回 EN_ERR_USER_DATA_MIGRATION_FAILED，此时数据不能继续使用
+func MigrateUserData(ctx cd.RpcContext, dbUser *private_protocol_pbdesc.DatabaseTableUser) cd.RpcResult {
+	if dbUser == nil {
+		return cd.CreateRpcResultError(fmt.Errorf("dbUser should not be nil"), public_protocol_pbdesc.EnErrorCode_EN_ERR_INVALID_PARAM)
+	}
+
+	if !dbUser.GetCreateInit() {
+		dbUser.MigrationData = createCurrentUserDataMigrationData()
+		return cd.CreateRpcResultOk()
+	}
+
+	migrationData := dbUser.MutableMigrationData()
+	if migrationData.ModuleVersions == nil {
+		migrationData.ModuleVersions = make(map[string]uint64, len(userDataMigrations))
+	}
+
+	// 按模块名排序保证执行顺序稳定
+	modules := make([]string, 0, len(userDataMigrations))
+	for module := range userDataMigrations {
+		modules = append(modules, module)
+	}
+	slices.Sort(modules)
+
+	for _, module := range modules {
+		dataVersion := migrationData.ModuleVersions[module]
+		currentVersion := GetUserDataMigrationVersion(module)
+		if dataVersion > currentVersion {
+			ctx.LogError("user data version is newer than server", "module", module,
+				"data_version", dataVersion, "current_version", currentVersion)
+			return cd.CreateRpcResultError(
+				fmt.Errorf("user data version of module %s is %d, newer than server version %d", module, dataVersion, currentVersion),
+				public_protocol_pbdesc.EnErrorCode_EN_ERR_USER_DATA_MIGRATION_FAILED)
+		}
+
+		for _, step := range userDataMigrations[module] {
+			if step.version <= dataVersion {
+				continue
+			}
+
+			if err := step.fn(ctx, dbUser); err != nil {
+				ctx.LogError("user data migration failed", "module", module, "version", step.version,
+					"name", step.name, "error", err)
+				return cd.CreateRpcResultError(
+					fmt.Errorf("user data migration %s.%d(%s) failed: %w", module, step.version, step.name, err),
+					public_protocol_pbdesc.EnErrorCode_EN_ERR_USER_DATA_MIGRATION_FAILED)
+			}
+
+			ctx.LogInfo("user data migration success", "module", module, "version", step.version, "name", step.name)
+			migrationData.ModuleVersions[module] = step.version
+		}
+	}
+
+	return cd.CreateRpcResultOk()
+}
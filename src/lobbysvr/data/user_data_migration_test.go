@@ -0,0 +1,160 @@
+package lobbysvr_data
+
+import (
+	"fmt"
+	"testing"
+
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	test_utility "github.com/atframework/atsf4g-go/component/test_utility"
+	"github.com/stretchr/testify/assert"
+)
+
+// resetUserDataMigrations 使用独立的迁移注册表，测试结束后恢复
+func resetUserDataMigrations(t *testing.T) {
+	saved := userDataMigrations
+	userDataMigrations = make(map[string][]userDataMigrationStep)
+	t.Cleanup(func() {
+		userDataMigrations = saved
+	})
+}
+
+// TestMigrateUserDataRunsPendingSteps 测试只执行未执行过的迁移步骤
+// Scenario: 模块数据版本为1，注册了版本1~3，只执行2和3并把版本更新为3
+func TestMigrateUserDataRunsPendingSteps(t *testing.T) {
+	// Arrange
+	resetUserDataMigrations(t)
+	executed := []uint64{}
+	for version := uint64(1); version <= 3; version++ {
+		RegisterUserDataMigration("user", version, fmt.Sprintf("step_%d", version),
+			func(_ cd.RpcContext, dbUser *private_protocol_pbdesc.DatabaseTableUser) error {
+				executed = append(executed, version)
+				dbUser.MutableUserData().UserLevel += 10
+				return nil
+			})
+	}
+	dbUser := &private_protocol_pbdesc.DatabaseTableUser{
+		CreateInit: true,
+		UserData:   &private_protocol_pbdesc.UserData{UserLevel: 1},
+		MigrationData: &private_protocol_pbdesc.UserDataMigrationData{
+			ModuleVersions: map[string]uint64{"user": 1},
+		},
+	}
+
+	// Act
+	result := MigrateUserData(test_utility.NewMockRpcContext(nil), dbUser)
+
+	// Assert
+	assert.False(t, result.IsError())
+	assert.Equal(t, []uint64{2, 3}, executed)
+	assert.Equal(t, uint32(21), dbUser.GetUserData().GetUserLevel())
+	assert.Equal(t, uint64(3), dbUser.GetMigrationData().GetModuleVersions()["user"])
+}
+
+// TestMigrateUserDataNewUser 测试新账号不执行迁移
+// Scenario: 未执行过创建初始化的数据直接标记为当前版本
+func TestMigrateUserDataNewUser(t *testing.T) {
+	// Arrange
+	resetUserDataMigrations(t)
+	called := false
+	RegisterUserDataMigration("inventory", 2, "rebuild_items",
+		func(_ cd.RpcContext, _ *private_protocol_pbdesc.DatabaseTableUser) error {
+			called = true
+			return nil
+		})
+	dbUser := &private_protocol_pbdesc.DatabaseTableUser{}
+
+	// Act
+	result := MigrateUserData(test_utility.NewMockRpcContext(nil), dbUser)
+
+	// Assert
+	assert.False(t, result.IsError())
+	assert.False(t, called)
+	assert.Equal(t, uint64(2), dbUser.GetMigrationData().GetModuleVersions()["inventory"])
+}
+
+// TestMigrateUserDataFailed 测试迁移失败返回独立的错误码
+// Scenario: 第二步失败时返回 EN_ERR_USER_DATA_MIGRATION_FAILED，版本号停留在第一步
+func TestMigrateUserDataFailed(t *testing.T) {
+	// Arrange
+	resetUserDataMigrations(t)
+	RegisterUserDataMigration("quest", 1, "ok",
+		func(_ cd.RpcContext, _ *private_protocol_pbdesc.DatabaseTableUser) error {
+			return nil
+		})
+	RegisterUserDataMigration("quest", 2, "broken",
+		func(_ cd.RpcContext, _ *private_protocol_pbdesc.DatabaseTableUser) error {
+			return fmt.Errorf("bad quest data")
+		})
+	dbUser := &private_protocol_pbdesc.DatabaseTableUser{CreateInit: true}
+
+	// Act
+	result := MigrateUserData(test_utility.NewMockRpcContext(nil), dbUser)
+
+	// Assert
+	assert.True(t, result.IsError())
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_USER_DATA_MIGRATION_FAILED), result.GetResponseCode())
+	assert.Equal(t, uint64(1), dbUser.GetMigrationData().GetModuleVersions()["quest"])
+}
+
+// TestMigrateUserDataNewerVersion 测试数据版本高于服务器版本
+// Scenario: 服务器回退后读取到更高版本的数据时拒绝加载
+func TestMigrateUserDataNewerVersion(t *testing.T) {
+	// Arrange
+	resetUserDataMigrations(t)
+	RegisterUserDataMigration("mall", 1, "init",
+		func(_ cd.RpcContext, _ *private_protocol_pbdesc.DatabaseTableUser) error {
+			return nil
+		})
+	dbUser := &private_protocol_pbdesc.DatabaseTableUser{
+		CreateInit: true,
+		MigrationData: &private_protocol_pbdesc.UserDataMigrationData{
+			ModuleVersions: map[string]uint64{"mall": 2},
+		},
+	}
+
+	// Act
+	result := MigrateUserData(test_utility.NewMockRpcContext(nil), dbUser)
+
+	// Assert
+	assert.True(t, result.IsError())
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_USER_DATA_MIGRATION_FAILED), result.GetResponseCode())
+}
+
+// TestRegisterUserDataMigrationOrder 测试注册时的版本号校验
+// Scenario: 同一模块版本号不递增时 panic
+func TestRegisterUserDataMigrationOrder(t *testing.T) {
+	// Arrange
+	resetUserDataMigrations(t)
+	fn := func(_ cd.RpcContext, _ *private_protocol_pbdesc.DatabaseTableUser) error { return nil }
+	RegisterUserDataMigration("mail", 2, "v2", fn)
+
+	// Act & Assert
+	assert.Panics(t, func() { RegisterUserDataMigration("mail", 2, "v2_again", fn) })
+	assert.Panics(t, func() { RegisterUserDataMigration("mail", 1, "v1", fn) })
+	assert.NotPanics(t, func() { RegisterUserDataMigration("mail", 3, "v3", fn) })
+	assert.Equal(t, uint64(3), GetUserDataMigrationVersion("mail"))
+}
+
+// TestUserDumpMigrationDataNotAliased 测试存盘时复制迁移版本数据
+// Scenario: 修改一次 DumpToDB 输出的迁移版本，不影响玩家对象和下一次存盘的结果
+func TestUserDumpMigrationDataNotAliased(t *testing.T) {
+	// Arrange
+	resetUserDataMigrations(t)
+	RegisterUserDataMigration("user", 1, "v1",
+		func(_ cd.RpcContext, _ *private_protocol_pbdesc.DatabaseTableUser) error { return nil })
+	ctx := test_utility.NewMockRpcContext(nil)
+	user, result := CreateUserForTest(ctx, 1, 10001, nil)
+	assert.False(t, result.IsError())
+
+	// Act
+	firstDump := &private_protocol_pbdesc.DatabaseTableUser{}
+	user.DumpToDB(ctx, firstDump)
+	firstDump.GetMigrationData().ModuleVersions["user"] = 99
+	secondDump := &private_protocol_pbdesc.DatabaseTableUser{}
+	user.DumpToDB(ctx, secondDump)
+
+	// Assert
+	assert.Equal(t, uint64(1), secondDump.GetMigrationData().GetModuleVersions()["user"])
+}
@@ -0,0 +1,23 @@
+package lobbysvr_data
+
+import (
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+)
+
+// CreateUserForTest 创建单元测试用的玩家对象，只包含测试进程中注册过的模块
+// 不经过登入流程，直接从 dbUser 初始化并标记为可写入
+func CreateUserForTest(ctx cd.RpcContext, zoneId uint32, userId uint64, dbUser *private_protocol_pbdesc.DatabaseTableUser) (*User, cd.RpcResult) {
+	ret := createUser(ctx, zoneId, userId, "", cd.CreateActorExecutor(nil))
+	if dbUser == nil {
+		dbUser = &private_protocol_pbdesc.DatabaseTableUser{}
+	}
+
+	result := ret.InitFromDB(ctx, dbUser)
+	if result.IsError() {
+		return nil, result
+	}
+
+	ret.isLoginInited = true
+	return ret, cd.CreateRpcResultOk()
+}
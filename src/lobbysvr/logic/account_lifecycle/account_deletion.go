@@ -0,0 +1,470 @@
+// Copyright 2026 atframework
+// @brief 账号注销流程：申请 -> 冷静期 -> 删除 -> 墓碑
+
+package lobbysvr_logic_account_lifecycle
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/atframework/libatapp-go"
+
+	async_jobs "github.com/atframework/atsf4g-go/component/async_jobs"
+	config "github.com/atframework/atsf4g-go/component/config"
+	db "github.com/atframework/atsf4g-go/component/db"
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	operation_support_system "github.com/atframework/atsf4g-go/component/operation_support_system"
+	private_protocol_common "github.com/atframework/atsf4g-go/component/protocol/private/common/protocol/common"
+	private_protocol_config "github.com/atframework/atsf4g-go/component/protocol/private/config/protocol/config"
+	private_protocol_log "github.com/atframework/atsf4g-go/component/protocol/private/log/protocol/log"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	uc "github.com/atframework/atsf4g-go/component/user_controller"
+)
+
+// accountDeletionRemovedCount 执行删除时各类数据的删除数量，用于 OSS 日志
+type accountDeletionRemovedCount struct {
+	asyncJobs           int32
+	mailContent         int32
+	snapshot            int32
+	rank                int32
+	chatOfflineMessages int32
+	notifiedFriends     int32
+	leftGuildId         uint64
+}
+
+func getAccountDeletionConfig() *private_protocol_config.Readonly_LogicAccountDeletionCfg {
+	return config.GetConfigManager().GetCurrentConfigGroup().GetSectionConfig().GetUser().GetAccountDeletion()
+}
+
+func isDBRecordNotFound(result cd.RpcResult) bool {
+	return result.GetResponseCode() == int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND)
+}
+
+// getAllAsyncJobsTypes 玩家可能存在的所有异步任务类型
+func getAllAsyncJobsTypes() []int32 {
+	ret := make([]int32, 0, len(private_protocol_pbdesc.EnPlayerAsyncJobsType_name))
+	for jobType := range private_protocol_pbdesc.EnPlayerAsyncJobsType_name {
+		if jobType == int32(private_protocol_pbdesc.EnPlayerAsyncJobsType_EN_PAJT_INVALID) {
+			continue
+		}
+		ret = append(ret, jobType)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i] < ret[j] })
+	return ret
+}
+
+// getUserMailContentIds 非全服邮件的内容单独存在 mail_content 表中，归属于玩家
+func getUserMailContentIds(userTb *private_protocol_pbdesc.DatabaseTableUser) []int64 {
+	ret := make([]int64, 0, len(userTb.GetMailData().GetMailBox())+len(userTb.GetMailData().GetPendingRemoveList()))
+	for _, record := range userTb.GetMailData().GetMailBox() {
+		if !record.GetIsGlobalMail() {
+			ret = append(ret, record.GetMailId())
+		}
+	}
+	for _, record := range userTb.GetMailData().GetPendingRemoveList() {
+		if !record.GetIsGlobalMail() {
+			ret = append(ret, record.GetMailId())
+		}
+	}
+	return ret
+}
+
+func getUserRankMember(userId uint64) string {
+	return strconv.FormatUint(userId, 10)
+}
+
+// LoadAccountDeletion 读取账号注销记录，不存在时返回 nil
+func LoadAccountDeletion(ctx cd.AwaitableContext, openId string) (*private_protocol_pbdesc.DatabaseTableAccountDeletion, uint64, cd.RpcResult) {
+	table, casVersion, result := db.DatabaseTableAccountDeletionLoadWithOpenId(ctx, openId)
+	if result.IsError() {
+		if isDBRecordNotFound(result) {
+			return nil, 0, cd.CreateRpcResultOk()
+		}
+		return nil, 0, result
+	}
+	return table, casVersion, result
+}
+
+// CheckAccountLoginAllowed 登入认证时检查账号是否已注销
+// 冷静期内允许登入，以便玩家撤销注销
+func CheckAccountLoginAllowed(ctx cd.AwaitableContext, openId string) cd.RpcResult {
+	table, _, result := LoadAccountDeletion(ctx, openId)
+	if result.IsError() {
+		return result
+	}
+	if table == nil {
+		return cd.CreateRpcResultOk()
+	}
+
+	switch table.GetState() {
+	case private_protocol_pbdesc.EnAccountDeletionState_EN_ACCOUNT_DELETION_STATE_DELETING,
+		private_protocol_pbdesc.EnAccountDeletionState_EN_ACCOUNT_DELETION_STATE_DELETED:
+		return cd.CreateRpcResultError(fmt.Errorf("account %s has been deleted", openId), public_protocol_pbdesc.EnErrorCode_EN_ERR_ACCOUNT_DELETED)
+	}
+	return cd.CreateRpcResultOk()
+}
+
+// RequestAccountDeletion 申请注销账号，gracePeriod 后由 AccountDeletionManager 执行删除
+// gracePeriod 小于 0 时使用配置的冷静期
+func RequestAccountDeletion(ctx cd.AwaitableContext, zoneId uint32, userId uint64, operator string, reason string,
+	gracePeriod time.Duration,
+) (*private_protocol_pbdesc.DatabaseTableAccountDeletion, cd.RpcResult) {
+	userTb, _, result := db.DatabaseTableUserLoadWithZoneIdUserId(ctx, zoneId, userId)
+	if result.IsError() {
+		return nil, result
+	}
+	openId := userTb.GetOpenId()
+
+	table, casVersion, result := LoadAccountDeletion(ctx, openId)
+	if result.IsError() {
+		return nil, result
+	}
+	if table != nil {
+		switch table.GetState() {
+		case private_protocol_pbdesc.EnAccountDeletionState_EN_ACCOUNT_DELETION_STATE_PENDING:
+			return table, cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_ACCOUNT_DELETION_PENDING)
+		case private_protocol_pbdesc.EnAccountDeletionState_EN_ACCOUNT_DELETION_STATE_DELETING,
+			private_protocol_pbdesc.EnAccountDeletionState_EN_ACCOUNT_DELETION_STATE_DELETED:
+			return table, cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_ACCOUNT_DELETED)
+		}
+	}
+
+	if gracePeriod < 0 {
+		gracePeriod = getAccountDeletionConfig().GetGracePeriod().AsDuration()
+	}
+	now := ctx.GetSysNow().Unix()
+	table = &private_protocol_pbdesc.DatabaseTableAccountDeletion{
+		OpenId:      openId,
+		UserId:      userId,
+		ZoneId:      zoneId,
+		State:       private_protocol_pbdesc.EnAccountDeletionState_EN_ACCOUNT_DELETION_STATE_PENDING,
+		RequestTime: now,
+		ExecuteTime: now + int64(gracePeriod/time.Second),
+		Operator:    operator,
+		Reason:      reason,
+	}
+	result = db.DatabaseTableAccountDeletionReplaceOpenId(ctx, table, &casVersion, false)
+	if result.IsError() {
+		result.LogError(ctx, "save account deletion record failed", "open_id", openId, "user_id", userId)
+		return nil, result
+	}
+
+	result = db.DatabaseTableAccountDeletionQueueZAddWithZoneId(ctx, zoneId, []db.SortedSetMember{
+		{Member: openId, Score: float64(table.GetExecuteTime())},
+	}, db.ZAddExistenceNone, db.ZAddComparisonNone)
+	if result.IsError() {
+		result.LogError(ctx, "add account deletion queue failed", "open_id", openId, "user_id", userId)
+		return nil, result
+	}
+
+	// 在线玩家踢下线，冷静期内重新登入不受影响
+	libatapp.AtappGetModule[*uc.UserManager](ctx.GetApp()).Remove(ctx, zoneId, userId, nil, true)
+
+	ctx.LogInfo("request account deletion", "open_id", openId, "zone_id", zoneId, "user_id", userId,
+		"operator", operator, "reason", reason, "execute_time", table.GetExecuteTime())
+	sendAccountDeletionOssLog(ctx, table,
+		private_protocol_log.OSSAccountDeletionFlow_EN_OSS_ACCOUNT_DELETION_OPERATION_TYPE_REQUEST, nil, "", result)
+	return table, result
+}
+
+// CancelAccountDeletion 冷静期内撤销注销
+func CancelAccountDeletion(ctx cd.AwaitableContext, openId string, operator string) cd.RpcResult {
+	table, casVersion, result := LoadAccountDeletion(ctx, openId)
+	if result.IsError() {
+		return result
+	}
+	if table == nil {
+		return cd.CreateRpcResultError(fmt.Errorf("account deletion of %s not found", openId), public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND)
+	}
+	if table.GetState() != private_protocol_pbdesc.EnAccountDeletionState_EN_ACCOUNT_DELETION_STATE_PENDING {
+		return cd.CreateRpcResultError(fmt.Errorf("account %s is not pending deletion", openId), public_protocol_pbdesc.EnErrorCode_EN_ERR_ACCOUNT_DELETED)
+	}
+
+	// CAS 删除前先置为 NONE，避免和正在执行的删除任务竞争
+	table.State = private_protocol_pbdesc.EnAccountDeletionState_EN_ACCOUNT_DELETION_STATE_NONE
+	result = db.DatabaseTableAccountDeletionReplaceOpenId(ctx, table, &casVersion, false)
+	if result.IsError() {
+		result.LogError(ctx, "cancel account deletion failed", "open_id", openId)
+		return result
+	}
+
+	if _, removeResult := db.DatabaseTableAccountDeletionQueueZRemWithZoneId(ctx, table.GetZoneId(), []string{openId}); removeResult.IsError() {
+		removeResult.LogWarn(ctx, "remove account deletion queue failed", "open_id", openId)
+	}
+	if delResult := db.DatabaseTableAccountDeletionDelWithOpenId(ctx, openId); delResult.IsError() && !isDBRecordNotFound(delResult) {
+		delResult.LogWarn(ctx, "remove account deletion record failed", "open_id", openId)
+	}
+
+	ctx.LogInfo("cancel account deletion", "open_id", openId, "zone_id", table.GetZoneId(), "user_id", table.GetUserId(), "operator", operator)
+	table.Operator = operator
+	sendAccountDeletionOssLog(ctx, table,
+		private_protocol_log.OSSAccountDeletionFlow_EN_OSS_ACCOUNT_DELETION_OPERATION_TYPE_CANCEL, nil, "", result)
+	return result
+}
+
+// ExecuteAccountDeletion 删除账号关联的所有数据，完成后保留墓碑记录
+// 支付订单需要保留用于对账，不会删除
+// 需要在目标玩家的 ActorExecutor 中执行
+func ExecuteAccountDeletion(ctx cd.AwaitableContext, openId string) cd.RpcResult {
+	table, casVersion, result := LoadAccountDeletion(ctx, openId)
+	if result.IsError() {
+		return result
+	}
+	if table == nil || table.GetState() == private_protocol_pbdesc.EnAccountDeletionState_EN_ACCOUNT_DELETION_STATE_NONE ||
+		table.GetState() == private_protocol_pbdesc.EnAccountDeletionState_EN_ACCOUNT_DELETION_STATE_DELETED {
+		// 已撤销或已完成，只需要清理队列
+		if table != nil {
+			db.DatabaseTableAccountDeletionQueueZRemWithZoneId(ctx, table.GetZoneId(), []string{openId})
+		}
+		return cd.CreateRpcResultOk()
+	}
+	if table.GetState() == private_protocol_pbdesc.EnAccountDeletionState_EN_ACCOUNT_DELETION_STATE_PENDING &&
+		table.GetExecuteTime() > ctx.GetSysNow().Unix() {
+		return cd.CreateRpcResultOk()
+	}
+
+	zoneId := table.GetZoneId()
+	userId := table.GetUserId()
+
+	// 先切换状态，认证流程会拒绝后续登入
+	table.State = private_protocol_pbdesc.EnAccountDeletionState_EN_ACCOUNT_DELETION_STATE_DELETING
+	result = db.DatabaseTableAccountDeletionReplaceOpenId(ctx, table, &casVersion, false)
+	if result.IsError() {
+		result.LogError(ctx, "set account deletion state failed", "open_id", openId, "user_id", userId)
+		return result
+	}
+
+	result = libatapp.AtappGetModule[*uc.UserManager](ctx.GetApp()).Remove(ctx, zoneId, userId, nil, true)
+	if result.IsError() {
+		result.LogError(ctx, "kickoff user before account deletion failed", "open_id", openId, "user_id", userId)
+		return result
+	}
+
+	loginLockTb, _, result := db.DatabaseTableLoginLockLoadWithUserId(ctx, userId)
+	if result.IsError() && !isDBRecordNotFound(result) {
+		return result
+	}
+	if loginLockTb != nil && loginLockTb.GetRouterServerId() != 0 &&
+		loginLockTb.GetRouterServerId() != uint64(config.GetConfigManager().GetLogicId()) &&
+		ctx.GetSysNow().Unix() < loginLockTb.GetLoginExpired() {
+		// 其他进程持有玩家数据，等登入锁过期后下一轮再执行
+		ctx.LogWarn("user is online on other server, delay account deletion", "open_id", openId, "user_id", userId,
+			"router_server_id", loginLockTb.GetRouterServerId())
+		return cd.CreateRpcResultError(fmt.Errorf("user is online on other server"), public_protocol_pbdesc.EnErrorCode_EN_ERR_ALREADY_LOGIN)
+	}
+
+	exportFile := ""
+	if getAccountDeletionConfig().GetExportBeforeDelete() {
+		archive, exportResult := ExportAccount(ctx, zoneId, userId, openId)
+		if exportResult.IsError() {
+			exportResult.LogError(ctx, "export account before deletion failed", "open_id", openId, "user_id", userId)
+			return exportResult
+		}
+		exportFile, exportResult = WriteAccountExportArchive(ctx, archive)
+		if exportResult.IsError() {
+			return exportResult
+		}
+	}
+
+	removed, result := removeAccountData(ctx, zoneId, userId, openId)
+	if result.IsError() {
+		result.LogError(ctx, "remove account data failed", "open_id", openId, "user_id", userId)
+		return result
+	}
+
+	table.State = private_protocol_pbdesc.EnAccountDeletionState_EN_ACCOUNT_DELETION_STATE_DELETED
+	table.FinishTime = ctx.GetSysNow().Unix()
+	result = db.DatabaseTableAccountDeletionReplaceOpenId(ctx, table, &casVersion, false)
+	if result.IsError() {
+		result.LogError(ctx, "save account deletion tombstone failed", "open_id", openId, "user_id", userId)
+		return result
+	}
+	if ttl := getAccountDeletionConfig().GetTombstoneTtl().AsDuration(); ttl > 0 {
+		db.DatabaseTableAccountDeletionExpireWithOpenId(ctx, openId, ttl, db.ExpireConditionNone)
+	}
+	db.DatabaseTableAccountDeletionQueueZRemWithZoneId(ctx, zoneId, []string{openId})
+
+	ctx.LogInfo("execute account deletion success", "open_id", openId, "zone_id", zoneId, "user_id", userId,
+		"export_file", exportFile, "removed_async_jobs", removed.asyncJobs, "removed_mail_content", removed.mailContent,
+		"removed_snapshot", removed.snapshot, "removed_rank", removed.rank, "removed_chat_offline_messages", removed.chatOfflineMessages,
+		"notified_friends", removed.notifiedFriends, "left_guild_id", removed.leftGuildId)
+	sendAccountDeletionOssLog(ctx, table,
+		private_protocol_log.OSSAccountDeletionFlow_EN_OSS_ACCOUNT_DELETION_OPERATION_TYPE_EXECUTE, &removed, exportFile, result)
+	return result
+}
+
+// removeAccountData 删除所有引用 user_id 或 open_id 的数据，登入锁最后删除
+// 其他玩家身上的好友关系通过删除好友的异步任务清理，公会按强制退出处理
+// 发出的好友申请和其他玩家的黑名单没有反向索引，申请到期后自动删除，黑名单保留无效的 user_id
+func removeAccountData(ctx cd.AwaitableContext, zoneId uint32, userId uint64, openId string) (accountDeletionRemovedCount, cd.RpcResult) {
+	removed := accountDeletionRemovedCount{}
+
+	userTb, _, result := db.DatabaseTableUserLoadWithZoneIdUserId(ctx, zoneId, userId)
+	if result.IsError() {
+		if !isDBRecordNotFound(result) {
+			return removed, result
+		}
+		userTb = nil
+	}
+
+	if userTb != nil {
+		// 昵称映射需要确认仍然属于这个玩家
+		nickName := userTb.GetAccountData().GetProfile().GetNickName()
+		if nickName != "" {
+			mappingTb, _, result := db.DatabaseTableNameMappingLoadWithTypeIdZoneIdName(ctx,
+				uint32(private_protocol_common.NameMappingType_EN_NAME_MAPPING_TYPE_USER_NICKNAME), zoneId, nickName)
+			if result.IsError() && !isDBRecordNotFound(result) {
+				return removed, result
+			}
+			if mappingTb != nil && mappingTb.GetMappingData().GetUserMapping().GetUserId() == userId {
+				result = db.DatabaseTableNameMappingDelWithTypeIdZoneIdName(ctx,
+					uint32(private_protocol_common.NameMappingType_EN_NAME_MAPPING_TYPE_USER_NICKNAME), zoneId, nickName)
+				if result.IsError() && !isDBRecordNotFound(result) {
+					return removed, result
+				}
+			}
+		}
+
+		for _, mailId := range getUserMailContentIds(userTb) {
+			result = db.DatabaseTableMailContentDelWithMailId(ctx, mailId)
+			if result.IsError() {
+				if isDBRecordNotFound(result) {
+					continue
+				}
+				return removed, result
+			}
+			removed.mailContent++
+		}
+
+		removed.notifiedFriends, result = notifyFriendsAccountRemoved(ctx, userTb)
+		if result.IsError() {
+			return removed, result
+		}
+
+		removed.leftGuildId, result = leaveUserGuild(ctx, userTb)
+		if result.IsError() {
+			return removed, result
+		}
+	}
+
+	chatMessages, result := db.DatabaseTableChatOfflineMessageLoadAllWithZoneIdUserId(ctx, zoneId, userId)
+	if result.IsError() && !isDBRecordNotFound(result) {
+		return removed, result
+	}
+	if len(chatMessages) > 0 {
+		result = db.DatabaseTableChatOfflineMessageDelWithZoneIdUserId(ctx, zoneId, userId)
+		if result.IsError() && !isDBRecordNotFound(result) {
+			return removed, result
+		}
+		removed.chatOfflineMessages = int32(len(chatMessages))
+	}
+
+	for _, jobType := range getAllAsyncJobsTypes() {
+		jobs, result := db.DatabaseTableUserAsyncJobsLoadAllWithJobTypeUserIdZoneId(ctx, jobType, userId, zoneId)
+		if result.IsError() && !isDBRecordNotFound(result) {
+			return removed, result
+		}
+		if len(jobs) == 0 {
+			continue
+		}
+		result = db.DatabaseTableUserAsyncJobsDelWithJobTypeUserIdZoneId(ctx, jobType, userId, zoneId)
+		if result.IsError() && !isDBRecordNotFound(result) {
+			return removed, result
+		}
+		removed.asyncJobs += int32(len(jobs))
+	}
+
+	removed.snapshot, result = uc.RemoveAllUserSnapshots(ctx, zoneId, userId)
+	if result.IsError() {
+		return removed, result
+	}
+
+	member := getUserRankMember(userId)
+	for _, rankCfg := range getAccountDeletionConfig().GetRank() {
+		count, result := db.DatabaseTableRankZRemWithIdTypeVersion(ctx, rankCfg.GetId(), rankCfg.GetType(), rankCfg.GetVersion(), []string{member})
+		if result.IsError() {
+			return removed, result
+		}
+		removed.rank += int32(count)
+	}
+
+	// access 表可能已经被重新绑定到其他账号
+	accessTb, result := db.DatabaseTableAccessLoadWithOpenId(ctx, openId)
+	if result.IsError() && !isDBRecordNotFound(result) {
+		return removed, result
+	}
+	if accessTb != nil && accessTb.GetUserId() == userId {
+		result = db.DatabaseTableAccessDelWithOpenId(ctx, openId)
+		if result.IsError() && !isDBRecordNotFound(result) {
+			return removed, result
+		}
+	}
+
+	result = db.DatabaseTableUserDelWithZoneIdUserId(ctx, zoneId, userId)
+	if result.IsError() && !isDBRecordNotFound(result) {
+		return removed, result
+	}
+
+	result = db.DatabaseTableLoginLockDelWithUserId(ctx, userId)
+	if result.IsError() && !isDBRecordNotFound(result) {
+		return removed, result
+	}
+
+	return removed, cd.CreateRpcResultOk()
+}
+
+// notifyFriendsAccountRemoved 通知所有好友删除好友关系，对方处理删除任务是幂等的，重新执行时可以重复投递
+func notifyFriendsAccountRemoved(ctx cd.AwaitableContext, userTb *private_protocol_pbdesc.DatabaseTableUser) (int32, cd.RpcResult) {
+	var count int32
+	for _, friend := range userTb.GetFriendData().GetFriends() {
+		result, _ := async_jobs.AddJobsWithRetry(ctx, private_protocol_pbdesc.EnPlayerAsyncJobsType_EN_PAJT_FRIEND_REMOVE,
+			friend.GetUserId(), friend.GetZoneId(), &private_protocol_pbdesc.UserAsyncJobsBlobData{
+				Action: &private_protocol_pbdesc.UserAsyncJobsBlobData_FriendRemove{
+					FriendRemove: &private_protocol_pbdesc.UserAsyncJobFriendMessage{
+						FromUserId: userTb.GetUserId(),
+						FromZoneId: userTb.GetZoneId(),
+					},
+				},
+			}, async_jobs.DefaultActionOptions())
+		if result.IsError() {
+			result.LogError(ctx, "notify friend account removed failed", "user_id", userTb.GetUserId(), "friend_user_id", friend.GetUserId())
+			return count, result
+		}
+		count++
+	}
+	return count, cd.CreateRpcResultOk()
+}
+
+func sendAccountDeletionOssLog(ctx cd.AwaitableContext, table *private_protocol_pbdesc.DatabaseTableAccountDeletion,
+	operationType private_protocol_log.OSSAccountDeletionFlow_EnOSSAccountDeletionOperationType,
+	removed *accountDeletionRemovedCount, exportFile string, result cd.RpcResult,
+) {
+	log := &private_protocol_log.OperationSupportSystemLog{}
+	log.MutableBasic().UserId = table.GetUserId()
+	log.MutableBasic().ZoneId = table.GetZoneId()
+	log.MutableBasic().OpenId = table.GetOpenId()
+
+	flow := log.MutableLog().MutableAccountDeletionFlow()
+	flow.OperationType = operationType
+	flow.Operator = table.GetOperator()
+	flow.Reason = table.GetReason()
+	flow.RequestTime = table.GetRequestTime()
+	flow.ExecuteTime = table.GetExecuteTime()
+	flow.FinishTime = table.GetFinishTime()
+	flow.ExportFile = exportFile
+	flow.Result = result.GetResponseCode()
+	if removed != nil {
+		flow.RemovedAsyncJobsCount = removed.asyncJobs
+		flow.RemovedMailContentCount = removed.mailContent
+		flow.RemovedSnapshotCount = removed.snapshot
+		flow.RemovedRankCount = removed.rank
+		flow.RemovedChatOfflineMessageCount = removed.chatOfflineMessages
+		flow.NotifiedFriendCount = removed.notifiedFriends
+		flow.LeftGuildId = removed.leftGuildId
+	}
+
+	operation_support_system.SendOssLog(ctx.GetApp(), log)
+}
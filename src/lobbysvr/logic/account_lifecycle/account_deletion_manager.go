@@ -0,0 +1,184 @@
+// Copyright 2026 atframework
+// @brief 定期扫描本区待删除账号队列，冷静期结束后执行删除
+
+package lobbysvr_logic_account_lifecycle
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/atframework/libatapp-go"
+
+	lu "github.com/atframework/atframe-utils-go/lang_utility"
+	config "github.com/atframework/atsf4g-go/component/config"
+	db "github.com/atframework/atsf4g-go/component/db"
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	uc "github.com/atframework/atsf4g-go/component/user_controller"
+)
+
+// AccountDeletionManager 账号删除队列扫描模块
+type AccountDeletionManager struct {
+	libatapp.AppModuleBase
+
+	scanTask          lu.AtomicInterface[cd.TaskActionImpl]
+	taskNextTimepoint int64
+}
+
+func init() {
+	var _ libatapp.AppModuleImpl = (*AccountDeletionManager)(nil)
+}
+
+// CreateAccountDeletionManager 创建账号删除队列扫描模块
+func CreateAccountDeletionManager(owner libatapp.AppImpl) *AccountDeletionManager {
+	return &AccountDeletionManager{
+		AppModuleBase: libatapp.CreateAppModuleBase(owner),
+		// 启动随机错峰
+		taskNextTimepoint: time.Now().Unix() + rand.Int63n(20),
+	}
+}
+
+func (m *AccountDeletionManager) Name() string {
+	return "AccountDeletionManager"
+}
+
+func (m *AccountDeletionManager) Init(parent context.Context) error {
+	return nil
+}
+
+func (m *AccountDeletionManager) Tick(parent context.Context) bool {
+	m.TryToStartScanTask()
+	return false
+}
+
+// IsScanTaskRunning 检查扫描任务是否正在运行
+func (m *AccountDeletionManager) IsScanTaskRunning() bool {
+	if lu.IsNil(m.scanTask.Load()) {
+		return false
+	}
+	if m.scanTask.Load().IsExiting() {
+		m.scanTask.Store(nil)
+		return false
+	}
+	return true
+}
+
+// TryToStartScanTask 到达扫描间隔后启动扫描任务
+func (m *AccountDeletionManager) TryToStartScanTask() {
+	d := libatapp.AtappGetModule[*cd.NoMessageDispatcher](m.GetApp())
+	ctx := d.CreateRpcContext()
+	now := ctx.GetNow().Unix()
+	if now < m.taskNextTimepoint {
+		return
+	}
+
+	if m.IsScanTaskRunning() {
+		return
+	}
+
+	m.taskNextTimepoint = now + getAccountDeletionScanInterval()
+
+	scanTask, startData := cd.CreateNoMessageTaskAction(
+		d, ctx, nil,
+		func(rd cd.DispatcherImpl, actor *cd.ActorExecutor, timeout time.Duration) *taskActionAccountDeletionScan {
+			return &taskActionAccountDeletionScan{
+				TaskActionNoMessageBase: cd.CreateNoMessageTaskActionBase(rd, actor, timeout),
+			}
+		},
+	)
+
+	err := libatapp.AtappGetModule[*cd.TaskManager](ctx.GetApp()).StartTaskAction(ctx, scanTask, &startData)
+	if err != nil {
+		m.GetApp().GetDefaultLogger().LogError("AccountDeletionManager StartTaskAction failed", "error", err)
+	} else {
+		m.scanTask.Store(scanTask)
+	}
+}
+
+func getAccountDeletionScanInterval() int64 {
+	interval := int64(getAccountDeletionConfig().GetScanInterval().AsDuration() / time.Second)
+	if interval <= 0 {
+		interval = 60
+	}
+	return interval
+}
+
+// taskActionAccountDeletionScan 拉取本区到期的待删除账号，逐个在玩家的 Actor 上执行删除
+type taskActionAccountDeletionScan struct {
+	cd.TaskActionNoMessageBase
+}
+
+func (t *taskActionAccountDeletionScan) Name() string {
+	return "TaskActionAccountDeletionScan"
+}
+
+func (t *taskActionAccountDeletionScan) Run(_ *cd.DispatcherStartData) error {
+	ctx := t.GetAwaitableContext()
+	batchCount := int64(getAccountDeletionConfig().GetBatchCount())
+	if batchCount <= 0 {
+		batchCount = 1
+	}
+
+	// 合服后并入的原大区队列也由本进程处理
+	for _, zoneId := range config.GetConfigManager().GetServedZoneIds() {
+		t.scanZone(ctx, zoneId, batchCount)
+	}
+	return nil
+}
+
+// scanZone 每次从队列头部取一批，执行失败的账号推迟到下一轮扫描之后，避免一直堵住后面的账号
+func (t *taskActionAccountDeletionScan) scanZone(ctx cd.AwaitableContext, zoneId uint32, batchCount int64) {
+	members, result := db.DatabaseTableAccountDeletionQueueZRangeByScoreWithZoneId(ctx, zoneId,
+		db.SortedSetScoreBound{Type: db.ScoreBoundNegativeInfinity},
+		db.SortedSetScoreBound{Type: db.ScoreBoundValue, Score: float64(ctx.GetSysNow().Unix())},
+		0, batchCount, false)
+	if result.IsError() {
+		result.LogError(ctx, "load account deletion queue failed", "zone_id", zoneId)
+		return
+	}
+
+	userManager := libatapp.AtappGetModule[*uc.UserManager](ctx.GetApp())
+	for _, member := range members {
+		openId := member.Member
+		table, _, result := LoadAccountDeletion(ctx, openId)
+		if result.IsError() {
+			result.LogError(ctx, "load account deletion record failed", "open_id", openId)
+			delayAccountDeletion(ctx, zoneId, openId)
+			continue
+		}
+		if table == nil {
+			// 记录已撤销或过期，清理残留的队列成员
+			db.DatabaseTableAccountDeletionQueueZRemWithZoneId(ctx, zoneId, []string{openId})
+			continue
+		}
+
+		// 和玩家自身的存盘等操作串行执行
+		var actor *cd.ActorExecutor
+		if user := userManager.Find(ctx, table.GetZoneId(), table.GetUserId()); !lu.IsNil(user) {
+			actor = user.GetActorExecutor()
+		}
+		task := cd.AsyncInvoke(ctx, "execute account deletion", actor, func(childCtx cd.AwaitableContext) cd.RpcResult {
+			return ExecuteAccountDeletion(childCtx, openId)
+		})
+		if lu.IsNil(task) {
+			delayAccountDeletion(ctx, zoneId, openId)
+			continue
+		}
+		result = cd.AwaitTask(ctx, task)
+		if result.IsError() {
+			result.LogWarn(ctx, "execute account deletion failed, will retry next round", "open_id", openId,
+				"user_id", table.GetUserId())
+			delayAccountDeletion(ctx, zoneId, openId)
+		}
+	}
+}
+
+// delayAccountDeletion 只更新仍在队列中的成员，已经撤销的账号不会被重新加入
+func delayAccountDeletion(ctx cd.AwaitableContext, zoneId uint32, openId string) {
+	result := db.DatabaseTableAccountDeletionQueueZAddWithZoneId(ctx, zoneId, []db.SortedSetMember{
+		{Member: openId, Score: float64(ctx.GetSysNow().Unix() + getAccountDeletionScanInterval())},
+	}, db.ZAddExistenceXX, db.ZAddComparisonNone)
+	if result.IsError() {
+		result.LogWarn(ctx, "delay account deletion queue failed", "zone_id", zoneId, "open_id", openId)
+	}
+}
@@ -0,0 +1,307 @@
+package lobbysvr_logic_account_lifecycle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	libatapp "github.com/atframework/libatapp-go"
+
+	db "github.com/atframework/atsf4g-go/component/db"
+	private_protocol_common "github.com/atframework/atsf4g-go/component/protocol/private/common/protocol/common"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	router "github.com/atframework/atsf4g-go/component/router"
+	test_utility "github.com/atframework/atsf4g-go/component/test_utility"
+	uc "github.com/atframework/atsf4g-go/component/user_controller"
+)
+
+const (
+	testLifecycleZoneId   uint32 = 1
+	testLifecycleUserId   uint64 = 30001
+	testLifecycleOpenId          = "lifecycle-open-id"
+	testLifecycleNickName        = "lifecycle-nick"
+	testLifecycleMailId   int64  = 880001
+	testLifecycleFriendId uint64 = 30002
+)
+
+// createAccountLifecycleTestContext 内存存储后端，加上踢人流程依赖的 UserManager 和路由管理器
+func createAccountLifecycleTestContext(now time.Time) *test_utility.MockRpcContext {
+	app, _ := test_utility.CreateMemoryStorageApp()
+	libatapp.AtappAddModule(app, router.CreateRouterManagerSet(app))
+	libatapp.AtappAddModule(app, uc.CreateUserManager(app))
+	uc.InitUserRouterManager(app)
+	return test_utility.NewMockRpcContextWithNow(app, now)
+}
+
+// seedAccountLifecycleData 写入一个玩家在各个表中的数据
+func seedAccountLifecycleData(t *testing.T, ctx *test_utility.MockRpcContext) {
+	userTb := &private_protocol_pbdesc.DatabaseTableUser{
+		ZoneId: testLifecycleZoneId,
+		UserId: testLifecycleUserId,
+		OpenId: testLifecycleOpenId,
+		AccountData: &private_protocol_pbdesc.AccountInformation{
+			Profile: &public_protocol_pbdesc.DUserProfile{
+				OpenId:   testLifecycleOpenId,
+				UserId:   testLifecycleUserId,
+				NickName: testLifecycleNickName,
+			},
+		},
+		UserData: &private_protocol_pbdesc.UserData{UserLevel: 12},
+		MailData: &private_protocol_pbdesc.UserMailData{
+			MailBox: []*public_protocol_pbdesc.DMailRecord{
+				{MailId: testLifecycleMailId},
+				{MailId: testLifecycleMailId + 1, IsGlobalMail: true},
+			},
+		},
+		FriendData: &private_protocol_pbdesc.UserFriendData{
+			Friends: []*public_protocol_pbdesc.DFriendData{{UserId: testLifecycleFriendId, ZoneId: testLifecycleZoneId}},
+		},
+	}
+	var userCASVersion uint64
+	assert.False(t, db.DatabaseTableUserReplaceZoneIdUserId(ctx, userTb, &userCASVersion, false).IsError())
+
+	assert.False(t, db.DatabaseTableAccessUpdateOpenId(ctx, &private_protocol_pbdesc.DatabaseTableAccess{
+		OpenId:    testLifecycleOpenId,
+		UserId:    testLifecycleUserId,
+		ZoneId:    testLifecycleZoneId,
+		LoginCode: "login-code",
+	}).IsError())
+
+	var loginLockCASVersion uint64
+	assert.False(t, db.DatabaseTableLoginLockReplaceUserId(ctx, &private_protocol_pbdesc.DatabaseTableLoginLock{
+		UserId:      testLifecycleUserId,
+		LoginZoneId: testLifecycleZoneId,
+	}, &loginLockCASVersion, false).IsError())
+
+	result, _ := db.DatabaseTableNameMappingAddTypeIdZoneIdName(ctx, &private_protocol_pbdesc.DatabaseTableNameMapping{
+		TypeId: uint32(private_protocol_common.NameMappingType_EN_NAME_MAPPING_TYPE_USER_NICKNAME),
+		ZoneId: testLifecycleZoneId,
+		Name:   testLifecycleNickName,
+		MappingData: &private_protocol_pbdesc.DatabaseNameMappingBlobData{
+			MappingType: &private_protocol_pbdesc.DatabaseNameMappingBlobData_UserMapping{
+				UserMapping: &private_protocol_pbdesc.UserMappingData{
+					UserId: testLifecycleUserId,
+					ZoneId: testLifecycleZoneId,
+				},
+			},
+		},
+	})
+	assert.False(t, result.IsError())
+
+	assert.False(t, db.DatabaseTableMailContentUpdateMailId(ctx, &private_protocol_pbdesc.DatabaseTableMailContent{
+		MailId: testLifecycleMailId,
+		JobData: &private_protocol_pbdesc.DatabaseMailContentBlobData{
+			MailContent: &public_protocol_pbdesc.DMailContent{MailId: testLifecycleMailId, Title: "hello"},
+		},
+	}).IsError())
+
+	result, _ = db.DatabaseTableUserAsyncJobsAddJobTypeUserIdZoneId(ctx, &private_protocol_pbdesc.DatabaseTableUserAsyncJobs{
+		JobType: int32(private_protocol_pbdesc.EnPlayerAsyncJobsType_EN_PAJT_NORMAL),
+		UserId:  testLifecycleUserId,
+		ZoneId:  testLifecycleZoneId,
+		JobData: &private_protocol_pbdesc.UserAsyncJobsBlobData{
+			ActionUuid: "job-1",
+			Action: &private_protocol_pbdesc.UserAsyncJobsBlobData_DebugMessage{
+				DebugMessage: &private_protocol_pbdesc.UserAsyncJobDebugMessage{Content: "debug"},
+			},
+		},
+	})
+	assert.False(t, result.IsError())
+
+	result, _ = db.DatabaseTableChatOfflineMessageAddZoneIdUserId(ctx, &private_protocol_pbdesc.DatabaseTableChatOfflineMessage{
+		ZoneId:  testLifecycleZoneId,
+		UserId:  testLifecycleUserId,
+		Message: &public_protocol_pbdesc.DChatMessage{MessageId: 1, FromUserId: testLifecycleFriendId, Content: "hi"},
+	})
+	assert.False(t, result.IsError())
+
+	assert.False(t, uc.AddUserSnapshot(ctx, userTb, userCASVersion, uc.UserSnapshotReasonGM).IsError())
+}
+
+func assertDBRecordNotFound(t *testing.T, result interface{ GetResponseCode() int32 }) {
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND), result.GetResponseCode())
+}
+
+// TestAccountDeletionRequestThenCancel 测试冷静期内撤销注销
+// Scenario: 申请后记录为 PENDING 且仍允许登入，撤销后记录和队列都被清理，玩家数据保持不变
+func TestAccountDeletionRequestThenCancel(t *testing.T) {
+	// Arrange
+	now := time.Unix(1700000000, 0)
+	ctx := createAccountLifecycleTestContext(now)
+	seedAccountLifecycleData(t, ctx)
+
+	// Act
+	requestTb, requestResult := RequestAccountDeletion(ctx, testLifecycleZoneId, testLifecycleUserId, "self", "test", time.Hour)
+	pendingLoginResult := CheckAccountLoginAllowed(ctx, testLifecycleOpenId)
+	cancelResult := CancelAccountDeletion(ctx, testLifecycleOpenId, "self")
+	cancelTb, _, loadResult := LoadAccountDeletion(ctx, testLifecycleOpenId)
+	queue, queueResult := db.DatabaseTableAccountDeletionQueueZRangeByScoreWithZoneId(ctx, testLifecycleZoneId,
+		db.SortedSetScoreBound{Type: db.ScoreBoundNegativeInfinity}, db.SortedSetScoreBound{Type: db.ScoreBoundPositiveInfinity},
+		0, 10, false)
+	userTb, _, userResult := db.DatabaseTableUserLoadWithZoneIdUserId(ctx, testLifecycleZoneId, testLifecycleUserId)
+
+	// Assert
+	assert.False(t, requestResult.IsError())
+	assert.Equal(t, private_protocol_pbdesc.EnAccountDeletionState_EN_ACCOUNT_DELETION_STATE_PENDING, requestTb.GetState())
+	assert.Equal(t, testLifecycleOpenId, requestTb.GetOpenId())
+	assert.Equal(t, now.Add(time.Hour).Unix(), requestTb.GetExecuteTime())
+	assert.False(t, pendingLoginResult.IsError())
+	assert.False(t, cancelResult.IsError())
+	assert.False(t, loadResult.IsError())
+	assert.Nil(t, cancelTb)
+	assert.False(t, queueResult.IsError())
+	assert.Empty(t, queue)
+	assert.False(t, userResult.IsError())
+	assert.Equal(t, uint32(12), userTb.GetUserData().GetUserLevel())
+}
+
+// TestAccountDeletionRequestTwice 测试重复申请注销
+// Scenario: 已经在冷静期内时再次申请返回 EN_ERR_ACCOUNT_DELETION_PENDING，执行时间不变
+func TestAccountDeletionRequestTwice(t *testing.T) {
+	// Arrange
+	now := time.Unix(1700000000, 0)
+	ctx := createAccountLifecycleTestContext(now)
+	seedAccountLifecycleData(t, ctx)
+	RequestAccountDeletion(ctx, testLifecycleZoneId, testLifecycleUserId, "self", "test", time.Hour)
+
+	// Act
+	ctx.SetNow(now.Add(time.Minute))
+	table, result := RequestAccountDeletion(ctx, testLifecycleZoneId, testLifecycleUserId, "self", "test", time.Hour)
+
+	// Assert
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_ACCOUNT_DELETION_PENDING), result.GetResponseCode())
+	assert.Equal(t, now.Add(time.Hour).Unix(), table.GetExecuteTime())
+}
+
+// TestAccountDeletionExecuteBeforeGracePeriod 测试冷静期内执行删除
+// Scenario: 冷静期未结束时执行删除不做任何修改
+func TestAccountDeletionExecuteBeforeGracePeriod(t *testing.T) {
+	// Arrange
+	now := time.Unix(1700000000, 0)
+	ctx := createAccountLifecycleTestContext(now)
+	seedAccountLifecycleData(t, ctx)
+	RequestAccountDeletion(ctx, testLifecycleZoneId, testLifecycleUserId, "self", "test", time.Hour)
+
+	// Act
+	ctx.SetNow(now.Add(30 * time.Minute))
+	result := ExecuteAccountDeletion(ctx, testLifecycleOpenId)
+	table, _, _ := LoadAccountDeletion(ctx, testLifecycleOpenId)
+	_, _, userResult := db.DatabaseTableUserLoadWithZoneIdUserId(ctx, testLifecycleZoneId, testLifecycleUserId)
+
+	// Assert
+	assert.False(t, result.IsError())
+	assert.Equal(t, private_protocol_pbdesc.EnAccountDeletionState_EN_ACCOUNT_DELETION_STATE_PENDING, table.GetState())
+	assert.False(t, userResult.IsError())
+}
+
+// TestAccountDeletionPurge 测试冷静期结束后执行删除
+// Scenario: 删除玩家在所有表中的数据并通知好友删除好友关系，只保留墓碑记录，之后的登入认证返回 EN_ERR_ACCOUNT_DELETED
+func TestAccountDeletionPurge(t *testing.T) {
+	// Arrange
+	now := time.Unix(1700000000, 0)
+	ctx := createAccountLifecycleTestContext(now)
+	seedAccountLifecycleData(t, ctx)
+	_, requestResult := RequestAccountDeletion(ctx, testLifecycleZoneId, testLifecycleUserId, "gm", "test", time.Hour)
+	assert.False(t, requestResult.IsError())
+
+	// Act
+	ctx.SetNow(now.Add(2 * time.Hour))
+	result := ExecuteAccountDeletion(ctx, testLifecycleOpenId)
+	secondResult := ExecuteAccountDeletion(ctx, testLifecycleOpenId)
+
+	// Assert
+	assert.False(t, result.IsError())
+	assert.False(t, secondResult.IsError())
+
+	tombstone, _, loadResult := LoadAccountDeletion(ctx, testLifecycleOpenId)
+	assert.False(t, loadResult.IsError())
+	assert.Equal(t, private_protocol_pbdesc.EnAccountDeletionState_EN_ACCOUNT_DELETION_STATE_DELETED, tombstone.GetState())
+	assert.Equal(t, now.Add(2*time.Hour).Unix(), tombstone.GetFinishTime())
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_ACCOUNT_DELETED),
+		CheckAccountLoginAllowed(ctx, testLifecycleOpenId).GetResponseCode())
+
+	_, _, userResult := db.DatabaseTableUserLoadWithZoneIdUserId(ctx, testLifecycleZoneId, testLifecycleUserId)
+	assertDBRecordNotFound(t, userResult)
+	_, accessResult := db.DatabaseTableAccessLoadWithOpenId(ctx, testLifecycleOpenId)
+	assertDBRecordNotFound(t, accessResult)
+	_, _, loginLockResult := db.DatabaseTableLoginLockLoadWithUserId(ctx, testLifecycleUserId)
+	assertDBRecordNotFound(t, loginLockResult)
+	_, _, mappingResult := db.DatabaseTableNameMappingLoadWithTypeIdZoneIdName(ctx,
+		uint32(private_protocol_common.NameMappingType_EN_NAME_MAPPING_TYPE_USER_NICKNAME), testLifecycleZoneId, testLifecycleNickName)
+	assertDBRecordNotFound(t, mappingResult)
+	_, mailResult := db.DatabaseTableMailContentLoadWithMailId(ctx, testLifecycleMailId)
+	assertDBRecordNotFound(t, mailResult)
+
+	jobs, _ := db.DatabaseTableUserAsyncJobsLoadAllWithJobTypeUserIdZoneId(ctx,
+		int32(private_protocol_pbdesc.EnPlayerAsyncJobsType_EN_PAJT_NORMAL), testLifecycleUserId, testLifecycleZoneId)
+	assert.Empty(t, jobs)
+	snapshots, snapshotResult := uc.ListUserSnapshots(ctx, testLifecycleZoneId, testLifecycleUserId)
+	assert.False(t, snapshotResult.IsError())
+	assert.Empty(t, snapshots)
+	chatMessages, _ := db.DatabaseTableChatOfflineMessageLoadAllWithZoneIdUserId(ctx, testLifecycleZoneId, testLifecycleUserId)
+	assert.Empty(t, chatMessages)
+	friendJobs, _ := db.DatabaseTableUserAsyncJobsLoadAllWithJobTypeUserIdZoneId(ctx,
+		int32(private_protocol_pbdesc.EnPlayerAsyncJobsType_EN_PAJT_FRIEND_REMOVE), testLifecycleFriendId, testLifecycleZoneId)
+	if assert.NotEmpty(t, friendJobs) {
+		friendMessage := friendJobs[0].Table.(*private_protocol_pbdesc.DatabaseTableUserAsyncJobs).GetJobData().GetFriendRemove()
+		assert.Equal(t, testLifecycleUserId, friendMessage.GetFromUserId())
+	}
+
+	queue, _ := db.DatabaseTableAccountDeletionQueueZRangeByScoreWithZoneId(ctx, testLifecycleZoneId,
+		db.SortedSetScoreBound{Type: db.ScoreBoundNegativeInfinity}, db.SortedSetScoreBound{Type: db.ScoreBoundPositiveInfinity},
+		0, 10, false)
+	assert.Empty(t, queue)
+}
+
+// TestAccountDeletionCancelAfterPurge 测试删除完成后撤销
+// Scenario: 已删除的账号不能撤销，墓碑保持 DELETED
+func TestAccountDeletionCancelAfterPurge(t *testing.T) {
+	// Arrange
+	now := time.Unix(1700000000, 0)
+	ctx := createAccountLifecycleTestContext(now)
+	seedAccountLifecycleData(t, ctx)
+	RequestAccountDeletion(ctx, testLifecycleZoneId, testLifecycleUserId, "self", "test", 0)
+	assert.False(t, ExecuteAccountDeletion(ctx, testLifecycleOpenId).IsError())
+
+	// Act
+	result := CancelAccountDeletion(ctx, testLifecycleOpenId, "self")
+	table, _, _ := LoadAccountDeletion(ctx, testLifecycleOpenId)
+
+	// Assert
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_ACCOUNT_DELETED), result.GetResponseCode())
+	assert.Equal(t, private_protocol_pbdesc.EnAccountDeletionState_EN_ACCOUNT_DELETION_STATE_DELETED, table.GetState())
+}
+
+// TestAccountDeletionDelayFailedEntry 测试执行失败的账号推迟到下一轮
+// Scenario: 失败的账号分数改为当前时间加扫描间隔，不会堵住队列中后面的账号；已经不在队列中的账号不会被重新加入
+func TestAccountDeletionDelayFailedEntry(t *testing.T) {
+	// Arrange
+	now := time.Unix(1700000000, 0)
+	ctx := createAccountLifecycleTestContext(now)
+	assert.False(t, db.DatabaseTableAccountDeletionQueueZAddWithZoneId(ctx, testLifecycleZoneId, []db.SortedSetMember{
+		{Member: "blocked-open-id", Score: float64(now.Unix() - 100)},
+		{Member: "next-open-id", Score: float64(now.Unix() - 50)},
+	}, db.ZAddExistenceNone, db.ZAddComparisonNone).IsError())
+
+	// Act
+	delayAccountDeletion(ctx, testLifecycleZoneId, "blocked-open-id")
+	delayAccountDeletion(ctx, testLifecycleZoneId, "removed-open-id")
+	due, dueResult := db.DatabaseTableAccountDeletionQueueZRangeByScoreWithZoneId(ctx, testLifecycleZoneId,
+		db.SortedSetScoreBound{Type: db.ScoreBoundNegativeInfinity}, db.SortedSetScoreBound{Type: db.ScoreBoundValue, Score: float64(now.Unix())},
+		0, 1, false)
+	all, _ := db.DatabaseTableAccountDeletionQueueZRangeByScoreWithZoneId(ctx, testLifecycleZoneId,
+		db.SortedSetScoreBound{Type: db.ScoreBoundNegativeInfinity}, db.SortedSetScoreBound{Type: db.ScoreBoundPositiveInfinity},
+		0, 10, false)
+
+	// Assert
+	assert.False(t, dueResult.IsError())
+	if assert.Len(t, due, 1) {
+		assert.Equal(t, "next-open-id", due[0].Member)
+	}
+	if assert.Len(t, all, 2) {
+		assert.Equal(t, "blocked-open-id", all[1].Member)
+		assert.Equal(t, float64(now.Unix()+getAccountDeletionScanInterval()), all[1].Score)
+	}
+}
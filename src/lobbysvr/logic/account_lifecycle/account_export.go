@@ -0,0 +1,204 @@
+// Copyright 2026 atframework
+// @brief 账号数据导出，打包所有引用 user_id 或 open_id 的数据
+
+package lobbysvr_logic_account_lifecycle
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	db "github.com/atframework/atsf4g-go/component/db"
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	operation_support_system "github.com/atframework/atsf4g-go/component/operation_support_system"
+	private_protocol_common "github.com/atframework/atsf4g-go/component/protocol/private/common/protocol/common"
+	private_protocol_log "github.com/atframework/atsf4g-go/component/protocol/private/log/protocol/log"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	uc "github.com/atframework/atsf4g-go/component/user_controller"
+)
+
+var accountExportMarshalOptions = protojson.MarshalOptions{
+	Multiline:     true,
+	Indent:        "  ",
+	UseProtoNames: true,
+}
+
+// ExportAccount 导出账号数据，openId 为空时从 user 表中读取
+// 支付订单不在导出范围内
+func ExportAccount(ctx cd.AwaitableContext, zoneId uint32, userId uint64, openId string) (*private_protocol_pbdesc.AccountExportArchive, cd.RpcResult) {
+	archive := &private_protocol_pbdesc.AccountExportArchive{
+		ExportTime: ctx.GetSysNow().Unix(),
+		ZoneId:     zoneId,
+		UserId:     userId,
+		OpenId:     openId,
+	}
+
+	// user 和 login_lock 都只依赖 user_id，合并成一次往返
+	pipeline := db.CreatePipeline(ctx, false)
+	userLoad := db.DatabaseTableUserPipelineLoadWithZoneIdUserId(pipeline, zoneId, userId)
+	loginLockLoad := db.DatabaseTableLoginLockPipelineLoadWithUserId(pipeline, userId)
+	result := pipeline.Execute()
+	if result.IsError() {
+		return nil, result
+	}
+	if userLoad.Result.IsError() && !isDBRecordNotFound(userLoad.Result) {
+		return nil, userLoad.Result
+	}
+	if loginLockLoad.Result.IsError() && !isDBRecordNotFound(loginLockLoad.Result) {
+		return nil, loginLockLoad.Result
+	}
+	userTb := userLoad.Table
+	archive.User = userTb
+	archive.LoginLock = loginLockLoad.Table
+	if archive.GetOpenId() == "" {
+		archive.OpenId = userTb.GetOpenId()
+	}
+	if archive.GetOpenId() == "" {
+		return nil, cd.CreateRpcResultError(fmt.Errorf("open_id of user %d not found", userId), public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND)
+	}
+
+	archive.Access, result = db.DatabaseTableAccessLoadWithOpenId(ctx, archive.GetOpenId())
+	if result.IsError() && !isDBRecordNotFound(result) {
+		return nil, result
+	}
+
+	archive.Deletion, _, result = LoadAccountDeletion(ctx, archive.GetOpenId())
+	if result.IsError() {
+		return nil, result
+	}
+
+	if nickName := userTb.GetAccountData().GetProfile().GetNickName(); nickName != "" {
+		mappingTb, _, result := db.DatabaseTableNameMappingLoadWithTypeIdZoneIdName(ctx,
+			uint32(private_protocol_common.NameMappingType_EN_NAME_MAPPING_TYPE_USER_NICKNAME), zoneId, nickName)
+		if result.IsError() && !isDBRecordNotFound(result) {
+			return nil, result
+		}
+		if mappingTb != nil && mappingTb.GetMappingData().GetUserMapping().GetUserId() == userId {
+			archive.NameMapping = append(archive.NameMapping, mappingTb)
+		}
+	}
+
+	for _, jobType := range getAllAsyncJobsTypes() {
+		jobs, result := db.DatabaseTableUserAsyncJobsLoadAllWithJobTypeUserIdZoneId(ctx, jobType, userId, zoneId)
+		if result.IsError() && !isDBRecordNotFound(result) {
+			return nil, result
+		}
+		for _, job := range jobs {
+			table, ok := job.Table.(*private_protocol_pbdesc.DatabaseTableUserAsyncJobs)
+			if !ok {
+				continue
+			}
+			archive.AsyncJobs = append(archive.AsyncJobs, &private_protocol_pbdesc.AccountExportArchive_AsyncJobsItem{
+				ListIndex: job.ListIndex,
+				Table:     table,
+			})
+		}
+	}
+
+	if userTb != nil {
+		for _, mailId := range getUserMailContentIds(userTb) {
+			mailTb, result := db.DatabaseTableMailContentLoadWithMailId(ctx, mailId)
+			if result.IsError() {
+				if isDBRecordNotFound(result) {
+					continue
+				}
+				return nil, result
+			}
+			archive.MailContent = append(archive.MailContent, mailTb)
+		}
+	}
+
+	snapshots, result := uc.ListUserSnapshots(ctx, zoneId, userId)
+	if result.IsError() {
+		return nil, result
+	}
+	for _, snapshot := range snapshots {
+		archive.UserSnapshot = append(archive.UserSnapshot, &private_protocol_pbdesc.AccountExportArchive_SnapshotItem{
+			ListIndex: snapshot.ListIndex,
+			Table: &private_protocol_pbdesc.DatabaseTableUserSnapshot{
+				ZoneId:       zoneId,
+				UserId:       userId,
+				SnapshotData: snapshot.Data,
+			},
+		})
+	}
+
+	chatMessages, result := db.DatabaseTableChatOfflineMessageLoadAllWithZoneIdUserId(ctx, zoneId, userId)
+	if result.IsError() && !isDBRecordNotFound(result) {
+		return nil, result
+	}
+	for _, message := range chatMessages {
+		table, ok := message.Table.(*private_protocol_pbdesc.DatabaseTableChatOfflineMessage)
+		if !ok {
+			continue
+		}
+		archive.ChatOfflineMessage = append(archive.ChatOfflineMessage, &private_protocol_pbdesc.AccountExportArchive_ChatOfflineMessageItem{
+			ListIndex: message.ListIndex,
+			Table:     table,
+		})
+	}
+
+	if guildId := userTb.GetGuildData().GetGuildId(); guildId != 0 {
+		guildTb, _, result := db.DatabaseTableGuildLoadWithZoneIdGuildId(ctx, userTb.GetGuildData().GetGuildZoneId(), guildId)
+		if result.IsError() && !isDBRecordNotFound(result) {
+			return nil, result
+		}
+		archive.Guild = guildTb
+	}
+
+	member := getUserRankMember(userId)
+	for _, rankCfg := range getAccountDeletionConfig().GetRank() {
+		rank, found, result := db.DatabaseTableRankZRankWithIdTypeVersion(ctx, rankCfg.GetId(), rankCfg.GetType(), rankCfg.GetVersion(), member, true)
+		if result.IsError() {
+			return nil, result
+		}
+		if !found {
+			continue
+		}
+		archive.Rank = append(archive.Rank, &private_protocol_pbdesc.AccountExportArchive_RankItem{
+			Id:      rankCfg.GetId(),
+			Type:    rankCfg.GetType(),
+			Version: rankCfg.GetVersion(),
+			Rank:    rank.Rank,
+			Score:   rank.Score,
+		})
+	}
+
+	return archive, cd.CreateRpcResultOk()
+}
+
+// WriteAccountExportArchive 把导出数据以 protojson 格式写到 export_path 目录下，返回文件路径
+func WriteAccountExportArchive(ctx cd.AwaitableContext, archive *private_protocol_pbdesc.AccountExportArchive) (string, cd.RpcResult) {
+	data, err := accountExportMarshalOptions.Marshal(archive)
+	if err != nil {
+		ctx.LogError("marshal account export archive failed", "user_id", archive.GetUserId(), "error", err)
+		return "", cd.CreateRpcResultError(err, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+	}
+
+	exportPath := getAccountDeletionConfig().GetExportPath()
+	if err = os.MkdirAll(exportPath, 0o750); err != nil {
+		ctx.LogError("create account export path failed", "path", exportPath, "error", err)
+		return "", cd.CreateRpcResultError(err, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+	}
+
+	fileName := filepath.Join(exportPath, fmt.Sprintf("%d_%d_%d.json", archive.GetZoneId(), archive.GetUserId(), archive.GetExportTime()))
+	if err = os.WriteFile(fileName, data, 0o640); err != nil {
+		ctx.LogError("write account export archive failed", "file", fileName, "error", err)
+		return "", cd.CreateRpcResultError(err, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+	}
+
+	ctx.LogInfo("write account export archive success", "file", fileName, "user_id", archive.GetUserId(), "size", len(data))
+
+	log := &private_protocol_log.OperationSupportSystemLog{}
+	log.MutableBasic().UserId = archive.GetUserId()
+	log.MutableBasic().ZoneId = archive.GetZoneId()
+	log.MutableBasic().OpenId = archive.GetOpenId()
+	flow := log.MutableLog().MutableAccountDeletionFlow()
+	flow.OperationType = private_protocol_log.OSSAccountDeletionFlow_EN_OSS_ACCOUNT_DELETION_OPERATION_TYPE_EXPORT
+	flow.ExportFile = fileName
+	operation_support_system.SendOssLog(ctx.GetApp(), log)
+	return fileName, cd.CreateRpcResultOk()
+}
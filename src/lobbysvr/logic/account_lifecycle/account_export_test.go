@@ -0,0 +1,87 @@
+package lobbysvr_logic_account_lifecycle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	uc "github.com/atframework/atsf4g-go/component/user_controller"
+)
+
+// TestExportAccountContents 测试导出内容
+// Scenario: 导出包含玩家在各个表中的数据，open_id 为空时从 user 表中读取，全服邮件不导出内容
+func TestExportAccountContents(t *testing.T) {
+	// Arrange
+	now := time.Unix(1700000000, 0)
+	ctx := createAccountLifecycleTestContext(now)
+	seedAccountLifecycleData(t, ctx)
+
+	// Act
+	archive, result := ExportAccount(ctx, testLifecycleZoneId, testLifecycleUserId, "")
+
+	// Assert
+	assert.False(t, result.IsError())
+	assert.Equal(t, now.Unix(), archive.GetExportTime())
+	assert.Equal(t, testLifecycleOpenId, archive.GetOpenId())
+	assert.Equal(t, uint32(12), archive.GetUser().GetUserData().GetUserLevel())
+	assert.Equal(t, testLifecycleUserId, archive.GetAccess().GetUserId())
+	assert.Equal(t, "login-code", archive.GetAccess().GetLoginCode())
+	assert.Equal(t, testLifecycleZoneId, archive.GetLoginLock().GetLoginZoneId())
+	assert.Nil(t, archive.GetDeletion())
+
+	assert.Len(t, archive.GetNameMapping(), 1)
+	assert.Equal(t, testLifecycleNickName, archive.GetNameMapping()[0].GetName())
+
+	assert.Len(t, archive.GetMailContent(), 1)
+	assert.Equal(t, testLifecycleMailId, archive.GetMailContent()[0].GetMailId())
+	assert.Equal(t, "hello", archive.GetMailContent()[0].GetJobData().GetMailContent().GetTitle())
+
+	assert.Len(t, archive.GetAsyncJobs(), 1)
+	assert.Equal(t, int32(private_protocol_pbdesc.EnPlayerAsyncJobsType_EN_PAJT_NORMAL), archive.GetAsyncJobs()[0].GetTable().GetJobType())
+	assert.Equal(t, "job-1", archive.GetAsyncJobs()[0].GetTable().GetJobData().GetActionUuid())
+
+	assert.Len(t, archive.GetUserSnapshot(), 1)
+	snapshotTb, err := uc.DecodeUserSnapshot(archive.GetUserSnapshot()[0].GetTable().GetSnapshotData())
+	assert.NoError(t, err)
+	assert.Equal(t, testLifecycleUserId, snapshotTb.GetUserId())
+	assert.Empty(t, archive.GetRank())
+
+	assert.Len(t, archive.GetChatOfflineMessage(), 1)
+	assert.Equal(t, "hi", archive.GetChatOfflineMessage()[0].GetTable().GetMessage().GetContent())
+	assert.Nil(t, archive.GetGuild())
+}
+
+// TestExportAccountWithPendingDeletion 测试冷静期内导出
+// Scenario: 冷静期内导出时包含注销记录
+func TestExportAccountWithPendingDeletion(t *testing.T) {
+	// Arrange
+	now := time.Unix(1700000000, 0)
+	ctx := createAccountLifecycleTestContext(now)
+	seedAccountLifecycleData(t, ctx)
+	RequestAccountDeletion(ctx, testLifecycleZoneId, testLifecycleUserId, "self", "test", time.Hour)
+
+	// Act
+	archive, result := ExportAccount(ctx, testLifecycleZoneId, testLifecycleUserId, testLifecycleOpenId)
+
+	// Assert
+	assert.False(t, result.IsError())
+	assert.Equal(t, private_protocol_pbdesc.EnAccountDeletionState_EN_ACCOUNT_DELETION_STATE_PENDING, archive.GetDeletion().GetState())
+	assert.Equal(t, "self", archive.GetDeletion().GetOperator())
+}
+
+// TestExportAccountUserNotFound 测试导出不存在的玩家
+// Scenario: 没有 user 表也没有传入 open_id 时返回 EN_ERR_DB_RECORD_NOT_FOUND
+func TestExportAccountUserNotFound(t *testing.T) {
+	// Arrange
+	ctx := createAccountLifecycleTestContext(time.Unix(1700000000, 0))
+
+	// Act
+	archive, result := ExportAccount(ctx, testLifecycleZoneId, testLifecycleUserId, "")
+
+	// Assert
+	assert.Nil(t, archive)
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND), result.GetResponseCode())
+}
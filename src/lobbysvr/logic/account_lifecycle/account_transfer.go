@@ -0,0 +1,434 @@
+// Copyright 2026 atframework
+// @brief 角色转区：占用登入锁后把玩家数据迁移到目标大区，并在原大区留下跳转记录
+
+package lobbysvr_logic_account_lifecycle
+
+import (
+	"fmt"
+
+	"github.com/atframework/libatapp-go"
+	"google.golang.org/protobuf/proto"
+
+	config "github.com/atframework/atsf4g-go/component/config"
+	db "github.com/atframework/atsf4g-go/component/db"
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	operation_support_system "github.com/atframework/atsf4g-go/component/operation_support_system"
+	private_protocol_common "github.com/atframework/atsf4g-go/component/protocol/private/common/protocol/common"
+	private_protocol_log "github.com/atframework/atsf4g-go/component/protocol/private/log/protocol/log"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	uc "github.com/atframework/atsf4g-go/component/user_controller"
+
+	logic_guild "github.com/atframework/atsf4g-go/service-lobbysvr/logic/guild"
+)
+
+// userZoneTransferResult 转区过程中迁移的数据统计，用于 OSS 日志
+type userZoneTransferResult struct {
+	asyncJobs           int32
+	chatOfflineMessages int32
+	snapshot            int32
+	nickNameConflict    bool
+	leftGuildId         uint64
+}
+
+// LoadUserZoneRedirect 读取转区跳转记录，不存在时返回 nil
+func LoadUserZoneRedirect(ctx cd.AwaitableContext, zoneId uint32, userId uint64) (*private_protocol_pbdesc.DatabaseTableUserZoneRedirect, cd.RpcResult) {
+	table, result := db.DatabaseTableUserZoneRedirectLoadWithZoneIdUserId(ctx, zoneId, userId)
+	if result.IsError() {
+		if isDBRecordNotFound(result) {
+			return nil, cd.CreateRpcResultOk()
+		}
+		return nil, result
+	}
+	return table, result
+}
+
+// lockUserLogin 占用登入锁，防止迁移期间登入或者旧进程继续存盘
+// 玩家在其他进程在线时返回 EN_ERR_ALREADY_LOGIN
+func lockUserLogin(ctx cd.AwaitableContext, zoneId uint32, userId uint64) (*private_protocol_pbdesc.DatabaseTableLoginLock, uint64, cd.RpcResult) {
+	loginLockTb, casVersion, result := db.DatabaseTableLoginLockLoadWithUserId(ctx, userId)
+	if result.IsError() {
+		if !isDBRecordNotFound(result) {
+			return nil, 0, result
+		}
+		loginLockTb = &private_protocol_pbdesc.DatabaseTableLoginLock{UserId: userId}
+		casVersion = 0
+	}
+
+	selfServerId := uint64(config.GetConfigManager().GetLogicId())
+	if loginLockTb.GetRouterServerId() != 0 && loginLockTb.GetRouterServerId() != selfServerId &&
+		ctx.GetSysNow().Unix() < loginLockTb.GetLoginExpired() {
+		return nil, 0, cd.CreateRpcResultError(fmt.Errorf("user is online on server 0x%x", loginLockTb.GetRouterServerId()),
+			public_protocol_pbdesc.EnErrorCode_EN_ERR_ALREADY_LOGIN)
+	}
+
+	loginLockTb.RouterServerId = selfServerId
+	loginLockTb.RouterVersion = loginLockTb.GetRouterVersion() + 1
+	loginLockTb.LoginZoneId = zoneId
+	loginLockTb.LoginExpired = ctx.GetSysNow().Unix() +
+		config.GetConfigManager().GetCurrentConfigGroup().GetSectionConfig().GetSession().GetLoginCodeValidSec().GetSeconds()
+	result = db.DatabaseTableLoginLockReplaceUserId(ctx, loginLockTb, &casVersion, false)
+	if result.IsError() {
+		return nil, 0, result
+	}
+	return loginLockTb, casVersion, result
+}
+
+func unlockUserLogin(ctx cd.AwaitableContext, loginLockTb *private_protocol_pbdesc.DatabaseTableLoginLock, casVersion uint64, zoneId uint32) cd.RpcResult {
+	loginLockTb.RouterServerId = 0
+	loginLockTb.RouterVersion = loginLockTb.GetRouterVersion() + 1
+	loginLockTb.LoginZoneId = zoneId
+	loginLockTb.LoginExpired = 0
+	loginLockTb.ExpectTableUserDbVersion = 0
+	return db.DatabaseTableLoginLockReplaceUserId(ctx, loginLockTb, &casVersion, false)
+}
+
+// TransferUserZone 把玩家从 sourceZoneId 迁移到 targetZoneId，user_id 全局唯一，迁移后保持不变
+// 迁移 user 表、异步任务（包含待投递的邮件）、离线私聊、快照，更新 access 表和昵称映射，并在原大区写入跳转记录
+// 公会按大区存储，转区时强制退出原大区的公会
+// 邮件内容按 mail_id 存储，不需要迁移；原大区的区服邮件在目标大区会因为找不到而被清理
+// 中途失败可以重新执行，目标大区的 user 表会被覆盖，已经迁移过的异步任务和离线私聊不会重复写入
+// 需要在目标玩家的 ActorExecutor 中执行
+func TransferUserZone(ctx cd.AwaitableContext, sourceZoneId uint32, userId uint64, targetZoneId uint32,
+	operator string, reason string,
+) cd.RpcResult {
+	if sourceZoneId == targetZoneId || targetZoneId == 0 {
+		return cd.CreateRpcResultError(fmt.Errorf("invalid target zone %d", targetZoneId), public_protocol_pbdesc.EnErrorCode_EN_ERR_INVALID_PARAM)
+	}
+
+	userTb, _, result := db.DatabaseTableUserLoadWithZoneIdUserId(ctx, sourceZoneId, userId)
+	if result.IsError() {
+		return result
+	}
+	openId := userTb.GetOpenId()
+
+	deletion, _, result := LoadAccountDeletion(ctx, openId)
+	if result.IsError() {
+		return result
+	}
+	if deletion != nil && deletion.GetState() != private_protocol_pbdesc.EnAccountDeletionState_EN_ACCOUNT_DELETION_STATE_NONE {
+		return cd.CreateRpcResultError(fmt.Errorf("account %s is pending deletion", openId), public_protocol_pbdesc.EnErrorCode_EN_ERR_ACCOUNT_DELETION_PENDING)
+	}
+
+	// 踢下线，会先走一次正常的存盘和登出流程
+	result = libatapp.AtappGetModule[*uc.UserManager](ctx.GetApp()).Remove(ctx, sourceZoneId, userId, nil, true)
+	if result.IsError() {
+		result.LogError(ctx, "kickoff user before zone transfer failed", "zone_id", sourceZoneId, "user_id", userId)
+		return result
+	}
+
+	loginLockTb, loginLockCASVersion, result := lockUserLogin(ctx, sourceZoneId, userId)
+	if result.IsError() {
+		result.LogWarn(ctx, "lock login lock before zone transfer failed", "zone_id", sourceZoneId, "user_id", userId)
+		return result
+	}
+
+	transferResult, result := transferUserZoneData(ctx, sourceZoneId, userId, targetZoneId, operator)
+
+	// 失败时登入锁还给原大区
+	unlockZoneId := targetZoneId
+	if result.IsError() {
+		unlockZoneId = sourceZoneId
+	}
+	unlockResult := unlockUserLogin(ctx, loginLockTb, loginLockCASVersion, unlockZoneId)
+	if unlockResult.IsError() {
+		unlockResult.LogError(ctx, "unlock login lock after zone transfer failed", "zone_id", sourceZoneId, "user_id", userId)
+	}
+
+	if result.IsError() {
+		result.LogError(ctx, "transfer user zone failed", "source_zone_id", sourceZoneId, "target_zone_id", targetZoneId, "user_id", userId)
+	} else {
+		ctx.LogInfo("transfer user zone success", "source_zone_id", sourceZoneId, "target_zone_id", targetZoneId,
+			"user_id", userId, "open_id", openId, "operator", operator, "reason", reason,
+			"moved_async_jobs", transferResult.asyncJobs, "moved_chat_offline_messages", transferResult.chatOfflineMessages,
+			"moved_snapshot", transferResult.snapshot, "nick_name_conflict", transferResult.nickNameConflict,
+			"left_guild_id", transferResult.leftGuildId)
+	}
+
+	log := &private_protocol_log.OperationSupportSystemLog{}
+	log.MutableBasic().UserId = userId
+	log.MutableBasic().ZoneId = sourceZoneId
+	log.MutableBasic().OpenId = openId
+	flow := log.MutableLog().MutableZoneTransferFlow()
+	flow.SourceZoneId = sourceZoneId
+	flow.TargetZoneId = targetZoneId
+	flow.Operator = operator
+	flow.Reason = reason
+	flow.MovedAsyncJobsCount = transferResult.asyncJobs
+	flow.MovedSnapshotCount = transferResult.snapshot
+	flow.NickNameConflict = transferResult.nickNameConflict
+	flow.Result = result.GetResponseCode()
+	operation_support_system.SendOssLog(ctx.GetApp(), log)
+
+	if result.IsError() {
+		return result
+	}
+	return unlockResult
+}
+
+// transferUserZoneData 先写目标大区，access 表切换后再删除原大区数据
+func transferUserZoneData(ctx cd.AwaitableContext, sourceZoneId uint32, userId uint64, targetZoneId uint32,
+	operator string,
+) (userZoneTransferResult, cd.RpcResult) {
+	ret := userZoneTransferResult{}
+
+	// 踢下线后重新读取，拿到最终存盘的数据
+	userTb, _, result := db.DatabaseTableUserLoadWithZoneIdUserId(ctx, sourceZoneId, userId)
+	if result.IsError() {
+		return ret, result
+	}
+	openId := userTb.GetOpenId()
+
+	ret.nickNameConflict, result = transferUserNickName(ctx, userTb, sourceZoneId, targetZoneId)
+	if result.IsError() {
+		return ret, result
+	}
+
+	ret.leftGuildId, result = leaveUserGuild(ctx, userTb)
+	if result.IsError() {
+		return ret, result
+	}
+
+	targetTb := userTb.Clone()
+	targetTb.ZoneId = targetZoneId
+	var targetCASVersion uint64
+	result = db.DatabaseTableUserReplaceZoneIdUserId(ctx, targetTb, &targetCASVersion, true)
+	if result.IsError() {
+		return ret, result
+	}
+
+	for _, jobType := range getAllAsyncJobsTypes() {
+		count, result := transferUserAsyncJobs(ctx, jobType, userId, sourceZoneId, targetZoneId)
+		if result.IsError() {
+			return ret, result
+		}
+		ret.asyncJobs += count
+	}
+
+	ret.chatOfflineMessages, result = transferChatOfflineMessages(ctx, userId, sourceZoneId, targetZoneId)
+	if result.IsError() {
+		return ret, result
+	}
+
+	snapshots, result := uc.ListUserSnapshots(ctx, sourceZoneId, userId)
+	if result.IsError() {
+		return ret, result
+	}
+	if len(snapshots) > 0 {
+		for _, snapshot := range snapshots {
+			if result = uc.ImportUserSnapshot(ctx, targetZoneId, userId, snapshot.Data); result.IsError() {
+				return ret, result
+			}
+			ret.snapshot++
+		}
+		if _, result = uc.RemoveAllUserSnapshots(ctx, sourceZoneId, userId); result.IsError() {
+			return ret, result
+		}
+	}
+
+	accessTb, result := db.DatabaseTableAccessLoadWithOpenId(ctx, openId)
+	if result.IsError() {
+		return ret, result
+	}
+	if accessTb.GetUserId() == userId {
+		// 保留登入码，客户端使用原大区登入时拿到跳转的大区ID后可以直接用同一个登入码登入新大区
+		accessTb.ZoneId = targetZoneId
+		result = db.DatabaseTableAccessUpdateOpenId(ctx, accessTb)
+		if result.IsError() {
+			return ret, result
+		}
+	}
+
+	result = db.DatabaseTableUserZoneRedirectUpdateZoneIdUserId(ctx, &private_protocol_pbdesc.DatabaseTableUserZoneRedirect{
+		ZoneId:       sourceZoneId,
+		UserId:       userId,
+		TargetZoneId: targetZoneId,
+		TransferTime: ctx.GetSysNow().Unix(),
+		Operator:     operator,
+	})
+	if result.IsError() {
+		return ret, result
+	}
+
+	result = db.DatabaseTableUserDelWithZoneIdUserId(ctx, sourceZoneId, userId)
+	if result.IsError() && !isDBRecordNotFound(result) {
+		return ret, result
+	}
+
+	return ret, cd.CreateRpcResultOk()
+}
+
+// leaveUserGuild 强制退出公会并清理玩家身上的公会归属，返回退出的公会ID
+func leaveUserGuild(ctx cd.AwaitableContext, userTb *private_protocol_pbdesc.DatabaseTableUser) (uint64, cd.RpcResult) {
+	guildId := userTb.GetGuildData().GetGuildId()
+	if guildId == 0 {
+		return 0, cd.CreateRpcResultOk()
+	}
+
+	guildManager := logic_guild.GetGuildManager(ctx.GetApp())
+	if guildManager == nil {
+		return 0, cd.CreateRpcResultError(fmt.Errorf("guild manager not registered"), public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+	}
+	resultCode := guildManager.RemoveMember(ctx, userTb.GetGuildData().GetGuildZoneId(), guildId, userTb.GetUserId(), userTb.GetZoneId())
+	if resultCode != 0 {
+		return 0, cd.CreateRpcResultError(fmt.Errorf("leave guild %d failed", guildId), public_protocol_pbdesc.EnErrorCode(resultCode))
+	}
+
+	userTb.GuildData = nil
+	return guildId, cd.CreateRpcResultOk()
+}
+
+// transferUserAsyncJobs 先写目标大区再删除原大区，重新执行时跳过目标大区已经存在的任务，避免重复发放
+func transferUserAsyncJobs(ctx cd.AwaitableContext, jobType int32, userId uint64, sourceZoneId uint32, targetZoneId uint32) (int32, cd.RpcResult) {
+	jobs, result := db.DatabaseTableUserAsyncJobsLoadAllWithJobTypeUserIdZoneId(ctx, jobType, userId, sourceZoneId)
+	if result.IsError() && !isDBRecordNotFound(result) {
+		return 0, result
+	}
+	if len(jobs) == 0 {
+		return 0, cd.CreateRpcResultOk()
+	}
+
+	targetJobs, result := db.DatabaseTableUserAsyncJobsLoadAllWithJobTypeUserIdZoneId(ctx, jobType, userId, targetZoneId)
+	if result.IsError() && !isDBRecordNotFound(result) {
+		return 0, result
+	}
+	existed := make([]*private_protocol_pbdesc.UserAsyncJobsBlobData, 0, len(targetJobs))
+	for _, job := range targetJobs {
+		if table, ok := job.Table.(*private_protocol_pbdesc.DatabaseTableUserAsyncJobs); ok {
+			existed = append(existed, table.GetJobData())
+		}
+	}
+
+	var count int32
+	for _, job := range jobs {
+		table, ok := job.Table.(*private_protocol_pbdesc.DatabaseTableUserAsyncJobs)
+		if !ok {
+			continue
+		}
+		if containsAsyncJob(existed, table.GetJobData()) {
+			continue
+		}
+		table.ZoneId = targetZoneId
+		if result, _ = db.DatabaseTableUserAsyncJobsAddJobTypeUserIdZoneId(ctx, table); result.IsError() {
+			return count, result
+		}
+		count++
+	}
+
+	result = db.DatabaseTableUserAsyncJobsDelWithJobTypeUserIdZoneId(ctx, jobType, userId, sourceZoneId)
+	if result.IsError() && !isDBRecordNotFound(result) {
+		return count, result
+	}
+	return count, cd.CreateRpcResultOk()
+}
+
+// containsAsyncJob 异步任务带有 action_uuid，内容完全相同时视为同一个任务
+func containsAsyncJob(jobs []*private_protocol_pbdesc.UserAsyncJobsBlobData, job *private_protocol_pbdesc.UserAsyncJobsBlobData) bool {
+	for _, existed := range jobs {
+		if proto.Equal(existed, job) {
+			return true
+		}
+	}
+	return false
+}
+
+// transferChatOfflineMessages 和异步任务一样先写后删，按 message_id 跳过已经迁移的消息
+func transferChatOfflineMessages(ctx cd.AwaitableContext, userId uint64, sourceZoneId uint32, targetZoneId uint32) (int32, cd.RpcResult) {
+	messages, result := db.DatabaseTableChatOfflineMessageLoadAllWithZoneIdUserId(ctx, sourceZoneId, userId)
+	if result.IsError() && !isDBRecordNotFound(result) {
+		return 0, result
+	}
+	if len(messages) == 0 {
+		return 0, cd.CreateRpcResultOk()
+	}
+
+	targetMessages, result := db.DatabaseTableChatOfflineMessageLoadAllWithZoneIdUserId(ctx, targetZoneId, userId)
+	if result.IsError() && !isDBRecordNotFound(result) {
+		return 0, result
+	}
+	existed := make(map[uint64]struct{}, len(targetMessages))
+	for _, message := range targetMessages {
+		if table, ok := message.Table.(*private_protocol_pbdesc.DatabaseTableChatOfflineMessage); ok {
+			existed[table.GetMessage().GetMessageId()] = struct{}{}
+		}
+	}
+
+	var count int32
+	for _, message := range messages {
+		table, ok := message.Table.(*private_protocol_pbdesc.DatabaseTableChatOfflineMessage)
+		if !ok || table.GetMessage() == nil {
+			continue
+		}
+		if _, ok = existed[table.GetMessage().GetMessageId()]; ok {
+			continue
+		}
+		table.ZoneId = targetZoneId
+		table.GetMessage().ToZoneId = targetZoneId
+		if result, _ = db.DatabaseTableChatOfflineMessageAddZoneIdUserId(ctx, table); result.IsError() {
+			return count, result
+		}
+		count++
+	}
+
+	result = db.DatabaseTableChatOfflineMessageDelWithZoneIdUserId(ctx, sourceZoneId, userId)
+	if result.IsError() && !isDBRecordNotFound(result) {
+		return count, result
+	}
+	return count, cd.CreateRpcResultOk()
+}
+
+// transferUserNickName 在目标大区占用昵称，冲突时清空昵称让玩家重新起名
+func transferUserNickName(ctx cd.AwaitableContext, userTb *private_protocol_pbdesc.DatabaseTableUser, sourceZoneId uint32, targetZoneId uint32) (bool, cd.RpcResult) {
+	nickName := userTb.GetAccountData().GetProfile().GetNickName()
+	if nickName == "" {
+		return false, cd.CreateRpcResultOk()
+	}
+
+	conflict := false
+	result, _ := db.DatabaseTableNameMappingAddTypeIdZoneIdName(ctx, &private_protocol_pbdesc.DatabaseTableNameMapping{
+		TypeId: uint32(private_protocol_common.NameMappingType_EN_NAME_MAPPING_TYPE_USER_NICKNAME),
+		ZoneId: targetZoneId,
+		Name:   nickName,
+		MappingData: &private_protocol_pbdesc.DatabaseNameMappingBlobData{
+			MappingType: &private_protocol_pbdesc.DatabaseNameMappingBlobData_UserMapping{
+				UserMapping: &private_protocol_pbdesc.UserMappingData{
+					UserId: userTb.GetUserId(),
+					ZoneId: targetZoneId,
+				},
+			},
+		},
+	})
+	if result.IsError() {
+		if result.GetResponseCode() != int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_EXIST) {
+			return false, result
+		}
+
+		// 重试时昵称可能已经是自己的
+		mappingTb, _, loadResult := db.DatabaseTableNameMappingLoadWithTypeIdZoneIdName(ctx,
+			uint32(private_protocol_common.NameMappingType_EN_NAME_MAPPING_TYPE_USER_NICKNAME), targetZoneId, nickName)
+		if loadResult.IsError() {
+			return false, loadResult
+		}
+		if mappingTb.GetMappingData().GetUserMapping().GetUserId() != userTb.GetUserId() {
+			conflict = true
+			userTb.MutableAccountData().MutableProfile().NickName = ""
+		}
+	}
+
+	mappingTb, _, result := db.DatabaseTableNameMappingLoadWithTypeIdZoneIdName(ctx,
+		uint32(private_protocol_common.NameMappingType_EN_NAME_MAPPING_TYPE_USER_NICKNAME), sourceZoneId, nickName)
+	if result.IsError() {
+		if isDBRecordNotFound(result) {
+			return conflict, cd.CreateRpcResultOk()
+		}
+		return conflict, result
+	}
+	if mappingTb.GetMappingData().GetUserMapping().GetUserId() == userTb.GetUserId() {
+		result = db.DatabaseTableNameMappingDelWithTypeIdZoneIdName(ctx,
+			uint32(private_protocol_common.NameMappingType_EN_NAME_MAPPING_TYPE_USER_NICKNAME), sourceZoneId, nickName)
+		if result.IsError() && !isDBRecordNotFound(result) {
+			return conflict, result
+		}
+	}
+	return conflict, cd.CreateRpcResultOk()
+}
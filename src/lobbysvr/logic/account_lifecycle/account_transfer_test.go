@@ -0,0 +1,71 @@
+package lobbysvr_logic_account_lifecycle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	db "github.com/atframework/atsf4g-go/component/db"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+)
+
+const testTransferTargetZoneId uint32 = 2
+
+// TestTransferAsyncJobsRetry 测试转区迁移异步任务中途失败后重新执行
+// Scenario: 上一次已经写入目标大区但是没有删除原大区的任务，重新执行时不会重复写入，原大区的任务被删除
+func TestTransferAsyncJobsRetry(t *testing.T) {
+	// Arrange
+	ctx := createAccountLifecycleTestContext(time.Unix(1700000000, 0))
+	seedAccountLifecycleData(t, ctx)
+	jobType := int32(private_protocol_pbdesc.EnPlayerAsyncJobsType_EN_PAJT_NORMAL)
+	sourceJobs, _ := db.DatabaseTableUserAsyncJobsLoadAllWithJobTypeUserIdZoneId(ctx, jobType, testLifecycleUserId, testLifecycleZoneId)
+	if !assert.Len(t, sourceJobs, 1) {
+		return
+	}
+	movedJob := sourceJobs[0].Table.(*private_protocol_pbdesc.DatabaseTableUserAsyncJobs).Clone()
+	movedJob.ZoneId = testTransferTargetZoneId
+	result, _ := db.DatabaseTableUserAsyncJobsAddJobTypeUserIdZoneId(ctx, movedJob)
+	assert.False(t, result.IsError())
+
+	// Act
+	count, result := transferUserAsyncJobs(ctx, jobType, testLifecycleUserId, testLifecycleZoneId, testTransferTargetZoneId)
+
+	// Assert
+	assert.False(t, result.IsError())
+	assert.Equal(t, int32(0), count)
+	targetJobs, _ := db.DatabaseTableUserAsyncJobsLoadAllWithJobTypeUserIdZoneId(ctx, jobType, testLifecycleUserId, testTransferTargetZoneId)
+	assert.Len(t, targetJobs, 1)
+	leftJobs, _ := db.DatabaseTableUserAsyncJobsLoadAllWithJobTypeUserIdZoneId(ctx, jobType, testLifecycleUserId, testLifecycleZoneId)
+	assert.Empty(t, leftJobs)
+}
+
+// TestTransferChatOfflineMessages 测试转区迁移离线私聊
+// Scenario: 消息迁移到目标大区并修正接收方大区，已经迁移过的消息按 message_id 跳过
+func TestTransferChatOfflineMessages(t *testing.T) {
+	// Arrange
+	ctx := createAccountLifecycleTestContext(time.Unix(1700000000, 0))
+	for _, message := range []*private_protocol_pbdesc.DatabaseTableChatOfflineMessage{
+		{ZoneId: testLifecycleZoneId, UserId: testLifecycleUserId, Message: &public_protocol_pbdesc.DChatMessage{MessageId: 1, ToZoneId: testLifecycleZoneId}},
+		{ZoneId: testLifecycleZoneId, UserId: testLifecycleUserId, Message: &public_protocol_pbdesc.DChatMessage{MessageId: 2, ToZoneId: testLifecycleZoneId}},
+		{ZoneId: testTransferTargetZoneId, UserId: testLifecycleUserId, Message: &public_protocol_pbdesc.DChatMessage{MessageId: 1, ToZoneId: testTransferTargetZoneId}},
+	} {
+		result, _ := db.DatabaseTableChatOfflineMessageAddZoneIdUserId(ctx, message)
+		assert.False(t, result.IsError())
+	}
+
+	// Act
+	count, result := transferChatOfflineMessages(ctx, testLifecycleUserId, testLifecycleZoneId, testTransferTargetZoneId)
+
+	// Assert
+	assert.False(t, result.IsError())
+	assert.Equal(t, int32(1), count)
+	targetMessages, _ := db.DatabaseTableChatOfflineMessageLoadAllWithZoneIdUserId(ctx, testTransferTargetZoneId, testLifecycleUserId)
+	assert.Len(t, targetMessages, 2)
+	for _, message := range targetMessages {
+		assert.Equal(t, testTransferTargetZoneId, message.Table.(*private_protocol_pbdesc.DatabaseTableChatOfflineMessage).GetMessage().GetToZoneId())
+	}
+	leftMessages, _ := db.DatabaseTableChatOfflineMessageLoadAllWithZoneIdUserId(ctx, testLifecycleZoneId, testLifecycleUserId)
+	assert.Empty(t, leftMessages)
+}
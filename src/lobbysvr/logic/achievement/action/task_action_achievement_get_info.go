@@ -0,0 +1,39 @@
+// Copyright 2026 atframework
+
+package lobbysvr_logic_achievement_action
+
+import (
+	"fmt"
+
+	component_dispatcher "github.com/atframework/atsf4g-go/component/dispatcher"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	user_controller "github.com/atframework/atsf4g-go/component/user_controller"
+	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
+	logic_achievement "github.com/atframework/atsf4g-go/service-lobbysvr/logic/achievement"
+	service_protocol "github.com/atframework/atsf4g-go/service-lobbysvr/protocol/public/protocol/pbdesc"
+)
+
+type TaskActionAchievementGetInfo struct {
+	user_controller.TaskActionCSBase[*service_protocol.CSAchievementGetInfoReq, *service_protocol.SCAchievementGetInfoRsp]
+}
+
+func (t *TaskActionAchievementGetInfo) Name() string {
+	return "TaskActionAchievementGetInfo"
+}
+
+func (t *TaskActionAchievementGetInfo) Run(_startData *component_dispatcher.DispatcherStartData) error {
+	user, ok := t.GetUser().(*data.User)
+	if !ok || user == nil {
+		t.SetResponseCode(int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_USER_NOT_FOUND))
+		return fmt.Errorf("user not found")
+	}
+
+	manager := data.UserGetModuleManager[logic_achievement.UserAchievementManager](user)
+	if manager == nil {
+		t.SetResponseError(public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+		return fmt.Errorf("user achievement manager not found")
+	}
+
+	manager.FetchData(t.GetRpcContext(), t.MutableResponseBody())
+	return nil
+}
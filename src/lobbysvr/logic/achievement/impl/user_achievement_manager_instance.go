@@ -0,0 +1,325 @@
+package lobbysvr_logic_achievement_impl
+
+import (
+	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
+
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+
+	config "github.com/atframework/atsf4g-go/component/config"
+	private_protocol_log "github.com/atframework/atsf4g-go/component/protocol/private/log/protocol/log"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_config "github.com/atframework/atsf4g-go/component/protocol/public/config/protocol/config"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	logic_achievement "github.com/atframework/atsf4g-go/service-lobbysvr/logic/achievement"
+	logic_quest "github.com/atframework/atsf4g-go/service-lobbysvr/logic/quest"
+	lobbysvr_logic_quest_data "github.com/atframework/atsf4g-go/service-lobbysvr/logic/quest/data"
+	lobbysvr_logic_quest_handler "github.com/atframework/atsf4g-go/service-lobbysvr/logic/quest/handler"
+	service_protocol "github.com/atframework/atsf4g-go/service-lobbysvr/protocol/public/protocol/pbdesc"
+)
+
+func init() {
+	var _ logic_achievement.UserAchievementManager = (*UserAchievementManager)(nil)
+	data.RegisterUserModuleManagerCreator[logic_achievement.UserAchievementManager](func(ctx cd.RpcContext, owner *data.User) data.UserModuleManagerImpl {
+		return CreateUserAchievementManager(owner)
+	})
+
+	logic_quest.RegisterProgressTriggerListener(onQuestProgressTrigger)
+}
+
+type UserAchievementManager struct {
+	owner *data.User
+	data.UserModuleManagerBase
+
+	achievements map[int32]*public_protocol_pbdesc.DAchievementData
+	showcase     *public_protocol_pbdesc.DUserAchievementShowcase
+
+	dirtyAchievements   map[int32]struct{}
+	dirtyCompletedTiers []*public_protocol_pbdesc.DAchievementTierCompleted
+	dirtyShowcase       bool
+}
+
+func CreateUserAchievementManager(owner *data.User) *UserAchievementManager {
+	return &UserAchievementManager{
+		owner:                 owner,
+		UserModuleManagerBase: *data.CreateUserModuleManagerBase(owner),
+
+		achievements:      make(map[int32]*public_protocol_pbdesc.DAchievementData),
+		showcase:          &public_protocol_pbdesc.DUserAchievementShowcase{},
+		dirtyAchievements: make(map[int32]struct{}),
+	}
+}
+
+func onQuestProgressTrigger(ctx cd.RpcContext, user *data.User, progressType int32, params *private_protocol_pbdesc.QuestTriggerParams) {
+	mgr := data.UserGetModuleManager[logic_achievement.UserAchievementManager](user)
+	if mgr == nil {
+		return
+	}
+	mgr.OnProgressTrigger(ctx, progressType, params)
+}
+
+func (m *UserAchievementManager) InitFromDB(ctx cd.RpcContext, _dbUser *private_protocol_pbdesc.DatabaseTableUser) cd.RpcResult {
+	for _, achievementData := range _dbUser.GetAchievementData().GetAchievements() {
+		m.achievements[achievementData.GetAchievementId()] = achievementData
+	}
+	if _dbUser.GetAchievementShowcase() != nil {
+		m.showcase = _dbUser.GetAchievementShowcase()
+	}
+
+	return cd.CreateRpcResultOk()
+}
+
+func (m *UserAchievementManager) DumpToDB(ctx cd.RpcContext, _dbUser *private_protocol_pbdesc.DatabaseTableUser) cd.RpcResult {
+	achievementData := _dbUser.MutableAchievementData()
+	achievementData.Achievements = make([]*public_protocol_pbdesc.DAchievementData, 0, len(m.achievements))
+	for _, v := range m.achievements {
+		achievementData.Achievements = append(achievementData.Achievements, v)
+	}
+	_dbUser.AchievementShowcase = m.showcase
+
+	return cd.CreateRpcResultOk()
+}
+
+// OnLogin 补齐新开放的成就，并按当前配置重新计算成就点数
+func (m *UserAchievementManager) OnLogin(ctx cd.RpcContext) {
+	allAchievements := config.GetConfigManager().GetCurrentConfigGroup().GetExcelAchievementAllOfAchievementId()
+	if allAchievements != nil {
+		for _, achievementRow := range *allAchievements {
+			if !achievementRow.GetIsOpen() {
+				continue
+			}
+
+			achievementData, created := m.mutableAchievement(ctx, achievementRow)
+			if created {
+				m.checkAchievementComplete(ctx, achievementRow, achievementData)
+			}
+		}
+	}
+
+	m.refreshShowcase(ctx)
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+
+func (m *UserAchievementManager) GetAchievementData(achievementId int32) *public_protocol_pbdesc.DAchievementData {
+	return m.achievements[achievementId]
+}
+
+func (m *UserAchievementManager) GetAchievementPoints() int64 {
+	return m.showcase.GetAchievementPoints()
+}
+
+func (m *UserAchievementManager) GetShowcase() *public_protocol_pbdesc.DUserAchievementShowcase {
+	return m.showcase
+}
+
+func (m *UserAchievementManager) FetchData(ctx cd.RpcContext, rspBody *service_protocol.SCAchievementGetInfoRsp) {
+	allAchievements := config.GetConfigManager().GetCurrentConfigGroup().GetExcelAchievementAllOfAchievementId()
+	if allAchievements != nil {
+		for _, achievementRow := range *allAchievements {
+			if !achievementRow.GetIsOpen() {
+				continue
+			}
+
+			achievementData, created := m.mutableAchievement(ctx, achievementRow)
+			if created {
+				m.checkAchievementComplete(ctx, achievementRow, achievementData)
+			}
+			rspBody.Achievements = append(rspBody.Achievements, achievementData)
+		}
+	}
+
+	rspBody.Showcase = m.showcase
+}
+
+func (m *UserAchievementManager) SetShowcase(ctx cd.RpcContext, achievementIds []int32) int32 {
+	cfgGroup := config.GetConfigManager().GetCurrentConfigGroup()
+	for _, achievementId := range achievementIds {
+		if cfgGroup.GetExcelAchievementByAchievementId(achievementId) == nil {
+			return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_ACHIEVEMENT_NOT_FOUND)
+		}
+	}
+
+	maxCount := cfgGroup.GetCustomIndex().GetConstIndex().GetAchievementShowcaseMaxCount()
+	items, resultCode := buildAchievementShowcase(achievementIds, maxCount, m.achievements)
+	if resultCode != 0 {
+		return resultCode
+	}
+
+	m.showcase.Items = items
+	m.dirtyShowcase = true
+	m.insertDirtyHandle()
+	return 0
+}
+
+func (m *UserAchievementManager) OnProgressTrigger(ctx cd.RpcContext, progressType int32, params *private_protocol_pbdesc.QuestTriggerParams) {
+	achievementRows := config.GetConfigManager().GetCurrentConfigGroup().GetCustomIndex().GetAchievementsByProgressType(progressType)
+	if len(achievementRows) == 0 {
+		return
+	}
+
+	// 和任务使用同样的索引参数筛选需要更新的进度
+	progressKeyParam := lobbysvr_logic_quest_handler.GetProgressKeyIndexHandlerByType(progressType)(ctx, progressType, params)
+	updateHandler := lobbysvr_logic_quest_handler.GetUpdateProgressHandlerByType(progressType)
+	for _, achievementRow := range achievementRows {
+		if !achievementRow.GetIsOpen() {
+			continue
+		}
+		if lobbysvr_logic_quest_data.GetProgressCfgParam1Value(achievementRow.GetProgress()) != progressKeyParam.ParamsOne {
+			continue
+		}
+
+		// 新创建的成就已经用初始化函数取到了当前进度，不需要再叠加本次事件
+		achievementData, created := m.mutableAchievement(ctx, achievementRow)
+		if !created {
+			if achievementData.GetCompletedTier() >= int32(len(achievementRow.GetTiers())) {
+				continue
+			}
+
+			originValue := achievementData.GetProgress().GetValue()
+			result := updateHandler(ctx, params, achievementRow.GetProgress(), achievementData.GetProgress())
+			if result.IsError() {
+				result.LogError(ctx, "update achievement progress failed", "achievement_id", achievementRow.GetAchievementId(), "progress_type", progressType)
+				continue
+			}
+			if originValue == achievementData.GetProgress().GetValue() {
+				continue
+			}
+
+			m.dirtyAchievements[achievementRow.GetAchievementId()] = struct{}{}
+			m.insertDirtyHandle()
+		}
+
+		m.checkAchievementComplete(ctx, achievementRow, achievementData)
+	}
+}
+
+// mutableAchievement 获取成就数据，不存在时创建并用进度初始化函数计算当前进度
+func (m *UserAchievementManager) mutableAchievement(ctx cd.RpcContext, achievementRow *public_protocol_config.Readonly_ExcelAchievement,
+) (*public_protocol_pbdesc.DAchievementData, bool) {
+	achievementData, ok := m.achievements[achievementRow.GetAchievementId()]
+	if ok && achievementData != nil {
+		return achievementData, false
+	}
+
+	achievementData = &public_protocol_pbdesc.DAchievementData{
+		AchievementId: achievementRow.GetAchievementId(),
+		Progress: &public_protocol_pbdesc.DUserQuestProgressData{
+			UniqueId:    achievementRow.GetProgress().GetUniqueId(),
+			CreatedTime: ctx.GetNow().Unix(),
+		},
+	}
+	m.achievements[achievementRow.GetAchievementId()] = achievementData
+
+	progressType := int32(achievementRow.GetProgress().GetProgressParamOneofCase())
+	initHandler := lobbysvr_logic_quest_handler.GetInitProgressHandlerByType(progressType)
+	result := initHandler(ctx, achievementRow.GetProgress(), achievementData.GetProgress(), m.GetOwner())
+	if result.IsError() {
+		result.LogError(ctx, "init achievement progress failed", "achievement_id", achievementRow.GetAchievementId(), "progress_type", progressType)
+	}
+
+	m.dirtyAchievements[achievementRow.GetAchievementId()] = struct{}{}
+	m.insertDirtyHandle()
+	return achievementData, true
+}
+
+// checkAchievementComplete 完成新的档位后增加成就点数，发送流水并通知客户端
+func (m *UserAchievementManager) checkAchievementComplete(ctx cd.RpcContext, achievementRow *public_protocol_config.Readonly_ExcelAchievement,
+	achievementData *public_protocol_pbdesc.DAchievementData,
+) {
+	tiers := achievementRow.GetTiers()
+	completedTier := calculateAchievementTier(tiers, achievementData.GetProgress().GetValue())
+	if completedTier <= achievementData.GetCompletedTier() {
+		return
+	}
+
+	for tier := achievementData.GetCompletedTier() + 1; tier <= completedTier; tier++ {
+		points := tiers[tier-1].GetPoints()
+		m.showcase.AchievementPoints += int64(points)
+		m.dirtyCompletedTiers = append(m.dirtyCompletedTiers, &public_protocol_pbdesc.DAchievementTierCompleted{
+			AchievementId: achievementRow.GetAchievementId(),
+			Tier:          tier,
+			Points:        points,
+		})
+
+		log := private_protocol_log.OperationSupportSystemLog{}
+		flow := log.MutableLog().MutableAchievementFlow()
+		flow.AchievementId = achievementRow.GetAchievementId()
+		flow.Tier = tier
+		flow.Value = achievementData.GetProgress().GetValue()
+		flow.Points = points
+		flow.TotalPoints = m.showcase.GetAchievementPoints()
+		m.GetOwner().SendUserOssLog(ctx, &log)
+	}
+
+	achievementData.CompletedTier = completedTier
+	achievementData.CompleteTime = ctx.GetNow().Unix()
+
+	for _, item := range m.showcase.GetItems() {
+		if item.GetAchievementId() == achievementRow.GetAchievementId() {
+			item.CompletedTier = completedTier
+		}
+	}
+
+	m.dirtyAchievements[achievementRow.GetAchievementId()] = struct{}{}
+	m.dirtyShowcase = true
+	m.insertDirtyHandle()
+}
+
+// refreshShowcase 按当前配置重新计算成就点数，并移除已经删除的成就
+func (m *UserAchievementManager) refreshShowcase(ctx cd.RpcContext) {
+	cfgGroup := config.GetConfigManager().GetCurrentConfigGroup()
+
+	var points int64
+	for achievementId, achievementData := range m.achievements {
+		achievementRow := cfgGroup.GetExcelAchievementByAchievementId(achievementId)
+		if achievementRow == nil {
+			continue
+		}
+		points += calculateAchievementPoints(achievementRow.GetTiers(), achievementData.GetCompletedTier())
+	}
+
+	items := make([]*public_protocol_pbdesc.DAchievementShowcaseItem, 0, len(m.showcase.GetItems()))
+	for _, item := range m.showcase.GetItems() {
+		if cfgGroup.GetExcelAchievementByAchievementId(item.GetAchievementId()) == nil {
+			ctx.LogWarn("remove deleted achievement from showcase", "achievement_id", item.GetAchievementId())
+			continue
+		}
+		items = append(items, item)
+	}
+
+	if points == m.showcase.GetAchievementPoints() && len(items) == len(m.showcase.GetItems()) {
+		return
+	}
+
+	m.showcase.AchievementPoints = points
+	m.showcase.Items = items
+	m.dirtyShowcase = true
+	m.insertDirtyHandle()
+}
+
+func (m *UserAchievementManager) insertDirtyHandle() {
+	m.GetOwner().InsertDirtyHandleIfNotExists(m,
+		func(ctx cd.RpcContext, dirty *data.UserDirtyData) bool {
+			dirtyData := &public_protocol_pbdesc.DUserAchievementDirtyChg{}
+			for achievementId := range m.dirtyAchievements {
+				if achievementData, ok := m.achievements[achievementId]; ok {
+					dirtyData.Achievements = append(dirtyData.Achievements, achievementData)
+				}
+			}
+			dirtyData.CompletedTiers = m.dirtyCompletedTiers
+			if m.dirtyShowcase {
+				dirtyData.Showcase = m.showcase
+			}
+			if len(dirtyData.Achievements) == 0 && len(dirtyData.CompletedTiers) == 0 && dirtyData.Showcase == nil {
+				return false
+			}
+			dirty.MutableNormalDirtyChangeMessage().DirtyAchievement = dirtyData
+			return true
+		},
+		func(ctx cd.RpcContext) {
+			clear(m.dirtyAchievements)
+			m.dirtyCompletedTiers = nil
+			m.dirtyShowcase = false
+		},
+	)
+}
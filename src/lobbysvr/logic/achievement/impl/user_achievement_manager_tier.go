@@ -0,0 +1,58 @@
+package lobbysvr_logic_achievement_impl
+
+import (
+	public_protocol_config "github.com/atframework/atsf4g-go/component/protocol/public/config/protocol/config"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+)
+
+// calculateAchievementTier 进度值已经达到的档位数，档位按阈值从小到大配置
+func calculateAchievementTier(tiers []*public_protocol_config.Readonly_DAchievementTier, value int64) int32 {
+	var ret int32
+	for _, tier := range tiers {
+		if value < tier.GetThreshold() {
+			break
+		}
+		ret++
+	}
+	return ret
+}
+
+// calculateAchievementPoints 前 completedTier 个档位的成就点数之和
+func calculateAchievementPoints(tiers []*public_protocol_config.Readonly_DAchievementTier, completedTier int32) int64 {
+	var ret int64
+	for i, tier := range tiers {
+		if int32(i) >= completedTier {
+			break
+		}
+		ret += int64(tier.GetPoints())
+	}
+	return ret
+}
+
+// buildAchievementShowcase 按顺序生成展示位，只能展示至少完成了一个档位的成就
+func buildAchievementShowcase(achievementIds []int32, maxCount int32,
+	achievements map[int32]*public_protocol_pbdesc.DAchievementData,
+) ([]*public_protocol_pbdesc.DAchievementShowcaseItem, int32) {
+	if int32(len(achievementIds)) > maxCount {
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_ACHIEVEMENT_SHOWCASE_FULL)
+	}
+
+	items := make([]*public_protocol_pbdesc.DAchievementShowcaseItem, 0, len(achievementIds))
+	exists := make(map[int32]struct{}, len(achievementIds))
+	for _, achievementId := range achievementIds {
+		if _, ok := exists[achievementId]; ok {
+			return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_ACHIEVEMENT_SHOWCASE_DUPLICATE)
+		}
+		exists[achievementId] = struct{}{}
+
+		achievementData := achievements[achievementId]
+		if achievementData.GetCompletedTier() <= 0 {
+			return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_ACHIEVEMENT_NOT_COMPLETED)
+		}
+		items = append(items, &public_protocol_pbdesc.DAchievementShowcaseItem{
+			AchievementId: achievementId,
+			CompletedTier: achievementData.GetCompletedTier(),
+		})
+	}
+	return items, 0
+}
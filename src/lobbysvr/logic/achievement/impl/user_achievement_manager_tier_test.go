@@ -0,0 +1,63 @@
+package lobbysvr_logic_achievement_impl
+
+import (
+	"testing"
+
+	public_protocol_config "github.com/atframework/atsf4g-go/component/protocol/public/config/protocol/config"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestAchievementTiers() []*public_protocol_config.Readonly_DAchievementTier {
+	return []*public_protocol_config.Readonly_DAchievementTier{
+		(&public_protocol_config.DAchievementTier{Threshold: 10, Points: 5}).ToReadonly(),
+		(&public_protocol_config.DAchievementTier{Threshold: 50, Points: 10}).ToReadonly(),
+		(&public_protocol_config.DAchievementTier{Threshold: 100, Points: 20}).ToReadonly(),
+	}
+}
+
+// TestAchievementTier 测试成就档位计算
+// Scenario: 进度值达到阈值才算完成档位，超过最高档位后不再增加，成就点数按已完成档位累加
+func TestAchievementTier(t *testing.T) {
+	// Arrange
+	tiers := newTestAchievementTiers()
+
+	// Act & Assert
+	assert.Equal(t, int32(0), calculateAchievementTier(tiers, 9))
+	assert.Equal(t, int32(1), calculateAchievementTier(tiers, 10))
+	assert.Equal(t, int32(2), calculateAchievementTier(tiers, 99))
+	assert.Equal(t, int32(3), calculateAchievementTier(tiers, 1000))
+
+	assert.Equal(t, int64(0), calculateAchievementPoints(tiers, 0))
+	assert.Equal(t, int64(15), calculateAchievementPoints(tiers, 2))
+	assert.Equal(t, int64(35), calculateAchievementPoints(tiers, 5))
+}
+
+// TestAchievementShowcase 测试成就展示位
+// Scenario: 超出展示位数量、重复展示或者展示未完成的成就时拒绝，成功时按请求顺序保存
+func TestAchievementShowcase(t *testing.T) {
+	// Arrange
+	achievements := map[int32]*public_protocol_pbdesc.DAchievementData{
+		1: {AchievementId: 1, CompletedTier: 2},
+		2: {AchievementId: 2, CompletedTier: 0},
+		3: {AchievementId: 3, CompletedTier: 1},
+	}
+
+	// Act
+	items, resultCode := buildAchievementShowcase([]int32{3, 1}, 2, achievements)
+
+	// Assert
+	assert.Equal(t, int32(0), resultCode)
+	assert.Len(t, items, 2)
+	assert.Equal(t, int32(3), items[0].GetAchievementId())
+	assert.Equal(t, int32(2), items[1].GetCompletedTier())
+
+	_, resultCode = buildAchievementShowcase([]int32{1, 3, 4}, 2, achievements)
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_ACHIEVEMENT_SHOWCASE_FULL), resultCode)
+	_, resultCode = buildAchievementShowcase([]int32{1, 1}, 2, achievements)
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_ACHIEVEMENT_SHOWCASE_DUPLICATE), resultCode)
+	_, resultCode = buildAchievementShowcase([]int32{2}, 2, achievements)
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_ACHIEVEMENT_NOT_COMPLETED), resultCode)
+	_, resultCode = buildAchievementShowcase([]int32{5}, 2, achievements)
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_ACHIEVEMENT_NOT_COMPLETED), resultCode)
+}
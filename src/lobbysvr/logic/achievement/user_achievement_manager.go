@@ -0,0 +1,27 @@
+package lobbysvr_logic_achievement
+
+import (
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
+	service_protocol "github.com/atframework/atsf4g-go/service-lobbysvr/protocol/public/protocol/pbdesc"
+)
+
+// UserAchievementManager 永久成就，不会过期和重置
+// 进度复用任务的进度类型和处理函数，按档位累计成就点数
+type UserAchievementManager interface {
+	data.UserModuleManagerImpl
+
+	GetAchievementData(achievementId int32) *public_protocol_pbdesc.DAchievementData
+	GetAchievementPoints() int64
+	// 展示位数据会随基础信息一起给其他玩家查询
+	GetShowcase() *public_protocol_pbdesc.DUserAchievementShowcase
+
+	// 只返回已开放的成就
+	FetchData(ctx cd.RpcContext, rspBody *service_protocol.SCAchievementGetInfoRsp)
+	SetShowcase(ctx cd.RpcContext, achievementIds []int32) int32
+
+	// 任务模块处理完进度事件后调用
+	OnProgressTrigger(ctx cd.RpcContext, progressType int32, params *private_protocol_pbdesc.QuestTriggerParams)
+}
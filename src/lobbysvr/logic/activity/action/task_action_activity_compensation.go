@@ -0,0 +1,68 @@
+// Copyright 2026 atframework
+
+package lobbysvr_logic_activity_action
+
+import (
+	"time"
+
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
+)
+
+// UserActivityManagerForCompensation 定义补发任务所需的 UserActivityManager 接口
+// 避免循环引用
+type UserActivityManagerForCompensation interface {
+	SendPendingCompensations(ctx cd.AwaitableContext) data.Result
+}
+
+// TaskActionActivityCompensation 活动结束后通过邮件补发未领取的奖励
+type TaskActionActivityCompensation struct {
+	cd.TaskActionNoMessageBase
+	owner   *data.User
+	manager UserActivityManagerForCompensation
+}
+
+func (t *TaskActionActivityCompensation) Name() string {
+	return "TaskActionActivityCompensation"
+}
+
+// CreateTaskActionActivityCompensation 创建活动补发任务
+func CreateTaskActionActivityCompensation(
+	dispatcher cd.DispatcherImpl,
+	actor *cd.ActorExecutor,
+	owner *data.User,
+	manager UserActivityManagerForCompensation,
+	timeoutDuration time.Duration,
+) *TaskActionActivityCompensation {
+	return &TaskActionActivityCompensation{
+		TaskActionNoMessageBase: cd.CreateNoMessageTaskActionBase(dispatcher, actor, timeoutDuration),
+		owner:                   owner,
+		manager:                 manager,
+	}
+}
+
+func (t *TaskActionActivityCompensation) Run(_startData *cd.DispatcherStartData) error {
+	if t.owner == nil || t.manager == nil || !t.owner.IsWriteable() {
+		return nil
+	}
+
+	result := t.manager.SendPendingCompensations(t.GetAwaitableContext())
+	if result.IsError() {
+		return result.GetStandardError()
+	}
+	return nil
+}
+
+// OnSuccess 推送补发后的数据变化和新邮件
+func (t *TaskActionActivityCompensation) OnSuccess() {
+	if t.owner != nil {
+		t.owner.SendAllSyncData(t.GetRpcContext())
+	}
+}
+
+func (t *TaskActionActivityCompensation) OnFailed() {
+	ctx := t.GetRpcContext()
+	if t.owner != nil {
+		ctx.LogError("TaskActionActivityCompensation failed", "user_id", t.owner.GetUserId())
+	}
+}
@@ -0,0 +1,91 @@
+package lobbysvr_logic_activity
+
+import (
+	"fmt"
+
+	"github.com/atframework/libatapp-go"
+
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	public_protocol_common "github.com/atframework/atsf4g-go/component/protocol/public/common/protocol/common"
+	public_protocol_config "github.com/atframework/atsf4g-go/component/protocol/public/config/protocol/config"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
+)
+
+// ActivityHandlerImpl 活动类型的处理器，每个 EnActivityType 注册一个
+// 玩家相关的接口在玩家的 actor 中调用，全服相关的接口在定时任务中调用，多个进程中只有一个进程调用，执行中崩溃时可能重复调用
+type ActivityHandlerImpl interface {
+	// OnJoin 玩家第一次参与某一期时初始化类型相关的数据
+	OnJoin(ctx cd.RpcContext, owner *data.User, activity *public_protocol_config.Readonly_ExcelActivity,
+		activityData *public_protocol_pbdesc.DActivityUserData)
+
+	// Refresh 登入和定时刷新时调用，返回数据是否有变化
+	Refresh(ctx cd.RpcContext, owner *data.User, activity *public_protocol_config.Readonly_ExcelActivity,
+		activityData *public_protocol_pbdesc.DActivityUserData) bool
+
+	// ReceiveReward 检查并记录领奖，返回的道具由调用方发放
+	ReceiveReward(ctx cd.RpcContext, owner *data.User, activity *public_protocol_config.Readonly_ExcelActivity,
+		activityData *public_protocol_pbdesc.DActivityUserData, rewardId int32) ([]*data.ItemAddGuard, int32)
+
+	// CollectUnclaimedRewards 某一期结束时返回已达成但未领取的奖励，通过邮件补发
+	CollectUnclaimedRewards(ctx cd.RpcContext, activity *public_protocol_config.Readonly_ExcelActivity,
+		activityData *public_protocol_pbdesc.DActivityUserData) []*public_protocol_common.DItemOffset
+
+	// OnInstanceOpen 全服状态，某一期开启时调用
+	OnInstanceOpen(app libatapp.AppImpl, activity *public_protocol_config.Readonly_ExcelActivity, instance ActivityInstance)
+
+	// OnInstanceClose 全服状态，某一期结束时调用，配置被删除时不会调用
+	OnInstanceClose(app libatapp.AppImpl, activity *public_protocol_config.Readonly_ExcelActivity, instance ActivityInstance)
+}
+
+// ActivityHandlerBase 默认实现，只需要全服状态或者只需要玩家数据的活动类型可以只覆盖部分接口
+type ActivityHandlerBase struct{}
+
+func (h *ActivityHandlerBase) OnJoin(_ctx cd.RpcContext, _owner *data.User, _activity *public_protocol_config.Readonly_ExcelActivity,
+	_activityData *public_protocol_pbdesc.DActivityUserData,
+) {
+}
+
+func (h *ActivityHandlerBase) Refresh(_ctx cd.RpcContext, _owner *data.User, _activity *public_protocol_config.Readonly_ExcelActivity,
+	_activityData *public_protocol_pbdesc.DActivityUserData,
+) bool {
+	return false
+}
+
+func (h *ActivityHandlerBase) ReceiveReward(_ctx cd.RpcContext, _owner *data.User, _activity *public_protocol_config.Readonly_ExcelActivity,
+	_activityData *public_protocol_pbdesc.DActivityUserData, _rewardId int32,
+) ([]*data.ItemAddGuard, int32) {
+	return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_ACTIVITY_OPERATION_NOT_SUPPORTED)
+}
+
+func (h *ActivityHandlerBase) CollectUnclaimedRewards(_ctx cd.RpcContext, _activity *public_protocol_config.Readonly_ExcelActivity,
+	_activityData *public_protocol_pbdesc.DActivityUserData,
+) []*public_protocol_common.DItemOffset {
+	return nil
+}
+
+func (h *ActivityHandlerBase) OnInstanceOpen(_app libatapp.AppImpl, _activity *public_protocol_config.Readonly_ExcelActivity, _instance ActivityInstance) {
+}
+
+func (h *ActivityHandlerBase) OnInstanceClose(_app libatapp.AppImpl, _activity *public_protocol_config.Readonly_ExcelActivity, _instance ActivityInstance) {
+}
+
+var activityHandlers = map[public_protocol_common.EnActivityType]ActivityHandlerImpl{}
+
+// RegisterActivityHandler 注册活动类型的处理器，只能在 init 中调用
+func RegisterActivityHandler(activityType public_protocol_common.EnActivityType, handler ActivityHandlerImpl) {
+	if handler == nil {
+		panic(fmt.Sprintf("activity handler of type %d is nil", activityType))
+	}
+
+	if _, ok := activityHandlers[activityType]; ok {
+		panic(fmt.Sprintf("activity handler of type %d already registered", activityType))
+	}
+
+	activityHandlers[activityType] = handler
+}
+
+// GetActivityHandler 没有注册时返回 nil
+func GetActivityHandler(activityType public_protocol_common.EnActivityType) ActivityHandlerImpl {
+	return activityHandlers[activityType]
+}
@@ -0,0 +1,211 @@
+// Copyright 2026 atframework
+// @brief 活动的全服状态，按配置和逻辑时间检测每一期的开启和结束，由定时任务调用活动类型的全服接口
+
+package lobbysvr_logic_activity
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/atframework/libatapp-go"
+
+	config "github.com/atframework/atsf4g-go/component/config"
+	db "github.com/atframework/atsf4g-go/component/db"
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	logical_time "github.com/atframework/atsf4g-go/component/logical_time"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	logic_cron "github.com/atframework/atsf4g-go/service-lobbysvr/logic/cron"
+)
+
+// activityManagerCheckInterval 全服状态的检查间隔
+const activityManagerCheckInterval = time.Second
+
+// activityTransitionCronJobName 切换全服开启状态的定时任务，每分钟检查一次，全服接口最多延迟一分钟调用
+const activityTransitionCronJobName = "activity_instance_transition"
+
+// ActivityManager 活动模块，查询用的开放状态每个进程按排期独立计算
+// 全服接口由定时任务根据数据库中记录的状态调用，多个进程和重启都不会重复调用
+// 玩家数据的开启和结束由 UserActivityManager 按相同的排期独立计算，不依赖这里的状态
+type ActivityManager struct {
+	libatapp.AppModuleBase
+
+	nextCheckTime time.Time
+
+	openedInstancesLock sync.RWMutex
+	openedInstances     map[int32]ActivityInstance // key 为 activity_id
+}
+
+func init() {
+	var _ libatapp.AppModuleImpl = (*ActivityManager)(nil)
+
+	logic_cron.RegisterCronJob(activityTransitionCronJobName, "* * * * *", logic_cron.CronCatchUpOnce, runActivityTransition)
+}
+
+// CreateActivityManager 创建活动模块
+func CreateActivityManager(owner libatapp.AppImpl) *ActivityManager {
+	return &ActivityManager{
+		AppModuleBase:   libatapp.CreateAppModuleBase(owner),
+		openedInstances: make(map[int32]ActivityInstance),
+	}
+}
+
+// GetActivityManager 获取活动模块
+func GetActivityManager(app libatapp.AppImpl) *ActivityManager {
+	return libatapp.AtappGetModule[*ActivityManager](app)
+}
+
+func (m *ActivityManager) Name() string {
+	return "ActivityManager"
+}
+
+func (m *ActivityManager) Init(parent context.Context) error {
+	return nil
+}
+
+func (m *ActivityManager) Cleanup() {
+	m.openedInstancesLock.Lock()
+	defer m.openedInstancesLock.Unlock()
+
+	clear(m.openedInstances)
+}
+
+func (m *ActivityManager) Tick(parent context.Context) bool {
+	now := logical_time.GetLogicalNow()
+	if now.Before(m.nextCheckTime) {
+		return false
+	}
+	m.nextCheckTime = now.Add(activityManagerCheckInterval)
+
+	return m.refreshInstances(now)
+}
+
+// GetOpenedInstance 全服当前开放的一期，没有开放时返回 false
+func (m *ActivityManager) GetOpenedInstance(activityId int32) (ActivityInstance, bool) {
+	m.openedInstancesLock.RLock()
+	defer m.openedInstancesLock.RUnlock()
+
+	instance, ok := m.openedInstances[activityId]
+	return instance, ok
+}
+
+// IsActivityOpen 全服状态，例如双倍掉落等不需要玩家数据的活动可以直接查询
+func (m *ActivityManager) IsActivityOpen(activityId int32) bool {
+	_, ok := m.GetOpenedInstance(activityId)
+	return ok
+}
+
+// refreshInstances 重新计算每个活动当前开放的一期，返回是否有变化
+func (m *ActivityManager) refreshInstances(now time.Time) bool {
+	configGroup := config.GetConfigManager().GetCurrentConfigGroup()
+	if configGroup == nil {
+		return false
+	}
+
+	current := make(map[int32]ActivityInstance)
+	if allActivities := configGroup.GetExcelActivityAllOfActivityId(); allActivities != nil {
+		for _, activityRow := range *allActivities {
+			if instance, ok := CalculateActivityInstance(activityRow, now); ok {
+				current[activityRow.GetActivityId()] = instance
+			}
+		}
+	}
+
+	m.openedInstancesLock.Lock()
+	previous := m.openedInstances
+	m.openedInstances = current
+	m.openedInstancesLock.Unlock()
+
+	if len(previous) != len(current) {
+		return true
+	}
+	for activityId, instance := range current {
+		if opened, ok := previous[activityId]; !ok || opened.InstanceId != instance.InstanceId {
+			return true
+		}
+	}
+	return false
+}
+
+// runActivityTransition 定时任务，对比每个活动当前开放的一期和数据库中的记录，调用全服接口
+func runActivityTransition(ctx cd.AwaitableContext, _ time.Time) cd.RpcResult {
+	configGroup := config.GetConfigManager().GetCurrentConfigGroup()
+	if configGroup == nil {
+		return cd.CreateRpcResultOk()
+	}
+	allActivities := configGroup.GetExcelActivityAllOfActivityId()
+	if allActivities == nil {
+		return cd.CreateRpcResultOk()
+	}
+
+	now := ctx.GetNow()
+	for _, activityRow := range *allActivities {
+		instance, opened := CalculateActivityInstance(activityRow, now)
+		handler := GetActivityHandler(activityRow.GetActivityType())
+		result := syncActivityState(ctx, activityRow.GetActivityId(), instance, opened,
+			func(closed ActivityInstance) {
+				if handler != nil {
+					handler.OnInstanceClose(ctx.GetApp(), activityRow, closed)
+				}
+			},
+			func(opened ActivityInstance) {
+				if handler != nil {
+					handler.OnInstanceOpen(ctx.GetApp(), activityRow, opened)
+				}
+			})
+		if result.IsError() {
+			return result
+		}
+	}
+	return cd.CreateRpcResultOk()
+}
+
+// syncActivityState 先结束数据库中记录的旧的一期再开启新的一期，保存后同一期不会再次处理
+// 回调之后保存之前进程崩溃时，下一次定时任务会再次调用回调
+func syncActivityState(ctx cd.AwaitableContext, activityId int32, current ActivityInstance, opened bool,
+	onClose func(ActivityInstance), onOpen func(ActivityInstance),
+) cd.RpcResult {
+	tb, casVersion, result := db.DatabaseTableActivityStateLoadWithActivityId(ctx, activityId)
+	if result.IsError() {
+		if result.GetResponseCode() != int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND) {
+			result.LogError(ctx, "load activity state failed", "activity_id", activityId)
+			return result
+		}
+		tb = &private_protocol_pbdesc.DatabaseTableActivityState{ActivityId: activityId}
+		casVersion = 0
+	}
+
+	if !opened {
+		current = ActivityInstance{ActivityId: activityId}
+	}
+	if tb.GetOpenedInstanceId() == current.InstanceId {
+		return cd.CreateRpcResultOk()
+	}
+
+	if tb.GetOpenedInstanceId() != 0 {
+		closed := ActivityInstance{
+			ActivityId: activityId,
+			InstanceId: tb.GetOpenedInstanceId(),
+			StartTime:  tb.GetOpenedStartTime(),
+			EndTime:    tb.GetOpenedEndTime(),
+		}
+		ctx.LogInfo("activity instance closed", "activity_id", activityId, "instance_id", closed.InstanceId)
+		onClose(closed)
+	}
+	if opened {
+		ctx.LogInfo("activity instance opened", "activity_id", activityId, "instance_id", current.InstanceId,
+			"start_time", current.StartTime, "end_time", current.EndTime)
+		onOpen(current)
+	}
+
+	tb.OpenedInstanceId = current.InstanceId
+	tb.OpenedStartTime = current.StartTime
+	tb.OpenedEndTime = current.EndTime
+	tb.UpdateTime = ctx.GetSysNow().Unix()
+	result = db.DatabaseTableActivityStateReplaceActivityId(ctx, tb, &casVersion, false)
+	if result.IsError() {
+		result.LogError(ctx, "save activity state failed", "activity_id", activityId, "instance_id", current.InstanceId)
+	}
+	return result
+}
@@ -0,0 +1,73 @@
+package lobbysvr_logic_activity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	test_utility "github.com/atframework/atsf4g-go/component/test_utility"
+)
+
+const testActivityStateId int32 = 1
+
+// activityStateRecorder 记录全服接口的调用顺序
+type activityStateRecorder struct {
+	events []string
+}
+
+func (r *activityStateRecorder) sync(t *testing.T, ctx *test_utility.MockRpcContext, instance ActivityInstance, opened bool) {
+	result := syncActivityState(ctx, testActivityStateId, instance, opened,
+		func(closed ActivityInstance) {
+			r.events = append(r.events, "close", time.Unix(closed.StartTime, 0).UTC().Format(time.DateOnly))
+		},
+		func(opened ActivityInstance) {
+			r.events = append(r.events, "open", time.Unix(opened.StartTime, 0).UTC().Format(time.DateOnly))
+		})
+	assert.False(t, result.IsError())
+}
+
+func newTestActivityStateInstance(periodIndex int64, startTime time.Time) ActivityInstance {
+	return ActivityInstance{
+		ActivityId: testActivityStateId,
+		InstanceId: MakeActivityInstanceId(testActivityStateId, periodIndex),
+		StartTime:  startTime.Unix(),
+		EndTime:    startTime.Add(24 * time.Hour).Unix(),
+	}
+}
+
+// TestActivityStateTransition 测试全服开启状态的持久化
+// Scenario: 同一期只调用一次开启，重启后不会重复调用，切换到下一期时先结束旧的一期，全部结束后只调用结束
+func TestActivityStateTransition(t *testing.T) {
+	// Arrange
+	app, _ := test_utility.CreateMemoryStorageApp()
+	ctx := test_utility.NewMockRpcContextWithNow(app, time.Unix(1700000000, 0))
+	first := newTestActivityStateInstance(1, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	second := newTestActivityStateInstance(2, time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC))
+	recorder := &activityStateRecorder{}
+
+	// Act & Assert: 没有开放时不记录任何状态
+	recorder.sync(t, ctx, ActivityInstance{}, false)
+	assert.Empty(t, recorder.events)
+
+	// Act & Assert: 开启第一期，再次检查不重复调用
+	recorder.sync(t, ctx, first, true)
+	recorder.sync(t, ctx, first, true)
+	assert.Equal(t, []string{"open", "2026-01-01"}, recorder.events)
+
+	// Act & Assert: 模拟重启，状态从数据库读取，不重复调用
+	restarted := &activityStateRecorder{}
+	restarted.sync(t, test_utility.NewMockRpcContextWithNow(app, time.Unix(1700000060, 0)), first, true)
+	assert.Empty(t, restarted.events)
+
+	// Act & Assert: 切换到第二期
+	recorder.events = nil
+	recorder.sync(t, ctx, second, true)
+	assert.Equal(t, []string{"close", "2026-01-01", "open", "2026-01-02"}, recorder.events)
+
+	// Act & Assert: 活动结束
+	recorder.events = nil
+	recorder.sync(t, ctx, ActivityInstance{}, false)
+	recorder.sync(t, ctx, ActivityInstance{}, false)
+	assert.Equal(t, []string{"close", "2026-01-02"}, recorder.events)
+}
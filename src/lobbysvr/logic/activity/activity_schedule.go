@@ -0,0 +1,75 @@
+package lobbysvr_logic_activity
+
+import (
+	"time"
+
+	logical_time "github.com/atframework/atsf4g-go/component/logical_time"
+	public_protocol_common "github.com/atframework/atsf4g-go/component/protocol/public/common/protocol/common"
+	public_protocol_config "github.com/atframework/atsf4g-go/component/protocol/public/config/protocol/config"
+)
+
+// ActivityInstance 活动的一期，按配置和当前逻辑时间计算
+type ActivityInstance struct {
+	ActivityId int32
+	InstanceId int64
+	StartTime  int64
+	EndTime    int64
+}
+
+// MakeActivityInstanceId 高 32 位为 activity_id，低 32 位为期数序号
+// 每日和每周活动的期数序号为逻辑日和逻辑周，固定时间活动为开始时间
+func MakeActivityInstanceId(activityId int32, periodIndex int64) int64 {
+	return int64(activityId)<<32 | (periodIndex & 0xffffffff)
+}
+
+// CalculateActivityInstance 计算 now 所在的一期，没有开放时返回 false
+// 每日和每周活动按 logical_time 的逻辑日和逻辑周切分，GM 修改服务器时间后同样生效
+func CalculateActivityInstance(activity *public_protocol_config.Readonly_ExcelActivity, now time.Time) (ActivityInstance, bool) {
+	if activity == nil || !activity.GetIsOpen() {
+		return ActivityInstance{}, false
+	}
+
+	var periodIndex, startTime, endTime int64
+	switch activity.GetScheduleType() {
+	case public_protocol_common.EnActivityScheduleType_EN_ACTIVITY_SCHEDULE_TYPE_FIXED:
+		startTime = activity.GetStartTime().GetSeconds()
+		endTime = activity.GetEndTime().GetSeconds()
+		// 同一个活动调整开始时间重新开放时视为新的一期，不能沿用上一轮的玩家数据
+		periodIndex = startTime
+
+	case public_protocol_common.EnActivityScheduleType_EN_ACTIVITY_SCHEDULE_TYPE_DAILY:
+		openOffset := activity.GetOpenOffset().AsDuration()
+		periodIndex = logical_time.GetDayId(now, &openOffset)
+		startTime = logical_time.GetGlobalBaseTime().Unix() + periodIndex*logical_time.DaySeconds + int64(openOffset.Seconds())
+		endTime = startTime + int64(activity.GetDuration().AsDuration().Seconds())
+
+	case public_protocol_common.EnActivityScheduleType_EN_ACTIVITY_SCHEDULE_TYPE_WEEKLY:
+		openOffset := activity.GetOpenOffset().AsDuration()
+		periodIndex = logical_time.GetWeekId(now, &openOffset)
+		startTime = logical_time.GetGlobalBaseTime().Unix() + periodIndex*logical_time.WeekSeconds + int64(openOffset.Seconds())
+		endTime = startTime + int64(activity.GetDuration().AsDuration().Seconds())
+
+	default:
+		return ActivityInstance{}, false
+	}
+
+	// 每一期都不能超出活动的总时间范围
+	if startTime < activity.GetStartTime().GetSeconds() {
+		startTime = activity.GetStartTime().GetSeconds()
+	}
+	if activity.GetEndTime().GetSeconds() > 0 && (endTime <= 0 || endTime > activity.GetEndTime().GetSeconds()) {
+		endTime = activity.GetEndTime().GetSeconds()
+	}
+
+	nowSec := now.Unix()
+	if nowSec < startTime || (endTime > 0 && nowSec >= endTime) {
+		return ActivityInstance{}, false
+	}
+
+	return ActivityInstance{
+		ActivityId: activity.GetActivityId(),
+		InstanceId: MakeActivityInstanceId(activity.GetActivityId(), periodIndex),
+		StartTime:  startTime,
+		EndTime:    endTime,
+	}, true
+}
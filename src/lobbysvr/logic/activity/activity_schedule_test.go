@@ -0,0 +1,123 @@
+package lobbysvr_logic_activity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	logical_time "github.com/atframework/atsf4g-go/component/logical_time"
+	public_protocol_common "github.com/atframework/atsf4g-go/component/protocol/public/common/protocol/common"
+	public_protocol_config "github.com/atframework/atsf4g-go/component/protocol/public/config/protocol/config"
+)
+
+func newTestActivity(scheduleType public_protocol_common.EnActivityScheduleType, openOffset time.Duration, duration time.Duration,
+	endTime time.Time,
+) *public_protocol_config.Readonly_ExcelActivity {
+	activity := &public_protocol_config.ExcelActivity{
+		ActivityId:   1,
+		IsOpen:       true,
+		ActivityType: public_protocol_common.EnActivityType_EN_ACTIVITY_TYPE_LOGIN,
+		ScheduleType: scheduleType,
+		StartTime:    timestamppb.New(time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)),
+		OpenOffset:   durationpb.New(openOffset),
+		Duration:     durationpb.New(duration),
+	}
+	if !endTime.IsZero() {
+		activity.EndTime = timestamppb.New(endTime)
+	}
+	return activity.ToReadonly()
+}
+
+// TestActivityDailySchedule 测试每日活动排期
+// Scenario: 每个逻辑日从偏移时间开始开放一段时间，每天是新的一期，最后一期在活动结束时间截止
+func TestActivityDailySchedule(t *testing.T) {
+	// Arrange
+	baseTime := time.Date(2026, time.January, 5, 0, 0, 0, 0, time.UTC)
+	logical_time.SetGlobalBaseTime(baseTime)
+	activity := newTestActivity(public_protocol_common.EnActivityScheduleType_EN_ACTIVITY_SCHEDULE_TYPE_DAILY,
+		20*time.Hour, 2*time.Hour, time.Date(2026, time.January, 7, 21, 0, 0, 0, time.UTC))
+
+	// Act
+	opened, openedOk := CalculateActivityInstance(activity, time.Date(2026, time.January, 6, 21, 0, 0, 0, time.UTC))
+	_, afterDurationOk := CalculateActivityInstance(activity, time.Date(2026, time.January, 6, 23, 0, 0, 0, time.UTC))
+	_, beforeOffsetOk := CalculateActivityInstance(activity, time.Date(2026, time.January, 7, 1, 0, 0, 0, time.UTC))
+	lastDay, lastDayOk := CalculateActivityInstance(activity, time.Date(2026, time.January, 7, 20, 30, 0, 0, time.UTC))
+
+	// Assert
+	assert.True(t, openedOk)
+	assert.Equal(t, MakeActivityInstanceId(1, 1), opened.InstanceId)
+	assert.Equal(t, time.Date(2026, time.January, 6, 20, 0, 0, 0, time.UTC).Unix(), opened.StartTime)
+	assert.Equal(t, time.Date(2026, time.January, 6, 22, 0, 0, 0, time.UTC).Unix(), opened.EndTime)
+
+	assert.False(t, afterDurationOk)
+	assert.False(t, beforeOffsetOk)
+
+	assert.True(t, lastDayOk)
+	assert.Equal(t, MakeActivityInstanceId(1, 2), lastDay.InstanceId)
+	assert.Equal(t, time.Date(2026, time.January, 7, 21, 0, 0, 0, time.UTC).Unix(), lastDay.EndTime)
+}
+
+// TestActivityWeeklyAndFixedSchedule 测试每周和固定时间活动排期
+// Scenario: 每周活动按逻辑周切换期数，固定时间活动只有一期，关闭的活动不会开放
+func TestActivityWeeklyAndFixedSchedule(t *testing.T) {
+	// Arrange
+	logical_time.SetGlobalBaseTime(time.Date(2026, time.January, 5, 0, 0, 0, 0, time.UTC))
+	weekend := newTestActivity(public_protocol_common.EnActivityScheduleType_EN_ACTIVITY_SCHEDULE_TYPE_WEEKLY,
+		5*24*time.Hour, 2*24*time.Hour, time.Time{})
+	fixed := newTestActivity(public_protocol_common.EnActivityScheduleType_EN_ACTIVITY_SCHEDULE_TYPE_FIXED,
+		0, 0, time.Date(2026, time.January, 10, 0, 0, 0, 0, time.UTC))
+	closed := (&public_protocol_config.ExcelActivity{
+		ActivityId:   2,
+		ScheduleType: public_protocol_common.EnActivityScheduleType_EN_ACTIVITY_SCHEDULE_TYPE_FIXED,
+	}).ToReadonly()
+
+	// Act
+	_, weekdayOk := CalculateActivityInstance(weekend, time.Date(2026, time.January, 9, 12, 0, 0, 0, time.UTC))
+	thisWeek, thisWeekOk := CalculateActivityInstance(weekend, time.Date(2026, time.January, 11, 12, 0, 0, 0, time.UTC))
+	nextWeek, nextWeekOk := CalculateActivityInstance(weekend, time.Date(2026, time.January, 17, 12, 0, 0, 0, time.UTC))
+	fixedInstance, fixedOk := CalculateActivityInstance(fixed, time.Date(2026, time.January, 9, 12, 0, 0, 0, time.UTC))
+	_, fixedEndedOk := CalculateActivityInstance(fixed, time.Date(2026, time.January, 10, 0, 0, 0, 0, time.UTC))
+	_, closedOk := CalculateActivityInstance(closed, time.Date(2026, time.January, 9, 12, 0, 0, 0, time.UTC))
+
+	// Assert
+	assert.False(t, weekdayOk)
+	assert.True(t, thisWeekOk)
+	assert.True(t, nextWeekOk)
+	assert.Equal(t, MakeActivityInstanceId(1, 0), thisWeek.InstanceId)
+	assert.Equal(t, MakeActivityInstanceId(1, 1), nextWeek.InstanceId)
+	assert.Equal(t, time.Date(2026, time.January, 19, 0, 0, 0, 0, time.UTC).Unix(), nextWeek.EndTime)
+
+	assert.True(t, fixedOk)
+	assert.Equal(t, MakeActivityInstanceId(1, time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC).Unix()), fixedInstance.InstanceId)
+	assert.False(t, fixedEndedOk)
+	assert.False(t, closedOk)
+}
+
+// TestActivityFixedScheduleRerun 测试固定时间活动重新开放
+// Scenario: 固定时间活动调整开始时间后再次开放，期数和上一轮不同
+func TestActivityFixedScheduleRerun(t *testing.T) {
+	// Arrange
+	firstRound := newTestActivity(public_protocol_common.EnActivityScheduleType_EN_ACTIVITY_SCHEDULE_TYPE_FIXED,
+		0, 0, time.Date(2026, time.January, 10, 0, 0, 0, 0, time.UTC))
+	secondRound := (&public_protocol_config.ExcelActivity{
+		ActivityId:   1,
+		IsOpen:       true,
+		ActivityType: public_protocol_common.EnActivityType_EN_ACTIVITY_TYPE_LOGIN,
+		ScheduleType: public_protocol_common.EnActivityScheduleType_EN_ACTIVITY_SCHEDULE_TYPE_FIXED,
+		StartTime:    timestamppb.New(time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC)),
+		EndTime:      timestamppb.New(time.Date(2026, time.February, 10, 0, 0, 0, 0, time.UTC)),
+	}).ToReadonly()
+
+	// Act
+	first, firstOk := CalculateActivityInstance(firstRound, time.Date(2026, time.January, 5, 0, 0, 0, 0, time.UTC))
+	second, secondOk := CalculateActivityInstance(secondRound, time.Date(2026, time.February, 5, 0, 0, 0, 0, time.UTC))
+
+	// Assert
+	assert.True(t, firstOk)
+	assert.True(t, secondOk)
+	assert.Equal(t, first.ActivityId, second.ActivityId)
+	assert.NotEqual(t, first.InstanceId, second.InstanceId)
+}
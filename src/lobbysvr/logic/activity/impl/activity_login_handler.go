@@ -0,0 +1,113 @@
+package lobbysvr_logic_activity_impl
+
+import (
+	"slices"
+
+	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
+
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+
+	config "github.com/atframework/atsf4g-go/component/config"
+	logical_time "github.com/atframework/atsf4g-go/component/logical_time"
+	public_protocol_common "github.com/atframework/atsf4g-go/component/protocol/public/common/protocol/common"
+	public_protocol_config "github.com/atframework/atsf4g-go/component/protocol/public/config/protocol/config"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	logic_activity "github.com/atframework/atsf4g-go/service-lobbysvr/logic/activity"
+)
+
+func init() {
+	logic_activity.RegisterActivityHandler(public_protocol_common.EnActivityType_EN_ACTIVITY_TYPE_LOGIN, &activityLoginHandler{})
+}
+
+// activityLoginHandler 登入活动，活动期间每个逻辑日登入累计一天，按累计天数领取奖励
+type activityLoginHandler struct {
+	logic_activity.ActivityHandlerBase
+}
+
+func (h *activityLoginHandler) OnJoin(_ctx cd.RpcContext, _owner *data.User, _activity *public_protocol_config.Readonly_ExcelActivity,
+	activityData *public_protocol_pbdesc.DActivityUserData,
+) {
+	activityData.Detail = &public_protocol_pbdesc.DActivityUserData_Login{
+		Login: &public_protocol_pbdesc.DActivityLoginData{},
+	}
+}
+
+func (h *activityLoginHandler) Refresh(ctx cd.RpcContext, _owner *data.User, _activity *public_protocol_config.Readonly_ExcelActivity,
+	activityData *public_protocol_pbdesc.DActivityUserData,
+) bool {
+	return refreshActivityLoginDays(activityData.MutableLogin(), logical_time.GetDayId(ctx.GetNow(), nil))
+}
+
+func (h *activityLoginHandler) ReceiveReward(ctx cd.RpcContext, owner *data.User, activity *public_protocol_config.Readonly_ExcelActivity,
+	activityData *public_protocol_pbdesc.DActivityUserData, rewardId int32,
+) ([]*data.ItemAddGuard, int32) {
+	loginData := activityData.MutableLogin()
+	if resultCode := checkActivityLoginReward(loginData, rewardId); resultCode != 0 {
+		return nil, resultCode
+	}
+
+	rewardRow := config.GetConfigManager().GetCurrentConfigGroup().GetExcelActivityLoginRewardByActivityIdDay(activity.GetActivityId(), rewardId)
+	if rewardRow == nil {
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_ACTIVITY_REWARD_NOT_FOUND)
+	}
+
+	instances, result := owner.GenerateMultipleItemInstancesFromCfgOffset(ctx, rewardRow.GetRewards(), true)
+	if result.IsError() {
+		result.LogError(ctx, "generate activity login reward item instances failed", "activity_id", activity.GetActivityId(), "day", rewardId)
+		return nil, result.GetResponseCode()
+	}
+
+	addGuard, result := owner.CheckAddItem(ctx, instances)
+	if result.IsError() {
+		result.LogError(ctx, "check add activity login reward items failed", "activity_id", activity.GetActivityId(), "day", rewardId)
+		return nil, result.GetResponseCode()
+	}
+
+	loginData.ReceivedDays = append(loginData.ReceivedDays, rewardId)
+	slices.Sort(loginData.ReceivedDays)
+	return addGuard, 0
+}
+
+func (h *activityLoginHandler) CollectUnclaimedRewards(_ctx cd.RpcContext, activity *public_protocol_config.Readonly_ExcelActivity,
+	activityData *public_protocol_pbdesc.DActivityUserData,
+) []*public_protocol_common.DItemOffset {
+	loginData := activityData.GetLogin()
+
+	var ret []*public_protocol_common.DItemOffset
+	for _, rewardRow := range config.GetConfigManager().GetCurrentConfigGroup().GetExcelActivityLoginRewardByActivityId(activity.GetActivityId()) {
+		if checkActivityLoginReward(loginData, rewardRow.GetDay()) != 0 {
+			continue
+		}
+
+		for _, reward := range rewardRow.GetRewards() {
+			ret = append(ret, &public_protocol_common.DItemOffset{
+				TypeId:       reward.GetTypeId(),
+				Count:        reward.GetCount(),
+				ExpireOffset: reward.GetExpireOffset(),
+			})
+		}
+	}
+	return ret
+}
+
+// refreshActivityLoginDays 每个逻辑日只累计一次
+func refreshActivityLoginDays(loginData *public_protocol_pbdesc.DActivityLoginData, dayId int64) bool {
+	if loginData.GetLastLoginDayId() == dayId && loginData.GetLoginDays() > 0 {
+		return false
+	}
+
+	loginData.LoginDays++
+	loginData.LastLoginDayId = dayId
+	return true
+}
+
+func checkActivityLoginReward(loginData *public_protocol_pbdesc.DActivityLoginData, day int32) int32 {
+	if day <= 0 || day > loginData.GetLoginDays() {
+		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_ACTIVITY_REWARD_NOT_REACHED)
+	}
+
+	if slices.Contains(loginData.GetReceivedDays(), day) {
+		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_ACTIVITY_REWARD_ALREADY_RECEIVED)
+	}
+	return 0
+}
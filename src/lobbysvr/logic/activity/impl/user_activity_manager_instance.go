@@ -0,0 +1,387 @@
+package lobbysvr_logic_activity_impl
+
+import (
+	"time"
+
+	"github.com/atframework/libatapp-go"
+
+	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
+
+	lu "github.com/atframework/atframe-utils-go/lang_utility"
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+
+	config "github.com/atframework/atsf4g-go/component/config"
+	mail_component "github.com/atframework/atsf4g-go/component/mail"
+	private_protocol_log "github.com/atframework/atsf4g-go/component/protocol/private/log/protocol/log"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_common "github.com/atframework/atsf4g-go/component/protocol/public/common/protocol/common"
+	public_protocol_config "github.com/atframework/atsf4g-go/component/protocol/public/config/protocol/config"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	logic_activity "github.com/atframework/atsf4g-go/service-lobbysvr/logic/activity"
+	activity_action "github.com/atframework/atsf4g-go/service-lobbysvr/logic/activity/action"
+	logic_condition "github.com/atframework/atsf4g-go/service-lobbysvr/logic/condition"
+	service_protocol "github.com/atframework/atsf4g-go/service-lobbysvr/protocol/public/protocol/pbdesc"
+)
+
+func init() {
+	var _ logic_activity.UserActivityManager = (*UserActivityManager)(nil)
+	data.RegisterUserModuleManagerCreator[logic_activity.UserActivityManager](func(ctx cd.RpcContext, owner *data.User) data.UserModuleManagerImpl {
+		return CreateUserActivityManager(owner)
+	})
+}
+
+type UserActivityManager struct {
+	owner *data.User
+	data.UserModuleManagerBase
+
+	activities           map[int64]*public_protocol_pbdesc.DActivityUserData // key 为 instance_id
+	pendingCompensations []*private_protocol_pbdesc.UserActivityCompensation
+
+	compensationTask lu.AtomicInterface[cd.TaskActionImpl]
+
+	dirtyInstances       map[int64]struct{}
+	dirtyClosedInstances []int64
+}
+
+func CreateUserActivityManager(owner *data.User) *UserActivityManager {
+	return &UserActivityManager{
+		owner:                 owner,
+		UserModuleManagerBase: *data.CreateUserModuleManagerBase(owner),
+
+		activities:     make(map[int64]*public_protocol_pbdesc.DActivityUserData),
+		dirtyInstances: make(map[int64]struct{}),
+	}
+}
+
+func (m *UserActivityManager) InitFromDB(ctx cd.RpcContext, _dbUser *private_protocol_pbdesc.DatabaseTableUser) cd.RpcResult {
+	for _, activityData := range _dbUser.GetActivityData().GetActivities() {
+		m.activities[activityData.GetInstanceId()] = activityData
+	}
+	m.pendingCompensations = _dbUser.GetActivityData().GetPendingCompensations()
+
+	return cd.CreateRpcResultOk()
+}
+
+func (m *UserActivityManager) DumpToDB(ctx cd.RpcContext, _dbUser *private_protocol_pbdesc.DatabaseTableUser) cd.RpcResult {
+	activityData := _dbUser.MutableActivityData()
+	activityData.Activities = make([]*public_protocol_pbdesc.DActivityUserData, 0, len(m.activities))
+	for _, v := range m.activities {
+		activityData.Activities = append(activityData.Activities, v)
+	}
+	activityData.PendingCompensations = m.pendingCompensations
+
+	return cd.CreateRpcResultOk()
+}
+
+func (m *UserActivityManager) OnLogin(ctx cd.RpcContext) {
+	m.refreshActivities(ctx)
+}
+
+func (m *UserActivityManager) RefreshLimitMinute(ctx cd.RpcContext) {
+	m.refreshActivities(ctx)
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+
+func (m *UserActivityManager) GetActivityData(activityId int32) *public_protocol_pbdesc.DActivityUserData {
+	for _, activityData := range m.activities {
+		if activityData.GetActivityId() == activityId {
+			return activityData
+		}
+	}
+	return nil
+}
+
+func (m *UserActivityManager) FetchData(ctx cd.RpcContext, rspBody *service_protocol.SCActivityGetInfoRsp) {
+	m.refreshActivities(ctx)
+
+	for _, activityData := range m.activities {
+		rspBody.Activities = append(rspBody.Activities, activityData)
+	}
+}
+
+func (m *UserActivityManager) ReceiveReward(ctx cd.RpcContext, activityId int32, rewardId int32,
+	rspBody *service_protocol.SCActivityReceiveRewardRsp,
+) int32 {
+	activityRow := config.GetConfigManager().GetCurrentConfigGroup().GetExcelActivityByActivityId(activityId)
+	if activityRow == nil {
+		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_ACTIVITY_NOT_FOUND)
+	}
+
+	handler := logic_activity.GetActivityHandler(activityRow.GetActivityType())
+	if handler == nil {
+		ctx.LogError("activity handler not found", "activity_id", activityId, "activity_type", activityRow.GetActivityType())
+		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_ACTIVITY_OPERATION_NOT_SUPPORTED)
+	}
+
+	m.refreshActivities(ctx)
+	activityData := m.GetActivityData(activityId)
+	if activityData == nil {
+		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_ACTIVITY_NOT_OPEN)
+	}
+
+	addGuard, resultCode := handler.ReceiveReward(ctx, m.GetOwner(), activityRow, activityData, rewardId)
+	if resultCode != 0 {
+		return resultCode
+	}
+
+	m.GetOwner().AddItem(ctx, addGuard, &data.ItemFlowReason{
+		MajorReason: int32(public_protocol_common.EnItemFlowReasonMajorType_EN_ITEM_FLOW_REASON_MAJOR_ACTIVITY),
+		MinorReason: int32(public_protocol_common.EnItemFlowReasonMinorType_EN_ITEM_FLOW_REASON_MINOR_ACTIVITY_REWARD),
+		Parameter:   int64(activityId),
+	})
+	m.markDirty(activityData.GetInstanceId())
+
+	log := private_protocol_log.OperationSupportSystemLog{}
+	flow := m.mutableActivityFlow(&log, activityData, private_protocol_log.OSSActivityFlow_EN_OSS_ACTIVITY_OPERATION_TYPE_REWARD)
+	flow.RewardId = rewardId
+	for _, guard := range addGuard {
+		flow.RewardItems = append(flow.RewardItems, guard.Item.GetItemBasic())
+		rspBody.MergeRewardItems(guard.GetAddedItems())
+	}
+	m.GetOwner().SendUserOssLog(ctx, &log)
+
+	rspBody.Activity = activityData
+	return 0
+}
+
+// SendPendingCompensations 逐个发送，失败时保留剩余的等下一次刷新再发
+func (m *UserActivityManager) SendPendingCompensations(ctx cd.AwaitableContext) data.Result {
+	for len(m.pendingCompensations) > 0 {
+		compensation := m.pendingCompensations[0]
+
+		sender := public_protocol_pbdesc.DMailUserInfo{}
+		receiver := public_protocol_pbdesc.DMailUserInfo{}
+		receiver.MutableProfile().UserId = m.GetOwner().GetUserId()
+		receiver.MutableProfile().ZoneId = m.GetOwner().GetZoneId()
+		result, _ := mail_component.AddUserMailWithTemplate(ctx, compensation.GetMailTemplateId(), &sender, &receiver,
+			m.GetOwner().GetZoneId(), int32(public_protocol_pbdesc.EnMailChannelType_EN_MAIL_CHANNEL_USER_REWARD), 0, compensation.GetRewards(),
+			&public_protocol_pbdesc.DMailFlowReason{
+				MajorReason: int32(public_protocol_common.EnItemFlowReasonMajorType_EN_ITEM_FLOW_REASON_MAJOR_ACTIVITY),
+				MinorReason: int32(public_protocol_common.EnItemFlowReasonMinorType_EN_ITEM_FLOW_REASON_MINOR_ACTIVITY_CLOSE_COMPENSATION),
+				Parameter:   int64(compensation.GetActivityId()),
+			}, nil, 0, 0)
+		if result.IsError() {
+			result.LogError(ctx, "send activity close compensation mail failed", "activity_id", compensation.GetActivityId(),
+				"instance_id", compensation.GetInstanceId(), "mail_template_id", compensation.GetMailTemplateId())
+			return result
+		}
+
+		m.pendingCompensations = m.pendingCompensations[1:]
+
+		log := private_protocol_log.OperationSupportSystemLog{}
+		flow := log.MutableLog().MutableActivityFlow()
+		flow.ActivityId = compensation.GetActivityId()
+		flow.InstanceId = compensation.GetInstanceId()
+		flow.Operation = private_protocol_log.OSSActivityFlow_EN_OSS_ACTIVITY_OPERATION_TYPE_COMPENSATION
+		for _, reward := range compensation.GetRewards() {
+			flow.RewardItems = append(flow.RewardItems, &public_protocol_common.DItemBasic{
+				TypeId: reward.GetTypeId(),
+				Count:  reward.GetCount(),
+			})
+		}
+		m.GetOwner().SendUserOssLog(ctx, &log)
+	}
+
+	return cd.CreateRpcResultOk()
+}
+
+// refreshActivities 先清理已经结束的期数，再参与新开放的期数
+func (m *UserActivityManager) refreshActivities(ctx cd.RpcContext) {
+	cfgGroup := config.GetConfigManager().GetCurrentConfigGroup()
+	now := ctx.GetNow()
+
+	for instanceId, activityData := range m.activities {
+		activityRow := cfgGroup.GetExcelActivityByActivityId(activityData.GetActivityId())
+		instance, ok := logic_activity.CalculateActivityInstance(activityRow, now)
+		if ok && instance.InstanceId == instanceId {
+			continue
+		}
+
+		m.closeActivity(ctx, activityRow, activityData)
+	}
+
+	if allActivities := cfgGroup.GetExcelActivityAllOfActivityId(); allActivities != nil {
+		for _, activityRow := range *allActivities {
+			instance, ok := logic_activity.CalculateActivityInstance(activityRow, now)
+			if !ok {
+				continue
+			}
+
+			handler := logic_activity.GetActivityHandler(activityRow.GetActivityType())
+			if handler == nil {
+				continue
+			}
+
+			activityData, exists := m.activities[instance.InstanceId]
+			if !exists {
+				activityData = m.joinActivity(ctx, activityRow, handler, instance)
+				if activityData == nil {
+					continue
+				}
+			}
+
+			if handler.Refresh(ctx, m.GetOwner(), activityRow, activityData) {
+				m.markDirty(instance.InstanceId)
+			}
+		}
+	}
+
+	if len(m.pendingCompensations) > 0 {
+		m.tryToStartCompensationTask(ctx)
+	}
+}
+
+// joinActivity 满足参与条件时创建这一期的数据
+func (m *UserActivityManager) joinActivity(ctx cd.RpcContext, activityRow *public_protocol_config.Readonly_ExcelActivity,
+	handler logic_activity.ActivityHandlerImpl, instance logic_activity.ActivityInstance,
+) *public_protocol_pbdesc.DActivityUserData {
+	if logic_condition.HasLimitData(activityRow.GetUnlockCondition()) {
+		conditionMgr := data.UserGetModuleManager[logic_condition.UserConditionManager](m.GetOwner())
+		if conditionMgr == nil {
+			ctx.LogError("UserConditionManager not found", "activity_id", activityRow.GetActivityId())
+			return nil
+		}
+
+		if !conditionMgr.CheckBasicLimit(ctx, activityRow.GetUnlockCondition(), logic_condition.CreateEmptyRuleCheckerRuntime()).IsOK() {
+			return nil
+		}
+	}
+
+	activityData := &public_protocol_pbdesc.DActivityUserData{
+		ActivityId:   activityRow.GetActivityId(),
+		InstanceId:   instance.InstanceId,
+		StartTime:    instance.StartTime,
+		EndTime:      instance.EndTime,
+		JoinTime:     ctx.GetNow().Unix(),
+		ActivityType: activityRow.GetActivityType(),
+	}
+	handler.OnJoin(ctx, m.GetOwner(), activityRow, activityData)
+	m.activities[instance.InstanceId] = activityData
+	m.markDirty(instance.InstanceId)
+
+	log := private_protocol_log.OperationSupportSystemLog{}
+	m.mutableActivityFlow(&log, activityData, private_protocol_log.OSSActivityFlow_EN_OSS_ACTIVITY_OPERATION_TYPE_JOIN)
+	m.GetOwner().SendUserOssLog(ctx, &log)
+	return activityData
+}
+
+// closeActivity 清理这一期的数据，配置了补发邮件时记录未领取的奖励
+func (m *UserActivityManager) closeActivity(ctx cd.RpcContext, activityRow *public_protocol_config.Readonly_ExcelActivity,
+	activityData *public_protocol_pbdesc.DActivityUserData,
+) {
+	instanceId := activityData.GetInstanceId()
+	delete(m.activities, instanceId)
+	delete(m.dirtyInstances, instanceId)
+	m.dirtyClosedInstances = append(m.dirtyClosedInstances, instanceId)
+	m.insertDirtyHandle()
+
+	log := private_protocol_log.OperationSupportSystemLog{}
+	m.mutableActivityFlow(&log, activityData, private_protocol_log.OSSActivityFlow_EN_OSS_ACTIVITY_OPERATION_TYPE_CLOSE)
+	m.GetOwner().SendUserOssLog(ctx, &log)
+
+	// 配置已经删除的活动不再补发
+	if activityRow == nil || activityRow.GetCloseCompensationMailTemplateId() == 0 {
+		return
+	}
+
+	handler := logic_activity.GetActivityHandler(activityData.GetActivityType())
+	if handler == nil {
+		return
+	}
+
+	rewards := handler.CollectUnclaimedRewards(ctx, activityRow, activityData)
+	if len(rewards) == 0 {
+		return
+	}
+
+	m.pendingCompensations = append(m.pendingCompensations, &private_protocol_pbdesc.UserActivityCompensation{
+		ActivityId:     activityData.GetActivityId(),
+		InstanceId:     instanceId,
+		MailTemplateId: activityRow.GetCloseCompensationMailTemplateId(),
+		Rewards:        rewards,
+	})
+}
+
+func (m *UserActivityManager) tryToStartCompensationTask(ctx cd.RpcContext) {
+	if m.isCompensationTaskRunning() {
+		return
+	}
+
+	owner := m.GetOwner()
+	if owner == nil || !owner.IsWriteable() {
+		return
+	}
+
+	d := libatapp.AtappGetModule[*cd.NoMessageDispatcher](ctx.GetApp())
+	if d == nil {
+		ctx.LogError("start activity compensation task failed: NoMessageDispatcher not found")
+		return
+	}
+
+	task, startData := cd.CreateNoMessageTaskAction(
+		d, d.CreateRpcContext(), owner.GetActorExecutor(),
+		func(rd cd.DispatcherImpl, actor *cd.ActorExecutor, timeout time.Duration) *activity_action.TaskActionActivityCompensation {
+			return activity_action.CreateTaskActionActivityCompensation(rd, actor, owner, m, timeout)
+		},
+	)
+
+	err := libatapp.AtappGetModule[*cd.TaskManager](ctx.GetApp()).StartTaskAction(ctx, task, &startData)
+	if err != nil {
+		ctx.LogError("start activity compensation task failed", "error", err, "user_id", owner.GetUserId())
+		return
+	}
+	m.compensationTask.Store(task)
+}
+
+func (m *UserActivityManager) isCompensationTaskRunning() bool {
+	task := m.compensationTask.Load()
+	if lu.IsNil(task) {
+		return false
+	}
+	if task.IsExiting() {
+		m.compensationTask.Store(nil)
+		return false
+	}
+	return true
+}
+
+func (m *UserActivityManager) mutableActivityFlow(log *private_protocol_log.OperationSupportSystemLog,
+	activityData *public_protocol_pbdesc.DActivityUserData, operation private_protocol_log.OSSActivityFlow_EnOSSActivityOperationType,
+) *private_protocol_log.OSSActivityFlow {
+	flow := log.MutableLog().MutableActivityFlow()
+	flow.ActivityId = activityData.GetActivityId()
+	flow.InstanceId = activityData.GetInstanceId()
+	flow.ActivityType = int32(activityData.GetActivityType())
+	flow.Operation = operation
+	return flow
+}
+
+func (m *UserActivityManager) markDirty(instanceId int64) {
+	m.dirtyInstances[instanceId] = struct{}{}
+	m.insertDirtyHandle()
+}
+
+func (m *UserActivityManager) insertDirtyHandle() {
+	m.GetOwner().InsertDirtyHandleIfNotExists(m,
+		func(ctx cd.RpcContext, dirty *data.UserDirtyData) bool {
+			dirtyData := &public_protocol_pbdesc.DUserActivityDirtyChg{
+				ClosedInstanceIds: m.dirtyClosedInstances,
+			}
+			for instanceId := range m.dirtyInstances {
+				if activityData, ok := m.activities[instanceId]; ok {
+					dirtyData.Activities = append(dirtyData.Activities, activityData)
+				}
+			}
+			if len(dirtyData.Activities) == 0 && len(dirtyData.ClosedInstanceIds) == 0 {
+				return false
+			}
+			dirty.MutableNormalDirtyChangeMessage().DirtyActivity = dirtyData
+			return true
+		},
+		func(ctx cd.RpcContext) {
+			clear(m.dirtyInstances)
+			m.dirtyClosedInstances = nil
+		},
+	)
+}
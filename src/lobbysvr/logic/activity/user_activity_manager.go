@@ -0,0 +1,24 @@
+package lobbysvr_logic_activity
+
+import (
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
+	service_protocol "github.com/atframework/atsf4g-go/service-lobbysvr/protocol/public/protocol/pbdesc"
+)
+
+// UserActivityManager 玩家的活动数据，按期数保存，每一期结束时清理
+// 结束时未领取的奖励先记录下来，再由异步任务通过邮件补发
+type UserActivityManager interface {
+	data.UserModuleManagerImpl
+
+	// GetActivityData 当前开放的一期的数据，没有参与时返回 nil
+	GetActivityData(activityId int32) *public_protocol_pbdesc.DActivityUserData
+
+	// 只返回当前开放且已参与的活动
+	FetchData(ctx cd.RpcContext, rspBody *service_protocol.SCActivityGetInfoRsp)
+	ReceiveReward(ctx cd.RpcContext, activityId int32, rewardId int32, rspBody *service_protocol.SCActivityReceiveRewardRsp) int32
+
+	// SendPendingCompensations 发送补发邮件，发送成功的从待发送列表中移除
+	SendPendingCompensations(ctx cd.AwaitableContext) data.Result
+}
@@ -12,7 +12,10 @@ import (
 	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
 	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
 	logic_async_jobs "github.com/atframework/atsf4g-go/service-lobbysvr/logic/async_jobs"
+	logic_friend "github.com/atframework/atsf4g-go/service-lobbysvr/logic/friend"
+	logic_guild "github.com/atframework/atsf4g-go/service-lobbysvr/logic/guild"
 	logic_mail "github.com/atframework/atsf4g-go/service-lobbysvr/logic/mail"
+	logic_payment "github.com/atframework/atsf4g-go/service-lobbysvr/logic/payment"
 )
 
 // UserAsyncJobsManagerForTask 异步任务需要的 UserAsyncJobsManager 接口
@@ -29,8 +32,8 @@ type UserAsyncJobsManagerForTask interface {
 // SyncCallback 同步回调函数类型
 type SyncCallback func(ctx cd.RpcContext, user *data.User, jobType int32, jobData *private_protocol_pbdesc.UserAsyncJobsBlobData) int32
 
-// AsyncCallback 异步回调函数类型
-type AsyncCallback func(ctx cd.RpcContext, user *data.User, jobType int32, jobData *private_protocol_pbdesc.UserAsyncJobsBlobData) cd.RpcResult
+// AsyncCallback 异步回调函数类型，可以在回调里等待其他 RPC
+type AsyncCallback func(ctx cd.AwaitableContext, user *data.User, jobType int32, jobData *private_protocol_pbdesc.UserAsyncJobsBlobData) cd.RpcResult
 
 // TaskActionPlayerRemotePatchJobs 远程补丁任务
 type TaskActionPlayerRemotePatchJobs struct {
@@ -140,6 +143,12 @@ func (t *TaskActionPlayerRemotePatchJobs) getAllAsyncJobTypes() []int32 {
 		int32(private_protocol_pbdesc.EnPlayerAsyncJobsType_EN_PAJT_NORMAL),
 		int32(private_protocol_pbdesc.EnPlayerAsyncJobsType_EN_PAJT_MAIL_IMPORTANT),
 		int32(private_protocol_pbdesc.EnPlayerAsyncJobsType_EN_PAJT_MAIL_SYSTEM),
+		// 好友的任务按申请、同意、删除、赠送的顺序处理，同一轮中先同意后删除时不会留下好友关系
+		int32(private_protocol_pbdesc.EnPlayerAsyncJobsType_EN_PAJT_FRIEND_REQUEST),
+		int32(private_protocol_pbdesc.EnPlayerAsyncJobsType_EN_PAJT_FRIEND_ACCEPT),
+		int32(private_protocol_pbdesc.EnPlayerAsyncJobsType_EN_PAJT_FRIEND_REMOVE),
+		int32(private_protocol_pbdesc.EnPlayerAsyncJobsType_EN_PAJT_FRIEND_GIFT),
+		int32(private_protocol_pbdesc.EnPlayerAsyncJobsType_EN_PAJT_GUILD),
 	}
 }
 
@@ -251,7 +260,7 @@ func (t *TaskActionPlayerRemotePatchJobs) processJobsFromDB(ctx cd.AwaitableCont
 }
 
 // processRetryJobs 处理重试队列中的任务
-func (t *TaskActionPlayerRemotePatchJobs) processRetryJobs(ctx cd.RpcContext, jobType int32) int {
+func (t *TaskActionPlayerRemotePatchJobs) processRetryJobs(ctx cd.AwaitableContext, jobType int32) int {
 	retryJobs := t.manager.GetRetryJobs(jobType)
 	if len(retryJobs) == 0 {
 		return 0
@@ -290,7 +299,7 @@ func (t *TaskActionPlayerRemotePatchJobs) processRetryJobs(ctx cd.RpcContext, jo
 }
 
 // doJob 执行单个任务
-func (t *TaskActionPlayerRemotePatchJobs) doJob(ctx cd.RpcContext, jobType int32, jobData *private_protocol_pbdesc.UserAsyncJobsBlobData) int32 {
+func (t *TaskActionPlayerRemotePatchJobs) doJob(ctx cd.AwaitableContext, jobType int32, jobData *private_protocol_pbdesc.UserAsyncJobsBlobData) int32 {
 	if jobData == nil {
 		return 0
 	}
@@ -357,6 +366,112 @@ func (t *TaskActionPlayerRemotePatchJobs) registerCallbacks() {
 		}
 		return ret
 	}
+
+	// 支付发货，背包放不下时需要发送邮件
+	t.asyncCallbacks[private_protocol_pbdesc.UserAsyncJobsBlobData_EnActionID_PayDelivery] = func(ctx cd.AwaitableContext, user *data.User, jobType int32, jobData *private_protocol_pbdesc.UserAsyncJobsBlobData) cd.RpcResult {
+		order := jobData.GetPayDelivery().GetOrder()
+		ctx.LogInfo("do async action pay_delivery", "order_id", order.GetOrderId(), "product_id", order.GetProductId())
+
+		paymentMgr := data.UserGetModuleManager[logic_payment.UserPaymentManager](user)
+		if paymentMgr == nil {
+			ctx.LogError("do async action pay_delivery failed: user payment manager is nil")
+			return cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+		}
+		return cd.CreateRpcResultOkResponse(paymentMgr.DeliverOrder(ctx, order))
+	}
+
+	// 支付退款/拒付
+	t.syncCallbacks[private_protocol_pbdesc.UserAsyncJobsBlobData_EnActionID_PayRefund] = func(ctx cd.RpcContext, user *data.User, jobType int32, jobData *private_protocol_pbdesc.UserAsyncJobsBlobData) int32 {
+		order := jobData.GetPayRefund().GetOrder()
+		ctx.LogInfo("do async action pay_refund", "order_id", order.GetOrderId(), "product_id", order.GetProductId())
+
+		paymentMgr := data.UserGetModuleManager[logic_payment.UserPaymentManager](user)
+		if paymentMgr == nil {
+			ctx.LogError("do async action pay_refund failed: user payment manager is nil")
+			return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+		}
+		return paymentMgr.RefundOrder(ctx, order)
+	}
+
+	// 收到好友申请
+	t.syncCallbacks[private_protocol_pbdesc.UserAsyncJobsBlobData_EnActionID_FriendRequest] = func(ctx cd.RpcContext, user *data.User, jobType int32, jobData *private_protocol_pbdesc.UserAsyncJobsBlobData) int32 {
+		friendMsg := jobData.GetFriendRequest()
+		ctx.LogInfo("do async action friend_request", "from_user_id", friendMsg.GetFromUserId(), "from_zone_id", friendMsg.GetFromZoneId())
+
+		friendMgr := data.UserGetModuleManager[logic_friend.UserFriendManager](user)
+		if friendMgr == nil {
+			ctx.LogError("do async action friend_request failed: user friend manager is nil")
+			return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+		}
+		return friendMgr.OnReceiveRequest(ctx, friendMsg)
+	}
+
+	// 对方同意了好友申请，好友已满时需要投递任务通知对方删除
+	t.asyncCallbacks[private_protocol_pbdesc.UserAsyncJobsBlobData_EnActionID_FriendAccept] = func(ctx cd.AwaitableContext, user *data.User, jobType int32, jobData *private_protocol_pbdesc.UserAsyncJobsBlobData) cd.RpcResult {
+		friendMsg := jobData.GetFriendAccept()
+		ctx.LogInfo("do async action friend_accept", "from_user_id", friendMsg.GetFromUserId(), "from_zone_id", friendMsg.GetFromZoneId())
+
+		friendMgr := data.UserGetModuleManager[logic_friend.UserFriendManager](user)
+		if friendMgr == nil {
+			ctx.LogError("do async action friend_accept failed: user friend manager is nil")
+			return cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+		}
+		return cd.CreateRpcResultOkResponse(friendMgr.OnRequestAccepted(ctx, friendMsg))
+	}
+
+	// 被对方删除或拉黑
+	t.syncCallbacks[private_protocol_pbdesc.UserAsyncJobsBlobData_EnActionID_FriendRemove] = func(ctx cd.RpcContext, user *data.User, jobType int32, jobData *private_protocol_pbdesc.UserAsyncJobsBlobData) int32 {
+		friendMsg := jobData.GetFriendRemove()
+		ctx.LogInfo("do async action friend_remove", "from_user_id", friendMsg.GetFromUserId(), "from_zone_id", friendMsg.GetFromZoneId())
+
+		friendMgr := data.UserGetModuleManager[logic_friend.UserFriendManager](user)
+		if friendMgr == nil {
+			ctx.LogError("do async action friend_remove failed: user friend manager is nil")
+			return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+		}
+		return friendMgr.OnRemoved(ctx, friendMsg)
+	}
+
+	// 收到好友赠送
+	t.syncCallbacks[private_protocol_pbdesc.UserAsyncJobsBlobData_EnActionID_FriendGift] = func(ctx cd.RpcContext, user *data.User, jobType int32, jobData *private_protocol_pbdesc.UserAsyncJobsBlobData) int32 {
+		friendMsg := jobData.GetFriendGift()
+		ctx.LogInfo("do async action friend_gift", "from_user_id", friendMsg.GetFromUserId(), "from_zone_id", friendMsg.GetFromZoneId())
+
+		friendMgr := data.UserGetModuleManager[logic_friend.UserFriendManager](user)
+		if friendMgr == nil {
+			ctx.LogError("do async action friend_gift failed: user friend manager is nil")
+			return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+		}
+		return friendMgr.OnReceiveGift(ctx, friendMsg)
+	}
+
+	// 入会申请被通过
+	t.syncCallbacks[private_protocol_pbdesc.UserAsyncJobsBlobData_EnActionID_GuildJoin] = func(ctx cd.RpcContext, user *data.User, jobType int32, jobData *private_protocol_pbdesc.UserAsyncJobsBlobData) int32 {
+		guildMsg := jobData.GetGuildJoin()
+		ctx.LogInfo("do async action guild_join", "guild_id", guildMsg.GetGuildId(), "guild_zone_id", guildMsg.GetGuildZoneId(),
+			"operator_user_id", guildMsg.GetOperatorUserId())
+
+		guildMgr := data.UserGetModuleManager[logic_guild.UserGuildManager](user)
+		if guildMgr == nil {
+			ctx.LogError("do async action guild_join failed: user guild manager is nil")
+			return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+		}
+		return guildMgr.OnJoined(ctx, guildMsg)
+	}
+
+	// 被移出公会
+	t.syncCallbacks[private_protocol_pbdesc.UserAsyncJobsBlobData_EnActionID_GuildRemoved] = func(ctx cd.RpcContext, user *data.User, jobType int32, jobData *private_protocol_pbdesc.UserAsyncJobsBlobData) int32 {
+		guildMsg := jobData.GetGuildRemoved()
+		ctx.LogInfo("do async action guild_removed", "guild_id", guildMsg.GetGuildId(), "guild_zone_id", guildMsg.GetGuildZoneId(),
+			"operator_user_id", guildMsg.GetOperatorUserId())
+
+		guildMgr := data.UserGetModuleManager[logic_guild.UserGuildManager](user)
+		if guildMgr == nil {
+			ctx.LogError("do async action guild_removed failed: user guild manager is nil")
+			return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+		}
+		return guildMgr.OnRemoved(ctx, guildMsg)
+	}
 }
 
 func (t *TaskActionPlayerRemotePatchJobs) OnSuccess() {
@@ -0,0 +1,47 @@
+// Copyright 2026 atframework
+
+package lobbysvr_logic_chat_action
+
+import (
+	"fmt"
+
+	libatapp "github.com/atframework/libatapp-go"
+
+	component_dispatcher "github.com/atframework/atsf4g-go/component/dispatcher"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	user_controller "github.com/atframework/atsf4g-go/component/user_controller"
+	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
+	logic_chat "github.com/atframework/atsf4g-go/service-lobbysvr/logic/chat"
+	service_protocol "github.com/atframework/atsf4g-go/service-lobbysvr/protocol/public/protocol/pbdesc"
+)
+
+type TaskActionChatGetOfflineMessages struct {
+	user_controller.TaskActionCSBase[*service_protocol.CSChatGetOfflineMessagesReq, *service_protocol.SCChatGetOfflineMessagesRsp]
+}
+
+func (t *TaskActionChatGetOfflineMessages) Name() string {
+	return "TaskActionChatGetOfflineMessages"
+}
+
+func (t *TaskActionChatGetOfflineMessages) Run(_startData *component_dispatcher.DispatcherStartData) error {
+	user, ok := t.GetUser().(*data.User)
+	if !ok || user == nil {
+		t.SetResponseCode(int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_USER_NOT_FOUND))
+		return fmt.Errorf("user not found")
+	}
+
+	manager := libatapp.AtappGetModule[*logic_chat.ChatManager](t.GetRpcContext().GetApp())
+	if manager == nil {
+		t.SetResponseError(public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+		return fmt.Errorf("chat manager not found")
+	}
+
+	messages, code := manager.FetchOfflineMessages(t.GetAwaitableContext(), user)
+	if code != 0 {
+		t.SetResponseCode(code)
+		return nil
+	}
+
+	t.MutableResponseBody().Messages = messages
+	return nil
+}
@@ -0,0 +1,90 @@
+// Copyright 2026 atframework
+// @brief 聊天内容检查和过滤钩子
+
+package lobbysvr_logic_chat
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	uc "github.com/atframework/atsf4g-go/component/user_controller"
+)
+
+// ChatContentFilter 聊天内容过滤器，用于接入脏字库或第三方内容安全服务
+// 返回替换后的内容，拒绝发送时返回非0错误码，一般使用 EN_ERR_TEXT_HAS_DIRTY_WORD
+type ChatContentFilter interface {
+	Name() string
+	Filter(ctx cd.RpcContext, sender uc.UserImpl, channelType public_protocol_pbdesc.EnChatChannelType, content string) (string, int32)
+}
+
+var (
+	contentFiltersLock sync.RWMutex
+	contentFilters     []ChatContentFilter
+)
+
+// RegisterChatContentFilter 注册内容过滤器，按注册顺序依次执行，同名覆盖
+func RegisterChatContentFilter(f ChatContentFilter) {
+	if f == nil {
+		return
+	}
+
+	contentFiltersLock.Lock()
+	defer contentFiltersLock.Unlock()
+
+	for i, exists := range contentFilters {
+		if exists.Name() == f.Name() {
+			contentFilters[i] = f
+			return
+		}
+	}
+	contentFilters = append(contentFilters, f)
+}
+
+// UnregisterChatContentFilter 移除内容过滤器
+func UnregisterChatContentFilter(name string) {
+	contentFiltersLock.Lock()
+	defer contentFiltersLock.Unlock()
+
+	for i, exists := range contentFilters {
+		if exists.Name() == name {
+			contentFilters = append(contentFilters[:i:i], contentFilters[i+1:]...)
+			return
+		}
+	}
+}
+
+func getChatContentFilters() []ChatContentFilter {
+	contentFiltersLock.RLock()
+	defer contentFiltersLock.RUnlock()
+
+	return slices.Clone(contentFilters)
+}
+
+// checkChatContent 检查长度后依次执行内容过滤器，返回最终发出的内容
+func checkChatContent(ctx cd.RpcContext, sender uc.UserImpl, channelType public_protocol_pbdesc.EnChatChannelType,
+	content string, maxLength int,
+) (string, int32) {
+	content = strings.TrimSpace(content)
+	length := utf8.RuneCountInString(content)
+	if length == 0 || (maxLength > 0 && length > maxLength) {
+		return "", int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_CHAT_CONTENT_LENGTH_INVALID)
+	}
+
+	for _, f := range getChatContentFilters() {
+		var code int32
+		content, code = f.Filter(ctx, sender, channelType, content)
+		if code != 0 {
+			return "", code
+		}
+	}
+
+	// 过滤器可能把内容全部替换掉
+	if content == "" {
+		return "", int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_TEXT_HAS_DIRTY_WORD)
+	}
+	return content, 0
+}
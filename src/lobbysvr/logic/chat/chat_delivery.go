@@ -0,0 +1,137 @@
+// Copyright 2026 atframework
+// @brief 聊天消息投递到本进程的连接
+
+package lobbysvr_logic_chat
+
+import (
+	lu "github.com/atframework/atframe-utils-go/lang_utility"
+
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	uc "github.com/atframework/atsf4g-go/component/user_controller"
+
+	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
+	logic_friend "github.com/atframework/atsf4g-go/service-lobbysvr/logic/friend"
+	service_protocol "github.com/atframework/atsf4g-go/service-lobbysvr/protocol/public/protocol/pbdesc"
+	lobbysvr_client_rpc "github.com/atframework/atsf4g-go/service-lobbysvr/rpc/lobbyclientservice"
+)
+
+type chatChannelKind int32
+
+const (
+	chatChannelInvalid chatChannelKind = iota
+	chatChannelWorld
+	chatChannelZone
+	chatChannelPrivate
+)
+
+// chatSessionSender 推送给单个连接，测试中替换成内存实现
+type chatSessionSender func(session *uc.Session, body *service_protocol.SCChatMessageSync) error
+
+func sendChatMessageSync(session *uc.Session, body *service_protocol.SCChatMessageSync) error {
+	return lobbysvr_client_rpc.SendChatMessageSync(session, body, 0)
+}
+
+// getChatChannelKind 频道类型按区间划分，同一区间内的子频道使用相同的转发规则
+func getChatChannelKind(channelType public_protocol_pbdesc.EnChatChannelType) chatChannelKind {
+	switch {
+	case channelType >= public_protocol_pbdesc.EnChatChannelType_EN_CCT_ALL_DEFAULT &&
+		channelType < public_protocol_pbdesc.EnChatChannelType_EN_CCT_ALL_BOUND:
+		return chatChannelWorld
+	case channelType >= public_protocol_pbdesc.EnChatChannelType_EN_CCT_ZONE_DEFAULT &&
+		channelType < public_protocol_pbdesc.EnChatChannelType_EN_CCT_ZONE_BOUND:
+		return chatChannelZone
+	case channelType >= public_protocol_pbdesc.EnChatChannelType_EN_CCT_PERSONAL_DEFAULT &&
+		channelType < public_protocol_pbdesc.EnChatChannelType_EN_CCT_PERSONAL_BOUND:
+		return chatChannelPrivate
+	default:
+		return chatChannelInvalid
+	}
+}
+
+// isChatSenderBlocked 接收方把发送方拉黑后不再收到对方的任何消息
+func isChatSenderBlocked(receiver uc.UserImpl, fromUserId uint64) bool {
+	user, ok := receiver.(*data.User)
+	if !ok || user == nil {
+		return false
+	}
+
+	manager := data.UserGetModuleManager[logic_friend.UserFriendManager](user)
+	if manager == nil {
+		return false
+	}
+	return manager.IsBlocked(fromUserId)
+}
+
+// isChatBroadcastReceiver 世界频道发给所有已登入的玩家，大区频道只发给同一个大区的玩家，发送者通过回包拿到消息
+func isChatBroadcastReceiver(receiver uc.UserImpl, msg *public_protocol_pbdesc.DChatMessage) bool {
+	if lu.IsNil(receiver) || receiver.GetUserId() == msg.GetFromUserId() {
+		return false
+	}
+
+	switch getChatChannelKind(msg.GetChannelType()) {
+	case chatChannelWorld:
+		return true
+	case chatChannelZone:
+		return receiver.GetZoneId() == msg.GetFromZoneId()
+	default:
+		return false
+	}
+}
+
+// fanOutChatMessage 把世界和大区消息推送给本进程内所有符合条件的连接，返回推送成功的数量
+func fanOutChatMessage(ctx cd.RpcContext, foreachSession func(func(*uc.Session) bool),
+	msg *public_protocol_pbdesc.DChatMessage, send chatSessionSender,
+) int {
+	body := &service_protocol.SCChatMessageSync{
+		Messages: []*public_protocol_pbdesc.DChatMessage{msg},
+	}
+
+	count := 0
+	foreachSession(func(session *uc.Session) bool {
+		receiver := session.GetUser()
+		if !isChatBroadcastReceiver(receiver, msg) || isChatSenderBlocked(receiver, msg.GetFromUserId()) {
+			return true
+		}
+
+		if err := send(session, body); err != nil {
+			ctx.LogWarn("send chat message to session failed", "session_node_id", session.GetSessionNodeId(),
+				"session_id", session.GetSessionId(), "message_id", msg.GetMessageId(), "error", err)
+			return true
+		}
+		count++
+		return true
+	})
+	return count
+}
+
+// deliverChatPrivateLocal 推送私聊给本进程内在线的目标玩家
+// 目标不在线或推送失败时返回 false，由调用方转存离线消息；被目标拉黑时直接丢弃
+func deliverChatPrivateLocal(ctx cd.RpcContext, receiver uc.UserImpl, msg *public_protocol_pbdesc.DChatMessage,
+	send chatSessionSender,
+) bool {
+	if lu.IsNil(receiver) {
+		return false
+	}
+
+	if isChatSenderBlocked(receiver, msg.GetFromUserId()) {
+		ctx.LogInfo("chat private message dropped by blocklist", "from_user_id", msg.GetFromUserId(),
+			"to_user_id", msg.GetToUserId(), "message_id", msg.GetMessageId())
+		return true
+	}
+
+	session := receiver.GetUserSession()
+	if session == nil {
+		return false
+	}
+
+	err := send(session, &service_protocol.SCChatMessageSync{
+		Messages: []*public_protocol_pbdesc.DChatMessage{msg},
+	})
+	if err != nil {
+		ctx.LogWarn("send chat private message failed", "to_user_id", msg.GetToUserId(),
+			"message_id", msg.GetMessageId(), "error", err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,206 @@
+package lobbysvr_logic_chat
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	public_protocol_extension "github.com/atframework/atsf4g-go/component/protocol/public/extension/protocol/extension"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	test_utility "github.com/atframework/atsf4g-go/component/test_utility"
+	uc "github.com/atframework/atsf4g-go/component/user_controller"
+	service_protocol "github.com/atframework/atsf4g-go/service-lobbysvr/protocol/public/protocol/pbdesc"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockNetworkHandle 进程内的连接，不需要真实的网络
+type mockNetworkHandle struct{}
+
+func (h *mockNetworkHandle) GetDispatcher() cd.DispatcherImpl                     { return nil }
+func (h *mockNetworkHandle) SendMessage(_ *public_protocol_extension.CSMsg) error { return nil }
+func (h *mockNetworkHandle) SetAuthorized(_ bool)                                 {}
+func (h *mockNetworkHandle) Close(_ cd.RpcContext, _ int32, _ string)             {}
+func (h *mockNetworkHandle) GetRemoteAddr() string                                { return "127.0.0.1" }
+
+// mockChatUser 只实现投递需要的接口
+type mockChatUser struct {
+	uc.UserImpl
+	zoneId  uint32
+	userId  uint64
+	session *uc.Session
+}
+
+func (u *mockChatUser) GetZoneId() uint32                                { return u.zoneId }
+func (u *mockChatUser) GetUserId() uint64                                { return u.userId }
+func (u *mockChatUser) GetUserSession() *uc.Session                      { return u.session }
+func (u *mockChatUser) BindSession(_ cd.RpcContext, session *uc.Session) { u.session = session }
+
+// mockChatSessionRecorder 记录推送给每个连接的消息
+type mockChatSessionRecorder struct {
+	received map[uint64][]*public_protocol_pbdesc.DChatMessage
+	failed   map[uint64]bool
+}
+
+func newMockChatSessionRecorder() *mockChatSessionRecorder {
+	return &mockChatSessionRecorder{
+		received: make(map[uint64][]*public_protocol_pbdesc.DChatMessage),
+		failed:   make(map[uint64]bool),
+	}
+}
+
+func (r *mockChatSessionRecorder) send(session *uc.Session, body *service_protocol.SCChatMessageSync) error {
+	if r.failed[session.GetSessionId()] {
+		return fmt.Errorf("session %d closed", session.GetSessionId())
+	}
+	r.received[session.GetSessionId()] = append(r.received[session.GetSessionId()], body.GetMessages()...)
+	return nil
+}
+
+func createMockChatSession(sessionId uint64, user *mockChatUser) *uc.Session {
+	session := uc.CreateSession(uc.CreateSessionKey(1, sessionId), &mockNetworkHandle{})
+	if user != nil {
+		session.BindUser(test_utility.NewMockRpcContext(nil), user)
+	}
+	return session
+}
+
+func foreachMockSessions(sessions ...*uc.Session) func(func(*uc.Session) bool) {
+	return func(fn func(*uc.Session) bool) {
+		for _, session := range sessions {
+			if !fn(session) {
+				return
+			}
+		}
+	}
+}
+
+// TestGetChatChannelKind 测试频道类型按区间划分
+// Scenario: 默认频道和区间内的子频道使用相同的规则，区间外和无效值不可发送
+func TestGetChatChannelKind(t *testing.T) {
+	// Act & Assert
+	assert.Equal(t, chatChannelWorld, getChatChannelKind(public_protocol_pbdesc.EnChatChannelType_EN_CCT_ALL_DEFAULT))
+	assert.Equal(t, chatChannelZone, getChatChannelKind(public_protocol_pbdesc.EnChatChannelType_EN_CCT_ZONE_DEFAULT))
+	assert.Equal(t, chatChannelZone, getChatChannelKind(public_protocol_pbdesc.EnChatChannelType(2100)))
+	assert.Equal(t, chatChannelPrivate, getChatChannelKind(public_protocol_pbdesc.EnChatChannelType_EN_CCT_PERSONAL_DEFAULT))
+	assert.Equal(t, chatChannelInvalid, getChatChannelKind(public_protocol_pbdesc.EnChatChannelType_EN_CCT_INVALID))
+	assert.Equal(t, chatChannelInvalid, getChatChannelKind(public_protocol_pbdesc.EnChatChannelType_EN_CCT_ALL_BOUND))
+	assert.Equal(t, chatChannelInvalid, getChatChannelKind(public_protocol_pbdesc.EnChatChannelType_EN_CCT_SUB_GUILD))
+}
+
+// TestFanOutChatMessage 测试世界和大区消息推送给本进程的连接
+// Scenario: 世界消息推给除发送者外所有已登入的连接，大区消息只推给同大区玩家，推送失败的连接不计数
+func TestFanOutChatMessage(t *testing.T) {
+	// Arrange
+	recorder := newMockChatSessionRecorder()
+	sender := createMockChatSession(1, &mockChatUser{zoneId: 1, userId: 1001})
+	sameZone := createMockChatSession(2, &mockChatUser{zoneId: 1, userId: 1002})
+	otherZone := createMockChatSession(3, &mockChatUser{zoneId: 2, userId: 2001})
+	unauthorized := createMockChatSession(4, nil)
+	broken := createMockChatSession(5, &mockChatUser{zoneId: 1, userId: 1005})
+	recorder.failed[broken.GetSessionId()] = true
+	sessions := foreachMockSessions(sender, sameZone, otherZone, unauthorized, broken)
+
+	worldMessage := &public_protocol_pbdesc.DChatMessage{
+		MessageId:   1,
+		ChannelType: public_protocol_pbdesc.EnChatChannelType_EN_CCT_ALL_DEFAULT,
+		FromUserId:  1001,
+		FromZoneId:  1,
+		Content:     "hello world",
+	}
+	zoneMessage := &public_protocol_pbdesc.DChatMessage{
+		MessageId:   2,
+		ChannelType: public_protocol_pbdesc.EnChatChannelType_EN_CCT_ZONE_DEFAULT,
+		FromUserId:  1001,
+		FromZoneId:  1,
+		Content:     "hello zone",
+	}
+
+	// Act
+	worldCount := fanOutChatMessage(test_utility.NewMockRpcContext(nil), sessions, worldMessage, recorder.send)
+	zoneCount := fanOutChatMessage(test_utility.NewMockRpcContext(nil), sessions, zoneMessage, recorder.send)
+
+	// Assert
+	assert.Equal(t, 2, worldCount)
+	assert.Equal(t, 1, zoneCount)
+	assert.Empty(t, recorder.received[sender.GetSessionId()])
+	assert.Equal(t, []*public_protocol_pbdesc.DChatMessage{worldMessage, zoneMessage}, recorder.received[sameZone.GetSessionId()])
+	assert.Equal(t, []*public_protocol_pbdesc.DChatMessage{worldMessage}, recorder.received[otherZone.GetSessionId()])
+	assert.Empty(t, recorder.received[unauthorized.GetSessionId()])
+}
+
+// TestDeliverChatPrivateLocal 测试私聊投递给本进程的目标玩家
+// Scenario: 目标有连接时推送成功；目标不在本进程、没有连接或推送失败时返回 false 由调用方转存离线消息
+func TestDeliverChatPrivateLocal(t *testing.T) {
+	// Arrange
+	recorder := newMockChatSessionRecorder()
+	online := &mockChatUser{zoneId: 1, userId: 1002}
+	createMockChatSession(2, online)
+	disconnected := &mockChatUser{zoneId: 1, userId: 1003}
+	broken := &mockChatUser{zoneId: 1, userId: 1004}
+	recorder.failed[createMockChatSession(4, broken).GetSessionId()] = true
+	msg := &public_protocol_pbdesc.DChatMessage{
+		MessageId:   3,
+		ChannelType: public_protocol_pbdesc.EnChatChannelType_EN_CCT_PERSONAL_DEFAULT,
+		FromUserId:  1001,
+		FromZoneId:  1,
+		ToUserId:    1002,
+		ToZoneId:    1,
+		Content:     "hi",
+	}
+
+	// Act & Assert
+	assert.True(t, deliverChatPrivateLocal(test_utility.NewMockRpcContext(nil), online, msg, recorder.send))
+	assert.Equal(t, []*public_protocol_pbdesc.DChatMessage{msg}, recorder.received[2])
+
+	assert.False(t, deliverChatPrivateLocal(test_utility.NewMockRpcContext(nil), nil, msg, recorder.send))
+	assert.False(t, deliverChatPrivateLocal(test_utility.NewMockRpcContext(nil), disconnected, msg, recorder.send))
+	assert.False(t, deliverChatPrivateLocal(test_utility.NewMockRpcContext(nil), broken, msg, recorder.send))
+}
+
+// mockChatContentFilter 把关键字替换成*，遇到 reject 时拒绝发送
+type mockChatContentFilter struct {
+	name    string
+	keyword string
+}
+
+func (f *mockChatContentFilter) Name() string { return f.name }
+
+func (f *mockChatContentFilter) Filter(_ cd.RpcContext, _ uc.UserImpl, _ public_protocol_pbdesc.EnChatChannelType,
+	content string,
+) (string, int32) {
+	if strings.Contains(content, "reject") {
+		return "", int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_TEXT_HAS_DIRTY_WORD)
+	}
+	return strings.ReplaceAll(content, f.keyword, strings.Repeat("*", len(f.keyword))), 0
+}
+
+// TestCheckChatContent 测试聊天内容长度检查和过滤钩子
+// Scenario: 空内容和超长内容被拒绝，长度按字符计算；注册的过滤器可以替换内容或拒绝发送
+func TestCheckChatContent(t *testing.T) {
+	// Arrange
+	RegisterChatContentFilter(&mockChatContentFilter{name: "test", keyword: "bad"})
+	t.Cleanup(func() {
+		UnregisterChatContentFilter("test")
+	})
+	ctx := test_utility.NewMockRpcContext(nil)
+	channelType := public_protocol_pbdesc.EnChatChannelType_EN_CCT_ALL_DEFAULT
+
+	// Act & Assert
+	_, code := checkChatContent(ctx, nil, channelType, "   ", 5)
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_CHAT_CONTENT_LENGTH_INVALID), code)
+
+	_, code = checkChatContent(ctx, nil, channelType, "123456", 5)
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_CHAT_CONTENT_LENGTH_INVALID), code)
+
+	content, code := checkChatContent(ctx, nil, channelType, " 你好世界 ", 4)
+	assert.Equal(t, int32(0), code)
+	assert.Equal(t, "你好世界", content)
+
+	content, code = checkChatContent(ctx, nil, channelType, "a bad word", 20)
+	assert.Equal(t, int32(0), code)
+	assert.Equal(t, "a *** word", content)
+
+	_, code = checkChatContent(ctx, nil, channelType, "reject me", 20)
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_TEXT_HAS_DIRTY_WORD), code)
+}
@@ -0,0 +1,369 @@
+// Copyright 2026 atframework
+// @brief 聊天频道转发，世界和大区消息广播给所有 lobbysvr，私聊按登入锁转发到目标玩家所在进程
+
+package lobbysvr_logic_chat
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/atframework/libatapp-go"
+
+	lu "github.com/atframework/atframe-utils-go/lang_utility"
+	pu "github.com/atframework/atframe-utils-go/proto_utility"
+	config "github.com/atframework/atsf4g-go/component/config"
+	db "github.com/atframework/atsf4g-go/component/db"
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	logical_time "github.com/atframework/atsf4g-go/component/logical_time"
+	private_protocol_config "github.com/atframework/atsf4g-go/component/protocol/private/config/protocol/config"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	uc "github.com/atframework/atsf4g-go/component/user_controller"
+	uuid "github.com/atframework/atsf4g-go/component/uuid"
+
+	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
+	logic_friend "github.com/atframework/atsf4g-go/service-lobbysvr/logic/friend"
+	service_protocol "github.com/atframework/atsf4g-go/service-lobbysvr/protocol/public/protocol/pbdesc"
+)
+
+// ChatManager 聊天模块
+// 通过存储后端的发布订阅转发，channel 为空时只在本进程内转发，私聊目标在其他进程时转存离线消息
+type ChatManager struct {
+	libatapp.AppModuleBase
+
+	rateLimiter            *chatRateLimiter
+	nextRateLimiterCleanup time.Time
+	sendToSession          chatSessionSender
+
+	pubsub           *db.PubSubChannel
+	broadcastChannel string
+	serverChannel    string
+}
+
+func init() {
+	var _ libatapp.AppModuleImpl = (*ChatManager)(nil)
+}
+
+// CreateChatManager 创建聊天模块，需要在 SessionManager 和 StorageBackendModule 之后注册
+func CreateChatManager(owner libatapp.AppImpl) *ChatManager {
+	return &ChatManager{
+		AppModuleBase: libatapp.CreateAppModuleBase(owner),
+		rateLimiter:   createChatRateLimiter(),
+		sendToSession: sendChatMessageSync,
+	}
+}
+
+func getChatConfig() *private_protocol_config.Readonly_LogicChatCfg {
+	return config.GetConfigManager().GetCurrentConfigGroup().GetSectionConfig().GetChat()
+}
+
+func (m *ChatManager) Name() string {
+	return "ChatManager"
+}
+
+func (m *ChatManager) Init(parent context.Context) error {
+	channel := getChatConfig().GetChannel()
+	if channel == "" {
+		return nil
+	}
+
+	m.broadcastChannel = getChatBroadcastChannel(m.GetApp(), channel)
+	m.serverChannel = db.GetPubSubServerChannelName(m.GetApp(), channel, uint64(config.GetConfigManager().GetLogicId()))
+	pubsub, err := db.SubscribePubSubChannel(m.GetApp(), m.Name(), []string{m.broadcastChannel, m.serverChannel}, m.receiveForwardMessage)
+	if err != nil {
+		return err
+	}
+	m.pubsub = pubsub
+	return nil
+}
+
+func (m *ChatManager) Cleanup() {
+	m.pubsub.Close()
+	m.pubsub = nil
+}
+
+func (m *ChatManager) Tick(parent context.Context) bool {
+	now := logical_time.GetSysNow()
+	if now.Before(m.nextRateLimiterCleanup) {
+		return false
+	}
+	m.nextRateLimiterCleanup = now.Add(time.Minute)
+	m.rateLimiter.removeIdle(now, getChatConfig().GetRateLimitWindow().AsDuration())
+	return false
+}
+
+func getChatBroadcastChannel(app libatapp.AppImpl, channel string) string {
+	return db.GetPubSubChannelName(app, channel, "broadcast")
+}
+
+// SendMessage 检查限频和内容后发送，私聊目标不在线时转存离线消息
+func (m *ChatManager) SendMessage(ctx cd.AwaitableContext, sender *data.User,
+	reqBody *service_protocol.CSChatSendReq,
+) (*public_protocol_pbdesc.DChatMessage, int32) {
+	kind := getChatChannelKind(reqBody.GetChannelType())
+	if kind == chatChannelInvalid {
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_CHAT_CHANNEL_INVALID)
+	}
+
+	toZoneId := reqBody.GetToZoneId()
+	if kind == chatChannelPrivate {
+		if toZoneId == 0 {
+			toZoneId = sender.GetZoneId()
+		}
+		if reqBody.GetToUserId() == 0 {
+			return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_CHAT_TARGET_NOT_FOUND)
+		}
+		if reqBody.GetToUserId() == sender.GetUserId() {
+			return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_CHAT_TARGET_IS_SELF)
+		}
+		friendManager := data.UserGetModuleManager[logic_friend.UserFriendManager](sender)
+		if friendManager != nil && friendManager.IsBlocked(reqBody.GetToUserId()) {
+			return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_CHAT_TARGET_IN_BLOCKLIST)
+		}
+	}
+
+	chatCfg := getChatConfig()
+	if session := sender.GetUserSession(); session != nil {
+		if !m.rateLimiter.allow(*session.GetKey(), ctx.GetSysNow(),
+			chatCfg.GetRateLimitWindow().AsDuration(), int(chatCfg.GetRateLimitCount())) {
+			return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_CHAT_RATE_LIMIT)
+		}
+	}
+
+	content, code := checkChatContent(ctx, sender, reqBody.GetChannelType(), reqBody.GetContent(), int(chatCfg.GetMaxContentLength()))
+	if code != 0 {
+		return nil, code
+	}
+
+	messageId, result := uuid.GenerateGlobalUniqueID(ctx,
+		private_protocol_pbdesc.EnGlobalUUIDMajorType_EN_GLOBAL_UUID_MAT_CHAT_MESSAGE,
+		private_protocol_pbdesc.EnGlobalUUIDMinorType_EN_GLOBAL_UUID_MIT_DEFAULT,
+		private_protocol_pbdesc.EnGlobalUUIDPatchType_EN_GLOBAL_UUID_PT_DEFAULT)
+	if result.IsError() {
+		result.LogError(ctx, "alloc chat message id failed")
+		return nil, result.GetResponseCode()
+	}
+
+	msg := &public_protocol_pbdesc.DChatMessage{
+		MessageId:    messageId,
+		ChannelType:  reqBody.GetChannelType(),
+		FromUserId:   sender.GetUserId(),
+		FromZoneId:   sender.GetZoneId(),
+		FromUserName: sender.GetAccountInfo().GetProfile().GetNickName(),
+		Content:      content,
+		SendTime:     ctx.GetNow().Unix(),
+	}
+
+	if kind == chatChannelPrivate {
+		msg.ToUserId = reqBody.GetToUserId()
+		msg.ToZoneId = toZoneId
+		if code = m.deliverPrivate(ctx, msg); code != 0 {
+			return nil, code
+		}
+		return msg, 0
+	}
+
+	count := fanOutChatMessage(ctx, m.foreachSession, msg, m.sendToSession)
+	m.publish(ctx, m.broadcastChannel, msg, nil)
+	ctx.LogDebug("chat message sent", "message_id", messageId, "channel_type", msg.GetChannelType(), "local_receiver_count", count)
+	return msg, 0
+}
+
+// FetchOfflineMessages 拉取并删除离线私聊，已拉黑的发送者的消息直接丢弃
+func (m *ChatManager) FetchOfflineMessages(ctx cd.AwaitableContext, user *data.User) ([]*public_protocol_pbdesc.DChatMessage, int32) {
+	indexMessages, result := db.DatabaseTableChatOfflineMessageLoadAllWithZoneIdUserId(ctx, user.GetZoneId(), user.GetUserId())
+	if result.IsError() {
+		if result.GetResponseCode() == int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND) {
+			return nil, 0
+		}
+		result.LogError(ctx, "load chat offline message failed", "zone_id", user.GetZoneId(), "user_id", user.GetUserId())
+		return nil, result.GetResponseCode()
+	}
+
+	slices.SortFunc(indexMessages, func(a, b pu.RedisListIndexMessage) int {
+		if a.ListIndex < b.ListIndex {
+			return -1
+		}
+		if a.ListIndex > b.ListIndex {
+			return 1
+		}
+		return 0
+	})
+
+	ret := make([]*public_protocol_pbdesc.DChatMessage, 0, len(indexMessages))
+	removeIndexes := make([]uint64, 0, len(indexMessages))
+	for _, indexMessage := range indexMessages {
+		removeIndexes = append(removeIndexes, indexMessage.ListIndex)
+
+		table, ok := indexMessage.Table.(*private_protocol_pbdesc.DatabaseTableChatOfflineMessage)
+		if !ok || table.GetMessage() == nil || isChatSenderBlocked(user, table.GetMessage().GetFromUserId()) {
+			continue
+		}
+		ret = append(ret, table.GetMessage())
+	}
+
+	if len(removeIndexes) > 0 {
+		result = db.DatabaseTableChatOfflineMessageDelIndexWithZoneIdUserId(ctx, removeIndexes, user.GetZoneId(), user.GetUserId())
+		if result.IsError() {
+			result.LogError(ctx, "remove chat offline message failed", "zone_id", user.GetZoneId(), "user_id", user.GetUserId())
+			return nil, result.GetResponseCode()
+		}
+	}
+	return ret, 0
+}
+
+func (m *ChatManager) foreachSession(fn func(*uc.Session) bool) {
+	sessionManager := libatapp.AtappGetModule[*uc.SessionManager](m.GetApp())
+	if sessionManager == nil {
+		return
+	}
+	sessionManager.ForeachSession(fn)
+}
+
+func (m *ChatManager) findLocalUser(ctx cd.RpcContext, zoneId uint32, userId uint64) uc.UserImpl {
+	userManager := libatapp.AtappGetModule[*uc.UserManager](m.GetApp())
+	if userManager == nil {
+		return nil
+	}
+	return userManager.Find(ctx, zoneId, userId)
+}
+
+// deliverPrivate 优先投递给本进程的目标玩家，其次按登入锁转发到目标玩家所在进程，都不在线时转存离线消息
+func (m *ChatManager) deliverPrivate(ctx cd.AwaitableContext, msg *public_protocol_pbdesc.DChatMessage) int32 {
+	receiver := m.findLocalUser(ctx, msg.GetToZoneId(), msg.GetToUserId())
+	if deliverChatPrivateLocal(ctx, receiver, msg, m.sendToSession) {
+		return 0
+	}
+
+	if lu.IsNil(receiver) {
+		loginLockTb, _, result := db.DatabaseTableLoginLockLoadWithUserId(ctx, msg.GetToUserId())
+		if result.IsError() {
+			if result.GetResponseCode() == int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND) {
+				return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_CHAT_TARGET_NOT_FOUND)
+			}
+			result.LogError(ctx, "load target login lock failed", "to_user_id", msg.GetToUserId())
+			return result.GetResponseCode()
+		}
+
+		selfServerId := uint64(config.GetConfigManager().GetLogicId())
+		if m.pubsub.IsSubscribed() && loginLockTb.GetRouterServerId() != 0 && loginLockTb.GetRouterServerId() != selfServerId &&
+			ctx.GetSysNow().Unix() < loginLockTb.GetLoginExpired() {
+			// 目标进程没有订阅时(已经停服)转存离线消息
+			m.publish(ctx, db.GetPubSubServerChannelName(m.GetApp(), getChatConfig().GetChannel(), loginLockTb.GetRouterServerId()), msg,
+				func() {
+					m.startSaveOfflineTask(msg)
+				})
+			return 0
+		}
+	}
+
+	return saveChatOfflineMessage(ctx, msg)
+}
+
+// saveChatOfflineMessage 写入离线私聊，超过上限时由 KL 表自动淘汰最早的消息
+func saveChatOfflineMessage(ctx cd.AwaitableContext, msg *public_protocol_pbdesc.DChatMessage) int32 {
+	result, _ := db.DatabaseTableChatOfflineMessageAddZoneIdUserId(ctx, &private_protocol_pbdesc.DatabaseTableChatOfflineMessage{
+		ZoneId:  msg.GetToZoneId(),
+		UserId:  msg.GetToUserId(),
+		Message: msg,
+	})
+	if result.IsError() {
+		result.LogError(ctx, "save chat offline message failed", "to_zone_id", msg.GetToZoneId(),
+			"to_user_id", msg.GetToUserId(), "message_id", msg.GetMessageId())
+		return result.GetResponseCode()
+	}
+	return 0
+}
+
+// publish 转发给其他进程，没有任何进程订阅时调用 onNoReceiver
+func (m *ChatManager) publish(ctx cd.RpcContext, channel string, msg *public_protocol_pbdesc.DChatMessage, onNoReceiver func()) {
+	if !m.pubsub.IsSubscribed() || channel == "" {
+		return
+	}
+
+	payload, err := proto.Marshal(&private_protocol_pbdesc.ChatForwardMessage{
+		SenderServerId: uint64(config.GetConfigManager().GetLogicId()),
+		Message:        msg,
+	})
+	if err != nil {
+		ctx.LogError("marshal chat forward message failed", "message_id", msg.GetMessageId(), "error", err)
+		return
+	}
+
+	err = m.pubsub.Publish(channel, payload, func(receivers int64, err error) {
+		if (err != nil || receivers == 0) && onNoReceiver != nil {
+			onNoReceiver()
+		}
+	})
+	if err != nil {
+		ctx.LogWarn("publish chat forward message failed", "message_id", msg.GetMessageId(), "error", err)
+	}
+}
+
+// receiveForwardMessage 在主线程调用
+func (m *ChatManager) receiveForwardMessage(channel string, payload []byte) {
+	forward := &private_protocol_pbdesc.ChatForwardMessage{}
+	if err := proto.Unmarshal(payload, forward); err != nil || forward.GetMessage() == nil {
+		m.GetApp().GetDefaultLogger().LogWarn("ChatManager bad forward message", "channel", channel, "error", err)
+		return
+	}
+
+	isPrivate := channel == m.serverChannel
+	if !isPrivate && forward.GetSenderServerId() == uint64(config.GetConfigManager().GetLogicId()) {
+		return
+	}
+	m.onForwardMessage(forward.GetMessage(), isPrivate)
+}
+
+func (m *ChatManager) onForwardMessage(msg *public_protocol_pbdesc.DChatMessage, isPrivate bool) {
+	ctx := libatapp.AtappGetModule[*cd.NoMessageDispatcher](m.GetApp()).CreateRpcContext()
+	if !isPrivate {
+		fanOutChatMessage(ctx, m.foreachSession, msg, m.sendToSession)
+		return
+	}
+
+	receiver := m.findLocalUser(ctx, msg.GetToZoneId(), msg.GetToUserId())
+	if deliverChatPrivateLocal(ctx, receiver, msg, m.sendToSession) {
+		return
+	}
+	// 转发过程中目标玩家已经下线
+	m.startSaveOfflineTask(msg)
+}
+
+func (m *ChatManager) startSaveOfflineTask(msg *public_protocol_pbdesc.DChatMessage) {
+	d := libatapp.AtappGetModule[*cd.NoMessageDispatcher](m.GetApp())
+	ctx := d.CreateRpcContext()
+	task, startData := cd.CreateNoMessageTaskAction(
+		d, ctx, nil,
+		func(rd cd.DispatcherImpl, actor *cd.ActorExecutor, timeout time.Duration) *taskActionChatSaveOfflineMessage {
+			return &taskActionChatSaveOfflineMessage{
+				TaskActionNoMessageBase: cd.CreateNoMessageTaskActionBase(rd, actor, timeout),
+				message:                 msg,
+			}
+		},
+	)
+
+	err := libatapp.AtappGetModule[*cd.TaskManager](ctx.GetApp()).StartTaskAction(ctx, task, &startData)
+	if err != nil {
+		ctx.LogError("ChatManager start save offline message task failed", "to_user_id", msg.GetToUserId(),
+			"message_id", msg.GetMessageId(), "error", err)
+	}
+}
+
+// taskActionChatSaveOfflineMessage 转发失败的私聊转存离线消息
+type taskActionChatSaveOfflineMessage struct {
+	cd.TaskActionNoMessageBase
+	message *public_protocol_pbdesc.DChatMessage
+}
+
+func (t *taskActionChatSaveOfflineMessage) Name() string {
+	return "TaskActionChatSaveOfflineMessage"
+}
+
+func (t *taskActionChatSaveOfflineMessage) Run(_ *cd.DispatcherStartData) error {
+	saveChatOfflineMessage(t.GetAwaitableContext(), t.message)
+	return nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2026 atframework
+// @brief 按连接限制聊天发言频率
+
+package lobbysvr_logic_chat
+
+import (
+	"sync"
+	"time"
+
+	uc "github.com/atframework/atsf4g-go/component/user_controller"
+)
+
+// chatRateLimitRing 滑动窗口，规则和 CS 协议限频的环形缓冲区一致
+type chatRateLimitRing struct {
+	timestamps []int64 // 固定长度，容量等于限频次数
+	index      int     // 指向最老记录的位置
+	count      int     // 当前有效记录数
+}
+
+// chatRateLimiter 按连接记录发言时间，断线重连后重新计算
+type chatRateLimiter struct {
+	lock  sync.Mutex
+	rings map[uc.SessionKey]*chatRateLimitRing
+}
+
+func createChatRateLimiter() *chatRateLimiter {
+	return &chatRateLimiter{
+		rings: make(map[uc.SessionKey]*chatRateLimitRing),
+	}
+}
+
+// allow 检查并记录一次发言，window 或 limitCount 不大于0时不限频
+func (l *chatRateLimiter) allow(key uc.SessionKey, now time.Time, window time.Duration, limitCount int) bool {
+	if window <= 0 || limitCount <= 0 {
+		return true
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	currentTime := now.UnixMilli()
+	ring := l.rings[key]
+	// 配置变化后重新计算
+	if ring == nil || len(ring.timestamps) != limitCount {
+		ring = &chatRateLimitRing{
+			timestamps: make([]int64, limitCount),
+		}
+		l.rings[key] = ring
+	}
+
+	if ring.count < limitCount {
+		ring.timestamps[(ring.index+ring.count)%limitCount] = currentTime
+		ring.count++
+		return true
+	}
+
+	oldestTime := ring.timestamps[ring.index]
+	if currentTime-oldestTime < 0 {
+		// 时间回退，重置
+		ring.timestamps[0] = currentTime
+		ring.index = 0
+		ring.count = 1
+		return true
+	}
+	if currentTime-oldestTime >= window.Milliseconds() {
+		ring.timestamps[ring.index] = currentTime
+		ring.index = (ring.index + 1) % limitCount
+		return true
+	}
+	return false
+}
+
+// removeIdle 移除整个窗口内都没有发言的连接，返回移除数量
+func (l *chatRateLimiter) removeIdle(now time.Time, window time.Duration) int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	currentTime := now.UnixMilli()
+	removed := 0
+	for key, ring := range l.rings {
+		if ring.count > 0 && window > 0 {
+			newestTime := ring.timestamps[(ring.index+ring.count-1)%len(ring.timestamps)]
+			if currentTime-newestTime < window.Milliseconds() {
+				continue
+			}
+		}
+		delete(l.rings, key)
+		removed++
+	}
+	return removed
+}
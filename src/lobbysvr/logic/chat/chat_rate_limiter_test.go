@@ -0,0 +1,55 @@
+package lobbysvr_logic_chat
+
+import (
+	"testing"
+	"time"
+
+	uc "github.com/atframework/atsf4g-go/component/user_controller"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestChatRateLimiterSlidingWindow 测试按连接的滑动窗口限频
+// Scenario: 窗口内最多发送2条，第3条被拒绝，最早一条移出窗口后可以继续发送，不同连接互不影响
+func TestChatRateLimiterSlidingWindow(t *testing.T) {
+	// Arrange
+	limiter := createChatRateLimiter()
+	sessionA := uc.CreateSessionKey(1, 1)
+	sessionB := uc.CreateSessionKey(1, 2)
+	window := 10 * time.Second
+	now := time.Unix(1700000000, 0)
+
+	// Act & Assert
+	assert.True(t, limiter.allow(sessionA, now, window, 2))
+	assert.True(t, limiter.allow(sessionA, now.Add(time.Second), window, 2))
+	assert.False(t, limiter.allow(sessionA, now.Add(2*time.Second), window, 2))
+	assert.True(t, limiter.allow(sessionB, now.Add(2*time.Second), window, 2))
+
+	assert.True(t, limiter.allow(sessionA, now.Add(window), window, 2))
+	assert.False(t, limiter.allow(sessionA, now.Add(window+500*time.Millisecond), window, 2))
+
+	// 未配置时不限频
+	assert.True(t, limiter.allow(sessionA, now.Add(window), 0, 2))
+	assert.True(t, limiter.allow(sessionA, now.Add(window), window, 0))
+}
+
+// TestChatRateLimiterRemoveIdle 测试清理空闲连接的限频记录
+// Scenario: 最近一次发言已经超出窗口的连接被移除，窗口内发过言的连接保留
+func TestChatRateLimiterRemoveIdle(t *testing.T) {
+	// Arrange
+	limiter := createChatRateLimiter()
+	idleSession := uc.CreateSessionKey(1, 1)
+	activeSession := uc.CreateSessionKey(1, 2)
+	window := 10 * time.Second
+	now := time.Unix(1700000000, 0)
+	limiter.allow(idleSession, now, window, 5)
+	limiter.allow(activeSession, now, window, 5)
+	limiter.allow(activeSession, now.Add(8*time.Second), window, 5)
+
+	// Act
+	removed := limiter.removeIdle(now.Add(window), window)
+
+	// Assert
+	assert.Equal(t, 1, removed)
+	assert.NotContains(t, limiter.rings, idleSession)
+	assert.Contains(t, limiter.rings, activeSession)
+}
@@ -0,0 +1,258 @@
+package lobbysvr_logic_condition_data
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	public_protocol_common "github.com/atframework/atsf4g-go/component/protocol/public/common/protocol/common"
+)
+
+// 规则表达式，用于GM/运营工具手工构造 DConditionRule
+// 多条规则使用 ';' 分隔，每条规则格式为 <规则名>:<参数>，多个参数使用 '#' 分隔
+//
+//	rule_ins_id:<rule_ins_id>
+//	login_channel:<channel>[#<channel>...]
+//	system_platform:<platform>[#<platform>...]
+//	user_level:<min>[#<max>]                 max<=0表示不限制
+//	has_item:<item_id>#<min_count>[#<max_count>]
+//	quest_status:<quest_id>#<status>
+//	module_unlock:<module_id>
+//
+// 例如: user_level:10#50;system_platform:1#2
+const (
+	RuleExpressionSeparator      = ";"
+	RuleExpressionNameSeparator  = ":"
+	RuleExpressionValueSeparator = "#"
+)
+
+type ruleExpressionParser func(values []string) (*public_protocol_common.DConditionRule, error)
+
+var ruleExpressionParsers = map[string]ruleExpressionParser{
+	"rule_ins_id":     parseRuleExpressionRuleInsId,
+	"login_channel":   parseRuleExpressionLoginChannel,
+	"system_platform": parseRuleExpressionSystemPlatform,
+	"user_level":      parseRuleExpressionUserLevel,
+	"has_item":        parseRuleExpressionHasItem,
+	"quest_status":    parseRuleExpressionQuestStatus,
+	"module_unlock":   parseRuleExpressionModuleUnlock,
+}
+
+// ParseRuleExpression 解析规则表达式，空表达式返回空列表
+func ParseRuleExpression(expr string) ([]*public_protocol_common.DConditionRule, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, nil
+	}
+
+	segments := strings.Split(expr, RuleExpressionSeparator)
+	ret := make([]*public_protocol_common.DConditionRule, 0, len(segments))
+	for _, segment := range segments {
+		segment = strings.TrimSpace(segment)
+		if segment == "" {
+			continue
+		}
+
+		parts := strings.SplitN(segment, RuleExpressionNameSeparator, 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid rule %q, expected <name>:<values>", segment)
+		}
+
+		name := strings.ToLower(strings.TrimSpace(parts[0]))
+		parser, ok := ruleExpressionParsers[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported rule name %q", name)
+		}
+
+		values := strings.Split(parts[1], RuleExpressionValueSeparator)
+		for i := range values {
+			values[i] = strings.TrimSpace(values[i])
+		}
+
+		rule, err := parser(values)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule %q: %w", segment, err)
+		}
+		ret = append(ret, rule)
+	}
+
+	return ret, nil
+}
+
+func parseRuleExpressionInt(values []string, index int, bitSize int) (int64, error) {
+	if index >= len(values) || values[index] == "" {
+		return 0, nil
+	}
+
+	return strconv.ParseInt(values[index], 10, bitSize)
+}
+
+func checkRuleExpressionValueCount(values []string, minCount int, maxCount int) error {
+	if len(values) < minCount || (maxCount > 0 && len(values) > maxCount) {
+		return fmt.Errorf("expected %d~%d values, got %d", minCount, maxCount, len(values))
+	}
+
+	return nil
+}
+
+func parseRuleExpressionMultipleUInt(values []string) (*public_protocol_common.DConditionRuleMultipleUInt, error) {
+	ret := &public_protocol_common.DConditionRuleMultipleUInt{}
+	for _, v := range values {
+		if v == "" {
+			continue
+		}
+
+		value, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		ret.Values = append(ret.Values, value)
+	}
+
+	if len(ret.GetValues()) == 0 {
+		return nil, fmt.Errorf("at least one value is required")
+	}
+
+	return ret, nil
+}
+
+func parseRuleExpressionRuleInsId(values []string) (*public_protocol_common.DConditionRule, error) {
+	if err := checkRuleExpressionValueCount(values, 1, 1); err != nil {
+		return nil, err
+	}
+
+	ruleInsId, err := parseRuleExpressionInt(values, 0, 32)
+	if err != nil {
+		return nil, err
+	}
+	if ruleInsId <= 0 {
+		return nil, fmt.Errorf("rule_ins_id must be positive")
+	}
+
+	return &public_protocol_common.DConditionRule{
+		RuleType: &public_protocol_common.DConditionRule_RuleInsId{RuleInsId: int32(ruleInsId)},
+	}, nil
+}
+
+func parseRuleExpressionLoginChannel(values []string) (*public_protocol_common.DConditionRule, error) {
+	channels, err := parseRuleExpressionMultipleUInt(values)
+	if err != nil {
+		return nil, err
+	}
+
+	return &public_protocol_common.DConditionRule{
+		RuleType: &public_protocol_common.DConditionRule_LoginChannel{LoginChannel: channels},
+	}, nil
+}
+
+func parseRuleExpressionSystemPlatform(values []string) (*public_protocol_common.DConditionRule, error) {
+	platforms, err := parseRuleExpressionMultipleUInt(values)
+	if err != nil {
+		return nil, err
+	}
+
+	return &public_protocol_common.DConditionRule{
+		RuleType: &public_protocol_common.DConditionRule_SystemPlatform{SystemPlatform: platforms},
+	}, nil
+}
+
+func parseRuleExpressionUserLevel(values []string) (*public_protocol_common.DConditionRule, error) {
+	if err := checkRuleExpressionValueCount(values, 1, 2); err != nil {
+		return nil, err
+	}
+
+	left, err := parseRuleExpressionInt(values, 0, 64)
+	if err != nil {
+		return nil, err
+	}
+	right, err := parseRuleExpressionInt(values, 1, 64)
+	if err != nil {
+		return nil, err
+	}
+	if right > 0 && right < left {
+		return nil, fmt.Errorf("max level %d is less than min level %d", right, left)
+	}
+
+	return &public_protocol_common.DConditionRule{
+		RuleType: &public_protocol_common.DConditionRule_UserLevel{UserLevel: &public_protocol_common.DConditionRuleRangeInt{
+			Left:  left,
+			Right: right,
+		}},
+	}, nil
+}
+
+func parseRuleExpressionHasItem(values []string) (*public_protocol_common.DConditionRule, error) {
+	if err := checkRuleExpressionValueCount(values, 2, 3); err != nil {
+		return nil, err
+	}
+
+	itemId, err := parseRuleExpressionInt(values, 0, 32)
+	if err != nil {
+		return nil, err
+	}
+	if itemId <= 0 {
+		return nil, fmt.Errorf("item_id must be positive")
+	}
+	minCount, err := parseRuleExpressionInt(values, 1, 64)
+	if err != nil {
+		return nil, err
+	}
+	maxCount, err := parseRuleExpressionInt(values, 2, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &public_protocol_common.DConditionRule{
+		RuleType: &public_protocol_common.DConditionRule_HasItem{HasItem: &public_protocol_common.DConditionRuleItemCount{
+			ItemId:   int32(itemId),
+			MinCount: minCount,
+			MaxCount: maxCount,
+		}},
+	}, nil
+}
+
+func parseRuleExpressionQuestStatus(values []string) (*public_protocol_common.DConditionRule, error) {
+	if err := checkRuleExpressionValueCount(values, 2, 2); err != nil {
+		return nil, err
+	}
+
+	questId, err := parseRuleExpressionInt(values, 0, 32)
+	if err != nil {
+		return nil, err
+	}
+	if questId <= 0 {
+		return nil, fmt.Errorf("quest_id must be positive")
+	}
+	status, err := parseRuleExpressionInt(values, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := public_protocol_common.EnQuestStatus_name[int32(status)]; !ok {
+		return nil, fmt.Errorf("unknown quest status %d", status)
+	}
+
+	return &public_protocol_common.DConditionRule{
+		RuleType: &public_protocol_common.DConditionRule_QuestStatus{QuestStatus: &public_protocol_common.DConditionRuleQuestStatus{
+			QuestId: int32(questId),
+			Status:  public_protocol_common.EnQuestStatus(status),
+		}},
+	}, nil
+}
+
+func parseRuleExpressionModuleUnlock(values []string) (*public_protocol_common.DConditionRule, error) {
+	if err := checkRuleExpressionValueCount(values, 1, 1); err != nil {
+		return nil, err
+	}
+
+	moduleId, err := parseRuleExpressionInt(values, 0, 32)
+	if err != nil {
+		return nil, err
+	}
+	if moduleId <= 0 {
+		return nil, fmt.Errorf("module_id must be positive")
+	}
+
+	return &public_protocol_common.DConditionRule{
+		RuleType: &public_protocol_common.DConditionRule_ModuleUnlock{ModuleUnlock: int32(moduleId)},
+	}, nil
+}
@@ -0,0 +1,105 @@
+package lobbysvr_logic_condition_data
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	public_protocol_common "github.com/atframework/atsf4g-go/component/protocol/public/common/protocol/common"
+)
+
+// TestParseRuleExpressionEmpty 测试空表达式
+// Scenario: 空字符串或只有分隔符时返回空规则列表且无错误
+func TestParseRuleExpressionEmpty(t *testing.T) {
+	// Arrange
+	inputs := []string{"", "   ", ";;"}
+
+	for _, input := range inputs {
+		// Act
+		rules, err := ParseRuleExpression(input)
+
+		// Assert
+		assert.NoError(t, err, "input %q should not fail", input)
+		assert.Empty(t, rules, "input %q should produce no rules", input)
+	}
+}
+
+// TestParseRuleExpressionMultipleRules 测试多条规则
+// Scenario: 等级区间+系统平台+任务状态组合，按顺序解析为对应的 oneof 类型
+func TestParseRuleExpressionMultipleRules(t *testing.T) {
+	// Arrange
+	expr := "user_level:10#50; system_platform:1#2 ;quest_status:1001#3"
+
+	// Act
+	rules, err := ParseRuleExpression(expr)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, rules, 3)
+
+	assert.Equal(t, int64(10), rules[0].GetUserLevel().GetLeft())
+	assert.Equal(t, int64(50), rules[0].GetUserLevel().GetRight())
+
+	assert.Equal(t, []uint64{1, 2}, rules[1].GetSystemPlatform().GetValues())
+
+	assert.Equal(t, int32(1001), rules[2].GetQuestStatus().GetQuestId())
+	assert.Equal(t, public_protocol_common.EnQuestStatus_EN_QUEST_STATUS_COMPLETE, rules[2].GetQuestStatus().GetStatus())
+}
+
+// TestParseRuleExpressionUserLevelOpenRange 测试只填最小等级
+// Scenario: 省略最大等级时 right 为 0，表示不限制上限
+func TestParseRuleExpressionUserLevelOpenRange(t *testing.T) {
+	// Act
+	rules, err := ParseRuleExpression("user_level:20")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, rules, 1)
+	assert.Equal(t, int64(20), rules[0].GetUserLevel().GetLeft())
+	assert.Equal(t, int64(0), rules[0].GetUserLevel().GetRight())
+}
+
+// TestParseRuleExpressionOtherRules 测试其余规则类型
+// Scenario: rule_ins_id/login_channel/has_item/module_unlock 均能正确解析
+func TestParseRuleExpressionOtherRules(t *testing.T) {
+	// Act
+	rules, err := ParseRuleExpression("rule_ins_id:7;LOGIN_CHANNEL:3;has_item:1001#1#99;module_unlock:5")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, rules, 4)
+	assert.Equal(t, int32(7), rules[0].GetRuleInsId())
+	assert.Equal(t, []uint64{3}, rules[1].GetLoginChannel().GetValues())
+	assert.Equal(t, int32(1001), rules[2].GetHasItem().GetItemId())
+	assert.Equal(t, int64(1), rules[2].GetHasItem().GetMinCount())
+	assert.Equal(t, int64(99), rules[2].GetHasItem().GetMaxCount())
+	assert.Equal(t, int32(5), rules[3].GetModuleUnlock())
+}
+
+// TestParseRuleExpressionInvalid 测试非法表达式
+// Scenario: 未知规则名、缺少参数、非数字、区间颠倒、未知任务状态都应返回错误
+func TestParseRuleExpressionInvalid(t *testing.T) {
+	// Arrange
+	inputs := []string{
+		"unknown_rule:1",
+		"user_level",
+		"user_level:abc",
+		"user_level:50#10",
+		"user_level:1#2#3",
+		"login_channel:",
+		"has_item:1001",
+		"has_item:0#1",
+		"quest_status:1001#99",
+		"module_unlock:0",
+		"rule_ins_id:-1",
+	}
+
+	for _, input := range inputs {
+		// Act
+		rules, err := ParseRuleExpression(input)
+
+		// Assert
+		assert.Error(t, err, "input %q should fail", input)
+		assert.Nil(t, rules, "input %q should not produce rules", input)
+	}
+}
@@ -120,6 +120,11 @@ func CreateEmptyRuleCheckerRuntime() *RuleCheckerRuntime {
 	return nil
 }
 
+// ParseRuleExpression 解析规则表达式，格式见 logic_condition_data.ParseRuleExpression
+func ParseRuleExpression(expr string) ([]*public_protocol_common.DConditionRule, error) {
+	return logic_condition_data.ParseRuleExpression(expr)
+}
+
 type conditionRuleCheckHandle struct {
 	StaticChecker  CheckConditionFunc
 	DynamicChecker CheckConditionFunc
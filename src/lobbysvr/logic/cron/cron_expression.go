@@ -0,0 +1,155 @@
+package lobbysvr_logic_cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSearchYears 查找下一次执行时间的最大范围，避免 2 月 30 日这类永远不会匹配的表达式死循环
+const cronSearchYears = 5
+
+// CronExpression 标准 5 段 cron 表达式: 分 时 日 月 周
+// 每段支持 *、数字、a-b、a,b 和 /n 步长，周的 0 和 7 都表示周日
+// 日和周都不是 * 时满足其中一个即可，和常见的 cron 实现一致
+type CronExpression struct {
+	minute     uint64
+	hour       uint64
+	dayOfMonth uint64
+	month      uint64
+	dayOfWeek  uint64
+
+	dayOfMonthAny bool
+	dayOfWeekAny  bool
+}
+
+var cronExpressionAlias = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 1",
+	"@monthly": "0 0 1 * *",
+	"@yearly":  "0 0 1 1 *",
+}
+
+// ParseCronExpression 解析 cron 表达式，也支持 @hourly、@daily、@weekly(周一)、@monthly 和 @yearly
+func ParseCronExpression(expression string) (*CronExpression, error) {
+	expression = strings.TrimSpace(expression)
+	if alias, ok := cronExpressionAlias[expression]; ok {
+		expression = alias
+	}
+
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields", expression)
+	}
+
+	ret := &CronExpression{}
+	var err error
+	if ret.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("cron expression %q minute invalid: %w", expression, err)
+	}
+	if ret.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("cron expression %q hour invalid: %w", expression, err)
+	}
+	if ret.dayOfMonth, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("cron expression %q day of month invalid: %w", expression, err)
+	}
+	if ret.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("cron expression %q month invalid: %w", expression, err)
+	}
+	if ret.dayOfWeek, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("cron expression %q day of week invalid: %w", expression, err)
+	}
+	if ret.dayOfWeek&(1<<7) != 0 {
+		ret.dayOfWeek |= 1
+	}
+
+	ret.dayOfMonthAny = fields[2] == "*"
+	ret.dayOfWeekAny = fields[4] == "*"
+	return ret, nil
+}
+
+func parseCronField(field string, minValue int, maxValue int) (uint64, error) {
+	var ret uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			v, err := strconv.Atoi(stepPart)
+			if err != nil || v <= 0 {
+				return 0, fmt.Errorf("step %q invalid", stepPart)
+			}
+			step = v
+		}
+
+		begin, end := minValue, maxValue
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			beginPart, endPart, _ := strings.Cut(rangePart, "-")
+			var err error
+			if begin, err = strconv.Atoi(beginPart); err != nil {
+				return 0, fmt.Errorf("range %q invalid", rangePart)
+			}
+			if end, err = strconv.Atoi(endPart); err != nil {
+				return 0, fmt.Errorf("range %q invalid", rangePart)
+			}
+		default:
+			v, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, fmt.Errorf("value %q invalid", rangePart)
+			}
+			// a/n 表示从 a 开始到最大值
+			begin = v
+			if !hasStep {
+				end = v
+			}
+		}
+
+		if begin < minValue || end > maxValue || begin > end {
+			return 0, fmt.Errorf("%q out of range [%d, %d]", part, minValue, maxValue)
+		}
+		for v := begin; v <= end; v += step {
+			ret |= 1 << uint(v)
+		}
+	}
+	return ret, nil
+}
+
+func (e *CronExpression) matchDay(t time.Time) bool {
+	matchDayOfMonth := e.dayOfMonth&(1<<uint(t.Day())) != 0
+	matchDayOfWeek := e.dayOfWeek&(1<<uint(t.Weekday())) != 0
+	if e.dayOfMonthAny || e.dayOfWeekAny {
+		return matchDayOfMonth && matchDayOfWeek
+	}
+	return matchDayOfMonth || matchDayOfWeek
+}
+
+// Next 严格晚于 after 的下一次执行时间，按 after 的时区计算，找不到时返回零值
+func (e *CronExpression) Next(after time.Time) time.Time {
+	loc := after.Location()
+	t := time.Date(after.Year(), after.Month(), after.Day(), after.Hour(), after.Minute()+1, 0, 0, loc)
+	limit := t.AddDate(cronSearchYears, 0, 0)
+
+	for t.Before(limit) {
+		if e.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !e.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if e.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if e.minute&(1<<uint(t.Minute())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, loc)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
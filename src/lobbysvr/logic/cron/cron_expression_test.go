@@ -0,0 +1,74 @@
+package lobbysvr_logic_cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCronExpressionNext 测试 cron 表达式计算下一次执行时间
+// Scenario: 支持步长、范围、列表和别名，日和周都限制时满足其一即可，非法表达式解析失败
+func TestCronExpressionNext(t *testing.T) {
+	// Arrange
+	everyQuarter, err1 := ParseCronExpression("*/15 * * * *")
+	weekdayMorning, err2 := ParseCronExpression("30 9 * * 1-5")
+	firstOrSunday, err3 := ParseCronExpression("0 0 1 * 0")
+	daily, err4 := ParseCronExpression("@daily")
+	after := time.Date(2026, time.January, 9, 9, 31, 20, 0, time.UTC) // 周五
+
+	// Act & Assert
+	assert.NoError(t, err1)
+	assert.NoError(t, err2)
+	assert.NoError(t, err3)
+	assert.NoError(t, err4)
+
+	assert.Equal(t, time.Date(2026, time.January, 9, 9, 45, 0, 0, time.UTC), everyQuarter.Next(after))
+	assert.Equal(t, time.Date(2026, time.January, 12, 9, 30, 0, 0, time.UTC), weekdayMorning.Next(after))
+	assert.Equal(t, time.Date(2026, time.January, 11, 0, 0, 0, 0, time.UTC), firstOrSunday.Next(after))
+	assert.Equal(t, time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC),
+		firstOrSunday.Next(time.Date(2026, time.January, 25, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, time.Date(2026, time.January, 10, 0, 0, 0, 0, time.UTC), daily.Next(after))
+
+	neverMatch, err := ParseCronExpression("0 0 30 2 *")
+	assert.NoError(t, err)
+	assert.True(t, neverMatch.Next(after).IsZero())
+
+	for _, invalid := range []string{"* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		_, err = ParseCronExpression(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+// TestCronCatchUpPolicy 测试错过执行时间的补执行策略
+// Scenario: 停机 3 小时后，跳过策略只在宽限时间内执行最后一次，补一次策略执行最后一次，补全部策略按上限分批执行
+func TestCronCatchUpPolicy(t *testing.T) {
+	// Arrange
+	hourly, err := ParseCronExpression("@hourly")
+	assert.NoError(t, err)
+	lastScheduled := time.Date(2026, time.January, 9, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2026, time.January, 9, 3, 0, 30, 0, time.UTC)
+	lastHour := time.Date(2026, time.January, 9, 3, 0, 0, 0, time.UTC)
+
+	// Act
+	skipDue, skipProcessed := calculateCronDueTimes(hourly, CronCatchUpSkip, lastScheduled, now, time.Minute, 100)
+	lateDue, lateProcessed := calculateCronDueTimes(hourly, CronCatchUpSkip, lastScheduled, now.Add(time.Hour/2), time.Minute, 100)
+	onceDue, _ := calculateCronDueTimes(hourly, CronCatchUpOnce, lastScheduled, now.Add(time.Hour/2), time.Minute, 100)
+	allDue, allProcessed := calculateCronDueTimes(hourly, CronCatchUpAll, lastScheduled, now, time.Minute, 2)
+	noneDue, noneProcessed := calculateCronDueTimes(hourly, CronCatchUpAll, lastHour, now, time.Minute, 2)
+
+	// Assert
+	assert.Equal(t, []time.Time{lastHour}, skipDue)
+	assert.Equal(t, lastHour, skipProcessed)
+
+	assert.Empty(t, lateDue)
+	assert.Equal(t, lastHour, lateProcessed)
+
+	assert.Equal(t, []time.Time{lastHour}, onceDue)
+
+	assert.Equal(t, []time.Time{lastScheduled.Add(time.Hour), lastScheduled.Add(2 * time.Hour)}, allDue)
+	assert.Equal(t, lastScheduled.Add(2*time.Hour), allProcessed)
+
+	assert.Empty(t, noneDue)
+	assert.Equal(t, lastHour, noneProcessed)
+}
@@ -0,0 +1,86 @@
+package lobbysvr_logic_cron
+
+import (
+	"fmt"
+	"time"
+
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+)
+
+// CronCatchUpPolicy 进程停机或者 GM 调整服务器时间后，错过的执行时间的处理方式
+type CronCatchUpPolicy int32
+
+const (
+	// CronCatchUpSkip 只执行 misfire_grace 以内的最后一次，更早的直接跳过
+	CronCatchUpSkip CronCatchUpPolicy = iota
+	// CronCatchUpOnce 错过多次时只补执行最后一次，例如过期清理
+	CronCatchUpOnce
+	// CronCatchUpAll 按顺序补执行每一次，例如每日排行榜结算
+	CronCatchUpAll
+)
+
+// CronJobHandler scheduledTime 为计划执行的逻辑时间，补执行时早于当前时间
+// 返回错误时不推进执行记录，下一次检查时重试同一个计划时间
+type CronJobHandler func(ctx cd.AwaitableContext, scheduledTime time.Time) cd.RpcResult
+
+type cronJob struct {
+	name       string
+	expression *CronExpression
+	catchUp    CronCatchUpPolicy
+	handler    CronJobHandler
+}
+
+var cronJobs = map[string]*cronJob{}
+
+// RegisterCronJob 注册全服定时任务，只能在 init 中调用
+// 多个 lobbysvr 之间通过数据库租约保证同一时间只有一个进程执行，每个计划时间执行前先保存到执行记录中作为去重标记:
+// 执行中崩溃或者单次执行超过租约时间被其他进程接管时，这个计划时间不会再次执行，只记录错误日志
+func RegisterCronJob(name string, expression string, catchUp CronCatchUpPolicy, handler CronJobHandler) {
+	if name == "" || handler == nil {
+		panic(fmt.Sprintf("cron job %q has no name or handler", name))
+	}
+
+	if _, ok := cronJobs[name]; ok {
+		panic(fmt.Sprintf("cron job %q already registered", name))
+	}
+
+	parsed, err := ParseCronExpression(expression)
+	if err != nil {
+		panic(err)
+	}
+
+	cronJobs[name] = &cronJob{
+		name:       name,
+		expression: parsed,
+		catchUp:    catchUp,
+		handler:    handler,
+	}
+}
+
+// calculateCronDueTimes 计算 (lastScheduled, now] 之间需要执行的计划时间
+// 返回的 processed 为处理后的最后一个计划时间，跳过的时间也算已处理
+func calculateCronDueTimes(expression *CronExpression, catchUp CronCatchUpPolicy, lastScheduled time.Time, now time.Time,
+	misfireGrace time.Duration, maxCatchUpCount int,
+) (due []time.Time, processed time.Time) {
+	processed = lastScheduled
+	for t := expression.Next(lastScheduled); !t.IsZero() && !t.After(now); t = expression.Next(t) {
+		processed = t
+		if catchUp != CronCatchUpAll {
+			continue
+		}
+
+		due = append(due, t)
+		if len(due) >= maxCatchUpCount {
+			return due, processed
+		}
+	}
+
+	if catchUp == CronCatchUpAll || processed.Equal(lastScheduled) {
+		return due, processed
+	}
+
+	if catchUp == CronCatchUpSkip && now.Sub(processed) > misfireGrace {
+		return nil, processed
+	}
+	return []time.Time{processed}, processed
+}
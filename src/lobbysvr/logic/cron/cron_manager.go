@@ -0,0 +1,283 @@
+// Copyright 2026 atframework
+// @brief 全服定时任务，按逻辑时间计算执行时间，多个 lobbysvr 之间通过数据库租约抢占执行权
+
+package lobbysvr_logic_cron
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/atframework/libatapp-go"
+
+	lu "github.com/atframework/atframe-utils-go/lang_utility"
+	config "github.com/atframework/atsf4g-go/component/config"
+	db "github.com/atframework/atsf4g-go/component/db"
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	private_protocol_config "github.com/atframework/atsf4g-go/component/protocol/private/config/protocol/config"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+)
+
+type cronJobState struct {
+	job           *cronJob
+	task          lu.AtomicInterface[cd.TaskActionImpl]
+	nextCheckTime atomic.Int64 // 逻辑时间，由执行任务更新
+}
+
+// CronManager 定时任务模块
+type CronManager struct {
+	libatapp.AppModuleBase
+
+	jobs         []*cronJobState
+	lastTickTime time.Time
+}
+
+func init() {
+	var _ libatapp.AppModuleImpl = (*CronManager)(nil)
+}
+
+// CreateCronManager 创建定时任务模块，需要在 StorageBackendModule 之后注册
+func CreateCronManager(owner libatapp.AppImpl) *CronManager {
+	return &CronManager{
+		AppModuleBase: libatapp.CreateAppModuleBase(owner),
+	}
+}
+
+func getCronConfig() *private_protocol_config.Readonly_LogicCronCfg {
+	return config.GetConfigManager().GetCurrentConfigGroup().GetSectionConfig().GetCron()
+}
+
+func (m *CronManager) Name() string {
+	return "CronManager"
+}
+
+// Init 启动后的第一次 Tick 会检查所有任务，补执行停机期间错过的时间
+func (m *CronManager) Init(parent context.Context) error {
+	m.jobs = make([]*cronJobState, 0, len(cronJobs))
+	for _, job := range cronJobs {
+		m.jobs = append(m.jobs, &cronJobState{job: job})
+	}
+	sort.Slice(m.jobs, func(i, j int) bool {
+		return m.jobs[i].job.name < m.jobs[j].job.name
+	})
+	return nil
+}
+
+func (m *CronManager) Tick(parent context.Context) bool {
+	if len(m.jobs) == 0 {
+		return false
+	}
+
+	d := libatapp.AtappGetModule[*cd.NoMessageDispatcher](m.GetApp())
+	now := d.GetNow()
+
+	// GM 把服务器时间往回调后重新检查，否则要等到原来的下一次执行时间
+	if now.Before(m.lastTickTime) {
+		for _, state := range m.jobs {
+			state.nextCheckTime.Store(0)
+		}
+	}
+	m.lastTickTime = now
+
+	// 大部分 Tick 没有到期的任务，有任务需要执行时才创建上下文
+	var ctx cd.RpcContext
+	for _, state := range m.jobs {
+		if now.Unix() < state.nextCheckTime.Load() || state.isRunning() {
+			continue
+		}
+		if ctx == nil {
+			ctx = d.CreateRpcContext()
+		}
+		m.startJobTask(ctx, d, state)
+	}
+	return false
+}
+
+func (s *cronJobState) isRunning() bool {
+	task := s.task.Load()
+	if lu.IsNil(task) {
+		return false
+	}
+	if task.IsExiting() {
+		s.task.Store(nil)
+		return false
+	}
+	return true
+}
+
+// scheduleNextCheck 表达式永远不会再匹配时不再检查，直到 GM 把时间往回调
+func (s *cronJobState) scheduleNextCheck(now time.Time) {
+	next := s.job.expression.Next(now)
+	if next.IsZero() {
+		s.nextCheckTime.Store(math.MaxInt64)
+		return
+	}
+	s.nextCheckTime.Store(next.Unix())
+}
+
+func (m *CronManager) startJobTask(ctx cd.RpcContext, d *cd.NoMessageDispatcher, state *cronJobState) {
+	// 任务结束前不会再次检查，任务没有正常结束时按重试间隔检查
+	state.nextCheckTime.Store(ctx.GetNow().Add(getCronConfig().GetRetryInterval().AsDuration()).Unix())
+
+	task, startData := cd.CreateNoMessageTaskAction(
+		d, ctx, nil,
+		func(rd cd.DispatcherImpl, actor *cd.ActorExecutor, timeout time.Duration) *taskActionCronJob {
+			return &taskActionCronJob{
+				TaskActionNoMessageBase: cd.CreateNoMessageTaskActionBase(rd, actor, timeout),
+				state:                   state,
+			}
+		},
+	)
+
+	err := libatapp.AtappGetModule[*cd.TaskManager](ctx.GetApp()).StartTaskAction(ctx, task, &startData)
+	if err != nil {
+		m.GetApp().GetDefaultLogger().LogError("CronManager StartTaskAction failed", "job_name", state.job.name, "error", err)
+		return
+	}
+	state.task.Store(task)
+}
+
+// taskActionCronJob 抢占租约后执行到期的计划时间，每执行一次保存一次执行记录
+type taskActionCronJob struct {
+	cd.TaskActionNoMessageBase
+	state *cronJobState
+}
+
+func (t *taskActionCronJob) Name() string {
+	return "TaskActionCronJob"
+}
+
+func isCronJobLeaseAlive(ctx cd.RpcContext, tb *private_protocol_pbdesc.DatabaseTableCronJob) bool {
+	return tb.GetLeaseServerId() != 0 && ctx.GetSysNow().Unix() < tb.GetLeaseExpired()
+}
+
+func (t *taskActionCronJob) Run(_ *cd.DispatcherStartData) error {
+	runCronJob(t.GetAwaitableContext(), t.state)
+	return nil
+}
+
+// runCronJob 读取执行记录，其他进程持有未过期的租约时跳过，否则抢占租约后执行到期的计划时间
+func runCronJob(ctx cd.AwaitableContext, state *cronJobState) {
+	job := state.job
+	cronCfg := getCronConfig()
+	now := ctx.GetNow()
+
+	tb, casVersion, result := db.DatabaseTableCronJobLoadWithJobName(ctx, job.name)
+	if result.IsError() {
+		if result.GetResponseCode() != int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND) {
+			result.LogError(ctx, "load cron job record failed", "job_name", job.name)
+			return
+		}
+
+		// 第一次部署的任务从现在开始计算，不补执行历史时间
+		tb = &private_protocol_pbdesc.DatabaseTableCronJob{
+			JobName:           job.name,
+			LastScheduledTime: now.Unix(),
+		}
+		casVersion = 0
+		result = db.DatabaseTableCronJobReplaceJobName(ctx, tb, &casVersion, false)
+		if result.IsError() {
+			result.LogWarn(ctx, "create cron job record failed, retry later", "job_name", job.name)
+			return
+		}
+	}
+
+	selfServerId := uint64(config.GetConfigManager().GetLogicId())
+	if tb.GetLeaseServerId() != selfServerId && isCronJobLeaseAlive(ctx, tb) {
+		ctx.LogDebug("cron job is running on other server", "job_name", job.name, "lease_server_id", tb.GetLeaseServerId())
+		return
+	}
+
+	// 上一个进程在执行中断，可能已经执行了部分逻辑，跳过这个计划时间
+	interrupted := tb.GetRunningScheduledTime() > tb.GetLastScheduledTime()
+	if interrupted {
+		ctx.LogError("cron job interrupted while running, skip the scheduled time", "job_name", job.name,
+			"scheduled_time", tb.GetRunningScheduledTime(), "last_run_server_id", tb.GetLastRunServerId())
+		tb.LastScheduledTime = tb.GetRunningScheduledTime()
+	}
+	tb.RunningScheduledTime = 0
+
+	lastScheduled := time.Unix(tb.GetLastScheduledTime(), 0).In(now.Location())
+	maxCatchUpCount := int(cronCfg.GetMaxCatchUpCount())
+	due, processed := calculateCronDueTimes(job.expression, job.catchUp, lastScheduled, now,
+		cronCfg.GetMisfireGrace().AsDuration(), maxCatchUpCount)
+	if processed.Equal(lastScheduled) && !interrupted {
+		state.scheduleNextCheck(now)
+		return
+	}
+
+	// 抢占租约
+	leaseDuration := cronCfg.GetLease().AsDuration()
+	tb.LeaseServerId = selfServerId
+	tb.LeaseExpired = ctx.GetSysNow().Add(leaseDuration).Unix()
+	result = db.DatabaseTableCronJobReplaceJobName(ctx, tb, &casVersion, false)
+	if result.IsError() {
+		if result.GetResponseCode() == int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_CAS_CHECK_FAILED) {
+			ctx.LogInfo("cron job lease taken by other server", "job_name", job.name)
+		} else {
+			result.LogError(ctx, "take cron job lease failed", "job_name", job.name)
+		}
+		return
+	}
+
+	succeed := true
+	for _, scheduledTime := range due {
+		// 先保存正在执行的计划时间，租约被其他进程接管后这里的 CAS 会失败，不会重复执行
+		tb.RunningScheduledTime = scheduledTime.Unix()
+		tb.LastRunServerId = selfServerId
+		tb.LeaseExpired = ctx.GetSysNow().Add(leaseDuration).Unix()
+		result = db.DatabaseTableCronJobReplaceJobName(ctx, tb, &casVersion, false)
+		if result.IsError() {
+			result.LogError(ctx, "save cron job running scheduled time failed, lease may be lost", "job_name", job.name,
+				"scheduled_time", scheduledTime.Unix())
+			return
+		}
+
+		runResult := job.handler(ctx, scheduledTime)
+		tb.RunningScheduledTime = 0
+		tb.LastRunTime = ctx.GetSysNow().Unix()
+		tb.LastResult = runResult.GetResponseCode()
+		tb.RunCount++
+		if runResult.IsError() {
+			runResult.LogError(ctx, "run cron job failed", "job_name", job.name, "scheduled_time", scheduledTime.Unix())
+			succeed = false
+			break
+		}
+
+		ctx.LogInfo("run cron job success", "job_name", job.name, "scheduled_time", scheduledTime.Unix())
+		tb.LastScheduledTime = scheduledTime.Unix()
+		tb.LeaseExpired = ctx.GetSysNow().Add(leaseDuration).Unix()
+		result = db.DatabaseTableCronJobReplaceJobName(ctx, tb, &casVersion, false)
+		if result.IsError() {
+			// 租约过期后被其他进程接管，由接管的进程继续执行
+			result.LogError(ctx, "save cron job record failed, lease may be lost", "job_name", job.name)
+			return
+		}
+	}
+
+	// 跳过的时间也算已处理
+	if succeed {
+		tb.LastScheduledTime = processed.Unix()
+	}
+	tb.LeaseServerId = 0
+	tb.LeaseExpired = 0
+	result = db.DatabaseTableCronJobReplaceJobName(ctx, tb, &casVersion, false)
+	if result.IsError() {
+		result.LogWarn(ctx, "release cron job lease failed", "job_name", job.name)
+		return
+	}
+
+	if !succeed {
+		return
+	}
+
+	// 补执行没有完成时马上继续
+	if job.catchUp == CronCatchUpAll && len(due) >= maxCatchUpCount {
+		state.nextCheckTime.Store(0)
+	} else {
+		state.scheduleNextCheck(ctx.GetNow())
+	}
+}
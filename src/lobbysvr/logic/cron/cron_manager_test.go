@@ -0,0 +1,112 @@
+package lobbysvr_logic_cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	db "github.com/atframework/atsf4g-go/component/db"
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	test_utility "github.com/atframework/atsf4g-go/component/test_utility"
+)
+
+const testCronJobName = "test_cron_job"
+
+// TestCronJobLeaseContention 测试多个进程抢占同一个定时任务的租约
+// Scenario: 第一次执行只创建记录，其他进程持有未过期的租约时跳过，租约过期后接管并补执行一次，执行后释放租约
+func TestCronJobLeaseContention(t *testing.T) {
+	// Arrange
+	app, _ := test_utility.CreateMemoryStorageApp()
+	expression, err := ParseCronExpression("* * * * *")
+	assert.NoError(t, err)
+
+	runCount := 0
+	state := &cronJobState{job: &cronJob{
+		name:       testCronJobName,
+		expression: expression,
+		catchUp:    CronCatchUpOnce,
+		handler: func(ctx cd.AwaitableContext, scheduledTime time.Time) cd.RpcResult {
+			runCount++
+			return cd.CreateRpcResultOk()
+		},
+	}}
+	start := time.Date(2026, time.January, 9, 9, 30, 10, 0, time.UTC)
+
+	// Act & Assert: 第一次执行只创建记录，不补执行历史时间
+	runCronJob(test_utility.NewMockRpcContextWithNow(app, start), state)
+	assert.Equal(t, 0, runCount)
+
+	// Act & Assert: 其他进程持有未过期的租约时跳过
+	ctx := test_utility.NewMockRpcContextWithNow(app, start.Add(2*time.Minute))
+	tb, casVersion, result := db.DatabaseTableCronJobLoadWithJobName(ctx, testCronJobName)
+	assert.False(t, result.IsError())
+	tb.LeaseServerId = 999
+	tb.LeaseExpired = ctx.GetSysNow().Add(time.Minute).Unix()
+	result = db.DatabaseTableCronJobReplaceJobName(ctx, tb, &casVersion, false)
+	assert.False(t, result.IsError())
+
+	runCronJob(ctx, state)
+	assert.Equal(t, 0, runCount)
+	tb, _, result = db.DatabaseTableCronJobLoadWithJobName(ctx, testCronJobName)
+	assert.False(t, result.IsError())
+	assert.Equal(t, uint64(999), tb.GetLeaseServerId())
+	assert.Equal(t, start.Unix(), tb.GetLastScheduledTime())
+
+	// Act & Assert: 租约过期后接管，错过的两次只补执行一次
+	ctx = test_utility.NewMockRpcContextWithNow(app, start.Add(3*time.Minute))
+	runCronJob(ctx, state)
+	assert.Equal(t, 1, runCount)
+	tb, _, result = db.DatabaseTableCronJobLoadWithJobName(ctx, testCronJobName)
+	assert.False(t, result.IsError())
+	assert.Equal(t, uint64(0), tb.GetLeaseServerId())
+	assert.Equal(t, int64(0), tb.GetLeaseExpired())
+	assert.Equal(t, time.Date(2026, time.January, 9, 9, 33, 0, 0, time.UTC).Unix(), tb.GetLastScheduledTime())
+	assert.Equal(t, int64(1), tb.GetRunCount())
+
+	// Act & Assert: 同一时间再次检查不会重复执行
+	runCronJob(ctx, state)
+	assert.Equal(t, 1, runCount)
+}
+
+// TestCronJobSkipInterruptedScheduledTime 测试执行中断的计划时间不会重复执行
+// Scenario: 执行记录中有未完成的计划时间且租约已经过期时，接管的进程跳过这个计划时间并清理标记，之后的计划时间正常执行
+func TestCronJobSkipInterruptedScheduledTime(t *testing.T) {
+	// Arrange
+	app, _ := test_utility.CreateMemoryStorageApp()
+	expression, err := ParseCronExpression("* * * * *")
+	assert.NoError(t, err)
+
+	var scheduledTimes []int64
+	state := &cronJobState{job: &cronJob{
+		name:       testCronJobName,
+		expression: expression,
+		catchUp:    CronCatchUpAll,
+		handler: func(ctx cd.AwaitableContext, scheduledTime time.Time) cd.RpcResult {
+			scheduledTimes = append(scheduledTimes, scheduledTime.Unix())
+			return cd.CreateRpcResultOk()
+		},
+	}}
+	start := time.Date(2026, time.January, 9, 9, 30, 0, 0, time.UTC)
+	runCronJob(test_utility.NewMockRpcContextWithNow(app, start), state)
+
+	ctx := test_utility.NewMockRpcContextWithNow(app, start.Add(2*time.Minute))
+	tb, casVersion, result := db.DatabaseTableCronJobLoadWithJobName(ctx, testCronJobName)
+	assert.False(t, result.IsError())
+	tb.LeaseServerId = 999
+	tb.LeaseExpired = ctx.GetSysNow().Add(-time.Second).Unix()
+	tb.RunningScheduledTime = start.Add(time.Minute).Unix()
+	result = db.DatabaseTableCronJobReplaceJobName(ctx, tb, &casVersion, false)
+	assert.False(t, result.IsError())
+
+	// Act
+	runCronJob(ctx, state)
+
+	// Assert
+	assert.Equal(t, []int64{start.Add(2 * time.Minute).Unix()}, scheduledTimes)
+	tb, _, result = db.DatabaseTableCronJobLoadWithJobName(ctx, testCronJobName)
+	assert.False(t, result.IsError())
+	assert.Equal(t, int64(0), tb.GetRunningScheduledTime())
+	assert.Equal(t, start.Add(2*time.Minute).Unix(), tb.GetLastScheduledTime())
+	assert.Equal(t, uint64(0), tb.GetLeaseServerId())
+}
@@ -0,0 +1,39 @@
+// Copyright 2026 atframework
+
+package lobbysvr_logic_friend_action
+
+import (
+	"fmt"
+
+	component_dispatcher "github.com/atframework/atsf4g-go/component/dispatcher"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	user_controller "github.com/atframework/atsf4g-go/component/user_controller"
+	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
+	logic_friend "github.com/atframework/atsf4g-go/service-lobbysvr/logic/friend"
+	service_protocol "github.com/atframework/atsf4g-go/service-lobbysvr/protocol/public/protocol/pbdesc"
+)
+
+type TaskActionFriendSendGift struct {
+	user_controller.TaskActionCSBase[*service_protocol.CSFriendSendGiftReq, *service_protocol.SCFriendSendGiftRsp]
+}
+
+func (t *TaskActionFriendSendGift) Name() string {
+	return "TaskActionFriendSendGift"
+}
+
+func (t *TaskActionFriendSendGift) Run(_startData *component_dispatcher.DispatcherStartData) error {
+	user, ok := t.GetUser().(*data.User)
+	if !ok || user == nil {
+		t.SetResponseCode(int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_USER_NOT_FOUND))
+		return fmt.Errorf("user not found")
+	}
+
+	manager := data.UserGetModuleManager[logic_friend.UserFriendManager](user)
+	if manager == nil {
+		t.SetResponseError(public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+		return fmt.Errorf("user friend manager not found")
+	}
+
+	t.SetResponseCode(manager.SendGift(t.GetAwaitableContext(), t.GetRequestBody().GetUserId(), t.MutableResponseBody()))
+	return nil
+}
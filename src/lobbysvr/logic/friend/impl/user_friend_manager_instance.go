@@ -0,0 +1,771 @@
+package lobbysvr_logic_friend_impl
+
+import (
+	"time"
+	"unicode/utf8"
+
+	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
+
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+
+	async_jobs "github.com/atframework/atsf4g-go/component/async_jobs"
+	config "github.com/atframework/atsf4g-go/component/config"
+	db "github.com/atframework/atsf4g-go/component/db"
+	logical_time "github.com/atframework/atsf4g-go/component/logical_time"
+	private_protocol_log "github.com/atframework/atsf4g-go/component/protocol/private/log/protocol/log"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_common "github.com/atframework/atsf4g-go/component/protocol/public/common/protocol/common"
+	public_protocol_config "github.com/atframework/atsf4g-go/component/protocol/public/config/protocol/config"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	logic_friend "github.com/atframework/atsf4g-go/service-lobbysvr/logic/friend"
+	logic_timer "github.com/atframework/atsf4g-go/service-lobbysvr/logic/timer"
+	service_protocol "github.com/atframework/atsf4g-go/service-lobbysvr/protocol/public/protocol/pbdesc"
+)
+
+func init() {
+	var _ logic_friend.UserFriendManager = (*UserFriendManager)(nil)
+	data.RegisterUserModuleManagerCreator[logic_friend.UserFriendManager](func(ctx cd.RpcContext, owner *data.User) data.UserModuleManagerImpl {
+		return CreateUserFriendManager(owner)
+	})
+
+	logic_timer.RegisterUserTimerCallback(friendRequestExpireTimerName, onFriendRequestExpired)
+}
+
+// friendRequestExpireTimerName 好友申请过期的定时器，param 为申请人的 user_id
+const friendRequestExpireTimerName = "friend_request_expire"
+
+// friendDirtyUserIds 需要推送给客户端的新增/更新和删除
+type friendDirtyUserIds struct {
+	updated map[uint64]struct{}
+	removed map[uint64]struct{}
+}
+
+func (d *friendDirtyUserIds) markUpdated(userId uint64) {
+	delete(d.removed, userId)
+	d.updated[userId] = struct{}{}
+}
+
+func (d *friendDirtyUserIds) markRemoved(userId uint64) {
+	delete(d.updated, userId)
+	d.removed[userId] = struct{}{}
+}
+
+func (d *friendDirtyUserIds) reset() {
+	clear(d.updated)
+	clear(d.removed)
+}
+
+func createFriendDirtyUserIds() friendDirtyUserIds {
+	return friendDirtyUserIds{
+		updated: make(map[uint64]struct{}),
+		removed: make(map[uint64]struct{}),
+	}
+}
+
+type UserFriendManager struct {
+	owner *data.User
+	data.UserModuleManagerBase
+
+	friends     map[uint64]*public_protocol_pbdesc.DFriendData
+	requests    []*public_protocol_pbdesc.DFriendRequest // 按申请时间从旧到新
+	blocklist   map[uint64]*public_protocol_pbdesc.DFriendBlockData
+	giftCounter *public_protocol_pbdesc.DFriendGiftCounter
+
+	dirtyFriends       friendDirtyUserIds
+	dirtyRequests      friendDirtyUserIds
+	dirtyBlocklist     friendDirtyUserIds
+	dirtyGiftCounter   bool
+	dirtyReceivedGifts []*public_protocol_pbdesc.DFriendGiftRecord
+}
+
+func CreateUserFriendManager(owner *data.User) *UserFriendManager {
+	return &UserFriendManager{
+		owner:                 owner,
+		UserModuleManagerBase: *data.CreateUserModuleManagerBase(owner),
+
+		friends:     make(map[uint64]*public_protocol_pbdesc.DFriendData),
+		blocklist:   make(map[uint64]*public_protocol_pbdesc.DFriendBlockData),
+		giftCounter: &public_protocol_pbdesc.DFriendGiftCounter{},
+
+		dirtyFriends:   createFriendDirtyUserIds(),
+		dirtyRequests:  createFriendDirtyUserIds(),
+		dirtyBlocklist: createFriendDirtyUserIds(),
+	}
+}
+
+func (m *UserFriendManager) InitFromDB(ctx cd.RpcContext, _dbUser *private_protocol_pbdesc.DatabaseTableUser) cd.RpcResult {
+	friendData := _dbUser.GetFriendData()
+	for _, friend := range friendData.GetFriends() {
+		m.friends[friend.GetUserId()] = friend
+	}
+	m.requests = friendData.GetRequests()
+	for _, blockData := range friendData.GetBlocklist() {
+		m.blocklist[blockData.GetUserId()] = blockData
+	}
+	if friendData.GetGiftCounter() != nil {
+		m.giftCounter = friendData.GetGiftCounter()
+	}
+
+	return cd.CreateRpcResultOk()
+}
+
+func (m *UserFriendManager) DumpToDB(ctx cd.RpcContext, _dbUser *private_protocol_pbdesc.DatabaseTableUser) cd.RpcResult {
+	friendData := _dbUser.MutableFriendData()
+	friendData.Friends = make([]*public_protocol_pbdesc.DFriendData, 0, len(m.friends))
+	for _, friend := range m.friends {
+		friendData.Friends = append(friendData.Friends, friend)
+	}
+	friendData.Requests = m.requests
+	friendData.Blocklist = make([]*public_protocol_pbdesc.DFriendBlockData, 0, len(m.blocklist))
+	for _, blockData := range m.blocklist {
+		friendData.Blocklist = append(friendData.Blocklist, blockData)
+	}
+	friendData.GiftCounter = m.giftCounter
+
+	return cd.CreateRpcResultOk()
+}
+
+func (m *UserFriendManager) RefreshLimitSecond(ctx cd.RpcContext) {
+	m.refreshGiftCounter(ctx)
+}
+
+// refreshGiftCounter 跨天重置赠送计数
+func (m *UserFriendManager) refreshGiftCounter(ctx cd.RpcContext) {
+	if logical_time.IsSameDay(time.Unix(m.giftCounter.GetLastResetTime(), 0), ctx.GetNow(), nil) {
+		return
+	}
+
+	m.giftCounter.LastResetTime = ctx.GetNow().Unix()
+	if m.giftCounter.GetSendCount() == 0 && m.giftCounter.GetReceiveCount() == 0 {
+		return
+	}
+	m.giftCounter.SendCount = 0
+	m.giftCounter.ReceiveCount = 0
+	m.dirtyGiftCounter = true
+	m.insertDirtyHandle()
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+
+// friendListLimit 配置的数量上限，未配置或超出系统上限时使用系统上限
+func friendListLimit(cfgValue int32) int {
+	hardLimit := int(private_protocol_pbdesc.EnSystemLimit_EN_SL_FRIEND_LIST_MAX_COUNT)
+	if cfgValue <= 0 || int(cfgValue) > hardLimit {
+		return hardLimit
+	}
+	return int(cfgValue)
+}
+
+// friendGiftDailyLimit 配置的每日赠送、领取次数上限，未配置或超出系统上限时使用系统上限
+func friendGiftDailyLimit(cfgValue int32) int32 {
+	hardLimit := int32(private_protocol_pbdesc.EnSystemLimit_EN_SL_FRIEND_GIFT_DAILY_MAX_COUNT)
+	if cfgValue <= 0 || cfgValue > hardLimit {
+		return hardLimit
+	}
+	return cfgValue
+}
+
+// checkGiftSendLimit 每个好友每天只能赠送一次，每天赠送的总次数不超过 sendLimit，sendLimit 为 0 时使用系统上限
+func checkGiftSendLimit(friend *public_protocol_pbdesc.DFriendData, counter *public_protocol_pbdesc.DFriendGiftCounter, sendLimit int32, now time.Time) int32 {
+	if friend.GetLastGiftSendTime() > 0 && logical_time.IsSameDay(time.Unix(friend.GetLastGiftSendTime(), 0), now, nil) {
+		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_FRIEND_GIFT_ALREADY_SENT)
+	}
+	if counter.GetSendCount() >= friendGiftDailyLimit(sendLimit) {
+		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_FRIEND_GIFT_DAILY_LIMIT)
+	}
+	return 0
+}
+
+// isGiftReceiveLimited 每天领取的次数达到 receiveLimit 时不再领取，receiveLimit 为 0 时使用系统上限
+func isGiftReceiveLimited(counter *public_protocol_pbdesc.DFriendGiftCounter, receiveLimit int32) bool {
+	return counter.GetReceiveCount() >= friendGiftDailyLimit(receiveLimit)
+}
+
+func getConstIndex() *public_protocol_config.Readonly_ExcelConstConfig {
+	return config.GetConfigManager().GetCurrentConfigGroup().GetCustomIndex().GetConstIndex()
+}
+
+func (m *UserFriendManager) FetchData(rspBody *service_protocol.SCFriendGetInfoRsp) {
+	rspBody.Friends = make([]*public_protocol_pbdesc.DFriendData, 0, len(m.friends))
+	for _, friend := range m.friends {
+		rspBody.Friends = append(rspBody.Friends, friend)
+	}
+	rspBody.Requests = m.requests
+	rspBody.Blocklist = make([]*public_protocol_pbdesc.DFriendBlockData, 0, len(m.blocklist))
+	for _, blockData := range m.blocklist {
+		rspBody.Blocklist = append(rspBody.Blocklist, blockData)
+	}
+	rspBody.GiftCounter = m.giftCounter
+}
+
+func (m *UserFriendManager) IsFriend(userId uint64) bool {
+	_, ok := m.friends[userId]
+	return ok
+}
+
+func (m *UserFriendManager) IsBlocked(userId uint64) bool {
+	_, ok := m.blocklist[userId]
+	return ok
+}
+
+func (m *UserFriendManager) findRequest(userId uint64) int {
+	for i, request := range m.requests {
+		if request.GetUserId() == userId {
+			return i
+		}
+	}
+	return -1
+}
+
+// removeRequest 删除申请时同时删除申请的过期定时器
+func (m *UserFriendManager) removeRequest(ctx cd.RpcContext, userId uint64) bool {
+	index := m.findRequest(userId)
+	if index < 0 {
+		return false
+	}
+
+	m.requests = append(m.requests[:index:index], m.requests[index+1:]...)
+	m.removeRequestExpireTimer(ctx, userId)
+	m.dirtyRequests.markRemoved(userId)
+	m.insertDirtyHandle()
+	return true
+}
+
+func getFriendRequestExpire() int64 {
+	return getConstIndex().GetFriendRequestExpire().GetSeconds()
+}
+
+// addRequestExpireTimer 定时器数量达到上限时不过期，申请保留到玩家处理或者被新的申请挤掉
+func (m *UserFriendManager) addRequestExpireTimer(ctx cd.RpcContext, request *public_protocol_pbdesc.DFriendRequest) {
+	expire := getFriendRequestExpire()
+	if expire <= 0 {
+		return
+	}
+
+	timerMgr := data.UserGetModuleManager[logic_timer.UserTimerManager](m.owner)
+	if timerMgr == nil {
+		return
+	}
+
+	_, resultCode := timerMgr.AddTimer(ctx, friendRequestExpireTimerName, request.GetRequestTime()+expire, int64(request.GetUserId()), "")
+	if resultCode != 0 {
+		ctx.LogWarn("add friend request expire timer failed", "from_user_id", request.GetUserId(), "result_code", resultCode)
+	}
+}
+
+// removeRequestExpireTimer 定时器和玩家的其他模块共用数量上限，申请被删除或者替换时要一起删除
+func (m *UserFriendManager) removeRequestExpireTimer(ctx cd.RpcContext, fromUserId uint64) {
+	timerMgr := data.UserGetModuleManager[logic_timer.UserTimerManager](m.owner)
+	if timerMgr == nil {
+		return
+	}
+
+	var timerIds []int64
+	for _, timer := range timerMgr.GetPendingTimers() {
+		if timer.GetCallbackName() == friendRequestExpireTimerName && uint64(timer.GetParam()) == fromUserId {
+			timerIds = append(timerIds, timer.GetTimerId())
+		}
+	}
+	for _, timerId := range timerIds {
+		timerMgr.RemoveTimer(ctx, timerId)
+	}
+}
+
+// onFriendRequestExpired 定时器保存前进程崩溃时可能触发已经替换过的申请的定时器，只删除已经过期的申请
+func onFriendRequestExpired(ctx cd.RpcContext, owner *data.User, timer *private_protocol_pbdesc.UserTimer) {
+	mgr, ok := data.UserGetModuleManager[logic_friend.UserFriendManager](owner).(*UserFriendManager)
+	if !ok || mgr == nil {
+		return
+	}
+
+	fromUserId := uint64(timer.GetParam())
+	index := mgr.findRequest(fromUserId)
+	if index < 0 {
+		return
+	}
+
+	expire := getFriendRequestExpire()
+	if expire <= 0 || mgr.requests[index].GetRequestTime()+expire > ctx.GetNow().Unix() {
+		return
+	}
+
+	ctx.LogInfo("friend request expired", "from_user_id", fromUserId, "request_time", mgr.requests[index].GetRequestTime())
+	mgr.removeRequest(ctx, fromUserId)
+}
+
+func (m *UserFriendManager) addFriend(ctx cd.RpcContext, userId uint64, zoneId uint32) *public_protocol_pbdesc.DFriendData {
+	if friend, ok := m.friends[userId]; ok {
+		return friend
+	}
+
+	friend := &public_protocol_pbdesc.DFriendData{
+		UserId:  userId,
+		ZoneId:  zoneId,
+		AddTime: ctx.GetNow().Unix(),
+	}
+	m.friends[userId] = friend
+	m.dirtyFriends.markUpdated(userId)
+	m.insertDirtyHandle()
+	return friend
+}
+
+func (m *UserFriendManager) removeFriend(userId uint64) bool {
+	if _, ok := m.friends[userId]; !ok {
+		return false
+	}
+
+	delete(m.friends, userId)
+	m.dirtyFriends.markRemoved(userId)
+	m.insertDirtyHandle()
+	return true
+}
+
+// checkTargetExists 检查目标玩家是否存在，只加载基础数据
+func (m *UserFriendManager) checkTargetExists(ctx cd.AwaitableContext, targetUserId uint64, targetZoneId uint32) int32 {
+	loadKeys := []db.DatabaseTableUserTableKey{{ZoneId: targetZoneId, UserId: targetUserId}}
+	loadResult, result := db.DatabaseTableUserBatchLoadWithZoneIdUserIdPartlyGetBasicInfo(ctx, loadKeys)
+	if result.IsError() {
+		result.LogError(ctx, "load friend target basic info failed", "target_user_id", targetUserId, "target_zone_id", targetZoneId)
+		return result.GetResponseCode()
+	}
+
+	if len(loadResult) == 0 || loadResult[0].Table == nil {
+		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_FRIEND_TARGET_NOT_FOUND)
+	}
+	if loadResult[0].Result.IsError() {
+		if loadResult[0].Result.GetResponseCode() == int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND) {
+			return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_FRIEND_TARGET_NOT_FOUND)
+		}
+		return loadResult[0].Result.GetResponseCode()
+	}
+	return 0
+}
+
+// sendFriendJob 投递好友异步任务给对方，不同操作使用不同的任务类型，对方按申请、同意、删除、赠送的顺序处理
+func (m *UserFriendManager) sendFriendJob(ctx cd.AwaitableContext, jobType private_protocol_pbdesc.EnPlayerAsyncJobsType,
+	targetUserId uint64, targetZoneId uint32, jobData *private_protocol_pbdesc.UserAsyncJobsBlobData,
+) int32 {
+	result, _ := async_jobs.AddJobsWithRetry(ctx, jobType, targetUserId, targetZoneId, jobData, async_jobs.DefaultActionOptions())
+	if result.IsError() {
+		result.LogError(ctx, "add friend async job failed", "target_user_id", targetUserId, "target_zone_id", targetZoneId,
+			"action_case", jobData.GetActionOneofCase())
+		return result.GetResponseCode()
+	}
+	return 0
+}
+
+func (m *UserFriendManager) createFriendJobMessage() *private_protocol_pbdesc.UserAsyncJobFriendMessage {
+	return &private_protocol_pbdesc.UserAsyncJobFriendMessage{
+		FromUserId: m.GetOwner().GetUserId(),
+		FromZoneId: m.GetOwner().GetZoneId(),
+	}
+}
+
+func (m *UserFriendManager) SendRequest(ctx cd.AwaitableContext, targetUserId uint64, targetZoneId uint32, message string) int32 {
+	if targetUserId == 0 {
+		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_INVALID_PARAM)
+	}
+	if targetUserId == m.GetOwner().GetUserId() {
+		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_FRIEND_TARGET_IS_SELF)
+	}
+	if m.IsFriend(targetUserId) {
+		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_FRIEND_ALREADY_FRIEND)
+	}
+	if m.IsBlocked(targetUserId) {
+		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_FRIEND_IN_BLOCKLIST)
+	}
+
+	// 对方已经向自己发送过申请，直接同意
+	if m.findRequest(targetUserId) >= 0 {
+		_, resultCode := m.HandleRequest(ctx, targetUserId, true)
+		return resultCode
+	}
+
+	if len(m.friends) >= friendListLimit(getConstIndex().GetFriendMaxCount()) {
+		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_FRIEND_COUNT_LIMIT)
+	}
+
+	textConfig := getConstIndex().GetFriendRequestMessageConfig()
+	if textConfig.GetMaxLength() > 0 && int32(utf8.RuneCountInString(message)) > textConfig.GetMaxLength() {
+		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_FRIEND_REQUEST_MESSAGE_LENGTH_INVALID)
+	}
+	if textConfig.GetMinLength() > 0 && int32(utf8.RuneCountInString(message)) < textConfig.GetMinLength() {
+		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_FRIEND_REQUEST_MESSAGE_LENGTH_INVALID)
+	}
+
+	if resultCode := m.checkTargetExists(ctx, targetUserId, targetZoneId); resultCode != 0 {
+		return resultCode
+	}
+
+	jobMessage := m.createFriendJobMessage()
+	jobMessage.Message = message
+	resultCode := m.sendFriendJob(ctx, private_protocol_pbdesc.EnPlayerAsyncJobsType_EN_PAJT_FRIEND_REQUEST, targetUserId, targetZoneId, &private_protocol_pbdesc.UserAsyncJobsBlobData{
+		Action: &private_protocol_pbdesc.UserAsyncJobsBlobData_FriendRequest{FriendRequest: jobMessage},
+	})
+	if resultCode != 0 {
+		return resultCode
+	}
+
+	m.sendFriendOssLog(ctx, private_protocol_log.OSSFriendFlow_EN_OSS_FRIEND_OPERATION_TYPE_SEND_REQUEST, targetUserId, targetZoneId, nil)
+	return 0
+}
+
+func (m *UserFriendManager) HandleRequest(ctx cd.AwaitableContext, targetUserId uint64, accept bool) (*public_protocol_pbdesc.DFriendData, int32) {
+	index := m.findRequest(targetUserId)
+	if index < 0 {
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_FRIEND_REQUEST_NOT_FOUND)
+	}
+	request := m.requests[index]
+
+	if !accept {
+		m.removeRequest(ctx, targetUserId)
+		m.sendFriendOssLog(ctx, private_protocol_log.OSSFriendFlow_EN_OSS_FRIEND_OPERATION_TYPE_REJECT, targetUserId, request.GetZoneId(), nil)
+		return nil, 0
+	}
+
+	if friend, ok := m.friends[targetUserId]; ok {
+		m.removeRequest(ctx, targetUserId)
+		return friend, 0
+	}
+
+	if len(m.friends) >= friendListLimit(getConstIndex().GetFriendMaxCount()) {
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_FRIEND_COUNT_LIMIT)
+	}
+
+	// 先通知对方，投递失败时本地数据不变
+	resultCode := m.sendFriendJob(ctx, private_protocol_pbdesc.EnPlayerAsyncJobsType_EN_PAJT_FRIEND_ACCEPT, targetUserId, request.GetZoneId(), &private_protocol_pbdesc.UserAsyncJobsBlobData{
+		Action: &private_protocol_pbdesc.UserAsyncJobsBlobData_FriendAccept{FriendAccept: m.createFriendJobMessage()},
+	})
+	if resultCode != 0 {
+		return nil, resultCode
+	}
+
+	m.removeRequest(ctx, targetUserId)
+	friend := m.addFriend(ctx, targetUserId, request.GetZoneId())
+	m.sendFriendOssLog(ctx, private_protocol_log.OSSFriendFlow_EN_OSS_FRIEND_OPERATION_TYPE_ACCEPT, targetUserId, request.GetZoneId(), nil)
+	return friend, 0
+}
+
+func (m *UserFriendManager) RemoveFriend(ctx cd.AwaitableContext, targetUserId uint64) int32 {
+	friend, ok := m.friends[targetUserId]
+	if !ok {
+		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_FRIEND_NOT_FRIEND)
+	}
+
+	resultCode := m.sendFriendJob(ctx, private_protocol_pbdesc.EnPlayerAsyncJobsType_EN_PAJT_FRIEND_REMOVE, targetUserId, friend.GetZoneId(), &private_protocol_pbdesc.UserAsyncJobsBlobData{
+		Action: &private_protocol_pbdesc.UserAsyncJobsBlobData_FriendRemove{FriendRemove: m.createFriendJobMessage()},
+	})
+	if resultCode != 0 {
+		return resultCode
+	}
+
+	m.removeFriend(targetUserId)
+	m.sendFriendOssLog(ctx, private_protocol_log.OSSFriendFlow_EN_OSS_FRIEND_OPERATION_TYPE_REMOVE, targetUserId, friend.GetZoneId(), nil)
+	return 0
+}
+
+func (m *UserFriendManager) Block(ctx cd.AwaitableContext, targetUserId uint64, targetZoneId uint32) (*public_protocol_pbdesc.DFriendBlockData, int32) {
+	if targetUserId == 0 {
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_INVALID_PARAM)
+	}
+	if targetUserId == m.GetOwner().GetUserId() {
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_FRIEND_TARGET_IS_SELF)
+	}
+	if blockData, ok := m.blocklist[targetUserId]; ok {
+		return blockData, 0
+	}
+	if len(m.blocklist) >= friendListLimit(getConstIndex().GetFriendBlocklistMaxCount()) {
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_FRIEND_BLOCKLIST_LIMIT)
+	}
+
+	if friend, ok := m.friends[targetUserId]; ok {
+		targetZoneId = friend.GetZoneId()
+	} else if resultCode := m.checkTargetExists(ctx, targetUserId, targetZoneId); resultCode != 0 {
+		return nil, resultCode
+	}
+
+	// 不是好友时也通知对方，删除自己发给对方的申请，避免对方之后同意申请时产生单向好友
+	resultCode := m.sendFriendJob(ctx, private_protocol_pbdesc.EnPlayerAsyncJobsType_EN_PAJT_FRIEND_REMOVE, targetUserId, targetZoneId, &private_protocol_pbdesc.UserAsyncJobsBlobData{
+		Action: &private_protocol_pbdesc.UserAsyncJobsBlobData_FriendRemove{FriendRemove: m.createFriendJobMessage()},
+	})
+	if resultCode != 0 {
+		return nil, resultCode
+	}
+	m.removeFriend(targetUserId)
+	m.removeRequest(ctx, targetUserId)
+
+	blockData := &public_protocol_pbdesc.DFriendBlockData{
+		UserId:    targetUserId,
+		ZoneId:    targetZoneId,
+		BlockTime: ctx.GetNow().Unix(),
+	}
+	m.blocklist[targetUserId] = blockData
+	m.dirtyBlocklist.markUpdated(targetUserId)
+	m.insertDirtyHandle()
+
+	m.sendFriendOssLog(ctx, private_protocol_log.OSSFriendFlow_EN_OSS_FRIEND_OPERATION_TYPE_BLOCK, targetUserId, targetZoneId, nil)
+	return blockData, 0
+}
+
+func (m *UserFriendManager) Unblock(ctx cd.RpcContext, targetUserId uint64) int32 {
+	blockData, ok := m.blocklist[targetUserId]
+	if !ok {
+		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_FRIEND_NOT_IN_BLOCKLIST)
+	}
+
+	delete(m.blocklist, targetUserId)
+	m.dirtyBlocklist.markRemoved(targetUserId)
+	m.insertDirtyHandle()
+
+	m.sendFriendOssLog(ctx, private_protocol_log.OSSFriendFlow_EN_OSS_FRIEND_OPERATION_TYPE_UNBLOCK, targetUserId, blockData.GetZoneId(), nil)
+	return 0
+}
+
+// SendGift 好友赠送是每日免费礼物，道具由系统按配置发放给对方，不扣除自己的道具
+// 产出由每日赠送次数和对方的每日领取次数限制，两者未配置时都使用系统上限
+func (m *UserFriendManager) SendGift(ctx cd.AwaitableContext, targetUserId uint64, rspBody *service_protocol.SCFriendSendGiftRsp) int32 {
+	friend, ok := m.friends[targetUserId]
+	if !ok {
+		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_FRIEND_NOT_FRIEND)
+	}
+
+	if len(getConstIndex().GetFriendGiftItems()) == 0 {
+		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_FRIEND_GIFT_NOT_CONFIGURED)
+	}
+
+	m.refreshGiftCounter(ctx)
+	if resultCode := checkGiftSendLimit(friend, m.giftCounter, getConstIndex().GetFriendGiftDailySendMaxCount(), ctx.GetNow()); resultCode != 0 {
+		return resultCode
+	}
+
+	resultCode := m.sendFriendJob(ctx, private_protocol_pbdesc.EnPlayerAsyncJobsType_EN_PAJT_FRIEND_GIFT, targetUserId, friend.GetZoneId(), &private_protocol_pbdesc.UserAsyncJobsBlobData{
+		Action: &private_protocol_pbdesc.UserAsyncJobsBlobData_FriendGift{FriendGift: m.createFriendJobMessage()},
+	})
+	if resultCode != 0 {
+		return resultCode
+	}
+
+	friend.LastGiftSendTime = ctx.GetNow().Unix()
+	m.giftCounter.SendCount++
+	m.dirtyFriends.markUpdated(targetUserId)
+	m.dirtyGiftCounter = true
+	m.insertDirtyHandle()
+
+	m.sendFriendOssLog(ctx, private_protocol_log.OSSFriendFlow_EN_OSS_FRIEND_OPERATION_TYPE_SEND_GIFT, targetUserId, friend.GetZoneId(), nil)
+
+	rspBody.Friend = friend
+	rspBody.GiftCounter = m.giftCounter
+	return 0
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+// 异步任务处理，返回负数时异步任务会重试
+
+// OnReceiveRequest 收到好友申请，黑名单中的玩家和已经是好友的申请直接丢弃
+func (m *UserFriendManager) OnReceiveRequest(ctx cd.RpcContext, jobData *private_protocol_pbdesc.UserAsyncJobFriendMessage) int32 {
+	fromUserId := jobData.GetFromUserId()
+	if fromUserId == 0 || m.IsBlocked(fromUserId) || m.IsFriend(fromUserId) {
+		ctx.LogInfo("drop friend request", "from_user_id", fromUserId, "is_blocked", m.IsBlocked(fromUserId), "is_friend", m.IsFriend(fromUserId))
+		return 0
+	}
+
+	// 重复申请只保留最新的一条，每个申请人最多只有一个过期定时器
+	if index := m.findRequest(fromUserId); index >= 0 {
+		m.requests = append(m.requests[:index:index], m.requests[index+1:]...)
+		m.removeRequestExpireTimer(ctx, fromUserId)
+	}
+	request := &public_protocol_pbdesc.DFriendRequest{
+		UserId:      fromUserId,
+		ZoneId:      jobData.GetFromZoneId(),
+		RequestTime: ctx.GetNow().Unix(),
+		Message:     jobData.GetMessage(),
+	}
+	m.requests = append(m.requests, request)
+	m.dirtyRequests.markUpdated(fromUserId)
+	m.addRequestExpireTimer(ctx, request)
+
+	// 超出上限时丢弃最早的申请
+	requestLimit := friendListLimit(getConstIndex().GetFriendRequestMaxCount())
+	if len(m.requests) > requestLimit {
+		dropCount := len(m.requests) - requestLimit
+		for _, request := range m.requests[:dropCount] {
+			m.dirtyRequests.markRemoved(request.GetUserId())
+			m.removeRequestExpireTimer(ctx, request.GetUserId())
+		}
+		m.requests = append(m.requests[:0:0], m.requests[dropCount:]...)
+	}
+
+	m.insertDirtyHandle()
+	return 0
+}
+
+// OnRequestAccepted 对方同意了自己的申请
+// 发送申请后可能已经加满了好友，达到上限时不添加，并通知对方删除好友关系，避免出现单向好友
+func (m *UserFriendManager) OnRequestAccepted(ctx cd.AwaitableContext, jobData *private_protocol_pbdesc.UserAsyncJobFriendMessage) int32 {
+	fromUserId := jobData.GetFromUserId()
+	if fromUserId == 0 {
+		return 0
+	}
+	if m.IsBlocked(fromUserId) {
+		// 申请之后又拉黑了对方，拉黑时投递的删除任务会让对方删除好友关系
+		ctx.LogWarn("friend request accepted by blocked user, ignore", "from_user_id", fromUserId)
+		return 0
+	}
+
+	m.removeRequest(ctx, fromUserId)
+	if m.IsFriend(fromUserId) {
+		return 0
+	}
+	if len(m.friends) >= friendListLimit(getConstIndex().GetFriendMaxCount()) {
+		ctx.LogWarn("friend count limit when request accepted, notify remove", "from_user_id", fromUserId, "friend_count", len(m.friends))
+		// 投递失败时返回错误码重试，申请已经删除，重试时仍然走到这里
+		return m.sendFriendJob(ctx, private_protocol_pbdesc.EnPlayerAsyncJobsType_EN_PAJT_FRIEND_REMOVE, fromUserId, jobData.GetFromZoneId(), &private_protocol_pbdesc.UserAsyncJobsBlobData{
+			Action: &private_protocol_pbdesc.UserAsyncJobsBlobData_FriendRemove{FriendRemove: m.createFriendJobMessage()},
+		})
+	}
+	m.addFriend(ctx, fromUserId, jobData.GetFromZoneId())
+	m.sendFriendOssLog(ctx, private_protocol_log.OSSFriendFlow_EN_OSS_FRIEND_OPERATION_TYPE_PASSIVE_ADD, fromUserId, jobData.GetFromZoneId(), nil)
+	return 0
+}
+
+// OnRemoved 被对方删除或拉黑
+func (m *UserFriendManager) OnRemoved(ctx cd.RpcContext, jobData *private_protocol_pbdesc.UserAsyncJobFriendMessage) int32 {
+	fromUserId := jobData.GetFromUserId()
+	m.removeRequest(ctx, fromUserId)
+	if m.removeFriend(fromUserId) {
+		m.sendFriendOssLog(ctx, private_protocol_log.OSSFriendFlow_EN_OSS_FRIEND_OPERATION_TYPE_PASSIVE_REMOVE, fromUserId, jobData.GetFromZoneId(), nil)
+	}
+	return 0
+}
+
+// OnReceiveGift 收到好友赠送，不是好友或者超出每日领取上限时丢弃
+func (m *UserFriendManager) OnReceiveGift(ctx cd.RpcContext, jobData *private_protocol_pbdesc.UserAsyncJobFriendMessage) int32 {
+	fromUserId := jobData.GetFromUserId()
+	if !m.IsFriend(fromUserId) || m.IsBlocked(fromUserId) {
+		ctx.LogInfo("drop friend gift from non-friend", "from_user_id", fromUserId)
+		return 0
+	}
+
+	m.refreshGiftCounter(ctx)
+	if isGiftReceiveLimited(m.giftCounter, getConstIndex().GetFriendGiftDailyReceiveMaxCount()) {
+		ctx.LogInfo("drop friend gift for daily receive limit", "from_user_id", fromUserId, "receive_count", m.giftCounter.GetReceiveCount())
+		return 0
+	}
+
+	instances, result := m.GetOwner().GenerateMultipleItemInstancesFromCfgOffset(ctx, getConstIndex().GetFriendGiftItems(), true)
+	if result.IsError() {
+		result.LogError(ctx, "generate friend gift item instances failed", "from_user_id", fromUserId)
+		return result.GetResponseCode()
+	}
+	if len(instances) == 0 {
+		ctx.LogWarn("friend gift items not configured, drop gift", "from_user_id", fromUserId)
+		return 0
+	}
+
+	addGuard, result := m.GetOwner().CheckAddItem(ctx, instances)
+	if result.IsError() {
+		result.LogError(ctx, "check add friend gift items failed", "from_user_id", fromUserId)
+		return result.GetResponseCode()
+	}
+
+	m.GetOwner().AddItem(ctx, addGuard, &data.ItemFlowReason{
+		MajorReason: int32(public_protocol_common.EnItemFlowReasonMajorType_EN_ITEM_FLOW_REASON_MAJOR_FRIEND),
+		MinorReason: int32(public_protocol_common.EnItemFlowReasonMinorType_EN_ITEM_FLOW_REASON_MINOR_FRIEND_GIFT_RECEIVE),
+		Parameter:   int64(fromUserId),
+	})
+
+	m.giftCounter.ReceiveCount++
+	m.dirtyGiftCounter = true
+	record := &public_protocol_pbdesc.DFriendGiftRecord{
+		FromUserId:  fromUserId,
+		FromZoneId:  jobData.GetFromZoneId(),
+		ReceiveTime: ctx.GetNow().Unix(),
+	}
+	for _, guard := range addGuard {
+		record.Items = append(record.Items, guard.GetAddedItems()...)
+	}
+	items := make([]*public_protocol_common.DItemBasic, 0, len(instances))
+	for _, instance := range instances {
+		items = append(items, instance.GetItemBasic())
+	}
+	m.dirtyReceivedGifts = append(m.dirtyReceivedGifts, record)
+	m.insertDirtyHandle()
+
+	m.sendFriendOssLog(ctx, private_protocol_log.OSSFriendFlow_EN_OSS_FRIEND_OPERATION_TYPE_RECEIVE_GIFT, fromUserId, jobData.GetFromZoneId(), items)
+	return 0
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+
+func (m *UserFriendManager) sendFriendOssLog(ctx cd.RpcContext, operationType private_protocol_log.OSSFriendFlow_EnOSSFriendOperationType,
+	targetUserId uint64, targetZoneId uint32, items []*public_protocol_common.DItemBasic,
+) {
+	log := private_protocol_log.OperationSupportSystemLog{}
+	flow := log.MutableLog().MutableFriendFlow()
+	flow.OperationType = operationType
+	flow.TargetUserId = targetUserId
+	flow.TargetZoneId = targetZoneId
+	flow.Items = items
+	flow.FriendCount = int32(len(m.friends))
+	flow.GiftSendCount = m.giftCounter.GetSendCount()
+	flow.GiftReceiveCount = m.giftCounter.GetReceiveCount()
+	m.GetOwner().SendUserOssLog(ctx, &log)
+}
+
+func (m *UserFriendManager) insertDirtyHandle() {
+	m.GetOwner().InsertDirtyHandleIfNotExists(m,
+		func(ctx cd.RpcContext, dirty *data.UserDirtyData) (ret bool) {
+			dirtyData := &public_protocol_pbdesc.DUserFriendDirtyChg{}
+			ret = false
+			for userId := range m.dirtyFriends.updated {
+				if friend, ok := m.friends[userId]; ok {
+					dirtyData.Friends = append(dirtyData.Friends, friend)
+					ret = true
+				}
+			}
+			for userId := range m.dirtyFriends.removed {
+				dirtyData.RemovedFriendUserIds = append(dirtyData.RemovedFriendUserIds, userId)
+				ret = true
+			}
+			for _, request := range m.requests {
+				if _, ok := m.dirtyRequests.updated[request.GetUserId()]; ok {
+					dirtyData.Requests = append(dirtyData.Requests, request)
+					ret = true
+				}
+			}
+			for userId := range m.dirtyRequests.removed {
+				dirtyData.RemovedRequestUserIds = append(dirtyData.RemovedRequestUserIds, userId)
+				ret = true
+			}
+			for userId := range m.dirtyBlocklist.updated {
+				if blockData, ok := m.blocklist[userId]; ok {
+					dirtyData.Blocklist = append(dirtyData.Blocklist, blockData)
+					ret = true
+				}
+			}
+			for userId := range m.dirtyBlocklist.removed {
+				dirtyData.RemovedBlockUserIds = append(dirtyData.RemovedBlockUserIds, userId)
+				ret = true
+			}
+			if m.dirtyGiftCounter {
+				dirtyData.GiftCounter = m.giftCounter
+				ret = true
+			}
+			if len(m.dirtyReceivedGifts) > 0 {
+				dirtyData.ReceivedGifts = append(dirtyData.ReceivedGifts, m.dirtyReceivedGifts...)
+				ret = true
+			}
+			if ret {
+				dirty.MutableNormalDirtyChangeMessage().DirtyFriend = dirtyData
+			}
+			return ret
+		},
+		func(ctx cd.RpcContext) {
+			m.dirtyFriends.reset()
+			m.dirtyRequests.reset()
+			m.dirtyBlocklist.reset()
+			m.dirtyGiftCounter = false
+			m.dirtyReceivedGifts = nil
+		},
+	)
+}
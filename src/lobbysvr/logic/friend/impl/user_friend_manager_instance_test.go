@@ -0,0 +1,353 @@
+package lobbysvr_logic_friend_impl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	async_jobs "github.com/atframework/atsf4g-go/component/async_jobs"
+	db "github.com/atframework/atsf4g-go/component/db"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	test_utility "github.com/atframework/atsf4g-go/component/test_utility"
+
+	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
+	logic_friend "github.com/atframework/atsf4g-go/service-lobbysvr/logic/friend"
+	logic_timer "github.com/atframework/atsf4g-go/service-lobbysvr/logic/timer"
+
+	// 注册定时器管理器
+	_ "github.com/atframework/atsf4g-go/service-lobbysvr/logic/timer/impl"
+)
+
+const (
+	testFriendZoneId uint32 = 1
+	testFriendUserA  uint64 = 10001
+	testFriendUserB  uint64 = 10002
+)
+
+var testFriendNow = time.Unix(1700000000, 0)
+
+func createTestFriendManager(t *testing.T, ctx *test_utility.MockRpcContext, userId uint64) *UserFriendManager {
+	var casVersion uint64
+	assert.False(t, db.DatabaseTableUserReplaceZoneIdUserId(ctx, &private_protocol_pbdesc.DatabaseTableUser{
+		ZoneId: testFriendZoneId,
+		UserId: userId,
+	}, &casVersion, true).IsError())
+
+	user, result := data.CreateUserForTest(ctx, testFriendZoneId, userId, nil)
+	assert.False(t, result.IsError())
+	mgr, ok := data.UserGetModuleManager[logic_friend.UserFriendManager](user).(*UserFriendManager)
+	assert.True(t, ok)
+	return mgr
+}
+
+// deliverTestFriendJobs 模拟异步任务轮询，处理并删除 receiver 某个类型的所有好友任务，返回处理的数量
+func deliverTestFriendJobs(t *testing.T, ctx *test_utility.MockRpcContext, jobType private_protocol_pbdesc.EnPlayerAsyncJobsType,
+	receiver *UserFriendManager,
+) int {
+	userId := receiver.GetOwner().GetUserId()
+	jobs, result := async_jobs.GetJobs(ctx, int32(jobType), userId, testFriendZoneId)
+	if result.GetResponseCode() == int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND) {
+		return 0
+	}
+	assert.False(t, result.IsError())
+
+	indexes := make([]uint64, 0, len(jobs))
+	for _, job := range jobs {
+		jobData := job.Table.(*private_protocol_pbdesc.DatabaseTableUserAsyncJobs).GetJobData()
+		switch {
+		case jobData.GetFriendRequest() != nil:
+			assert.Equal(t, int32(0), receiver.OnReceiveRequest(ctx, jobData.GetFriendRequest()))
+		case jobData.GetFriendAccept() != nil:
+			assert.Equal(t, int32(0), receiver.OnRequestAccepted(ctx, jobData.GetFriendAccept()))
+		case jobData.GetFriendRemove() != nil:
+			assert.Equal(t, int32(0), receiver.OnRemoved(ctx, jobData.GetFriendRemove()))
+		case jobData.GetFriendGift() != nil:
+			assert.Equal(t, int32(0), receiver.OnReceiveGift(ctx, jobData.GetFriendGift()))
+		}
+		indexes = append(indexes, job.ListIndex)
+	}
+	assert.False(t, async_jobs.DelJobs(ctx, jobType, userId, testFriendZoneId, indexes).IsError())
+	return len(jobs)
+}
+
+// makeTestFriends A 向 B 发送申请，B 同意后双方成为好友
+func makeTestFriends(t *testing.T, ctx *test_utility.MockRpcContext, managerA *UserFriendManager, managerB *UserFriendManager) {
+	assert.Equal(t, int32(0), managerA.SendRequest(ctx, testFriendUserB, testFriendZoneId, ""))
+	deliverTestFriendJobs(t, ctx, private_protocol_pbdesc.EnPlayerAsyncJobsType_EN_PAJT_FRIEND_REQUEST, managerB)
+	_, resultCode := managerB.HandleRequest(ctx, testFriendUserA, true)
+	assert.Equal(t, int32(0), resultCode)
+	deliverTestFriendJobs(t, ctx, private_protocol_pbdesc.EnPlayerAsyncJobsType_EN_PAJT_FRIEND_ACCEPT, managerA)
+}
+
+// TestFriendListLimit 测试好友列表数量上限
+// Scenario: 未配置或超出系统上限时使用系统上限，否则使用配置值
+func TestFriendListLimit(t *testing.T) {
+	// Arrange
+	hardLimit := int(private_protocol_pbdesc.EnSystemLimit_EN_SL_FRIEND_LIST_MAX_COUNT)
+
+	// Act & Assert
+	assert.Equal(t, hardLimit, friendListLimit(0))
+	assert.Equal(t, hardLimit, friendListLimit(-1))
+	assert.Equal(t, hardLimit, friendListLimit(int32(hardLimit+1)))
+	assert.Equal(t, 50, friendListLimit(50))
+}
+
+// TestFriendDirtyUserIds 测试推送给客户端的好友变化
+// Scenario: 同一个玩家先删除后添加只推送添加，先添加后删除只推送删除
+func TestFriendDirtyUserIds(t *testing.T) {
+	// Arrange
+	dirty := createFriendDirtyUserIds()
+
+	// Act
+	dirty.markRemoved(1)
+	dirty.markUpdated(1)
+	dirty.markUpdated(2)
+	dirty.markRemoved(2)
+
+	// Assert
+	assert.Equal(t, map[uint64]struct{}{1: {}}, dirty.updated)
+	assert.Equal(t, map[uint64]struct{}{2: {}}, dirty.removed)
+
+	dirty.reset()
+	assert.Empty(t, dirty.updated)
+	assert.Empty(t, dirty.removed)
+}
+
+// TestFriendSendRequest 测试发送好友申请
+// Scenario: 申请以 EN_PAJT_FRIEND_REQUEST 投递给对方，对方收到后保存申请，目标不存在或者是自己时失败
+func TestFriendSendRequest(t *testing.T) {
+	// Arrange
+	app, _ := test_utility.CreateMemoryStorageApp()
+	ctx := test_utility.NewMockRpcContextWithNow(app, testFriendNow)
+	managerA := createTestFriendManager(t, ctx, testFriendUserA)
+	managerB := createTestFriendManager(t, ctx, testFriendUserB)
+
+	// Act
+	resultCode := managerA.SendRequest(ctx, testFriendUserB, testFriendZoneId, "hello")
+	delivered := deliverTestFriendJobs(t, ctx, private_protocol_pbdesc.EnPlayerAsyncJobsType_EN_PAJT_FRIEND_REQUEST, managerB)
+
+	// Assert
+	assert.Equal(t, int32(0), resultCode)
+	assert.Equal(t, 1, delivered)
+	if assert.Len(t, managerB.requests, 1) {
+		assert.Equal(t, testFriendUserA, managerB.requests[0].GetUserId())
+		assert.Equal(t, "hello", managerB.requests[0].GetMessage())
+		assert.Equal(t, testFriendNow.Unix(), managerB.requests[0].GetRequestTime())
+	}
+	assert.Empty(t, managerA.requests)
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_FRIEND_TARGET_IS_SELF),
+		managerA.SendRequest(ctx, testFriendUserA, testFriendZoneId, ""))
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_FRIEND_TARGET_NOT_FOUND),
+		managerA.SendRequest(ctx, 99999, testFriendZoneId, ""))
+}
+
+// TestFriendHandleRequestAccept 测试同意好友申请
+// Scenario: 同意后自己添加好友并以 EN_PAJT_FRIEND_ACCEPT 通知对方，对方处理后也添加好友，申请被删除
+func TestFriendHandleRequestAccept(t *testing.T) {
+	// Arrange
+	app, _ := test_utility.CreateMemoryStorageApp()
+	ctx := test_utility.NewMockRpcContextWithNow(app, testFriendNow)
+	managerA := createTestFriendManager(t, ctx, testFriendUserA)
+	managerB := createTestFriendManager(t, ctx, testFriendUserB)
+	assert.Equal(t, int32(0), managerA.SendRequest(ctx, testFriendUserB, testFriendZoneId, ""))
+	deliverTestFriendJobs(t, ctx, private_protocol_pbdesc.EnPlayerAsyncJobsType_EN_PAJT_FRIEND_REQUEST, managerB)
+
+	// Act
+	friend, resultCode := managerB.HandleRequest(ctx, testFriendUserA, true)
+	delivered := deliverTestFriendJobs(t, ctx, private_protocol_pbdesc.EnPlayerAsyncJobsType_EN_PAJT_FRIEND_ACCEPT, managerA)
+
+	// Assert
+	assert.Equal(t, int32(0), resultCode)
+	assert.Equal(t, testFriendUserA, friend.GetUserId())
+	assert.Equal(t, 1, delivered)
+	assert.True(t, managerA.IsFriend(testFriendUserB))
+	assert.True(t, managerB.IsFriend(testFriendUserA))
+	assert.Empty(t, managerB.requests)
+	_, resultCode = managerB.HandleRequest(ctx, testFriendUserA, true)
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_FRIEND_REQUEST_NOT_FOUND), resultCode)
+}
+
+// TestFriendAcceptWhenRequesterFull 测试申请人好友已满时被同意
+// Scenario: 申请人发送申请后加满了好友，收到同意通知时不添加好友，并通知对方删除，双方都不是好友
+func TestFriendAcceptWhenRequesterFull(t *testing.T) {
+	// Arrange
+	app, _ := test_utility.CreateMemoryStorageApp()
+	ctx := test_utility.NewMockRpcContextWithNow(app, testFriendNow)
+	managerA := createTestFriendManager(t, ctx, testFriendUserA)
+	managerB := createTestFriendManager(t, ctx, testFriendUserB)
+	assert.Equal(t, int32(0), managerA.SendRequest(ctx, testFriendUserB, testFriendZoneId, ""))
+	deliverTestFriendJobs(t, ctx, private_protocol_pbdesc.EnPlayerAsyncJobsType_EN_PAJT_FRIEND_REQUEST, managerB)
+	friendLimit := friendListLimit(0)
+	for i := 0; i < friendLimit; i++ {
+		managerA.addFriend(ctx, 20000+uint64(i), testFriendZoneId)
+	}
+
+	// Act
+	_, resultCode := managerB.HandleRequest(ctx, testFriendUserA, true)
+	deliverTestFriendJobs(t, ctx, private_protocol_pbdesc.EnPlayerAsyncJobsType_EN_PAJT_FRIEND_ACCEPT, managerA)
+	removeDelivered := deliverTestFriendJobs(t, ctx, private_protocol_pbdesc.EnPlayerAsyncJobsType_EN_PAJT_FRIEND_REMOVE, managerB)
+
+	// Assert
+	assert.Equal(t, int32(0), resultCode)
+	assert.Equal(t, 1, removeDelivered)
+	assert.False(t, managerA.IsFriend(testFriendUserB))
+	assert.False(t, managerB.IsFriend(testFriendUserA))
+	assert.Len(t, managerA.friends, friendLimit)
+}
+
+// TestFriendRemove 测试删除好友
+// Scenario: 删除后以 EN_PAJT_FRIEND_REMOVE 通知对方，双方都不再是好友，再次删除返回 EN_ERR_FRIEND_NOT_FRIEND
+func TestFriendRemove(t *testing.T) {
+	// Arrange
+	app, _ := test_utility.CreateMemoryStorageApp()
+	ctx := test_utility.NewMockRpcContextWithNow(app, testFriendNow)
+	managerA := createTestFriendManager(t, ctx, testFriendUserA)
+	managerB := createTestFriendManager(t, ctx, testFriendUserB)
+	makeTestFriends(t, ctx, managerA, managerB)
+
+	// Act
+	resultCode := managerA.RemoveFriend(ctx, testFriendUserB)
+	delivered := deliverTestFriendJobs(t, ctx, private_protocol_pbdesc.EnPlayerAsyncJobsType_EN_PAJT_FRIEND_REMOVE, managerB)
+
+	// Assert
+	assert.Equal(t, int32(0), resultCode)
+	assert.Equal(t, 1, delivered)
+	assert.False(t, managerA.IsFriend(testFriendUserB))
+	assert.False(t, managerB.IsFriend(testFriendUserA))
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_FRIEND_NOT_FRIEND), managerA.RemoveFriend(ctx, testFriendUserB))
+}
+
+// TestFriendBlockWithPendingRequest 测试申请未处理时拉黑对方
+// Scenario: 对方在收到删除通知之前同意了申请，处理删除通知后双方都不是好友，不会留下单向好友
+func TestFriendBlockWithPendingRequest(t *testing.T) {
+	// Arrange
+	app, _ := test_utility.CreateMemoryStorageApp()
+	ctx := test_utility.NewMockRpcContextWithNow(app, testFriendNow)
+	managerA := createTestFriendManager(t, ctx, testFriendUserA)
+	managerB := createTestFriendManager(t, ctx, testFriendUserB)
+	assert.Equal(t, int32(0), managerA.SendRequest(ctx, testFriendUserB, testFriendZoneId, ""))
+	deliverTestFriendJobs(t, ctx, private_protocol_pbdesc.EnPlayerAsyncJobsType_EN_PAJT_FRIEND_REQUEST, managerB)
+
+	// Act
+	_, blockResultCode := managerA.Block(ctx, testFriendUserB, testFriendZoneId)
+	_, acceptResultCode := managerB.HandleRequest(ctx, testFriendUserA, true)
+	deliverTestFriendJobs(t, ctx, private_protocol_pbdesc.EnPlayerAsyncJobsType_EN_PAJT_FRIEND_ACCEPT, managerA)
+	deliverTestFriendJobs(t, ctx, private_protocol_pbdesc.EnPlayerAsyncJobsType_EN_PAJT_FRIEND_REMOVE, managerB)
+
+	// Assert
+	assert.Equal(t, int32(0), blockResultCode)
+	assert.Equal(t, int32(0), acceptResultCode)
+	assert.True(t, managerA.IsBlocked(testFriendUserB))
+	assert.False(t, managerA.IsFriend(testFriendUserB))
+	assert.False(t, managerB.IsFriend(testFriendUserA))
+	assert.Empty(t, managerB.requests)
+}
+
+// TestFriendBlockBeforeAccept 测试拉黑通知先于同意到达
+// Scenario: 对方处理删除通知后申请被删除，无法再同意
+func TestFriendBlockBeforeAccept(t *testing.T) {
+	// Arrange
+	app, _ := test_utility.CreateMemoryStorageApp()
+	ctx := test_utility.NewMockRpcContextWithNow(app, testFriendNow)
+	managerA := createTestFriendManager(t, ctx, testFriendUserA)
+	managerB := createTestFriendManager(t, ctx, testFriendUserB)
+	assert.Equal(t, int32(0), managerA.SendRequest(ctx, testFriendUserB, testFriendZoneId, ""))
+	deliverTestFriendJobs(t, ctx, private_protocol_pbdesc.EnPlayerAsyncJobsType_EN_PAJT_FRIEND_REQUEST, managerB)
+
+	// Act
+	_, blockResultCode := managerA.Block(ctx, testFriendUserB, testFriendZoneId)
+	deliverTestFriendJobs(t, ctx, private_protocol_pbdesc.EnPlayerAsyncJobsType_EN_PAJT_FRIEND_REMOVE, managerB)
+	_, acceptResultCode := managerB.HandleRequest(ctx, testFriendUserA, true)
+
+	// Assert
+	assert.Equal(t, int32(0), blockResultCode)
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_FRIEND_REQUEST_NOT_FOUND), acceptResultCode)
+	assert.False(t, managerB.IsFriend(testFriendUserA))
+}
+
+// countTestFriendRequestTimers 统计某个申请人的申请过期定时器数量
+func countTestFriendRequestTimers(mgr *UserFriendManager, fromUserId uint64) int {
+	count := 0
+	timerMgr := data.UserGetModuleManager[logic_timer.UserTimerManager](mgr.GetOwner())
+	for _, timer := range timerMgr.GetPendingTimers() {
+		if timer.GetCallbackName() == friendRequestExpireTimerName && uint64(timer.GetParam()) == fromUserId {
+			count++
+		}
+	}
+	return count
+}
+
+// TestFriendRequestExpireTimerRemoved 测试申请被处理或替换时删除过期定时器
+// Scenario: 重复申请只保留一个定时器，拒绝申请后定时器被删除，不会占用玩家的定时器数量上限
+func TestFriendRequestExpireTimerRemoved(t *testing.T) {
+	// Arrange
+	app, _ := test_utility.CreateMemoryStorageApp()
+	ctx := test_utility.NewMockRpcContextWithNow(app, testFriendNow)
+	managerB := createTestFriendManager(t, ctx, testFriendUserB)
+	timerMgr := data.UserGetModuleManager[logic_timer.UserTimerManager](managerB.GetOwner())
+	assert.NotNil(t, timerMgr)
+	// 测试环境没有配置过期时间，直接添加过期定时器
+	_, resultCode := timerMgr.AddTimer(ctx, friendRequestExpireTimerName, testFriendNow.Unix()+3600, int64(testFriendUserA), "")
+	assert.Equal(t, int32(0), resultCode)
+	jobData := &private_protocol_pbdesc.UserAsyncJobFriendMessage{FromUserId: testFriendUserA, FromZoneId: testFriendZoneId}
+	assert.Equal(t, int32(0), managerB.OnReceiveRequest(ctx, jobData))
+
+	// Act: 重复申请替换旧的申请
+	assert.Equal(t, int32(0), managerB.OnReceiveRequest(ctx, jobData))
+	timerCountAfterResend := countTestFriendRequestTimers(managerB, testFriendUserA)
+	_, resultCode = timerMgr.AddTimer(ctx, friendRequestExpireTimerName, testFriendNow.Unix()+3600, int64(testFriendUserA), "")
+	assert.Equal(t, int32(0), resultCode)
+	_, rejectResultCode := managerB.HandleRequest(ctx, testFriendUserA, false)
+
+	// Assert
+	assert.Equal(t, 0, timerCountAfterResend)
+	assert.Equal(t, int32(0), rejectResultCode)
+	assert.Empty(t, managerB.requests)
+	assert.Equal(t, 0, countTestFriendRequestTimers(managerB, testFriendUserA))
+}
+
+// TestFriendGiftSendLimit 测试好友赠送的发送上限
+// Scenario: 同一个好友每天只能赠送一次，跨天后可以再次赠送，每日总次数达到上限后失败，上限为 0 时使用系统上限
+func TestFriendGiftSendLimit(t *testing.T) {
+	// Arrange
+	now := testFriendNow
+	sentToday := &public_protocol_pbdesc.DFriendData{LastGiftSendTime: now.Unix()}
+	sentYesterday := &public_protocol_pbdesc.DFriendData{LastGiftSendTime: now.Add(-24 * time.Hour).Unix()}
+	neverSent := &public_protocol_pbdesc.DFriendData{}
+	counter := &public_protocol_pbdesc.DFriendGiftCounter{SendCount: 3}
+
+	// Act & Assert
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_FRIEND_GIFT_ALREADY_SENT), checkGiftSendLimit(sentToday, counter, 0, now))
+	assert.Equal(t, int32(0), checkGiftSendLimit(sentYesterday, counter, 0, now))
+	assert.Equal(t, int32(0), checkGiftSendLimit(neverSent, counter, 4, now))
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_FRIEND_GIFT_DAILY_LIMIT), checkGiftSendLimit(neverSent, counter, 3, now))
+	hardLimitCounter := &public_protocol_pbdesc.DFriendGiftCounter{SendCount: int32(private_protocol_pbdesc.EnSystemLimit_EN_SL_FRIEND_GIFT_DAILY_MAX_COUNT)}
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_FRIEND_GIFT_DAILY_LIMIT), checkGiftSendLimit(neverSent, hardLimitCounter, 0, now))
+}
+
+// TestFriendGiftReceiveLimit 测试好友赠送的领取上限
+// Scenario: 领取次数达到上限后不再领取，上限为 0 时使用系统上限；不是好友的赠送直接丢弃
+func TestFriendGiftReceiveLimit(t *testing.T) {
+	// Arrange
+	app, _ := test_utility.CreateMemoryStorageApp()
+	ctx := test_utility.NewMockRpcContextWithNow(app, testFriendNow)
+	managerB := createTestFriendManager(t, ctx, testFriendUserB)
+	counter := &public_protocol_pbdesc.DFriendGiftCounter{ReceiveCount: 5}
+
+	// Act & Assert
+	assert.False(t, isGiftReceiveLimited(counter, 0))
+	assert.False(t, isGiftReceiveLimited(counter, 6))
+	assert.True(t, isGiftReceiveLimited(counter, 5))
+	assert.True(t, isGiftReceiveLimited(&public_protocol_pbdesc.DFriendGiftCounter{
+		ReceiveCount: int32(private_protocol_pbdesc.EnSystemLimit_EN_SL_FRIEND_GIFT_DAILY_MAX_COUNT),
+	}, 0))
+
+	assert.Equal(t, int32(0), managerB.OnReceiveGift(ctx, &private_protocol_pbdesc.UserAsyncJobFriendMessage{
+		FromUserId: testFriendUserA,
+		FromZoneId: testFriendZoneId,
+	}))
+	assert.Equal(t, int32(0), managerB.giftCounter.GetReceiveCount())
+	assert.Empty(t, managerB.dirtyReceivedGifts)
+}
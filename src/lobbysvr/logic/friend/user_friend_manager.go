@@ -0,0 +1,35 @@
+package lobbysvr_logic_friend
+
+import (
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
+	service_protocol "github.com/atframework/atsf4g-go/service-lobbysvr/protocol/public/protocol/pbdesc"
+)
+
+// UserFriendManager 好友关系、好友申请、黑名单和好友赠送
+// 涉及对方数据的操作都通过异步任务投递给对方，对方在线时由异步任务轮询处理，离线时登入后处理
+type UserFriendManager interface {
+	data.UserModuleManagerImpl
+
+	FetchData(rspBody *service_protocol.SCFriendGetInfoRsp)
+
+	IsFriend(userId uint64) bool
+	IsBlocked(userId uint64) bool
+
+	// 对方已经向自己发送过申请时直接成为好友
+	SendRequest(ctx cd.AwaitableContext, targetUserId uint64, targetZoneId uint32, message string) int32
+	HandleRequest(ctx cd.AwaitableContext, targetUserId uint64, accept bool) (*public_protocol_pbdesc.DFriendData, int32)
+	RemoveFriend(ctx cd.AwaitableContext, targetUserId uint64) int32
+	// 拉黑时会同时删除好友关系和对方的申请
+	Block(ctx cd.AwaitableContext, targetUserId uint64, targetZoneId uint32) (*public_protocol_pbdesc.DFriendBlockData, int32)
+	Unblock(ctx cd.RpcContext, targetUserId uint64) int32
+	SendGift(ctx cd.AwaitableContext, targetUserId uint64, rspBody *service_protocol.SCFriendSendGiftRsp) int32
+
+	// 以下为异步任务的处理接口
+	OnReceiveRequest(ctx cd.RpcContext, jobData *private_protocol_pbdesc.UserAsyncJobFriendMessage) int32
+	OnRequestAccepted(ctx cd.AwaitableContext, jobData *private_protocol_pbdesc.UserAsyncJobFriendMessage) int32
+	OnRemoved(ctx cd.RpcContext, jobData *private_protocol_pbdesc.UserAsyncJobFriendMessage) int32
+	OnReceiveGift(ctx cd.RpcContext, jobData *private_protocol_pbdesc.UserAsyncJobFriendMessage) int32
+}
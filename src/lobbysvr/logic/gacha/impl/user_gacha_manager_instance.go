@@ -0,0 +1,301 @@
+package lobbysvr_logic_gacha_impl
+
+import (
+	"math/rand/v2"
+
+	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
+
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+
+	config "github.com/atframework/atsf4g-go/component/config"
+	private_protocol_log "github.com/atframework/atsf4g-go/component/protocol/private/log/protocol/log"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_common "github.com/atframework/atsf4g-go/component/protocol/public/common/protocol/common"
+	public_protocol_config "github.com/atframework/atsf4g-go/component/protocol/public/config/protocol/config"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	logic_condition "github.com/atframework/atsf4g-go/service-lobbysvr/logic/condition"
+	logic_gacha "github.com/atframework/atsf4g-go/service-lobbysvr/logic/gacha"
+	service_protocol "github.com/atframework/atsf4g-go/service-lobbysvr/protocol/public/protocol/pbdesc"
+)
+
+func init() {
+	var _ logic_gacha.UserGachaManager = (*UserGachaManager)(nil)
+	data.RegisterUserModuleManagerCreator[logic_gacha.UserGachaManager](func(ctx cd.RpcContext, owner *data.User) data.UserModuleManagerImpl {
+		return CreateUserGachaManager(owner)
+	})
+}
+
+type UserGachaManager struct {
+	owner *data.User
+	data.UserModuleManagerBase
+
+	bannerData  map[int32]*public_protocol_pbdesc.DGachaBannerData
+	drawHistory []*public_protocol_pbdesc.DGachaDrawRecord // 最近抽卡记录，按时间从旧到新
+
+	// 每个玩家独立的随机数生成器
+	random *rand.Rand
+}
+
+func CreateUserGachaManager(owner *data.User) *UserGachaManager {
+	return &UserGachaManager{
+		owner:                 owner,
+		UserModuleManagerBase: *data.CreateUserModuleManagerBase(owner),
+
+		bannerData: make(map[int32]*public_protocol_pbdesc.DGachaBannerData),
+		random:     rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())),
+	}
+}
+
+func (m *UserGachaManager) InitFromDB(ctx cd.RpcContext, _dbUser *private_protocol_pbdesc.DatabaseTableUser) cd.RpcResult {
+	gachaData := _dbUser.GetGachaData()
+	for _, bannerData := range gachaData.GetBannerData() {
+		m.bannerData[bannerData.GetBannerId()] = bannerData
+	}
+	m.drawHistory = gachaData.GetDrawHistory()
+
+	return cd.CreateRpcResultOk()
+}
+
+func (m *UserGachaManager) DumpToDB(ctx cd.RpcContext, _dbUser *private_protocol_pbdesc.DatabaseTableUser) cd.RpcResult {
+	gachaData := _dbUser.MutableGachaData()
+	gachaData.BannerData = make([]*public_protocol_pbdesc.DGachaBannerData, 0, len(m.bannerData))
+	for _, bannerData := range m.bannerData {
+		gachaData.BannerData = append(gachaData.BannerData, bannerData)
+	}
+	gachaData.DrawHistory = m.drawHistory
+
+	return cd.CreateRpcResultOk()
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+
+// gachaDrawPull 单抽的结果
+type gachaDrawPull struct {
+	roll      gachaRollResult
+	instances []*public_protocol_common.DItemInstance
+}
+
+func (m *UserGachaManager) GachaDraw(ctx cd.RpcContext, req *service_protocol.CSGachaDrawReq,
+	rspBody *service_protocol.SCGachaDrawRsp,
+) int32 {
+	drawCount := req.GetDrawCount()
+	if drawCount != int32(public_protocol_common.EnGachaDrawCount_EN_GACHA_DRAW_COUNT_SINGLE) &&
+		drawCount != int32(public_protocol_common.EnGachaDrawCount_EN_GACHA_DRAW_COUNT_TEN_PULL) {
+		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_GACHA_DRAW_COUNT_INVALID)
+	}
+
+	bannerRow, resultCode := m.checkBanner(ctx, req.GetBannerId())
+	if resultCode != 0 {
+		return resultCode
+	}
+
+	// 检查消耗
+	costCfg, costRatio := gachaDrawCost(bannerRow, drawCount)
+	var subGuard []*data.ItemSubGuard
+	if len(costCfg) != 0 {
+		result := m.GetOwner().CheckCostRatioItemCfg(ctx, req.GetExpectCostItems(), costCfg, costRatio)
+		if result.IsError() {
+			ctx.LogError("check gacha cost item failed", "banner_id", bannerRow.GetBannerId(), "error", result.GetResponseCode())
+			return result.GetResponseCode()
+		}
+
+		subGuard, result = m.GetOwner().CheckSubItem(ctx, req.GetExpectCostItems())
+		if result.IsError() {
+			ctx.LogError("check gacha sub item failed", "banner_id", bannerRow.GetBannerId(), "error", result.GetResponseCode())
+			return result.GetResponseCode()
+		}
+	}
+
+	// 在副本上逐抽计算保底，全部成功后才提交
+	bannerData := m.GetBannerData(bannerRow.GetBannerId())
+	state := gachaPityState{
+		pityCount:         bannerData.GetPityCount(),
+		featuredGuarantee: bannerData.GetFeaturedGuarantee(),
+	}
+	pulls := make([]gachaDrawPull, 0, drawCount)
+	rewardInstances := make([]*public_protocol_common.DItemInstance, 0, drawCount)
+	for i := int32(0); i < drawCount; i++ {
+		roll := gachaRoll(m.random, bannerRow, &state)
+		poolId := gachaPoolId(bannerRow, roll)
+		errCode, offsets := config.RandomWithPoolSource(m.random, poolId, 1, nil)
+		if errCode != 0 {
+			ctx.LogError("gacha random with pool failed", "banner_id", bannerRow.GetBannerId(), "pool_id", poolId, "error_code", errCode)
+			return int32(errCode)
+		}
+
+		instances, result := m.GetOwner().GenerateMultipleItemInstancesFromOffset(ctx, offsets, true)
+		if result.IsError() {
+			result.LogError(ctx, "generate gacha reward item instances failed", "banner_id", bannerRow.GetBannerId(), "pool_id", poolId)
+			return result.GetResponseCode()
+		}
+		if len(instances) == 0 {
+			ctx.LogError("gacha draw result is empty", "banner_id", bannerRow.GetBannerId(), "pool_id", poolId)
+			return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_GACHA_DRAW_RESULT_EMPTY)
+		}
+
+		pulls = append(pulls, gachaDrawPull{
+			roll:      roll,
+			instances: instances,
+		})
+		rewardInstances = append(rewardInstances, instances...)
+	}
+
+	addGuard, result := m.GetOwner().CheckAddItem(ctx, rewardInstances)
+	if result.IsError() {
+		result.LogError(ctx, "check add gacha reward items failed", "banner_id", bannerRow.GetBannerId())
+		return result.GetResponseCode()
+	}
+
+	// 扣除消耗
+	if len(subGuard) != 0 {
+		m.GetOwner().SubItem(ctx, subGuard, &data.ItemFlowReason{
+			MajorReason: int32(public_protocol_common.EnItemFlowReasonMajorType_EN_ITEM_FLOW_REASON_MAJOR_GACHA),
+			MinorReason: int32(public_protocol_common.EnItemFlowReasonMinorType_EN_ITEM_FLOW_REASON_MINOR_GACHA_DRAW_COST),
+			Parameter:   int64(bannerRow.GetBannerId()),
+		})
+	}
+
+	// 发放奖励
+	m.GetOwner().AddItem(ctx, addGuard, &data.ItemFlowReason{
+		MajorReason: int32(public_protocol_common.EnItemFlowReasonMajorType_EN_ITEM_FLOW_REASON_MAJOR_GACHA),
+		MinorReason: int32(public_protocol_common.EnItemFlowReasonMinorType_EN_ITEM_FLOW_REASON_MINOR_GACHA_DRAW_REWARD),
+		Parameter:   int64(bannerRow.GetBannerId()),
+	})
+
+	bannerData = m.mutableBannerData(bannerRow.GetBannerId())
+	bannerData.PityCount = state.pityCount
+	bannerData.FeaturedGuarantee = state.featuredGuarantee
+	bannerData.TotalDrawCount += int64(drawCount)
+
+	m.recordDraw(ctx, bannerRow, bannerData, pulls, req.GetExpectCostItems(), rspBody)
+
+	for _, guard := range addGuard {
+		rspBody.MergeRewardItems(guard.GetAddedItems())
+	}
+	rspBody.BannerData = bannerData
+	return 0
+}
+
+// checkBanner 检查卡池是否存在且已开放
+func (m *UserGachaManager) checkBanner(ctx cd.RpcContext, bannerId int32) (*public_protocol_config.Readonly_ExcelGachaBanner, int32) {
+	bannerRow := config.GetConfigManager().GetCurrentConfigGroup().GetExcelGachaBannerByBannerId(bannerId)
+	if bannerRow == nil {
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_GACHA_BANNER_NOT_FOUND)
+	}
+
+	if !bannerRow.GetIsOpen() {
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_GACHA_BANNER_NOT_OPEN)
+	}
+
+	now := ctx.GetNow().Unix()
+	if now < bannerRow.GetStartTime().GetSeconds() ||
+		(bannerRow.GetEndTime().GetSeconds() > 0 && now >= bannerRow.GetEndTime().GetSeconds()) {
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_GACHA_BANNER_NOT_OPEN)
+	}
+
+	if logic_condition.HasLimitData(bannerRow.GetUnlockCondition()) {
+		conditionMgr := data.UserGetModuleManager[logic_condition.UserConditionManager](m.GetOwner())
+		if conditionMgr == nil {
+			ctx.LogError("UserConditionManager not found", "banner_id", bannerId)
+			return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+		}
+
+		rpcResult := conditionMgr.CheckBasicLimit(ctx, bannerRow.GetUnlockCondition(), logic_condition.CreateEmptyRuleCheckerRuntime())
+		if !rpcResult.IsOK() {
+			return nil, rpcResult.GetResponseCode()
+		}
+	}
+
+	return bannerRow, 0
+}
+
+// gachaDrawCost 十连配置了单独消耗时使用十连消耗，否则为单抽消耗乘以抽数
+func gachaDrawCost(bannerRow *public_protocol_config.Readonly_ExcelGachaBanner, drawCount int32) ([]*public_protocol_common.Readonly_DItemOffset, int64) {
+	if drawCount == int32(public_protocol_common.EnGachaDrawCount_EN_GACHA_DRAW_COUNT_TEN_PULL) && len(bannerRow.GetTenPullCost()) > 0 {
+		return bannerRow.GetTenPullCost(), 1
+	}
+	return bannerRow.GetSingleCost(), int64(drawCount)
+}
+
+func (m *UserGachaManager) mutableBannerData(bannerId int32) *public_protocol_pbdesc.DGachaBannerData {
+	bannerData, ok := m.bannerData[bannerId]
+	if ok && bannerData != nil {
+		return bannerData
+	}
+
+	bannerData = &public_protocol_pbdesc.DGachaBannerData{
+		BannerId: bannerId,
+	}
+	m.bannerData[bannerId] = bannerData
+	return bannerData
+}
+
+// recordDraw 写入抽卡记录并为每一抽发送抽卡流水
+func (m *UserGachaManager) recordDraw(ctx cd.RpcContext, bannerRow *public_protocol_config.Readonly_ExcelGachaBanner,
+	bannerData *public_protocol_pbdesc.DGachaBannerData, pulls []gachaDrawPull,
+	costItems []*public_protocol_common.DItemBasic, rspBody *service_protocol.SCGachaDrawRsp,
+) {
+	for i, pull := range pulls {
+		items := make([]*public_protocol_common.DItemBasic, 0, len(pull.instances))
+		for _, instance := range pull.instances {
+			items = append(items, instance.GetItemBasic())
+		}
+
+		record := &public_protocol_pbdesc.DGachaDrawRecord{
+			BannerId:   bannerRow.GetBannerId(),
+			DrawTime:   ctx.GetNow().Unix(),
+			Items:      items,
+			IsRare:     pull.roll.isRare,
+			IsFeatured: pull.roll.isFeatured,
+			PityCount:  pull.roll.pityCount,
+		}
+		m.appendDrawHistory(record)
+		rspBody.Results = append(rspBody.Results, record)
+
+		log := private_protocol_log.OperationSupportSystemLog{}
+		flow := log.MutableLog().MutableGachaDrawFlow()
+		flow.BannerId = bannerRow.GetBannerId()
+		flow.DrawCount = int32(len(pulls))
+		flow.BatchIndex = int32(i + 1)
+		flow.RewardItems = items
+		if i == 0 {
+			flow.CostItems = costItems
+		}
+		flow.IsRare = pull.roll.isRare
+		flow.IsFeatured = pull.roll.isFeatured
+		flow.PityCount = pull.roll.pityCount
+		flow.AfterPityCount = bannerData.GetPityCount()
+		flow.AfterFeaturedGuarantee = bannerData.GetFeaturedGuarantee()
+		flow.TotalDrawCount = bannerData.GetTotalDrawCount()
+		m.GetOwner().SendUserOssLog(ctx, &log)
+	}
+}
+
+func (m *UserGachaManager) appendDrawHistory(record *public_protocol_pbdesc.DGachaDrawRecord) {
+	m.drawHistory = append(m.drawHistory, record)
+	maxCount := int(private_protocol_pbdesc.EnSystemLimit_EN_SL_GACHA_DRAW_HISTORY_MAX_COUNT)
+	if len(m.drawHistory) > maxCount {
+		m.drawHistory = append(m.drawHistory[:0:0], m.drawHistory[len(m.drawHistory)-maxCount:]...)
+	}
+}
+
+func (m *UserGachaManager) GetBannerData(bannerId int32) *public_protocol_pbdesc.DGachaBannerData {
+	return m.bannerData[bannerId]
+}
+
+func (m *UserGachaManager) FetchBannerData() []*public_protocol_pbdesc.DGachaBannerData {
+	ret := make([]*public_protocol_pbdesc.DGachaBannerData, 0, len(m.bannerData))
+	for _, bannerData := range m.bannerData {
+		ret = append(ret, bannerData)
+	}
+	return ret
+}
+
+// GetDrawHistory 获取最近抽卡记录，按时间从新到旧
+func (m *UserGachaManager) GetDrawHistory() []*public_protocol_pbdesc.DGachaDrawRecord {
+	ret := make([]*public_protocol_pbdesc.DGachaDrawRecord, 0, len(m.drawHistory))
+	for i := len(m.drawHistory) - 1; i >= 0; i-- {
+		ret = append(ret, m.drawHistory[i])
+	}
+	return ret
+}
@@ -0,0 +1,80 @@
+package lobbysvr_logic_gacha_impl
+
+import (
+	"math/rand/v2"
+
+	public_protocol_common "github.com/atframework/atsf4g-go/component/protocol/public/common/protocol/common"
+	public_protocol_config "github.com/atframework/atsf4g-go/component/protocol/public/config/protocol/config"
+)
+
+// 抽卡概率万分比基数
+const gachaRateBase int32 = 10000
+
+// gachaPityState 单个卡池的保底状态
+type gachaPityState struct {
+	pityCount         int32 // 距离上次出高稀有度的抽数
+	featuredGuarantee bool  // 下一次高稀有度必定是 UP
+}
+
+// gachaRollResult 单抽的稀有度判定结果
+type gachaRollResult struct {
+	isRare     bool
+	isFeatured bool
+	pityCount  int32 // 本抽是距离上次高稀有度的第几抽
+}
+
+// gachaRareRate 计算距离上次高稀有度第 drawIndex 抽出高稀有度的概率(万分比)
+func gachaRareRate(pity *public_protocol_common.Readonly_DGachaPityCfg, drawIndex int32) int32 {
+	if pity.GetHardPity() > 0 && drawIndex >= pity.GetHardPity() {
+		return gachaRateBase
+	}
+
+	rate := pity.GetBaseRate()
+	if pity.GetSoftPityStart() > 0 && drawIndex >= pity.GetSoftPityStart() {
+		rate += (drawIndex - pity.GetSoftPityStart() + 1) * pity.GetSoftPityRateStep()
+	}
+	if rate > gachaRateBase {
+		rate = gachaRateBase
+	}
+	return rate
+}
+
+// gachaPoolId 根据判定结果选择随机池
+func gachaPoolId(bannerRow *public_protocol_config.Readonly_ExcelGachaBanner, roll gachaRollResult) int32 {
+	if roll.isFeatured {
+		return bannerRow.GetFeaturedPoolId()
+	}
+	if roll.isRare {
+		return bannerRow.GetRarePoolId()
+	}
+	return bannerRow.GetNormalPoolId()
+}
+
+// gachaRoll 判定一抽的稀有度并更新保底状态
+// 相同种子的 rng 和相同的初始状态得到相同的结果
+func gachaRoll(rng *rand.Rand, bannerRow *public_protocol_config.Readonly_ExcelGachaBanner, state *gachaPityState) gachaRollResult {
+	drawIndex := state.pityCount + 1
+	ret := gachaRollResult{
+		pityCount: drawIndex,
+	}
+
+	if rng.Int32N(gachaRateBase) >= gachaRareRate(bannerRow.GetPity(), drawIndex) {
+		state.pityCount = drawIndex
+		return ret
+	}
+
+	ret.isRare = true
+	state.pityCount = 0
+	if bannerRow.GetFeaturedPoolId() == 0 {
+		return ret
+	}
+
+	featured := bannerRow.GetFeatured()
+	if state.featuredGuarantee || rng.Int32N(gachaRateBase) < featured.GetFeaturedRate() {
+		ret.isFeatured = true
+		state.featuredGuarantee = false
+	} else if featured.GetGuaranteeAfterMiss() {
+		state.featuredGuarantee = true
+	}
+	return ret
+}
@@ -0,0 +1,123 @@
+package lobbysvr_logic_gacha_impl
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	public_protocol_common "github.com/atframework/atsf4g-go/component/protocol/public/common/protocol/common"
+	public_protocol_config "github.com/atframework/atsf4g-go/component/protocol/public/config/protocol/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestGachaBanner(pity *public_protocol_common.DGachaPityCfg, featured *public_protocol_common.DGachaFeaturedCfg) *public_protocol_config.Readonly_ExcelGachaBanner {
+	banner := &public_protocol_config.ExcelGachaBanner{
+		BannerId:     1,
+		NormalPoolId: 2300001,
+		RarePoolId:   2300002,
+		Pity:         pity,
+		Featured:     featured,
+	}
+	if featured != nil {
+		banner.FeaturedPoolId = 2300003
+	}
+	return banner.ToReadonly()
+}
+
+// TestGachaRareRateCurve 测试保底概率曲线
+// Scenario: 软保底前为基础概率，软保底后逐抽递增且不超过 100%，硬保底必出
+func TestGachaRareRateCurve(t *testing.T) {
+	// Arrange
+	banner := newTestGachaBanner(&public_protocol_common.DGachaPityCfg{
+		BaseRate:         60,
+		SoftPityStart:    74,
+		SoftPityRateStep: 600,
+		HardPity:         90,
+	}, nil)
+
+	// Act & Assert
+	assert.Equal(t, int32(60), gachaRareRate(banner.GetPity(), 1))
+	assert.Equal(t, int32(60), gachaRareRate(banner.GetPity(), 73))
+	assert.Equal(t, int32(660), gachaRareRate(banner.GetPity(), 74))
+	assert.Equal(t, int32(1260), gachaRareRate(banner.GetPity(), 75))
+	assert.Equal(t, int32(9660), gachaRareRate(banner.GetPity(), 89))
+	assert.Equal(t, gachaRateBase, gachaRareRate(banner.GetPity(), 90))
+}
+
+// TestGachaRollHardPity 测试硬保底
+// Scenario: 基础概率为 0 时只在硬保底的那一抽出高稀有度，之后重新计数
+func TestGachaRollHardPity(t *testing.T) {
+	// Arrange
+	banner := newTestGachaBanner(&public_protocol_common.DGachaPityCfg{HardPity: 10}, nil)
+	rng := rand.New(rand.NewPCG(1, 2))
+	state := gachaPityState{}
+
+	// Act
+	rarePityCounts := make([]int32, 0)
+	for i := 0; i < 30; i++ {
+		roll := gachaRoll(rng, banner, &state)
+		if roll.isRare {
+			rarePityCounts = append(rarePityCounts, roll.pityCount)
+		}
+	}
+
+	// Assert
+	assert.Equal(t, []int32{10, 10, 10}, rarePityCounts)
+	assert.Equal(t, int32(0), state.pityCount)
+}
+
+// TestGachaRollFeaturedGuarantee 测试大保底
+// Scenario: UP 概率为 0 时，歪一次之后下一次高稀有度必定是 UP
+func TestGachaRollFeaturedGuarantee(t *testing.T) {
+	// Arrange
+	banner := newTestGachaBanner(&public_protocol_common.DGachaPityCfg{BaseRate: gachaRateBase},
+		&public_protocol_common.DGachaFeaturedCfg{GuaranteeAfterMiss: true})
+	rng := rand.New(rand.NewPCG(1, 2))
+	state := gachaPityState{}
+
+	// Act
+	first := gachaRoll(rng, banner, &state)
+	guaranteeAfterMiss := state.featuredGuarantee
+	second := gachaRoll(rng, banner, &state)
+
+	// Assert
+	assert.True(t, first.isRare)
+	assert.False(t, first.isFeatured)
+	assert.True(t, guaranteeAfterMiss)
+	assert.True(t, second.isFeatured)
+	assert.False(t, state.featuredGuarantee)
+	assert.Equal(t, banner.GetFeaturedPoolId(), gachaPoolId(banner, second))
+}
+
+// TestGachaRollSeededProbability 测试固定种子下的出货概率
+// Scenario: 相同种子结果完全一致，无保底时出货率和 UP 率接近配置值
+func TestGachaRollSeededProbability(t *testing.T) {
+	// Arrange
+	banner := newTestGachaBanner(&public_protocol_common.DGachaPityCfg{BaseRate: 600},
+		&public_protocol_common.DGachaFeaturedCfg{FeaturedRate: 5000})
+	const drawCount = 100000
+
+	draw := func(seed uint64) (rareCount int, featuredCount int) {
+		rng := rand.New(rand.NewPCG(seed, seed))
+		state := gachaPityState{}
+		for i := 0; i < drawCount; i++ {
+			roll := gachaRoll(rng, banner, &state)
+			if roll.isRare {
+				rareCount++
+			}
+			if roll.isFeatured {
+				featuredCount++
+			}
+		}
+		return
+	}
+
+	// Act
+	rareCount, featuredCount := draw(41)
+	sameRareCount, sameFeaturedCount := draw(41)
+
+	// Assert
+	assert.Equal(t, rareCount, sameRareCount)
+	assert.Equal(t, featuredCount, sameFeaturedCount)
+	assert.InDelta(t, 0.06, float64(rareCount)/drawCount, 0.005)
+	assert.InDelta(t, 0.5, float64(featuredCount)/float64(rareCount), 0.03)
+}
@@ -0,0 +1,21 @@
+package lobbysvr_logic_gacha
+
+import (
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
+	service_protocol "github.com/atframework/atsf4g-go/service-lobbysvr/protocol/public/protocol/pbdesc"
+)
+
+type UserGachaManager interface {
+	data.UserModuleManagerImpl
+
+	// 单抽或十连，十连按顺序逐抽计算保底
+	GachaDraw(ctx cd.RpcContext, req *service_protocol.CSGachaDrawReq, rspBody *service_protocol.SCGachaDrawRsp) int32
+
+	GetBannerData(bannerId int32) *public_protocol_pbdesc.DGachaBannerData
+	FetchBannerData() []*public_protocol_pbdesc.DGachaBannerData
+
+	// 最近抽卡记录，按时间从新到旧
+	GetDrawHistory() []*public_protocol_pbdesc.DGachaDrawRecord
+}
@@ -12,6 +12,8 @@ import (
 
 // GlobalMailManagerForSync 定义 GlobalMailManager 所需的接口
 type GlobalMailManagerForSync interface {
+	LoadGlobalMailTable(ctx cd.AwaitableContext, zoneId uint32, majorType int32) (*private_protocol_pbdesc.DatabaseTableGlobalMail, cd.RpcResult)
+	SaveGlobalMailTable(ctx cd.RpcContext, table *private_protocol_pbdesc.DatabaseTableGlobalMail) cd.RpcResult
 	UpdateFromDB(ctx cd.RpcContext, zoneId uint32, majorType int32, blobData *private_protocol_pbdesc.DatabaseGlobalMailBlobData, rewriteDbData bool) bool
 	FetchAllUnloadedMails(ctx cd.RpcContext) []int64
 	SetMailContentLoaded(mailId int64)
@@ -53,43 +55,37 @@ func (t *TaskActionGlobalMailSyncObjects) Run(_startData *cd.DispatcherStartData
 	}
 
 	ctx := t.GetAwaitableContext()
-	now := ctx.GetNow().Unix()
-	t.fetchTimepoint = now
-
-	// TODO: 服务发现功能尚未实现，暂时假设当前节点为主节点
-	isZoneMaster := true   // TODO: 从服务发现获取
-	isGlobalMaster := true // TODO: 从服务发现获取
+	t.fetchTimepoint = ctx.GetNow().Unix()
+	t.fetchMailNumber, t.removeMailNumber = SyncGlobalMails(ctx, t.manager, false)
+	return nil
+}
 
-	localZoneId := atframework_component_config.GetConfigManager().GetLogicId()
+// SyncGlobalMails 拉取全服和本进程服务的各大区邮件记录和内容，返回拉取和删除的邮件数量
+// rewriteDbData 为 true 时回写过期清理后的记录并删除已经移除的邮件内容，只能由持有定时任务租约的进程执行
+func SyncGlobalMails(ctx cd.AwaitableContext, manager GlobalMailManagerForSync, rewriteDbData bool) (int, int) {
+	fetchMailNumber := 0
+	removeMailNumber := 0
 
 	// 拉取邮件记录
-	// zone_id = 0 表示全服邮件，local_zone_id 表示本区邮件
-	zoneIds := []uint32{0, localZoneId}
+	// zone_id = 0 表示全服邮件，其余为本进程服务的各大区（含合服并入的原大区）邮件
+	zoneIds := append([]uint32{0}, atframework_component_config.GetConfigManager().GetServedZoneIds()...)
 	majorTypes := atframework_component_config.GetAllGlobalMailMajorTypesCurrent()
 	for _, zoneId := range zoneIds {
 		for _, majorType := range majorTypes {
-			dbData, retResult := db.DatabaseTableGlobalMailLoadWithZoneIdMajorType(ctx, zoneId, majorType)
+			dbData, retResult := manager.LoadGlobalMailTable(ctx, zoneId, majorType)
 			if retResult.IsError() {
-				if retResult.GetResponseCode() != int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND) {
-					ctx.LogError("TaskActionGlobalMailSyncObjects load global mail failed",
-						"zone_id", zoneId,
-						"major_type", majorType,
-						"error", retResult.GetErrorString())
-					continue
-				}
-				dbData = &private_protocol_pbdesc.DatabaseTableGlobalMail{
-					ZoneId:    zoneId,
-					MajorType: majorType,
-				}
+				ctx.LogError("TaskActionGlobalMailSyncObjects load global mail failed",
+					"zone_id", zoneId,
+					"major_type", majorType,
+					"error", retResult.GetErrorString())
+				continue
 			}
 
-			// 判断是否需要写回数据库
-			rewriteDbData := (zoneId == 0 && isGlobalMaster) || (zoneId == localZoneId && isZoneMaster)
-			needUpdate := t.manager.UpdateFromDB(ctx, zoneId, majorType, dbData.MutableJobData(), rewriteDbData)
+			needUpdate := manager.UpdateFromDB(ctx, zoneId, majorType, dbData.MutableJobData(), rewriteDbData)
 
-			// 主节点更新删除无效数据
+			// 更新删除无效数据，由共享缓存回写，CAS 冲突时丢弃，下一轮重新加载
 			if needUpdate && rewriteDbData {
-				retResult = db.DatabaseTableGlobalMailUpdateZoneIdMajorType(ctx, dbData)
+				retResult = manager.SaveGlobalMailTable(ctx, dbData)
 				if retResult.IsError() {
 					ctx.LogInfo("TaskActionGlobalMailSyncObjects replace global mail failed, will retry on next round",
 						"zone_id", zoneId,
@@ -104,27 +100,22 @@ func (t *TaskActionGlobalMailSyncObjects) Run(_startData *cd.DispatcherStartData
 		}
 	}
 
-	fetchResult := t.fetchMailContents()
+	fetchResult := fetchMailContents(ctx, manager)
 	if fetchResult >= 0 {
-		t.fetchMailNumber += fetchResult
+		fetchMailNumber += fetchResult
 	}
 
-	removeResult := t.cleanupRemovedMails()
+	removeResult := cleanupRemovedMails(ctx, manager)
 	if removeResult >= 0 {
-		t.removeMailNumber += removeResult
+		removeMailNumber += removeResult
 	}
 
-	return nil
+	return fetchMailNumber, removeMailNumber
 }
 
 // fetchMailContents 拉取邮件内容
-func (t *TaskActionGlobalMailSyncObjects) fetchMailContents() int {
-	if t.manager == nil {
-		return 0
-	}
-	ctx := t.GetAwaitableContext()
-
-	mailUnloaded := t.manager.FetchAllUnloadedMails(ctx)
+func fetchMailContents(ctx cd.AwaitableContext, manager GlobalMailManagerForSync) int {
+	mailUnloaded := manager.FetchAllUnloadedMails(ctx)
 	if len(mailUnloaded) == 0 {
 		return 0
 	}
@@ -150,10 +141,10 @@ func (t *TaskActionGlobalMailSyncObjects) fetchMailContents() int {
 		}
 
 		delete(undoMails, mailId)
-		t.manager.SetMailContentLoaded(mailId)
+		manager.SetMailContentLoaded(mailId)
 
 		// 获取原始邮件数据并更新内容
-		mailData := t.manager.GetMailRaw(mailId)
+		mailData := manager.GetMailRaw(mailId)
 		if mailData != nil {
 
 			record := mailData.Record
@@ -185,20 +176,20 @@ func (t *TaskActionGlobalMailSyncObjects) fetchMailContents() int {
 
 	// 处理无效的脏数据
 	for dirtyMailId := range undoMails {
-		mailData := t.manager.GetMailRaw(dirtyMailId)
+		mailData := manager.GetMailRaw(dirtyMailId)
 		if mailData != nil {
 			// 增加错误计数
 			record := mailData.Record
 			if record != nil {
 				record.FetchErrorCount = record.GetFetchErrorCount() + 1
 				if record.GetFetchErrorCount() > global_mail_data.EN_CL_MAIL_PLAYER_TOLERANCE_ERROR_COUNT {
-					t.manager.RemoveGlobalMail(ctx, dirtyMailId)
+					manager.RemoveGlobalMail(ctx, dirtyMailId)
 				}
 			} else {
-				t.manager.RemoveGlobalMail(ctx, dirtyMailId)
+				manager.RemoveGlobalMail(ctx, dirtyMailId)
 			}
 		} else {
-			t.manager.RemoveGlobalMail(ctx, dirtyMailId)
+			manager.RemoveGlobalMail(ctx, dirtyMailId)
 		}
 	}
 
@@ -206,19 +197,13 @@ func (t *TaskActionGlobalMailSyncObjects) fetchMailContents() int {
 }
 
 // cleanupRemovedMails 清理待删除的邮件内容
-func (t *TaskActionGlobalMailSyncObjects) cleanupRemovedMails() int {
-	if t.manager == nil {
-		return 0
-	}
-
+func cleanupRemovedMails(ctx cd.AwaitableContext, manager GlobalMailManagerForSync) int {
 	ret := 0
-	ctx := t.GetAwaitableContext()
-
-	pendingToRemoveList := t.manager.GetPendingToRemoveContentsList()
+	pendingToRemoveList := manager.GetPendingToRemoveContentsList()
 
 	for _, delMailId := range pendingToRemoveList {
 		if delMailId == 0 {
-			t.manager.RemovePendingToRemoveContent(delMailId)
+			manager.RemovePendingToRemoveContent(delMailId)
 			ret++
 			continue
 		}
@@ -238,7 +223,7 @@ func (t *TaskActionGlobalMailSyncObjects) cleanupRemovedMails() int {
 				"mail_id", delMailId)
 		}
 
-		t.manager.RemovePendingToRemoveContent(delMailId)
+		manager.RemovePendingToRemoveContent(delMailId)
 		ret++
 	}
 
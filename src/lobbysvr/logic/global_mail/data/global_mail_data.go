@@ -14,6 +14,10 @@ const (
 	// EN_CL_MAIL_GLOBAL_JOBS_TASK_INTERVAL 全局邮件异步任务间隔（秒）
 	EN_CL_MAIL_GLOBAL_JOBS_TASK_INTERVAL int64 = 60
 
+	// EN_CL_MAIL_GLOBAL_TABLE_CACHE_TTL 全服邮件表本地缓存时间（秒），GM 写入后会通过失效通知刷新，
+	// 关闭失效通知时新的全服邮件最多延迟这么久
+	EN_CL_MAIL_GLOBAL_TABLE_CACHE_TTL int64 = 600
+
 	// EN_CL_MAIL_GLOBAL_LEAK_CHECK_TIMEOUT 全局邮件泄漏检查超时（秒）
 	EN_CL_MAIL_GLOBAL_LEAK_CHECK_TIMEOUT int64 = 300
 
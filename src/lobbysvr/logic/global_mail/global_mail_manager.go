@@ -23,6 +23,12 @@ type GlobalMailManager interface {
 	// ResetAsyncJobsProtect 重置异步任务保护
 	ResetAsyncJobsProtect()
 
+	// LoadGlobalMailTable 通过共享缓存读取全服邮件表，返回副本，不存在时返回空表
+	LoadGlobalMailTable(ctx cd.AwaitableContext, zoneId uint32, majorType int32) (*private_protocol_pbdesc.DatabaseTableGlobalMail, cd.RpcResult)
+
+	// SaveGlobalMailTable 修改共享缓存中的全服邮件表，由 TableCacheManager 回写
+	SaveGlobalMailTable(ctx cd.RpcContext, table *private_protocol_pbdesc.DatabaseTableGlobalMail) cd.RpcResult
+
 	// UpdateFromDB 从数据库更新全局邮件
 	UpdateFromDB(ctx cd.RpcContext, zoneId uint32, majorType int32, blobData *private_protocol_pbdesc.DatabaseGlobalMailBlobData, rewriteDbData bool) bool
 
@@ -10,6 +10,7 @@ import (
 
 	lu "github.com/atframework/atframe-utils-go/lang_utility"
 	config "github.com/atframework/atsf4g-go/component/config"
+	db "github.com/atframework/atsf4g-go/component/db"
 	cd "github.com/atframework/atsf4g-go/component/dispatcher"
 	mail_util "github.com/atframework/atsf4g-go/component/mail"
 	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
@@ -17,6 +18,7 @@ import (
 	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
 	uc "github.com/atframework/atsf4g-go/component/user_controller"
 	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
+	logic_cron "github.com/atframework/atsf4g-go/service-lobbysvr/logic/cron"
 	global_mail_action "github.com/atframework/atsf4g-go/service-lobbysvr/logic/global_mail/action"
 	global_mail_data "github.com/atframework/atsf4g-go/service-lobbysvr/logic/global_mail/data"
 	logic_mail "github.com/atframework/atsf4g-go/service-lobbysvr/logic/mail"
@@ -28,6 +30,10 @@ type GlobalMailManager struct {
 	libatapp.AppModuleBase
 
 	globalMailSyncTask lu.AtomicInterface[cd.TaskActionImpl]
+	cleanupRunning     bool // 过期清理的定时任务正在执行，期间不启动普通的同步任务
+
+	// 全服邮件表的共享缓存，GM 写入时会通知失效
+	globalMailTables *db.TableCache[mail_util.GlobalMailTableCacheKey, *private_protocol_pbdesc.DatabaseTableGlobalMail]
 
 	// 异步任务相关
 	// TODO: mail_async_task_ 异步任务类型需要适配Go的协程模型
@@ -43,8 +49,13 @@ type GlobalMailManager struct {
 	pendingToRemoveContents map[int64]struct{}                   // 待移除内容的邮件ID集合
 }
 
+// globalMailCleanupCronJobName 全服邮件的过期清理和回写，多个 lobbysvr 中只有持有租约的进程执行
+const globalMailCleanupCronJobName = "global_mail_cleanup"
+
 func init() {
 	var _ libatapp.AppModuleImpl = (*GlobalMailManager)(nil)
+
+	logic_cron.RegisterCronJob(globalMailCleanupCronJobName, "* * * * *", logic_cron.CronCatchUpOnce, runGlobalMailCleanup)
 }
 
 // NewGlobalMailManager 创建全局邮件管理器实例
@@ -64,10 +75,37 @@ func NewGlobalMailManager(owner libatapp.AppImpl) *GlobalMailManager {
 		mailUnloadedIndex:         make(mail_data.MailIndex),
 		pendingToRemoveContents:   make(map[int64]struct{}),
 	}
+	ret.globalMailTables = db.CreateTableCache(db.TableCacheOptions[mail_util.GlobalMailTableCacheKey, *private_protocol_pbdesc.DatabaseTableGlobalMail]{
+		Name: mail_util.GlobalMailTableCacheName,
+		TTL:  time.Duration(global_mail_data.EN_CL_MAIL_GLOBAL_TABLE_CACHE_TTL) * time.Second,
+		Load: loadGlobalMailTable,
+		Save: func(pipeline *db.Pipeline, _ mail_util.GlobalMailTableCacheKey, table *private_protocol_pbdesc.DatabaseTableGlobalMail,
+			currentCASVersion *uint64,
+		) *db.PipelineResult {
+			return db.DatabaseTableGlobalMailPipelineReplaceZoneIdMajorType(pipeline, table, currentCASVersion, false)
+		},
+	})
 	return ret
 }
 
+// loadGlobalMailTable 不存在的记录也缓存为空表，新增时 GM 会通知失效
+func loadGlobalMailTable(ctx cd.AwaitableContext, key mail_util.GlobalMailTableCacheKey) (*private_protocol_pbdesc.DatabaseTableGlobalMail, uint64, cd.RpcResult) {
+	table, casVersion, result := db.DatabaseTableGlobalMailLoadWithZoneIdMajorType(ctx, key.ZoneId, key.MajorType)
+	if result.GetResponseCode() == int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND) {
+		return &private_protocol_pbdesc.DatabaseTableGlobalMail{
+			ZoneId:    key.ZoneId,
+			MajorType: key.MajorType,
+		}, 0, cd.CreateRpcResultOk()
+	}
+	return table, casVersion, result
+}
+
 func (m *GlobalMailManager) Init(parent context.Context) error {
+	// 需要 TableCacheManager 回写同步任务的修改
+	if err := db.RegisterTableCache(m.GetApp(), m.globalMailTables); err != nil {
+		m.GetApp().GetDefaultLogger().LogError("GlobalMailManager register table cache failed", "error", err)
+		return err
+	}
 	return nil
 }
 
@@ -125,7 +163,7 @@ func (m *GlobalMailManager) TryToStartAsyncJobs() {
 		return
 	}
 
-	if m.IsAsyncTaskRunning() {
+	if m.IsAsyncTaskRunning() || m.cleanupRunning {
 		return
 	}
 
@@ -154,6 +192,43 @@ func (m *GlobalMailManager) TryToStartAsyncJobs() {
 	m.taskNextTimepoint = now + interval - (interval >> 2) + randomOffset
 }
 
+// runGlobalMailCleanup 定时任务，同步的同时回写过期清理后的邮件记录并删除已经移除的邮件内容
+// 普通的同步任务正在执行时返回错误，由定时任务稍后重试
+func runGlobalMailCleanup(ctx cd.AwaitableContext, _ time.Time) cd.RpcResult {
+	m := libatapp.AtappGetModule[*GlobalMailManager](ctx.GetApp())
+	if m == nil {
+		return cd.CreateRpcResultOk()
+	}
+	if m.IsAsyncTaskRunning() || m.cleanupRunning {
+		return cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM_BUSY)
+	}
+
+	m.cleanupRunning = true
+	defer func() {
+		m.cleanupRunning = false
+	}()
+
+	fetchTimepoint := ctx.GetNow().Unix()
+	fetchMailNumber, removeMailNumber := global_mail_action.SyncGlobalMails(ctx, m, true)
+	m.SetLastSuccessFetchTimepoint(fetchTimepoint)
+	ctx.LogInfo("global mail cleanup success", "fetch_mail_number", fetchMailNumber, "remove_mail_number", removeMailNumber)
+	return cd.CreateRpcResultOk()
+}
+
+// LoadGlobalMailTable 通过共享缓存读取全服邮件表，返回副本，修改后需要调用 SaveGlobalMailTable
+func (m *GlobalMailManager) LoadGlobalMailTable(ctx cd.AwaitableContext, zoneId uint32, majorType int32) (*private_protocol_pbdesc.DatabaseTableGlobalMail, cd.RpcResult) {
+	table, result := m.globalMailTables.Get(ctx, mail_util.GlobalMailTableCacheKey{ZoneId: zoneId, MajorType: majorType})
+	if result.IsError() {
+		return nil, result
+	}
+	return proto.Clone(table).(*private_protocol_pbdesc.DatabaseTableGlobalMail), result
+}
+
+// SaveGlobalMailTable 修改共享缓存中的全服邮件表，由 TableCacheManager 回写
+func (m *GlobalMailManager) SaveGlobalMailTable(ctx cd.RpcContext, table *private_protocol_pbdesc.DatabaseTableGlobalMail) cd.RpcResult {
+	return m.globalMailTables.Set(ctx, mail_util.GlobalMailTableCacheKey{ZoneId: table.GetZoneId(), MajorType: table.GetMajorType()}, table)
+}
+
 // ResetAsyncJobsProtect 重置异步任务保护
 func (m *GlobalMailManager) ResetAsyncJobsProtect() {
 	m.taskNextTimepoint = 0
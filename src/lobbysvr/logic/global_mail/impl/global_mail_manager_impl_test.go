@@ -1,15 +1,13 @@
 package lobbysvr_logic_global_mail_impl
 
 import (
-	"context"
-	"log/slog"
 	"testing"
 	"time"
 
-	cd "github.com/atframework/atsf4g-go/component/dispatcher"
 	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
 	public_protocol_common "github.com/atframework/atsf4g-go/component/protocol/public/common/protocol/common"
 	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	test_utility "github.com/atframework/atsf4g-go/component/test_utility"
 	global_mail_data "github.com/atframework/atsf4g-go/service-lobbysvr/logic/global_mail/data"
 	mail_data "github.com/atframework/atsf4g-go/service-lobbysvr/logic/mail/data"
 	"github.com/atframework/libatapp-go"
@@ -18,48 +16,14 @@ import (
 
 // ==================== Mock RpcContext ====================
 
-// testRpcContext 用于测试的模拟 RpcContext
-type testRpcContext struct {
-	now time.Time
-	app libatapp.AppImpl
+// newTestRpcContext 创建固定为当前时间的 RpcContext
+func newTestRpcContext(app libatapp.AppImpl) *test_utility.MockRpcContext {
+	return test_utility.NewMockRpcContextWithNow(app, time.Now())
 }
 
-func newTestRpcContext(app libatapp.AppImpl) *testRpcContext {
-	return &testRpcContext{
-		now: time.Now(),
-		app: app,
-	}
-}
-
-func newTestRpcContextWithNow(app libatapp.AppImpl, now time.Time) *testRpcContext {
-	return &testRpcContext{
-		now: now,
-		app: app,
-	}
-}
-
-func (m *testRpcContext) GetNow() time.Time                                          { return m.now }
-func (m *testRpcContext) GetSysNow() time.Time                                       { return m.now }
-func (m *testRpcContext) GetApp() libatapp.AppImpl                                   { return m.app }
-func (m *testRpcContext) GetAction() cd.TaskActionImpl                               { return nil }
-func (m *testRpcContext) BindAction(_ cd.TaskActionImpl)                             {}
-func (m *testRpcContext) GetContext() context.Context                                { return context.Background() }
-func (m *testRpcContext) GetCancelFn() context.CancelFunc                            { return nil }
-func (m *testRpcContext) SetContext(_ context.Context)                               {}
-func (m *testRpcContext) SetCancelFn(_ context.CancelFunc)                           {}
-func (m *testRpcContext) SetContextCancelFn(_ context.Context, _ context.CancelFunc) {}
-
-func (m *testRpcContext) LogWithLevelContextWithCaller(_ uintptr, _ context.Context, _ slog.Level, _ string, _ ...any) {
+func newTestRpcContextWithNow(app libatapp.AppImpl, now time.Time) *test_utility.MockRpcContext {
+	return test_utility.NewMockRpcContextWithNow(app, now)
 }
-func (m *testRpcContext) LogWithLevelWithCaller(_ uintptr, _ slog.Level, _ string, _ ...any) {}
-func (m *testRpcContext) LogErrorContext(_ context.Context, _ string, _ ...any)              {}
-func (m *testRpcContext) LogError(_ string, _ ...any)                                        {}
-func (m *testRpcContext) LogWarnContext(_ context.Context, _ string, _ ...any)               {}
-func (m *testRpcContext) LogWarn(_ string, _ ...any)                                         {}
-func (m *testRpcContext) LogInfoContext(_ context.Context, _ string, _ ...any)               {}
-func (m *testRpcContext) LogInfo(_ string, _ ...any)                                         {}
-func (m *testRpcContext) LogDebugContext(_ context.Context, _ string, _ ...any)              {}
-func (m *testRpcContext) LogDebug(_ string, _ ...any)                                        {}
 
 // ==================== 辅助函数 ====================
 
@@ -0,0 +1,44 @@
+// Copyright 2026 atframework
+
+package lobbysvr_logic_guild_action
+
+import (
+	"fmt"
+
+	component_dispatcher "github.com/atframework/atsf4g-go/component/dispatcher"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	user_controller "github.com/atframework/atsf4g-go/component/user_controller"
+	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
+	logic_guild "github.com/atframework/atsf4g-go/service-lobbysvr/logic/guild"
+	service_protocol "github.com/atframework/atsf4g-go/service-lobbysvr/protocol/public/protocol/pbdesc"
+)
+
+type TaskActionGuildHandleApplication struct {
+	user_controller.TaskActionCSBase[*service_protocol.CSGuildHandleApplicationReq, *service_protocol.SCGuildHandleApplicationRsp]
+}
+
+func (t *TaskActionGuildHandleApplication) Name() string {
+	return "TaskActionGuildHandleApplication"
+}
+
+func (t *TaskActionGuildHandleApplication) Run(_startData *component_dispatcher.DispatcherStartData) error {
+	user, ok := t.GetUser().(*data.User)
+	if !ok || user == nil {
+		t.SetResponseCode(int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_USER_NOT_FOUND))
+		return fmt.Errorf("user not found")
+	}
+
+	manager := data.UserGetModuleManager[logic_guild.UserGuildManager](user)
+	if manager == nil {
+		t.SetResponseError(public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+		return fmt.Errorf("user guild manager not found")
+	}
+
+	requestBody := t.GetRequestBody()
+	guild, resultCode := manager.HandleApplication(t.GetAwaitableContext(), requestBody.GetUserId(), requestBody.GetAccept())
+	t.SetResponseCode(resultCode)
+	if resultCode == 0 {
+		t.MutableResponseBody().Guild = guild
+	}
+	return nil
+}
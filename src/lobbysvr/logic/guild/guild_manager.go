@@ -0,0 +1,472 @@
+// Copyright 2026 atframework
+// @brief 公会操作入口，操作在持有公会路由租约的 lobbysvr 上执行，其他进程通过 pub/sub 转发并等待结果
+
+package lobbysvr_logic_guild
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/atframework/libatapp-go"
+
+	lu "github.com/atframework/atframe-utils-go/lang_utility"
+	async_jobs "github.com/atframework/atsf4g-go/component/async_jobs"
+	config "github.com/atframework/atsf4g-go/component/config"
+	db "github.com/atframework/atsf4g-go/component/db"
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	private_protocol_common "github.com/atframework/atsf4g-go/component/protocol/private/common/protocol/common"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	uuid "github.com/atframework/atsf4g-go/component/uuid"
+)
+
+// GuildManager 公会模块
+// 通过存储后端的发布订阅转发，channel 为空时不转发，其他进程持有的公会返回 EN_ERR_GUILD_SERVICE_BUSY
+type GuildManager struct {
+	libatapp.AppModuleBase
+
+	pubsub        *db.PubSubChannel
+	serverChannel string
+
+	pendingForwardsLock sync.Mutex
+	pendingForwards     map[uint64]cd.TaskActionImpl // 等待转发结果的任务，key 为 await sequence
+}
+
+func init() {
+	var _ libatapp.AppModuleImpl = (*GuildManager)(nil)
+}
+
+// CreateGuildManager 创建公会模块，需要在 StorageBackendModule 之后注册，并且先调用 InitGuildRouterManager
+func CreateGuildManager(owner libatapp.AppImpl) *GuildManager {
+	return &GuildManager{
+		AppModuleBase:   libatapp.CreateAppModuleBase(owner),
+		pendingForwards: make(map[uint64]cd.TaskActionImpl),
+	}
+}
+
+// GetGuildManager 获取公会模块
+func GetGuildManager(app libatapp.AppImpl) *GuildManager {
+	return libatapp.AtappGetModule[*GuildManager](app)
+}
+
+func (m *GuildManager) Name() string {
+	return "GuildManager"
+}
+
+func (m *GuildManager) Init(parent context.Context) error {
+	channel := getGuildConfig().GetChannel()
+	if channel == "" {
+		return nil
+	}
+
+	m.serverChannel = getGuildServerChannel(m.GetApp(), uint64(config.GetConfigManager().GetLogicId()))
+	pubsub, err := db.SubscribePubSubChannel(m.GetApp(), m.Name(), []string{m.serverChannel}, m.receiveForwardMessage)
+	if err != nil {
+		return err
+	}
+	m.pubsub = pubsub
+	return nil
+}
+
+func (m *GuildManager) Cleanup() {
+	m.pubsub.Close()
+	m.pubsub = nil
+}
+
+func getGuildServerChannel(app libatapp.AppImpl, serverId uint64) string {
+	return db.GetPubSubServerChannelName(app, getGuildConfig().GetChannel(), serverId)
+}
+
+func createGuildOperationResponse(code public_protocol_pbdesc.EnErrorCode) *private_protocol_pbdesc.GuildOperationResponse {
+	return &private_protocol_pbdesc.GuildOperationResponse{ResultCode: int32(code)}
+}
+
+// CreateGuild 占用公会名并写入新公会，路由租约由第一次操作时接管
+func (m *GuildManager) CreateGuild(ctx cd.AwaitableContext, zoneId uint32, name string,
+	leaderUserId uint64, leaderZoneId uint32,
+) (*public_protocol_pbdesc.DGuildData, int32) {
+	guildId, result := uuid.GenerateGlobalUniqueID(ctx,
+		private_protocol_pbdesc.EnGlobalUUIDMajorType_EN_GLOBAL_UUID_MAT_GUILD_ID,
+		private_protocol_pbdesc.EnGlobalUUIDMinorType_EN_GLOBAL_UUID_MIT_DEFAULT,
+		private_protocol_pbdesc.EnGlobalUUIDPatchType_EN_GLOBAL_UUID_PT_DEFAULT)
+	if result.IsError() {
+		result.LogError(ctx, "alloc guild id failed")
+		return nil, result.GetResponseCode()
+	}
+
+	mappingTable := &private_protocol_pbdesc.DatabaseTableNameMapping{
+		TypeId: uint32(private_protocol_common.NameMappingType_EN_NAME_MAPPING_TYPE_GUILD_NAME),
+		Name:   name,
+		ZoneId: zoneId,
+		MappingData: &private_protocol_pbdesc.DatabaseNameMappingBlobData{
+			MappingType: &private_protocol_pbdesc.DatabaseNameMappingBlobData_GuildMapping{
+				GuildMapping: &private_protocol_pbdesc.GuildMappingData{
+					ZoneId:  zoneId,
+					GuildId: guildId,
+				},
+			},
+		},
+	}
+	result, _ = db.DatabaseTableNameMappingAddTypeIdZoneIdName(ctx, mappingTable)
+	if result.IsError() {
+		if result.GetResponseCode() == int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_EXIST) {
+			return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_NAME_DUPLICATE)
+		}
+		result.LogError(ctx, "add guild name mapping failed", "name", name)
+		return nil, result.GetResponseCode()
+	}
+
+	guild := createGuildData(ctx.GetNow().Unix(), zoneId, guildId, name, leaderUserId, leaderZoneId)
+	result, _ = db.DatabaseTableGuildAddZoneIdGuildId(ctx, &private_protocol_pbdesc.DatabaseTableGuild{
+		ZoneId:  zoneId,
+		GuildId: guildId,
+		Guild:   guild,
+	})
+	if result.IsError() {
+		result.LogError(ctx, "add guild table failed", "guild_id", guildId, "name", name)
+		db.DatabaseTableNameMappingDelWithTypeIdZoneIdName(ctx,
+			uint32(private_protocol_common.NameMappingType_EN_NAME_MAPPING_TYPE_GUILD_NAME), zoneId, name)
+		return nil, result.GetResponseCode()
+	}
+
+	ctx.LogInfo("guild created", "zone_id", zoneId, "guild_id", guildId, "name", name, "leader_user_id", leaderUserId)
+	return guild, 0
+}
+
+// RemoveCreatedGuild 创建后扣除消耗失败时删除刚创建的公会，此时还没有进程接管路由租约
+func (m *GuildManager) RemoveCreatedGuild(ctx cd.AwaitableContext, guild *public_protocol_pbdesc.DGuildData) {
+	result := db.DatabaseTableGuildDelWithZoneIdGuildId(ctx, guild.GetZoneId(), guild.GetGuildId())
+	if result.IsError() {
+		result.LogError(ctx, "remove created guild failed", "guild_id", guild.GetGuildId())
+	}
+	db.DatabaseTableNameMappingDelWithTypeIdZoneIdName(ctx,
+		uint32(private_protocol_common.NameMappingType_EN_NAME_MAPPING_TYPE_GUILD_NAME), guild.GetZoneId(), guild.GetName())
+}
+
+// FindByName 按公会名查找公会
+func (m *GuildManager) FindByName(ctx cd.AwaitableContext, zoneId uint32, name string) (*private_protocol_pbdesc.GuildMappingData, int32) {
+	tb, _, result := db.DatabaseTableNameMappingLoadWithTypeIdZoneIdName(ctx,
+		uint32(private_protocol_common.NameMappingType_EN_NAME_MAPPING_TYPE_GUILD_NAME), zoneId, name)
+	if result.IsError() {
+		if result.GetResponseCode() == int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND) {
+			return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_NOT_FOUND)
+		}
+		result.LogError(ctx, "load guild name mapping failed", "name", name)
+		return nil, result.GetResponseCode()
+	}
+
+	mapping := tb.GetMappingData().GetGuildMapping()
+	if mapping.GetGuildId() == 0 {
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_NOT_FOUND)
+	}
+	return mapping, 0
+}
+
+// Execute 在持有公会的进程上执行操作，返回给操作者看到的公会数据
+// 拉取公会实体会切换任务的 actor，所以在独立的任务中执行，不能直接在玩家任务中拉取
+func (m *GuildManager) Execute(ctx cd.AwaitableContext, req *private_protocol_pbdesc.GuildOperationRequest) (*public_protocol_pbdesc.DGuildData, int32) {
+	var rsp *private_protocol_pbdesc.GuildOperationResponse
+	task := cd.AsyncInvoke(ctx, "GuildManager.Execute", nil, func(childCtx cd.AwaitableContext) cd.RpcResult {
+		rsp = m.executeOperation(childCtx, req, true)
+		return cd.CreateRpcResultOk()
+	})
+	if lu.IsNil(task) {
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+	}
+
+	result := cd.AwaitTask(ctx, task)
+	if result.IsError() {
+		result.LogError(ctx, "wait guild operation task failed", "zone_id", req.GetZoneId(), "guild_id", req.GetGuildId())
+		return nil, result.GetResponseCode()
+	}
+	if rsp == nil {
+		// 任务超时或者被终止
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_TIMEOUT)
+	}
+	return rsp.GetGuild(), rsp.GetResultCode()
+}
+
+// RemoveMember 玩家转区、注销时强制退出公会，公会已经解散或者已经不是成员时视为成功
+func (m *GuildManager) RemoveMember(ctx cd.AwaitableContext, guildZoneId uint32, guildId uint64, userId uint64, userZoneId uint32) int32 {
+	_, resultCode := m.Execute(ctx, &private_protocol_pbdesc.GuildOperationRequest{
+		ZoneId:         guildZoneId,
+		GuildId:        guildId,
+		OperatorUserId: userId,
+		OperatorZoneId: userZoneId,
+		Operation: &private_protocol_pbdesc.GuildOperationRequest_Leave{
+			Leave: &private_protocol_pbdesc.GuildOperationLeave{Force: true},
+		},
+	})
+	switch public_protocol_pbdesc.EnErrorCode(resultCode) {
+	case public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_NOT_FOUND, public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_NOT_IN_GUILD:
+		return 0
+	default:
+		return resultCode
+	}
+}
+
+func (m *GuildManager) executeOperation(ctx cd.AwaitableContext, req *private_protocol_pbdesc.GuildOperationRequest,
+	allowForward bool,
+) *private_protocol_pbdesc.GuildOperationResponse {
+	privateData := &GuildRouterPrivateData{}
+	cache, result := GetGuildRouterManager(ctx.GetApp()).MutableObject(ctx, createGuildRouterKey(req.GetZoneId(), req.GetGuildId()), privateData)
+	if result.IsError() {
+		switch public_protocol_pbdesc.EnErrorCode(result.GetResponseCode()) {
+		case public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND:
+			return createGuildOperationResponse(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_NOT_FOUND)
+		case public_protocol_pbdesc.EnErrorCode_EN_ERR_ROUTER_NOT_WRITABLE:
+			if allowForward && privateData.ownerServerId != 0 {
+				return m.forward(ctx, privateData.ownerServerId, req)
+			}
+			return createGuildOperationResponse(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_SERVICE_BUSY)
+		default:
+			result.LogError(ctx, "mutable guild object failed", "zone_id", req.GetZoneId(), "guild_id", req.GetGuildId())
+			return &private_protocol_pbdesc.GuildOperationResponse{ResultCode: result.GetResponseCode()}
+		}
+	}
+
+	return m.applyOnOwner(ctx, cache, req)
+}
+
+// applyOnOwner 执行并保存，保存失败时回滚内存数据
+func (m *GuildManager) applyOnOwner(ctx cd.AwaitableContext, cache *GuildRouterCache,
+	req *private_protocol_pbdesc.GuildOperationRequest,
+) *private_protocol_pbdesc.GuildOperationResponse {
+	backup := proto.Clone(cache.guild).(*public_protocol_pbdesc.DGuildData)
+	opResult := applyGuildOperation(ctx.GetNow().Unix(), cache.guild, req, getGuildRules())
+	if opResult.resultCode != 0 {
+		return &private_protocol_pbdesc.GuildOperationResponse{ResultCode: opResult.resultCode}
+	}
+	cache.RefreshVisitTime(ctx)
+
+	if opResult.dismissed {
+		cache.dismissed = true
+		result := GetGuildRouterManager(ctx.GetApp()).RemoveCache(ctx, cache.GetKey(), cache, nil)
+		if result.IsError() {
+			result.LogError(ctx, "dismiss guild failed", "zone_id", req.GetZoneId(), "guild_id", req.GetGuildId())
+			cache.dismissed = false
+			cache.guild = backup
+			return &private_protocol_pbdesc.GuildOperationResponse{ResultCode: result.GetResponseCode()}
+		}
+		ctx.LogInfo("guild dismissed", "zone_id", req.GetZoneId(), "guild_id", req.GetGuildId(), "name", backup.GetName())
+		return &private_protocol_pbdesc.GuildOperationResponse{}
+	}
+
+	if opResult.changed {
+		result := cache.Save(ctx)
+		if result.IsError() {
+			result.LogError(ctx, "save guild failed", "zone_id", req.GetZoneId(), "guild_id", req.GetGuildId())
+			cache.guild = backup
+			return &private_protocol_pbdesc.GuildOperationResponse{ResultCode: result.GetResponseCode()}
+		}
+	}
+
+	for _, member := range opResult.joined {
+		m.notifyMember(ctx, member, &private_protocol_pbdesc.UserAsyncJobsBlobData{
+			Action: &private_protocol_pbdesc.UserAsyncJobsBlobData_GuildJoin{GuildJoin: createGuildJobMessage(req)},
+		})
+	}
+	for _, member := range opResult.removed {
+		m.notifyMember(ctx, member, &private_protocol_pbdesc.UserAsyncJobsBlobData{
+			Action: &private_protocol_pbdesc.UserAsyncJobsBlobData_GuildRemoved{GuildRemoved: createGuildJobMessage(req)},
+		})
+	}
+
+	return &private_protocol_pbdesc.GuildOperationResponse{
+		Guild: buildGuildView(cache.guild, req.GetOperatorUserId()),
+	}
+}
+
+func createGuildJobMessage(req *private_protocol_pbdesc.GuildOperationRequest) *private_protocol_pbdesc.UserAsyncJobGuildMessage {
+	return &private_protocol_pbdesc.UserAsyncJobGuildMessage{
+		GuildId:        req.GetGuildId(),
+		GuildZoneId:    req.GetZoneId(),
+		OperatorUserId: req.GetOperatorUserId(),
+	}
+}
+
+// notifyMember 公会数据已经保存，投递失败时只记录日志，玩家查看公会时会修正自己的归属
+func (m *GuildManager) notifyMember(ctx cd.AwaitableContext, member *public_protocol_pbdesc.DGuildMember,
+	jobData *private_protocol_pbdesc.UserAsyncJobsBlobData,
+) {
+	result, _ := async_jobs.AddJobsWithRetry(ctx, private_protocol_pbdesc.EnPlayerAsyncJobsType_EN_PAJT_GUILD,
+		member.GetUserId(), member.GetZoneId(), jobData, async_jobs.DefaultActionOptions())
+	if result.IsError() {
+		result.LogError(ctx, "add guild async job failed", "target_user_id", member.GetUserId(), "target_zone_id", member.GetZoneId(),
+			"action_case", jobData.GetActionOneofCase())
+	}
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+// 跨进程转发
+
+func (m *GuildManager) addPendingForward(sequence uint64, action cd.TaskActionImpl) {
+	m.pendingForwardsLock.Lock()
+	defer m.pendingForwardsLock.Unlock()
+	m.pendingForwards[sequence] = action
+}
+
+func (m *GuildManager) removePendingForward(sequence uint64) cd.TaskActionImpl {
+	m.pendingForwardsLock.Lock()
+	defer m.pendingForwardsLock.Unlock()
+	action, ok := m.pendingForwards[sequence]
+	if !ok {
+		return nil
+	}
+	delete(m.pendingForwards, sequence)
+	return action
+}
+
+// resumePendingForward 唤醒等待转发结果的任务，已经超时的任务直接忽略
+func (m *GuildManager) resumePendingForward(ctx cd.RpcContext, awaitType uint64, sequence uint64,
+	result cd.RpcResult, rsp *private_protocol_pbdesc.GuildOperationResponse,
+) {
+	action := m.removePendingForward(sequence)
+	if lu.IsNil(action) {
+		return
+	}
+
+	cd.ResumeTaskAction(ctx, action, &cd.DispatcherResumeData{
+		Message: &cd.DispatcherRawMessage{
+			Type: awaitType,
+		},
+		Sequence:    sequence,
+		Result:      result,
+		PrivateData: rsp,
+	})
+}
+
+// forward 转发给持有公会的进程并等待结果，对方不在线或超时都返回 EN_ERR_GUILD_SERVICE_BUSY
+func (m *GuildManager) forward(ctx cd.AwaitableContext, ownerServerId uint64,
+	req *private_protocol_pbdesc.GuildOperationRequest,
+) *private_protocol_pbdesc.GuildOperationResponse {
+	if !m.pubsub.IsSubscribed() {
+		return createGuildOperationResponse(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_SERVICE_BUSY)
+	}
+
+	currentAction := ctx.GetAction()
+	if lu.IsNil(currentAction) {
+		ctx.LogError("not in context action")
+		return createGuildOperationResponse(public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+	}
+
+	// 等待转发结果复用 RedisMessageDispatcher 的 await 类型和序号分配
+	dispatcher := libatapp.AtappGetModule[*cd.RedisMessageDispatcher](ctx.GetApp())
+	if dispatcher == nil {
+		ctx.LogError("GuildManager need RedisMessageDispatcher to wait forward response")
+		return createGuildOperationResponse(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_SERVICE_BUSY)
+	}
+
+	awaitOption := dispatcher.CreateDispatcherAwaitOptions()
+	awaitOption.Timeout = getGuildConfig().GetForwardTimeout().AsDuration()
+
+	payload, err := proto.Marshal(&private_protocol_pbdesc.GuildForwardMessage{
+		SenderServerId: uint64(config.GetConfigManager().GetLogicId()),
+		AwaitType:      awaitOption.Type,
+		Sequence:       awaitOption.Sequence,
+		Body:           &private_protocol_pbdesc.GuildForwardMessage_Request{Request: req},
+	})
+	if err != nil {
+		ctx.LogError("marshal guild forward request failed", "guild_id", req.GetGuildId(), "error", err)
+		return createGuildOperationResponse(public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+	}
+	channel := getGuildServerChannel(ctx.GetApp(), ownerServerId)
+
+	hooks := &cd.YieldTaskHookSet{
+		PreYield: func(ctx cd.RpcContext) cd.RpcResult {
+			m.addPendingForward(awaitOption.Sequence, currentAction)
+			err := m.pubsub.Publish(channel, payload, func(receivers int64, err error) {
+				if err == nil && receivers > 0 {
+					return
+				}
+
+				// 持有公会的进程已经停服，等租约过期后由其他进程接管
+				ctx.GetApp().GetLogger(2).LogWarn("GuildManager forward request failed", "channel", channel,
+					"receivers", receivers, "error", err)
+				m.resumePendingForward(ctx, awaitOption.Type, awaitOption.Sequence,
+					cd.CreateRpcResultError(err, public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_SERVICE_BUSY), nil)
+			})
+			if err != nil {
+				m.removePendingForward(awaitOption.Sequence)
+				return cd.CreateRpcResultError(err, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+			}
+			return cd.CreateRpcResultOk()
+		},
+		PostYield: func(ctx cd.RpcContext, _ *cd.DispatcherResumeData, result cd.RpcResult) cd.RpcResult {
+			m.removePendingForward(awaitOption.Sequence)
+			return result
+		},
+	}
+
+	resumeData, result := cd.YieldTaskAction(ctx, currentAction, awaitOption, hooks)
+	if result.IsError() {
+		result.LogWarn(ctx, "wait guild forward response failed", "owner_server_id", ownerServerId, "guild_id", req.GetGuildId())
+		return createGuildOperationResponse(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_SERVICE_BUSY)
+	}
+	if resumeData.Result.IsError() {
+		return &private_protocol_pbdesc.GuildOperationResponse{ResultCode: resumeData.Result.GetResponseCode()}
+	}
+
+	rsp, ok := resumeData.PrivateData.(*private_protocol_pbdesc.GuildOperationResponse)
+	if !ok || rsp == nil {
+		ctx.LogError("guild forward PrivateData is not GuildOperationResponse", "guild_id", req.GetGuildId())
+		return createGuildOperationResponse(public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+	}
+	return rsp
+}
+
+func (m *GuildManager) publishForwardResponse(ctx cd.RpcContext, senderServerId uint64, awaitType uint64, sequence uint64,
+	rsp *private_protocol_pbdesc.GuildOperationResponse,
+) {
+	if !m.pubsub.IsSubscribed() {
+		return
+	}
+
+	payload, err := proto.Marshal(&private_protocol_pbdesc.GuildForwardMessage{
+		SenderServerId: uint64(config.GetConfigManager().GetLogicId()),
+		AwaitType:      awaitType,
+		Sequence:       sequence,
+		Body:           &private_protocol_pbdesc.GuildForwardMessage_Response{Response: rsp},
+	})
+	if err != nil {
+		ctx.LogError("marshal guild forward response failed", "sender_server_id", senderServerId, "error", err)
+		return
+	}
+
+	if err := m.pubsub.Publish(getGuildServerChannel(ctx.GetApp(), senderServerId), payload, nil); err != nil {
+		ctx.LogWarn("publish guild forward response failed", "sender_server_id", senderServerId, "error", err)
+	}
+}
+
+// receiveForwardMessage 在主线程调用
+func (m *GuildManager) receiveForwardMessage(channel string, payload []byte) {
+	forward := &private_protocol_pbdesc.GuildForwardMessage{}
+	if err := proto.Unmarshal(payload, forward); err != nil {
+		m.GetApp().GetDefaultLogger().LogWarn("GuildManager bad forward message", "channel", channel, "error", err)
+		return
+	}
+	m.onForwardMessage(forward)
+}
+
+func (m *GuildManager) onForwardMessage(forward *private_protocol_pbdesc.GuildForwardMessage) {
+	ctx := libatapp.AtappGetModule[*cd.NoMessageDispatcher](m.GetApp()).CreateRpcContext()
+	switch body := forward.GetBody().(type) {
+	case *private_protocol_pbdesc.GuildForwardMessage_Request:
+		// 转发过来的请求不再二次转发，避免租约切换期间来回转发
+		task := cd.AsyncInvoke(ctx, "GuildManager.ForwardRequest", nil, func(childCtx cd.AwaitableContext) cd.RpcResult {
+			rsp := m.executeOperation(childCtx, body.Request, false)
+			m.publishForwardResponse(childCtx, forward.GetSenderServerId(), forward.GetAwaitType(), forward.GetSequence(), rsp)
+			return cd.CreateRpcResultOk()
+		})
+		if lu.IsNil(task) {
+			ctx.LogError("GuildManager start forward request task failed", "sender_server_id", forward.GetSenderServerId(),
+				"guild_id", body.Request.GetGuildId())
+		}
+	case *private_protocol_pbdesc.GuildForwardMessage_Response:
+		m.resumePendingForward(ctx, forward.GetAwaitType(), forward.GetSequence(), cd.CreateRpcResultOk(), body.Response)
+	default:
+		ctx.LogWarn("GuildManager forward message without body", "sender_server_id", forward.GetSenderServerId())
+	}
+}
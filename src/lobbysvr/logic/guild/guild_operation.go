@@ -0,0 +1,380 @@
+// Copyright 2026 atframework
+// @brief 公会操作的纯逻辑，只修改 DGuildData，不涉及存储和转发
+
+package lobbysvr_logic_guild
+
+import (
+	"google.golang.org/protobuf/proto"
+
+	config "github.com/atframework/atsf4g-go/component/config"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_config "github.com/atframework/atsf4g-go/component/protocol/public/config/protocol/config"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+)
+
+// guildAllPermissions 会长始终拥有的全部权限
+const guildAllPermissions = int32(public_protocol_pbdesc.EnGuildPermission_EN_GUILD_PERMISSION_HANDLE_APPLICATION) |
+	int32(public_protocol_pbdesc.EnGuildPermission_EN_GUILD_PERMISSION_KICK_MEMBER) |
+	int32(public_protocol_pbdesc.EnGuildPermission_EN_GUILD_PERMISSION_EDIT_ANNOUNCEMENT) |
+	int32(public_protocol_pbdesc.EnGuildPermission_EN_GUILD_PERMISSION_APPOINT)
+
+// guildRules 公会操作需要的配置，测试中直接构造
+type guildRules struct {
+	levelUpExp          []int64
+	baseMemberCount     int32
+	memberCountPerLevel int32
+	applicationMaxCount int32
+}
+
+func getConstIndex() *public_protocol_config.Readonly_ExcelConstConfig {
+	return config.GetConfigManager().GetCurrentConfigGroup().GetCustomIndex().GetConstIndex()
+}
+
+func getGuildRules() guildRules {
+	constIndex := getConstIndex()
+	return guildRules{
+		levelUpExp:          constIndex.GetGuildLevelUpExp(),
+		baseMemberCount:     constIndex.GetGuildBaseMemberCount(),
+		memberCountPerLevel: constIndex.GetGuildMemberCountPerLevel(),
+		applicationMaxCount: constIndex.GetGuildApplicationMaxCount(),
+	}
+}
+
+// memberLimit 返回 0 表示不限制
+func (r guildRules) memberLimit(level int32) int {
+	if r.baseMemberCount <= 0 {
+		return 0
+	}
+	if level < 1 {
+		level = 1
+	}
+	return int(r.baseMemberCount + r.memberCountPerLevel*(level-1))
+}
+
+func (r guildRules) isMemberFull(guild *public_protocol_pbdesc.DGuildData) bool {
+	limit := r.memberLimit(guild.GetLevel())
+	return limit > 0 && len(guild.GetMembers()) >= limit
+}
+
+// guildOperationResult 公会操作的结果，成员变化需要通过异步任务通知对应玩家
+type guildOperationResult struct {
+	resultCode int32
+	changed    bool // 需要保存
+	dismissed  bool // 最后一名成员退出，公会解散
+	joined     []*public_protocol_pbdesc.DGuildMember
+	removed    []*public_protocol_pbdesc.DGuildMember
+}
+
+func createGuildOperationError(code public_protocol_pbdesc.EnErrorCode) guildOperationResult {
+	return guildOperationResult{resultCode: int32(code)}
+}
+
+func createDefaultGuildRolePermissions() []*public_protocol_pbdesc.DGuildRolePermission {
+	return []*public_protocol_pbdesc.DGuildRolePermission{
+		{
+			Role:        public_protocol_pbdesc.EnGuildMemberRole_EN_GUILD_MEMBER_ROLE_VICE_LEADER,
+			Permissions: guildAllPermissions,
+		},
+		{
+			Role:        public_protocol_pbdesc.EnGuildMemberRole_EN_GUILD_MEMBER_ROLE_ELITE,
+			Permissions: int32(public_protocol_pbdesc.EnGuildPermission_EN_GUILD_PERMISSION_HANDLE_APPLICATION),
+		},
+	}
+}
+
+// createGuildData 创建1级公会，创建者为会长
+func createGuildData(now int64, zoneId uint32, guildId uint64, name string, leaderUserId uint64, leaderZoneId uint32) *public_protocol_pbdesc.DGuildData {
+	return &public_protocol_pbdesc.DGuildData{
+		GuildId:    guildId,
+		ZoneId:     zoneId,
+		Name:       name,
+		Level:      1,
+		CreateTime: now,
+		Members: []*public_protocol_pbdesc.DGuildMember{
+			{
+				UserId:   leaderUserId,
+				ZoneId:   leaderZoneId,
+				Role:     public_protocol_pbdesc.EnGuildMemberRole_EN_GUILD_MEMBER_ROLE_LEADER,
+				JoinTime: now,
+			},
+		},
+		RolePermissions: createDefaultGuildRolePermissions(),
+	}
+}
+
+func getGuildRolePermissions(guild *public_protocol_pbdesc.DGuildData, role public_protocol_pbdesc.EnGuildMemberRole) int32 {
+	if role == public_protocol_pbdesc.EnGuildMemberRole_EN_GUILD_MEMBER_ROLE_LEADER {
+		return guildAllPermissions
+	}
+	for _, rolePermission := range guild.GetRolePermissions() {
+		if rolePermission.GetRole() == role {
+			return rolePermission.GetPermissions()
+		}
+	}
+	return 0
+}
+
+func hasGuildPermission(guild *public_protocol_pbdesc.DGuildData, member *public_protocol_pbdesc.DGuildMember,
+	permission public_protocol_pbdesc.EnGuildPermission,
+) bool {
+	return getGuildRolePermissions(guild, member.GetRole())&int32(permission) != 0
+}
+
+func findGuildMember(guild *public_protocol_pbdesc.DGuildData, userId uint64) (int, *public_protocol_pbdesc.DGuildMember) {
+	for i, member := range guild.GetMembers() {
+		if member.GetUserId() == userId {
+			return i, member
+		}
+	}
+	return -1, nil
+}
+
+// IsGuildMember 玩家是否在公会成员列表中
+func IsGuildMember(guild *public_protocol_pbdesc.DGuildData, userId uint64) bool {
+	_, member := findGuildMember(guild, userId)
+	return member != nil
+}
+
+func findGuildApplication(guild *public_protocol_pbdesc.DGuildData, userId uint64) int {
+	for i, application := range guild.GetApplications() {
+		if application.GetUserId() == userId {
+			return i
+		}
+	}
+	return -1
+}
+
+func removeGuildMember(guild *public_protocol_pbdesc.DGuildData, index int) {
+	guild.Members = append(guild.Members[:index:index], guild.Members[index+1:]...)
+}
+
+// selectGuildLeaderSuccessor 会长强制退出时选择职位最高、入会最早的成员接任，调用方保证还有成员
+func selectGuildLeaderSuccessor(guild *public_protocol_pbdesc.DGuildData) *public_protocol_pbdesc.DGuildMember {
+	var successor *public_protocol_pbdesc.DGuildMember
+	for _, member := range guild.GetMembers() {
+		if successor == nil || member.GetRole() > successor.GetRole() ||
+			(member.GetRole() == successor.GetRole() && member.GetJoinTime() < successor.GetJoinTime()) {
+			successor = member
+		}
+	}
+	return successor
+}
+
+func removeGuildApplication(guild *public_protocol_pbdesc.DGuildData, index int) {
+	guild.Applications = append(guild.Applications[:index:index], guild.Applications[index+1:]...)
+}
+
+// addGuildExp 增加经验并升级，最高等级时经验继续累积
+func addGuildExp(guild *public_protocol_pbdesc.DGuildData, exp int64, rules guildRules) {
+	guild.Exp += exp
+	for guild.GetLevel() >= 1 && int(guild.GetLevel()) <= len(rules.levelUpExp) {
+		needExp := rules.levelUpExp[guild.GetLevel()-1]
+		if needExp <= 0 || guild.GetExp() < needExp {
+			break
+		}
+		guild.Exp -= needExp
+		guild.Level++
+	}
+}
+
+// isGuildRoleAssignable 可以任命和设置权限的职位
+func isGuildRoleAssignable(role public_protocol_pbdesc.EnGuildMemberRole) bool {
+	return role >= public_protocol_pbdesc.EnGuildMemberRole_EN_GUILD_MEMBER_ROLE_MEMBER &&
+		role <= public_protocol_pbdesc.EnGuildMemberRole_EN_GUILD_MEMBER_ROLE_LEADER
+}
+
+// buildGuildView 返回给操作者的公会数据，非成员看不到入会申请
+func buildGuildView(guild *public_protocol_pbdesc.DGuildData, operatorUserId uint64) *public_protocol_pbdesc.DGuildData {
+	view := proto.Clone(guild).(*public_protocol_pbdesc.DGuildData)
+	if !IsGuildMember(guild, operatorUserId) {
+		view.Applications = nil
+	}
+	return view
+}
+
+// applyGuildOperation 在负责公会的进程上执行操作，失败时不修改公会数据
+func applyGuildOperation(now int64, guild *public_protocol_pbdesc.DGuildData,
+	req *private_protocol_pbdesc.GuildOperationRequest, rules guildRules,
+) guildOperationResult {
+	operatorUserId := req.GetOperatorUserId()
+	_, operator := findGuildMember(guild, operatorUserId)
+
+	switch operation := req.GetOperation().(type) {
+	case *private_protocol_pbdesc.GuildOperationRequest_GetInfo:
+		return guildOperationResult{}
+
+	case *private_protocol_pbdesc.GuildOperationRequest_Apply:
+		if operator != nil {
+			return createGuildOperationError(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_ALREADY_IN_GUILD)
+		}
+		if rules.isMemberFull(guild) {
+			return createGuildOperationError(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_MEMBER_FULL)
+		}
+
+		// 重复申请只保留最新的一条
+		if index := findGuildApplication(guild, operatorUserId); index >= 0 {
+			removeGuildApplication(guild, index)
+		} else if rules.applicationMaxCount > 0 && len(guild.GetApplications()) >= int(rules.applicationMaxCount) {
+			return createGuildOperationError(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_APPLICATION_FULL)
+		}
+		guild.Applications = append(guild.Applications, &public_protocol_pbdesc.DGuildApplication{
+			UserId:    operatorUserId,
+			ZoneId:    req.GetOperatorZoneId(),
+			ApplyTime: now,
+			Message:   operation.Apply.GetMessage(),
+		})
+		return guildOperationResult{changed: true}
+
+	case *private_protocol_pbdesc.GuildOperationRequest_HandleApplication:
+		if operator == nil {
+			return createGuildOperationError(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_NOT_IN_GUILD)
+		}
+		if !hasGuildPermission(guild, operator, public_protocol_pbdesc.EnGuildPermission_EN_GUILD_PERMISSION_HANDLE_APPLICATION) {
+			return createGuildOperationError(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_NO_PERMISSION)
+		}
+		index := findGuildApplication(guild, operation.HandleApplication.GetUserId())
+		if index < 0 {
+			return createGuildOperationError(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_APPLICATION_NOT_FOUND)
+		}
+		application := guild.GetApplications()[index]
+
+		if !operation.HandleApplication.GetAccept() {
+			removeGuildApplication(guild, index)
+			return guildOperationResult{changed: true}
+		}
+
+		if rules.isMemberFull(guild) {
+			return createGuildOperationError(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_MEMBER_FULL)
+		}
+		removeGuildApplication(guild, index)
+		member := &public_protocol_pbdesc.DGuildMember{
+			UserId:   application.GetUserId(),
+			ZoneId:   application.GetZoneId(),
+			Role:     public_protocol_pbdesc.EnGuildMemberRole_EN_GUILD_MEMBER_ROLE_MEMBER,
+			JoinTime: now,
+		}
+		guild.Members = append(guild.Members, member)
+		return guildOperationResult{changed: true, joined: []*public_protocol_pbdesc.DGuildMember{member}}
+
+	case *private_protocol_pbdesc.GuildOperationRequest_Leave:
+		index, member := findGuildMember(guild, operatorUserId)
+		if member == nil {
+			return createGuildOperationError(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_NOT_IN_GUILD)
+		}
+		if member.GetRole() == public_protocol_pbdesc.EnGuildMemberRole_EN_GUILD_MEMBER_ROLE_LEADER && len(guild.GetMembers()) > 1 {
+			if !operation.Leave.GetForce() {
+				return createGuildOperationError(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_LEADER_CANNOT_LEAVE)
+			}
+			removeGuildMember(guild, index)
+			selectGuildLeaderSuccessor(guild).Role = public_protocol_pbdesc.EnGuildMemberRole_EN_GUILD_MEMBER_ROLE_LEADER
+			return guildOperationResult{changed: true}
+		}
+		removeGuildMember(guild, index)
+		return guildOperationResult{changed: true, dismissed: len(guild.GetMembers()) == 0}
+
+	case *private_protocol_pbdesc.GuildOperationRequest_KickMember:
+		if operator == nil {
+			return createGuildOperationError(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_NOT_IN_GUILD)
+		}
+		if !hasGuildPermission(guild, operator, public_protocol_pbdesc.EnGuildPermission_EN_GUILD_PERMISSION_KICK_MEMBER) {
+			return createGuildOperationError(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_NO_PERMISSION)
+		}
+		index, target := findGuildMember(guild, operation.KickMember.GetUserId())
+		if target == nil {
+			return createGuildOperationError(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_MEMBER_NOT_FOUND)
+		}
+		// 只能踢出职位比自己低的成员
+		if target.GetRole() >= operator.GetRole() {
+			return createGuildOperationError(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_NO_PERMISSION)
+		}
+		removeGuildMember(guild, index)
+		return guildOperationResult{changed: true, removed: []*public_protocol_pbdesc.DGuildMember{target}}
+
+	case *private_protocol_pbdesc.GuildOperationRequest_SetMemberRole:
+		if operator == nil {
+			return createGuildOperationError(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_NOT_IN_GUILD)
+		}
+		if !hasGuildPermission(guild, operator, public_protocol_pbdesc.EnGuildPermission_EN_GUILD_PERMISSION_APPOINT) {
+			return createGuildOperationError(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_NO_PERMISSION)
+		}
+		role := operation.SetMemberRole.GetRole()
+		if !isGuildRoleAssignable(role) {
+			return createGuildOperationError(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_ROLE_INVALID)
+		}
+		_, target := findGuildMember(guild, operation.SetMemberRole.GetUserId())
+		if target == nil {
+			return createGuildOperationError(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_MEMBER_NOT_FOUND)
+		}
+		if target.GetUserId() == operatorUserId {
+			return createGuildOperationError(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_ROLE_INVALID)
+		}
+		if target.GetRole() >= operator.GetRole() {
+			return createGuildOperationError(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_NO_PERMISSION)
+		}
+
+		if role == public_protocol_pbdesc.EnGuildMemberRole_EN_GUILD_MEMBER_ROLE_LEADER {
+			// 转让会长，自己降为副会长
+			if operator.GetRole() != public_protocol_pbdesc.EnGuildMemberRole_EN_GUILD_MEMBER_ROLE_LEADER {
+				return createGuildOperationError(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_NO_PERMISSION)
+			}
+			operator.Role = public_protocol_pbdesc.EnGuildMemberRole_EN_GUILD_MEMBER_ROLE_VICE_LEADER
+		} else if role >= operator.GetRole() {
+			return createGuildOperationError(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_NO_PERMISSION)
+		}
+		target.Role = role
+		return guildOperationResult{changed: true}
+
+	case *private_protocol_pbdesc.GuildOperationRequest_SetRolePermission:
+		if operator == nil {
+			return createGuildOperationError(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_NOT_IN_GUILD)
+		}
+		if operator.GetRole() != public_protocol_pbdesc.EnGuildMemberRole_EN_GUILD_MEMBER_ROLE_LEADER {
+			return createGuildOperationError(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_NO_PERMISSION)
+		}
+		role := operation.SetRolePermission.GetRole()
+		if !isGuildRoleAssignable(role) || role == public_protocol_pbdesc.EnGuildMemberRole_EN_GUILD_MEMBER_ROLE_LEADER {
+			return createGuildOperationError(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_ROLE_INVALID)
+		}
+		permissions := operation.SetRolePermission.GetPermissions() & guildAllPermissions
+		for _, rolePermission := range guild.GetRolePermissions() {
+			if rolePermission.GetRole() == role {
+				rolePermission.Permissions = permissions
+				return guildOperationResult{changed: true}
+			}
+		}
+		guild.RolePermissions = append(guild.RolePermissions, &public_protocol_pbdesc.DGuildRolePermission{
+			Role:        role,
+			Permissions: permissions,
+		})
+		return guildOperationResult{changed: true}
+
+	case *private_protocol_pbdesc.GuildOperationRequest_SetAnnouncement:
+		if operator == nil {
+			return createGuildOperationError(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_NOT_IN_GUILD)
+		}
+		if !hasGuildPermission(guild, operator, public_protocol_pbdesc.EnGuildPermission_EN_GUILD_PERMISSION_EDIT_ANNOUNCEMENT) {
+			return createGuildOperationError(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_NO_PERMISSION)
+		}
+		guild.Announcement = &public_protocol_pbdesc.DGuildAnnouncement{
+			Content:      operation.SetAnnouncement.GetContent(),
+			EditorUserId: operatorUserId,
+			EditTime:     now,
+		}
+		return guildOperationResult{changed: true}
+
+	case *private_protocol_pbdesc.GuildOperationRequest_Donate:
+		if operator == nil {
+			return createGuildOperationError(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_NOT_IN_GUILD)
+		}
+		exp := operation.Donate.GetExp()
+		if exp <= 0 {
+			return createGuildOperationError(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_DONATE_NOT_CONFIGURED)
+		}
+		operator.Contribution += exp
+		addGuildExp(guild, exp, rules)
+		return guildOperationResult{changed: true}
+
+	default:
+		return createGuildOperationError(public_protocol_pbdesc.EnErrorCode_EN_ERR_INVALID_PARAM)
+	}
+}
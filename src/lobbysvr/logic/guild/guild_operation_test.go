@@ -0,0 +1,180 @@
+package lobbysvr_logic_guild
+
+import (
+	"testing"
+
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestGuildRequest(operatorUserId uint64) *private_protocol_pbdesc.GuildOperationRequest {
+	return &private_protocol_pbdesc.GuildOperationRequest{
+		ZoneId:         1,
+		GuildId:        100,
+		OperatorUserId: operatorUserId,
+		OperatorZoneId: 1,
+	}
+}
+
+func createTestApplyRequest(operatorUserId uint64) *private_protocol_pbdesc.GuildOperationRequest {
+	req := createTestGuildRequest(operatorUserId)
+	req.Operation = &private_protocol_pbdesc.GuildOperationRequest_Apply{Apply: &private_protocol_pbdesc.GuildOperationApply{}}
+	return req
+}
+
+func createTestHandleApplicationRequest(operatorUserId uint64, userId uint64) *private_protocol_pbdesc.GuildOperationRequest {
+	req := createTestGuildRequest(operatorUserId)
+	req.Operation = &private_protocol_pbdesc.GuildOperationRequest_HandleApplication{
+		HandleApplication: &private_protocol_pbdesc.GuildOperationHandleApplication{UserId: userId, Accept: true},
+	}
+	return req
+}
+
+// TestGuildApplyAndAccept 测试入会申请和审批
+// Scenario: 重复申请只保留一条，申请数量和成员数量达到上限时拒绝，通过后成为普通成员
+func TestGuildApplyAndAccept(t *testing.T) {
+	// Arrange
+	rules := guildRules{baseMemberCount: 2, applicationMaxCount: 2}
+	guild := createGuildData(1000, 1, 100, "guild", 1, 1)
+
+	// Act & Assert
+	assert.Equal(t, int32(0), applyGuildOperation(1001, guild, createTestApplyRequest(2), rules).resultCode)
+	assert.Equal(t, int32(0), applyGuildOperation(1002, guild, createTestApplyRequest(2), rules).resultCode)
+	assert.Len(t, guild.GetApplications(), 1)
+	assert.Equal(t, int64(1002), guild.GetApplications()[0].GetApplyTime())
+
+	assert.Equal(t, int32(0), applyGuildOperation(1003, guild, createTestApplyRequest(3), rules).resultCode)
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_APPLICATION_FULL),
+		applyGuildOperation(1004, guild, createTestApplyRequest(4), rules).resultCode)
+
+	result := applyGuildOperation(1005, guild, createTestHandleApplicationRequest(1, 2), rules)
+	assert.Equal(t, int32(0), result.resultCode)
+	assert.Len(t, result.joined, 1)
+	assert.Equal(t, uint64(2), result.joined[0].GetUserId())
+	assert.Equal(t, public_protocol_pbdesc.EnGuildMemberRole_EN_GUILD_MEMBER_ROLE_MEMBER, result.joined[0].GetRole())
+
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_MEMBER_FULL),
+		applyGuildOperation(1006, guild, createTestHandleApplicationRequest(1, 3), rules).resultCode)
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_ALREADY_IN_GUILD),
+		applyGuildOperation(1007, guild, createTestApplyRequest(2), rules).resultCode)
+}
+
+// TestGuildKickMemberPermission 测试踢出成员的权限
+// Scenario: 没有权限或者目标职位不低于自己时拒绝，会长可以踢出副会长
+func TestGuildKickMemberPermission(t *testing.T) {
+	// Arrange
+	guild := createGuildData(1000, 1, 100, "guild", 1, 1)
+	guild.Members = append(guild.Members,
+		&public_protocol_pbdesc.DGuildMember{UserId: 2, ZoneId: 1, Role: public_protocol_pbdesc.EnGuildMemberRole_EN_GUILD_MEMBER_ROLE_VICE_LEADER},
+		&public_protocol_pbdesc.DGuildMember{UserId: 3, ZoneId: 1, Role: public_protocol_pbdesc.EnGuildMemberRole_EN_GUILD_MEMBER_ROLE_ELITE},
+		&public_protocol_pbdesc.DGuildMember{UserId: 4, ZoneId: 1, Role: public_protocol_pbdesc.EnGuildMemberRole_EN_GUILD_MEMBER_ROLE_MEMBER},
+	)
+	kick := func(operatorUserId uint64, userId uint64) guildOperationResult {
+		req := createTestGuildRequest(operatorUserId)
+		req.Operation = &private_protocol_pbdesc.GuildOperationRequest_KickMember{
+			KickMember: &private_protocol_pbdesc.GuildOperationKickMember{UserId: userId},
+		}
+		return applyGuildOperation(1001, guild, req, guildRules{})
+	}
+
+	// Act & Assert
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_NO_PERMISSION), kick(3, 4).resultCode)
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_NO_PERMISSION), kick(2, 1).resultCode)
+
+	result := kick(1, 2)
+	assert.Equal(t, int32(0), result.resultCode)
+	assert.Len(t, result.removed, 1)
+	assert.False(t, IsGuildMember(guild, 2))
+	assert.Len(t, guild.GetMembers(), 3)
+}
+
+// TestGuildTransferLeader 测试转让会长
+// Scenario: 会长任命新会长后自己降为副会长，会长在还有其他成员时不能退出
+func TestGuildTransferLeader(t *testing.T) {
+	// Arrange
+	guild := createGuildData(1000, 1, 100, "guild", 1, 1)
+	guild.Members = append(guild.Members,
+		&public_protocol_pbdesc.DGuildMember{UserId: 2, ZoneId: 1, Role: public_protocol_pbdesc.EnGuildMemberRole_EN_GUILD_MEMBER_ROLE_MEMBER})
+	leave := createTestGuildRequest(1)
+	leave.Operation = &private_protocol_pbdesc.GuildOperationRequest_Leave{Leave: &private_protocol_pbdesc.GuildOperationLeave{}}
+	transfer := createTestGuildRequest(1)
+	transfer.Operation = &private_protocol_pbdesc.GuildOperationRequest_SetMemberRole{
+		SetMemberRole: &private_protocol_pbdesc.GuildOperationSetMemberRole{
+			UserId: 2,
+			Role:   public_protocol_pbdesc.EnGuildMemberRole_EN_GUILD_MEMBER_ROLE_LEADER,
+		},
+	}
+
+	// Act & Assert
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_LEADER_CANNOT_LEAVE),
+		applyGuildOperation(1001, guild, leave, guildRules{}).resultCode)
+
+	assert.Equal(t, int32(0), applyGuildOperation(1002, guild, transfer, guildRules{}).resultCode)
+	_, oldLeader := findGuildMember(guild, 1)
+	_, newLeader := findGuildMember(guild, 2)
+	assert.Equal(t, public_protocol_pbdesc.EnGuildMemberRole_EN_GUILD_MEMBER_ROLE_VICE_LEADER, oldLeader.GetRole())
+	assert.Equal(t, public_protocol_pbdesc.EnGuildMemberRole_EN_GUILD_MEMBER_ROLE_LEADER, newLeader.GetRole())
+
+	result := applyGuildOperation(1003, guild, leave, guildRules{})
+	assert.Equal(t, int32(0), result.resultCode)
+	assert.False(t, result.dismissed)
+}
+
+// TestGuildForceLeave 测试转区、注销时强制退出公会
+// Scenario: 会长强制退出时职位最高、入会最早的成员接任，最后一个成员退出时解散公会
+func TestGuildForceLeave(t *testing.T) {
+	// Arrange
+	guild := createGuildData(1000, 1, 100, "guild", 1, 1)
+	guild.Members = append(guild.Members,
+		&public_protocol_pbdesc.DGuildMember{UserId: 2, ZoneId: 1, Role: public_protocol_pbdesc.EnGuildMemberRole_EN_GUILD_MEMBER_ROLE_ELITE, JoinTime: 1200},
+		&public_protocol_pbdesc.DGuildMember{UserId: 3, ZoneId: 1, Role: public_protocol_pbdesc.EnGuildMemberRole_EN_GUILD_MEMBER_ROLE_ELITE, JoinTime: 1100},
+		&public_protocol_pbdesc.DGuildMember{UserId: 4, ZoneId: 1, Role: public_protocol_pbdesc.EnGuildMemberRole_EN_GUILD_MEMBER_ROLE_MEMBER, JoinTime: 1000})
+	createForceLeave := func(operatorUserId uint64) *private_protocol_pbdesc.GuildOperationRequest {
+		req := createTestGuildRequest(operatorUserId)
+		req.Operation = &private_protocol_pbdesc.GuildOperationRequest_Leave{Leave: &private_protocol_pbdesc.GuildOperationLeave{Force: true}}
+		return req
+	}
+
+	// Act
+	leaderResult := applyGuildOperation(2000, guild, createForceLeave(1), guildRules{})
+
+	// Assert
+	assert.Equal(t, int32(0), leaderResult.resultCode)
+	assert.False(t, leaderResult.dismissed)
+	assert.False(t, IsGuildMember(guild, 1))
+	_, successor := findGuildMember(guild, 3)
+	assert.Equal(t, public_protocol_pbdesc.EnGuildMemberRole_EN_GUILD_MEMBER_ROLE_LEADER, successor.GetRole())
+
+	assert.Equal(t, int32(0), applyGuildOperation(2001, guild, createForceLeave(2), guildRules{}).resultCode)
+	assert.Equal(t, int32(0), applyGuildOperation(2002, guild, createForceLeave(3), guildRules{}).resultCode)
+	_, lastMember := findGuildMember(guild, 4)
+	assert.Equal(t, public_protocol_pbdesc.EnGuildMemberRole_EN_GUILD_MEMBER_ROLE_LEADER, lastMember.GetRole())
+	assert.True(t, applyGuildOperation(2003, guild, createForceLeave(4), guildRules{}).dismissed)
+}
+
+// TestGuildDonateLevelUp 测试捐献升级
+// Scenario: 经验足够时连续升级，达到最高等级后经验累积不再升级
+func TestGuildDonateLevelUp(t *testing.T) {
+	// Arrange
+	rules := guildRules{levelUpExp: []int64{100, 200}, baseMemberCount: 10, memberCountPerLevel: 5}
+	guild := createGuildData(1000, 1, 100, "guild", 1, 1)
+	donate := createTestGuildRequest(1)
+	donate.Operation = &private_protocol_pbdesc.GuildOperationRequest_Donate{
+		Donate: &private_protocol_pbdesc.GuildOperationDonate{Exp: 350},
+	}
+
+	// Act
+	result := applyGuildOperation(1001, guild, donate, rules)
+
+	// Assert
+	assert.Equal(t, int32(0), result.resultCode)
+	assert.Equal(t, int32(3), guild.GetLevel())
+	assert.Equal(t, int64(50), guild.GetExp())
+	assert.Equal(t, int64(350), guild.GetMembers()[0].GetContribution())
+	assert.Equal(t, 20, rules.memberLimit(guild.GetLevel()))
+
+	applyGuildOperation(1002, guild, donate, rules)
+	assert.Equal(t, int32(3), guild.GetLevel())
+	assert.Equal(t, int64(400), guild.GetExp())
+}
@@ -0,0 +1,177 @@
+// Copyright 2026 atframework
+// @brief 公会路由对象，同一时间只有持有路由租约的 lobbysvr 可以写入
+
+package lobbysvr_logic_guild
+
+import (
+	"fmt"
+	"log/slog"
+
+	lu "github.com/atframework/atframe-utils-go/lang_utility"
+	config "github.com/atframework/atsf4g-go/component/config"
+	db "github.com/atframework/atsf4g-go/component/db"
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	private_protocol_common "github.com/atframework/atsf4g-go/component/protocol/private/common/protocol/common"
+	private_protocol_config "github.com/atframework/atsf4g-go/component/protocol/private/config/protocol/config"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	router "github.com/atframework/atsf4g-go/component/router"
+)
+
+type GuildRouterCache struct {
+	router.RouterObjectBase
+
+	guild      *public_protocol_pbdesc.DGuildData
+	casVersion uint64
+	dismissed  bool // 已经解散，保存时删除记录
+}
+
+// GuildRouterPrivateData 拉取实体失败时带回当前持有租约的进程，用于转发
+type GuildRouterPrivateData struct {
+	ownerServerId uint64
+}
+
+func (p *GuildRouterPrivateData) RouterPrivateDataImpl() {}
+
+func getGuildConfig() *private_protocol_config.Readonly_LogicGuildCfg {
+	return config.GetConfigManager().GetCurrentConfigGroup().GetSectionConfig().GetGuild()
+}
+
+func (p *GuildRouterCache) GetGuildData() *public_protocol_pbdesc.DGuildData {
+	if p == nil {
+		return nil
+	}
+	return p.guild
+}
+
+func (p *GuildRouterCache) LogValue() slog.Value {
+	return slog.StringValue(fmt.Sprintf("GuildRouterCache Key %v", p.GetKey()))
+}
+
+// isGuildRouterLeaseAlive 其他进程持有的租约是否还有效
+func isGuildRouterLeaseAlive(ctx cd.RpcContext, tb *private_protocol_pbdesc.DatabaseTableGuild) bool {
+	return tb.GetRouterServerId() != 0 && ctx.GetSysNow().Unix() < tb.GetRouterExpired()
+}
+
+func (p *GuildRouterCache) load(ctx cd.AwaitableContext) (*private_protocol_pbdesc.DatabaseTableGuild, cd.RpcResult) {
+	key := p.GetKey()
+	tb, casVersion, result := db.DatabaseTableGuildLoadWithZoneIdGuildId(ctx, key.ZoneID, key.ObjectID)
+	if result.IsError() {
+		if result.GetResponseCode() != int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND) {
+			result.LogError(ctx, "load guild table from db failed")
+		}
+		return nil, result
+	}
+
+	p.guild = tb.GetGuild()
+	p.casVersion = casVersion
+	return tb, cd.CreateRpcResultOk()
+}
+
+// PullCache 只读取数据，不接管租约
+func (p *GuildRouterCache) PullCache(ctx cd.AwaitableContext, privateData router.RouterPrivateData) cd.RpcResult {
+	tb, result := p.load(ctx)
+	if result.IsError() {
+		return result
+	}
+
+	if isGuildRouterLeaseAlive(ctx, tb) {
+		p.SetRouterServerId(tb.GetRouterServerId(), tb.GetRouterVersion())
+	} else {
+		p.SetRouterServerId(0, tb.GetRouterVersion())
+	}
+	return cd.CreateRpcResultOk()
+}
+
+// PullObject 其他进程的租约未过期时只记录持有者，否则接管租约
+func (p *GuildRouterCache) PullObject(ctx cd.AwaitableContext, privateData router.RouterPrivateData) cd.RpcResult {
+	var guildPrivateData *GuildRouterPrivateData
+	if !lu.IsNil(privateData) {
+		guildPrivateData, _ = privateData.(*GuildRouterPrivateData)
+	}
+
+	tb, result := p.load(ctx)
+	if result.IsError() {
+		return result
+	}
+
+	selfServerId := uint64(config.GetConfigManager().GetLogicId())
+	if tb.GetRouterServerId() != selfServerId && isGuildRouterLeaseAlive(ctx, tb) {
+		// 路由检查不通过，由路由系统返回 EN_ERR_ROUTER_NOT_WRITABLE
+		p.SetRouterServerId(tb.GetRouterServerId(), tb.GetRouterVersion())
+		if guildPrivateData != nil {
+			guildPrivateData.ownerServerId = tb.GetRouterServerId()
+		}
+		return cd.CreateRpcResultOk()
+	}
+
+	if tb.GetRouterServerId() != selfServerId {
+		tb.RouterServerId = selfServerId
+		tb.RouterVersion = tb.GetRouterVersion() + 1
+	}
+	tb.RouterExpired = ctx.GetSysNow().Add(getGuildConfig().GetRouterLease().AsDuration()).Unix()
+
+	casVersion := p.casVersion
+	result = db.DatabaseTableGuildReplaceZoneIdGuildId(ctx, tb, &casVersion, false)
+	if result.IsError() {
+		if result.GetResponseCode() == int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_CAS_CHECK_FAILED) {
+			// 其他进程同时在接管，稍后重试
+			ctx.LogWarn("guild router lease conflict, retry later", "key", p.GetKey())
+			return cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_ROUTER_EAGAIN)
+		}
+		result.LogError(ctx, "take over guild router lease failed")
+		return result
+	}
+
+	p.casVersion = casVersion
+	p.SetRouterServerId(tb.GetRouterServerId(), tb.GetRouterVersion())
+	ctx.LogInfo("take over guild router lease success", "key", p.GetKey(), "router_version", tb.GetRouterVersion())
+	return cd.CreateRpcResultOk()
+}
+
+// SaveObject 路由进程为 0 时释放租约，否则续期
+func (p *GuildRouterCache) SaveObject(ctx cd.AwaitableContext, _ router.RouterPrivateData) cd.RpcResult {
+	key := p.GetKey()
+	if p.dismissed {
+		result := db.DatabaseTableGuildDelWithZoneIdGuildId(ctx, key.ZoneID, key.ObjectID)
+		if result.IsError() && result.GetResponseCode() != int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND) {
+			result.LogError(ctx, "delete dismissed guild failed")
+			return result
+		}
+
+		result = db.DatabaseTableNameMappingDelWithTypeIdZoneIdName(ctx,
+			uint32(private_protocol_common.NameMappingType_EN_NAME_MAPPING_TYPE_GUILD_NAME), key.ZoneID, p.guild.GetName())
+		if result.IsError() {
+			// 名字映射残留只影响重名检查，不影响解散
+			result.LogWarn(ctx, "delete dismissed guild name mapping failed", "name", p.guild.GetName())
+		}
+		return cd.CreateRpcResultOk()
+	}
+
+	tb := &private_protocol_pbdesc.DatabaseTableGuild{
+		ZoneId:         key.ZoneID,
+		GuildId:        key.ObjectID,
+		RouterServerId: p.GetRouterSvrId(),
+		RouterVersion:  p.GetRouterSvrVer(),
+		Guild:          p.guild,
+	}
+	if tb.GetRouterServerId() != 0 {
+		tb.RouterExpired = ctx.GetSysNow().Add(getGuildConfig().GetRouterLease().AsDuration()).Unix()
+	}
+
+	casVersion := p.casVersion
+	result := db.DatabaseTableGuildReplaceZoneIdGuildId(ctx, tb, &casVersion, false)
+	if result.IsError() {
+		if result.GetResponseCode() == int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_CAS_CHECK_FAILED) {
+			// 租约过期后被其他进程接管，本地数据作废
+			ctx.LogError("guild router lease taken over by other server, downgrade", "key", key)
+			p.Downgrade(ctx)
+			return cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_ROUTER_NOT_WRITABLE)
+		}
+		result.LogError(ctx, "save guild table failed")
+		return result
+	}
+
+	p.casVersion = casVersion
+	return cd.CreateRpcResultOk()
+}
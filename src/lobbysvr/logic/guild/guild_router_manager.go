@@ -0,0 +1,63 @@
+package lobbysvr_logic_guild
+
+import (
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	router "github.com/atframework/atsf4g-go/component/router"
+	libatapp "github.com/atframework/libatapp-go"
+)
+
+type GuildRouterManager struct {
+	*router.RouterManager[*GuildRouterCache, *GuildRouterPrivateData]
+}
+
+func InitGuildRouterManager(app libatapp.AppImpl) {
+	guildRouterManager := &GuildRouterManager{}
+	guildRouterManager.RouterManager = router.CreateRouterManager[*GuildRouterCache, *GuildRouterPrivateData](
+		app,
+		"GuildRouterManager",
+		public_protocol_pbdesc.EnRouterObjectType_EN_ROT_GUILD,
+		func(ctx cd.RpcContext, key router.RouterObjectKey) *GuildRouterCache {
+			cache := &GuildRouterCache{
+				RouterObjectBase: router.CreateRouterObjectBase(ctx, key),
+			}
+			cache.RouterObjectBase.InitRouterObjectImpl(cache)
+			return cache
+		},
+		guildRouterManager,
+	)
+	libatapp.AtappGetModule[*router.RouterManagerSet](app).RegisterManager(guildRouterManager)
+}
+
+func GetGuildRouterManager(app libatapp.AppImpl) *GuildRouterManager {
+	return libatapp.AtappGetModule[*router.RouterManagerSet](app).
+		GetManager(uint32(public_protocol_pbdesc.EnRouterObjectType_EN_ROT_GUILD)).(*GuildRouterManager)
+}
+
+func createGuildRouterKey(zoneId uint32, guildId uint64) router.RouterObjectKey {
+	return router.RouterObjectKey{
+		TypeID:   uint32(public_protocol_pbdesc.EnRouterObjectType_EN_ROT_GUILD),
+		ZoneID:   zoneId,
+		ObjectID: guildId,
+	}
+}
+
+// 公会没有绑定连接等本地资源，移除时不需要额外处理
+
+func (manager *GuildRouterManager) OnRemoveObject(ctx cd.RpcContext, key router.RouterObjectKey, obj router.RouterObjectImpl, privData router.RouterPrivateData) {
+}
+
+func (manager *GuildRouterManager) OnRemoveCache(ctx cd.RpcContext, key router.RouterObjectKey, obj router.RouterObjectImpl, privData router.RouterPrivateData) {
+}
+
+func (manager *GuildRouterManager) OnObjectRemoved(ctx cd.RpcContext, key router.RouterObjectKey, obj router.RouterObjectImpl, privData router.RouterPrivateData) {
+}
+
+func (manager *GuildRouterManager) OnCacheRemoved(ctx cd.RpcContext, key router.RouterObjectKey, obj router.RouterObjectImpl, privData router.RouterPrivateData) {
+}
+
+func (manager *GuildRouterManager) OnPullObject(ctx cd.RpcContext, obj router.RouterObjectImpl, privData router.RouterPrivateData) {
+}
+
+func (manager *GuildRouterManager) OnPullCache(ctx cd.RpcContext, cache router.RouterObjectImpl, privData router.RouterPrivateData) {
+}
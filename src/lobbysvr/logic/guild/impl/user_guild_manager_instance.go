@@ -0,0 +1,517 @@
+package lobbysvr_logic_guild_impl
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
+
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+
+	config "github.com/atframework/atsf4g-go/component/config"
+	private_protocol_log "github.com/atframework/atsf4g-go/component/protocol/private/log/protocol/log"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_common "github.com/atframework/atsf4g-go/component/protocol/public/common/protocol/common"
+	public_protocol_config "github.com/atframework/atsf4g-go/component/protocol/public/config/protocol/config"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	logic_guild "github.com/atframework/atsf4g-go/service-lobbysvr/logic/guild"
+	service_protocol "github.com/atframework/atsf4g-go/service-lobbysvr/protocol/public/protocol/pbdesc"
+)
+
+func init() {
+	var _ logic_guild.UserGuildManager = (*UserGuildManager)(nil)
+	data.RegisterUserModuleManagerCreator[logic_guild.UserGuildManager](func(ctx cd.RpcContext, owner *data.User) data.UserModuleManagerImpl {
+		return CreateUserGuildManager(owner)
+	})
+}
+
+type UserGuildManager struct {
+	owner *data.User
+	data.UserModuleManagerBase
+
+	guildData *public_protocol_pbdesc.DUserGuildData
+}
+
+func CreateUserGuildManager(owner *data.User) *UserGuildManager {
+	return &UserGuildManager{
+		owner:                 owner,
+		UserModuleManagerBase: *data.CreateUserModuleManagerBase(owner),
+
+		guildData: &public_protocol_pbdesc.DUserGuildData{},
+	}
+}
+
+func (m *UserGuildManager) InitFromDB(ctx cd.RpcContext, _dbUser *private_protocol_pbdesc.DatabaseTableUser) cd.RpcResult {
+	if _dbUser.GetGuildData() != nil {
+		m.guildData = _dbUser.GetGuildData()
+	}
+	return cd.CreateRpcResultOk()
+}
+
+func (m *UserGuildManager) DumpToDB(ctx cd.RpcContext, _dbUser *private_protocol_pbdesc.DatabaseTableUser) cd.RpcResult {
+	_dbUser.GuildData = m.guildData
+	return cd.CreateRpcResultOk()
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+
+func getConstIndex() *public_protocol_config.Readonly_ExcelConstConfig {
+	return config.GetConfigManager().GetCurrentConfigGroup().GetCustomIndex().GetConstIndex()
+}
+
+// checkTextLength 按文本配置检查长度，没有配置时不限制
+func checkTextLength(textConfig *public_protocol_config.Readonly_UserTextConfig, text string, errorCode public_protocol_pbdesc.EnErrorCode) int32 {
+	length := int32(utf8.RuneCountInString(text))
+	if textConfig.GetMaxLength() > 0 && length > textConfig.GetMaxLength() {
+		return int32(errorCode)
+	}
+	if textConfig.GetMinLength() > 0 && length < textConfig.GetMinLength() {
+		return int32(errorCode)
+	}
+	return 0
+}
+
+func (m *UserGuildManager) GetGuildData() *public_protocol_pbdesc.DUserGuildData {
+	return m.guildData
+}
+
+func (m *UserGuildManager) IsInGuild() bool {
+	return m.guildData.GetGuildId() != 0
+}
+
+func (m *UserGuildManager) setGuild(ctx cd.RpcContext, guildId uint64, guildZoneId uint32) {
+	m.guildData = &public_protocol_pbdesc.DUserGuildData{
+		GuildId:     guildId,
+		GuildZoneId: guildZoneId,
+	}
+	if guildId != 0 {
+		m.guildData.JoinTime = ctx.GetNow().Unix()
+	}
+	m.insertDirtyHandle()
+}
+
+func (m *UserGuildManager) createOperationRequest(guildId uint64, guildZoneId uint32) *private_protocol_pbdesc.GuildOperationRequest {
+	return &private_protocol_pbdesc.GuildOperationRequest{
+		ZoneId:         guildZoneId,
+		GuildId:        guildId,
+		OperatorUserId: m.GetOwner().GetUserId(),
+		OperatorZoneId: m.GetOwner().GetZoneId(),
+	}
+}
+
+func (m *UserGuildManager) execute(ctx cd.AwaitableContext, req *private_protocol_pbdesc.GuildOperationRequest) (*public_protocol_pbdesc.DGuildData, int32) {
+	guildManager := logic_guild.GetGuildManager(ctx.GetApp())
+	if guildManager == nil {
+		ctx.LogError("guild manager not registered")
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+	}
+	return guildManager.Execute(ctx, req)
+}
+
+// executeInOwnGuild 对自己所在的公会执行操作，公会已经解散或者已经被移出时清理归属
+func (m *UserGuildManager) executeInOwnGuild(ctx cd.AwaitableContext, req *private_protocol_pbdesc.GuildOperationRequest) (*public_protocol_pbdesc.DGuildData, int32) {
+	if !m.IsInGuild() {
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_NOT_IN_GUILD)
+	}
+
+	guildId := m.guildData.GetGuildId()
+	req.ZoneId = m.guildData.GetGuildZoneId()
+	req.GuildId = guildId
+	req.OperatorUserId = m.GetOwner().GetUserId()
+	req.OperatorZoneId = m.GetOwner().GetZoneId()
+
+	guild, resultCode := m.execute(ctx, req)
+	if resultCode == int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_NOT_FOUND) ||
+		resultCode == int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_NOT_IN_GUILD) ||
+		(resultCode == 0 && guild != nil && !logic_guild.IsGuildMember(guild, m.GetOwner().GetUserId())) {
+		// 执行期间可能已经通过异步任务切换了公会
+		if m.guildData.GetGuildId() == guildId {
+			ctx.LogWarn("user guild data outdated, clear it", "guild_id", guildId, "result_code", resultCode)
+			m.setGuild(ctx, 0, 0)
+		}
+		if resultCode == 0 {
+			resultCode = int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_NOT_IN_GUILD)
+		}
+		return nil, resultCode
+	}
+	return guild, resultCode
+}
+
+func (m *UserGuildManager) FetchData(ctx cd.AwaitableContext, rspBody *service_protocol.SCGuildGetInfoRsp) int32 {
+	if m.IsInGuild() {
+		guild, resultCode := m.executeInOwnGuild(ctx, &private_protocol_pbdesc.GuildOperationRequest{
+			Operation: &private_protocol_pbdesc.GuildOperationRequest_GetInfo{GetInfo: &private_protocol_pbdesc.GuildOperationGetInfo{}},
+		})
+		if resultCode != 0 && m.IsInGuild() {
+			return resultCode
+		}
+		rspBody.Guild = guild
+	}
+
+	rspBody.UserGuild = m.guildData
+	return 0
+}
+
+func (m *UserGuildManager) FindByName(ctx cd.AwaitableContext, name string, rspBody *service_protocol.SCGuildFindByNameRsp) int32 {
+	if name == "" {
+		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_INVALID_PARAM)
+	}
+
+	guildManager := logic_guild.GetGuildManager(ctx.GetApp())
+	if guildManager == nil {
+		ctx.LogError("guild manager not registered")
+		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+	}
+
+	mapping, resultCode := guildManager.FindByName(ctx, m.GetOwner().GetZoneId(), name)
+	if resultCode != 0 {
+		return resultCode
+	}
+	rspBody.GuildId = mapping.GetGuildId()
+	rspBody.ZoneId = mapping.GetZoneId()
+	return 0
+}
+
+func (m *UserGuildManager) CreateGuild(ctx cd.AwaitableContext, name string,
+	expectCostItems []*public_protocol_common.DItemBasic,
+) (*public_protocol_pbdesc.DGuildData, int32) {
+	if m.IsInGuild() {
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_ALREADY_IN_GUILD)
+	}
+
+	if name == "" || strings.ContainsAny(name, " \t") {
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_INVALID_PARAM)
+	}
+	if resultCode := checkTextLength(getConstIndex().GetGuildNameConfig(), name,
+		public_protocol_pbdesc.EnErrorCode_EN_ERR_NAME_LENGTH_INVALID); resultCode != 0 {
+		return nil, resultCode
+	}
+
+	// 检查消耗
+	costItems := getConstIndex().GetGuildCreateCost()
+	if len(costItems) != 0 {
+		result := m.GetOwner().CheckCostItemCfg(ctx, expectCostItems, costItems)
+		if result.IsError() {
+			return nil, result.GetResponseCode()
+		}
+		_, result = m.GetOwner().CheckSubItem(ctx, expectCostItems)
+		if result.IsError() {
+			return nil, result.GetResponseCode()
+		}
+	}
+
+	guildManager := logic_guild.GetGuildManager(ctx.GetApp())
+	if guildManager == nil {
+		ctx.LogError("guild manager not registered")
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+	}
+	guild, resultCode := guildManager.CreateGuild(ctx, m.GetOwner().GetZoneId(), name, m.GetOwner().GetUserId(), m.GetOwner().GetZoneId())
+	if resultCode != 0 {
+		return nil, resultCode
+	}
+
+	// 创建期间其他任务可能修改了道具或者公会归属，重新检查
+	if m.IsInGuild() {
+		guildManager.RemoveCreatedGuild(ctx, guild)
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_ALREADY_IN_GUILD)
+	}
+	if len(costItems) != 0 {
+		result := m.GetOwner().CheckCostItemCfg(ctx, expectCostItems, costItems)
+		if result.IsError() {
+			guildManager.RemoveCreatedGuild(ctx, guild)
+			return nil, result.GetResponseCode()
+		}
+		subGuard, result := m.GetOwner().CheckSubItem(ctx, expectCostItems)
+		if result.IsError() {
+			guildManager.RemoveCreatedGuild(ctx, guild)
+			return nil, result.GetResponseCode()
+		}
+
+		m.GetOwner().SubItem(ctx, subGuard, &data.ItemFlowReason{
+			MajorReason: int32(public_protocol_common.EnItemFlowReasonMajorType_EN_ITEM_FLOW_REASON_MAJOR_GUILD),
+			MinorReason: int32(public_protocol_common.EnItemFlowReasonMinorType_EN_ITEM_FLOW_REASON_MINOR_GUILD_CREATE_COST),
+			Parameter:   int64(guild.GetGuildId()),
+		})
+	}
+
+	m.setGuild(ctx, guild.GetGuildId(), guild.GetZoneId())
+	m.sendGuildOssLog(ctx, private_protocol_log.OSSGuildFlow_EN_OSS_GUILD_OPERATION_TYPE_CREATE,
+		guild.GetGuildId(), guild.GetZoneId(), expectCostItems, guild)
+	return guild, 0
+}
+
+func (m *UserGuildManager) Apply(ctx cd.AwaitableContext, guildId uint64, zoneId uint32, message string) int32 {
+	if m.IsInGuild() {
+		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_ALREADY_IN_GUILD)
+	}
+	if guildId == 0 {
+		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_INVALID_PARAM)
+	}
+	if zoneId == 0 {
+		zoneId = m.GetOwner().GetZoneId()
+	}
+	if resultCode := checkTextLength(getConstIndex().GetGuildApplicationMessageConfig(), message,
+		public_protocol_pbdesc.EnErrorCode_EN_ERR_NAME_LENGTH_INVALID); resultCode != 0 {
+		return resultCode
+	}
+
+	req := m.createOperationRequest(guildId, zoneId)
+	req.Operation = &private_protocol_pbdesc.GuildOperationRequest_Apply{Apply: &private_protocol_pbdesc.GuildOperationApply{
+		Message: message,
+	}}
+	_, resultCode := m.execute(ctx, req)
+	if resultCode != 0 {
+		return resultCode
+	}
+
+	m.sendGuildOssLog(ctx, private_protocol_log.OSSGuildFlow_EN_OSS_GUILD_OPERATION_TYPE_APPLY, guildId, zoneId, nil, nil)
+	return 0
+}
+
+func (m *UserGuildManager) HandleApplication(ctx cd.AwaitableContext, userId uint64, accept bool) (*public_protocol_pbdesc.DGuildData, int32) {
+	return m.executeInOwnGuild(ctx, &private_protocol_pbdesc.GuildOperationRequest{
+		Operation: &private_protocol_pbdesc.GuildOperationRequest_HandleApplication{
+			HandleApplication: &private_protocol_pbdesc.GuildOperationHandleApplication{
+				UserId: userId,
+				Accept: accept,
+			},
+		},
+	})
+}
+
+func (m *UserGuildManager) Leave(ctx cd.AwaitableContext) int32 {
+	if !m.IsInGuild() {
+		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_NOT_IN_GUILD)
+	}
+
+	guildId := m.guildData.GetGuildId()
+	guildZoneId := m.guildData.GetGuildZoneId()
+	_, resultCode := m.executeInOwnGuild(ctx, &private_protocol_pbdesc.GuildOperationRequest{
+		Operation: &private_protocol_pbdesc.GuildOperationRequest_Leave{Leave: &private_protocol_pbdesc.GuildOperationLeave{}},
+	})
+	if resultCode != 0 {
+		// 公会已经解散或者已经被移出，归属已经清理，视为退出成功
+		if !m.IsInGuild() {
+			return 0
+		}
+		return resultCode
+	}
+
+	if m.guildData.GetGuildId() == guildId {
+		m.setGuild(ctx, 0, 0)
+	}
+	m.sendGuildOssLog(ctx, private_protocol_log.OSSGuildFlow_EN_OSS_GUILD_OPERATION_TYPE_LEAVE, guildId, guildZoneId, nil, nil)
+	return 0
+}
+
+func (m *UserGuildManager) KickMember(ctx cd.AwaitableContext, userId uint64) (*public_protocol_pbdesc.DGuildData, int32) {
+	if userId == m.GetOwner().GetUserId() {
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_INVALID_PARAM)
+	}
+	return m.executeInOwnGuild(ctx, &private_protocol_pbdesc.GuildOperationRequest{
+		Operation: &private_protocol_pbdesc.GuildOperationRequest_KickMember{
+			KickMember: &private_protocol_pbdesc.GuildOperationKickMember{UserId: userId},
+		},
+	})
+}
+
+func (m *UserGuildManager) SetMemberRole(ctx cd.AwaitableContext, userId uint64,
+	role public_protocol_pbdesc.EnGuildMemberRole,
+) (*public_protocol_pbdesc.DGuildData, int32) {
+	return m.executeInOwnGuild(ctx, &private_protocol_pbdesc.GuildOperationRequest{
+		Operation: &private_protocol_pbdesc.GuildOperationRequest_SetMemberRole{
+			SetMemberRole: &private_protocol_pbdesc.GuildOperationSetMemberRole{
+				UserId: userId,
+				Role:   role,
+			},
+		},
+	})
+}
+
+func (m *UserGuildManager) SetRolePermission(ctx cd.AwaitableContext, role public_protocol_pbdesc.EnGuildMemberRole,
+	permissions int32,
+) (*public_protocol_pbdesc.DGuildData, int32) {
+	return m.executeInOwnGuild(ctx, &private_protocol_pbdesc.GuildOperationRequest{
+		Operation: &private_protocol_pbdesc.GuildOperationRequest_SetRolePermission{
+			SetRolePermission: &private_protocol_pbdesc.GuildOperationSetRolePermission{
+				Role:        role,
+				Permissions: permissions,
+			},
+		},
+	})
+}
+
+func (m *UserGuildManager) SetAnnouncement(ctx cd.AwaitableContext, content string) (*public_protocol_pbdesc.DGuildData, int32) {
+	if resultCode := checkTextLength(getConstIndex().GetGuildAnnouncementConfig(), content,
+		public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_ANNOUNCEMENT_LENGTH_INVALID); resultCode != 0 {
+		return nil, resultCode
+	}
+
+	return m.executeInOwnGuild(ctx, &private_protocol_pbdesc.GuildOperationRequest{
+		Operation: &private_protocol_pbdesc.GuildOperationRequest_SetAnnouncement{
+			SetAnnouncement: &private_protocol_pbdesc.GuildOperationSetAnnouncement{Content: content},
+		},
+	})
+}
+
+func (m *UserGuildManager) Donate(ctx cd.AwaitableContext, expectCostItems []*public_protocol_common.DItemBasic) (*public_protocol_pbdesc.DGuildData, int32) {
+	if !m.IsInGuild() {
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_NOT_IN_GUILD)
+	}
+
+	costItems := getConstIndex().GetGuildDonateCost()
+	donateExp := getConstIndex().GetGuildDonateExp()
+	if len(costItems) == 0 || donateExp <= 0 {
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_DONATE_NOT_CONFIGURED)
+	}
+
+	result := m.GetOwner().CheckCostItemCfg(ctx, expectCostItems, costItems)
+	if result.IsError() {
+		return nil, result.GetResponseCode()
+	}
+
+	return m.donate(ctx, expectCostItems, donateExp)
+}
+
+// isGuildDonateRejected 公会明确拒绝了捐献，经验一定没有增加，可以返还消耗
+// 超时和转发失败时持有公会的进程可能已经增加了经验，不能返还
+func isGuildDonateRejected(resultCode int32) bool {
+	switch public_protocol_pbdesc.EnErrorCode(resultCode) {
+	case public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_NOT_FOUND,
+		public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_NOT_IN_GUILD,
+		public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_DONATE_NOT_CONFIGURED:
+		return true
+	default:
+		return false
+	}
+}
+
+// donate 先扣除捐献消耗再增加公会经验，公会明确拒绝时返还已经扣除的道具
+func (m *UserGuildManager) donate(ctx cd.AwaitableContext, costItems []*public_protocol_common.DItemBasic, donateExp int64) (*public_protocol_pbdesc.DGuildData, int32) {
+	if logic_guild.GetGuildManager(ctx.GetApp()) == nil {
+		ctx.LogError("guild manager not registered")
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+	}
+
+	subGuard, result := m.GetOwner().CheckSubItem(ctx, costItems)
+	if result.IsError() {
+		return nil, result.GetResponseCode()
+	}
+
+	guildId := m.guildData.GetGuildId()
+	guildZoneId := m.guildData.GetGuildZoneId()
+	m.GetOwner().SubItem(ctx, subGuard, &data.ItemFlowReason{
+		MajorReason: int32(public_protocol_common.EnItemFlowReasonMajorType_EN_ITEM_FLOW_REASON_MAJOR_GUILD),
+		MinorReason: int32(public_protocol_common.EnItemFlowReasonMinorType_EN_ITEM_FLOW_REASON_MINOR_GUILD_DONATE_COST),
+		Parameter:   int64(guildId),
+	})
+
+	guild, resultCode := m.executeInOwnGuild(ctx, &private_protocol_pbdesc.GuildOperationRequest{
+		Operation: &private_protocol_pbdesc.GuildOperationRequest_Donate{
+			Donate: &private_protocol_pbdesc.GuildOperationDonate{Exp: donateExp},
+		},
+	})
+	if resultCode != 0 {
+		if isGuildDonateRejected(resultCode) {
+			m.refundDonateCost(ctx, costItems, guildId)
+		} else {
+			// 结果不确定时不返还，由运营根据日志核对公会贡献后补偿
+			ctx.LogError("guild donate result unknown, cost not refunded", "guild_id", guildId, "guild_zone_id", guildZoneId,
+				"result_code", resultCode, "cost_items", costItems)
+		}
+		return nil, resultCode
+	}
+
+	m.sendGuildOssLog(ctx, private_protocol_log.OSSGuildFlow_EN_OSS_GUILD_OPERATION_TYPE_DONATE, guildId, guildZoneId, costItems, guild)
+	return guild, 0
+}
+
+// refundDonateCost 返还捐献失败时已经扣除的道具，道具刚刚扣除过，不再经过 CheckAddItem 的上限检查以免返还失败
+func (m *UserGuildManager) refundDonateCost(ctx cd.RpcContext, costItems []*public_protocol_common.DItemBasic, guildId uint64) {
+	instances, result := m.GetOwner().GenerateMultipleItemInstancesFromBasic(ctx, costItems, false)
+	if result.IsError() {
+		result.LogError(ctx, "generate guild donate refund items failed", "guild_id", guildId)
+		return
+	}
+
+	addGuard := make([]*data.ItemAddGuard, 0, len(instances))
+	for _, instance := range instances {
+		addGuard = append(addGuard, &data.ItemAddGuard{Item: instance})
+	}
+	result = m.GetOwner().AddItem(ctx, addGuard, &data.ItemFlowReason{
+		MajorReason: int32(public_protocol_common.EnItemFlowReasonMajorType_EN_ITEM_FLOW_REASON_MAJOR_GUILD),
+		MinorReason: int32(public_protocol_common.EnItemFlowReasonMinorType_EN_ITEM_FLOW_REASON_MINOR_GUILD_DONATE_REFUND),
+		Parameter:   int64(guildId),
+	})
+	if result.IsError() {
+		result.LogError(ctx, "refund guild donate cost failed", "guild_id", guildId)
+	}
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+// 异步任务处理，返回负数时异步任务会重试
+
+// OnJoined 入会申请被通过，已经加入其他公会时退出新加入的公会
+func (m *UserGuildManager) OnJoined(ctx cd.RpcContext, jobData *private_protocol_pbdesc.UserAsyncJobGuildMessage) int32 {
+	guildId := jobData.GetGuildId()
+	if guildId == 0 || guildId == m.guildData.GetGuildId() {
+		return 0
+	}
+
+	if m.IsInGuild() {
+		ctx.LogWarn("joined guild while already in another guild, leave it", "guild_id", guildId,
+			"current_guild_id", m.guildData.GetGuildId())
+		req := m.createOperationRequest(guildId, jobData.GetGuildZoneId())
+		req.Operation = &private_protocol_pbdesc.GuildOperationRequest_Leave{Leave: &private_protocol_pbdesc.GuildOperationLeave{}}
+		cd.AsyncInvoke(ctx, "UserGuildManager.LeaveDuplicatedGuild", nil, func(childCtx cd.AwaitableContext) cd.RpcResult {
+			if _, resultCode := m.execute(childCtx, req); resultCode != 0 {
+				childCtx.LogError("leave duplicated guild failed", "guild_id", guildId, "result_code", resultCode)
+			}
+			return cd.CreateRpcResultOk()
+		})
+		return 0
+	}
+
+	m.setGuild(ctx, guildId, jobData.GetGuildZoneId())
+	m.sendGuildOssLog(ctx, private_protocol_log.OSSGuildFlow_EN_OSS_GUILD_OPERATION_TYPE_JOIN, guildId, jobData.GetGuildZoneId(), nil, nil)
+	return 0
+}
+
+// OnRemoved 被踢出公会
+func (m *UserGuildManager) OnRemoved(ctx cd.RpcContext, jobData *private_protocol_pbdesc.UserAsyncJobGuildMessage) int32 {
+	if jobData.GetGuildId() == 0 || jobData.GetGuildId() != m.guildData.GetGuildId() {
+		return 0
+	}
+
+	m.setGuild(ctx, 0, 0)
+	m.sendGuildOssLog(ctx, private_protocol_log.OSSGuildFlow_EN_OSS_GUILD_OPERATION_TYPE_REMOVED,
+		jobData.GetGuildId(), jobData.GetGuildZoneId(), nil, nil)
+	return 0
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+
+func (m *UserGuildManager) sendGuildOssLog(ctx cd.RpcContext, operationType private_protocol_log.OSSGuildFlow_EnOSSGuildOperationType,
+	guildId uint64, guildZoneId uint32, items []*public_protocol_common.DItemBasic, guild *public_protocol_pbdesc.DGuildData,
+) {
+	log := private_protocol_log.OperationSupportSystemLog{}
+	flow := log.MutableLog().MutableGuildFlow()
+	flow.OperationType = operationType
+	flow.GuildId = guildId
+	flow.GuildZoneId = guildZoneId
+	flow.Items = items
+	flow.GuildLevel = guild.GetLevel()
+	m.GetOwner().SendUserOssLog(ctx, &log)
+}
+
+func (m *UserGuildManager) insertDirtyHandle() {
+	m.GetOwner().InsertDirtyHandleIfNotExists(m,
+		func(ctx cd.RpcContext, dirty *data.UserDirtyData) bool {
+			dirty.MutableNormalDirtyChangeMessage().DirtyGuild = &public_protocol_pbdesc.DUserGuildDirtyChg{
+				Guild: m.guildData,
+			}
+			return true
+		},
+		func(ctx cd.RpcContext) {},
+	)
+}
@@ -0,0 +1,113 @@
+package lobbysvr_logic_guild_impl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_common "github.com/atframework/atsf4g-go/component/protocol/public/common/protocol/common"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	test_utility "github.com/atframework/atsf4g-go/component/test_utility"
+
+	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
+	logic_guild "github.com/atframework/atsf4g-go/service-lobbysvr/logic/guild"
+
+	// 注册道具管理器
+	_ "github.com/atframework/atsf4g-go/service-lobbysvr/logic/inventory/impl"
+)
+
+const (
+	testGuildZoneId      uint32 = 1
+	testGuildUserId      uint64 = 10001
+	testGuildId          uint64 = 20001
+	testGuildDonateItem  int32  = int32(public_protocol_common.EnItemTypeRange_EN_ITEM_TYPE_RANGE_PROP_BEGIN) + 1
+	testGuildDonateCount int64  = 10
+)
+
+// TestGuildDonateWithoutGuildManager 测试公会服务不可用时捐献
+// Scenario: 玩家持有捐献道具且在公会中，没有注册公会管理器时捐献直接失败，不会扣除道具
+func TestGuildDonateWithoutGuildManager(t *testing.T) {
+	// Arrange
+	app, _ := test_utility.CreateMemoryStorageApp()
+	ctx := test_utility.NewMockRpcContextWithNow(app, time.Unix(1700000000, 0))
+
+	user, result := data.CreateUserForTest(ctx, testGuildZoneId, testGuildUserId, &private_protocol_pbdesc.DatabaseTableUser{
+		InventoryData: &private_protocol_pbdesc.UserInventoryData{
+			Item: []*public_protocol_common.DItemInstance{
+				{ItemBasic: &public_protocol_common.DItemBasic{TypeId: testGuildDonateItem, Count: testGuildDonateCount}},
+			},
+		},
+		GuildData: &public_protocol_pbdesc.DUserGuildData{GuildId: testGuildId, GuildZoneId: testGuildZoneId},
+	})
+	assert.False(t, result.IsError())
+	mgr, ok := data.UserGetModuleManager[logic_guild.UserGuildManager](user).(*UserGuildManager)
+	assert.True(t, ok)
+	assert.True(t, mgr.IsInGuild())
+
+	costItems := []*public_protocol_common.DItemBasic{{TypeId: testGuildDonateItem, Count: 3}}
+
+	// Act: 没有注册公会管理器
+	guild, resultCode := mgr.donate(ctx, costItems, 100)
+
+	// Assert
+	assert.Nil(t, guild)
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM), resultCode)
+	assert.Equal(t, testGuildDonateCount, user.GetItemTypeStatistics(ctx, testGuildDonateItem).GetTotalCount())
+	assert.True(t, mgr.IsInGuild())
+}
+
+// TestGuildDonateNotEnoughItem 测试捐献道具不足
+// Scenario: 玩家道具不足时捐献直接失败，不会扣除道具
+func TestGuildDonateNotEnoughItem(t *testing.T) {
+	// Arrange
+	app, _ := test_utility.CreateMemoryStorageApp()
+	ctx := test_utility.NewMockRpcContextWithNow(app, time.Unix(1700000000, 0))
+
+	user, result := data.CreateUserForTest(ctx, testGuildZoneId, testGuildUserId, &private_protocol_pbdesc.DatabaseTableUser{
+		InventoryData: &private_protocol_pbdesc.UserInventoryData{
+			Item: []*public_protocol_common.DItemInstance{
+				{ItemBasic: &public_protocol_common.DItemBasic{TypeId: testGuildDonateItem, Count: testGuildDonateCount}},
+			},
+		},
+		GuildData: &public_protocol_pbdesc.DUserGuildData{GuildId: testGuildId, GuildZoneId: testGuildZoneId},
+	})
+	assert.False(t, result.IsError())
+	mgr, ok := data.UserGetModuleManager[logic_guild.UserGuildManager](user).(*UserGuildManager)
+	assert.True(t, ok)
+
+	costItems := []*public_protocol_common.DItemBasic{{TypeId: testGuildDonateItem, Count: testGuildDonateCount + 1}}
+
+	// Act
+	guild, resultCode := mgr.donate(ctx, costItems, 100)
+
+	// Assert
+	assert.Nil(t, guild)
+	assert.NotEqual(t, int32(0), resultCode)
+	assert.Equal(t, testGuildDonateCount, user.GetItemTypeStatistics(ctx, testGuildDonateItem).GetTotalCount())
+}
+
+// TestGuildDonateRejectedResult 测试捐献失败时哪些错误码可以返还消耗
+// Scenario: 公会不存在、不在公会中等明确拒绝可以返还，超时和服务繁忙时公会经验可能已经增加，不能返还
+func TestGuildDonateRejectedResult(t *testing.T) {
+	// Arrange
+	rejected := []public_protocol_pbdesc.EnErrorCode{
+		public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_NOT_FOUND,
+		public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_NOT_IN_GUILD,
+		public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_DONATE_NOT_CONFIGURED,
+	}
+	unknown := []public_protocol_pbdesc.EnErrorCode{
+		public_protocol_pbdesc.EnErrorCode_EN_ERR_GUILD_SERVICE_BUSY,
+		public_protocol_pbdesc.EnErrorCode_EN_ERR_TIMEOUT,
+		public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM,
+	}
+
+	// Act & Assert
+	for _, code := range rejected {
+		assert.True(t, isGuildDonateRejected(int32(code)), code.String())
+	}
+	for _, code := range unknown {
+		assert.False(t, isGuildDonateRejected(int32(code)), code.String())
+	}
+}
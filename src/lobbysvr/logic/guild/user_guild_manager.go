@@ -0,0 +1,38 @@
+package lobbysvr_logic_guild
+
+import (
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_common "github.com/atframework/atsf4g-go/component/protocol/public/common/protocol/common"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
+	service_protocol "github.com/atframework/atsf4g-go/service-lobbysvr/protocol/public/protocol/pbdesc"
+)
+
+// UserGuildManager 玩家的公会归属和公会操作
+// 公会数据由持有路由租约的进程写入，入会申请被通过和被踢出通过异步任务通知玩家
+type UserGuildManager interface {
+	data.UserModuleManagerImpl
+
+	GetGuildData() *public_protocol_pbdesc.DUserGuildData
+	IsInGuild() bool
+
+	// 公会已经解散或者自己已经不在公会中时会清理归属
+	FetchData(ctx cd.AwaitableContext, rspBody *service_protocol.SCGuildGetInfoRsp) int32
+	FindByName(ctx cd.AwaitableContext, name string, rspBody *service_protocol.SCGuildFindByNameRsp) int32
+
+	CreateGuild(ctx cd.AwaitableContext, name string, expectCostItems []*public_protocol_common.DItemBasic) (*public_protocol_pbdesc.DGuildData, int32)
+	Apply(ctx cd.AwaitableContext, guildId uint64, zoneId uint32, message string) int32
+	HandleApplication(ctx cd.AwaitableContext, userId uint64, accept bool) (*public_protocol_pbdesc.DGuildData, int32)
+	// 会长只有在最后一名成员时才能退出，退出后公会解散
+	Leave(ctx cd.AwaitableContext) int32
+	KickMember(ctx cd.AwaitableContext, userId uint64) (*public_protocol_pbdesc.DGuildData, int32)
+	SetMemberRole(ctx cd.AwaitableContext, userId uint64, role public_protocol_pbdesc.EnGuildMemberRole) (*public_protocol_pbdesc.DGuildData, int32)
+	SetRolePermission(ctx cd.AwaitableContext, role public_protocol_pbdesc.EnGuildMemberRole, permissions int32) (*public_protocol_pbdesc.DGuildData, int32)
+	SetAnnouncement(ctx cd.AwaitableContext, content string) (*public_protocol_pbdesc.DGuildData, int32)
+	Donate(ctx cd.AwaitableContext, expectCostItems []*public_protocol_common.DItemBasic) (*public_protocol_pbdesc.DGuildData, int32)
+
+	// 以下为异步任务的处理接口
+	OnJoined(ctx cd.RpcContext, jobData *private_protocol_pbdesc.UserAsyncJobGuildMessage) int32
+	OnRemoved(ctx cd.RpcContext, jobData *private_protocol_pbdesc.UserAsyncJobGuildMessage) int32
+}
@@ -125,13 +125,13 @@ func (g *UserInventoryItemGroup) addGroupCount(item *public_protocol_common.DIte
 	}
 }
 
-func (g *UserInventoryItemGroup) subGroupCount(item *public_protocol_common.DItemBasic, count int64) {
+func (g *UserInventoryItemGroup) subGroupCount(item *public_protocol_common.DItemInstance, count int64) {
 	if item == nil || count <= 0 || g == nil {
 		return
 	}
 
-	if count > item.GetCount() {
-		count = item.GetCount()
+	if count > item.GetItemBasic().GetCount() {
+		count = item.GetItemBasic().GetCount()
 	}
 	resetStats := false
 	if count > g.statistics.TotalCount {
@@ -139,9 +139,10 @@ func (g *UserInventoryItemGroup) subGroupCount(item *public_protocol_common.DIte
 		resetStats = true
 	}
 
-	item.Count -= count
-	if item.GetCount() <= 0 {
-		delete(g.items, item.GetGuid())
+	item.MutableItemBasic().Count -= count
+	// 可回复道具保留回复时间
+	if item.GetItemBasic().GetCount() <= 0 && item.GetRegenTimepoint() == 0 {
+		delete(g.items, item.GetItemBasic().GetGuid())
 	}
 
 	g.statistics.TotalCount -= count
@@ -213,6 +214,7 @@ func (m *UserInventoryManager) GetOwner() *data.User {
 
 func (m *UserInventoryManager) LoginInit(ctx cd.RpcContext) {
 	m.RefreshLimitSecond(ctx)
+	m.settleAllRegenItems(ctx)
 }
 
 func (m *UserInventoryManager) InitFromDB(_ctx cd.RpcContext, dbUser *private_protocol_pbdesc.DatabaseTableUser) cd.RpcResult {
@@ -362,7 +364,7 @@ func (m *UserInventoryManager) resetItem(itemType int32) {
 	if subSet.GetItemBasic().GetCount() == 0 {
 		return
 	}
-	group.subGroupCount(subSet.GetItemBasic(), subSet.GetItemBasic().GetCount())
+	group.subGroupCount(subSet, subSet.GetItemBasic().GetCount())
 	if group.empty() {
 		delete(m.itemGroups, itemType)
 	}
@@ -449,16 +451,19 @@ func (m *UserInventoryManager) AddItem(ctx cd.RpcContext, itemOffset []*data.Ite
 		}
 
 		typeId := add.Item.GetItemBasic().GetTypeId()
-		// 虚拟道具分发
-		if typeId >= int32(public_protocol_common.EnItemTypeRange_EN_ITEM_TYPE_RANGE_VIRTUAL_ITEM_BEGIN) &&
+		addCount := add.Item.GetItemBasic().GetCount()
+		if regenCfg := m.settleRegenItem(ctx, typeId); regenCfg != nil {
+			// 可回复道具按溢出规则修正实际获得的数量
+			addCount = m.regenAddCount(ctx, regenCfg, add)
+		} else if typeId >= int32(public_protocol_common.EnItemTypeRange_EN_ITEM_TYPE_RANGE_VIRTUAL_ITEM_BEGIN) &&
 			typeId < int32(public_protocol_common.EnItemTypeRange_EN_ITEM_TYPE_RANGE_VIRTUAL_ITEM_END) {
+			// 虚拟道具分发
 			process, _ := m.virtualItemManager.AddItem(ctx, add, reason)
 			if process {
 				continue
 			}
 		}
 
-		addCount := add.Item.GetItemBasic().GetCount()
 		groupGuid := add.Item.GetItemBasic().GetGuid()
 
 		// 通用道具管理
@@ -501,6 +506,9 @@ func (m *UserInventoryManager) SubItem(ctx cd.RpcContext, itemOffset []*data.Ite
 		}
 
 		typeId := sub.Item.GetTypeId()
+		// 可回复道具先结算，扣除前达到上限时从现在开始重新计时
+		m.settleRegenItem(ctx, typeId)
+
 		// 虚拟道具分发
 		if typeId >= int32(public_protocol_common.EnItemTypeRange_EN_ITEM_TYPE_RANGE_VIRTUAL_ITEM_BEGIN) &&
 			typeId < int32(public_protocol_common.EnItemTypeRange_EN_ITEM_TYPE_RANGE_VIRTUAL_ITEM_END) {
@@ -525,7 +533,7 @@ func (m *UserInventoryManager) SubItem(ctx cd.RpcContext, itemOffset []*data.Ite
 				"item_id", sub.Item.GetTypeId(), "item_guid", sub.Item.GetGuid(), "has_item_count", subSet.GetItemBasic().GetCount(), "sub_item_count", sub.Item.GetCount(),
 			)
 		}
-		group.subGroupCount(subSet.GetItemBasic(), sub.Item.GetCount())
+		group.subGroupCount(subSet, sub.Item.GetCount())
 		if group.empty() {
 			delete(m.itemGroups, sub.Item.GetTypeId())
 		}
@@ -614,6 +622,14 @@ func (m *UserInventoryManager) CheckAddItem(ctx cd.RpcContext, itemOffset []*pub
 			continue
 		}
 		typeId := item.GetItemBasic().GetTypeId()
+		if regenCfg := m.settleRegenItem(ctx, typeId); regenCfg != nil {
+			result := m.checkAddRegenItem(ctx, regenCfg, item)
+			if result.IsError() {
+				return nil, result
+			}
+			continue
+		}
+
 		if typeId >= int32(public_protocol_common.EnItemTypeRange_EN_ITEM_TYPE_RANGE_VIRTUAL_ITEM_BEGIN) &&
 			typeId < int32(public_protocol_common.EnItemTypeRange_EN_ITEM_TYPE_RANGE_VIRTUAL_ITEM_END) {
 			result := m.virtualItemManager.CheckAddItem(ctx, item)
@@ -645,8 +661,10 @@ func (m *UserInventoryManager) CheckSubItem(ctx cd.RpcContext, itemOffset []*pub
 			continue
 		}
 
-		// 虚拟道具分发
 		typeId := sub.Item.GetTypeId()
+		m.settleRegenItem(ctx, typeId)
+
+		// 虚拟道具分发
 		if typeId >= int32(public_protocol_common.EnItemTypeRange_EN_ITEM_TYPE_RANGE_VIRTUAL_ITEM_BEGIN) &&
 			typeId < int32(public_protocol_common.EnItemTypeRange_EN_ITEM_TYPE_RANGE_VIRTUAL_ITEM_END) {
 			process, result := m.virtualItemManager.CheckSubItem(ctx, sub.Item)
@@ -673,6 +691,8 @@ func (m *UserInventoryManager) CheckSubItem(ctx cd.RpcContext, itemOffset []*pub
 }
 
 func (m *UserInventoryManager) GetTypeStatistics(ctx cd.RpcContext, typeId int32) *data.ItemTypeStatistics {
+	m.settleRegenItem(ctx, typeId)
+
 	// 虚拟道具分发
 	if typeId >= int32(public_protocol_common.EnItemTypeRange_EN_ITEM_TYPE_RANGE_VIRTUAL_ITEM_BEGIN) &&
 		typeId < int32(public_protocol_common.EnItemTypeRange_EN_ITEM_TYPE_RANGE_VIRTUAL_ITEM_END) {
@@ -708,6 +728,7 @@ func (m *UserInventoryManager) GetItemFromBasic(ctx cd.RpcContext, itemBasic *pu
 	}
 
 	typeId := itemBasic.GetTypeId()
+	m.settleRegenItem(ctx, typeId)
 
 	// 虚拟道具分发
 	if typeId >= int32(public_protocol_common.EnItemTypeRange_EN_ITEM_TYPE_RANGE_VIRTUAL_ITEM_BEGIN) &&
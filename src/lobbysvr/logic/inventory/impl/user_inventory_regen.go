@@ -0,0 +1,179 @@
+package lobbysvr_logic_inventory_impl
+
+import (
+	"math"
+
+	config "github.com/atframework/atsf4g-go/component/config"
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	public_protocol_common "github.com/atframework/atsf4g-go/component/protocol/public/common/protocol/common"
+	public_protocol_config "github.com/atframework/atsf4g-go/component/protocol/public/config/protocol/config"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
+)
+
+// 可回复道具的 group guid 固定为 0，数量为 0 时也保留道具实例以记录回复时间
+
+// getItemRegenConfigFn 读取可回复道具配置，单元测试中没有配置表时替换
+var getItemRegenConfigFn = func(typeId int32) *public_protocol_config.Readonly_ExcelItemRegen {
+	return config.GetConfigManager().GetCurrentConfigGroup().GetExcelItemRegenByItemId(typeId)
+}
+
+func getItemRegenConfig(typeId int32) *public_protocol_config.Readonly_ExcelItemRegen {
+	return getItemRegenConfigFn(typeId)
+}
+
+// calculateItemRegen 按上次结算时间补算自然回复，返回新的数量和结算时间
+// 达到回复上限或者时间被 GM 往回调时，结算时间重置为 now，从 now 开始重新计时
+func calculateItemRegen(cfg *public_protocol_config.Readonly_ExcelItemRegen, count int64, regenTimepoint int64, now int64) (int64, int64) {
+	interval := int64(cfg.GetRegenInterval().AsDuration().Seconds())
+	if interval <= 0 || cfg.GetRegenCount() <= 0 || count >= cfg.GetRegenCap() {
+		return count, now
+	}
+
+	if regenTimepoint <= 0 || regenTimepoint > now {
+		return count, now
+	}
+
+	times := (now - regenTimepoint) / interval
+	if times <= 0 {
+		return count, regenTimepoint
+	}
+
+	// 先按次数比较，避免乘法溢出
+	needTimes := (cfg.GetRegenCap() - count + cfg.GetRegenCount() - 1) / cfg.GetRegenCount()
+	if times >= needTimes {
+		return cfg.GetRegenCap(), now
+	}
+
+	return count + times*cfg.GetRegenCount(), regenTimepoint + times*interval
+}
+
+// getItemRegenMaxCount 获得道具时允许持有的最大数量
+func getItemRegenMaxCount(cfg *public_protocol_config.Readonly_ExcelItemRegen) int64 {
+	if cfg.GetOverflowType() != public_protocol_common.EnItemRegenOverflowType_EN_ITEM_REGEN_OVERFLOW_TYPE_ALLOW {
+		return cfg.GetRegenCap()
+	}
+
+	if cfg.GetOverflowMaxCount() > 0 {
+		return cfg.GetOverflowMaxCount()
+	}
+	return math.MaxInt64
+}
+
+// calculateItemRegenAddCount 按溢出规则计算实际获得的数量，丢弃模式下超出的部分不发放
+func calculateItemRegenAddCount(cfg *public_protocol_config.Readonly_ExcelItemRegen, count int64, addCount int64) (int64, int32) {
+	maxCount := getItemRegenMaxCount(cfg)
+	if count >= maxCount {
+		if cfg.GetOverflowType() == public_protocol_common.EnItemRegenOverflowType_EN_ITEM_REGEN_OVERFLOW_TYPE_DISCARD {
+			return 0, 0
+		}
+		return 0, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_ITEM_REGEN_OVERFLOW)
+	}
+
+	if addCount <= maxCount-count {
+		return addCount, 0
+	}
+
+	if cfg.GetOverflowType() == public_protocol_common.EnItemRegenOverflowType_EN_ITEM_REGEN_OVERFLOW_TYPE_DISCARD {
+		return maxCount - count, 0
+	}
+	return 0, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_ITEM_REGEN_OVERFLOW)
+}
+
+// settleRegenItem 结算可回复道具，不是可回复道具时返回 nil
+func (m *UserInventoryManager) settleRegenItem(ctx cd.RpcContext, typeId int32) *public_protocol_config.Readonly_ExcelItemRegen {
+	cfg := getItemRegenConfig(typeId)
+	if cfg == nil {
+		return nil
+	}
+
+	group := m.mutableItemGroup(typeId)
+	item := group.MutableGroup(0)
+	count, regenTimepoint := calculateItemRegen(cfg, item.GetItemBasic().GetCount(), item.GetRegenTimepoint(), ctx.GetNow().Unix())
+	if count == item.GetItemBasic().GetCount() && regenTimepoint == item.GetRegenTimepoint() {
+		return cfg
+	}
+
+	// 达到上限时每次结算都会更新时间，客户端不需要，只保存
+	if count != item.GetItemBasic().GetCount() || count < cfg.GetRegenCap() {
+		m.markItemDirty(typeId, 0)
+	}
+	if count != item.GetItemBasic().GetCount() {
+		ctx.LogDebug("UserInventoryManager settle regen item", "item_id", typeId,
+			"before_item_count", item.GetItemBasic().GetCount(), "after_item_count", count)
+		item.MutableItemBasic().Count = count
+		group.recalcStatistics()
+	}
+	item.RegenTimepoint = regenTimepoint
+	return cfg
+}
+
+func (m *UserInventoryManager) settleAllRegenItems(ctx cd.RpcContext) {
+	regenCfgs := config.GetConfigManager().GetCurrentConfigGroup().GetExcelItemRegenAllOfItemId()
+	if regenCfgs == nil {
+		return
+	}
+
+	for _, regenCfg := range *regenCfgs {
+		if regenCfg == nil {
+			continue
+		}
+		m.settleRegenItem(ctx, regenCfg.GetItemId())
+	}
+}
+
+// checkAddRegenItem 调用前需要先结算
+func (m *UserInventoryManager) checkAddRegenItem(ctx cd.RpcContext, cfg *public_protocol_config.Readonly_ExcelItemRegen,
+	item *public_protocol_common.DItemInstance,
+) data.Result {
+	if item.GetItemBasic().GetGuid() != 0 {
+		return cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_INVALID_PARAM)
+	}
+
+	current := m.mutableItemGroup(cfg.GetItemId()).MutableGroup(0).GetItemBasic().GetCount()
+	_, resultCode := calculateItemRegenAddCount(cfg, current, item.GetItemBasic().GetCount())
+	if resultCode != 0 {
+		return cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode(resultCode))
+	}
+	return cd.CreateRpcResultOk()
+}
+
+// GetItemRegenRefillCount 补满到回复上限需要的数量，不是可回复道具时返回 0
+func (m *UserInventoryManager) GetItemRegenRefillCount(ctx cd.RpcContext, typeId int32) int64 {
+	cfg := m.settleRegenItem(ctx, typeId)
+	if cfg == nil {
+		return 0
+	}
+
+	current := m.mutableItemGroup(typeId).MutableGroup(0).GetItemBasic().GetCount()
+	if current >= cfg.GetRegenCap() {
+		return 0
+	}
+	return cfg.GetRegenCap() - current
+}
+
+// regenAddCount 按溢出规则计算实际获得的数量，丢弃了一部分时通过 ExchangeItems 返回实际获得的道具
+func (m *UserInventoryManager) regenAddCount(ctx cd.RpcContext, cfg *public_protocol_config.Readonly_ExcelItemRegen, add *data.ItemAddGuard) int64 {
+	current := m.mutableItemGroup(cfg.GetItemId()).MutableGroup(0).GetItemBasic().GetCount()
+	expectCount := add.Item.GetItemBasic().GetCount()
+	addCount, resultCode := calculateItemRegenAddCount(cfg, current, expectCount)
+	if resultCode != 0 {
+		ctx.LogError("add regen item overflow, should failed in CheckAddItem",
+			"item_id", cfg.GetItemId(), "has_item_count", current, "add_item_count", expectCount,
+		)
+		return 0
+	}
+
+	if addCount < expectCount {
+		add.ExchangeItems = []*public_protocol_common.DItemInstance{
+			{
+				ItemBasic: &public_protocol_common.DItemBasic{
+					TypeId: cfg.GetItemId(),
+					Count:  addCount,
+					Guid:   0,
+				},
+			},
+		}
+	}
+	return addCount
+}
@@ -0,0 +1,177 @@
+package lobbysvr_logic_inventory_impl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	logical_time "github.com/atframework/atsf4g-go/component/logical_time"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_common "github.com/atframework/atsf4g-go/component/protocol/public/common/protocol/common"
+	public_protocol_config "github.com/atframework/atsf4g-go/component/protocol/public/config/protocol/config"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	test_utility "github.com/atframework/atsf4g-go/component/test_utility"
+
+	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
+	logic_inventory "github.com/atframework/atsf4g-go/service-lobbysvr/logic/inventory"
+)
+
+const (
+	testRegenZoneId uint32 = 1
+	testRegenUserId uint64 = 10001
+	testRegenItemId int32  = int32(public_protocol_common.EnItemTypeRange_EN_ITEM_TYPE_RANGE_PROP_BEGIN) + 3
+)
+
+func newTestItemRegen(overflowType public_protocol_common.EnItemRegenOverflowType, overflowMaxCount int64) *public_protocol_config.Readonly_ExcelItemRegen {
+	return (&public_protocol_config.ExcelItemRegen{
+		ItemId:           testRegenItemId,
+		RegenInterval:    durationpb.New(6 * time.Minute),
+		RegenCount:       1,
+		RegenCap:         120,
+		OverflowType:     overflowType,
+		OverflowMaxCount: overflowMaxCount,
+	}).ToReadonly()
+}
+
+// TestItemRegenSettle 测试可回复道具按逻辑时间补算回复
+// Scenario: GM 把时间往后调后按经过的间隔回复并保留不足一个间隔的进度，回复满后从结算时间重新计时，时间往回调时重新计时
+func TestItemRegenSettle(t *testing.T) {
+	// Arrange
+	defer logical_time.SetGlobalLogicalOffset(0)
+	cfg := newTestItemRegen(public_protocol_common.EnItemRegenOverflowType_EN_ITEM_REGEN_OVERFLOW_TYPE_ALLOW, 0)
+	logical_time.SetGlobalLogicalOffset(0)
+	start := logical_time.GetLogicalNow().Unix()
+
+	// Act
+	logical_time.SetGlobalLogicalOffset(time.Hour + 3*time.Minute)
+	afterHour := logical_time.GetLogicalNow().Unix()
+	countAfterHour, timepointAfterHour := calculateItemRegen(cfg, 1, start, afterHour)
+	countNoInterval, timepointNoInterval := calculateItemRegen(cfg, countAfterHour, timepointAfterHour, afterHour)
+
+	logical_time.SetGlobalLogicalOffset(24 * time.Hour)
+	afterDay := logical_time.GetLogicalNow().Unix()
+	countAfterDay, timepointAfterDay := calculateItemRegen(cfg, 1, start, afterDay)
+	countOverflow, timepointOverflow := calculateItemRegen(cfg, 150, start, afterDay)
+
+	logical_time.SetGlobalLogicalOffset(-time.Hour)
+	rollback := logical_time.GetLogicalNow().Unix()
+	countRollback, timepointRollback := calculateItemRegen(cfg, 1, start, rollback)
+
+	// Assert
+	assert.Equal(t, int64(11), countAfterHour)
+	assert.Equal(t, start+int64(time.Hour.Seconds()), timepointAfterHour)
+	assert.Equal(t, countAfterHour, countNoInterval)
+	assert.Equal(t, timepointAfterHour, timepointNoInterval)
+
+	assert.Equal(t, int64(120), countAfterDay)
+	assert.Equal(t, afterDay, timepointAfterDay)
+	assert.Equal(t, int64(150), countOverflow)
+	assert.Equal(t, afterDay, timepointOverflow)
+
+	assert.Equal(t, int64(1), countRollback)
+	assert.Equal(t, rollback, timepointRollback)
+}
+
+// TestItemRegenOverflow 测试获得可回复道具时的溢出规则
+// Scenario: 允许溢出时只受最大数量限制，丢弃模式只发放到回复上限，拒绝模式超过回复上限时失败
+func TestItemRegenOverflow(t *testing.T) {
+	// Arrange
+	allow := newTestItemRegen(public_protocol_common.EnItemRegenOverflowType_EN_ITEM_REGEN_OVERFLOW_TYPE_ALLOW, 0)
+	allowLimited := newTestItemRegen(public_protocol_common.EnItemRegenOverflowType_EN_ITEM_REGEN_OVERFLOW_TYPE_ALLOW, 200)
+	discard := newTestItemRegen(public_protocol_common.EnItemRegenOverflowType_EN_ITEM_REGEN_OVERFLOW_TYPE_DISCARD, 0)
+	reject := newTestItemRegen(public_protocol_common.EnItemRegenOverflowType_EN_ITEM_REGEN_OVERFLOW_TYPE_REJECT, 0)
+	overflowCode := int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_ITEM_REGEN_OVERFLOW)
+
+	// Act & Assert
+	count, resultCode := calculateItemRegenAddCount(allow, 110, 60)
+	assert.Equal(t, int64(60), count)
+	assert.Equal(t, int32(0), resultCode)
+
+	count, resultCode = calculateItemRegenAddCount(allowLimited, 150, 50)
+	assert.Equal(t, int64(50), count)
+	assert.Equal(t, int32(0), resultCode)
+	_, resultCode = calculateItemRegenAddCount(allowLimited, 150, 60)
+	assert.Equal(t, overflowCode, resultCode)
+
+	count, resultCode = calculateItemRegenAddCount(discard, 110, 20)
+	assert.Equal(t, int64(10), count)
+	assert.Equal(t, int32(0), resultCode)
+	count, resultCode = calculateItemRegenAddCount(discard, 130, 20)
+	assert.Equal(t, int64(0), count)
+	assert.Equal(t, int32(0), resultCode)
+
+	count, resultCode = calculateItemRegenAddCount(reject, 110, 10)
+	assert.Equal(t, int64(10), count)
+	assert.Equal(t, int32(0), resultCode)
+	_, resultCode = calculateItemRegenAddCount(reject, 110, 11)
+	assert.Equal(t, overflowCode, resultCode)
+}
+
+// TestItemRegenManager 测试背包中可回复道具随逻辑时间回复
+// Scenario: GM 把时间往后调后查询数量时结算回复，扣光后保留回复进度继续回复，回复满后允许溢出获得，扣除到上限以下后从扣除时开始重新计时
+func TestItemRegenManager(t *testing.T) {
+	// Arrange
+	defer logical_time.SetGlobalLogicalOffset(0)
+	cfg := newTestItemRegen(public_protocol_common.EnItemRegenOverflowType_EN_ITEM_REGEN_OVERFLOW_TYPE_ALLOW, 0)
+	originGetItemRegenConfigFn := getItemRegenConfigFn
+	getItemRegenConfigFn = func(typeId int32) *public_protocol_config.Readonly_ExcelItemRegen {
+		if typeId == cfg.GetItemId() {
+			return cfg
+		}
+		return nil
+	}
+	defer func() {
+		getItemRegenConfigFn = originGetItemRegenConfigFn
+	}()
+
+	logical_time.SetGlobalLogicalOffset(0)
+	app, _ := test_utility.CreateMemoryStorageApp()
+	ctx := test_utility.NewMockRpcContext(app)
+	start := ctx.GetNow().Unix()
+
+	user, result := data.CreateUserForTest(ctx, testRegenZoneId, testRegenUserId, &private_protocol_pbdesc.DatabaseTableUser{
+		InventoryData: &private_protocol_pbdesc.UserInventoryData{
+			Item: []*public_protocol_common.DItemInstance{
+				{
+					ItemBasic:      &public_protocol_common.DItemBasic{TypeId: testRegenItemId, Count: 10},
+					RegenTimepoint: start,
+				},
+			},
+		},
+	})
+	assert.False(t, result.IsError())
+	mgr, ok := data.UserGetModuleManager[logic_inventory.UserInventoryManager](user).(*UserInventoryManager)
+	assert.True(t, ok)
+	subItem := func(count int64) {
+		assert.False(t, mgr.SubItem(ctx, []*data.ItemSubGuard{
+			{Item: &public_protocol_common.DItemBasic{TypeId: testRegenItemId, Count: count}},
+		}, &data.ItemFlowReason{}).IsError())
+	}
+
+	// Act & Assert: 半小时回复 5 个
+	logical_time.SetGlobalLogicalOffset(30 * time.Minute)
+	assert.Equal(t, int64(15), mgr.GetTypeStatistics(ctx, testRegenItemId).GetTotalCount())
+
+	// Act & Assert: 扣光后保留回复进度，再过半小时回复 5 个
+	subItem(15)
+	assert.Equal(t, int64(0), mgr.GetTypeStatistics(ctx, testRegenItemId).GetTotalCount())
+	logical_time.SetGlobalLogicalOffset(time.Hour)
+	assert.Equal(t, int64(5), mgr.GetTypeStatistics(ctx, testRegenItemId).GetTotalCount())
+
+	// Act & Assert: 一天后回复满，允许溢出时可以继续获得，超过上限后不再回复
+	logical_time.SetGlobalLogicalOffset(24 * time.Hour)
+	assert.Equal(t, cfg.GetRegenCap(), mgr.GetTypeStatistics(ctx, testRegenItemId).GetTotalCount())
+	assert.False(t, mgr.AddItem(ctx, []*data.ItemAddGuard{
+		{Item: &public_protocol_common.DItemInstance{ItemBasic: &public_protocol_common.DItemBasic{TypeId: testRegenItemId, Count: 10}}},
+	}, &data.ItemFlowReason{}).IsError())
+	logical_time.SetGlobalLogicalOffset(25 * time.Hour)
+	assert.Equal(t, cfg.GetRegenCap()+10, mgr.GetTypeStatistics(ctx, testRegenItemId).GetTotalCount())
+
+	// Act & Assert: 扣除到上限以下后从扣除时开始计时
+	subItem(20)
+	assert.Equal(t, cfg.GetRegenCap()-10, mgr.GetTypeStatistics(ctx, testRegenItemId).GetTotalCount())
+	logical_time.SetGlobalLogicalOffset(25*time.Hour + 6*time.Minute)
+	assert.Equal(t, cfg.GetRegenCap()-9, mgr.GetTypeStatistics(ctx, testRegenItemId).GetTotalCount())
+}
@@ -1,10 +1,14 @@
 package lobbysvr_logic_inventory
 
 import (
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
 	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
 )
 
 type UserInventoryManager interface {
 	data.UserItemManagerImpl
 	data.UserModuleManagerImpl
+
+	// GetItemRegenRefillCount 可回复道具补满到回复上限需要的数量，不是可回复道具或者已经满了时返回 0
+	GetItemRegenRefillCount(ctx cd.RpcContext, typeId int32) int64
 }
@@ -79,6 +79,7 @@ func (t *TaskActionMailGetAll) Run(_startData *component_dispatcher.DispatcherSt
 				// 合并邮件内容和记录
 				out := &public_protocol_pbdesc.DMailContent{}
 				mail.MailMergeContentAndRecord(out, mailData.Content, mailData.Record)
+				mailMgr.LocalizeMailContent(out)
 				responseBody.Mails = append(responseBody.Mails, out)
 			}
 			return true
@@ -19,6 +19,7 @@ import (
 	logic_mail "github.com/atframework/atsf4g-go/service-lobbysvr/logic/mail"
 	mail_action "github.com/atframework/atsf4g-go/service-lobbysvr/logic/mail/action"
 	mail_data "github.com/atframework/atsf4g-go/service-lobbysvr/logic/mail/data"
+	logic_user "github.com/atframework/atsf4g-go/service-lobbysvr/logic/user"
 	service_protocol "github.com/atframework/atsf4g-go/service-lobbysvr/protocol/public/protocol/pbdesc"
 	rpc_lobbyclientservice "github.com/atframework/atsf4g-go/service-lobbysvr/rpc/lobbyclientservice"
 )
@@ -47,6 +48,9 @@ type UserMailManager struct {
 	pendingRemoveList   mail_data.MailRecordMap // 待移除队列
 	receivedGlobalMails mail_data.MailRecordMap // 已接收的全服邮件
 
+	// 因mail_limit动态条件不满足而暂未接收的全服邮件及其限制条件，条件满足后需要重新合并
+	limitedGlobalMails map[int64][]*public_protocol_common.Readonly_DConditionRule
+
 	dirtyCache *mail_data.DirtyCache // 脏数据缓存
 
 	unreadMailCount               int32 // 未读邮件数量
@@ -69,6 +73,7 @@ func CreateUserMailManager(owner *data.User) *UserMailManager {
 		mailBoxUnloadedIndex:          make(mail_data.MailIndex),
 		pendingRemoveList:             make(mail_data.MailRecordMap),
 		receivedGlobalMails:           make(mail_data.MailRecordMap),
+		limitedGlobalMails:            make(map[int64][]*public_protocol_common.Readonly_DConditionRule),
 		dirtyCache:                    mail_data.NewDirtyCache(),
 		mailAsyncTaskProtectTimepoint: time.Time{},
 		unreadMailCount:               0,
@@ -93,6 +98,32 @@ func (m *UserMailManager) RefreshLimitSecond(ctx cd.RpcContext) {
 	}
 }
 
+func (m *UserMailManager) RefreshLimitMinute(ctx cd.RpcContext) {
+	if len(m.limitedGlobalMails) == 0 || !m.isGlobalMailsMerged {
+		return
+	}
+
+	conditionMgr := data.UserGetModuleManager[logic_condition.UserConditionManager](m.GetOwner())
+	if conditionMgr == nil {
+		return
+	}
+
+	// 只有受限的全服邮件条件变为满足时才重新合并，避免每分钟都启动异步任务
+	if m.hasLimitedGlobalMailSatisfied(ctx, conditionMgr) {
+		m.isGlobalMailsMerged = false
+	}
+}
+
+// hasLimitedGlobalMailSatisfied 检查是否有受限的全服邮件的动态条件已经满足
+func (m *UserMailManager) hasLimitedGlobalMailSatisfied(ctx cd.RpcContext, conditionMgr logic_condition.UserConditionManager) bool {
+	for _, rules := range m.limitedGlobalMails {
+		if conditionMgr.CheckDynamicRules(ctx, rules, logic_condition.CreateEmptyRuleCheckerRuntime()).IsOK() {
+			return true
+		}
+	}
+	return false
+}
+
 func (m *UserMailManager) InitFromDB(ctx cd.RpcContext, dbUser *private_protocol_pbdesc.DatabaseTableUser) cd.RpcResult {
 
 	m.mailBoxIdIndex = make(mail_data.MailIndex)
@@ -100,6 +131,7 @@ func (m *UserMailManager) InitFromDB(ctx cd.RpcContext, dbUser *private_protocol
 	m.pendingRemoveList = make(mail_data.MailRecordMap)
 	m.mailBoxUnloadedIndex = make(mail_data.MailIndex)
 	m.receivedGlobalMails = make(mail_data.MailRecordMap)
+	m.limitedGlobalMails = make(map[int64][]*public_protocol_common.Readonly_DConditionRule)
 
 	m.isDirty = false
 	m.isGlobalMailsMerged = false
@@ -662,6 +694,7 @@ func (m *UserMailManager) SendAllSyncData(ctx cd.RpcContext) {
 
 		peddingAddMail := public_protocol_pbdesc.DMailContent{}
 		peddingAddMail = *idIter.Content.Clone()
+		m.LocalizeMailContent(&peddingAddMail)
 		syncData.NewMails = append(syncData.NewMails, &peddingAddMail)
 
 		delete(m.dirtyCache.NewMails, mailId)
@@ -1027,17 +1060,20 @@ func (m *UserMailManager) MergeGlobalMails(ctx cd.RpcContext) (cd.RpcResult, int
 		return cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_UNKNOWN), 0
 	}
 
+	clear(m.limitedGlobalMails)
 	for _, mail := range logic_global_mail.GetUserRouterManager(ctx.GetApp()).GetAllGlobalMails() {
 		if mail.MailData.Content != nil {
-			// 检查各类限制后添加
-			if len(mail.MailData.Content.MailLimit) > 0 {
-				readOnlyRuls := []*public_protocol_common.Readonly_DConditionRule{}
-				for _, rule := range mail.MailData.Content.MailLimit {
-					readOnlyRuls = append(readOnlyRuls, rule.ToReadonly())
-				}
-				ok := conditionMgr.CheckRules(ctx, readOnlyRuls, logic_condition.CreateEmptyRuleCheckerRuntime())
-				if !ok.IsOK() {
-					ctx.LogDebug("CheckRules failed", "user_id", m.GetOwner().GetUserId(), "mail_id", mail.MailData.Record.GetMailId(), "error", ok.GetStandardError())
+			// 已接收过的全服邮件只刷新记录，不再检查限制
+			if _, received := m.receivedGlobalMails[mail.MailData.Record.GetMailId()]; !received {
+				// 检查各类限制后添加
+				limitRules := makeGlobalMailLimitRules(mail.MailData.Content)
+				limitResult, isDynamic := m.checkGlobalMailLimit(ctx, conditionMgr, limitRules)
+				if !limitResult.IsOK() {
+					ctx.LogDebug("global mail limit check failed", "user_id", m.GetOwner().GetUserId(),
+						"mail_id", mail.MailData.Record.GetMailId(), "is_dynamic", isDynamic, "error", limitResult.GetStandardError())
+					if isDynamic {
+						m.limitedGlobalMails[mail.MailData.Record.GetMailId()] = limitRules
+					}
 					continue
 				}
 			}
@@ -1050,6 +1086,55 @@ func (m *UserMailManager) MergeGlobalMails(ctx cd.RpcContext) (cd.RpcResult, int
 	return cd.CreateRpcResultOk(), ret
 }
 
+func (m *UserMailManager) LocalizeMailContent(mail *public_protocol_pbdesc.DMailContent) {
+	if mail == nil || len(mail.GetLanguageData()) == 0 {
+		return
+	}
+
+	language := mail_util.MailGetServerLanguage()
+	if basicMgr := data.UserGetModuleManager[logic_user.UserBasicManager](m.GetOwner()); basicMgr != nil {
+		language = basicMgr.GetUserLanguage()
+	}
+
+	mail_util.MailApplyLanguage(mail, language)
+}
+
+// makeGlobalMailLimitRules 转换全服邮件的 mail_limit 条件
+func makeGlobalMailLimitRules(content *public_protocol_pbdesc.DMailContent) []*public_protocol_common.Readonly_DConditionRule {
+	if len(content.GetMailLimit()) == 0 {
+		return nil
+	}
+
+	ret := make([]*public_protocol_common.Readonly_DConditionRule, 0, len(content.GetMailLimit()))
+	for _, rule := range content.GetMailLimit() {
+		ret = append(ret, rule.ToReadonly())
+	}
+	return ret
+}
+
+// checkGlobalMailLimit 检查全服邮件的 mail_limit 条件
+// 返回值 isDynamic 表示是否只是动态条件不满足（之后可能满足）
+func (m *UserMailManager) checkGlobalMailLimit(ctx cd.RpcContext, conditionMgr logic_condition.UserConditionManager,
+	readOnlyRules []*public_protocol_common.Readonly_DConditionRule,
+) (cd.RpcResult, bool) {
+	if len(readOnlyRules) == 0 {
+		return cd.CreateRpcResultOk(), false
+	}
+
+	runtime := logic_condition.CreateEmptyRuleCheckerRuntime()
+	result := conditionMgr.CheckStaticRules(ctx, readOnlyRules, runtime)
+	if !result.IsOK() {
+		return result, false
+	}
+
+	result = conditionMgr.CheckDynamicRules(ctx, readOnlyRules, runtime)
+	if !result.IsOK() {
+		return result, true
+	}
+
+	return cd.CreateRpcResultOk(), false
+}
+
 // FetchAllUnloadedMails 获取所有未加载内容的邮件ID
 func (m *UserMailManager) FetchAllUnloadedMails(ctx cd.RpcContext) []int64 {
 	var invalidMailIds []int64
@@ -1,8 +1,6 @@
 package lobbysvr_logic_mail_internal
 
 import (
-	"context"
-	"log/slog"
 	"testing"
 	"time"
 
@@ -10,6 +8,8 @@ import (
 	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
 	public_protocol_common "github.com/atframework/atsf4g-go/component/protocol/public/common/protocol/common"
 	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	test_utility "github.com/atframework/atsf4g-go/component/test_utility"
+	logic_condition "github.com/atframework/atsf4g-go/service-lobbysvr/logic/condition"
 	global_mail_impl "github.com/atframework/atsf4g-go/service-lobbysvr/logic/global_mail/impl"
 	mail_data "github.com/atframework/atsf4g-go/service-lobbysvr/logic/mail/data"
 	"github.com/atframework/libatapp-go"
@@ -18,42 +18,13 @@ import (
 
 // ==================== Mock RpcContext ====================
 
-// mockRpcContext 用于测试的模拟 RpcContext
-type mockRpcContext struct {
-	app libatapp.AppImpl
-}
-
-func newMockRpcContext() *mockRpcContext {
+// newMockRpcContext 创建测试用的 RpcContext
+func newMockRpcContext() *test_utility.MockRpcContext {
 	app := libatapp.CreateAppInstance()
 	// 注册 GlobalMailManager 模块，AddGlobalMail 等方法需要通过 app 获取该模块
 	libatapp.AtappAddModule(app, global_mail_impl.NewGlobalMailManager(app))
-	return &mockRpcContext{
-		app: app,
-	}
-}
-
-func (m *mockRpcContext) GetNow() time.Time                                          { return time.Now() }
-func (m *mockRpcContext) GetSysNow() time.Time                                       { return time.Now() }
-func (m *mockRpcContext) GetApp() libatapp.AppImpl                                   { return m.app }
-func (m *mockRpcContext) GetAction() cd.TaskActionImpl                               { return nil }
-func (m *mockRpcContext) BindAction(_ cd.TaskActionImpl)                             {}
-func (m *mockRpcContext) GetContext() context.Context                                { return context.Background() }
-func (m *mockRpcContext) GetCancelFn() context.CancelFunc                            { return nil }
-func (m *mockRpcContext) SetContext(_ context.Context)                               {}
-func (m *mockRpcContext) SetCancelFn(_ context.CancelFunc)                           {}
-func (m *mockRpcContext) SetContextCancelFn(_ context.Context, _ context.CancelFunc) {}
-
-func (m *mockRpcContext) LogWithLevelContextWithCaller(_ uintptr, _ context.Context, _ slog.Level, _ string, _ ...any) {
+	return test_utility.NewMockRpcContext(app)
 }
-func (m *mockRpcContext) LogWithLevelWithCaller(_ uintptr, _ slog.Level, _ string, _ ...any) {}
-func (m *mockRpcContext) LogErrorContext(_ context.Context, _ string, _ ...any)              {}
-func (m *mockRpcContext) LogError(_ string, _ ...any)                                        {}
-func (m *mockRpcContext) LogWarnContext(_ context.Context, _ string, _ ...any)               {}
-func (m *mockRpcContext) LogWarn(_ string, _ ...any)                                         {}
-func (m *mockRpcContext) LogInfoContext(_ context.Context, _ string, _ ...any)               {}
-func (m *mockRpcContext) LogInfo(_ string, _ ...any)                                         {}
-func (m *mockRpcContext) LogDebugContext(_ context.Context, _ string, _ ...any)              {}
-func (m *mockRpcContext) LogDebug(_ string, _ ...any)                                        {}
 
 // ==================== 辅助函数 ====================
 
@@ -68,6 +39,7 @@ func newTestUserMailManager() *UserMailManager {
 		mailBoxUnloadedIndex:          make(mail_data.MailIndex),
 		pendingRemoveList:             make(mail_data.MailRecordMap),
 		receivedGlobalMails:           make(mail_data.MailRecordMap),
+		limitedGlobalMails:            make(map[int64][]*public_protocol_common.Readonly_DConditionRule),
 		dirtyCache:                    mail_data.NewDirtyCache(),
 		mailAsyncTaskProtectTimepoint: time.Time{},
 	}
@@ -1188,3 +1160,62 @@ func TestNeedStartAsyncJobsNoWork(t *testing.T) {
 	// Assert
 	assert.False(t, result, "should not need async jobs when no work to do")
 }
+
+// fakeDynamicRuleConditionManager 只实现 CheckDynamicRules，结果由测试指定
+type fakeDynamicRuleConditionManager struct {
+	logic_condition.UserConditionManager
+	satisfied bool
+}
+
+func (f *fakeDynamicRuleConditionManager) CheckDynamicRules(_ctx cd.RpcContext, _rules []*public_protocol_common.Readonly_DConditionRule,
+	_runtime *logic_condition.RuleCheckerRuntime,
+) cd.RpcResult {
+	if f.satisfied {
+		return cd.CreateRpcResultOk()
+	}
+	return cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_INVALID_PARAM)
+}
+
+// TestRefreshLimitMinuteRemergeLimitedGlobalMails 测试受限全服邮件的重新合并
+// Scenario: 没有受限全服邮件或者无法检查条件时保持合并标记，不会每分钟启动异步任务
+func TestRefreshLimitMinuteRemergeLimitedGlobalMails(t *testing.T) {
+	// Arrange
+	mgr := newTestUserMailManager()
+	ctx := newMockRpcContext()
+	mgr.isGlobalMailsMerged = true
+
+	// Act
+	mgr.RefreshLimitMinute(ctx)
+
+	// Assert
+	assert.True(t, mgr.isGlobalMailsMerged, "no limited mails, should keep merged flag")
+
+	// Arrange
+	mgr.limitedGlobalMails[2001] = nil
+
+	// Act
+	mgr.RefreshLimitMinute(ctx)
+
+	// Assert
+	assert.True(t, mgr.isGlobalMailsMerged, "no condition manager, should keep merged flag")
+	assert.False(t, mgr.needStartAsyncJobs(ctx.GetNow()))
+}
+
+// TestHasLimitedGlobalMailSatisfied 测试受限全服邮件的条件检查
+// Scenario: 动态条件仍不满足时不需要重新合并，条件变为满足后需要重新合并
+func TestHasLimitedGlobalMailSatisfied(t *testing.T) {
+	// Arrange
+	mgr := newTestUserMailManager()
+	ctx := newMockRpcContext()
+	mgr.limitedGlobalMails[2001] = []*public_protocol_common.Readonly_DConditionRule{
+		(&public_protocol_common.DConditionRule{}).ToReadonly(),
+	}
+	conditionMgr := &fakeDynamicRuleConditionManager{}
+
+	// Act & Assert: 条件不满足
+	assert.False(t, mgr.hasLimitedGlobalMailSatisfied(ctx, conditionMgr))
+
+	// Act & Assert: 条件变为满足
+	conditionMgr.satisfied = true
+	assert.True(t, mgr.hasLimitedGlobalMailSatisfied(ctx, conditionMgr))
+}
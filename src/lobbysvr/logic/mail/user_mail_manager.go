@@ -38,6 +38,9 @@ type UserMailManager interface {
 	// ReceiveMailAttachmentsAll 领取邮件所有附件
 	ReceiveMailAttachmentsAll(ctx cd.RpcContext, needRemove bool) ([]*public_protocol_pbdesc.DMailOperationResult, cd.RpcResult)
 
+	// LocalizeMailContent 按玩家偏好语言替换下发给客户端的邮件标题和正文
+	LocalizeMailContent(mail *public_protocol_pbdesc.DMailContent)
+
 	// PackMailUser 打包邮件用户信息
 	PackMailUser(userInfo *public_protocol_pbdesc.DMailUserInfo)
 
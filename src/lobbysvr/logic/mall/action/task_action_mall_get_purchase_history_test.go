@@ -0,0 +1,69 @@
+package lobbysvr_logic_mall_action
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	test_utility "github.com/atframework/atsf4g-go/component/test_utility"
+	user_controller "github.com/atframework/atsf4g-go/component/user_controller"
+
+	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
+	_ "github.com/atframework/atsf4g-go/service-lobbysvr/logic/mall/impl"
+	service_protocol "github.com/atframework/atsf4g-go/service-lobbysvr/protocol/public/protocol/pbdesc"
+)
+
+// TestTaskActionMallGetPurchaseHistory 测试查询购买记录
+// Scenario: 返回存档中的购买记录，按时间从新到旧排列
+func TestTaskActionMallGetPurchaseHistory(t *testing.T) {
+	// Arrange
+	ctx := test_utility.NewMockRpcContext(nil)
+	user, result := data.CreateUserForTest(ctx, 1, 10001, &private_protocol_pbdesc.DatabaseTableUser{
+		MallData: &private_protocol_pbdesc.UserMallData{
+			PurchaseHistory: []*public_protocol_pbdesc.DMallPurchaseRecord{
+				{ProductId: 1, PurchaseTime: 100},
+				{ProductId: 2, PurchaseTime: 200},
+				{ProductId: 3, PurchaseTime: 300},
+			},
+		},
+	})
+	assert.False(t, result.IsError())
+
+	action := &TaskActionMallGetPurchaseHistory{
+		TaskActionCSBase: user_controller.CreateCSTaskActionBaseWithUser(user, &service_protocol.CSMallGetPurchaseHistoryReq{},
+			func() *service_protocol.SCMallGetPurchaseHistoryRsp {
+				return &service_protocol.SCMallGetPurchaseHistoryRsp{}
+			}),
+	}
+
+	// Act
+	err := action.Run(nil)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, int32(0), action.GetResponseCode())
+	records := action.MutableResponseBody().GetRecords()
+	assert.Len(t, records, 3)
+	assert.Equal(t, []int32{3, 2, 1}, []int32{records[0].GetProductId(), records[1].GetProductId(), records[2].GetProductId()})
+}
+
+// TestTaskActionMallGetPurchaseHistoryWithoutUser 测试未登入时查询购买记录
+// Scenario: 没有玩家对象时返回 EN_ERR_USER_NOT_FOUND
+func TestTaskActionMallGetPurchaseHistoryWithoutUser(t *testing.T) {
+	// Arrange
+	action := &TaskActionMallGetPurchaseHistory{
+		TaskActionCSBase: user_controller.CreateCSTaskActionBaseWithUser(nil, &service_protocol.CSMallGetPurchaseHistoryReq{},
+			func() *service_protocol.SCMallGetPurchaseHistoryRsp {
+				return &service_protocol.SCMallGetPurchaseHistoryRsp{}
+			}),
+	}
+
+	// Act
+	err := action.Run(nil)
+
+	// Assert
+	assert.Error(t, err)
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_USER_NOT_FOUND), action.GetResponseCode())
+}
@@ -0,0 +1,108 @@
+package lobbysvr_logic_mall_impl
+
+import (
+	public_protocol_common "github.com/atframework/atsf4g-go/component/protocol/public/common/protocol/common"
+	public_protocol_config "github.com/atframework/atsf4g-go/component/protocol/public/config/protocol/config"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+)
+
+// 折扣万分比基数
+const mallDiscountRatioBase int64 = 10000
+
+type mallProductKey struct {
+	productId        int32
+	purchasePriority int32
+}
+
+// mallProductBaseCost 按已购买次数选择阶梯价格，未命中阶梯时使用 purchase_cost
+func mallProductBaseCost(productRow *public_protocol_config.Readonly_ExcelMallProduct,
+	purchasedCount int64,
+) []*public_protocol_common.Readonly_DItemOffset {
+	ret := productRow.GetPurchaseCost()
+	matchedCount := int64(-1)
+	for _, tier := range productRow.GetDiscount().GetTieredPrice() {
+		if purchasedCount >= tier.GetMinPurchasedCount() && tier.GetMinPurchasedCount() > matchedCount {
+			ret = tier.GetPurchaseCost()
+			matchedCount = tier.GetMinPurchasedCount()
+		}
+	}
+	return ret
+}
+
+// mallProductDiscountRatio 计算生效的折扣，多种折扣取最低，返回折扣万分比和限时折扣结束时间(未使用限时折扣时为0)
+func mallProductDiscountRatio(productRow *public_protocol_config.Readonly_ExcelMallProduct,
+	purchasedCount int64, now int64,
+) (int64, int64) {
+	discount := productRow.GetDiscount()
+	ratio := mallDiscountRatioBase
+	var endTime int64
+
+	if purchasedCount == 0 && discount.GetFirstPurchaseRatio() > 0 && int64(discount.GetFirstPurchaseRatio()) < ratio {
+		ratio = int64(discount.GetFirstPurchaseRatio())
+	}
+
+	if discount.GetTimeLimitRatio() > 0 && int64(discount.GetTimeLimitRatio()) < ratio &&
+		now >= discount.GetTimeLimitStart().GetSeconds() &&
+		(discount.GetTimeLimitEnd().GetSeconds() <= 0 || now < discount.GetTimeLimitEnd().GetSeconds()) {
+		ratio = int64(discount.GetTimeLimitRatio())
+		endTime = discount.GetTimeLimitEnd().GetSeconds()
+	}
+
+	return ratio, endTime
+}
+
+// mallProductEffectiveCost 计算从已购买 purchasedCount 次开始连续购买 purchaseCount 次的总价
+// 每一次购买单独计算阶梯和折扣，折扣后向上取整
+func mallProductEffectiveCost(productRow *public_protocol_config.Readonly_ExcelMallProduct,
+	purchasedCount int64, purchaseCount int32, now int64,
+) []*public_protocol_common.DItemOffset {
+	ret := make([]*public_protocol_common.DItemOffset, 0, len(productRow.GetPurchaseCost()))
+	indexByTypeId := make(map[int32]int)
+	for i := int64(0); i < int64(purchaseCount); i++ {
+		ratio, _ := mallProductDiscountRatio(productRow, purchasedCount+i, now)
+		for _, cost := range mallProductBaseCost(productRow, purchasedCount+i) {
+			count := (cost.GetCount()*ratio + mallDiscountRatioBase - 1) / mallDiscountRatioBase
+			if count <= 0 {
+				continue
+			}
+
+			if idx, ok := indexByTypeId[cost.GetTypeId()]; ok {
+				ret[idx].Count += count
+				continue
+			}
+
+			indexByTypeId[cost.GetTypeId()] = len(ret)
+			ret = append(ret, &public_protocol_common.DItemOffset{
+				TypeId: cost.GetTypeId(),
+				Count:  count,
+			})
+		}
+	}
+	return ret
+}
+
+// makeMallProductPrice 生成下发给客户端的商品当前价格
+func makeMallProductPrice(productRow *public_protocol_config.Readonly_ExcelMallProduct,
+	purchasedCount int64, now int64,
+) *public_protocol_pbdesc.DMallProductPrice {
+	ratio, endTime := mallProductDiscountRatio(productRow, purchasedCount, now)
+	ret := &public_protocol_pbdesc.DMallProductPrice{
+		ProductId:        productRow.GetProductId(),
+		PurchasePriority: productRow.GetPurchasePriority(),
+		DiscountRatio:    int32(ratio),
+		DiscountEndTime:  endTime,
+	}
+
+	for _, cost := range mallProductEffectiveCost(productRow, purchasedCount, 1, now) {
+		ret.EffectiveCost = append(ret.EffectiveCost, &public_protocol_common.DItemBasic{
+			TypeId: cost.GetTypeId(),
+			Count:  cost.GetCount(),
+		})
+	}
+	return ret
+}
+
+func hasMallProductDiscount(productRow *public_protocol_config.Readonly_ExcelMallProduct) bool {
+	discount := productRow.GetDiscount()
+	return discount.GetTimeLimitRatio() > 0 || discount.GetFirstPurchaseRatio() > 0 || len(discount.GetTieredPrice()) > 0
+}
@@ -0,0 +1,182 @@
+package lobbysvr_logic_mall_impl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	public_protocol_common "github.com/atframework/atsf4g-go/component/protocol/public/common/protocol/common"
+	public_protocol_config "github.com/atframework/atsf4g-go/component/protocol/public/config/protocol/config"
+)
+
+const testMallCostTypeId int32 = 1001
+
+func newTestDiscountProduct(discount *public_protocol_common.DMallDiscountCfg, cost int64) *public_protocol_config.Readonly_ExcelMallProduct {
+	return (&public_protocol_config.ExcelMallProduct{
+		ProductId:        1,
+		PurchasePriority: 1,
+		PurchaseCost:     []*public_protocol_common.DItemOffset{{TypeId: testMallCostTypeId, Count: cost}},
+		Discount:         discount,
+	}).ToReadonly()
+}
+
+func newTestTieredPrice(minPurchasedCount int64, cost int64) *public_protocol_common.DMallTieredPrice {
+	return &public_protocol_common.DMallTieredPrice{
+		MinPurchasedCount: minPurchasedCount,
+		PurchaseCost:      []*public_protocol_common.DItemOffset{{TypeId: testMallCostTypeId, Count: cost}},
+	}
+}
+
+// TestMallProductBaseCostTierBoundaries 测试阶梯价格边界
+// Scenario: 未达到第一档时使用原价，正好达到某一档时使用该档价格，配置顺序不影响结果
+func TestMallProductBaseCostTierBoundaries(t *testing.T) {
+	// Arrange
+	productRow := newTestDiscountProduct(&public_protocol_common.DMallDiscountCfg{
+		TieredPrice: []*public_protocol_common.DMallTieredPrice{
+			newTestTieredPrice(10, 300),
+			newTestTieredPrice(3, 200),
+		},
+	}, 100)
+
+	tests := []struct {
+		name           string
+		purchasedCount int64
+		expectCost     int64
+	}{
+		{"below first tier", 2, 100},
+		{"first tier boundary", 3, 200},
+		{"inside first tier", 9, 200},
+		{"second tier boundary", 10, 300},
+		{"above last tier", 100, 300},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			cost := mallProductBaseCost(productRow, tt.purchasedCount)
+
+			// Assert
+			assert.Len(t, cost, 1)
+			assert.Equal(t, tt.expectCost, cost[0].GetCount())
+		})
+	}
+}
+
+// TestMallProductDiscountRatioWindows 测试限时折扣和首购折扣
+// Scenario: 限时折扣只在 [start, end) 内生效，end 为 0 表示不结束，多种折扣取最低
+func TestMallProductDiscountRatioWindows(t *testing.T) {
+	// Arrange
+	timeLimited := newTestDiscountProduct(&public_protocol_common.DMallDiscountCfg{
+		TimeLimitStart: &timestamppb.Timestamp{Seconds: 1000},
+		TimeLimitEnd:   &timestamppb.Timestamp{Seconds: 2000},
+		TimeLimitRatio: 8000,
+	}, 100)
+	noEnd := newTestDiscountProduct(&public_protocol_common.DMallDiscountCfg{
+		TimeLimitStart: &timestamppb.Timestamp{Seconds: 1000},
+		TimeLimitRatio: 8000,
+	}, 100)
+	combined := newTestDiscountProduct(&public_protocol_common.DMallDiscountCfg{
+		TimeLimitStart:     &timestamppb.Timestamp{Seconds: 1000},
+		TimeLimitEnd:       &timestamppb.Timestamp{Seconds: 2000},
+		TimeLimitRatio:     8000,
+		FirstPurchaseRatio: 5000,
+	}, 100)
+
+	tests := []struct {
+		name           string
+		productRow     *public_protocol_config.Readonly_ExcelMallProduct
+		purchasedCount int64
+		now            int64
+		expectRatio    int64
+		expectEndTime  int64
+	}{
+		{"before window", timeLimited, 1, 999, mallDiscountRatioBase, 0},
+		{"window start", timeLimited, 1, 1000, 8000, 2000},
+		{"window last second", timeLimited, 1, 1999, 8000, 2000},
+		{"window end", timeLimited, 1, 2000, mallDiscountRatioBase, 0},
+		{"no end time", noEnd, 1, 100000, 8000, 0},
+		{"first purchase lower than time limit", combined, 0, 1500, 5000, 0},
+		{"time limit after first purchase", combined, 1, 1500, 8000, 2000},
+		{"first purchase outside window", combined, 0, 3000, 5000, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			ratio, endTime := mallProductDiscountRatio(tt.productRow, tt.purchasedCount, tt.now)
+
+			// Assert
+			assert.Equal(t, tt.expectRatio, ratio)
+			assert.Equal(t, tt.expectEndTime, endTime)
+		})
+	}
+}
+
+// TestMallProductEffectiveCost 测试连续购买多次的总价
+// Scenario: 每次购买单独计算阶梯和折扣并向上取整，跨阶梯时分段累加
+func TestMallProductEffectiveCost(t *testing.T) {
+	// Arrange
+	tiered := newTestDiscountProduct(&public_protocol_common.DMallDiscountCfg{
+		FirstPurchaseRatio: 5000,
+		TieredPrice:        []*public_protocol_common.DMallTieredPrice{newTestTieredPrice(2, 150)},
+	}, 101)
+
+	tests := []struct {
+		name           string
+		purchasedCount int64
+		purchaseCount  int32
+		expectCost     int64
+	}{
+		// 51(首购五折向上取整) + 101
+		{"first purchase ceil", 0, 2, 51 + 101},
+		// 101 + 150 + 150
+		{"cross tier", 1, 3, 101 + 150 + 150},
+		{"all in tier", 5, 2, 300},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			cost := mallProductEffectiveCost(tiered, tt.purchasedCount, tt.purchaseCount, 0)
+
+			// Assert
+			assert.Len(t, cost, 1)
+			assert.Equal(t, testMallCostTypeId, cost[0].GetTypeId())
+			assert.Equal(t, tt.expectCost, cost[0].GetCount())
+		})
+	}
+}
+
+// TestMallBundleProductCost 测试捆绑包价格
+// Scenario: 捆绑包按自身 purchase_cost 打包价计算，多种货币分别累加，折扣同样生效
+func TestMallBundleProductCost(t *testing.T) {
+	// Arrange
+	bundleRow := (&public_protocol_config.ExcelMallProduct{
+		ProductId:        2,
+		PurchasePriority: 1,
+		PurchaseCost: []*public_protocol_common.DItemOffset{
+			{TypeId: testMallCostTypeId, Count: 500},
+			{TypeId: testMallCostTypeId + 1, Count: 3},
+		},
+		Discount: &public_protocol_common.DMallDiscountCfg{FirstPurchaseRatio: 8000},
+		BundleProducts: []*public_protocol_common.DMallBundleProduct{
+			{ProductId: 10, PurchasePriority: 1},
+			{ProductId: 11, PurchasePriority: 1},
+		},
+	}).ToReadonly()
+
+	// Act
+	cost := mallProductEffectiveCost(bundleRow, 0, 2, 0)
+	price := makeMallProductPrice(bundleRow, 0, 0)
+
+	// Assert
+	assert.Equal(t, []*public_protocol_common.DItemOffset{
+		{TypeId: testMallCostTypeId, Count: 400 + 500},
+		// 3 * 0.8 向上取整为 3
+		{TypeId: testMallCostTypeId + 1, Count: 3 + 3},
+	}, cost)
+	assert.Equal(t, int32(8000), price.GetDiscountRatio())
+	assert.Equal(t, int64(400), price.GetEffectiveCost()[0].GetCount())
+	assert.True(t, hasMallProductDiscount(bundleRow))
+}
@@ -0,0 +1,108 @@
+package lobbysvr_logic_mall_impl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_common "github.com/atframework/atsf4g-go/component/protocol/public/common/protocol/common"
+	public_protocol_config "github.com/atframework/atsf4g-go/component/protocol/public/config/protocol/config"
+	test_utility "github.com/atframework/atsf4g-go/component/test_utility"
+
+	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
+	logic_mall "github.com/atframework/atsf4g-go/service-lobbysvr/logic/mall"
+)
+
+func createTestMallManager(t *testing.T, ctx *test_utility.MockRpcContext) *UserMallManager {
+	user, result := data.CreateUserForTest(ctx, 1, 10001, nil)
+	assert.False(t, result.IsError())
+
+	mgr, ok := data.UserGetModuleManager[logic_mall.UserMallManager](user).(*UserMallManager)
+	assert.True(t, ok)
+	return mgr
+}
+
+func createTestPurchaseCheckResult(productId int32) *mallPurchaseCheckResult {
+	return &mallPurchaseCheckResult{
+		productRow: (&public_protocol_config.ExcelMallProduct{
+			ProductId:        productId,
+			PurchasePriority: 1,
+			MallSheetId:      100,
+		}).ToReadonly(),
+	}
+}
+
+// TestMallRecordPurchaseOrdering 测试购买记录的顺序
+// Scenario: 记录按购买顺序保存，查询时按时间从新到旧返回，并带上花费和订单号
+func TestMallRecordPurchaseOrdering(t *testing.T) {
+	// Arrange
+	app, _ := test_utility.CreateMemoryStorageApp()
+	ctx := test_utility.NewMockRpcContextWithNow(app, time.Unix(1700000000, 0))
+	mgr := createTestMallManager(t, ctx)
+	costItems := []*public_protocol_common.DItemBasic{{TypeId: 1001, Count: 60}}
+
+	// Act
+	mgr.recordPurchase(ctx, createTestPurchaseCheckResult(1), 1, costItems, nil, nil, "")
+	ctx.SetNow(time.Unix(1700000010, 0))
+	mgr.recordPurchase(ctx, createTestPurchaseCheckResult(2), 3, nil, nil, nil, "order-2")
+	history := mgr.GetPurchaseHistory()
+
+	// Assert
+	assert.Len(t, history, 2)
+	assert.Equal(t, int32(2), history[0].GetProductId())
+	assert.Equal(t, int32(3), history[0].GetPurchaseCount())
+	assert.Equal(t, int64(1700000010), history[0].GetPurchaseTime())
+	assert.Equal(t, "order-2", history[0].GetOrderId())
+	assert.Equal(t, int32(1), history[1].GetProductId())
+	assert.Equal(t, int32(100), history[1].GetMallSheetId())
+	assert.Equal(t, int64(60), history[1].GetCostItems()[0].GetCount())
+	assert.Empty(t, history[1].GetOrderId())
+}
+
+// TestMallRecordPurchaseHistoryCap 测试购买记录上限
+// Scenario: 超过系统上限时淘汰最早的记录，存盘后重新加载保持顺序
+func TestMallRecordPurchaseHistoryCap(t *testing.T) {
+	// Arrange
+	app, _ := test_utility.CreateMemoryStorageApp()
+	ctx := test_utility.NewMockRpcContext(app)
+	mgr := createTestMallManager(t, ctx)
+	maxCount := int(private_protocol_pbdesc.EnSystemLimit_EN_SL_MALL_PURCHASE_HISTORY_MAX_COUNT)
+
+	// Act
+	for i := 1; i <= maxCount+5; i++ {
+		mgr.recordPurchase(ctx, createTestPurchaseCheckResult(int32(i)), 1, nil, nil, nil, "")
+	}
+	dbUser := &private_protocol_pbdesc.DatabaseTableUser{}
+	mgr.DumpToDB(ctx, dbUser)
+	reloaded := createTestMallManager(t, ctx)
+	reloaded.InitFromDB(ctx, dbUser)
+	history := reloaded.GetPurchaseHistory()
+
+	// Assert
+	assert.Len(t, history, maxCount)
+	assert.Equal(t, int32(maxCount+5), history[0].GetProductId())
+	assert.Equal(t, int32(6), history[maxCount-1].GetProductId())
+}
+
+// TestMallPurchaseHistoryDumpIsolated 测试购买记录存盘和加载时不共享数据
+// Scenario: 存盘后修改数据库结构或加载后修改源数据，都不影响管理器中的购买记录
+func TestMallPurchaseHistoryDumpIsolated(t *testing.T) {
+	// Arrange
+	app, _ := test_utility.CreateMemoryStorageApp()
+	ctx := test_utility.NewMockRpcContext(app)
+	mgr := createTestMallManager(t, ctx)
+	mgr.recordPurchase(ctx, createTestPurchaseCheckResult(1), 1, nil, nil, nil, "order-1")
+
+	// Act
+	dbUser := &private_protocol_pbdesc.DatabaseTableUser{}
+	mgr.DumpToDB(ctx, dbUser)
+	reloaded := createTestMallManager(t, ctx)
+	reloaded.InitFromDB(ctx, dbUser)
+	dbUser.GetMallData().GetPurchaseHistory()[0].OrderId = "modified"
+
+	// Assert
+	assert.Equal(t, "order-1", mgr.GetPurchaseHistory()[0].GetOrderId())
+	assert.Equal(t, "order-1", reloaded.GetPurchaseHistory()[0].GetOrderId())
+}
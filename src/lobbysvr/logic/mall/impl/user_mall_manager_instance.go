@@ -8,11 +8,17 @@ import (
 	cd "github.com/atframework/atsf4g-go/component/dispatcher"
 
 	config "github.com/atframework/atsf4g-go/component/config"
+	logical_time "github.com/atframework/atsf4g-go/component/logical_time"
+	mail_component "github.com/atframework/atsf4g-go/component/mail"
+	private_protocol_config "github.com/atframework/atsf4g-go/component/protocol/private/config/protocol/config"
+	private_protocol_log "github.com/atframework/atsf4g-go/component/protocol/private/log/protocol/log"
 	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
 	public_protocol_common "github.com/atframework/atsf4g-go/component/protocol/public/common/protocol/common"
+	public_protocol_config "github.com/atframework/atsf4g-go/component/protocol/public/config/protocol/config"
 	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
 	logic_condition "github.com/atframework/atsf4g-go/service-lobbysvr/logic/condition"
 	logic_condition_data "github.com/atframework/atsf4g-go/service-lobbysvr/logic/condition/data"
+	logic_inventory "github.com/atframework/atsf4g-go/service-lobbysvr/logic/inventory"
 	logic_mall "github.com/atframework/atsf4g-go/service-lobbysvr/logic/mall"
 	logic_quest "github.com/atframework/atsf4g-go/service-lobbysvr/logic/quest"
 	service_protocol "github.com/atframework/atsf4g-go/service-lobbysvr/protocol/public/protocol/pbdesc"
@@ -59,10 +65,13 @@ type UserMallManager struct {
 	randomSheetData  map[int32]*private_protocol_pbdesc.UserMallRandomSheetData // mall_sheet_id -> random sheet data
 	randomProductMap map[int32]bool                                             // 当前随机池中的商品ID集合，方便快速判断商品是否在随机池内
 
+	purchaseHistory []*public_protocol_pbdesc.DMallPurchaseRecord // 最近购买记录，按时间从旧到新
+
 	// Dirty 数据
 	dirtyMallProduct map[int32]struct{}
 	dirtyMall        map[public_protocol_common.EnMallType]struct{}
 	dirtyRandomSheet []int32
+	dirtyPrice       map[mallProductKey]struct{}
 }
 
 func CreateUserMallManager(owner *data.User) *UserMallManager {
@@ -75,6 +84,7 @@ func CreateUserMallManager(owner *data.User) *UserMallManager {
 		randomSheetData:  make(map[int32]*private_protocol_pbdesc.UserMallRandomSheetData),
 		randomProductMap: make(map[int32]bool),
 		dirtyMallProduct: make(map[int32]struct{}),
+		dirtyPrice:       make(map[mallProductKey]struct{}),
 	}
 }
 
@@ -96,6 +106,11 @@ func (m *UserMallManager) InitFromDB(ctx cd.RpcContext, _dbUser *private_protoco
 		}
 	}
 
+	m.purchaseHistory = make([]*public_protocol_pbdesc.DMallPurchaseRecord, 0, len(mallData.GetPurchaseHistory()))
+	for _, record := range mallData.GetPurchaseHistory() {
+		m.purchaseHistory = append(m.purchaseHistory, record.Clone())
+	}
+
 	return cd.RpcResult{
 		Error:        nil,
 		ResponseCode: 0,
@@ -126,6 +141,11 @@ func (m *UserMallManager) DumpToDB(ctx cd.RpcContext, _dbUser *private_protocol_
 		})
 	}
 
+	_dbUser.MutableMallData().PurchaseHistory = make([]*public_protocol_pbdesc.DMallPurchaseRecord, 0, len(m.purchaseHistory))
+	for _, record := range m.purchaseHistory {
+		_dbUser.MutableMallData().PurchaseHistory = append(_dbUser.MutableMallData().PurchaseHistory, record.Clone())
+	}
+
 	return cd.RpcResult{
 		Error:        nil,
 		ResponseCode: 0,
@@ -190,32 +210,221 @@ func (m *UserMallManager) MallPurchase(ctx cd.RpcContext, reqs []*service_protoc
 	return 0
 }
 
+// mallPurchaseCheckResult 购买前置检查的结果，供扣除和发放阶段使用
+type mallPurchaseCheckResult struct {
+	productRow   *public_protocol_config.Readonly_ExcelMallProduct
+	mallRow      *public_protocol_config.Readonly_ExcelMall
+	productData  *private_protocol_pbdesc.UserMallProductData
+	conditionMgr logic_condition.UserConditionManager
+}
+
 func (m *UserMallManager) MallPurchaseSingle(ctx cd.RpcContext, req *service_protocol.DMallPurchaseData,
 	rspBody *service_protocol.SCMallPurchaseRsp,
 ) int32 {
+	checkResult, resultCode := m.checkPurchase(ctx, req, false)
+	if resultCode != 0 {
+		return resultCode
+	}
+	productRow := checkResult.productRow
+
+	// 检查消耗(阶梯价格和折扣后的实际价格)
+	var subGuard []*data.ItemSubGuard
+	effectiveCost := mallProductEffectiveCost(productRow,
+		checkResult.productData.GetTotalCounterStorageData().GetVersionCounter().GetSumCounter(),
+		req.GetPurchaseCount(), ctx.GetNow().Unix())
+	if len(effectiveCost) != 0 {
+		result := m.GetOwner().CheckCostItem(ctx, req.GetExpectCostItems(), effectiveCost)
+		if result.IsError() {
+			ctx.LogError("check cost item failed", "error", result.GetResponseCode())
+			return result.GetResponseCode()
+		}
+
+		subGuard, result = m.GetOwner().CheckSubItem(ctx, req.GetExpectCostItems())
+		if result.IsError() {
+			ctx.LogError("check sub item failed", "error", result.GetResponseCode())
+			return result.GetResponseCode()
+		}
+
+	}
+
+	// 检查发放
+	addGuard, resultCode := m.checkPurchaseReward(ctx, productRow, req.GetPurchaseCount(), false)
+	if resultCode != 0 {
+		return resultCode
+	}
+
+	// 扣除消耗
+	if len(subGuard) != 0 {
+		m.GetOwner().SubItem(ctx, subGuard, &data.ItemFlowReason{
+			MajorReason: int32(public_protocol_common.EnItemFlowReasonMajorType_EN_ITEM_FLOW_REASON_MAJOR_MALL),
+			MinorReason: int32(public_protocol_common.EnItemFlowReasonMinorType_EN_ITEM_FLOW_REASON_MINOR_MALL_PURCHASE_COST),
+			Parameter:   int64(productRow.GetProductId()),
+		})
+	}
+
+	// 购买记录按实际扣除的价格记录，不使用客户端上报的预期价格
+	m.commitPurchase(ctx, checkResult, req.GetPurchaseCount(), mallCostToItemBasic(effectiveCost), addGuard, nil, &data.ItemFlowReason{
+		MajorReason: int32(public_protocol_common.EnItemFlowReasonMajorType_EN_ITEM_FLOW_REASON_MAJOR_MALL),
+		MinorReason: int32(public_protocol_common.EnItemFlowReasonMinorType_EN_ITEM_FLOW_REASON_MINOR_MALL_PURCHASE_REWARD),
+		Parameter:   int64(productRow.GetProductId()),
+	}, "")
+
+	for _, guard := range addGuard {
+		rspBody.MergeRewardItems(guard.GetAddedItems())
+	}
+	return 0
+}
+
+// CheckPaidProductPurchase 创建支付订单前检查付费商品是否可购买（上架、解锁、购买条件和次数）
+// 补满商品在可回复道具全部已经满了时不允许下单
+func (m *UserMallManager) CheckPaidProductPurchase(ctx cd.RpcContext, req *service_protocol.DMallPurchaseData) int32 {
+	checkResult, resultCode := m.checkPurchase(ctx, req, true)
+	if resultCode != 0 {
+		return resultCode
+	}
+
+	if checkResult.productRow.GetPayPrice() <= 0 {
+		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_PAYMENT_NOT_PAID_PRODUCT)
+	}
+
+	if checkResult.productRow.GetRegenRefill() {
+		_, resultCode = m.generatePurchaseRewardInstances(ctx, checkResult.productRow, req.GetPurchaseCount(), false)
+		if resultCode != 0 {
+			return resultCode
+		}
+	}
+	return 0
+}
+
+// DeliverPaidProduct 支付成功后发放付费商品
+// 玩家已经完成支付，这里不再检查上架和购买条件，只计次数和发放道具，背包放不下时改为邮件发放
+func (m *UserMallManager) DeliverPaidProduct(ctx cd.AwaitableContext, req *service_protocol.DMallPurchaseData, orderId string,
+	reason *data.ItemFlowReason,
+) ([]*public_protocol_common.DItemInstance, int32) {
+	productRow := config.GetConfigManager().GetCurrentConfigGroup().GetExcelMallProductByProductIdPurchasePriority(req.GetProductId(), req.GetPurchasePriority())
+	if productRow == nil {
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_MALL_PRODUCT_NOT_FOUND)
+	}
+
+	if req.GetPurchaseCount() <= 0 {
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_INVALID_PARAM)
+	}
+
+	conditionMgr := data.UserGetModuleManager[logic_condition.UserConditionManager](m.GetOwner())
+	if conditionMgr == nil {
+		ctx.LogError("UserConditionManager not found",
+			"product_id", productRow.GetProductId(),
+		)
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+	}
+
+	productData, resultCode := m.mutableProductData(ctx, conditionMgr, productRow)
+	if resultCode != 0 {
+		return nil, resultCode
+	}
+
+	// 支付期间可回复道具可能已经回复满了，这时按配置的数量发放
+	rewardInstances, resultCode := m.generatePurchaseRewardInstances(ctx, productRow, req.GetPurchaseCount(), true)
+	if resultCode != 0 {
+		return nil, resultCode
+	}
+
+	var mailItems []*public_protocol_common.DItemBasic
+	addGuard, result := m.GetOwner().CheckAddItem(ctx, rewardInstances)
+	if result.IsError() {
+		mailTemplateId := config.GetServerConfig[*private_protocol_config.Readonly_LobbyServerCfg](
+			config.GetConfigManager().GetCurrentConfigGroup()).GetPayment().GetDeliverMailTemplateId()
+		if mailTemplateId == 0 {
+			result.LogError(ctx, "check add paid product reward items failed and deliver mail template not configured",
+				"order_id", orderId, "product_id", productRow.GetProductId())
+			return nil, result.GetResponseCode()
+		}
+
+		result.LogWarn(ctx, "check add paid product reward items failed, deliver by mail",
+			"order_id", orderId, "product_id", productRow.GetProductId())
+		addGuard = nil
+		mailItems, resultCode = m.mailPaidProductReward(ctx, productRow, rewardInstances, mailTemplateId, orderId)
+		if resultCode != 0 {
+			return nil, resultCode
+		}
+	}
+
+	m.commitPurchase(ctx, &mallPurchaseCheckResult{
+		productRow:   productRow,
+		mallRow:      config.GetConfigManager().GetCurrentConfigGroup().GetCustomIndex().GetMallByMallSheet(productRow.GetMallSheetId()),
+		productData:  productData,
+		conditionMgr: conditionMgr,
+	}, req.GetPurchaseCount(), nil, addGuard, mailItems, reason, orderId)
+
+	ret := make([]*public_protocol_common.DItemInstance, 0, len(addGuard))
+	for _, guard := range addGuard {
+		ret = append(ret, guard.GetAddedItems()...)
+	}
+	return ret, 0
+}
+
+// mailPaidProductReward 付费商品的奖励通过邮件发放，返回记录到购买记录中的道具
+func (m *UserMallManager) mailPaidProductReward(ctx cd.AwaitableContext, productRow *public_protocol_config.Readonly_ExcelMallProduct,
+	rewardInstances []*public_protocol_common.DItemInstance, mailTemplateId int32, orderId string,
+) ([]*public_protocol_common.DItemBasic, int32) {
+	attachments := make([]*public_protocol_common.DItemOffset, 0, len(rewardInstances))
+	mailItems := make([]*public_protocol_common.DItemBasic, 0, len(rewardInstances))
+	for _, item := range rewardInstances {
+		attachments = append(attachments, &public_protocol_common.DItemOffset{
+			TypeId: item.GetItemBasic().GetTypeId(),
+			Count:  item.GetItemBasic().GetCount(),
+		})
+		mailItems = append(mailItems, item.GetItemBasic())
+	}
+
+	sender := public_protocol_pbdesc.DMailUserInfo{}
+	receiver := public_protocol_pbdesc.DMailUserInfo{}
+	receiver.MutableProfile().UserId = m.GetOwner().GetUserId()
+	receiver.MutableProfile().ZoneId = m.GetOwner().GetZoneId()
+	result, _ := mail_component.AddUserMailWithTemplate(ctx, mailTemplateId, &sender, &receiver,
+		m.GetOwner().GetZoneId(), int32(public_protocol_pbdesc.EnMailChannelType_EN_MAIL_CHANNEL_USER_REWARD), 0, attachments,
+		&public_protocol_pbdesc.DMailFlowReason{
+			MajorReason: int32(public_protocol_common.EnItemFlowReasonMajorType_EN_ITEM_FLOW_REASON_MAJOR_PAYMENT),
+			MinorReason: int32(public_protocol_common.EnItemFlowReasonMinorType_EN_ITEM_FLOW_REASON_MINOR_PAYMENT_DELIVERY),
+			Parameter:   int64(productRow.GetProductId()),
+		}, nil, 0, 0)
+	if result.IsError() {
+		result.LogError(ctx, "send paid product deliver mail failed", "order_id", orderId, "mail_template_id", mailTemplateId)
+		return nil, result.GetResponseCode()
+	}
+	return mailItems, 0
+}
+
+// checkPurchase 检查商品是否可购买，allowPaid 为 false 时拒绝付费商品
+func (m *UserMallManager) checkPurchase(ctx cd.RpcContext, req *service_protocol.DMallPurchaseData, allowPaid bool) (*mallPurchaseCheckResult, int32) {
 	// 先判断商城是否解锁
 	productRow := config.GetConfigManager().GetCurrentConfigGroup().GetExcelMallProductByProductIdPurchasePriority(req.GetProductId(), req.GetPurchasePriority())
 	if productRow == nil {
-		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_MALL_PRODUCT_NOT_FOUND)
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_MALL_PRODUCT_NOT_FOUND)
 	}
 
 	if !productRow.GetIsOnSale() {
-		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_MALL_PRODUCT_NOT_ON_SELL)
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_MALL_PRODUCT_NOT_ON_SELL)
+	}
+
+	// 付费商品只能通过支付订单发货
+	if !allowPaid && productRow.GetPayPrice() > 0 {
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_MALL_PRODUCT_NEED_PAYMENT)
 	}
 
 	sheetRow := config.GetConfigManager().GetCurrentConfigGroup().GetExcelMallSheetByMallSheetId(productRow.GetMallSheetId())
 	if sheetRow == nil {
-		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_MALL_SHEET_NOT_FOUND)
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_MALL_SHEET_NOT_FOUND)
 	}
 
 	mallRow := config.GetConfigManager().GetCurrentConfigGroup().GetCustomIndex().GetMallByMallSheet(productRow.GetMallSheetId())
 
 	if mallRow == nil {
-		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_MALL_PRODUCT_NOT_ON_SELL)
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_MALL_PRODUCT_NOT_ON_SELL)
 	}
 
 	if req.GetPurchaseCount() <= 0 {
-		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_INVALID_PARAM)
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_INVALID_PARAM)
 	}
 
 	// 商城解锁条件
@@ -228,18 +437,18 @@ func (m *UserMallManager) MallPurchaseSingle(ctx cd.RpcContext, req *service_pro
 		ctx.LogError("UserConditionManager not found",
 			"product_id", productRow.GetProductId(),
 		)
-		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
 	}
 
 	// 随机sheet需要检查是否在当前随机池内
 	if sheetRow.GetRandomCfg().GetIsRandom() {
 		_, ok := m.randomSheetData[productRow.GetMallSheetId()]
 		if !ok {
-			return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_MALL_SHEET_NOT_FOUND)
+			return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_MALL_SHEET_NOT_FOUND)
 		}
 		isSale, ok := m.randomProductMap[productRow.GetProductId()]
 		if !ok || !isSale {
-			return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_MALL_PRODUCT_NOT_ON_SELL)
+			return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_MALL_PRODUCT_NOT_ON_SELL)
 		}
 
 	}
@@ -247,7 +456,7 @@ func (m *UserMallManager) MallPurchaseSingle(ctx cd.RpcContext, req *service_pro
 	if logic_condition.HasLimitData(mallRow.GetUnlockCondition()) {
 		rpcResult := conditionMgr.CheckBasicLimit(ctx, mallRow.GetUnlockCondition(), conditionRuntime)
 		if !rpcResult.IsOK() {
-			return rpcResult.GetResponseCode()
+			return nil, rpcResult.GetResponseCode()
 		}
 	}
 
@@ -256,7 +465,7 @@ func (m *UserMallManager) MallPurchaseSingle(ctx cd.RpcContext, req *service_pro
 		if logic_condition.HasLimitData(productRow.GetVisibleCondition()) {
 			rpcResult := conditionMgr.CheckBasicLimit(ctx, productRow.GetVisibleCondition(), conditionRuntime)
 			if !rpcResult.IsOK() {
-				return rpcResult.GetResponseCode()
+				return nil, rpcResult.GetResponseCode()
 			}
 		}
 
@@ -264,7 +473,7 @@ func (m *UserMallManager) MallPurchaseSingle(ctx cd.RpcContext, req *service_pro
 		if logic_condition.HasLimitData(productRow.GetUnlockCondition()) {
 			rpcResult := conditionMgr.CheckBasicLimit(ctx, productRow.GetUnlockCondition(), conditionRuntime)
 			if !rpcResult.IsOK() {
-				return rpcResult.GetResponseCode()
+				return nil, rpcResult.GetResponseCode()
 			}
 		}
 
@@ -272,112 +481,295 @@ func (m *UserMallManager) MallPurchaseSingle(ctx cd.RpcContext, req *service_pro
 		if logic_condition.HasLimitData(productRow.GetPurchaseCondition()) {
 			rpcResult := conditionMgr.CheckBasicLimit(ctx, productRow.GetPurchaseCondition(), conditionRuntime)
 			if !rpcResult.IsOK() {
-				return rpcResult.GetResponseCode()
+				return nil, rpcResult.GetResponseCode()
 			}
 		}
 	}
 
 	// 检查次数
-	productData, ok := m.productData[productRow.GetProductId()]
-	if !ok || productData == nil {
-		// 不存在则创建
-		totalCounterStorage := conditionMgr.AllocateCouterStorage(ctx, productRow.GetTotalCounterCondition().GetCounterVersion())
-		if totalCounterStorage == nil {
-			ctx.LogError("AllocateCouterStorage failed",
-				"product_id", productRow.GetProductId(),
-			)
-			return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_MALL_PRODUCT_INIT_FAILED)
-		}
-		productData = &private_protocol_pbdesc.UserMallProductData{
-			ProductData: &public_protocol_pbdesc.DMallProductData{
-				ProductId:             productRow.GetProductId(),
-				TotalCounterStorageId: totalCounterStorage.GetCounterStorageId(),
-			},
-			TotalCounterStorageData: totalCounterStorage,
-		}
-		m.productData[productRow.GetProductId()] = productData
-		m.dirtyMallProduct[productRow.GetProductId()] = struct{}{}
-		m.insertDirtyHandle()
+	productData, resultCode := m.mutableProductData(ctx, conditionMgr, productRow)
+	if resultCode != 0 {
+		return nil, resultCode
 	}
 	checkResult := conditionMgr.CheckCounterLimit(ctx, ctx.GetNow(), int64(req.GetPurchaseCount()),
 		productRow.GetTotalCounterCondition(), productData.MutableTotalCounterStorageData())
 	if checkResult.IsError() {
-		return checkResult.GetResponseCode()
+		return nil, checkResult.GetResponseCode()
 	}
 
-	// 检查消耗
-	var subGuard []*data.ItemSubGuard
-	if len(productRow.GetPurchaseCost()) != 0 {
-		result := m.GetOwner().CheckCostRatioItemCfg(ctx, req.GetExpectCostItems(), productRow.GetPurchaseCost(), int64(req.GetPurchaseCount()))
-		if result.IsError() {
-			ctx.LogError("check cost item failed", "error", result.GetResponseCode())
-			return result.GetResponseCode()
-		}
+	return &mallPurchaseCheckResult{
+		productRow:   productRow,
+		mallRow:      mallRow,
+		productData:  productData,
+		conditionMgr: conditionMgr,
+	}, 0
+}
 
-		subGuard, result = m.GetOwner().CheckSubItem(ctx, req.GetExpectCostItems())
-		if result.IsError() {
-			ctx.LogError("check sub item failed", "error", result.GetResponseCode())
-			return result.GetResponseCode()
-		}
+func (m *UserMallManager) mutableProductData(ctx cd.RpcContext, conditionMgr logic_condition.UserConditionManager,
+	productRow *public_protocol_config.Readonly_ExcelMallProduct,
+) (*private_protocol_pbdesc.UserMallProductData, int32) {
+	productData, ok := m.productData[productRow.GetProductId()]
+	if ok && productData != nil {
+		return productData, 0
+	}
 
+	// 不存在则创建
+	totalCounterStorage := conditionMgr.AllocateCouterStorage(ctx, productRow.GetTotalCounterCondition().GetCounterVersion())
+	if totalCounterStorage == nil {
+		ctx.LogError("AllocateCouterStorage failed",
+			"product_id", productRow.GetProductId(),
+		)
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_MALL_PRODUCT_INIT_FAILED)
 	}
+	productData = &private_protocol_pbdesc.UserMallProductData{
+		ProductData: &public_protocol_pbdesc.DMallProductData{
+			ProductId:             productRow.GetProductId(),
+			TotalCounterStorageId: totalCounterStorage.GetCounterStorageId(),
+		},
+		TotalCounterStorageData: totalCounterStorage,
+	}
+	m.productData[productRow.GetProductId()] = productData
+	m.dirtyMallProduct[productRow.GetProductId()] = struct{}{}
+	m.insertDirtyHandle()
+	return productData, 0
+}
 
-	// 检查发放
-	rewardInstances, result := m.GetOwner().GenerateMultipleItemInstancesFromCfgOffsetRatio(ctx, productRow.GetProductItems(), true, int64(req.GetPurchaseCount()))
-	if result.IsError() {
-		result.LogError(ctx, "generate reward item instances failed")
-		return result.GetResponseCode()
+// checkPurchaseReward keepConfiguredCount 见 fillRegenRefillCount
+func (m *UserMallManager) checkPurchaseReward(ctx cd.RpcContext, productRow *public_protocol_config.Readonly_ExcelMallProduct,
+	purchaseCount int32, keepConfiguredCount bool,
+) ([]*data.ItemAddGuard, int32) {
+	rewardInstances, resultCode := m.generatePurchaseRewardInstances(ctx, productRow, purchaseCount, keepConfiguredCount)
+	if resultCode != 0 {
+		return nil, resultCode
 	}
 
 	addGuard, result := m.GetOwner().CheckAddItem(ctx, rewardInstances)
 	if result.IsError() {
-		result.LogError(ctx, "add golden pot upgrade reward items failed")
-		return result.GetResponseCode()
+		result.LogError(ctx, "check add mall product reward items failed")
+		return nil, result.GetResponseCode()
 	}
+	return addGuard, 0
+}
+
+// generatePurchaseRewardInstances 生成商品和捆绑商品的奖励道具
+func (m *UserMallManager) generatePurchaseRewardInstances(ctx cd.RpcContext, productRow *public_protocol_config.Readonly_ExcelMallProduct,
+	purchaseCount int32, keepConfiguredCount bool,
+) ([]*public_protocol_common.DItemInstance, int32) {
+	rewardInstances, result := m.GetOwner().GenerateMultipleItemInstancesFromCfgOffsetRatio(ctx, productRow.GetProductItems(), true, int64(purchaseCount))
+	if result.IsError() {
+		result.LogError(ctx, "generate reward item instances failed")
+		return nil, result.GetResponseCode()
+	}
+
+	if productRow.GetRegenRefill() {
+		if purchaseCount != 1 {
+			return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_INVALID_PARAM)
+		}
+
+		var resultCode int32
+		rewardInstances, resultCode = m.fillRegenRefillCount(ctx, productRow, rewardInstances, keepConfiguredCount)
+		if resultCode != 0 {
+			return nil, resultCode
+		}
+	}
+
+	// 礼包商品：同时发放捆绑商品的道具，捆绑商品自身不计购买次数
+	for _, bundle := range productRow.GetBundleProducts() {
+		bundleRow := config.GetConfigManager().GetCurrentConfigGroup().GetExcelMallProductByProductIdPurchasePriority(bundle.GetProductId(), bundle.GetPurchasePriority())
+		if bundleRow == nil {
+			ctx.LogError("mall bundle product not found",
+				"product_id", productRow.GetProductId(),
+				"bundle_product_id", bundle.GetProductId(),
+				"bundle_purchase_priority", bundle.GetPurchasePriority(),
+			)
+			return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_MALL_PRODUCT_NOT_FOUND)
+		}
+
+		bundleInstances, result := m.GetOwner().GenerateMultipleItemInstancesFromCfgOffsetRatio(ctx, bundleRow.GetProductItems(), true, int64(purchaseCount))
+		if result.IsError() {
+			result.LogError(ctx, "generate bundle reward item instances failed")
+			return nil, result.GetResponseCode()
+		}
+		rewardInstances = append(rewardInstances, bundleInstances...)
+	}
+	return rewardInstances, 0
+}
+
+// fillRegenRefillCount 可回复道具改为补满到回复上限需要的数量，全部已经满了时不允许购买
+// keepConfiguredCount 用于已经支付的订单，已经满了的道具按配置的数量发放，不再拒绝
+func (m *UserMallManager) fillRegenRefillCount(ctx cd.RpcContext, productRow *public_protocol_config.Readonly_ExcelMallProduct,
+	rewardInstances []*public_protocol_common.DItemInstance, keepConfiguredCount bool,
+) ([]*public_protocol_common.DItemInstance, int32) {
+	inventoryMgr := data.UserGetModuleManager[logic_inventory.UserInventoryManager](m.GetOwner())
+	if inventoryMgr == nil {
+		ctx.LogError("can not find user inventory manager", "product_id", productRow.GetProductId())
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+	}
+
+	ret := make([]*public_protocol_common.DItemInstance, 0, len(rewardInstances))
+	refilled := make(map[int32]struct{})
+	for _, item := range rewardInstances {
+		typeId := item.GetItemBasic().GetTypeId()
+		if config.GetConfigManager().GetCurrentConfigGroup().GetExcelItemRegenByItemId(typeId) == nil {
+			ret = append(ret, item)
+			continue
+		}
+
+		// 同一个道具只补满一次
+		if _, ok := refilled[typeId]; ok {
+			continue
+		}
+
+		refillCount := inventoryMgr.GetItemRegenRefillCount(ctx, typeId)
+		if refillCount > 0 {
+			item.MutableItemBasic().Count = refillCount
+		} else if !keepConfiguredCount {
+			continue
+		} else {
+			ctx.LogWarn("regen item already full when delivering refill product, deliver configured count",
+				"product_id", productRow.GetProductId(), "type_id", typeId, "count", item.GetItemBasic().GetCount())
+		}
+		ret = append(ret, item)
+		refilled[typeId] = struct{}{}
+	}
+
+	if len(refilled) == 0 {
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_ITEM_REGEN_ALREADY_FULL)
+	}
+	return ret, 0
+}
+
+// commitPurchase 计次数、发放奖励、记录购买并触发任务事件，orderId 非空表示付费商品发货
+// mailItems 为已经通过邮件发放的奖励，只记录不再发放
+func (m *UserMallManager) commitPurchase(ctx cd.RpcContext, checkResult *mallPurchaseCheckResult, purchaseCount int32,
+	costItems []*public_protocol_common.DItemBasic, addGuard []*data.ItemAddGuard, mailItems []*public_protocol_common.DItemBasic,
+	reason *data.ItemFlowReason, orderId string,
+) {
+	productRow := checkResult.productRow
 
 	// 加次数
-	conditionMgr.AddCounter(ctx, ctx.GetNow(), int64(req.GetPurchaseCount()),
+	checkResult.conditionMgr.AddCounter(ctx, ctx.GetNow(), int64(purchaseCount),
 		productRow.GetTotalCounterCondition(),
-		productData.MutableTotalCounterStorageData())
+		checkResult.productData.MutableTotalCounterStorageData())
 
-	// 扣除消耗
-	if len(subGuard) != 0 {
-		m.GetOwner().SubItem(ctx, subGuard, &data.ItemFlowReason{
-			MajorReason: int32(public_protocol_common.EnItemFlowReasonMajorType_EN_ITEM_FLOW_REASON_MAJOR_MALL),
-			MinorReason: int32(public_protocol_common.EnItemFlowReasonMinorType_EN_ITEM_FLOW_REASON_MINOR_MALL_PURCHASE_COST),
-			Parameter:   int64(productRow.GetProductId()),
-		})
+	// 发放奖励
+	m.GetOwner().AddItem(ctx, addGuard, reason)
+
+	// 阶梯价格和首购折扣依赖购买次数，需要同步新价格
+	if hasMallProductDiscount(productRow) {
+		m.dirtyPrice[mallProductKey{
+			productId:        productRow.GetProductId(),
+			purchasePriority: productRow.GetPurchasePriority(),
+		}] = struct{}{}
+		m.insertDirtyHandle()
 	}
 
-	// 发放奖励
-	m.GetOwner().AddItem(ctx, addGuard, &data.ItemFlowReason{
-		MajorReason: int32(public_protocol_common.EnItemFlowReasonMajorType_EN_ITEM_FLOW_REASON_MAJOR_MALL),
-		MinorReason: int32(public_protocol_common.EnItemFlowReasonMinorType_EN_ITEM_FLOW_REASON_MINOR_MALL_PURCHASE_REWARD),
-		Parameter:   int64(productRow.GetProductId()),
-	})
+	if mallRow := checkResult.mallRow; mallRow != nil {
+		data.UserGetModuleManager[logic_quest.UserQuestManager](m.GetOwner()).QuestTriggerEvent(ctx, private_protocol_pbdesc.QuestTriggerParams_EnParamID_MallPurchase,
+			&private_protocol_pbdesc.QuestTriggerParams{
+				Param: &private_protocol_pbdesc.QuestTriggerParams_MallPurchase{
+					MallPurchase: &private_protocol_pbdesc.QuestTriggerParamsMallPurchase{
+						MallId:    int32(mallRow.GetMallType()),
+						ProductId: productRow.GetProductId(),
+						Count:     purchaseCount,
+					},
+				},
+			})
+		if mallData, ok := m.mallData[mallRow.GetMallType()]; ok && mallData != nil {
+			mallData.purchaseSum += int64(purchaseCount)
+		} else {
+			m.mallData[mallRow.GetMallType()] = &UserMallData{
+				purchaseSum: int64(purchaseCount),
+			}
+		}
+	}
 
-	for _, guard := range addGuard {
-		rspBody.MergeRewardItems(guard.GetAddedItems())
+	// 流水里的商城累计购买次数需要包含本次购买
+	m.recordPurchase(ctx, checkResult, purchaseCount, costItems, addGuard, mailItems, orderId)
+}
+
+// mallCostToItemBasic 实际价格转为购买记录中的道具
+func mallCostToItemBasic(costs []*public_protocol_common.DItemOffset) []*public_protocol_common.DItemBasic {
+	if len(costs) == 0 {
+		return nil
 	}
 
-	data.UserGetModuleManager[logic_quest.UserQuestManager](m.GetOwner()).QuestTriggerEvent(ctx, private_protocol_pbdesc.QuestTriggerParams_EnParamID_MallPurchase,
-		&private_protocol_pbdesc.QuestTriggerParams{
-			Param: &private_protocol_pbdesc.QuestTriggerParams_MallPurchase{
-				MallPurchase: &private_protocol_pbdesc.QuestTriggerParamsMallPurchase{
-					MallId:    int32(mallRow.GetMallType()),
-					ProductId: productRow.GetProductId(),
-					Count:     req.GetPurchaseCount(),
-				},
-			},
+	ret := make([]*public_protocol_common.DItemBasic, 0, len(costs))
+	for _, cost := range costs {
+		ret = append(ret, &public_protocol_common.DItemBasic{
+			TypeId: cost.GetTypeId(),
+			Count:  cost.GetCount(),
 		})
-	if mallData, ok := m.mallData[mallRow.GetMallType()]; ok && mallData != nil {
-		mallData.purchaseSum += int64(req.GetPurchaseCount())
-	} else {
-		m.mallData[mallRow.GetMallType()] = &UserMallData{
-			purchaseSum: int64(req.GetPurchaseCount()),
+	}
+	return ret
+}
+
+// recordPurchase 写入购买记录并发送商城购买流水
+func (m *UserMallManager) recordPurchase(ctx cd.RpcContext, checkResult *mallPurchaseCheckResult, purchaseCount int32,
+	costItems []*public_protocol_common.DItemBasic, addGuard []*data.ItemAddGuard, mailItems []*public_protocol_common.DItemBasic, orderId string,
+) {
+	productRow := checkResult.productRow
+	rewardItems := make([]*public_protocol_common.DItemBasic, 0, len(addGuard)+len(mailItems))
+	for _, guard := range addGuard {
+		for _, item := range guard.GetAddedItems() {
+			rewardItems = append(rewardItems, item.GetItemBasic())
 		}
 	}
-	return 0
+	rewardItems = append(rewardItems, mailItems...)
+
+	m.appendPurchaseHistory(&public_protocol_pbdesc.DMallPurchaseRecord{
+		ProductId:        productRow.GetProductId(),
+		PurchasePriority: productRow.GetPurchasePriority(),
+		MallSheetId:      productRow.GetMallSheetId(),
+		PurchaseCount:    purchaseCount,
+		PurchaseTime:     ctx.GetNow().Unix(),
+		CostItems:        costItems,
+		RewardItems:      rewardItems,
+		OrderId:          orderId,
+	})
+
+	log := private_protocol_log.OperationSupportSystemLog{}
+	flow := log.MutableLog().MutableMallPurchaseFlow()
+	if orderId != "" {
+		flow.OperationType = private_protocol_log.OSSMallPurchaseFlow_EN_OSS_MALL_OPERATION_TYPE_PAYMENT_DELIVERY
+	} else {
+		flow.OperationType = private_protocol_log.OSSMallPurchaseFlow_EN_OSS_MALL_OPERATION_TYPE_PURCHASE
+	}
+	flow.ProductId = productRow.GetProductId()
+	flow.PurchasePriority = productRow.GetPurchasePriority()
+	flow.MallSheetId = productRow.GetMallSheetId()
+	flow.PurchaseCount = purchaseCount
+	flow.CostItems = costItems
+	flow.RewardItems = rewardItems
+	flow.OrderId = orderId
+
+	counter := checkResult.productData.GetTotalCounterStorageData().GetVersionCounter()
+	flow.SumCounter = counter.GetSumCounter()
+	flow.DailyCounter = counter.GetDailyCounter()
+	flow.WeeklyCounter = counter.GetWeeklyCounter()
+	flow.MonthlyCounter = counter.GetMonthlyCounter()
+	if checkResult.mallRow != nil {
+		flow.MallType = int32(checkResult.mallRow.GetMallType())
+		flow.MallPurchaseSum = m.GetMallPurchaseSum(checkResult.mallRow.GetMallType())
+	}
+	m.GetOwner().SendUserOssLog(ctx, &log)
+}
+
+func (m *UserMallManager) appendPurchaseHistory(record *public_protocol_pbdesc.DMallPurchaseRecord) {
+	m.purchaseHistory = append(m.purchaseHistory, record)
+	maxCount := int(private_protocol_pbdesc.EnSystemLimit_EN_SL_MALL_PURCHASE_HISTORY_MAX_COUNT)
+	if len(m.purchaseHistory) > maxCount {
+		m.purchaseHistory = append(m.purchaseHistory[:0:0], m.purchaseHistory[len(m.purchaseHistory)-maxCount:]...)
+	}
+}
+
+// GetPurchaseHistory 获取最近购买记录，按时间从新到旧
+func (m *UserMallManager) GetPurchaseHistory() []*public_protocol_pbdesc.DMallPurchaseRecord {
+	ret := make([]*public_protocol_pbdesc.DMallPurchaseRecord, 0, len(m.purchaseHistory))
+	for i := len(m.purchaseHistory) - 1; i >= 0; i-- {
+		ret = append(ret, m.purchaseHistory[i])
+	}
+	return ret
 }
 
 func (m *UserMallManager) GetMallPurchaseSum(mallType public_protocol_common.EnMallType) int64 {
@@ -388,6 +780,8 @@ func (m *UserMallManager) GetMallPurchaseSum(mallType public_protocol_common.EnM
 }
 
 func (m *UserMallManager) FetchData() *public_protocol_pbdesc.DUserMallData {
+	now := logical_time.GetLogicalNow().Unix()
+
 	ret := &public_protocol_pbdesc.DUserMallData{}
 	ret.ProductData = make([]*public_protocol_pbdesc.DMallProductData, 0, len(m.productData))
 	for _, data := range m.productData {
@@ -408,9 +802,18 @@ func (m *UserMallManager) FetchData() *public_protocol_pbdesc.DUserMallData {
 		})
 	}
 
+	for _, productRow := range config.GetConfigManager().GetCurrentConfigGroup().GetCustomIndex().GetMallDiscountProducts() {
+		ret.ProductPrices = append(ret.ProductPrices, makeMallProductPrice(productRow, m.getProductPurchasedCount(productRow.GetProductId()), now))
+	}
+
 	return ret
 }
 
+// getProductPurchasedCount 获取商品累计购买次数
+func (m *UserMallManager) getProductPurchasedCount(productId int32) int64 {
+	return m.GetProductCounter(productId).GetVersionCounter().GetSumCounter()
+}
+
 func (m *UserMallManager) insertDirtyHandle() {
 	m.GetOwner().InsertDirtyHandleIfNotExists(m,
 		func(ctx cd.RpcContext, dirty *data.UserDirtyData) (ret bool) {
@@ -444,10 +847,19 @@ func (m *UserMallManager) insertDirtyHandle() {
 					ret = true
 				}
 			}
+			for key := range m.dirtyPrice {
+				productRow := config.GetConfigManager().GetCurrentConfigGroup().GetExcelMallProductByProductIdPurchasePriority(key.productId, key.purchasePriority)
+				if productRow != nil {
+					dirtyData.MutableDirtyMall().AppendAndReverseProductPrices(len(m.dirtyPrice),
+						makeMallProductPrice(productRow, m.getProductPurchasedCount(key.productId), ctx.GetNow().Unix()))
+					ret = true
+				}
+			}
 
 			return ret
 		},
 		func(ctx cd.RpcContext) {
+			clear(m.dirtyPrice)
 			clear(m.dirtyMallProduct)
 			clear(m.dirtyMall)
 			clear(m.dirtyRandomSheet)
@@ -508,6 +920,24 @@ func (m *UserMallManager) RefreshMallRandomSheet(ctx cd.RpcContext, mallSheetId
 	m.refreshMallRandomSheet(ctx, mallSheetId)
 	mallRadonSheetData.RefreshCount += 1
 
+	{
+		log := private_protocol_log.OperationSupportSystemLog{}
+		flow := log.MutableLog().MutableMallPurchaseFlow()
+		flow.OperationType = private_protocol_log.OSSMallPurchaseFlow_EN_OSS_MALL_OPERATION_TYPE_REFRESH_RANDOM_SHEET
+		flow.MallSheetId = mallSheetId
+		flow.CostItems = realCosts
+		flow.RefreshCount = mallRadonSheetData.GetRefreshCount()
+		if mallRow := config.GetConfigManager().GetCurrentConfigGroup().GetCustomIndex().GetMallByMallSheet(mallSheetId); mallRow != nil {
+			flow.MallType = int32(mallRow.GetMallType())
+		}
+		if sheetData := m.randomSheetData[mallSheetId]; sheetData != nil {
+			for _, product := range sheetData.GetProduct() {
+				flow.RandomProductIds = append(flow.RandomProductIds, product.GetProductId())
+			}
+		}
+		m.GetOwner().SendUserOssLog(ctx, &log)
+	}
+
 	return cd.CreateRpcResultOk()
 }
 
@@ -19,4 +19,12 @@ type UserMallManager interface {
 	GetProductCounter(productId int32) *public_protocol_common.DConditionCounterStorage
 	GetMallPurchaseSum(mallType public_protocol_common.EnMallType) int64
 	RefreshMallRandomSheet(ctx cd.RpcContext, mallSheetId int32, realCosts []*public_protocol_common.DItemBasic) cd.RpcResult
+
+	// 付费商品
+	CheckPaidProductPurchase(ctx cd.RpcContext, req *service_protocol.DMallPurchaseData) int32
+	DeliverPaidProduct(ctx cd.AwaitableContext, req *service_protocol.DMallPurchaseData, orderId string,
+		reason *data.ItemFlowReason) ([]*public_protocol_common.DItemInstance, int32)
+
+	// 最近购买记录，按时间从新到旧
+	GetPurchaseHistory() []*public_protocol_pbdesc.DMallPurchaseRecord
 }
@@ -0,0 +1,52 @@
+// Copyright 2026 atframework
+
+package lobbysvr_logic_payment_action
+
+import (
+	"fmt"
+
+	component_dispatcher "github.com/atframework/atsf4g-go/component/dispatcher"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	user_controller "github.com/atframework/atsf4g-go/component/user_controller"
+	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
+	service_protocol "github.com/atframework/atsf4g-go/service-lobbysvr/protocol/public/protocol/pbdesc"
+
+	logic_payment "github.com/atframework/atsf4g-go/service-lobbysvr/logic/payment"
+)
+
+type TaskActionPaymentCreateOrder struct {
+	user_controller.TaskActionCSBase[*service_protocol.CSPaymentCreateOrderReq, *service_protocol.SCPaymentCreateOrderRsp]
+}
+
+func (t *TaskActionPaymentCreateOrder) Name() string {
+	return "TaskActionPaymentCreateOrder"
+}
+
+func (t *TaskActionPaymentCreateOrder) Run(_startData *component_dispatcher.DispatcherStartData) error {
+	user, ok := t.GetUser().(*data.User)
+	if !ok || user == nil {
+		t.SetResponseCode(int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_USER_NOT_FOUND))
+		return fmt.Errorf("user not found")
+	}
+
+	request_body := t.GetRequestBody()
+	if request_body.GetPurchaseCount() <= 0 {
+		t.SetResponseError(public_protocol_pbdesc.EnErrorCode_EN_ERR_INVALID_PARAM)
+		return fmt.Errorf("invalid purchase count %d", request_body.GetPurchaseCount())
+	}
+
+	mgr := data.UserGetModuleManager[logic_payment.UserPaymentManager](user)
+	if mgr == nil {
+		t.SetResponseError(public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+		return fmt.Errorf("UserPaymentManager not found")
+	}
+
+	order, result := mgr.CreateOrder(t.GetAwaitableContext(), request_body)
+	if result.IsError() {
+		t.SetResponseCode(result.GetResponseCode())
+		return result.GetStandardError()
+	}
+
+	t.MutableResponseBody().Order = order
+	return nil
+}
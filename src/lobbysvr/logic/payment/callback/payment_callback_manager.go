@@ -0,0 +1,257 @@
+// Copyright 2026 atframework
+// @brief 平台支付回调处理，挂在 lobbysvr.webserver 上
+
+package lobbysvr_logic_payment_callback
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/atframework/libatapp-go"
+
+	lu "github.com/atframework/atframe-utils-go/lang_utility"
+	async_jobs "github.com/atframework/atsf4g-go/component/async_jobs"
+	config "github.com/atframework/atsf4g-go/component/config"
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	operation_support_system "github.com/atframework/atsf4g-go/component/operation_support_system"
+	private_protocol_config "github.com/atframework/atsf4g-go/component/protocol/private/config/protocol/config"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+
+	payment_data "github.com/atframework/atsf4g-go/service-lobbysvr/logic/payment/data"
+	payment_verifier "github.com/atframework/atsf4g-go/service-lobbysvr/logic/payment/verifier"
+)
+
+const (
+	paymentCallbackMaxBodySize = 64 * 1024
+	paymentCallbackTimeout     = 10 * time.Second
+)
+
+// PaymentCallbackManager 接收平台支付回调，更新订单表并投递 EN_PAJT_PAY 异步任务给玩家
+type PaymentCallbackManager struct {
+	libatapp.AppModuleBase
+
+	webServer    *cd.WebSocketMessageDispatcher
+	callbackPath string
+}
+
+type paymentCallbackResponse struct {
+	Code    int32  `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+func init() {
+	var _ libatapp.AppModuleImpl = (*PaymentCallbackManager)(nil)
+}
+
+// CreatePaymentCallbackManager 创建支付回调模块，webServer 为 lobbysvr.webserver 所在的分发器
+func CreatePaymentCallbackManager(owner libatapp.AppImpl, webServer *cd.WebSocketMessageDispatcher) *PaymentCallbackManager {
+	return &PaymentCallbackManager{
+		AppModuleBase: libatapp.CreateAppModuleBase(owner),
+		webServer:     webServer,
+	}
+}
+
+func (m *PaymentCallbackManager) Name() string {
+	return "PaymentCallbackManager"
+}
+
+func (m *PaymentCallbackManager) Init(parent context.Context) error {
+	m.setupHandler()
+	return nil
+}
+
+func (m *PaymentCallbackManager) Reload() error {
+	m.setupHandler()
+	return nil
+}
+
+func getPaymentConfig() *private_protocol_config.Readonly_LogicPaymentCfg {
+	return config.GetServerConfig[*private_protocol_config.Readonly_LobbyServerCfg](config.GetConfigManager().GetCurrentConfigGroup()).GetPayment()
+}
+
+func (m *PaymentCallbackManager) setupHandler() {
+	if m.webServer == nil {
+		return
+	}
+
+	path := getPaymentConfig().GetCallbackPath()
+	if !getPaymentConfig().GetEnable() {
+		path = ""
+	}
+
+	if path == m.callbackPath {
+		return
+	}
+
+	if m.callbackPath != "" {
+		m.webServer.SetHttpHandler(m.callbackPath, nil)
+	}
+	m.callbackPath = path
+	if path != "" {
+		m.webServer.SetHttpHandler(path, m.handleCallback)
+	}
+}
+
+func writeCallbackResponse(w http.ResponseWriter, statusCode int, code int32, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(&paymentCallbackResponse{Code: code, Message: message})
+}
+
+// handleCallback 只有返回 200 时平台才会停止重试
+func (m *PaymentCallbackManager) handleCallback(w http.ResponseWriter, r *http.Request) {
+	logger := m.GetApp().GetDefaultLogger()
+	if r.Method != http.MethodPost {
+		writeCallbackResponse(w, http.StatusMethodNotAllowed, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_INVALID_PARAM), "method not allowed")
+		return
+	}
+
+	paymentCfg := getPaymentConfig()
+	if !paymentCfg.GetEnable() {
+		writeCallbackResponse(w, http.StatusServiceUnavailable, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_PAYMENT_DISABLED), "payment disabled")
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, paymentCallbackMaxBodySize))
+	if err != nil {
+		writeCallbackResponse(w, http.StatusBadRequest, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_INVALID_PARAM), err.Error())
+		return
+	}
+
+	verifier := payment_verifier.GetPaymentVerifier(paymentCfg.GetVerifier())
+	if verifier == nil {
+		logger.LogError("payment verifier not found", "verifier", paymentCfg.GetVerifier())
+		writeCallbackResponse(w, http.StatusInternalServerError, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM), "verifier not found")
+		return
+	}
+
+	notify, err := verifier.Verify(body, paymentCfg.GetSecret())
+	if err != nil {
+		logger.LogWarn("payment notify verify failed", "error", err, "remote_addr", r.RemoteAddr)
+		writeCallbackResponse(w, http.StatusBadRequest, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_PAYMENT_VERIFY_FAILED), err.Error())
+		return
+	}
+
+	// 订单表和异步任务的IO放在任务里执行，HTTP协程只等待结果
+	done := make(chan cd.RpcResult, 1)
+	ctx := libatapp.AtappGetModule[*cd.NoMessageDispatcher](m.GetApp()).CreateRpcContext()
+	task := cd.AsyncInvoke(ctx, "PaymentCallbackManager.processNotify", nil, func(childCtx cd.AwaitableContext) cd.RpcResult {
+		result := processPaymentNotify(childCtx, notify, body)
+		done <- result
+		return result
+	})
+	if lu.IsNil(task) {
+		writeCallbackResponse(w, http.StatusServiceUnavailable, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM), "start task failed")
+		return
+	}
+
+	select {
+	case result := <-done:
+		if result.IsError() {
+			writeCallbackResponse(w, http.StatusInternalServerError, result.GetResponseCode(), "")
+			return
+		}
+		writeCallbackResponse(w, http.StatusOK, 0, "")
+	case <-r.Context().Done():
+	case <-time.After(paymentCallbackTimeout):
+		writeCallbackResponse(w, http.StatusGatewayTimeout, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_TIMEOUT), "timeout")
+	}
+}
+
+// processPaymentNotify 更新订单状态并投递发货/退款任务
+// 玩家侧处理完成后订单表记录了 user_deliver_time/user_refund_time，重复的回调不再投递任务
+// 回写完成前的重复任务由 action_uuid 和玩家数据中的订单记录去重
+// 退款后到达的支付回调和未支付订单的退款回调只记录日志并返回成功，避免平台一直重试
+func processPaymentNotify(ctx cd.AwaitableContext, notify *payment_verifier.PaymentNotify, body []byte) cd.RpcResult {
+	table, result := payment_data.UpdatePaymentOrder(ctx, notify.OrderId, func(table *private_protocol_pbdesc.DatabaseTablePaymentOrder) (bool, cd.RpcResult) {
+		order := table.MutableOrder()
+		switch notify.Event {
+		case payment_verifier.PaymentNotifyEventPay:
+			switch order.GetStatus() {
+			case public_protocol_pbdesc.EnPaymentOrderStatus_EN_PAYMENT_ORDER_STATUS_PAID,
+				public_protocol_pbdesc.EnPaymentOrderStatus_EN_PAYMENT_ORDER_STATUS_DELIVERED:
+				return false, cd.CreateRpcResultOk()
+			case public_protocol_pbdesc.EnPaymentOrderStatus_EN_PAYMENT_ORDER_STATUS_CREATED,
+				// 超时关闭后才到达的支付仍然发货
+				public_protocol_pbdesc.EnPaymentOrderStatus_EN_PAYMENT_ORDER_STATUS_CLOSED:
+			case public_protocol_pbdesc.EnPaymentOrderStatus_EN_PAYMENT_ORDER_STATUS_REFUNDED:
+				ctx.LogWarn("payment notify arrived after order refunded, ignore", "order_id", notify.OrderId,
+					"platform_order_id", notify.PlatformOrderId)
+				return false, cd.CreateRpcResultOk()
+			default:
+				return false, cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_PAYMENT_ORDER_STATUS_INVALID)
+			}
+
+			if notify.Amount != order.GetPrice() || (notify.Currency != "" && notify.Currency != order.GetCurrency()) {
+				ctx.LogError("payment notify amount mismatch", "order_id", notify.OrderId,
+					"amount", notify.Amount, "currency", notify.Currency,
+					"expect_amount", order.GetPrice(), "expect_currency", order.GetCurrency())
+				return false, cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_PAYMENT_AMOUNT_MISMATCH)
+			}
+
+			order.Status = public_protocol_pbdesc.EnPaymentOrderStatus_EN_PAYMENT_ORDER_STATUS_PAID
+			order.PayTime = ctx.GetNow().Unix()
+		case payment_verifier.PaymentNotifyEventRefund:
+			switch order.GetStatus() {
+			case public_protocol_pbdesc.EnPaymentOrderStatus_EN_PAYMENT_ORDER_STATUS_REFUNDED:
+				return false, cd.CreateRpcResultOk()
+			case public_protocol_pbdesc.EnPaymentOrderStatus_EN_PAYMENT_ORDER_STATUS_PAID,
+				public_protocol_pbdesc.EnPaymentOrderStatus_EN_PAYMENT_ORDER_STATUS_DELIVERED:
+			case public_protocol_pbdesc.EnPaymentOrderStatus_EN_PAYMENT_ORDER_STATUS_CREATED,
+				public_protocol_pbdesc.EnPaymentOrderStatus_EN_PAYMENT_ORDER_STATUS_CLOSED:
+				// 支付回调可能晚于退款到达，先标记为已退款，之后的支付回调不再发货
+				ctx.LogWarn("refund notify arrived before payment confirmed", "order_id", notify.OrderId,
+					"status", order.GetStatus(), "platform_order_id", notify.PlatformOrderId)
+			default:
+				return false, cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_PAYMENT_ORDER_STATUS_INVALID)
+			}
+
+			order.Status = public_protocol_pbdesc.EnPaymentOrderStatus_EN_PAYMENT_ORDER_STATUS_REFUNDED
+			order.RefundTime = ctx.GetNow().Unix()
+		}
+
+		if notify.PlatformOrderId != "" {
+			order.PlatformOrderId = notify.PlatformOrderId
+		}
+		table.LastNotify = body
+		return true, cd.CreateRpcResultOk()
+	})
+
+	if table != nil {
+		operation_support_system.SendOssLog(ctx.GetApp(), payment_data.MakePaymentOssLog(table.GetOrder(), result.GetResponseCode()))
+	}
+	if result.IsError() {
+		ctx.LogError("process payment notify failed", "order_id", notify.OrderId, "event", notify.Event, "error", result)
+		return result
+	}
+
+	jobData := &private_protocol_pbdesc.UserAsyncJobsBlobData{}
+	switch {
+	case table.GetOrder().GetStatus() == public_protocol_pbdesc.EnPaymentOrderStatus_EN_PAYMENT_ORDER_STATUS_PAID &&
+		table.GetUserDeliverTime() == 0:
+		jobData.ActionUuid = "pay_delivery:" + notify.OrderId
+		jobData.Action = &private_protocol_pbdesc.UserAsyncJobsBlobData_PayDelivery{
+			PayDelivery: &private_protocol_pbdesc.UserAsyncJobPayMessage{Order: table.GetOrder().Clone()},
+		}
+	case table.GetOrder().GetStatus() == public_protocol_pbdesc.EnPaymentOrderStatus_EN_PAYMENT_ORDER_STATUS_REFUNDED &&
+		table.GetUserRefundTime() == 0:
+		jobData.ActionUuid = "pay_refund:" + notify.OrderId
+		jobData.Action = &private_protocol_pbdesc.UserAsyncJobsBlobData_PayRefund{
+			PayRefund: &private_protocol_pbdesc.UserAsyncJobPayMessage{Order: table.GetOrder().Clone()},
+		}
+	default:
+		// 玩家侧已经处理过的重复通知，无需处理
+		return cd.CreateRpcResultOk()
+	}
+
+	result, _ = async_jobs.AddJobsWithRetry(ctx, private_protocol_pbdesc.EnPlayerAsyncJobsType_EN_PAJT_PAY,
+		table.GetUserId(), table.GetZoneId(), jobData, async_jobs.DefaultActionOptions())
+	if result.IsError() {
+		ctx.LogError("add payment async job failed", "order_id", notify.OrderId, "user_id", table.GetUserId(), "error", result)
+	}
+	return result
+}
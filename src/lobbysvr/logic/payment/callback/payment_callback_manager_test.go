@@ -0,0 +1,154 @@
+package lobbysvr_logic_payment_callback
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	async_jobs "github.com/atframework/atsf4g-go/component/async_jobs"
+	db "github.com/atframework/atsf4g-go/component/db"
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	test_utility "github.com/atframework/atsf4g-go/component/test_utility"
+
+	payment_data "github.com/atframework/atsf4g-go/service-lobbysvr/logic/payment/data"
+	payment_verifier "github.com/atframework/atsf4g-go/service-lobbysvr/logic/payment/verifier"
+)
+
+const (
+	testPaymentUserId uint64 = 10001
+	testPaymentZoneId uint32 = 1
+)
+
+func addTestPaymentOrder(t *testing.T, ctx *test_utility.MockRpcContext, orderId string) {
+	result, _ := db.DatabaseTablePaymentOrderAddOrderId(ctx, &private_protocol_pbdesc.DatabaseTablePaymentOrder{
+		OrderId: orderId,
+		UserId:  testPaymentUserId,
+		ZoneId:  testPaymentZoneId,
+		Order: &public_protocol_pbdesc.DPaymentOrder{
+			OrderId: orderId,
+			Price:   600,
+			Status:  public_protocol_pbdesc.EnPaymentOrderStatus_EN_PAYMENT_ORDER_STATUS_CREATED,
+		},
+	})
+	assert.False(t, result.IsError())
+}
+
+func countPaymentJobs(ctx *test_utility.MockRpcContext) int {
+	jobs, result := async_jobs.GetJobs(ctx, int32(private_protocol_pbdesc.EnPlayerAsyncJobsType_EN_PAJT_PAY), testPaymentUserId, testPaymentZoneId)
+	if result.IsError() {
+		return 0
+	}
+	return len(jobs)
+}
+
+func markUserProcessed(ctx *test_utility.MockRpcContext, orderId string, isRefund bool) {
+	payment_data.UpdatePaymentOrder(ctx, orderId, func(table *private_protocol_pbdesc.DatabaseTablePaymentOrder) (bool, cd.RpcResult) {
+		if isRefund {
+			table.UserRefundTime = ctx.GetNow().Unix()
+		} else {
+			table.UserDeliverTime = ctx.GetNow().Unix()
+			table.MutableOrder().Status = public_protocol_pbdesc.EnPaymentOrderStatus_EN_PAYMENT_ORDER_STATUS_DELIVERED
+		}
+		return true, cd.CreateRpcResultOk()
+	})
+}
+
+// TestProcessPaymentNotifyDeliver 测试支付回调投递发货任务
+// Scenario: 首次支付回调投递任务，玩家侧处理前的重复回调也投递(由 action_uuid 去重)，处理后的重复回调不再投递
+func TestProcessPaymentNotifyDeliver(t *testing.T) {
+	// Arrange
+	app, _ := test_utility.CreateMemoryStorageApp()
+	ctx := test_utility.NewMockRpcContext(app)
+	addTestPaymentOrder(t, ctx, "order-pay")
+	notify := &payment_verifier.PaymentNotify{OrderId: "order-pay", Event: payment_verifier.PaymentNotifyEventPay, Amount: 600}
+
+	// Act
+	firstResult := processPaymentNotify(ctx, notify, nil)
+	jobsAfterFirst := countPaymentJobs(ctx)
+	duplicateResult := processPaymentNotify(ctx, notify, nil)
+	jobsAfterDuplicate := countPaymentJobs(ctx)
+	markUserProcessed(ctx, "order-pay", false)
+	processedResult := processPaymentNotify(ctx, notify, nil)
+	jobsAfterProcessed := countPaymentJobs(ctx)
+
+	// Assert
+	assert.False(t, firstResult.IsError())
+	assert.False(t, duplicateResult.IsError())
+	assert.False(t, processedResult.IsError())
+	assert.Equal(t, 1, jobsAfterFirst)
+	assert.Equal(t, 2, jobsAfterDuplicate)
+	assert.Equal(t, 2, jobsAfterProcessed)
+}
+
+// TestProcessPaymentNotifyRefund 测试退款回调投递退款任务
+// Scenario: 已发货订单退款投递任务，玩家侧退款完成后重复的退款回调不再投递
+func TestProcessPaymentNotifyRefund(t *testing.T) {
+	// Arrange
+	app, _ := test_utility.CreateMemoryStorageApp()
+	ctx := test_utility.NewMockRpcContext(app)
+	addTestPaymentOrder(t, ctx, "order-refund")
+	payNotify := &payment_verifier.PaymentNotify{OrderId: "order-refund", Event: payment_verifier.PaymentNotifyEventPay, Amount: 600}
+	refundNotify := &payment_verifier.PaymentNotify{OrderId: "order-refund", Event: payment_verifier.PaymentNotifyEventRefund}
+	processPaymentNotify(ctx, payNotify, nil)
+	markUserProcessed(ctx, "order-refund", false)
+
+	// Act
+	refundResult := processPaymentNotify(ctx, refundNotify, nil)
+	jobsAfterRefund := countPaymentJobs(ctx)
+	markUserProcessed(ctx, "order-refund", true)
+	duplicateResult := processPaymentNotify(ctx, refundNotify, nil)
+	jobsAfterDuplicate := countPaymentJobs(ctx)
+	table, _, _ := db.DatabaseTablePaymentOrderLoadWithOrderId(ctx, "order-refund")
+
+	// Assert
+	assert.False(t, refundResult.IsError())
+	assert.False(t, duplicateResult.IsError())
+	assert.Equal(t, 2, jobsAfterRefund)
+	assert.Equal(t, 2, jobsAfterDuplicate)
+	assert.Equal(t, public_protocol_pbdesc.EnPaymentOrderStatus_EN_PAYMENT_ORDER_STATUS_REFUNDED, table.GetOrder().GetStatus())
+}
+
+// TestProcessPaymentNotifyAmountMismatch 测试金额不一致的支付回调
+// Scenario: 金额不一致时返回错误，不投递发货任务
+func TestProcessPaymentNotifyAmountMismatch(t *testing.T) {
+	// Arrange
+	app, _ := test_utility.CreateMemoryStorageApp()
+	ctx := test_utility.NewMockRpcContext(app)
+	addTestPaymentOrder(t, ctx, "order-mismatch")
+	notify := &payment_verifier.PaymentNotify{OrderId: "order-mismatch", Event: payment_verifier.PaymentNotifyEventPay, Amount: 1}
+
+	// Act
+	result := processPaymentNotify(ctx, notify, nil)
+
+	// Assert
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_PAYMENT_AMOUNT_MISMATCH), result.GetResponseCode())
+	assert.Equal(t, 0, countPaymentJobs(ctx))
+}
+
+// TestProcessPaymentNotifyRefundBeforePay 测试退款回调先于支付回调到达
+// Scenario: 未支付订单的退款回调返回成功并标记为已退款，之后到达的支付回调返回成功但不再投递发货任务
+func TestProcessPaymentNotifyRefundBeforePay(t *testing.T) {
+	// Arrange
+	app, _ := test_utility.CreateMemoryStorageApp()
+	ctx := test_utility.NewMockRpcContext(app)
+	addTestPaymentOrder(t, ctx, "order-late")
+	payNotify := &payment_verifier.PaymentNotify{OrderId: "order-late", Event: payment_verifier.PaymentNotifyEventPay, Amount: 600}
+	refundNotify := &payment_verifier.PaymentNotify{OrderId: "order-late", Event: payment_verifier.PaymentNotifyEventRefund}
+
+	// Act
+	refundResult := processPaymentNotify(ctx, refundNotify, nil)
+	jobsAfterRefund := countPaymentJobs(ctx)
+	markUserProcessed(ctx, "order-late", true)
+	payResult := processPaymentNotify(ctx, payNotify, nil)
+	jobsAfterPay := countPaymentJobs(ctx)
+	table, _, _ := db.DatabaseTablePaymentOrderLoadWithOrderId(ctx, "order-late")
+
+	// Assert
+	assert.False(t, refundResult.IsError())
+	assert.False(t, payResult.IsError())
+	assert.Equal(t, 1, jobsAfterRefund)
+	assert.Equal(t, 1, jobsAfterPay)
+	assert.Equal(t, public_protocol_pbdesc.EnPaymentOrderStatus_EN_PAYMENT_ORDER_STATUS_REFUNDED, table.GetOrder().GetStatus())
+}
@@ -0,0 +1,67 @@
+// Copyright 2026 atframework
+// @brief 支付订单表操作
+
+package lobbysvr_logic_payment_data
+
+import (
+	db "github.com/atframework/atsf4g-go/component/db"
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	private_protocol_log "github.com/atframework/atsf4g-go/component/protocol/private/log/protocol/log"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+)
+
+// 订单表CAS冲突时的最大重试次数
+const paymentOrderCASRetryTimes = 3
+
+// PaymentOrderMutator 修改订单，返回 false 表示无需写回
+type PaymentOrderMutator func(table *private_protocol_pbdesc.DatabaseTablePaymentOrder) (bool, cd.RpcResult)
+
+// UpdatePaymentOrder 读取订单并按CAS写回，冲突时重新读取重试
+func UpdatePaymentOrder(ctx cd.AwaitableContext, orderId string, mutator PaymentOrderMutator) (*private_protocol_pbdesc.DatabaseTablePaymentOrder, cd.RpcResult) {
+	var result cd.RpcResult
+	for i := 0; i < paymentOrderCASRetryTimes; i++ {
+		table, casVersion, loadResult := db.DatabaseTablePaymentOrderLoadWithOrderId(ctx, orderId)
+		if loadResult.IsError() {
+			if loadResult.GetResponseCode() == int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND) {
+				return nil, cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_PAYMENT_ORDER_NOT_FOUND)
+			}
+			return nil, loadResult
+		}
+
+		changed, mutateResult := mutator(table)
+		if mutateResult.IsError() || !changed {
+			return table, mutateResult
+		}
+
+		result = db.DatabaseTablePaymentOrderReplaceOrderId(ctx, table, &casVersion, false)
+		if result.GetResponseCode() == int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_CAS_CHECK_FAILED) {
+			ctx.LogWarn("payment order cas check failed, retry", "order_id", orderId, "retry", i)
+			continue
+		}
+
+		if result.IsError() {
+			return nil, result
+		}
+		return table, result
+	}
+
+	ctx.LogError("update payment order failed", "order_id", orderId, "error", result)
+	return nil, result
+}
+
+// MakePaymentOssLog 生成支付流水
+func MakePaymentOssLog(order *public_protocol_pbdesc.DPaymentOrder, resultCode int32) *private_protocol_log.OperationSupportSystemLog {
+	log := &private_protocol_log.OperationSupportSystemLog{}
+	flow := log.MutableLog().MutablePayFlow()
+	flow.OrderId = order.GetOrderId()
+	flow.ProductId = order.GetProductId()
+	flow.PurchaseCount = order.GetPurchaseCount()
+	flow.Price = order.GetPrice()
+	flow.Currency = order.GetCurrency()
+	flow.Status = order.GetStatus()
+	flow.PlatformOrderId = order.GetPlatformOrderId()
+	flow.Channel = order.GetChannel()
+	flow.Result = resultCode
+	return log
+}
@@ -0,0 +1,394 @@
+package lobbysvr_logic_payment_impl
+
+import (
+	"fmt"
+
+	config "github.com/atframework/atsf4g-go/component/config"
+	db "github.com/atframework/atsf4g-go/component/db"
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	private_protocol_config "github.com/atframework/atsf4g-go/component/protocol/private/config/protocol/config"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_common "github.com/atframework/atsf4g-go/component/protocol/public/common/protocol/common"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	uuid "github.com/atframework/atsf4g-go/component/uuid"
+
+	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
+	logic_mall "github.com/atframework/atsf4g-go/service-lobbysvr/logic/mall"
+	logic_payment "github.com/atframework/atsf4g-go/service-lobbysvr/logic/payment"
+	payment_data "github.com/atframework/atsf4g-go/service-lobbysvr/logic/payment/data"
+	service_protocol "github.com/atframework/atsf4g-go/service-lobbysvr/protocol/public/protocol/pbdesc"
+)
+
+func init() {
+	var _ logic_payment.UserPaymentManager = (*UserPaymentManager)(nil)
+	data.RegisterUserModuleManagerCreator[logic_payment.UserPaymentManager](func(ctx cd.RpcContext, owner *data.User) data.UserModuleManagerImpl {
+		return CreateUserPaymentManager(owner)
+	})
+}
+
+// orderHistory 按处理顺序保存的订单号集合，超过上限时淘汰最早的记录
+type orderHistory struct {
+	orderIds []string
+	index    map[string]struct{}
+}
+
+func (h *orderHistory) contains(orderId string) bool {
+	_, ok := h.index[orderId]
+	return ok
+}
+
+func (h *orderHistory) add(orderId string, limit int) {
+	if h.contains(orderId) {
+		return
+	}
+
+	h.orderIds = append(h.orderIds, orderId)
+	h.index[orderId] = struct{}{}
+	for limit > 0 && len(h.orderIds) > limit {
+		delete(h.index, h.orderIds[0])
+		h.orderIds = h.orderIds[1:]
+	}
+}
+
+func (h *orderHistory) reset(orderIds []string) {
+	h.orderIds = make([]string, 0, len(orderIds))
+	h.index = make(map[string]struct{}, len(orderIds))
+	for _, orderId := range orderIds {
+		h.add(orderId, 0)
+	}
+}
+
+type UserPaymentManager struct {
+	data.UserModuleManagerBase
+
+	deliveredOrders orderHistory
+	refundedOrders  orderHistory
+	reclaimDebts    []*private_protocol_pbdesc.UserPaymentReclaimDebt
+}
+
+func CreateUserPaymentManager(owner *data.User) *UserPaymentManager {
+	ret := &UserPaymentManager{
+		UserModuleManagerBase: *data.CreateUserModuleManagerBase(owner),
+	}
+	ret.deliveredOrders.reset(nil)
+	ret.refundedOrders.reset(nil)
+	return ret
+}
+
+func getPaymentConfig() *private_protocol_config.Readonly_LogicPaymentCfg {
+	return config.GetServerConfig[*private_protocol_config.Readonly_LobbyServerCfg](config.GetConfigManager().GetCurrentConfigGroup()).GetPayment()
+}
+
+func (m *UserPaymentManager) InitFromDB(_ctx cd.RpcContext, dbUser *private_protocol_pbdesc.DatabaseTableUser) cd.RpcResult {
+	m.deliveredOrders.reset(dbUser.GetPaymentData().GetDeliveredOrderIds())
+	m.refundedOrders.reset(dbUser.GetPaymentData().GetRefundedOrderIds())
+	m.reclaimDebts = make([]*private_protocol_pbdesc.UserPaymentReclaimDebt, 0, len(dbUser.GetPaymentData().GetReclaimDebts()))
+	for _, debt := range dbUser.GetPaymentData().GetReclaimDebts() {
+		m.reclaimDebts = append(m.reclaimDebts, debt.Clone())
+	}
+	return cd.CreateRpcResultOk()
+}
+
+func (m *UserPaymentManager) DumpToDB(_ctx cd.RpcContext, dbUser *private_protocol_pbdesc.DatabaseTableUser) cd.RpcResult {
+	paymentData := dbUser.MutablePaymentData()
+	paymentData.DeliveredOrderIds = append(make([]string, 0, len(m.deliveredOrders.orderIds)), m.deliveredOrders.orderIds...)
+	paymentData.RefundedOrderIds = append(make([]string, 0, len(m.refundedOrders.orderIds)), m.refundedOrders.orderIds...)
+	paymentData.ReclaimDebts = make([]*private_protocol_pbdesc.UserPaymentReclaimDebt, 0, len(m.reclaimDebts))
+	for _, debt := range m.reclaimDebts {
+		paymentData.ReclaimDebts = append(paymentData.ReclaimDebts, debt.Clone())
+	}
+	return cd.CreateRpcResultOk()
+}
+
+func (m *UserPaymentManager) LoginInit(ctx cd.RpcContext) {
+	m.reclaimPendingDebts(ctx)
+}
+
+func (m *UserPaymentManager) RefreshLimitMinute(ctx cd.RpcContext) {
+	m.reclaimPendingDebts(ctx)
+}
+
+func (m *UserPaymentManager) IsOrderDelivered(orderId string) bool {
+	return m.deliveredOrders.contains(orderId)
+}
+
+func (m *UserPaymentManager) IsOrderRefunded(orderId string) bool {
+	return m.refundedOrders.contains(orderId)
+}
+
+func (m *UserPaymentManager) CreateOrder(ctx cd.AwaitableContext, req *service_protocol.CSPaymentCreateOrderReq) (*public_protocol_pbdesc.DPaymentOrder, cd.RpcResult) {
+	paymentCfg := getPaymentConfig()
+	if !paymentCfg.GetEnable() {
+		return nil, cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_PAYMENT_DISABLED)
+	}
+
+	mallMgr := data.UserGetModuleManager[logic_mall.UserMallManager](m.GetOwner())
+	if mallMgr == nil {
+		return nil, cd.CreateRpcResultError(fmt.Errorf("user mall manager not found"), public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+	}
+
+	purchaseData := &service_protocol.DMallPurchaseData{
+		ProductId:        req.GetProductId(),
+		PurchasePriority: req.GetPurchasePriority(),
+		PurchaseCount:    req.GetPurchaseCount(),
+	}
+	if resultCode := mallMgr.CheckPaidProductPurchase(ctx, purchaseData); resultCode != 0 {
+		return nil, cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode(resultCode))
+	}
+
+	productRow := config.GetConfigManager().GetCurrentConfigGroup().GetExcelMallProductByProductIdPurchasePriority(req.GetProductId(), req.GetPurchasePriority())
+	now := ctx.GetNow().Unix()
+	order := &public_protocol_pbdesc.DPaymentOrder{
+		OrderId:          uuid.GenerateShortUUID(ctx),
+		ProductId:        req.GetProductId(),
+		PurchasePriority: req.GetPurchasePriority(),
+		PurchaseCount:    req.GetPurchaseCount(),
+		Price:            productRow.GetPayPrice() * int64(req.GetPurchaseCount()),
+		Currency:         productRow.GetPayCurrency(),
+		Status:           public_protocol_pbdesc.EnPaymentOrderStatus_EN_PAYMENT_ORDER_STATUS_CREATED,
+		Channel:          req.GetChannel(),
+		CreateTime:       now,
+		ExpireTime:       now + int64(paymentCfg.GetOrderTimeout().AsDuration().Seconds()),
+	}
+
+	result, _ := db.DatabaseTablePaymentOrderAddOrderId(ctx, &private_protocol_pbdesc.DatabaseTablePaymentOrder{
+		OrderId: order.GetOrderId(),
+		UserId:  m.GetOwner().GetUserId(),
+		ZoneId:  m.GetOwner().GetZoneId(),
+		Order:   order,
+	})
+	if result.IsError() {
+		ctx.LogError("add payment order failed", "order_id", order.GetOrderId(), "error", result)
+		return nil, result
+	}
+
+	m.GetOwner().SendUserOssLog(ctx, payment_data.MakePaymentOssLog(order, 0))
+	return order, cd.CreateRpcResultOk()
+}
+
+func (m *UserPaymentManager) DeliverOrder(ctx cd.AwaitableContext, order *public_protocol_pbdesc.DPaymentOrder) int32 {
+	if m.IsOrderDelivered(order.GetOrderId()) {
+		ctx.LogInfo("payment order already delivered, skip", "order_id", order.GetOrderId())
+		return 0
+	}
+
+	// 退款先于发货到达时不再发货
+	if m.IsOrderRefunded(order.GetOrderId()) {
+		ctx.LogWarn("payment order already refunded, skip delivery", "order_id", order.GetOrderId())
+		return 0
+	}
+
+	mallMgr := data.UserGetModuleManager[logic_mall.UserMallManager](m.GetOwner())
+	if mallMgr == nil {
+		ctx.LogError("user mall manager not found", "order_id", order.GetOrderId())
+		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+	}
+
+	_, resultCode := mallMgr.DeliverPaidProduct(ctx, &service_protocol.DMallPurchaseData{
+		ProductId:        order.GetProductId(),
+		PurchasePriority: order.GetPurchasePriority(),
+		PurchaseCount:    order.GetPurchaseCount(),
+	}, order.GetOrderId(), &data.ItemFlowReason{
+		MajorReason: int32(public_protocol_common.EnItemFlowReasonMajorType_EN_ITEM_FLOW_REASON_MAJOR_PAYMENT),
+		MinorReason: int32(public_protocol_common.EnItemFlowReasonMinorType_EN_ITEM_FLOW_REASON_MINOR_PAYMENT_DELIVERY),
+		Parameter:   int64(order.GetProductId()),
+	})
+	if resultCode != 0 {
+		ctx.LogError("deliver payment order failed", "order_id", order.GetOrderId(), "result", resultCode)
+		return resultCode
+	}
+
+	m.deliveredOrders.add(order.GetOrderId(), int(getPaymentConfig().GetMaxOrderHistory()))
+
+	deliveredOrder := order.Clone()
+	deliveredOrder.Status = public_protocol_pbdesc.EnPaymentOrderStatus_EN_PAYMENT_ORDER_STATUS_DELIVERED
+	deliveredOrder.DeliverTime = ctx.GetNow().Unix()
+	m.GetOwner().SendUserOssLog(ctx, payment_data.MakePaymentOssLog(deliveredOrder, 0))
+
+	m.updateOrderUserProcessed(ctx, deliveredOrder, false)
+	return 0
+}
+
+func (m *UserPaymentManager) RefundOrder(ctx cd.RpcContext, order *public_protocol_pbdesc.DPaymentOrder) int32 {
+	if m.IsOrderRefunded(order.GetOrderId()) {
+		ctx.LogInfo("payment order already refunded, skip", "order_id", order.GetOrderId())
+		return 0
+	}
+
+	refundedOrder := order.Clone()
+	refundedOrder.Status = public_protocol_pbdesc.EnPaymentOrderStatus_EN_PAYMENT_ORDER_STATUS_REFUNDED
+	if refundedOrder.GetRefundTime() == 0 {
+		refundedOrder.RefundTime = ctx.GetNow().Unix()
+	}
+
+	// 未发货的订单只需要标记，后续的发货任务会被跳过
+	// 订单表回写的发货时间覆盖玩家数据中已经淘汰的订单记录
+	if !m.IsOrderDelivered(order.GetOrderId()) && order.GetDeliverTime() == 0 {
+		m.refundedOrders.add(order.GetOrderId(), int(getPaymentConfig().GetMaxOrderHistory()))
+		m.GetOwner().SendUserOssLog(ctx, payment_data.MakePaymentOssLog(refundedOrder, 0))
+		m.updateOrderUserProcessed(ctx, refundedOrder, true)
+		return 0
+	}
+
+	reclaimItems, resultCode := getPaymentOrderItems(order)
+	if resultCode == 0 {
+		var debtItems []*public_protocol_common.DItemBasic
+		debtItems, resultCode = m.reclaimItems(ctx, reclaimItems, order.GetProductId())
+		if resultCode == 0 && len(debtItems) > 0 {
+			// 道具不足的部分记为欠款，之后获得道具时继续回收
+			ctx.LogWarn("refunded payment order items not enough, record reclaim debt", "order_id", order.GetOrderId(),
+				"debt_items", debtItems)
+			m.reclaimDebts = append(m.reclaimDebts, &private_protocol_pbdesc.UserPaymentReclaimDebt{
+				OrderId:    order.GetOrderId(),
+				ProductId:  order.GetProductId(),
+				Items:      debtItems,
+				CreateTime: ctx.GetNow().Unix(),
+			})
+		}
+	}
+
+	if resultCode != 0 {
+		// 返回失败由异步任务重试，不能记为已退款
+		ctx.LogError("reclaim refunded payment order items failed", "order_id", order.GetOrderId(), "result", resultCode)
+		return resultCode
+	}
+
+	m.refundedOrders.add(order.GetOrderId(), int(getPaymentConfig().GetMaxOrderHistory()))
+	m.GetOwner().SendUserOssLog(ctx, payment_data.MakePaymentOssLog(refundedOrder, 0))
+	m.updateOrderUserProcessed(ctx, refundedOrder, true)
+	return 0
+}
+
+// getPaymentOrderItems 订单发放的道具
+func getPaymentOrderItems(order *public_protocol_pbdesc.DPaymentOrder) ([]*public_protocol_common.DItemBasic, int32) {
+	productRow := config.GetConfigManager().GetCurrentConfigGroup().GetExcelMallProductByProductIdPurchasePriority(order.GetProductId(), order.GetPurchasePriority())
+	if productRow == nil {
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_MALL_PRODUCT_NOT_FOUND)
+	}
+
+	ret := make([]*public_protocol_common.DItemBasic, 0, len(productRow.GetProductItems()))
+	for _, item := range productRow.GetProductItems() {
+		ret = append(ret, &public_protocol_common.DItemBasic{
+			TypeId: item.GetTypeId(),
+			Count:  item.GetCount() * int64(order.GetPurchaseCount()),
+		})
+	}
+	return ret, 0
+}
+
+// splitPaymentReclaimItems 按当前持有的数量拆分出本次能回收的部分和不足的部分
+func splitPaymentReclaimItems(items []*public_protocol_common.DItemBasic, getHasCount func(typeId int32) int64) (
+	subItems []*public_protocol_common.DItemBasic, debtItems []*public_protocol_common.DItemBasic,
+) {
+	hasCounts := make(map[int32]int64, len(items))
+	for _, item := range items {
+		if item.GetCount() <= 0 {
+			continue
+		}
+
+		hasCount, ok := hasCounts[item.GetTypeId()]
+		if !ok {
+			hasCount = getHasCount(item.GetTypeId())
+		}
+
+		subCount := min(item.GetCount(), max(hasCount, 0))
+		hasCounts[item.GetTypeId()] = hasCount - subCount
+		if subCount > 0 {
+			subItems = append(subItems, &public_protocol_common.DItemBasic{TypeId: item.GetTypeId(), Count: subCount})
+		}
+		if subCount < item.GetCount() {
+			debtItems = append(debtItems, &public_protocol_common.DItemBasic{TypeId: item.GetTypeId(), Count: item.GetCount() - subCount})
+		}
+	}
+	return
+}
+
+// reclaimItems 回收当前持有的部分，返回没有回收到的道具
+func (m *UserPaymentManager) reclaimItems(ctx cd.RpcContext, items []*public_protocol_common.DItemBasic, productId int32) (
+	[]*public_protocol_common.DItemBasic, int32,
+) {
+	subItems, debtItems := splitPaymentReclaimItems(items, func(typeId int32) int64 {
+		return m.GetOwner().GetItemTypeStatistics(ctx, typeId).GetTotalCount()
+	})
+	if len(subItems) == 0 {
+		return debtItems, 0
+	}
+
+	subGuard, result := m.GetOwner().CheckSubItem(ctx, subItems)
+	if result.IsError() {
+		return nil, result.GetResponseCode()
+	}
+
+	m.GetOwner().SubItem(ctx, subGuard, &data.ItemFlowReason{
+		MajorReason: int32(public_protocol_common.EnItemFlowReasonMajorType_EN_ITEM_FLOW_REASON_MAJOR_PAYMENT),
+		MinorReason: int32(public_protocol_common.EnItemFlowReasonMinorType_EN_ITEM_FLOW_REASON_MINOR_PAYMENT_REFUND),
+		Parameter:   int64(productId),
+	})
+	return debtItems, 0
+}
+
+// reclaimPendingDebts 继续回收退款欠款，回收完的记录删除
+func (m *UserPaymentManager) reclaimPendingDebts(ctx cd.RpcContext) {
+	if len(m.reclaimDebts) == 0 {
+		return
+	}
+
+	remainDebts := m.reclaimDebts[:0]
+	for _, debt := range m.reclaimDebts {
+		debtItems, resultCode := m.reclaimItems(ctx, debt.GetItems(), debt.GetProductId())
+		if resultCode != 0 {
+			ctx.LogError("reclaim payment debt failed", "order_id", debt.GetOrderId(), "result", resultCode)
+			remainDebts = append(remainDebts, debt)
+			continue
+		}
+
+		if len(debtItems) == 0 {
+			ctx.LogInfo("payment reclaim debt cleared", "order_id", debt.GetOrderId())
+			continue
+		}
+		debt.Items = debtItems
+		remainDebts = append(remainDebts, debt)
+	}
+	m.reclaimDebts = remainDebts
+}
+
+// updateOrderUserProcessed 玩家侧处理完成后异步回写订单表，之后重复的平台回调不再投递任务
+// 回写完成前的重复任务由玩家数据中最近的订单记录去重
+func (m *UserPaymentManager) updateOrderUserProcessed(ctx cd.RpcContext, order *public_protocol_pbdesc.DPaymentOrder, isRefund bool) {
+	orderId := order.GetOrderId()
+	now := ctx.GetNow().Unix()
+	cd.AsyncInvoke(ctx, "UserPaymentManager.updateOrderUserProcessed", nil, func(childCtx cd.AwaitableContext) cd.RpcResult {
+		_, result := payment_data.UpdatePaymentOrder(childCtx, orderId, func(table *private_protocol_pbdesc.DatabaseTablePaymentOrder) (bool, cd.RpcResult) {
+			return markPaymentOrderUserProcessed(table, order, isRefund, now), cd.CreateRpcResultOk()
+		})
+		if result.IsError() {
+			childCtx.LogError("update payment order user processed failed", "order_id", orderId, "is_refund", isRefund, "error", result)
+		}
+		return result
+	})
+}
+
+// markPaymentOrderUserProcessed 返回 false 表示已经标记过，无需写回
+func markPaymentOrderUserProcessed(table *private_protocol_pbdesc.DatabaseTablePaymentOrder, order *public_protocol_pbdesc.DPaymentOrder,
+	isRefund bool, now int64,
+) bool {
+	if isRefund {
+		if table.GetUserRefundTime() != 0 {
+			return false
+		}
+		table.UserRefundTime = now
+		return true
+	}
+
+	if table.GetUserDeliverTime() != 0 {
+		return false
+	}
+	table.UserDeliverTime = now
+
+	// 已经退款的订单不再回写为已发货
+	if table.GetOrder().GetStatus() == public_protocol_pbdesc.EnPaymentOrderStatus_EN_PAYMENT_ORDER_STATUS_PAID {
+		table.MutableOrder().Status = public_protocol_pbdesc.EnPaymentOrderStatus_EN_PAYMENT_ORDER_STATUS_DELIVERED
+		table.MutableOrder().DeliverTime = order.GetDeliverTime()
+	}
+	return true
+}
@@ -0,0 +1,94 @@
+package lobbysvr_logic_payment_impl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_common "github.com/atframework/atsf4g-go/component/protocol/public/common/protocol/common"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+)
+
+// TestOrderHistoryDeduplicateAndCap 测试订单记录去重和上限
+// Scenario: 重复添加只保留一条，超过上限时淘汰最早的订单
+func TestOrderHistoryDeduplicateAndCap(t *testing.T) {
+	// Arrange
+	history := orderHistory{}
+	history.reset(nil)
+
+	// Act
+	history.add("order-1", 2)
+	history.add("order-1", 2)
+	history.add("order-2", 2)
+	history.add("order-3", 2)
+
+	// Assert
+	assert.Equal(t, []string{"order-2", "order-3"}, history.orderIds)
+	assert.False(t, history.contains("order-1"))
+	assert.True(t, history.contains("order-2"))
+	assert.True(t, history.contains("order-3"))
+}
+
+// TestSplitPaymentReclaimItems 测试退款回收拆分
+// Scenario: 持有足够的全部回收，不足的部分记为欠款，同类道具多次出现时共享持有数量
+func TestSplitPaymentReclaimItems(t *testing.T) {
+	// Arrange
+	hasCounts := map[int32]int64{1001: 100, 1002: 30}
+	items := []*public_protocol_common.DItemBasic{
+		{TypeId: 1001, Count: 60},
+		{TypeId: 1002, Count: 50},
+		{TypeId: 1001, Count: 60},
+		{TypeId: 1003, Count: 10},
+	}
+
+	// Act
+	subItems, debtItems := splitPaymentReclaimItems(items, func(typeId int32) int64 {
+		return hasCounts[typeId]
+	})
+
+	// Assert
+	assert.Equal(t, []*public_protocol_common.DItemBasic{
+		{TypeId: 1001, Count: 60},
+		{TypeId: 1002, Count: 30},
+		{TypeId: 1001, Count: 40},
+	}, subItems)
+	assert.Equal(t, []*public_protocol_common.DItemBasic{
+		{TypeId: 1002, Count: 20},
+		{TypeId: 1001, Count: 20},
+		{TypeId: 1003, Count: 10},
+	}, debtItems)
+}
+
+// TestMarkPaymentOrderUserProcessed 测试订单表的玩家侧处理标记
+// Scenario: 首次发货标记为已发货，重复标记不写回，已退款的订单只记录时间不改状态
+func TestMarkPaymentOrderUserProcessed(t *testing.T) {
+	// Arrange
+	paidTable := &private_protocol_pbdesc.DatabaseTablePaymentOrder{
+		Order: &public_protocol_pbdesc.DPaymentOrder{Status: public_protocol_pbdesc.EnPaymentOrderStatus_EN_PAYMENT_ORDER_STATUS_PAID},
+	}
+	refundedTable := &private_protocol_pbdesc.DatabaseTablePaymentOrder{
+		Order: &public_protocol_pbdesc.DPaymentOrder{Status: public_protocol_pbdesc.EnPaymentOrderStatus_EN_PAYMENT_ORDER_STATUS_REFUNDED},
+	}
+	deliveredOrder := &public_protocol_pbdesc.DPaymentOrder{DeliverTime: 1000}
+
+	// Act
+	firstDeliver := markPaymentOrderUserProcessed(paidTable, deliveredOrder, false, 1000)
+	secondDeliver := markPaymentOrderUserProcessed(paidTable, deliveredOrder, false, 2000)
+	lateDeliver := markPaymentOrderUserProcessed(refundedTable, deliveredOrder, false, 1000)
+	firstRefund := markPaymentOrderUserProcessed(refundedTable, deliveredOrder, true, 3000)
+	secondRefund := markPaymentOrderUserProcessed(refundedTable, deliveredOrder, true, 4000)
+
+	// Assert
+	assert.True(t, firstDeliver)
+	assert.False(t, secondDeliver)
+	assert.Equal(t, int64(1000), paidTable.GetUserDeliverTime())
+	assert.Equal(t, public_protocol_pbdesc.EnPaymentOrderStatus_EN_PAYMENT_ORDER_STATUS_DELIVERED, paidTable.GetOrder().GetStatus())
+	assert.Equal(t, int64(1000), paidTable.GetOrder().GetDeliverTime())
+
+	assert.True(t, lateDeliver)
+	assert.Equal(t, public_protocol_pbdesc.EnPaymentOrderStatus_EN_PAYMENT_ORDER_STATUS_REFUNDED, refundedTable.GetOrder().GetStatus())
+	assert.True(t, firstRefund)
+	assert.False(t, secondRefund)
+	assert.Equal(t, int64(3000), refundedTable.GetUserRefundTime())
+}
@@ -0,0 +1,23 @@
+package lobbysvr_logic_payment
+
+import (
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
+	service_protocol "github.com/atframework/atsf4g-go/service-lobbysvr/protocol/public/protocol/pbdesc"
+)
+
+type UserPaymentManager interface {
+	data.UserModuleManagerImpl
+
+	// CreateOrder 创建支付订单，写入订单表后返回给客户端拉起平台支付
+	CreateOrder(ctx cd.AwaitableContext, req *service_protocol.CSPaymentCreateOrderReq) (*public_protocol_pbdesc.DPaymentOrder, cd.RpcResult)
+
+	// DeliverOrder 支付成功发货（异步任务调用），同一订单只发一次，背包放不下时改为邮件发放
+	DeliverOrder(ctx cd.AwaitableContext, order *public_protocol_pbdesc.DPaymentOrder) int32
+	// RefundOrder 退款/拒付回收（异步任务调用），同一订单只处理一次
+	RefundOrder(ctx cd.RpcContext, order *public_protocol_pbdesc.DPaymentOrder) int32
+
+	IsOrderDelivered(orderId string) bool
+	IsOrderRefunded(orderId string) bool
+}
@@ -0,0 +1,134 @@
+// Copyright 2026 atframework
+// @brief 支付平台回调校验
+
+package lobbysvr_logic_payment_verifier
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const (
+	PaymentNotifyEventPay    = "pay"
+	PaymentNotifyEventRefund = "refund" // 退款和拒付(chargeback)都按退款处理
+)
+
+// PaymentNotify 平台支付回调内容
+type PaymentNotify struct {
+	OrderId         string `json:"order_id"`
+	PlatformOrderId string `json:"platform_order_id"`
+	Event           string `json:"event"`
+	Amount          int64  `json:"amount"`
+	Currency        string `json:"currency"`
+	Timestamp       int64  `json:"timestamp"`
+	Signature       string `json:"signature"`
+}
+
+// PaymentVerifier 支付回调校验器，不同平台实现不同的签名规则
+type PaymentVerifier interface {
+	Name() string
+	// Verify 解析并校验回调内容，校验失败返回 error
+	Verify(body []byte, secret string) (*PaymentNotify, error)
+}
+
+var (
+	verifiersLock sync.RWMutex
+	verifiers     = map[string]PaymentVerifier{}
+)
+
+func init() {
+	RegisterPaymentVerifier(&stubVerifier{})
+	RegisterPaymentVerifier(&hmacSha256Verifier{})
+}
+
+// RegisterPaymentVerifier 注册校验器，同名覆盖
+func RegisterPaymentVerifier(v PaymentVerifier) {
+	if v == nil {
+		return
+	}
+
+	verifiersLock.Lock()
+	defer verifiersLock.Unlock()
+	verifiers[v.Name()] = v
+}
+
+// GetPaymentVerifier 按名称获取校验器
+func GetPaymentVerifier(name string) PaymentVerifier {
+	verifiersLock.RLock()
+	defer verifiersLock.RUnlock()
+	return verifiers[name]
+}
+
+func parsePaymentNotify(body []byte) (*PaymentNotify, error) {
+	notify := &PaymentNotify{}
+	if err := json.Unmarshal(body, notify); err != nil {
+		return nil, fmt.Errorf("invalid payment notify body: %w", err)
+	}
+
+	if notify.OrderId == "" {
+		return nil, fmt.Errorf("payment notify without order_id")
+	}
+
+	switch notify.Event {
+	case PaymentNotifyEventPay, PaymentNotifyEventRefund:
+	default:
+		return nil, fmt.Errorf("unknown payment notify event %q", notify.Event)
+	}
+
+	return notify, nil
+}
+
+// PaymentNotifySignContent 生成签名原文: order_id|platform_order_id|event|amount|currency|timestamp
+func PaymentNotifySignContent(notify *PaymentNotify) string {
+	return strings.Join([]string{
+		notify.OrderId,
+		notify.PlatformOrderId,
+		notify.Event,
+		fmt.Sprintf("%d", notify.Amount),
+		notify.Currency,
+		fmt.Sprintf("%d", notify.Timestamp),
+	}, "|")
+}
+
+// PaymentNotifySign 计算 hmac_sha256 签名(小写十六进制)
+func PaymentNotifySign(notify *PaymentNotify, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(PaymentNotifySignContent(notify)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// stubVerifier 不校验签名，仅用于测试环境
+type stubVerifier struct{}
+
+func (v *stubVerifier) Name() string { return "stub" }
+
+func (v *stubVerifier) Verify(body []byte, _secret string) (*PaymentNotify, error) {
+	return parsePaymentNotify(body)
+}
+
+type hmacSha256Verifier struct{}
+
+func (v *hmacSha256Verifier) Name() string { return "hmac_sha256" }
+
+func (v *hmacSha256Verifier) Verify(body []byte, secret string) (*PaymentNotify, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("payment secret is not configured")
+	}
+
+	notify, err := parsePaymentNotify(body)
+	if err != nil {
+		return nil, err
+	}
+
+	expect := PaymentNotifySign(notify, secret)
+	if !hmac.Equal([]byte(expect), []byte(strings.ToLower(notify.Signature))) {
+		return nil, fmt.Errorf("payment notify signature mismatch")
+	}
+
+	return notify, nil
+}
@@ -0,0 +1,59 @@
+package lobbysvr_logic_payment_verifier
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestHmacSha256VerifierSignature 测试 hmac_sha256 校验
+// Scenario: 正确签名通过，篡改金额或密钥错误时校验失败
+func TestHmacSha256VerifierSignature(t *testing.T) {
+	// Arrange
+	notify := &PaymentNotify{
+		OrderId:         "order-1",
+		PlatformOrderId: "platform-1",
+		Event:           PaymentNotifyEventPay,
+		Amount:          600,
+		Currency:        "CNY",
+		Timestamp:       1700000000,
+	}
+	notify.Signature = PaymentNotifySign(notify, "secret")
+	body, _ := json.Marshal(notify)
+
+	tampered := *notify
+	tampered.Amount = 1
+	tamperedBody, _ := json.Marshal(&tampered)
+
+	verifier := GetPaymentVerifier("hmac_sha256")
+
+	// Act
+	parsed, err := verifier.Verify(body, "secret")
+	_, wrongSecretErr := verifier.Verify(body, "other")
+	_, tamperedErr := verifier.Verify(tamperedBody, "secret")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "order-1", parsed.OrderId)
+	assert.Equal(t, int64(600), parsed.Amount)
+	assert.Error(t, wrongSecretErr)
+	assert.Error(t, tamperedErr)
+}
+
+// TestStubVerifierRejectsInvalidNotify 测试 stub 校验器
+// Scenario: 不校验签名，但缺少订单号或事件未知时仍然失败
+func TestStubVerifierRejectsInvalidNotify(t *testing.T) {
+	// Arrange
+	verifier := GetPaymentVerifier("stub")
+
+	// Act
+	_, okErr := verifier.Verify([]byte(`{"order_id":"o","event":"refund"}`), "")
+	_, noOrderErr := verifier.Verify([]byte(`{"event":"pay"}`), "")
+	_, badEventErr := verifier.Verify([]byte(`{"order_id":"o","event":"unknown"}`), "")
+
+	// Assert
+	assert.NoError(t, okErr)
+	assert.Error(t, noOrderErr)
+	assert.Error(t, badEventErr)
+}
@@ -81,3 +81,23 @@ func GetProgressKeyIndexHandler(progressType int32) ProgressKeyIndexHandler {
 	}
 	return handlers.progressKeyIndexHandler
 }
+
+// ProgressTriggerListener 进度事件监听函数，任务处理完一个进度类型后调用
+// 用于成就等同样按进度类型累计但不属于任务的模块复用进度处理函数
+type ProgressTriggerListener func(ctx cd.RpcContext, user *data.User,
+	progressType int32, params *private_protocol_pbdesc.QuestTriggerParams)
+
+var progressTriggerListeners []ProgressTriggerListener
+
+// RegisterProgressTriggerListener 注册进度事件监听函数，只能在 init 阶段调用
+func RegisterProgressTriggerListener(listener ProgressTriggerListener) {
+	if listener == nil {
+		return
+	}
+
+	progressTriggerListeners = append(progressTriggerListeners, listener)
+}
+
+func GetProgressTriggerListeners() []ProgressTriggerListener {
+	return progressTriggerListeners
+}
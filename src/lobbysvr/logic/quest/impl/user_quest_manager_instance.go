@@ -482,6 +482,11 @@ func (m *UserQuestManager) triggerEventInner(ctx cd.RpcContext, eventItem EventQ
 	// 任务进度更新
 	for _, pregressType := range triggerCfg.GetProgressTypes() {
 		m.updateQuestProgressByType(ctx, eventItem.EventType, pregressType, eventItem.Params)
+
+		// 成就等模块共用同一套进度类型
+		for _, listener := range lobbysvr_logic_quest_handler.GetProgressTriggerListeners() {
+			listener(ctx, m.GetOwner(), pregressType, eventItem.Params)
+		}
 	}
 }
 
@@ -58,3 +58,10 @@ type UserQuestManager interface {
 func RegisterProgressHandler(progressType public_protocol_config.DQuestConditionProgress_EnProgressParamID, initHandler InitProgressHandler, updateHandler UpdateProgressHandler, progressKeyIndexHandler ProgressKeyIndexHandler) {
 	lobbysvr_logic_quest_handler.RegisterProgressHandler(int32(progressType), initHandler, updateHandler, progressKeyIndexHandler)
 }
+
+type ProgressTriggerListener = lobbysvr_logic_quest_handler.ProgressTriggerListener
+
+// RegisterProgressTriggerListener 监听任务进度事件，监听方可以复用已注册的进度处理函数
+func RegisterProgressTriggerListener(listener ProgressTriggerListener) {
+	lobbysvr_logic_quest_handler.RegisterProgressTriggerListener(listener)
+}
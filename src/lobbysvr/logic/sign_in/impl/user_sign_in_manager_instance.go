@@ -0,0 +1,353 @@
+package lobbysvr_logic_sign_in_impl
+
+import (
+	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
+
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+
+	config "github.com/atframework/atsf4g-go/component/config"
+	logical_time "github.com/atframework/atsf4g-go/component/logical_time"
+	private_protocol_log "github.com/atframework/atsf4g-go/component/protocol/private/log/protocol/log"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_common "github.com/atframework/atsf4g-go/component/protocol/public/common/protocol/common"
+	public_protocol_config "github.com/atframework/atsf4g-go/component/protocol/public/config/protocol/config"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	logic_condition "github.com/atframework/atsf4g-go/service-lobbysvr/logic/condition"
+	logic_quest "github.com/atframework/atsf4g-go/service-lobbysvr/logic/quest"
+	logic_sign_in "github.com/atframework/atsf4g-go/service-lobbysvr/logic/sign_in"
+	service_protocol "github.com/atframework/atsf4g-go/service-lobbysvr/protocol/public/protocol/pbdesc"
+)
+
+func init() {
+	var _ logic_sign_in.UserSignInManager = (*UserSignInManager)(nil)
+	data.RegisterUserModuleManagerCreator[logic_sign_in.UserSignInManager](func(ctx cd.RpcContext, owner *data.User) data.UserModuleManagerImpl {
+		return CreateUserSignInManager(owner)
+	})
+
+	registerQuestProgressHandlers()
+}
+
+type UserSignInManager struct {
+	owner *data.User
+	data.UserModuleManagerBase
+
+	calendars map[int32]*public_protocol_pbdesc.DSignInCalendarData
+
+	dirtyCalendars map[int32]struct{}
+}
+
+func CreateUserSignInManager(owner *data.User) *UserSignInManager {
+	return &UserSignInManager{
+		owner:                 owner,
+		UserModuleManagerBase: *data.CreateUserModuleManagerBase(owner),
+
+		calendars:      make(map[int32]*public_protocol_pbdesc.DSignInCalendarData),
+		dirtyCalendars: make(map[int32]struct{}),
+	}
+}
+
+func (m *UserSignInManager) InitFromDB(ctx cd.RpcContext, _dbUser *private_protocol_pbdesc.DatabaseTableUser) cd.RpcResult {
+	for _, calendarData := range _dbUser.GetSignInData().GetCalendars() {
+		m.calendars[calendarData.GetCalendarId()] = calendarData
+	}
+
+	return cd.CreateRpcResultOk()
+}
+
+func (m *UserSignInManager) DumpToDB(ctx cd.RpcContext, _dbUser *private_protocol_pbdesc.DatabaseTableUser) cd.RpcResult {
+	signInData := _dbUser.MutableSignInData()
+	signInData.Calendars = make([]*public_protocol_pbdesc.DSignInCalendarData, 0, len(m.calendars))
+	for _, calendarData := range m.calendars {
+		signInData.Calendars = append(signInData.Calendars, calendarData)
+	}
+
+	return cd.CreateRpcResultOk()
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+
+func (m *UserSignInManager) GetCalendarData(calendarId int32) *public_protocol_pbdesc.DSignInCalendarData {
+	return m.calendars[calendarId]
+}
+
+func (m *UserSignInManager) GetTotalSignInDays(calendarId int32) int64 {
+	if calendarId != 0 {
+		return m.calendars[calendarId].GetTotalSignInDays()
+	}
+
+	var ret int64
+	for _, calendarData := range m.calendars {
+		ret += calendarData.GetTotalSignInDays()
+	}
+	return ret
+}
+
+func (m *UserSignInManager) FetchData(ctx cd.RpcContext, rspBody *service_protocol.SCSignInGetInfoRsp) {
+	allCalendars := config.GetConfigManager().GetCurrentConfigGroup().GetExcelSignInCalendarAllOfCalendarId()
+	if allCalendars == nil {
+		return
+	}
+
+	for _, calendarRow := range *allCalendars {
+		if m.checkCalendarOpen(ctx, calendarRow) != 0 {
+			continue
+		}
+
+		calendarData, _ := m.refreshCalendar(ctx, calendarRow)
+		rspBody.Calendars = append(rspBody.Calendars, calendarData)
+	}
+}
+
+func (m *UserSignInManager) SignIn(ctx cd.RpcContext, calendarId int32, rspBody *service_protocol.SCSignInRsp) int32 {
+	calendarRow, resultCode := m.checkCalendar(ctx, calendarId)
+	if resultCode != 0 {
+		return resultCode
+	}
+
+	calendarData, state := m.refreshCalendar(ctx, calendarRow)
+	if isSignInDaySigned(calendarData, state.today) {
+		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_SIGN_IN_ALREADY_SIGNED)
+	}
+
+	addGuard, isVipDouble, resultCode := m.checkReward(ctx, calendarRow, state.today)
+	if resultCode != 0 {
+		return resultCode
+	}
+
+	m.GetOwner().AddItem(ctx, addGuard, &data.ItemFlowReason{
+		MajorReason: int32(public_protocol_common.EnItemFlowReasonMajorType_EN_ITEM_FLOW_REASON_MAJOR_SIGN_IN),
+		MinorReason: int32(public_protocol_common.EnItemFlowReasonMinorType_EN_ITEM_FLOW_REASON_MINOR_SIGN_IN_REWARD),
+		Parameter:   int64(calendarId),
+	})
+
+	markSignInDaySigned(calendarData, state, logical_time.GetDayId(ctx.GetNow(), nil), state.today)
+	calendarData.LastSignInTime = ctx.GetNow().Unix()
+	m.onSignedIn(ctx, calendarData, state.today, false, isVipDouble, addGuard, nil)
+
+	for _, guard := range addGuard {
+		rspBody.MergeRewardItems(guard.GetAddedItems())
+	}
+	rspBody.Calendar = calendarData
+	rspBody.Day = state.today
+	return 0
+}
+
+func (m *UserSignInManager) MakeUp(ctx cd.RpcContext, req *service_protocol.CSSignInMakeUpReq, rspBody *service_protocol.SCSignInMakeUpRsp) int32 {
+	calendarRow, resultCode := m.checkCalendar(ctx, req.GetCalendarId())
+	if resultCode != 0 {
+		return resultCode
+	}
+
+	calendarData, state := m.refreshCalendar(ctx, calendarRow)
+	day := req.GetDay()
+	if day <= 0 || day >= state.today {
+		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_SIGN_IN_DAY_INVALID)
+	}
+	if isSignInDaySigned(calendarData, day) {
+		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_SIGN_IN_ALREADY_SIGNED)
+	}
+	if calendarData.GetMakeUpCount() >= calendarRow.GetMakeUpMaxCount() {
+		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_SIGN_IN_MAKE_UP_LIMIT)
+	}
+
+	// 检查消耗
+	var subGuard []*data.ItemSubGuard
+	if len(calendarRow.GetMakeUpCost()) != 0 {
+		result := m.GetOwner().CheckCostItemCfg(ctx, req.GetExpectCostItems(), calendarRow.GetMakeUpCost())
+		if result.IsError() {
+			return result.GetResponseCode()
+		}
+
+		subGuard, result = m.GetOwner().CheckSubItem(ctx, req.GetExpectCostItems())
+		if result.IsError() {
+			return result.GetResponseCode()
+		}
+	}
+
+	addGuard, isVipDouble, resultCode := m.checkReward(ctx, calendarRow, day)
+	if resultCode != 0 {
+		return resultCode
+	}
+
+	if len(subGuard) != 0 {
+		m.GetOwner().SubItem(ctx, subGuard, &data.ItemFlowReason{
+			MajorReason: int32(public_protocol_common.EnItemFlowReasonMajorType_EN_ITEM_FLOW_REASON_MAJOR_SIGN_IN),
+			MinorReason: int32(public_protocol_common.EnItemFlowReasonMinorType_EN_ITEM_FLOW_REASON_MINOR_SIGN_IN_MAKE_UP_COST),
+			Parameter:   int64(calendarRow.GetCalendarId()),
+		})
+	}
+
+	m.GetOwner().AddItem(ctx, addGuard, &data.ItemFlowReason{
+		MajorReason: int32(public_protocol_common.EnItemFlowReasonMajorType_EN_ITEM_FLOW_REASON_MAJOR_SIGN_IN),
+		MinorReason: int32(public_protocol_common.EnItemFlowReasonMinorType_EN_ITEM_FLOW_REASON_MINOR_SIGN_IN_REWARD),
+		Parameter:   int64(calendarRow.GetCalendarId()),
+	})
+
+	markSignInDaySigned(calendarData, state, logical_time.GetDayId(ctx.GetNow(), nil), day)
+	calendarData.MakeUpCount++
+	m.onSignedIn(ctx, calendarData, day, true, isVipDouble, addGuard, req.GetExpectCostItems())
+
+	for _, guard := range addGuard {
+		rspBody.MergeRewardItems(guard.GetAddedItems())
+	}
+	rspBody.Calendar = calendarData
+	return 0
+}
+
+// checkCalendar 检查签到日历是否存在且已开放
+func (m *UserSignInManager) checkCalendar(ctx cd.RpcContext, calendarId int32) (*public_protocol_config.Readonly_ExcelSignInCalendar, int32) {
+	calendarRow := config.GetConfigManager().GetCurrentConfigGroup().GetExcelSignInCalendarByCalendarId(calendarId)
+	if calendarRow == nil {
+		return nil, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_SIGN_IN_CALENDAR_NOT_FOUND)
+	}
+
+	if resultCode := m.checkCalendarOpen(ctx, calendarRow); resultCode != 0 {
+		return nil, resultCode
+	}
+	return calendarRow, 0
+}
+
+func (m *UserSignInManager) checkCalendarOpen(ctx cd.RpcContext, calendarRow *public_protocol_config.Readonly_ExcelSignInCalendar) int32 {
+	if !calendarRow.GetIsOpen() {
+		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_SIGN_IN_CALENDAR_NOT_OPEN)
+	}
+
+	now := ctx.GetNow().Unix()
+	if now < calendarRow.GetStartTime().GetSeconds() ||
+		(calendarRow.GetEndTime().GetSeconds() > 0 && now >= calendarRow.GetEndTime().GetSeconds()) {
+		return int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_SIGN_IN_CALENDAR_NOT_OPEN)
+	}
+
+	if logic_condition.HasLimitData(calendarRow.GetUnlockCondition()) {
+		rpcResult := m.checkCondition(ctx, calendarRow, calendarRow.GetUnlockCondition())
+		if !rpcResult.IsOK() {
+			return rpcResult.GetResponseCode()
+		}
+	}
+
+	return 0
+}
+
+func (m *UserSignInManager) checkCondition(ctx cd.RpcContext, calendarRow *public_protocol_config.Readonly_ExcelSignInCalendar,
+	limit *public_protocol_common.Readonly_DConditionBasicLimit,
+) cd.RpcResult {
+	conditionMgr := data.UserGetModuleManager[logic_condition.UserConditionManager](m.GetOwner())
+	if conditionMgr == nil {
+		ctx.LogError("UserConditionManager not found", "calendar_id", calendarRow.GetCalendarId())
+		return cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+	}
+
+	return conditionMgr.CheckBasicLimit(ctx, limit, logic_condition.CreateEmptyRuleCheckerRuntime())
+}
+
+// refreshCalendar 按当前时间计算周期，周期切换时重置签到记录
+func (m *UserSignInManager) refreshCalendar(ctx cd.RpcContext, calendarRow *public_protocol_config.Readonly_ExcelSignInCalendar,
+) (*public_protocol_pbdesc.DSignInCalendarData, signInPeriodState) {
+	calendarData, ok := m.calendars[calendarRow.GetCalendarId()]
+	if !ok || calendarData == nil {
+		calendarData = &public_protocol_pbdesc.DSignInCalendarData{
+			CalendarId: calendarRow.GetCalendarId(),
+		}
+		m.calendars[calendarRow.GetCalendarId()] = calendarData
+	}
+
+	now := ctx.GetNow()
+	state := calculateSignInPeriod(calendarRow, logical_time.CalculateDayStart(now), logical_time.GetDayId(now, nil), calendarData.GetPeriodId())
+	if refreshSignInPeriod(calendarData, state) {
+		m.dirtyCalendars[calendarRow.GetCalendarId()] = struct{}{}
+		m.insertDirtyHandle()
+	}
+	return calendarData, state
+}
+
+// checkReward 生成签到奖励，满足 VIP 条件时标记了翻倍的奖励数量翻倍
+func (m *UserSignInManager) checkReward(ctx cd.RpcContext, calendarRow *public_protocol_config.Readonly_ExcelSignInCalendar,
+	day int32,
+) ([]*data.ItemAddGuard, bool, int32) {
+	rewardRow := config.GetConfigManager().GetCurrentConfigGroup().GetExcelSignInRewardByCalendarIdDay(calendarRow.GetCalendarId(), day)
+	if rewardRow == nil {
+		ctx.LogError("sign in reward not found", "calendar_id", calendarRow.GetCalendarId(), "day", day)
+		return nil, false, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_SIGN_IN_REWARD_NOT_FOUND)
+	}
+
+	isVipDouble := false
+	if rewardRow.GetVipDouble() && logic_condition.HasLimitData(calendarRow.GetVipCondition()) {
+		isVipDouble = m.checkCondition(ctx, calendarRow, calendarRow.GetVipCondition()).IsOK()
+	}
+
+	ratio := int64(1)
+	if isVipDouble {
+		ratio = 2
+	}
+	instances, result := m.GetOwner().GenerateMultipleItemInstancesFromCfgOffsetRatio(ctx, rewardRow.GetRewards(), true, ratio)
+	if result.IsError() {
+		result.LogError(ctx, "generate sign in reward item instances failed", "calendar_id", calendarRow.GetCalendarId(), "day", day)
+		return nil, false, result.GetResponseCode()
+	}
+
+	addGuard, result := m.GetOwner().CheckAddItem(ctx, instances)
+	if result.IsError() {
+		result.LogError(ctx, "check add sign in reward items failed", "calendar_id", calendarRow.GetCalendarId(), "day", day)
+		return nil, false, result.GetResponseCode()
+	}
+	return addGuard, isVipDouble, 0
+}
+
+// onSignedIn 签到或补签成功后推送变化、发送流水并更新任务进度
+func (m *UserSignInManager) onSignedIn(ctx cd.RpcContext, calendarData *public_protocol_pbdesc.DSignInCalendarData,
+	day int32, isMakeUp bool, isVipDouble bool, addGuard []*data.ItemAddGuard, costItems []*public_protocol_common.DItemBasic,
+) {
+	m.dirtyCalendars[calendarData.GetCalendarId()] = struct{}{}
+	m.insertDirtyHandle()
+
+	log := private_protocol_log.OperationSupportSystemLog{}
+	flow := log.MutableLog().MutableSignInFlow()
+	flow.CalendarId = calendarData.GetCalendarId()
+	flow.PeriodId = calendarData.GetPeriodId()
+	flow.Day = day
+	flow.IsMakeUp = isMakeUp
+	flow.IsVipDouble = isVipDouble
+	for _, guard := range addGuard {
+		flow.RewardItems = append(flow.RewardItems, guard.Item.GetItemBasic())
+	}
+	flow.CostItems = costItems
+	flow.MakeUpCount = calendarData.GetMakeUpCount()
+	flow.TotalSignInDays = calendarData.GetTotalSignInDays()
+	m.GetOwner().SendUserOssLog(ctx, &log)
+
+	questMgr := data.UserGetModuleManager[logic_quest.UserQuestManager](m.GetOwner())
+	if questMgr == nil {
+		ctx.LogError("can not find user quest manager")
+		return
+	}
+	questMgr.QuestTriggerEvent(ctx, private_protocol_pbdesc.QuestTriggerParams_EnParamID_SignIn,
+		&private_protocol_pbdesc.QuestTriggerParams{
+			Param: &private_protocol_pbdesc.QuestTriggerParams_SignIn{
+				SignIn: &private_protocol_pbdesc.QuestTriggerParamsSignIn{
+					CalendarId: calendarData.GetCalendarId(),
+					Count:      1,
+				},
+			},
+		})
+}
+
+func (m *UserSignInManager) insertDirtyHandle() {
+	m.GetOwner().InsertDirtyHandleIfNotExists(m,
+		func(ctx cd.RpcContext, dirty *data.UserDirtyData) bool {
+			dirtyData := &public_protocol_pbdesc.DUserSignInDirtyChg{}
+			for calendarId := range m.dirtyCalendars {
+				if calendarData, ok := m.calendars[calendarId]; ok {
+					dirtyData.Calendars = append(dirtyData.Calendars, calendarData)
+				}
+			}
+			if len(dirtyData.Calendars) == 0 {
+				return false
+			}
+			dirty.MutableNormalDirtyChangeMessage().DirtySignIn = dirtyData
+			return true
+		},
+		func(ctx cd.RpcContext) {
+			clear(m.dirtyCalendars)
+		},
+	)
+}
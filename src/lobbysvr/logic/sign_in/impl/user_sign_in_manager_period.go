@@ -0,0 +1,76 @@
+package lobbysvr_logic_sign_in_impl
+
+import (
+	"slices"
+	"time"
+
+	public_protocol_common "github.com/atframework/atsf4g-go/component/protocol/public/common/protocol/common"
+	public_protocol_config "github.com/atframework/atsf4g-go/component/protocol/public/config/protocol/config"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+)
+
+// signInPeriodState 按当前时间计算出的签到周期
+type signInPeriodState struct {
+	periodId int64 // 循环签还没有开始新周期时为 0，第一次签到时以当天作为周期第一天
+	today    int32 // 今天是周期内第几天，从 1 开始
+	days     int32 // 本周期的天数
+}
+
+// calculateSignInPeriod dayStart 和 dayId 都按 logical_time 计算，GM 修改服务器时间后同样生效
+func calculateSignInPeriod(calendar *public_protocol_config.Readonly_ExcelSignInCalendar,
+	dayStart time.Time, dayId int64, currentPeriodId int64,
+) signInPeriodState {
+	switch calendar.GetCalendarType() {
+	case public_protocol_common.EnSignInCalendarType_EN_SIGN_IN_CALENDAR_TYPE_MONTHLY:
+		year, month, day := dayStart.Date()
+		return signInPeriodState{
+			periodId: int64(year)*100 + int64(month),
+			today:    int32(day),
+			days:     int32(time.Date(year, month+1, 0, 0, 0, 0, 0, dayStart.Location()).Day()),
+		}
+
+	case public_protocol_common.EnSignInCalendarType_EN_SIGN_IN_CALENDAR_TYPE_CYCLE:
+		cycleDays := calendar.GetCycleDays()
+		if currentPeriodId > 0 && dayId >= currentPeriodId && dayId-currentPeriodId < int64(cycleDays) {
+			return signInPeriodState{
+				periodId: currentPeriodId,
+				today:    int32(dayId-currentPeriodId) + 1,
+				days:     cycleDays,
+			}
+		}
+		return signInPeriodState{
+			today: 1,
+			days:  cycleDays,
+		}
+
+	default:
+		return signInPeriodState{}
+	}
+}
+
+// refreshSignInPeriod 周期切换时重置本周期的签到和补签记录，累计签到天数保留
+func refreshSignInPeriod(calendarData *public_protocol_pbdesc.DSignInCalendarData, state signInPeriodState) bool {
+	if calendarData.GetPeriodId() == state.periodId {
+		return false
+	}
+
+	calendarData.PeriodId = state.periodId
+	calendarData.SignedDays = nil
+	calendarData.MakeUpCount = 0
+	return true
+}
+
+func isSignInDaySigned(calendarData *public_protocol_pbdesc.DSignInCalendarData, day int32) bool {
+	return slices.Contains(calendarData.GetSignedDays(), day)
+}
+
+// markSignInDaySigned 记录签到，循环签的第一次签到作为新周期的第一天
+func markSignInDaySigned(calendarData *public_protocol_pbdesc.DSignInCalendarData, state signInPeriodState, dayId int64, day int32) {
+	if state.periodId == 0 {
+		calendarData.PeriodId = dayId
+	}
+
+	calendarData.SignedDays = append(calendarData.SignedDays, day)
+	slices.Sort(calendarData.SignedDays)
+	calendarData.TotalSignInDays++
+}
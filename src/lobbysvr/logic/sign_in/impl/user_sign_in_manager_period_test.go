@@ -0,0 +1,75 @@
+package lobbysvr_logic_sign_in_impl
+
+import (
+	"testing"
+	"time"
+
+	public_protocol_common "github.com/atframework/atsf4g-go/component/protocol/public/common/protocol/common"
+	public_protocol_config "github.com/atframework/atsf4g-go/component/protocol/public/config/protocol/config"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSignInCalendar(calendarType public_protocol_common.EnSignInCalendarType, cycleDays int32) *public_protocol_config.Readonly_ExcelSignInCalendar {
+	return (&public_protocol_config.ExcelSignInCalendar{
+		CalendarId:   1,
+		CalendarType: calendarType,
+		CycleDays:    cycleDays,
+	}).ToReadonly()
+}
+
+// TestSignInMonthlyPeriod 测试月签周期
+// Scenario: 周期为自然月，今天为当月几号，跨月后重置签到和补签记录但保留累计天数
+func TestSignInMonthlyPeriod(t *testing.T) {
+	// Arrange
+	calendar := newTestSignInCalendar(public_protocol_common.EnSignInCalendarType_EN_SIGN_IN_CALENDAR_TYPE_MONTHLY, 0)
+	calendarData := &public_protocol_pbdesc.DSignInCalendarData{
+		CalendarId:      1,
+		PeriodId:        202601,
+		SignedDays:      []int32{1, 2},
+		MakeUpCount:     1,
+		TotalSignInDays: 2,
+	}
+
+	// Act
+	sameMonth := calculateSignInPeriod(calendar, time.Date(2026, time.January, 31, 0, 0, 0, 0, time.UTC), 0, calendarData.GetPeriodId())
+	nextMonth := calculateSignInPeriod(calendar, time.Date(2026, time.February, 3, 0, 0, 0, 0, time.UTC), 0, calendarData.GetPeriodId())
+
+	// Assert
+	assert.Equal(t, signInPeriodState{periodId: 202601, today: 31, days: 31}, sameMonth)
+	assert.False(t, refreshSignInPeriod(calendarData, sameMonth))
+
+	assert.Equal(t, signInPeriodState{periodId: 202602, today: 3, days: 28}, nextMonth)
+	assert.True(t, refreshSignInPeriod(calendarData, nextMonth))
+	assert.Empty(t, calendarData.GetSignedDays())
+	assert.Equal(t, int32(0), calendarData.GetMakeUpCount())
+	assert.Equal(t, int64(2), calendarData.GetTotalSignInDays())
+}
+
+// TestSignInCyclePeriod 测试循环签周期
+// Scenario: 第一次签到的当天作为周期第一天，超过周期天数后等待下一次签到开始新周期
+func TestSignInCyclePeriod(t *testing.T) {
+	// Arrange
+	calendar := newTestSignInCalendar(public_protocol_common.EnSignInCalendarType_EN_SIGN_IN_CALENDAR_TYPE_CYCLE, 7)
+	calendarData := &public_protocol_pbdesc.DSignInCalendarData{CalendarId: 1}
+	dayStart := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	// Act
+	notStarted := calculateSignInPeriod(calendar, dayStart, 100, calendarData.GetPeriodId())
+	markSignInDaySigned(calendarData, notStarted, 100, notStarted.today)
+	lastDay := calculateSignInPeriod(calendar, dayStart, 106, calendarData.GetPeriodId())
+	expired := calculateSignInPeriod(calendar, dayStart, 107, calendarData.GetPeriodId())
+
+	// Assert
+	assert.Equal(t, signInPeriodState{today: 1, days: 7}, notStarted)
+	assert.Equal(t, int64(100), calendarData.GetPeriodId())
+	assert.True(t, isSignInDaySigned(calendarData, 1))
+
+	assert.Equal(t, signInPeriodState{periodId: 100, today: 7, days: 7}, lastDay)
+	assert.False(t, isSignInDaySigned(calendarData, 7))
+
+	assert.Equal(t, signInPeriodState{today: 1, days: 7}, expired)
+	assert.True(t, refreshSignInPeriod(calendarData, expired))
+	assert.Equal(t, int64(0), calendarData.GetPeriodId())
+	assert.Equal(t, int64(1), calendarData.GetTotalSignInDays())
+}
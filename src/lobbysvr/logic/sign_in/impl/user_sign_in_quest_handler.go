@@ -0,0 +1,55 @@
+package lobbysvr_logic_sign_in_impl
+
+import (
+	"fmt"
+
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_config "github.com/atframework/atsf4g-go/component/protocol/public/config/protocol/config"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
+	logic_quest "github.com/atframework/atsf4g-go/service-lobbysvr/logic/quest"
+	logic_sign_in "github.com/atframework/atsf4g-go/service-lobbysvr/logic/sign_in"
+)
+
+func registerQuestProgressHandlers() {
+	// 累计签到天数，不区分日历时配置为 0，所以不需要按日历建立索引
+	logic_quest.RegisterProgressHandler(public_protocol_config.DQuestConditionProgress_EnProgressParamID_SignInDaysAccumulative,
+		questInitSignInDaysAccumulative,
+		questUpdateSignInDaysAccumulative,
+		nil,
+	)
+}
+
+// 累计签到天数
+func questInitSignInDaysAccumulative(ctx cd.RpcContext,
+	progressCfg *public_protocol_config.Readonly_DQuestConditionProgress,
+	questData *public_protocol_pbdesc.DUserQuestProgressData,
+	user *data.User,
+) cd.RpcResult {
+	userSignInMgr := data.UserGetModuleManager[logic_sign_in.UserSignInManager](user)
+	if userSignInMgr == nil {
+		return cd.CreateRpcResultError(fmt.Errorf("can not get UserSignInManager"), public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+	}
+
+	if questData != nil {
+		questData.Value = userSignInMgr.GetTotalSignInDays(progressCfg.GetSignInDaysAccumulative())
+	}
+
+	return cd.CreateRpcResultOk()
+}
+
+func questUpdateSignInDaysAccumulative(ctx cd.RpcContext,
+	params *private_protocol_pbdesc.QuestTriggerParams,
+	progressCfg *public_protocol_config.Readonly_DQuestConditionProgress,
+	questData *public_protocol_pbdesc.DUserQuestProgressData,
+) cd.RpcResult {
+	if questData == nil {
+		return cd.CreateRpcResultOk()
+	}
+	calendarId := progressCfg.GetSignInDaysAccumulative()
+	if calendarId == 0 || calendarId == params.GetSignIn().GetCalendarId() {
+		questData.Value += int64(params.GetSignIn().GetCount())
+	}
+	return cd.CreateRpcResultOk()
+}
@@ -0,0 +1,23 @@
+package lobbysvr_logic_sign_in
+
+import (
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
+	service_protocol "github.com/atframework/atsf4g-go/service-lobbysvr/protocol/public/protocol/pbdesc"
+)
+
+// UserSignInManager 签到日历，按 logical_time 的天切换周期
+type UserSignInManager interface {
+	data.UserModuleManagerImpl
+
+	GetCalendarData(calendarId int32) *public_protocol_pbdesc.DSignInCalendarData
+	// 累计签到天数，calendarId 为 0 时返回所有日历的总和
+	GetTotalSignInDays(calendarId int32) int64
+
+	// 只返回已开放的日历，周期已经切换的会先重置
+	FetchData(ctx cd.RpcContext, rspBody *service_protocol.SCSignInGetInfoRsp)
+
+	SignIn(ctx cd.RpcContext, calendarId int32, rspBody *service_protocol.SCSignInRsp) int32
+	MakeUp(ctx cd.RpcContext, req *service_protocol.CSSignInMakeUpReq, rspBody *service_protocol.SCSignInMakeUpRsp) int32
+}
@@ -1,9 +1,14 @@
 package lobbysvr_logic_timer_internal
 
 import (
+	"sort"
 	"time"
 
+	config "github.com/atframework/atsf4g-go/component/config"
 	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	uc "github.com/atframework/atsf4g-go/component/user_controller"
 	libatapp "github.com/atframework/libatapp-go"
 
 	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
@@ -25,6 +30,10 @@ type UserTimerManager struct {
 
 	nextTriggerTimestamp int64
 	triggerTimestamps    []int64
+
+	// 持久化定时器，按 (trigger_time, timer_id) 排序
+	timers      []*private_protocol_pbdesc.UserTimer
+	lastTimerId int64
 }
 
 func CreateUserTimerManager(app libatapp.AppImpl, owner *data.User) *UserTimerManager {
@@ -34,25 +43,120 @@ func CreateUserTimerManager(app libatapp.AppImpl, owner *data.User) *UserTimerMa
 	}
 }
 
-// SetTimer 设置/更新用户定时器，传入触发时间戳（Unix 秒）。
+func (m *UserTimerManager) InitFromDB(_ctx cd.RpcContext, _dbUser *private_protocol_pbdesc.DatabaseTableUser) cd.RpcResult {
+	m.timers = append(m.timers[:0], _dbUser.GetTimerData().GetTimers()...)
+	m.lastTimerId = _dbUser.GetTimerData().GetLastTimerId()
+	sort.SliceStable(m.timers, func(i, j int) bool {
+		return lessUserTimer(m.timers[i], m.timers[j])
+	})
+
+	return cd.CreateRpcResultOk()
+}
+
+func (m *UserTimerManager) DumpToDB(_ctx cd.RpcContext, _dbUser *private_protocol_pbdesc.DatabaseTableUser) cd.RpcResult {
+	timerData := _dbUser.MutableTimerData()
+	timerData.Timers = append(make([]*private_protocol_pbdesc.UserTimer, 0, len(m.timers)), m.timers...)
+	timerData.LastTimerId = m.lastTimerId
+
+	return cd.CreateRpcResultOk()
+}
+
+// getUserTimerLimit 配置的数量上限，未配置或超出系统上限时使用系统上限
+func getUserTimerLimit() int {
+	hardLimit := int(private_protocol_pbdesc.EnSystemLimit_EN_SL_USER_TIMER_MAX_COUNT)
+	cfgValue := config.GetConfigManager().GetCurrentConfigGroup().GetCustomIndex().GetConstIndex().GetUserTimerMaxCount()
+	if cfgValue <= 0 || int(cfgValue) > hardLimit {
+		return hardLimit
+	}
+	return int(cfgValue)
+}
+
+func lessUserTimer(l *private_protocol_pbdesc.UserTimer, r *private_protocol_pbdesc.UserTimer) bool {
+	if l.GetTriggerTime() != r.GetTriggerTime() {
+		return l.GetTriggerTime() < r.GetTriggerTime()
+	}
+	return l.GetTimerId() < r.GetTimerId()
+}
+
+// SetTimer 设置用户刷新定时器，传入触发时间戳（逻辑时间 Unix 秒）。
 // 仅当新时间比当前注册时间更早（或当前无 timer）时才会重新创建 time.Timer。
 func (m *UserTimerManager) SetTimer(ctx cd.RpcContext, triggerTimestamp int64) {
 	if triggerTimestamp <= 0 {
 		return
 	}
 
-	owner := m.GetOwner()
-	if owner == nil {
+	if m.GetOwner() == nil {
 		return
 	}
 
-	if triggerTimestamp == m.nextTriggerTimestamp {
+	m.triggerTimestamps = append(m.triggerTimestamps, triggerTimestamp)
+
+	// 插入 但是不更新 timer
+	if m.timer != nil && m.nextTriggerTimestamp != 0 && triggerTimestamp >= m.nextTriggerTimestamp {
 		return
 	}
 
-	// 插入 但是不更新 timer
-	if m.nextTriggerTimestamp != 0 && triggerTimestamp > m.nextTriggerTimestamp {
-		m.triggerTimestamps = append(m.triggerTimestamps, triggerTimestamp)
+	m.startTimer(ctx, triggerTimestamp)
+}
+
+func (m *UserTimerManager) AddTimer(ctx cd.RpcContext, callbackName string, triggerTime int64, param int64, paramStr string) (int64, int32) {
+	if triggerTime <= 0 {
+		return 0, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_INVALID_PARAM)
+	}
+
+	if logic_timer.GetUserTimerCallback(callbackName) == nil {
+		return 0, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_USER_TIMER_CALLBACK_NOT_FOUND)
+	}
+
+	if len(m.timers) >= getUserTimerLimit() {
+		return 0, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_USER_TIMER_COUNT_LIMIT)
+	}
+
+	m.lastTimerId++
+	timer := &private_protocol_pbdesc.UserTimer{
+		TimerId:      m.lastTimerId,
+		CallbackName: callbackName,
+		TriggerTime:  triggerTime,
+		CreateTime:   ctx.GetNow().Unix(),
+		Param:        param,
+		ParamStr:     paramStr,
+	}
+
+	index := sort.Search(len(m.timers), func(i int) bool {
+		return lessUserTimer(timer, m.timers[i])
+	})
+	m.timers = append(m.timers, nil)
+	copy(m.timers[index+1:], m.timers[index:])
+	m.timers[index] = timer
+
+	if m.timer == nil || m.nextTriggerTimestamp == 0 || triggerTime < m.nextTriggerTimestamp {
+		m.startTimer(ctx, triggerTime)
+	}
+
+	ctx.LogDebug("add user timer", "user_id", m.GetOwner().GetUserId(), "timer_id", timer.GetTimerId(),
+		"callback_name", callbackName, "trigger_time", triggerTime)
+	return timer.GetTimerId(), 0
+}
+
+// RemoveTimer 不会重新创建 time.Timer，提前触发时 Tick 会重新计算下一次触发时间
+func (m *UserTimerManager) RemoveTimer(_ctx cd.RpcContext, timerId int64) bool {
+	for i, timer := range m.timers {
+		if timer.GetTimerId() == timerId {
+			m.timers = append(m.timers[:i], m.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (m *UserTimerManager) GetPendingTimers() []*private_protocol_pbdesc.UserTimer {
+	return m.timers
+}
+
+// startTimer 重新创建 time.Timer，触发后在玩家的 actor 上执行 Tick
+func (m *UserTimerManager) startTimer(ctx cd.RpcContext, triggerTimestamp int64) {
+	owner := m.GetOwner()
+	if owner == nil {
 		return
 	}
 
@@ -64,7 +168,8 @@ func (m *UserTimerManager) SetTimer(ctx cd.RpcContext, triggerTimestamp int64) {
 		m.timer = nil
 	}
 
-	delay := time.Until(time.Unix(triggerTimestamp, 0))
+	// 触发时间是逻辑时间，GM 调整过服务器时间时不能直接用系统时间计算
+	delay := time.Unix(triggerTimestamp, 0).Sub(ctx.GetNow())
 	if delay < 0 {
 		delay = 0
 	}
@@ -78,29 +183,49 @@ func (m *UserTimerManager) SetTimer(ctx cd.RpcContext, triggerTimestamp int64) {
 			if timerMgr == nil {
 				return cd.CreateRpcResultOk()
 			}
-			timerMgr.Tick(ctx)
+			timerMgr.Tick(childCtx)
 			return cd.CreateRpcResultOk()
 		})
 	})
 }
 
-// cancelTimer 停止并清除用户定时器
-func (m *UserTimerManager) cancelTimer() {
+// startNextTimer 按刷新定时器和持久化定时器中最早的时间重新创建 time.Timer
+func (m *UserTimerManager) startNextTimer(ctx cd.RpcContext) {
+	var next int64
+	for _, ts := range m.triggerTimestamps {
+		if next == 0 || ts < next {
+			next = ts
+		}
+	}
+	if len(m.timers) > 0 && (next == 0 || m.timers[0].GetTriggerTime() < next) {
+		next = m.timers[0].GetTriggerTime()
+	}
+
+	if next == 0 {
+		m.stopTimer()
+		return
+	}
+	m.startTimer(ctx, next)
+}
+
+// stopTimer 只停止 time.Timer，不清除定时器数据
+func (m *UserTimerManager) stopTimer() {
 	m.nextTriggerTimestamp = 0
-	m.triggerTimestamps = m.triggerTimestamps[:0]
 	if m.timer != nil {
 		m.timer.Stop()
 		m.timer = nil
 	}
 }
 
-func (m *UserTimerManager) Tick(ctx cd.RpcContext) {
-	// timer 已触发，清除记录
-	m.timer = nil
+// cancelTimer 停止并清除用户刷新定时器，持久化定时器保留到下次登录
+func (m *UserTimerManager) cancelTimer() {
+	m.triggerTimestamps = m.triggerTimestamps[:0]
+	m.stopTimer()
+}
 
-	if m.nextTriggerTimestamp <= 0 {
-		return
-	}
+func (m *UserTimerManager) Tick(ctx cd.RpcContext) {
+	// 旧的 time.Timer 可能已经触发过，统一停止后重新计算
+	m.stopTimer()
 
 	owner := m.GetOwner()
 	if owner == nil {
@@ -108,27 +233,60 @@ func (m *UserTimerManager) Tick(ctx cd.RpcContext) {
 	}
 
 	now := ctx.GetNow()
-	if now.Unix() < m.nextTriggerTimestamp {
-		m.SetTimer(ctx, m.nextTriggerTimestamp)
-		return
-	}
-
-	m.nextTriggerTimestamp = 0
 
 	// 遍历定时器 清除过期定时器
 	validTimestamps := m.triggerTimestamps[:0]
 	for _, ts := range m.triggerTimestamps {
 		if ts > now.Unix() {
 			validTimestamps = append(validTimestamps, ts)
-			if m.nextTriggerTimestamp == 0 || ts < m.nextTriggerTimestamp {
-				m.nextTriggerTimestamp = ts
-			}
 		}
 	}
 	m.triggerTimestamps = validTimestamps
 
 	// 调用 User.RefreshLimit 触发所有模块的 RefreshLimit / RefreshLimitSecond / RefreshLimitMinute
 	owner.RefreshLimit(ctx, now)
+
+	// 不在 CS 请求中触发，需要主动标记保存
+	if m.fireExpiredTimers(ctx, now.Unix()) > 0 {
+		if userManager := libatapp.AtappGetModule[*uc.UserManager](ctx.GetApp()); userManager != nil {
+			userManager.MarkFastSave(ctx, owner)
+		}
+	}
+	m.startNextTimer(ctx)
+}
+
+// fireExpiredTimers 按顺序触发到期的持久化定时器，返回移除的定时器数量，回调中新加的定时器即使已经到期也留到下一次 Tick
+// 定时器的移除和回调的修改一起保存，保存前进程崩溃时登录后会再次触发，回调需要可以重入
+func (m *UserTimerManager) fireExpiredTimers(ctx cd.RpcContext, now int64) int {
+	expiredCount := sort.Search(len(m.timers), func(i int) bool {
+		return m.timers[i].GetTriggerTime() > now
+	})
+	if expiredCount == 0 {
+		return 0
+	}
+
+	expired := append([]*private_protocol_pbdesc.UserTimer(nil), m.timers[:expiredCount]...)
+	m.timers = append(m.timers[:0], m.timers[expiredCount:]...)
+
+	owner := m.GetOwner()
+	for _, timer := range expired {
+		callback := logic_timer.GetUserTimerCallback(timer.GetCallbackName())
+		if callback == nil {
+			ctx.LogError("user timer callback not found, drop it", "user_id", owner.GetUserId(), "timer_id", timer.GetTimerId(),
+				"callback_name", timer.GetCallbackName(), "trigger_time", timer.GetTriggerTime())
+			continue
+		}
+
+		ctx.LogDebug("fire user timer", "user_id", owner.GetUserId(), "timer_id", timer.GetTimerId(),
+			"callback_name", timer.GetCallbackName(), "trigger_time", timer.GetTriggerTime(), "delay", now-timer.GetTriggerTime())
+		callback(ctx, owner, timer)
+	}
+	return expiredCount
+}
+
+// OnLogin 离线期间到期的定时器在登录流程结束后的第一次 Tick 中触发，此时所有模块都已经初始化完成
+func (m *UserTimerManager) OnLogin(ctx cd.RpcContext) {
+	m.startNextTimer(ctx)
 }
 
 // OnLogout 用户下线时取消定时器
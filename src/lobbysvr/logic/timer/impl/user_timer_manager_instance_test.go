@@ -0,0 +1,145 @@
+package lobbysvr_logic_timer_internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	test_utility "github.com/atframework/atsf4g-go/component/test_utility"
+
+	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
+	logic_timer "github.com/atframework/atsf4g-go/service-lobbysvr/logic/timer"
+)
+
+const (
+	testTimerZoneId       uint32 = 1
+	testTimerUserId       uint64 = 10001
+	testTimerCallbackName        = "test_user_timer"
+)
+
+var testTimerNow = time.Unix(1700000000, 0)
+
+// testFiredTimerIds 测试回调按触发顺序记录的定时器 ID
+var testFiredTimerIds []int64
+
+func init() {
+	logic_timer.RegisterUserTimerCallback(testTimerCallbackName, func(_ cd.RpcContext, _ *data.User, timer *private_protocol_pbdesc.UserTimer) {
+		testFiredTimerIds = append(testFiredTimerIds, timer.GetTimerId())
+	})
+}
+
+func createTestTimerManager(t *testing.T, ctx *test_utility.MockRpcContext, dbUser *private_protocol_pbdesc.DatabaseTableUser) *UserTimerManager {
+	user, result := data.CreateUserForTest(ctx, testTimerZoneId, testTimerUserId, dbUser)
+	assert.False(t, result.IsError())
+	mgr, ok := data.UserGetModuleManager[logic_timer.UserTimerManager](user).(*UserTimerManager)
+	assert.True(t, ok)
+	t.Cleanup(func() {
+		mgr.OnLogout(ctx)
+	})
+	return mgr
+}
+
+func getTestPendingTimerIds(mgr *UserTimerManager) []int64 {
+	ret := make([]int64, 0, len(mgr.GetPendingTimers()))
+	for _, timer := range mgr.GetPendingTimers() {
+		ret = append(ret, timer.GetTimerId())
+	}
+	return ret
+}
+
+// TestUserTimerOrderedInsertion 测试持久化定时器按触发顺序插入
+// Scenario: 乱序添加定时器后按 (trigger_time, timer_id) 排列，删除后保持顺序，回调不存在或时间无效时添加失败
+func TestUserTimerOrderedInsertion(t *testing.T) {
+	// Arrange
+	app, _ := test_utility.CreateMemoryStorageApp()
+	ctx := test_utility.NewMockRpcContextWithNow(app, testTimerNow)
+	mgr := createTestTimerManager(t, ctx, nil)
+	now := testTimerNow.Unix()
+
+	// Act
+	id1, code1 := mgr.AddTimer(ctx, testTimerCallbackName, now+300, 1, "")
+	id2, code2 := mgr.AddTimer(ctx, testTimerCallbackName, now+100, 2, "")
+	id3, code3 := mgr.AddTimer(ctx, testTimerCallbackName, now+200, 3, "")
+	id4, code4 := mgr.AddTimer(ctx, testTimerCallbackName, now+100, 4, "")
+	_, notFoundCode := mgr.AddTimer(ctx, "not_exists_callback", now+100, 0, "")
+	_, invalidCode := mgr.AddTimer(ctx, testTimerCallbackName, 0, 0, "")
+
+	// Assert
+	assert.Equal(t, []int32{0, 0, 0, 0}, []int32{code1, code2, code3, code4})
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_USER_TIMER_CALLBACK_NOT_FOUND), notFoundCode)
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_INVALID_PARAM), invalidCode)
+	assert.Equal(t, []int64{id2, id4, id3, id1}, getTestPendingTimerIds(mgr))
+	assert.Equal(t, now+100, mgr.nextTriggerTimestamp)
+
+	assert.True(t, mgr.RemoveTimer(ctx, id4))
+	assert.False(t, mgr.RemoveTimer(ctx, id4))
+	assert.Equal(t, []int64{id2, id3, id1}, getTestPendingTimerIds(mgr))
+}
+
+// TestUserTimerReplayAfterLogin 测试离线期间到期的定时器在登录后触发
+// Scenario: 定时器随玩家数据保存，重新登录后第一次 Tick 按顺序触发离线期间到期的定时器，未到期的保留，已经触发的不会重复触发
+func TestUserTimerReplayAfterLogin(t *testing.T) {
+	// Arrange
+	app, _ := test_utility.CreateMemoryStorageApp()
+	ctx := test_utility.NewMockRpcContextWithNow(app, testTimerNow)
+	mgr := createTestTimerManager(t, ctx, nil)
+	now := testTimerNow.Unix()
+
+	late, _ := mgr.AddTimer(ctx, testTimerCallbackName, now+120, 0, "")
+	early, _ := mgr.AddTimer(ctx, testTimerCallbackName, now+60, 0, "")
+	pending, _ := mgr.AddTimer(ctx, testTimerCallbackName, now+3600, 0, "")
+
+	dbUser := &private_protocol_pbdesc.DatabaseTableUser{}
+	assert.False(t, mgr.DumpToDB(ctx, dbUser).IsError())
+	mgr.OnLogout(ctx)
+	testFiredTimerIds = nil
+
+	// Act: 十分钟后重新登录，OnLogin 创建的 time.Timer 会异步执行 Tick，这里直接调用
+	loginCtx := test_utility.NewMockRpcContextWithNow(app, testTimerNow.Add(10*time.Minute))
+	relogin := createTestTimerManager(t, loginCtx, dbUser)
+	relogin.Tick(loginCtx)
+	relogin.Tick(loginCtx)
+
+	// Assert
+	assert.Equal(t, []int64{early, late}, testFiredTimerIds)
+	assert.Equal(t, []int64{pending}, getTestPendingTimerIds(relogin))
+
+	// Assert: 新分配的 ID 不会和保存前的重复
+	next, resultCode := relogin.AddTimer(loginCtx, testTimerCallbackName, now+7200, 0, "")
+	assert.Equal(t, int32(0), resultCode)
+	assert.Greater(t, next, pending)
+}
+
+// TestUserTimerCountLimit 测试定时器数量上限
+// Scenario: 未配置数量上限时使用系统上限，达到上限后添加失败，删除一个后可以再次添加
+func TestUserTimerCountLimit(t *testing.T) {
+	// Arrange
+	app, _ := test_utility.CreateMemoryStorageApp()
+	ctx := test_utility.NewMockRpcContextWithNow(app, testTimerNow)
+	mgr := createTestTimerManager(t, ctx, nil)
+	now := testTimerNow.Unix()
+	limit := int(private_protocol_pbdesc.EnSystemLimit_EN_SL_USER_TIMER_MAX_COUNT)
+
+	var firstId int64
+	for i := 0; i < limit; i++ {
+		timerId, resultCode := mgr.AddTimer(ctx, testTimerCallbackName, now+int64(i+1)*60, 0, "")
+		assert.Equal(t, int32(0), resultCode)
+		if i == 0 {
+			firstId = timerId
+		}
+	}
+
+	// Act
+	_, limitCode := mgr.AddTimer(ctx, testTimerCallbackName, now+60, 0, "")
+	assert.True(t, mgr.RemoveTimer(ctx, firstId))
+	_, retryCode := mgr.AddTimer(ctx, testTimerCallbackName, now+60, 0, "")
+
+	// Assert
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_USER_TIMER_COUNT_LIMIT), limitCode)
+	assert.Equal(t, int32(0), retryCode)
+	assert.Len(t, mgr.GetPendingTimers(), limit)
+}
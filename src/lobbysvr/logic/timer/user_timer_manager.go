@@ -1,13 +1,48 @@
 package lobbysvr_logic_timer
 
 import (
+	"fmt"
+
 	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
 	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
 )
 
 type UserTimerManager interface {
 	data.UserModuleManagerImpl
 
+	// SetTimer 到时间后触发一次 User.RefreshLimit，不保存，下线后丢弃
 	SetTimer(ctx cd.RpcContext, triggerTimestamp int64)
 	Tick(ctx cd.RpcContext)
+
+	// AddTimer 添加持久化定时器，triggerTime 为逻辑时间，返回定时器 ID
+	// 离线期间到期的定时器在下次登录后按 (trigger_time, timer_id) 的顺序触发
+	AddTimer(ctx cd.RpcContext, callbackName string, triggerTime int64, param int64, paramStr string) (int64, int32)
+	RemoveTimer(ctx cd.RpcContext, timerId int64) bool
+	// GetPendingTimers 按触发顺序排列，调用方不能修改
+	GetPendingTimers() []*private_protocol_pbdesc.UserTimer
+}
+
+// UserTimerCallback 触发前定时器已经被移除，回调里可以重新添加定时器
+// 定时器的移除和回调的修改一起保存，保存前进程崩溃时登录后会再次触发，回调需要可以重入
+type UserTimerCallback func(ctx cd.RpcContext, owner *data.User, timer *private_protocol_pbdesc.UserTimer)
+
+var userTimerCallbacks = map[string]UserTimerCallback{}
+
+// RegisterUserTimerCallback 注册定时器回调，只能在 init 中调用
+// 名字会保存在玩家数据里，上线后不能修改，删除回调时未触发的定时器会被丢弃
+func RegisterUserTimerCallback(name string, callback UserTimerCallback) {
+	if name == "" || callback == nil {
+		panic(fmt.Sprintf("user timer callback %q has no name or callback", name))
+	}
+
+	if _, ok := userTimerCallbacks[name]; ok {
+		panic(fmt.Sprintf("user timer callback %q already registered", name))
+	}
+
+	userTimerCallbacks[name] = callback
+}
+
+func GetUserTimerCallback(name string) UserTimerCallback {
+	return userTimerCallbacks[name]
 }
@@ -23,6 +23,8 @@ import (
 	router "github.com/atframework/atsf4g-go/component/router"
 	uc "github.com/atframework/atsf4g-go/component/user_controller"
 	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
+	logic_account_lifecycle "github.com/atframework/atsf4g-go/service-lobbysvr/logic/account_lifecycle"
+	logic_user "github.com/atframework/atsf4g-go/service-lobbysvr/logic/user"
 
 	logic_open_platform "github.com/atframework/atsf4g-go/service-lobbysvr/logic/open_platform"
 )
@@ -63,8 +65,15 @@ func (t *TaskActionLogin) Run(_startData *cd.DispatcherStartData) error {
 	}
 	csSession.SetUnflushActorLogName(request_body.GetOpenId())
 
-	// 登入鉴权
-	authTable, rpcResult := db.DatabaseTableAccessLoadWithOpenId(t.GetAwaitableContext(), request_body.GetOpenId())
+	// 登入鉴权，登入锁和 access 表一起读取，本地没有玩家缓存时踢线检查直接使用
+	loadPipeline := db.CreatePipeline(t.GetAwaitableContext(), false)
+	authResult := db.DatabaseTableAccessPipelineLoadWithOpenId(loadPipeline, request_body.GetOpenId())
+	loginLockResult := db.DatabaseTableLoginLockPipelineLoadWithUserId(loadPipeline, request_body.GetUserId())
+	rpcResult := loadPipeline.Execute()
+	if rpcResult.IsOK() {
+		rpcResult = authResult.Result
+	}
+	authTable := authResult.Table
 	if rpcResult.IsError() {
 		if rpcResult.GetResponseCode() == int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND) {
 			t.SetResponseError(public_protocol_pbdesc.EnErrorCode_EN_ERR_LOGIN_OPENID_NOT_FOUND)
@@ -94,7 +103,24 @@ func (t *TaskActionLogin) Run(_startData *cd.DispatcherStartData) error {
 		return nil
 	}
 
-	if request_body.GetUserId() != authTable.GetUserId() || request_body.GetZoneId() != authTable.GetZoneId() {
+	// 合服后客户端可能仍然使用原大区ID登入，两个大区都由本进程服务时直接以 access 表中的大区为准
+	isMergedZone := request_body.GetUserId() == authTable.GetUserId() && request_body.GetZoneId() != authTable.GetZoneId() &&
+		config.GetConfigManager().IsServedZoneId(request_body.GetZoneId()) &&
+		config.GetConfigManager().IsServedZoneId(authTable.GetZoneId())
+	if !isMergedZone && (request_body.GetUserId() != authTable.GetUserId() || request_body.GetZoneId() != authTable.GetZoneId()) {
+		// 角色已转区，告知客户端新的大区
+		if request_body.GetUserId() == authTable.GetUserId() {
+			redirect, result := logic_account_lifecycle.LoadUserZoneRedirect(t.GetAwaitableContext(), request_body.GetZoneId(), request_body.GetUserId())
+			if result.IsOK() && redirect != nil {
+				t.MutableResponseBody().RedirectZoneId = redirect.GetTargetZoneId()
+				t.SetResponseError(public_protocol_pbdesc.EnErrorCode_EN_ERR_LOGIN_ZONE_TRANSFERRED)
+				t.GetRpcContext().LogInfo("user has been transferred to other zone",
+					"open_id", request_body.GetOpenId(), "user_id", request_body.GetUserId(),
+					"zone_id", request_body.GetZoneId(), "target_zone_id", redirect.GetTargetZoneId())
+				return nil
+			}
+		}
+
 		t.SetResponseError(public_protocol_pbdesc.EnErrorCode_EN_ERR_LOGIN_AUTHORIZE)
 		t.GetRpcContext().LogWarn("invalid user id or zone id",
 			"open_id", request_body.GetOpenId(),
@@ -128,7 +154,7 @@ func (t *TaskActionLogin) Run(_startData *cd.DispatcherStartData) error {
 		platformUserKey, accountData, accessData)
 
 	userId := request_body.GetUserId()
-	zoneId := request_body.GetZoneId()
+	zoneId := authTable.GetZoneId()
 
 	{
 		// 开始尝试登录
@@ -199,11 +225,18 @@ func (t *TaskActionLogin) Run(_startData *cd.DispatcherStartData) error {
 		operation_support_system.SendOssLog(t.GetRpcContext().GetApp(), &log)
 	}
 
+	// 有缓存时 Remove 可能会存盘并修改登入锁，这时候不能使用一起读取的登入锁
+	// 没有缓存时读取之后登入锁被其他进程修改的话，后面写入登入锁时 CAS 检查会失败
+	var preloadLoginLock *db.DatabaseTableLoginLockBatchGetResult
+	if user == nil {
+		preloadLoginLock = loginLockResult
+	}
+
 	// 如果有缓存要强制失效，因为可能其他地方登入了，这时候也不能复用缓存
 	libatapp.AtappGetModule[*uc.UserManager](t.GetAwaitableContext().GetApp()).Remove(t.GetAwaitableContext(), zoneId, userId, nil, true)
 	t.GetRpcContext().LogDebug("Try Remove User Success", "zoneId", zoneId, "userId", userId)
 
-	loginTb, loginCASVersion, result := t.kickoffOtherSession(t.GetAwaitableContext(), zoneId, userId)
+	loginTb, loginCASVersion, result := t.kickoffOtherSession(t.GetAwaitableContext(), zoneId, userId, preloadLoginLock)
 	if result.IsError() {
 		if result.GetResponseCode() == int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_NOT_FOUND) {
 			// 初次全区服登录
@@ -318,6 +351,11 @@ func (t *TaskActionLogin) Run(_startData *cd.DispatcherStartData) error {
 	// 登入初始化
 	user.LoginInit(t.GetRpcContext())
 
+	// 合服重名补偿，失败不影响登入，下次登入重试
+	if basicMgr := data.UserGetModuleManager[logic_user.UserBasicManager](user); basicMgr != nil {
+		basicMgr.SendZoneMergeCompensation(t.GetAwaitableContext())
+	}
+
 	return nil
 }
 
@@ -386,8 +424,14 @@ func (t *TaskActionLogin) awaitLogoutTask(ctx cd.AwaitableContext, user *data.Us
 	return cd.CreateRpcResultOk()
 }
 
-func (t *TaskActionLogin) kickoffOtherSession(ctx cd.AwaitableContext, zoneId uint32, userId uint64) (table *private_protocol_pbdesc.DatabaseTableLoginLock, CASVersion uint64, retResult cd.RpcResult) {
-	table, CASVersion, retResult = db.DatabaseTableLoginLockLoadWithUserId(ctx, userId)
+func (t *TaskActionLogin) kickoffOtherSession(ctx cd.AwaitableContext, zoneId uint32, userId uint64,
+	preloadLoginLock *db.DatabaseTableLoginLockBatchGetResult,
+) (table *private_protocol_pbdesc.DatabaseTableLoginLock, CASVersion uint64, retResult cd.RpcResult) {
+	if preloadLoginLock != nil {
+		table, CASVersion, retResult = preloadLoginLock.Table, preloadLoginLock.CASVersion, preloadLoginLock.Result
+	} else {
+		table, CASVersion, retResult = db.DatabaseTableLoginLockLoadWithUserId(ctx, userId)
+	}
 	if retResult.IsError() {
 		return
 	}
@@ -23,6 +23,7 @@ import (
 	user_controller "github.com/atframework/atsf4g-go/component/user_controller"
 
 	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
+	logic_account_lifecycle "github.com/atframework/atsf4g-go/service-lobbysvr/logic/account_lifecycle"
 	user_auth "github.com/atframework/atsf4g-go/service-lobbysvr/logic/user/auth"
 	service_protocol "github.com/atframework/atsf4g-go/service-lobbysvr/protocol/public/protocol/pbdesc"
 
@@ -100,6 +101,18 @@ func (t *TaskActionLoginAuth) Run(_startData *component_dispatcher.DispatcherSta
 		return nil
 	}
 
+	// 已注销的账号不允许再登入
+	result = logic_account_lifecycle.CheckAccountLoginAllowed(t.GetAwaitableContext(), request_body.GetOpenId())
+	if result.IsError() {
+		if result.GetResponseCode() == int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_ACCOUNT_DELETED) {
+			t.SetResponseError(public_protocol_pbdesc.EnErrorCode_EN_ERR_ACCOUNT_DELETED)
+			return nil
+		}
+		result.LogError(t.GetRpcContext(), "load account deletion table failed", "open_id", request_body.GetOpenId())
+		t.SetResponseError(public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+		return nil
+	}
+
 	// 新用户
 	if authTable == nil {
 		authTable = &pbdesc.DatabaseTableAccess{
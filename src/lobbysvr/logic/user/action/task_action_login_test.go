@@ -0,0 +1,125 @@
+package lobbysvr_logic_user_action
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	libatapp "github.com/atframework/libatapp-go"
+
+	db "github.com/atframework/atsf4g-go/component/db"
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	router "github.com/atframework/atsf4g-go/component/router"
+	test_utility "github.com/atframework/atsf4g-go/component/test_utility"
+	uc "github.com/atframework/atsf4g-go/component/user_controller"
+	logic_account_lifecycle "github.com/atframework/atsf4g-go/service-lobbysvr/logic/account_lifecycle"
+	service_protocol "github.com/atframework/atsf4g-go/service-lobbysvr/protocol/public/protocol/pbdesc"
+)
+
+const (
+	testLoginSourceZoneId uint32 = 1
+	testLoginTargetZoneId uint32 = 2
+	testLoginUserId       uint64 = 40001
+	testLoginOpenId              = "login-open-id"
+	testLoginCode                = "login-code"
+)
+
+func createLoginTestAction(ctx *test_utility.MockRpcContext, request *service_protocol.CSLoginReq) *TaskActionLogin {
+	action := &TaskActionLogin{
+		TaskActionCSBase: uc.CreateCSTaskActionBaseWithUser(nil, request, func() *service_protocol.SCLoginRsp {
+			return &service_protocol.SCLoginRsp{}
+		}),
+	}
+	action.SetSession(uc.CreateSession(uc.CreateSessionKey(1, 1), nil))
+	action.PrepareHookRun(&cd.DispatcherStartData{MessageRpcContext: ctx})
+	return action
+}
+
+// TestTaskActionLoginAfterZoneTransfer 测试转区后登入
+// Scenario: 转区后使用原大区和原登入码登入返回 EN_ERR_LOGIN_ZONE_TRANSFERRED 和新的大区ID，而不是鉴权失败
+func TestTaskActionLoginAfterZoneTransfer(t *testing.T) {
+	// Arrange
+	app, _ := test_utility.CreateMemoryStorageApp()
+	libatapp.AtappAddModule(app, router.CreateRouterManagerSet(app))
+	libatapp.AtappAddModule(app, uc.CreateUserManager(app))
+	uc.InitUserRouterManager(app)
+	ctx := test_utility.NewMockRpcContextWithNow(app, time.Unix(1700000000, 0))
+
+	var casVersion uint64
+	assert.False(t, db.DatabaseTableUserReplaceZoneIdUserId(ctx, &private_protocol_pbdesc.DatabaseTableUser{
+		ZoneId: testLoginSourceZoneId,
+		UserId: testLoginUserId,
+		OpenId: testLoginOpenId,
+	}, &casVersion, false).IsError())
+	assert.False(t, db.DatabaseTableAccessUpdateOpenId(ctx, &private_protocol_pbdesc.DatabaseTableAccess{
+		OpenId:    testLoginOpenId,
+		UserId:    testLoginUserId,
+		ZoneId:    testLoginSourceZoneId,
+		LoginCode: testLoginCode,
+	}).IsError())
+	transferResult := logic_account_lifecycle.TransferUserZone(ctx, testLoginSourceZoneId, testLoginUserId, testLoginTargetZoneId, "gm", "test")
+	assert.False(t, transferResult.IsError())
+
+	action := createLoginTestAction(ctx, &service_protocol.CSLoginReq{
+		OpenId:    testLoginOpenId,
+		ZoneId:    testLoginSourceZoneId,
+		UserId:    testLoginUserId,
+		LoginCode: testLoginCode,
+	})
+
+	// Act
+	err := action.Run(nil)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_LOGIN_ZONE_TRANSFERRED), action.GetResponseCode())
+	assert.Equal(t, testLoginTargetZoneId, action.MutableResponseBody().GetRedirectZoneId())
+
+	accessTb, accessResult := db.DatabaseTableAccessLoadWithOpenId(ctx, testLoginOpenId)
+	assert.False(t, accessResult.IsError())
+	assert.Equal(t, testLoginTargetZoneId, accessTb.GetZoneId())
+	assert.Equal(t, testLoginCode, accessTb.GetLoginCode())
+}
+
+// TestTaskActionLoginInvalidCodeAfterZoneTransfer 测试转区后使用错误的登入码
+// Scenario: 登入码不匹配时仍然返回 EN_ERR_LOGIN_AUTHORIZE，不泄露跳转信息
+func TestTaskActionLoginInvalidCodeAfterZoneTransfer(t *testing.T) {
+	// Arrange
+	app, _ := test_utility.CreateMemoryStorageApp()
+	libatapp.AtappAddModule(app, router.CreateRouterManagerSet(app))
+	libatapp.AtappAddModule(app, uc.CreateUserManager(app))
+	uc.InitUserRouterManager(app)
+	ctx := test_utility.NewMockRpcContextWithNow(app, time.Unix(1700000000, 0))
+
+	var casVersion uint64
+	db.DatabaseTableUserReplaceZoneIdUserId(ctx, &private_protocol_pbdesc.DatabaseTableUser{
+		ZoneId: testLoginSourceZoneId,
+		UserId: testLoginUserId,
+		OpenId: testLoginOpenId,
+	}, &casVersion, false)
+	db.DatabaseTableAccessUpdateOpenId(ctx, &private_protocol_pbdesc.DatabaseTableAccess{
+		OpenId:    testLoginOpenId,
+		UserId:    testLoginUserId,
+		ZoneId:    testLoginSourceZoneId,
+		LoginCode: testLoginCode,
+	})
+	logic_account_lifecycle.TransferUserZone(ctx, testLoginSourceZoneId, testLoginUserId, testLoginTargetZoneId, "gm", "test")
+
+	action := createLoginTestAction(ctx, &service_protocol.CSLoginReq{
+		OpenId:    testLoginOpenId,
+		ZoneId:    testLoginSourceZoneId,
+		UserId:    testLoginUserId,
+		LoginCode: "invalid-code",
+	})
+
+	// Act
+	err := action.Run(nil)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_LOGIN_AUTHORIZE), action.GetResponseCode())
+	assert.Equal(t, uint32(0), action.MutableResponseBody().GetRedirectZoneId())
+}
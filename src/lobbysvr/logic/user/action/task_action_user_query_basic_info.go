@@ -70,6 +70,8 @@ func (t *TaskActionUserQueryBasicInfo) Run(_startData *component_dispatcher.Disp
 
 			PlatformNickName: profile.GetPlatformNickName(),
 			PlatformAvatar:   profile.GetPlatformAvatar(),
+
+			AchievementShowcase: loaded.Table.GetAchievementShowcase(),
 		}
 	}
 
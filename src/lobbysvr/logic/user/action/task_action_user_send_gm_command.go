@@ -25,6 +25,7 @@ import (
 	uc "github.com/atframework/atsf4g-go/component/user_controller"
 	user_controller "github.com/atframework/atsf4g-go/component/user_controller"
 	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
+	logic_account_lifecycle "github.com/atframework/atsf4g-go/service-lobbysvr/logic/account_lifecycle"
 	service_protocol "github.com/atframework/atsf4g-go/service-lobbysvr/protocol/public/protocol/pbdesc"
 	libatapp "github.com/atframework/libatapp-go"
 
@@ -33,9 +34,11 @@ import (
 
 	logical_time "github.com/atframework/atsf4g-go/component/logical_time"
 	mail_component "github.com/atframework/atsf4g-go/component/mail"
+	logic_condition "github.com/atframework/atsf4g-go/service-lobbysvr/logic/condition"
 	logic_mail "github.com/atframework/atsf4g-go/service-lobbysvr/logic/mail"
 	logic_module_unlock "github.com/atframework/atsf4g-go/service-lobbysvr/logic/module_unlock"
 	logic_quest "github.com/atframework/atsf4g-go/service-lobbysvr/logic/quest"
+	logic_timer "github.com/atframework/atsf4g-go/service-lobbysvr/logic/timer"
 	logic_user "github.com/atframework/atsf4g-go/service-lobbysvr/logic/user"
 	user_auth "github.com/atframework/atsf4g-go/service-lobbysvr/logic/user/auth"
 )
@@ -134,19 +137,28 @@ func buildCommandCallbacks() map[string]*gmCommandHandle {
 	registerGmCommandHandle(callbacks, "unlock-all-modules", "", "unlock all modules", (*TaskActionUserSendGmCommand).runGMCmdUnlockAllModules)
 	registerGmCommandHandle(callbacks, "unlock-module", "<module_id>", "unlock special module", (*TaskActionUserSendGmCommand).runGMCmdUnlockModule)
 	registerGmCommandHandle(callbacks, "query-module-status", "<module_id>", "query special module", (*TaskActionUserSendGmCommand).runGMCmdQueryModuleStatus)
-	registerGmCommandHandle(callbacks, "del-account", "", "delete account [user_id]", (*TaskActionUserSendGmCommand).runGMCmdDelAccount)
+	registerGmCommandHandle(callbacks, "del-account", "", "delete account and all related data immediately [user_id]", (*TaskActionUserSendGmCommand).runGMCmdDelAccount)
+	registerGmCommandHandle(callbacks, "account-export", "[user_id]", "Export account data to export_path", (*TaskActionUserSendGmCommand).runGMCmdAccountExport)
+	registerGmCommandHandle(callbacks, "account-delete", "[user_id] [reason]", "Request account deletion after grace period", (*TaskActionUserSendGmCommand).runGMCmdAccountDelete)
+	registerGmCommandHandle(callbacks, "account-delete-cancel", "[user_id]", "Cancel pending account deletion", (*TaskActionUserSendGmCommand).runGMCmdAccountDeleteCancel)
+	registerGmCommandHandle(callbacks, "zone-transfer", "<target_zone_id> [user_id] [reason]", "Transfer user to another zone, user will be kicked off", (*TaskActionUserSendGmCommand).runGMCmdZoneTransfer)
+	registerGmCommandHandle(callbacks, "snapshot-list", "[user_id]", "List user data snapshots", (*TaskActionUserSendGmCommand).runGMCmdSnapshotList)
+	registerGmCommandHandle(callbacks, "snapshot-create", "[user_id]", "Create user data snapshot", (*TaskActionUserSendGmCommand).runGMCmdSnapshotCreate)
+	registerGmCommandHandle(callbacks, "snapshot-diff", "<index> [user_id]", "Diff user data snapshot with current data", (*TaskActionUserSendGmCommand).runGMCmdSnapshotDiff)
+	registerGmCommandHandle(callbacks, "snapshot-restore", "<index> [user_id]", "Restore user data to snapshot, user will be kicked off", (*TaskActionUserSendGmCommand).runGMCmdSnapshotRestore)
 	registerGmCommandHandle(callbacks, "copy-account", "<new_account_id>", "copy account", (*TaskActionUserSendGmCommand).runGMCmdCopyAccount)
 	registerGmCommandHandle(callbacks, "enable-random-delay", "", "Enable random delay", (*TaskActionUserSendGmCommand).runGMCmdEnableRandomDelay)
 	registerGmCommandHandle(callbacks, "disable-random-delay", "", "Disable random delay", (*TaskActionUserSendGmCommand).runGMCmdDisableRandomDelay)
 	registerGmCommandHandle(callbacks, "send-user-mail", "", "Send user mail", (*TaskActionUserSendGmCommand).runGMCmdSendUserMail)
 	registerGmCommandHandle(callbacks, "send-global-mail", "", "Send global mail", (*TaskActionUserSendGmCommand).runGMCmdSendGlobalMail)
 	registerGmCommandHandle(callbacks, "send-user-mail-custom", "<major_type> <title> <content> <user_id> [delivery_time_unix=<unix>] [expired_time_unix=<unix>] [items=typeId1,count1;typeId2,count2;...]", "Send user mail with custom title/content/attachments", (*TaskActionUserSendGmCommand).runGMCmdSendUserMailCustom)
-	registerGmCommandHandle(callbacks, "send-global-mail-custom", "<major_type> <title> <content>  [delivery_time_unix=<unix>] [expired_time_unix=<unix>] [items=typeId1,count1;typeId2,count2;...]", "Send global mail with custom title/content/attachments", (*TaskActionUserSendGmCommand).runGMCmdSendGlobalMailCustom)
+	registerGmCommandHandle(callbacks, "send-global-mail-custom", "<major_type> <title> <content>  [delivery_time_unix=<unix>] [expired_time_unix=<unix>] [items=typeId1,count1;typeId2,count2;...] [limit=user_level:10#50;system_platform:1]", "Send global mail with custom title/content/attachments/limit rules", (*TaskActionUserSendGmCommand).runGMCmdSendGlobalMailCustom)
 	registerGmCommandHandle(callbacks, "delete-user-mail", "", "Delete user mail", (*TaskActionUserSendGmCommand).runGMCmdDeleteUserMail)
 	registerGmCommandHandle(callbacks, "delete-global-mail", "", "Delete global mail", (*TaskActionUserSendGmCommand).runGMCmdDeleteGlobalMail)
 	registerGmCommandHandle(callbacks, "mail-test-extension", "", "Send user mail", (*TaskActionUserSendGmCommand).runGMCmdMailTestExtension)
 	registerGmCommandHandle(callbacks, "generate-account-password", "[openid...]", "Generate password", (*TaskActionUserSendGmCommand).runGMCmdGeneratePassword)
 	registerGmCommandHandle(callbacks, "wait-ms", "<milliseconds>", "Wait for specified milliseconds", (*TaskActionUserSendGmCommand).runGMCmdWaitMs)
+	registerGmCommandHandle(callbacks, "timer-list", "", "List pending user timers", (*TaskActionUserSendGmCommand).runGMCmdTimerList)
 	registerGmCommandHandle(callbacks, "run-excel-test", "<time>", "Run excel test", (*TaskActionUserSendGmCommand).runGMCmdRunExcelTest)
 	return callbacks
 }
@@ -682,20 +694,275 @@ func (t *TaskActionUserSendGmCommand) runGMCmdQuestForceFinish(ctx component_dis
 }
 
 func (t *TaskActionUserSendGmCommand) runGMCmdDelAccount(ctx component_dispatcher.AwaitableContext, user *data.User, args []string) ([]string, error) {
-	component_dispatcher.AsyncThen(ctx, "del account", user.GetActorExecutor(), ctx.GetAction(), func(childCtx cd.AwaitableContext) {
-		userId := user.GetUserId()
-		if len(args) >= 1 {
-			userId, _ = strconv.ParseUint(args[0], 10, 64)
+	userId, err := parseGMTargetUserId(user, args)
+	if err != nil {
+		return nil, err
+	}
+
+	zoneId := user.GetZoneId()
+	operator := fmt.Sprintf("gm:%d", user.GetUserId())
+	component_dispatcher.AsyncThen(ctx, "del account", getGMTargetUserActor(ctx, zoneId, userId), ctx.GetAction(), func(childCtx cd.AwaitableContext) {
+		// 不走冷静期，直接删除
+		deletion, result := logic_account_lifecycle.RequestAccountDeletion(childCtx, zoneId, userId, operator, "gm del-account", 0)
+		if result.IsError() && result.GetResponseCode() != int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_ACCOUNT_DELETED) {
+			result.LogError(childCtx, "request account deletion failed", "zone_id", zoneId, "user_id", userId)
+			return
 		}
-		if userId == user.GetUserId() {
-			libatapp.AtappGetModule[*uc.UserManager](childCtx.GetApp()).Remove(childCtx, user.GetZoneId(), userId, user, true)
+		if deletion == nil {
+			return
 		}
-		db.DatabaseTableLoginLockDelWithUserId(childCtx, userId)
-		db.DatabaseTableUserDelWithZoneIdUserId(childCtx, user.GetZoneId(), userId)
+		logic_account_lifecycle.ExecuteAccountDeletion(childCtx, deletion.GetOpenId())
 	})
 	return []string{""}, nil
 }
 
+// getGMTargetUserActor 操作其他玩家数据时需要在目标玩家的 Actor 上执行，避免和玩家自身的存盘并发
+func getGMTargetUserActor(ctx component_dispatcher.AwaitableContext, zoneId uint32, userId uint64) *cd.ActorExecutor {
+	if target := libatapp.AtappGetModule[*uc.UserManager](ctx.GetApp()).Find(ctx, zoneId, userId); !lu.IsNil(target) {
+		return target.GetActorExecutor()
+	}
+	return nil
+}
+
+func (t *TaskActionUserSendGmCommand) runGMCmdAccountExport(ctx component_dispatcher.AwaitableContext, user *data.User, args []string) ([]string, error) {
+	userId, err := parseGMTargetUserId(user, args)
+	if err != nil {
+		return nil, err
+	}
+
+	// 导出的是数据库中的数据，当前玩家先存盘
+	if userId == user.GetUserId() {
+		result := libatapp.AtappGetModule[*uc.UserManager](ctx.GetApp()).Save(ctx, user)
+		if result.IsError() {
+			return nil, result.GetStandardError()
+		}
+	}
+
+	archive, result := logic_account_lifecycle.ExportAccount(ctx, user.GetZoneId(), userId, "")
+	if result.IsError() {
+		return nil, result.GetStandardError()
+	}
+	fileName, result := logic_account_lifecycle.WriteAccountExportArchive(ctx, archive)
+	if result.IsError() {
+		return nil, result.GetStandardError()
+	}
+	return []string{fmt.Sprintf("export account success, file: %s", fileName)}, nil
+}
+
+func (t *TaskActionUserSendGmCommand) runGMCmdAccountDelete(ctx component_dispatcher.AwaitableContext, user *data.User, args []string) ([]string, error) {
+	userId, err := parseGMTargetUserId(user, args)
+	if err != nil {
+		return nil, err
+	}
+	reason := "gm account-delete"
+	if len(args) >= 2 {
+		reason = strings.Join(args[1:], " ")
+	}
+
+	zoneId := user.GetZoneId()
+	operator := fmt.Sprintf("gm:%d", user.GetUserId())
+	component_dispatcher.AsyncThen(ctx, "request account deletion", getGMTargetUserActor(ctx, zoneId, userId), ctx.GetAction(), func(childCtx cd.AwaitableContext) {
+		logic_account_lifecycle.RequestAccountDeletion(childCtx, zoneId, userId, operator, reason, -1)
+	})
+	return []string{"account deletion requested, user will be kicked off"}, nil
+}
+
+func (t *TaskActionUserSendGmCommand) runGMCmdAccountDeleteCancel(ctx component_dispatcher.AwaitableContext, user *data.User, args []string) ([]string, error) {
+	userId, err := parseGMTargetUserId(user, args)
+	if err != nil {
+		return nil, err
+	}
+
+	var openId string
+	if userId == user.GetUserId() {
+		openId = user.GetOpenId()
+	} else {
+		userTb, _, result := db.DatabaseTableUserLoadWithZoneIdUserId(ctx, user.GetZoneId(), userId)
+		if result.IsError() {
+			return nil, result.GetStandardError()
+		}
+		openId = userTb.GetOpenId()
+	}
+
+	result := logic_account_lifecycle.CancelAccountDeletion(ctx, openId, fmt.Sprintf("gm:%d", user.GetUserId()))
+	if result.IsError() {
+		return nil, result.GetStandardError()
+	}
+	return []string{"account deletion cancelled"}, nil
+}
+
+func (t *TaskActionUserSendGmCommand) runGMCmdZoneTransfer(ctx component_dispatcher.AwaitableContext, user *data.User, args []string) ([]string, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("invalid arguments for zone-transfer <target_zone_id> [user_id] [reason] command")
+	}
+	targetZoneId, err := strconv.ParseUint(args[0], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid target_zone_id: %w", err)
+	}
+	userId, err := parseGMTargetUserId(user, args[1:])
+	if err != nil {
+		return nil, err
+	}
+	reason := "gm zone-transfer"
+	if len(args) >= 3 {
+		reason = strings.Join(args[2:], " ")
+	}
+
+	zoneId := user.GetZoneId()
+	operator := fmt.Sprintf("gm:%d", user.GetUserId())
+	component_dispatcher.AsyncThen(ctx, "transfer user zone", getGMTargetUserActor(ctx, zoneId, userId), ctx.GetAction(), func(childCtx cd.AwaitableContext) {
+		logic_account_lifecycle.TransferUserZone(childCtx, zoneId, userId, uint32(targetZoneId), operator, reason)
+	})
+	return []string{"zone transfer started, user will be kicked off"}, nil
+}
+
+func parseGMTargetUserId(user *data.User, args []string) (uint64, error) {
+	if len(args) < 1 {
+		return user.GetUserId(), nil
+	}
+	userId, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid user_id: %w", err)
+	}
+	return userId, nil
+}
+
+// loadGMSnapshotCurrentUserTable 当前玩家直接导出内存数据，其他玩家从数据库读取
+func loadGMSnapshotCurrentUserTable(ctx component_dispatcher.AwaitableContext, user *data.User, userId uint64) (*private_protocol_pbdesc.DatabaseTableUser, uint64, error) {
+	if userId == user.GetUserId() {
+		userTb := &private_protocol_pbdesc.DatabaseTableUser{}
+		result := user.DumpToDB(ctx, userTb)
+		if result.IsError() {
+			return nil, 0, result.GetStandardError()
+		}
+		return userTb, user.GetUserCASVersion(), nil
+	}
+
+	userTb, casVersion, result := db.DatabaseTableUserLoadWithZoneIdUserId(ctx, user.GetZoneId(), userId)
+	if result.IsError() {
+		return nil, 0, result.GetStandardError()
+	}
+	return userTb, casVersion, nil
+}
+
+func (t *TaskActionUserSendGmCommand) runGMCmdSnapshotList(ctx component_dispatcher.AwaitableContext, user *data.User, args []string) ([]string, error) {
+	userId, err := parseGMTargetUserId(user, args)
+	if err != nil {
+		return nil, err
+	}
+
+	records, result := uc.ListUserSnapshots(ctx, user.GetZoneId(), userId)
+	if result.IsError() {
+		return nil, result.GetStandardError()
+	}
+
+	ret := make([]string, 0, len(records))
+	for _, record := range records {
+		ret = append(ret, fmt.Sprintf("index: %d, time: %s, reason: %s, cas_version: %d, raw_size: %d, compressed_size: %d",
+			record.ListIndex, time.Unix(record.Data.GetSnapshotTime(), 0).Format(time.DateTime), record.Data.GetReason(),
+			record.Data.GetUserCasVersion(), record.Data.GetRawSize(), len(record.Data.GetCompressedData())))
+	}
+	if len(ret) == 0 {
+		ret = append(ret, "no snapshot")
+	}
+	return ret, nil
+}
+
+func (t *TaskActionUserSendGmCommand) runGMCmdSnapshotCreate(ctx component_dispatcher.AwaitableContext, user *data.User, args []string) ([]string, error) {
+	userId, err := parseGMTargetUserId(user, args)
+	if err != nil {
+		return nil, err
+	}
+
+	userTb, casVersion, err := loadGMSnapshotCurrentUserTable(ctx, user, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	result := uc.AddUserSnapshot(ctx, userTb, casVersion, uc.UserSnapshotReasonGM)
+	if result.IsError() {
+		return nil, result.GetStandardError()
+	}
+	return []string{"snapshot created"}, nil
+}
+
+func (t *TaskActionUserSendGmCommand) runGMCmdSnapshotDiff(ctx component_dispatcher.AwaitableContext, user *data.User, args []string) ([]string, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("invalid arguments for snapshot-diff <index> [user_id] command")
+	}
+	listIndex, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid index: %w", err)
+	}
+	userId, err := parseGMTargetUserId(user, args[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	snapshotTb, _, result := uc.LoadUserSnapshot(ctx, user.GetZoneId(), userId, listIndex)
+	if result.IsError() {
+		return nil, result.GetStandardError()
+	}
+	currentTb, _, err := loadGMSnapshotCurrentUserTable(ctx, user, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := uc.DiffUserSnapshot(snapshotTb, currentTb)
+	if len(ret) == 0 {
+		ret = append(ret, "no difference")
+	}
+	return ret, nil
+}
+
+func (t *TaskActionUserSendGmCommand) runGMCmdSnapshotRestore(ctx component_dispatcher.AwaitableContext, user *data.User, args []string) ([]string, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("invalid arguments for snapshot-restore <index> [user_id] command")
+	}
+	listIndex, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid index: %w", err)
+	}
+	userId, err := parseGMTargetUserId(user, args[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	// 先校验快照存在且可以解压，失败时直接返回给 GM
+	zoneId := user.GetZoneId()
+	if _, _, result := uc.LoadUserSnapshot(ctx, zoneId, userId, listIndex); result.IsError() {
+		return nil, result.GetStandardError()
+	}
+
+	// 回档需要在目标玩家的 Actor 上执行，避免和玩家自身的存盘并发
+	// 回档自己时当前任务占用着 Actor，只能等本任务结束后执行，结果只能记录日志
+	if userId == user.GetUserId() {
+		component_dispatcher.AsyncThen(ctx, "restore user snapshot", user.GetActorExecutor(), ctx.GetAction(), func(childCtx cd.AwaitableContext) {
+			result := uc.RestoreUserSnapshot(childCtx, zoneId, userId, listIndex)
+			if result.IsError() {
+				result.LogError(childCtx, "gm restore user snapshot failed", "zone_id", zoneId, "user_id", userId, "list_index", listIndex)
+			}
+		})
+		return []string{"restore started, you will be kicked off"}, nil
+	}
+
+	restoreResult := cd.CreateRpcResultError(fmt.Errorf("restore task not finished"), public_protocol_pbdesc.EnErrorCode_EN_ERR_TIMEOUT)
+	task := cd.AsyncInvoke(ctx, "restore user snapshot", getGMTargetUserActor(ctx, zoneId, userId), func(childCtx cd.AwaitableContext) cd.RpcResult {
+		restoreResult = uc.RestoreUserSnapshot(childCtx, zoneId, userId, listIndex)
+		return restoreResult
+	})
+	if lu.IsNil(task) {
+		return nil, fmt.Errorf("start restore task failed")
+	}
+	if result := cd.AwaitTask(ctx, task); result.IsError() {
+		return nil, result.GetStandardError()
+	}
+	if restoreResult.IsError() {
+		return nil, restoreResult.GetStandardError()
+	}
+	return []string{"restore success, user has been kicked off"}, nil
+}
+
 func (t *TaskActionUserSendGmCommand) runGMCmdCopyAccount(ctx component_dispatcher.AwaitableContext, user *data.User, args []string) ([]string, error) {
 	if len(args) < 1 {
 		return nil, fmt.Errorf("invalid arguments for lottery-reset-pool <pool id> command")
@@ -989,7 +1256,7 @@ func (t *TaskActionUserSendGmCommand) runGMCmdSendUserMailCustom(ctx component_d
 
 func (t *TaskActionUserSendGmCommand) runGMCmdSendGlobalMailCustom(ctx component_dispatcher.AwaitableContext, user *data.User, args []string) ([]string, error) {
 	if len(args) < 3 {
-		return nil, fmt.Errorf("invalid arguments: send-global-mail-custom <major_type> <title> <content> [delivery_time_unix=<unix>] [expired_time_unix=<unix>] [items=typeId1,count1;typeId2,count2;...]")
+		return nil, fmt.Errorf("invalid arguments: send-global-mail-custom <major_type> <title> <content> [delivery_time_unix=<unix>] [expired_time_unix=<unix>] [items=typeId1,count1;typeId2,count2;...] [limit=user_level:10#50;system_platform:1]")
 	}
 
 	majorType, err := strconv.ParseInt(args[0], 10, 32)
@@ -1023,6 +1290,14 @@ func (t *TaskActionUserSendGmCommand) runGMCmdSendGlobalMailCustom(ctx component
 		}
 	}
 
+	var mailLimit []*public_protocol_common.DConditionRule
+	if v, ok := kv["limit"]; ok && v != "" {
+		mailLimit, err = logic_condition.ParseRuleExpression(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid limit: %w", err)
+		}
+	}
+
 	if deliveryTime == 0 {
 		deliveryTime = ctx.GetNow().Unix()
 	}
@@ -1033,6 +1308,7 @@ func (t *TaskActionUserSendGmCommand) runGMCmdSendGlobalMailCustom(ctx component
 		Content:      content,
 		DeliveryTime: deliveryTime,
 		ExpiredTime:  expiredTime + deliveryTime,
+		MailLimit:    mailLimit,
 	}
 	for i, item := range items {
 		cfg := config.GetConfigManager().GetCurrentConfigGroup().GetExcelItemByItemId(item.TypeId)
@@ -1143,6 +1419,25 @@ func (t *TaskActionUserSendGmCommand) runGMCmdWaitMs(ctx component_dispatcher.Aw
 	return nil, nil
 }
 
+func (t *TaskActionUserSendGmCommand) runGMCmdTimerList(ctx component_dispatcher.AwaitableContext, user *data.User, args []string) ([]string, error) {
+	mgr := data.UserGetModuleManager[logic_timer.UserTimerManager](user)
+	if mgr == nil {
+		return nil, fmt.Errorf("user timer manager not found")
+	}
+
+	timers := mgr.GetPendingTimers()
+	now := ctx.GetNow()
+	reply := make([]string, 0, len(timers)+1)
+	reply = append(reply, fmt.Sprintf("Pending timers: %d", len(timers)))
+	for _, timer := range timers {
+		triggerTime := time.Unix(timer.GetTriggerTime(), 0).In(now.Location())
+		reply = append(reply, fmt.Sprintf("id=%d callback=%s trigger_time=%s remain=%s param=%d param_str=%q",
+			timer.GetTimerId(), timer.GetCallbackName(), triggerTime.Format(time.DateTime),
+			triggerTime.Sub(now).Truncate(time.Second), timer.GetParam(), timer.GetParamStr()))
+	}
+	return reply, nil
+}
+
 func (t *TaskActionUserSendGmCommand) runGMCmdRunExcelTest(ctx component_dispatcher.AwaitableContext, user *data.User, args []string) ([]string, error) {
 	if len(args) < 1 {
 		return nil, fmt.Errorf("invalid arguments")
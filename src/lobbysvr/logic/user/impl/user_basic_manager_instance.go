@@ -17,6 +17,7 @@ import (
 
 	config "github.com/atframework/atsf4g-go/component/config"
 	db "github.com/atframework/atsf4g-go/component/db"
+	mail_component "github.com/atframework/atsf4g-go/component/mail"
 
 	data "github.com/atframework/atsf4g-go/service-lobbysvr/data"
 
@@ -50,6 +51,7 @@ type UserBasicManager struct {
 
 	dirtyLevelUpRewards map[int32]map[int64]*public_protocol_common.DItemInstance
 	userOptions         *private_protocol_pbdesc.UserOptionsData
+	zoneMergeData       *private_protocol_pbdesc.UserZoneMergeData
 }
 
 func CreateUserBasicManager(owner *data.User) *UserBasicManager {
@@ -65,11 +67,13 @@ func (m *UserBasicManager) InitFromDB(_ctx cd.RpcContext, _dbUser *private_proto
 	if m.userOptions == nil {
 		m.userOptions = &private_protocol_pbdesc.UserOptionsData{}
 	}
+	m.zoneMergeData = _dbUser.GetZoneMergeData()
 	return cd.CreateRpcResultOk()
 }
 
 func (m *UserBasicManager) DumpToDB(_ctx cd.RpcContext, dbUser *private_protocol_pbdesc.DatabaseTableUser) cd.RpcResult {
 	dbUser.OptionsData = m.userOptions
+	dbUser.ZoneMergeData = m.zoneMergeData
 	return cd.CreateRpcResultOk()
 }
 
@@ -440,6 +444,23 @@ func (m *UserBasicManager) GetUserClientOptions() *public_protocol_pbdesc.DUserO
 	return m.userOptions.GetCustomOptions()
 }
 
+// GetUserLanguage 获取玩家偏好语言: 客户端选项 -> 设备系统语言 -> 服务器默认语言
+func (m *UserBasicManager) GetUserLanguage() public_protocol_pbdesc.EnMutlLanguageType {
+	if m == nil {
+		return mail_component.MailGetServerLanguage()
+	}
+
+	if lang := m.GetUserClientOptions().GetLanguage(); lang != int32(public_protocol_pbdesc.EnMutlLanguageType_EN_MUTL_LANGUAGE_NONE) {
+		return public_protocol_pbdesc.EnMutlLanguageType(lang)
+	}
+
+	if lang := mail_component.MailParseLanguage(m.GetOwner().GetClientInfo().GetSystemLanguage()); lang != public_protocol_pbdesc.EnMutlLanguageType_EN_MUTL_LANGUAGE_NONE {
+		return lang
+	}
+
+	return mail_component.MailGetServerLanguage()
+}
+
 func (m *UserBasicManager) UpdateUserClientOptions(ctx cd.RpcContext, opts *public_protocol_pbdesc.DUserOptions) data.Result {
 	if opts == nil {
 		return cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_INVALID_PARAM)
@@ -450,6 +471,10 @@ func (m *UserBasicManager) UpdateUserClientOptions(ctx cd.RpcContext, opts *publ
 		return cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_USER_CLIENT_OPTIONS_SIZE_LIMIT)
 	}
 
+	if _, ok := public_protocol_pbdesc.EnMutlLanguageType_name[opts.GetLanguage()]; !ok {
+		return cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_INVALID_PARAM)
+	}
+
 	m.userOptions.CustomOptions = opts.Clone()
 
 	m.dirtyUserOptions = true
@@ -564,6 +589,41 @@ func (m *UserBasicManager) Rename(ctx cd.AwaitableContext, newName string, expec
 	return cd.CreateRpcResultOk()
 }
 
+// SendZoneMergeCompensation 合服时因重名被清空昵称的玩家，登入后补发改名补偿邮件
+// 昵称为空时改名不消耗道具，补偿邮件只用于告知和额外补偿
+func (m *UserBasicManager) SendZoneMergeCompensation(ctx cd.AwaitableContext) data.Result {
+	if !m.zoneMergeData.GetPendingRenameCompensation() {
+		return cd.CreateRpcResultOk()
+	}
+
+	mailTemplateId := config.GetConfigManager().GetCurrentConfigGroup().GetSectionConfig().GetZoneMerge().GetRenameCompensationMailTemplateId()
+	if mailTemplateId != 0 {
+		sender := public_protocol_pbdesc.DMailUserInfo{}
+		receiver := public_protocol_pbdesc.DMailUserInfo{}
+		receiver.MutableProfile().UserId = m.GetOwner().GetUserId()
+		receiver.MutableProfile().ZoneId = m.GetOwner().GetZoneId()
+		extensions := map[string]string{
+			"0": m.zoneMergeData.GetOriginalNickName(),
+			"1": fmt.Sprintf("%d", m.zoneMergeData.GetSourceZoneId()),
+		}
+		result, _ := mail_component.AddUserMailWithTemplate(ctx, mailTemplateId, &sender, &receiver,
+			m.GetOwner().GetZoneId(), int32(public_protocol_pbdesc.EnMailChannelType_EN_MAIL_CHANNEL_USER_REWARD), 0, nil,
+			&public_protocol_pbdesc.DMailFlowReason{
+				MajorReason: int32(public_protocol_common.EnItemFlowReasonMajorType_EN_ITEM_FLOW_REASON_MAJOR_USER),
+				MinorReason: int32(public_protocol_common.EnItemFlowReasonMinorType_EN_ITEM_FLOW_REASON_MINOR_USER_ZONE_MERGE_RENAME),
+				Parameter:   int64(m.zoneMergeData.GetSourceZoneId()),
+			}, extensions, 0, 0)
+		if result.IsError() {
+			result.LogError(ctx, "send zone merge rename compensation mail failed", "mail_template_id", mailTemplateId,
+				"original_nick_name", m.zoneMergeData.GetOriginalNickName())
+			return result
+		}
+	}
+
+	m.zoneMergeData.PendingRenameCompensation = false
+	return cd.CreateRpcResultOk()
+}
+
 func (m *UserBasicManager) EditProfileCard(ctx cd.AwaitableContext, profileCard string) data.Result {
 	if profileCard == m.GetOwner().GetAccountInfo().GetProfile().GetProfileCard() {
 		return cd.CreateRpcResultOk()
@@ -28,6 +28,8 @@ type UserBasicManager interface {
 	Rename(ctx cd.AwaitableContext, newName string, expectCostItems []*public_protocol_common.DItemBasic) data.Result
 	EditProfileCard(ctx cd.AwaitableContext, profileCard string) data.Result
 	EditAvatar(ctx cd.AwaitableContext, avatar string) data.Result
+	// SendZoneMergeCompensation 补发合服重名改名补偿邮件
+	SendZoneMergeCompensation(ctx cd.AwaitableContext) data.Result
 
 	GmResetUserExp(ctx cd.RpcContext, v int64) data.Result
 
@@ -35,6 +37,8 @@ type UserBasicManager interface {
 
 	GetUserClientOptions() *public_protocol_pbdesc.DUserOptions
 	UpdateUserClientOptions(ctx cd.RpcContext, opts *public_protocol_pbdesc.DUserOptions) data.Result
+	// GetUserLanguage 获取玩家偏好语言，用于邮件等多语言内容选择
+	GetUserLanguage() public_protocol_pbdesc.EnMutlLanguageType
 
 	AllowGMCmd() bool
 }
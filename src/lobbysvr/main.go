@@ -12,6 +12,7 @@ import (
 	log "github.com/atframework/atframe-utils-go/log"
 	config "github.com/atframework/atsf4g-go/component/config"
 	generate_config "github.com/atframework/atsf4g-go/component/config/generate_config"
+	db "github.com/atframework/atsf4g-go/component/db"
 	cd "github.com/atframework/atsf4g-go/component/dispatcher"
 	private_protocol_config "github.com/atframework/atsf4g-go/component/protocol/private/config/protocol/config"
 	uc "github.com/atframework/atsf4g-go/component/user_controller"
@@ -20,7 +21,13 @@ import (
 	component_open_platform "github.com/atframework/atsf4g-go/component/open_platform"
 
 	lobbysvr_app "github.com/atframework/atsf4g-go/service-lobbysvr/app"
+	logic_account_lifecycle "github.com/atframework/atsf4g-go/service-lobbysvr/logic/account_lifecycle"
+	logic_activity "github.com/atframework/atsf4g-go/service-lobbysvr/logic/activity"
+	logic_chat "github.com/atframework/atsf4g-go/service-lobbysvr/logic/chat"
+	logic_cron "github.com/atframework/atsf4g-go/service-lobbysvr/logic/cron"
 	logic_global_mail "github.com/atframework/atsf4g-go/service-lobbysvr/logic/global_mail"
+	logic_guild "github.com/atframework/atsf4g-go/service-lobbysvr/logic/guild"
+	logic_payment_callback "github.com/atframework/atsf4g-go/service-lobbysvr/logic/payment/callback"
 	logic_user_impl "github.com/atframework/atsf4g-go/service-lobbysvr/logic/user/impl"
 )
 
@@ -31,6 +38,7 @@ func main() {
 	app := ssc.CreateServiceApplication()
 
 	uc.InitUserRouterManager(app)
+	logic_guild.InitGuildRouterManager(app)
 
 	config.GetConfigManager().SetServerConfigureLoadFunc(func(originConfigData interface{}, callback generate_config.ConfigCallback) (interface{}, error) {
 		serverConfig := &private_protocol_config.LobbyServerCfg{}
@@ -58,6 +66,12 @@ func main() {
 	redisDispatcher := cd.CreateRedisMessageDispatcher(app)
 	atapp.AtappAddModule(app, redisDispatcher)
 
+	storageBackendModule := db.CreateStorageBackendModule(app)
+	atapp.AtappAddModule(app, storageBackendModule)
+
+	tableCacheManager := db.CreateTableCacheManager(app)
+	atapp.AtappAddModule(app, tableCacheManager)
+
 	httpClientDispatcher := cd.CreateHttpClientDispatcher(app, "lobbysvr.http_client")
 	atapp.AtappAddModule(app, httpClientDispatcher)
 
@@ -67,10 +81,29 @@ func main() {
 	globalMailManager := logic_global_mail.CreateGlobalMailManager(app)
 	atapp.AtappAddModule(app, globalMailManager)
 
+	accountDeletionManager := logic_account_lifecycle.CreateAccountDeletionManager(app)
+	atapp.AtappAddModule(app, accountDeletionManager)
+
+	chatManager := logic_chat.CreateChatManager(app)
+	atapp.AtappAddModule(app, chatManager)
+
+	guildManager := logic_guild.CreateGuildManager(app)
+	atapp.AtappAddModule(app, guildManager)
+
+	activityManager := logic_activity.CreateActivityManager(app)
+	atapp.AtappAddModule(app, activityManager)
+
+	cronManager := logic_cron.CreateCronManager(app)
+	atapp.AtappAddModule(app, cronManager)
+
 	// CS消息WebSocket分发器 放在最后，确保其他模块都已注册完成
 	csDispatcher := uc_d.WebsocketDispatcherCreateCSMessage(app, "lobbysvr.webserver", "lobbysvr.websocket")
 	atapp.AtappAddModule(app, csDispatcher)
 
+	// 支付回调挂在 webserver 上，需要在 csDispatcher 之后初始化
+	paymentCallbackManager := logic_payment_callback.CreatePaymentCallbackManager(app, csDispatcher)
+	atapp.AtappAddModule(app, paymentCallbackManager)
+
 	if err := lobbysvr_app.RegisterLobbyClientService(csDispatcher, uc_d.WebsocketDispatcherFindSessionFromMessage); err != nil {
 		println("RegisterLobbyClientService fail: %s", err.Error())
 		return
@@ -89,8 +89,9 @@ import sys
 <%include file="db_rpc_redis_expire.mako" args="message_name=message_name,message=message,index=index,index_meta=index_meta" />
 
 % if index_type == "sorted_set":
-## ========== Sorted Set 类型：ZAdd / ZRange / ZRank ==========
+## ========== Sorted Set 类型：ZAdd / ZRem / ZRange / ZRank ==========
 <%include file="db_rpc_redis_zadd.mako" args="message_name=message_name,message=message,index=index,index_meta=index_meta" />
+<%include file="db_rpc_redis_zrem.mako" args="message_name=message_name,message=message,index=index,index_meta=index_meta" />
 <%include file="db_rpc_redis_zrange.mako" args="message_name=message_name,message=message,index=index,index_meta=index_meta" />
 <%include file="db_rpc_redis_zrank.mako" args="message_name=message_name,message=message,index=index,index_meta=index_meta" />
 <%include file="db_rpc_redis_del.mako" args="message_name=message_name,message=message,index=index,index_meta=index_meta" />
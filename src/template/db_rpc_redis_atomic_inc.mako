@@ -16,17 +16,16 @@ func ${message_name}AtomicInc${index_key_name}${inc_field["ident"]}(
 % endfor
     incValue ${inc_field["go_type"]},
 ) (newValue ${inc_field["go_type"]}, retResult cd.RpcResult) {
-    dispatcher := libatapp.AtappGetModule[*cd.RedisMessageDispatcher](ctx.GetApp())
-    instance := dispatcher.GetRedisInstance()
-    if instance == nil {
-        ctx.LogError("get redis instance failed")
+    backend := GetStorageBackend(ctx.GetApp())
+    if backend == nil {
+        ctx.LogError("get db storage backend failed")
         retResult = cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
         return
     }
     ${args_redis_key_call}
 
-    newValue, retResult = HashTableAtomicInc(ctx, index, "${message_name}",
-        dispatcher, instance, "${inc_field["raw_name"]}", uint64(incValue))
+    newValue, retResult = backend.HashTableAtomicInc(ctx, index, "${message_name}",
+        "${inc_field["raw_name"]}", uint64(incValue))
     if retResult.IsError() {
         return
     }
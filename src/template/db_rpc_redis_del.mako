@@ -13,16 +13,15 @@ func ${message_name}DelWith${index_key_name}(
     ${field["ident"]} ${field["go_type"]},
 % endfor
 ) (retResult cd.RpcResult) {
-	dispatcher := libatapp.AtappGetModule[*cd.RedisMessageDispatcher](ctx.GetApp())
-	instance := dispatcher.GetRedisInstance()
-	if instance == nil {
-        ctx.LogError("get redis instance failed")
+	backend := GetStorageBackend(ctx.GetApp())
+	if backend == nil {
+        ctx.LogError("get db storage backend failed")
 		retResult = cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
 		return
 	}
 	${args_redis_key_call}
 
-	retResult = TableDel(ctx, index, "${message_name}", dispatcher, instance)
+	retResult = backend.TableDel(ctx, index, "${message_name}")
 	if retResult.IsError() {
 		return
 	}
@@ -36,6 +35,22 @@ func ${message_name}DelWith${index_key_name}(
 	return
 }
 
+// ${message_name}PipelineDelWith${index_key_name} 加入批量操作，结果在 pipeline.Execute() 之后有效
+func ${message_name}PipelineDelWith${index_key_name}(
+	pipeline *Pipeline,
+% for field in key_fields:
+    ${field["ident"]} ${field["go_type"]},
+% endfor
+) (dbResult *PipelineResult) {
+	ctx := pipeline.GetContext()
+	${args_redis_key_call}
+	dbResult = &PipelineResult{}
+	pipeline.TableDel(index, "${message_name}", func(result cd.RpcResult) {
+		dbResult.Result = result
+	})
+	return
+}
+
 % if not index_type == "kv":
 func ${message_name}DelIndexWith${index_key_name}(
 	ctx cd.AwaitableContext,
@@ -44,16 +59,15 @@ func ${message_name}DelIndexWith${index_key_name}(
     ${field["ident"]} ${field["go_type"]},
 % endfor
 ) (retResult cd.RpcResult) {
-	dispatcher := libatapp.AtappGetModule[*cd.RedisMessageDispatcher](ctx.GetApp())
-	instance := dispatcher.GetRedisInstance()
-	if instance == nil {
-        ctx.LogError("get redis instance failed")
+	backend := GetStorageBackend(ctx.GetApp())
+	if backend == nil {
+        ctx.LogError("get db storage backend failed")
 		retResult = cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
 		return
 	}
 	${args_redis_key_call}
 
-	retResult = HashTableDelListIndex(ctx, index, "${message_name}", dispatcher, instance, listIndex)
+	retResult = backend.HashTableDelListIndex(ctx, index, "${message_name}", listIndex)
 	if retResult.IsError() {
 		ctx.LogError("delete ${message_name} table with key from db failed",
 			"listIndex", listIndex,
@@ -21,16 +21,15 @@ func ${message_name}ExpireWith${index_key_name}(
 	expiration time.Duration,
 	condition ExpireConditionOption,
 ) (success bool, retResult cd.RpcResult) {
-	dispatcher := libatapp.AtappGetModule[*cd.RedisMessageDispatcher](ctx.GetApp())
-	instance := dispatcher.GetRedisInstance()
-	if instance == nil {
-        ctx.LogError("get redis instance failed")
+	backend := GetStorageBackend(ctx.GetApp())
+	if backend == nil {
+        ctx.LogError("get db storage backend failed")
 		retResult = cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
 		return
 	}
 	${args_redis_key_call}
-	success, retResult = TableExpire(ctx, index, "${message_name}",
-		dispatcher, instance, expiration, condition)
+	success, retResult = backend.TableExpire(ctx, index, "${message_name}",
+		expiration, condition)
 	if retResult.IsError() {
 		return
 	}
@@ -45,6 +44,25 @@ func ${message_name}ExpireWith${index_key_name}(
 	return
 }
 
+// ${message_name}PipelineExpireWith${index_key_name} 加入批量操作，结果在 pipeline.Execute() 之后有效
+func ${message_name}PipelineExpireWith${index_key_name}(
+	pipeline *Pipeline,
+% for field in key_fields:
+    ${field["ident"]} ${field["go_type"]},
+% endfor
+	expiration time.Duration,
+	condition ExpireConditionOption,
+) (dbResult *PipelineExpireResult) {
+	ctx := pipeline.GetContext()
+	${args_redis_key_call}
+	dbResult = &PipelineExpireResult{}
+	pipeline.TableExpire(index, "${message_name}", expiration, condition, func(success bool, result cd.RpcResult) {
+		dbResult.Success = success
+		dbResult.Result = result
+	})
+	return
+}
+
 // ${message_name}ExpireAtWith${index_key_name} 对指定表的 Redis Key 设置过期时间点（绝对时间戳）
 // expireAt: 过期时间点（time.Time）
 func ${message_name}ExpireAtWith${index_key_name}(
@@ -54,16 +72,15 @@ func ${message_name}ExpireAtWith${index_key_name}(
 % endfor
 	expireAt time.Time,
 ) (success bool, retResult cd.RpcResult) {
-	dispatcher := libatapp.AtappGetModule[*cd.RedisMessageDispatcher](ctx.GetApp())
-	instance := dispatcher.GetRedisInstance()
-	if instance == nil {
-        ctx.LogError("get redis instance failed")
+	backend := GetStorageBackend(ctx.GetApp())
+	if backend == nil {
+        ctx.LogError("get db storage backend failed")
 		retResult = cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
 		return
 	}
 	${args_redis_key_call}
-	success, retResult = TableExpireAt(ctx, index, "${message_name}",
-		dispatcher, instance, expireAt)
+	success, retResult = backend.TableExpireAt(ctx, index, "${message_name}",
+		expireAt)
 	if retResult.IsError() {
 		return
 	}
@@ -14,7 +14,7 @@
         args_key_fmt_args += ident + ", "
 
     prefix_fmt_key = "%s-"
-    prefix_fmt_value = "dispatcher.GetRecordPrefix()"
+    prefix_fmt_value = "GetStorageRecordPrefix(ctx.GetApp())"
     args_index_key = (
         "index := fmt.Sprintf(\"" + prefix_fmt_key + db_fmt_key + "\", "
         + prefix_fmt_value
@@ -31,7 +31,6 @@ func ${message_name}RedisKeyWith${index_key_name}(
     ${field["ident"]} ${field["go_type"]},
 % endfor
 ) string {
-	dispatcher := libatapp.AtappGetModule[*cd.RedisMessageDispatcher](ctx.GetApp())
 	${args_index_key}
 	return index
 }
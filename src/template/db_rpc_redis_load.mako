@@ -19,21 +19,20 @@ func ${message_name}LoadWith${index_key_name}(
 % else:
 ) (table *private_protocol_pbdesc.${message_name}, retResult cd.RpcResult) {
 % endif
-	dispatcher := libatapp.AtappGetModule[*cd.RedisMessageDispatcher](ctx.GetApp())
-	instance := dispatcher.GetRedisInstance()
-	if instance == nil {
-        ctx.LogError("get redis instance failed")
+	backend := GetStorageBackend(ctx.GetApp())
+	if backend == nil {
+        ctx.LogError("get db storage backend failed")
 		retResult = cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
 		return
 	}
 	${args_redis_key_call}
 	var message proto.Message
 % if cas_enabled:
-	message, CASVersion, retResult = HashTableLoad(
+	message, CASVersion, retResult = backend.HashTableLoad(
 % else:
-	message, _, retResult = HashTableLoad(
+	message, _, retResult = backend.HashTableLoad(
 % endif
-		ctx, index, "${message_name}", dispatcher, instance, func() proto.Message {
+		ctx, index, "${message_name}", func() proto.Message {
 			return new(private_protocol_pbdesc.${message_name})
 		})
 	if retResult.IsError() {
@@ -73,10 +72,9 @@ func ${message_name}BatchLoadWith${index_key_name}(
 	ctx cd.AwaitableContext,
 	keys []${message_name}TableKey,
 ) (dbResult []${message_name}BatchGetResult, retResult cd.RpcResult) {
-	dispatcher := libatapp.AtappGetModule[*cd.RedisMessageDispatcher](ctx.GetApp())
-	instance := dispatcher.GetRedisInstance()
-	if instance == nil {
-        ctx.LogError("get redis instance failed")
+	backend := GetStorageBackend(ctx.GetApp())
+	if backend == nil {
+        ctx.LogError("get db storage backend failed")
 		retResult = cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
 		return
 	}
@@ -85,8 +83,8 @@ func ${message_name}BatchLoadWith${index_key_name}(
 		${batch_redis_key_call}
 	}
 	var messages []*RedisSetIndexMessage
-	messages, retResult = HashTableBatchLoad(
-		ctx, loadIndexs, "${message_name}", dispatcher, instance, func() proto.Message {
+	messages, retResult = backend.HashTableBatchLoad(
+		ctx, loadIndexs, "${message_name}", func() proto.Message {
 			return new(private_protocol_pbdesc.${message_name})
 		})
 	if retResult.IsError() {
@@ -132,6 +130,34 @@ func ${message_name}BatchLoadWith${index_key_name}(
 	retResult = cd.CreateRpcResultOk()
 	return
 }
+
+// ${message_name}PipelineLoadWith${index_key_name} 加入批量操作，结果在 pipeline.Execute() 之后有效
+func ${message_name}PipelineLoadWith${index_key_name}(
+	pipeline *Pipeline,
+% for field in key_fields:
+    ${field["ident"]} ${field["go_type"]},
+% endfor
+) (dbResult *${message_name}BatchGetResult) {
+	ctx := pipeline.GetContext()
+	${args_redis_key_call}
+	dbResult = &${message_name}BatchGetResult{}
+	pipeline.HashTableLoad(index, "${message_name}", func() proto.Message {
+		return new(private_protocol_pbdesc.${message_name})
+	}, func(message proto.Message, CASVersion uint64, result cd.RpcResult) {
+		dbResult.Result = result
+		if result.IsError() {
+			return
+		}
+		dbResult.Table = message.(*private_protocol_pbdesc.${message_name})
+% for field in key_fields:
+		dbResult.Table.${field["ident"]} = ${field["ident"]}
+% endfor
+% if cas_enabled:
+		dbResult.CASVersion = CASVersion
+% endif
+	})
+	return
+}
 % else:
 func ${message_name}LoadAllWith${index_key_name}(
 	ctx cd.AwaitableContext,
@@ -139,16 +165,15 @@ func ${message_name}LoadAllWith${index_key_name}(
     ${field["ident"]} ${field["go_type"]},
 % endfor
 ) (indexMessage []pu.RedisListIndexMessage, retResult cd.RpcResult) {
-	dispatcher := libatapp.AtappGetModule[*cd.RedisMessageDispatcher](ctx.GetApp())
-	instance := dispatcher.GetRedisInstance()
-	if instance == nil {
-        ctx.LogError("get redis instance failed")
+	backend := GetStorageBackend(ctx.GetApp())
+	if backend == nil {
+        ctx.LogError("get db storage backend failed")
 		retResult = cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
 		return
 	}
 	${args_redis_key_call}
-	indexMessage, retResult = HashTableLoadListAll(
-		ctx, index, "${message_name}", dispatcher, instance, func() proto.Message {
+	indexMessage, retResult = backend.HashTableLoadListAll(
+		ctx, index, "${message_name}", func() proto.Message {
 			return new(private_protocol_pbdesc.${message_name})
 		})
 	if retResult.IsError() {
@@ -172,6 +197,36 @@ func ${message_name}LoadAllWith${index_key_name}(
 	return
 }
 
+// ${message_name}PipelineLoadAllWith${index_key_name} 加入批量操作，结果在 pipeline.Execute() 之后有效
+func ${message_name}PipelineLoadAllWith${index_key_name}(
+	pipeline *Pipeline,
+% for field in key_fields:
+    ${field["ident"]} ${field["go_type"]},
+% endfor
+) (dbResult *PipelineListLoadResult) {
+	ctx := pipeline.GetContext()
+	${args_redis_key_call}
+	dbResult = &PipelineListLoadResult{}
+	pipeline.HashTableLoadListAll(index, "${message_name}", func() proto.Message {
+		return new(private_protocol_pbdesc.${message_name})
+	}, func(indexMessage []pu.RedisListIndexMessage, result cd.RpcResult) {
+		dbResult.Result = result
+		if result.IsError() {
+			return
+		}
+		for _, item := range indexMessage {
+			table, ok := item.Table.(*private_protocol_pbdesc.${message_name})
+			if ok {
+% for field in key_fields:
+				table.${field["ident"]} = ${field["ident"]}
+% endfor
+			}
+		}
+		dbResult.IndexMessage = indexMessage
+	})
+	return
+}
+
 func ${message_name}LoadIndexWith${index_key_name}(
 	ctx cd.AwaitableContext,
 	listIndex []uint64,
@@ -179,16 +234,15 @@ func ${message_name}LoadIndexWith${index_key_name}(
     ${field["ident"]} ${field["go_type"]},
 % endfor
 ) (indexMessage []pu.RedisListIndexMessage, retResult cd.RpcResult) {
-	dispatcher := libatapp.AtappGetModule[*cd.RedisMessageDispatcher](ctx.GetApp())
-	instance := dispatcher.GetRedisInstance()
-	if instance == nil {
-        ctx.LogError("get redis instance failed")
+	backend := GetStorageBackend(ctx.GetApp())
+	if backend == nil {
+        ctx.LogError("get db storage backend failed")
 		retResult = cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
 		return
 	}
 	${args_redis_key_call}
-	indexMessage, retResult = HashTableLoadListIndex(
-		ctx, index, "${message_name}", dispatcher, instance, func() proto.Message {
+	indexMessage, retResult = backend.HashTableLoadListIndex(
+		ctx, index, "${message_name}", func() proto.Message {
 			return new(private_protocol_pbdesc.${message_name})
 		}, listIndex)
 	if retResult.IsError() {
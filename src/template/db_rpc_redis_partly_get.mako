@@ -19,10 +19,9 @@ func ${message_name}LoadWith${index_meta["index_key_name"]}PartlyGet${partly_fie
 % else:
 ) (table *private_protocol_pbdesc.${message_name}, retResult cd.RpcResult) {
 % endif
-	dispatcher := libatapp.AtappGetModule[*cd.RedisMessageDispatcher](ctx.GetApp())
-	instance := dispatcher.GetRedisInstance()
-	if instance == nil {
-        ctx.LogError("get redis instance failed")
+	backend := GetStorageBackend(ctx.GetApp())
+	if backend == nil {
+        ctx.LogError("get db storage backend failed")
 		retResult = cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
 		return
 	}
@@ -44,11 +43,11 @@ func ${message_name}LoadWith${index_meta["index_key_name"]}PartlyGet${partly_fie
 	}
 	var message proto.Message
 % if cas_enabled:
-	message, CASVersion, retResult = HashTablePartlyGet(
+	message, CASVersion, retResult = backend.HashTablePartlyGet(
 % else:
-	message, _, retResult = HashTablePartlyGet(
+	message, _, retResult = backend.HashTablePartlyGet(
 % endif
-		ctx, index, "${message_name}", dispatcher, instance, func() proto.Message {
+		ctx, index, "${message_name}", func() proto.Message {
 			return new(private_protocol_pbdesc.${message_name})
 		}, redisField)
 	if retResult.IsError() {
@@ -74,10 +73,9 @@ func ${message_name}BatchLoadWith${index_meta["index_key_name"]}PartlyGet${partl
 	ctx cd.AwaitableContext,
 	keys []${message_name}TableKey,
 ) (dbResult []${message_name}BatchGetResult, retResult cd.RpcResult) {
-	dispatcher := libatapp.AtappGetModule[*cd.RedisMessageDispatcher](ctx.GetApp())
-	instance := dispatcher.GetRedisInstance()
-	if instance == nil {
-        ctx.LogError("get redis instance failed")
+	backend := GetStorageBackend(ctx.GetApp())
+	if backend == nil {
+        ctx.LogError("get db storage backend failed")
 		retResult = cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
 		return
 	}
@@ -97,8 +95,8 @@ func ${message_name}BatchLoadWith${index_meta["index_key_name"]}PartlyGet${partl
 % endfor
 	}
 	var messages []*RedisSetIndexMessage
-	messages, retResult = HashTableBatchPartlyGet(
-		ctx, loadIndexs, "${message_name}", dispatcher, instance, func() proto.Message {
+	messages, retResult = backend.HashTableBatchPartlyGet(
+		ctx, loadIndexs, "${message_name}", func() proto.Message {
 			return new(private_protocol_pbdesc.${message_name})
 		}, redisField)
 	dbResult = make([]${message_name}BatchGetResult, len(messages))
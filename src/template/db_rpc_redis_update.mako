@@ -13,17 +13,16 @@ func ${message_name}Add${index_meta["index_key_name"]}(
 	ctx cd.AwaitableContext,
     table *private_protocol_pbdesc.${message_name},
 ) (retResult cd.RpcResult, CASVersion uint64) {
-	dispatcher := libatapp.AtappGetModule[*cd.RedisMessageDispatcher](ctx.GetApp())
-	instance := dispatcher.GetRedisInstance()
-	if instance == nil {
-        ctx.LogError("get redis instance failed")
+	backend := GetStorageBackend(ctx.GetApp())
+	if backend == nil {
+        ctx.LogError("get db storage backend failed")
 		retResult = cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
 		return
 	}
 	${struct_redis_key_call}
 	CASVersion = 0
-	retResult = HashTableUpdateCAS(ctx, index, "${message_name}",
-		dispatcher, instance, table, &CASVersion, false)
+	retResult = backend.HashTableUpdateCAS(ctx, index, "${message_name}",
+		table, &CASVersion, false)
 	if retResult.IsError() {
 		if retResult.GetResponseCode() == int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_CAS_CHECK_FAILED) {
 			retResult = cd.CreateRpcResultError(fmt.Errorf("record exist"), public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_EXIST)
@@ -47,10 +46,9 @@ func ${message_name}Replace${index_meta["index_key_name"]}(
 	currentCASVersion *uint64,
 	forceUpdate bool,
 ) (retResult cd.RpcResult) {
-	dispatcher := libatapp.AtappGetModule[*cd.RedisMessageDispatcher](ctx.GetApp())
-	instance := dispatcher.GetRedisInstance()
-	if instance == nil {
-        ctx.LogError("get redis instance failed")
+	backend := GetStorageBackend(ctx.GetApp())
+	if backend == nil {
+        ctx.LogError("get db storage backend failed")
 		retResult = cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
 		return
 	}
@@ -60,8 +58,8 @@ func ${message_name}Replace${index_meta["index_key_name"]}(
 		retResult = cd.CreateRpcResultError(fmt.Errorf("currentCASVersion nil"), public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
 		return
 	}
-	retResult = HashTableUpdateCAS(ctx, index, "${message_name}",
-		dispatcher, instance, table, currentCASVersion, forceUpdate)
+	retResult = backend.HashTableUpdateCAS(ctx, index, "${message_name}",
+		table, currentCASVersion, forceUpdate)
 	if retResult.IsError() {
 		return
 	}
@@ -75,21 +73,36 @@ func ${message_name}Replace${index_meta["index_key_name"]}(
 	retResult = cd.CreateRpcResultOk()
 	return
 }
+
+// ${message_name}PipelineReplace${index_meta["index_key_name"]} 加入批量操作，Execute 之后 currentCASVersion 更新为数据库中的版本
+func ${message_name}PipelineReplace${index_meta["index_key_name"]}(
+	pipeline *Pipeline,
+    table *private_protocol_pbdesc.${message_name},
+	currentCASVersion *uint64,
+	forceUpdate bool,
+) (dbResult *PipelineResult) {
+	ctx := pipeline.GetContext()
+	${struct_redis_key_call}
+	dbResult = &PipelineResult{}
+	pipeline.HashTableUpdateCAS(index, "${message_name}", table, currentCASVersion, forceUpdate, func(result cd.RpcResult) {
+		dbResult.Result = result
+	})
+	return
+}
 % else:
 func ${message_name}Update${index_meta["index_key_name"]}(
 	ctx cd.AwaitableContext,
     table *private_protocol_pbdesc.${message_name},
 ) (retResult cd.RpcResult) {
-	dispatcher := libatapp.AtappGetModule[*cd.RedisMessageDispatcher](ctx.GetApp())
-	instance := dispatcher.GetRedisInstance()
-	if instance == nil {
-        ctx.LogError("get redis instance failed")
+	backend := GetStorageBackend(ctx.GetApp())
+	if backend == nil {
+        ctx.LogError("get db storage backend failed")
 		retResult = cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
 		return
 	}
 	${struct_redis_key_call}
-	retResult = HashTableUpdate(ctx, index, "${message_name}",
-		dispatcher, instance, table)
+	retResult = backend.HashTableUpdate(ctx, index, "${message_name}",
+		table)
 	if retResult.IsError() {
 		return
 	}
@@ -102,22 +115,35 @@ func ${message_name}Update${index_meta["index_key_name"]}(
 	retResult = cd.CreateRpcResultOk()
 	return
 }
+
+// ${message_name}PipelineUpdate${index_meta["index_key_name"]} 加入批量操作，结果在 pipeline.Execute() 之后有效
+func ${message_name}PipelineUpdate${index_meta["index_key_name"]}(
+	pipeline *Pipeline,
+    table *private_protocol_pbdesc.${message_name},
+) (dbResult *PipelineResult) {
+	ctx := pipeline.GetContext()
+	${struct_redis_key_call}
+	dbResult = &PipelineResult{}
+	pipeline.HashTableUpdate(index, "${message_name}", table, func(result cd.RpcResult) {
+		dbResult.Result = result
+	})
+	return
+}
 % endif
 % else:
 func ${message_name}Add${index_meta["index_key_name"]}(
 	ctx cd.AwaitableContext,
     table *private_protocol_pbdesc.${message_name},
 ) (retResult cd.RpcResult, newListIndex uint64) {
-	dispatcher := libatapp.AtappGetModule[*cd.RedisMessageDispatcher](ctx.GetApp())
-	instance := dispatcher.GetRedisInstance()
-	if instance == nil {
-        ctx.LogError("get redis instance failed")
+	backend := GetStorageBackend(ctx.GetApp())
+	if backend == nil {
+        ctx.LogError("get db storage backend failed")
 		retResult = cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
 		return
 	}
 	${struct_redis_key_call}
-	retResult, newListIndex = HashTableAddList(ctx, index, "${message_name}",
-		dispatcher, instance, table, ${max_list_length})
+	retResult, newListIndex = backend.HashTableAddList(ctx, index, "${message_name}",
+		table, ${max_list_length})
 	if retResult.IsError() {
 		if retResult.GetResponseCode() == int32(public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_CAS_CHECK_FAILED) {
 			retResult = cd.CreateRpcResultError(fmt.Errorf("record exist"), public_protocol_pbdesc.EnErrorCode_EN_ERR_DB_RECORD_EXIST)
@@ -139,16 +165,15 @@ func ${message_name}Update${index_meta["index_key_name"]}(
     table *private_protocol_pbdesc.${message_name},
 	listIndex uint64,
 ) (retResult cd.RpcResult) {
-	dispatcher := libatapp.AtappGetModule[*cd.RedisMessageDispatcher](ctx.GetApp())
-	instance := dispatcher.GetRedisInstance()
-	if instance == nil {
-        ctx.LogError("get redis instance failed")
+	backend := GetStorageBackend(ctx.GetApp())
+	if backend == nil {
+        ctx.LogError("get db storage backend failed")
 		retResult = cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
 		return
 	}
 	${struct_redis_key_call}
-	retResult = HashTableUpdateList(ctx, index, "${message_name}",
-		dispatcher, instance, table, listIndex)
+	retResult = backend.HashTableUpdateList(ctx, index, "${message_name}",
+		table, listIndex)
 	if retResult.IsError() {
 		return
 	}
@@ -162,4 +187,19 @@ func ${message_name}Update${index_meta["index_key_name"]}(
 	retResult = cd.CreateRpcResultOk()
 	return
 }
+
+// ${message_name}PipelineUpdate${index_meta["index_key_name"]} 加入批量操作，结果在 pipeline.Execute() 之后有效
+func ${message_name}PipelineUpdate${index_meta["index_key_name"]}(
+	pipeline *Pipeline,
+    table *private_protocol_pbdesc.${message_name},
+	listIndex uint64,
+) (dbResult *PipelineResult) {
+	ctx := pipeline.GetContext()
+	${struct_redis_key_call}
+	dbResult = &PipelineResult{}
+	pipeline.HashTableUpdateList(index, "${message_name}", table, listIndex, func(result cd.RpcResult) {
+		dbResult.Result = result
+	})
+	return
+}
 % endif
\ No newline at end of file
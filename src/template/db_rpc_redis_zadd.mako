@@ -17,16 +17,15 @@ func ${message_name}ZAddWith${index_key_name}(
 	existence ZAddExistenceOption,
 	comparison ZAddComparisonOption,
 ) (retResult cd.RpcResult) {
-	dispatcher := libatapp.AtappGetModule[*cd.RedisMessageDispatcher](ctx.GetApp())
-	instance := dispatcher.GetRedisInstance()
-	if instance == nil {
-        ctx.LogError("get redis instance failed")
+	backend := GetStorageBackend(ctx.GetApp())
+	if backend == nil {
+        ctx.LogError("get db storage backend failed")
 		retResult = cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
 		return
 	}
 	${args_redis_key_call}
-	retResult = SortedSetZAdd(ctx, index, "${message_name}",
-		dispatcher, instance, members, existence, comparison)
+	retResult = backend.SortedSetZAdd(ctx, index, "${message_name}",
+		members, existence, comparison)
 	if retResult.IsError() {
 		return
 	}
@@ -47,16 +46,15 @@ func ${message_name}ZAddIncrWith${index_key_name}(
 % endfor
 	member SortedSetMember,
 ) (result ZAddIncrResult, retResult cd.RpcResult) {
-	dispatcher := libatapp.AtappGetModule[*cd.RedisMessageDispatcher](ctx.GetApp())
-	instance := dispatcher.GetRedisInstance()
-	if instance == nil {
-        ctx.LogError("get redis instance failed")
+	backend := GetStorageBackend(ctx.GetApp())
+	if backend == nil {
+        ctx.LogError("get db storage backend failed")
 		retResult = cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
 		return
 	}
 	${args_redis_key_call}
-	result, retResult = SortedSetZAddIncr(ctx, index, "${message_name}",
-		dispatcher, instance, member)
+	result, retResult = backend.SortedSetZAddIncr(ctx, index, "${message_name}",
+		member)
 	if retResult.IsError() {
 		return
 	}
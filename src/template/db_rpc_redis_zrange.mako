@@ -15,16 +15,15 @@ func ${message_name}ZRangeByRankWith${index_key_name}(
 % endfor
 	start int64, stop int64, rev bool,
 ) (members []SortedSetRangeMember, retResult cd.RpcResult) {
-	dispatcher := libatapp.AtappGetModule[*cd.RedisMessageDispatcher](ctx.GetApp())
-	instance := dispatcher.GetRedisInstance()
-	if instance == nil {
-        ctx.LogError("get redis instance failed")
+	backend := GetStorageBackend(ctx.GetApp())
+	if backend == nil {
+        ctx.LogError("get db storage backend failed")
 		retResult = cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
 		return
 	}
 	${args_redis_key_call}
-	members, retResult = SortedSetZRangeByRank(ctx, index, "${message_name}",
-		dispatcher, instance, start, stop, rev)
+	members, retResult = backend.SortedSetZRangeByRank(ctx, index, "${message_name}",
+		start, stop, rev)
 	if retResult.IsError() {
 		return
 	}
@@ -50,16 +49,15 @@ func ${message_name}ZRangeByScoreWith${index_key_name}(
 % endfor
 	min SortedSetScoreBound, max SortedSetScoreBound, offset int64, count int64, rev bool,
 ) (members []SortedSetRangeMember, retResult cd.RpcResult) {
-	dispatcher := libatapp.AtappGetModule[*cd.RedisMessageDispatcher](ctx.GetApp())
-	instance := dispatcher.GetRedisInstance()
-	if instance == nil {
-        ctx.LogError("get redis instance failed")
+	backend := GetStorageBackend(ctx.GetApp())
+	if backend == nil {
+        ctx.LogError("get db storage backend failed")
 		retResult = cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
 		return
 	}
 	${args_redis_key_call}
-	members, retResult = SortedSetZRangeByScore(ctx, index, "${message_name}",
-		dispatcher, instance, min, max, offset, count, rev)
+	members, retResult = backend.SortedSetZRangeByScore(ctx, index, "${message_name}",
+		min, max, offset, count, rev)
 	if retResult.IsError() {
 		return
 	}
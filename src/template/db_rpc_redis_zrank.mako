@@ -14,16 +14,15 @@ func ${message_name}ZRankWith${index_key_name}(
 % endfor
 	member string, rev bool,
 ) (result SortedSetZRankResult, found bool, retResult cd.RpcResult) {
-	dispatcher := libatapp.AtappGetModule[*cd.RedisMessageDispatcher](ctx.GetApp())
-	instance := dispatcher.GetRedisInstance()
-	if instance == nil {
-        ctx.LogError("get redis instance failed")
+	backend := GetStorageBackend(ctx.GetApp())
+	if backend == nil {
+        ctx.LogError("get db storage backend failed")
 		retResult = cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
 		return
 	}
 	${args_redis_key_call}
-	result, found, retResult = SortedSetZRank(ctx, index, "${message_name}",
-		dispatcher, instance, member, rev)
+	result, found, retResult = backend.SortedSetZRank(ctx, index, "${message_name}",
+		member, rev)
 	if retResult.IsError() {
 		return
 	}
@@ -0,0 +1,36 @@
+## -*- coding: utf-8 -*-
+<%page args="message_name,message,index,index_meta" />
+<%
+    index_key_name = index_meta["index_key_name"]
+    args_redis_key_call = index_meta["args_redis_key_call"]
+    key_fields = index_meta["key_fields"]
+%>
+// ${message_name}ZRemWith${index_key_name} 从有序集合删除成员，返回实际删除的成员数
+func ${message_name}ZRemWith${index_key_name}(
+	ctx cd.AwaitableContext,
+% for field in key_fields:
+    ${field["ident"]} ${field["go_type"]},
+% endfor
+	members []string,
+) (removed int64, retResult cd.RpcResult) {
+	backend := GetStorageBackend(ctx.GetApp())
+	if backend == nil {
+        ctx.LogError("get db storage backend failed")
+		retResult = cd.CreateRpcResultError(nil, public_protocol_pbdesc.EnErrorCode_EN_ERR_SYSTEM)
+		return
+	}
+	${args_redis_key_call}
+	removed, retResult = backend.SortedSetZRem(ctx, index, "${message_name}",
+		members)
+	if retResult.IsError() {
+		return
+	}
+	ctx.LogDebug("zrem ${message_name} success",
+		"removed", removed,
+% for field in key_fields:
+		"${field["ident"]}", ${field["ident"]},
+% endfor
+	)
+	retResult = cd.CreateRpcResultOk()
+	return
+}
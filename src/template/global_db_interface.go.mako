@@ -17,8 +17,6 @@ import (
 	cd "github.com/atframework/atsf4g-go/component/dispatcher"
 	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
 	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
-
-	"github.com/atframework/libatapp-go"
 )
 <%
 file = database.get_file(generate_proto_file)
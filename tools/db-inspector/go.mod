@@ -0,0 +1,25 @@
+module github.com/atframework/atsf4g-go/tools/db-inspector
+
+go 1.25.1
+
+replace github.com/atframework/atsf4g-go/component => ../../src/component
+
+replace github.com/xresloader/xresloader => ../../third_party/xresloader/protocols/core
+
+replace github.com/xresloader/xres-code-generator => ../../third_party/xresloader/protocols/code
+
+replace github.com/atframework/atframe-utils-go => ../../atframework/atframe-utils-go
+
+replace github.com/atframework/libatapp-go => ../../atframework/libatapp-go
+
+require (
+	github.com/alicebob/miniredis/v2 v2.37.0
+	github.com/atframework/atframe-utils-go v1.0.5-0.20260416024202-66c04636f055
+	github.com/atframework/atsf4g-go/component v0.0.0-00010101000000-000000000000
+	github.com/redis/go-redis/v9 v9.18.0
+	github.com/stretchr/testify v1.11.1
+	google.golang.org/protobuf v1.36.11
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require github.com/yuin/gopher-lua v1.1.1 // indirect
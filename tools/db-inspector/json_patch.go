@@ -0,0 +1,214 @@
+// Copyright 2025 atframework
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// jsonPatchOperation RFC 6902 JSON Patch 操作.
+type jsonPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// applyJSONPatch 对 JSON 文档应用 RFC 6902 JSON Patch
+// 修数据只需要 add/remove/replace/test，move/copy 不支持.
+func applyJSONPatch(document []byte, patch []byte) ([]byte, error) {
+	var operations []jsonPatchOperation
+	if err := json.Unmarshal(patch, &operations); err != nil {
+		return nil, fmt.Errorf("invalid json patch: %w", err)
+	}
+
+	doc, err := decodeJSONValue(document)
+	if err != nil {
+		return nil, fmt.Errorf("invalid json document: %w", err)
+	}
+
+	for i, op := range operations {
+		doc, err = applyJSONPatchOperation(doc, &op)
+		if err != nil {
+			return nil, fmt.Errorf("json patch operation %d(%s %s) failed: %w", i, op.Op, op.Path, err)
+		}
+	}
+
+	return json.Marshal(doc)
+}
+
+func decodeJSONValue(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// 保留原始数字，避免 uint64 丢失精度
+	decoder.UseNumber()
+	var ret interface{}
+	if err := decoder.Decode(&ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func applyJSONPatchOperation(doc interface{}, op *jsonPatchOperation) (interface{}, error) {
+	switch op.Op {
+	case "add", "replace", "test":
+		if len(op.Value) == 0 {
+			return nil, fmt.Errorf("missing value")
+		}
+		value, err := decodeJSONValue(op.Value)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "test" {
+			current, err := getJSONPointer(doc, op.Path)
+			if err != nil {
+				return nil, err
+			}
+			if !reflect.DeepEqual(current, value) {
+				return nil, fmt.Errorf("test failed")
+			}
+			return doc, nil
+		}
+		return setJSONPointer(doc, op.Path, value, op.Op == "replace")
+	case "remove":
+		return removeJSONPointer(doc, op.Path)
+	default:
+		return nil, fmt.Errorf("unsupported op %q", op.Op)
+	}
+}
+
+// parseJSONPointer 按 RFC 6901 拆分路径.
+func parseJSONPointer(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("json pointer %q must start with /", path)
+	}
+
+	ret := strings.Split(path[1:], "/")
+	for i, token := range ret {
+		ret[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return ret, nil
+}
+
+func parseJSONArrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if allowEnd && token == "-" {
+		return length, nil
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	if index > length || (!allowEnd && index == length) {
+		return 0, fmt.Errorf("array index %d out of range", index)
+	}
+	return index, nil
+}
+
+func getJSONPointer(doc interface{}, path string) (interface{}, error) {
+	tokens, err := parseJSONPointer(path)
+	if err != nil {
+		return nil, err
+	}
+
+	current := doc
+	for _, token := range tokens {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("path %q not found", path)
+			}
+			current = value
+		case []interface{}:
+			index, err := parseJSONArrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			current = node[index]
+		default:
+			return nil, fmt.Errorf("path %q not found", path)
+		}
+	}
+	return current, nil
+}
+
+// setJSONPointer 设置路径上的值，返回新的根节点
+// mustExist 为 true 时对应 replace，目标必须已经存在.
+func setJSONPointer(doc interface{}, path string, value interface{}, mustExist bool) (interface{}, error) {
+	tokens, err := parseJSONPointer(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	parentPath := path[:strings.LastIndex(path, "/")]
+	parent, err := getJSONPointer(doc, parentPath)
+	if err != nil {
+		return nil, err
+	}
+
+	last := tokens[len(tokens)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		if _, ok := node[last]; mustExist && !ok {
+			return nil, fmt.Errorf("path %q not found", path)
+		}
+		node[last] = value
+		return doc, nil
+	case []interface{}:
+		index, err := parseJSONArrayIndex(last, len(node), !mustExist)
+		if err != nil {
+			return nil, err
+		}
+		if mustExist {
+			node[index] = value
+			return doc, nil
+		}
+		node = append(node[:index], append([]interface{}{value}, node[index:]...)...)
+		return setJSONPointer(doc, parentPath, node, true)
+	default:
+		return nil, fmt.Errorf("parent of %q is not an object or array", path)
+	}
+}
+
+func removeJSONPointer(doc interface{}, path string) (interface{}, error) {
+	tokens, err := parseJSONPointer(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("can not remove root")
+	}
+
+	parentPath := path[:strings.LastIndex(path, "/")]
+	parent, err := getJSONPointer(doc, parentPath)
+	if err != nil {
+		return nil, err
+	}
+
+	last := tokens[len(tokens)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		if _, ok := node[last]; !ok {
+			return nil, fmt.Errorf("path %q not found", path)
+		}
+		delete(node, last)
+		return doc, nil
+	case []interface{}:
+		index, err := parseJSONArrayIndex(last, len(node), false)
+		if err != nil {
+			return nil, err
+		}
+		node = append(node[:index], node[index+1:]...)
+		return setJSONPointer(doc, parentPath, node, true)
+	default:
+		return nil, fmt.Errorf("path %q not found", path)
+	}
+}
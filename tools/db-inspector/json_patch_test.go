@@ -0,0 +1,141 @@
+// Copyright 2025 atframework
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestApplyJSONPatch 测试 RFC 6902 各操作
+// Scenario: add/remove/replace/test 作用在对象和数组上的结果和 RFC 6902 一致.
+func TestApplyJSONPatch(t *testing.T) {
+	testCases := []struct {
+		name     string
+		document string
+		patch    string
+		expect   string
+	}{
+		{
+			name:     "add object member",
+			document: `{"a":1}`,
+			patch:    `[{"op":"add","path":"/b","value":2}]`,
+			expect:   `{"a":1,"b":2}`,
+		},
+		{
+			name:     "add replaces existing member",
+			document: `{"a":1}`,
+			patch:    `[{"op":"add","path":"/a","value":{"c":3}}]`,
+			expect:   `{"a":{"c":3}}`,
+		},
+		{
+			name:     "add array element in middle",
+			document: `{"list":[1,3]}`,
+			patch:    `[{"op":"add","path":"/list/1","value":2}]`,
+			expect:   `{"list":[1,2,3]}`,
+		},
+		{
+			name:     "add array element at end",
+			document: `{"list":[1,2]}`,
+			patch:    `[{"op":"add","path":"/list/-","value":3}]`,
+			expect:   `{"list":[1,2,3]}`,
+		},
+		{
+			name:     "replace nested member",
+			document: `{"user_data":{"user_level":1,"user_exp":10}}`,
+			patch:    `[{"op":"replace","path":"/user_data/user_level","value":30}]`,
+			expect:   `{"user_data":{"user_exp":10,"user_level":30}}`,
+		},
+		{
+			name:     "replace array element",
+			document: `{"list":[1,2,3]}`,
+			patch:    `[{"op":"replace","path":"/list/0","value":9}]`,
+			expect:   `{"list":[9,2,3]}`,
+		},
+		{
+			name:     "remove object member",
+			document: `{"a":1,"b":2}`,
+			patch:    `[{"op":"remove","path":"/a"}]`,
+			expect:   `{"b":2}`,
+		},
+		{
+			name:     "remove array element",
+			document: `{"list":[1,2,3]}`,
+			patch:    `[{"op":"remove","path":"/list/1"}]`,
+			expect:   `{"list":[1,3]}`,
+		},
+		{
+			name:     "test then replace",
+			document: `{"a":"x"}`,
+			patch:    `[{"op":"test","path":"/a","value":"x"},{"op":"replace","path":"/a","value":"y"}]`,
+			expect:   `{"a":"y"}`,
+		},
+		{
+			name:     "escaped pointer tokens",
+			document: `{"a/b":1,"c~d":2}`,
+			patch:    `[{"op":"replace","path":"/a~1b","value":3},{"op":"remove","path":"/c~0d"}]`,
+			expect:   `{"a/b":3}`,
+		},
+		{
+			name:     "replace root",
+			document: `{"a":1}`,
+			patch:    `[{"op":"replace","path":"","value":{"b":2}}]`,
+			expect:   `{"b":2}`,
+		},
+		{
+			name:     "uint64 keeps precision",
+			document: `{"user_id":"1"}`,
+			patch:    `[{"op":"add","path":"/cas","value":18446744073709551615}]`,
+			expect:   `{"cas":18446744073709551615,"user_id":"1"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			result, err := applyJSONPatch([]byte(tc.document), []byte(tc.patch))
+
+			// Assert
+			assert.NoError(t, err)
+			assert.JSONEq(t, tc.expect, string(result))
+		})
+	}
+}
+
+// TestApplyJSONPatchError 测试非法的 patch
+// Scenario: 路径不存在、越界、test 不匹配、未支持的操作等情况返回错误，不返回部分修改的结果.
+func TestApplyJSONPatchError(t *testing.T) {
+	testCases := []struct {
+		name     string
+		document string
+		patch    string
+	}{
+		{name: "invalid patch json", document: `{}`, patch: `{"op":"add"}`},
+		{name: "invalid document", document: `{`, patch: `[]`},
+		{name: "unknown op", document: `{}`, patch: `[{"op":"merge","path":"/a","value":1}]`},
+		{name: "missing value", document: `{}`, patch: `[{"op":"add","path":"/a"}]`},
+		{name: "pointer without slash", document: `{"a":1}`, patch: `[{"op":"remove","path":"a"}]`},
+		{name: "replace missing member", document: `{}`, patch: `[{"op":"replace","path":"/a","value":1}]`},
+		{name: "remove missing member", document: `{}`, patch: `[{"op":"remove","path":"/a"}]`},
+		{name: "remove root", document: `{}`, patch: `[{"op":"remove","path":""}]`},
+		{name: "add missing parent", document: `{}`, patch: `[{"op":"add","path":"/a/b","value":1}]`},
+		{name: "array index out of range", document: `{"list":[1]}`, patch: `[{"op":"add","path":"/list/2","value":1}]`},
+		{name: "replace array end", document: `{"list":[1]}`, patch: `[{"op":"replace","path":"/list/-","value":1}]`},
+		{name: "negative array index", document: `{"list":[1]}`, patch: `[{"op":"remove","path":"/list/-1"}]`},
+		{name: "test mismatch", document: `{"a":1}`, patch: `[{"op":"test","path":"/a","value":2}]`},
+		{name: "move unsupported", document: `{"a":1}`, patch: `[{"op":"move","from":"/a","path":"/b"}]`},
+		{name: "copy unsupported", document: `{"a":1}`, patch: `[{"op":"copy","from":"/a","path":"/b"}]`},
+		{name: "parent is scalar", document: `{"a":1}`, patch: `[{"op":"add","path":"/a/b","value":1}]`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			result, err := applyJSONPatch([]byte(tc.document), []byte(tc.patch))
+
+			// Assert
+			assert.Error(t, err)
+			assert.Nil(t, result)
+		})
+	}
+}
@@ -0,0 +1,276 @@
+// Copyright 2025 atframework
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var (
+	marshalOptions = protojson.MarshalOptions{
+		Multiline:     true,
+		Indent:        "  ",
+		UseProtoNames: true,
+	}
+	unmarshalOptions = protojson.UnmarshalOptions{}
+)
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: db-inspector [options] <command> <table> <keys...>\n\n")
+	fmt.Fprintf(out, "Commands:\n")
+	fmt.Fprintf(out, "  get <table> <keys...>                               Print record as json\n")
+	fmt.Fprintf(out, "  patch [-list-index N] [-dry-run] -patch <file> <table> <keys...>\n")
+	fmt.Fprintf(out, "                                                      Apply RFC 6902 json patch (add/remove/replace/test) and write back with CAS\n")
+	fmt.Fprintf(out, "  zone-merge [-dry-run] [-rank <src>=<dst>]... [-rank-policy max|sum] -source <zone_id> -target <zone_id>\n")
+	fmt.Fprintf(out, "                                                      Merge all data of source zone into target zone, servers must be stopped\n")
+	fmt.Fprintf(out, "                                                      and -timeout should be large enough for the whole zone\n\n")
+	fmt.Fprintf(out, "Tables: %s\n", tableNames())
+	fmt.Fprintf(out, "Json field names use proto names, e.g. /user_data/user_level\n\n")
+	fmt.Fprintf(out, "Options:\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	configPath := flag.String("config", "", "redis config yaml, same format as logic_redis_cfg (redis.yaml)")
+	addrs := flag.String("addrs", "", "redis addrs, split by comma, override config")
+	username := flag.String("username", "", "redis ACL username, override config")
+	password := flag.String("password", "", "redis password, override config")
+	prefix := flag.String("prefix", "", "record prefix, override config")
+	standalone := flag.Bool("standalone", false, "use standalone redis client instead of cluster client")
+	timeout := flag.Duration("timeout", 10*time.Second, "timeout of whole command")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() < 2 {
+		usage()
+		os.Exit(1)
+	}
+
+	cfg, err := loadRedisConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *addrs != "" {
+		cfg.Addrs = strings.Split(*addrs, ",")
+	}
+	if *username != "" {
+		cfg.Username = *username
+	}
+	if *password != "" {
+		cfg.Password = *password
+	}
+	if *prefix != "" {
+		cfg.RecordPrefix = *prefix
+	}
+	if *standalone {
+		cfg.ClusterMode = false
+	}
+
+	store, err := createDbStore(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer store.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	switch flag.Arg(0) {
+	case "get":
+		err = runGet(ctx, store, flag.Args()[1:])
+	case "patch":
+		err = runPatch(ctx, store, flag.Args()[1:])
+	case "zone-merge":
+		err = runZoneMerge(ctx, store, flag.Args()[1:])
+	default:
+		err = fmt.Errorf("unknown command %q", flag.Arg(0))
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func parseTableArgs(args []string) (*tableDesc, []protoreflect.Value, error) {
+	if len(args) < 1 {
+		return nil, nil, fmt.Errorf("table name is required, available tables: %s", tableNames())
+	}
+	desc := findTableDesc(args[0])
+	if desc == nil || desc.sortedSet {
+		return nil, nil, fmt.Errorf("unknown table %q, available tables: %s", args[0], tableNames())
+	}
+	keys, err := desc.parseKeys(args[1:])
+	if err != nil {
+		return nil, nil, err
+	}
+	return desc, keys, nil
+}
+
+func runGet(ctx context.Context, store *dbStore, args []string) error {
+	desc, keys, err := parseTableArgs(args)
+	if err != nil {
+		return err
+	}
+
+	if desc.listTable {
+		record, err := store.loadList(ctx, desc, keys)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("# key: %s, count: %d\n", record.key, len(record.items))
+		for _, item := range record.items {
+			fmt.Printf("# list_index: %d\n", item.ListIndex)
+			if err = printMessage(item.Table); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	record, err := store.loadKV(ctx, desc, keys)
+	if err != nil {
+		return err
+	}
+	if desc.casEnabled {
+		fmt.Printf("# key: %s, cas_version: %d\n", record.key, record.casVersion)
+	} else {
+		fmt.Printf("# key: %s\n", record.key)
+	}
+	return printMessage(record.table)
+}
+
+func runPatch(ctx context.Context, store *dbStore, args []string) error {
+	flags := flag.NewFlagSet("patch", flag.ContinueOnError)
+	patchPath := flags.String("patch", "", "json patch file, - for stdin")
+	listIndex := flags.Uint64("list-index", 0, "list index of item to patch, required by list table")
+	dryRun := flags.Bool("dry-run", false, "print patched record without writing")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *patchPath == "" {
+		return fmt.Errorf("-patch is required")
+	}
+
+	desc, keys, err := parseTableArgs(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	var patch []byte
+	if *patchPath == "-" {
+		patch, err = io.ReadAll(os.Stdin)
+	} else {
+		patch, err = os.ReadFile(*patchPath)
+	}
+	if err != nil {
+		return fmt.Errorf("read patch file failed: %w", err)
+	}
+
+	var (
+		kv        *kvRecord
+		list      *listRecord
+		origin    proto.Message
+		listFound bool
+	)
+	if desc.listTable {
+		if list, err = store.loadList(ctx, desc, keys); err != nil {
+			return err
+		}
+		for _, item := range list.items {
+			if item.ListIndex == *listIndex {
+				origin = item.Table
+				listFound = true
+				break
+			}
+		}
+		if !listFound {
+			return fmt.Errorf("list index %d not found in %s, use get to list all indexes", *listIndex, list.key)
+		}
+	} else {
+		if kv, err = store.loadKV(ctx, desc, keys); err != nil {
+			return err
+		}
+		origin = kv.table
+	}
+
+	patched, err := patchMessage(desc, origin, patch)
+	if err != nil {
+		return err
+	}
+	if err = desc.checkKeys(patched, keys); err != nil {
+		return err
+	}
+
+	if proto.Equal(origin, patched) {
+		fmt.Println("# nothing changed")
+		return nil
+	}
+
+	if *dryRun {
+		fmt.Println("# dry run, patched record:")
+		return printMessage(patched)
+	}
+
+	if err = store.checkUserOffline(ctx, desc.getUserId(origin), time.Now()); err != nil {
+		return err
+	}
+
+	if desc.listTable {
+		if err = store.saveListItem(ctx, list, *listIndex, patched); err != nil {
+			return err
+		}
+		fmt.Printf("# write %s list_index %d success\n", list.key, *listIndex)
+		return nil
+	}
+
+	newCASVersion, err := store.saveKV(ctx, desc, kv, patched)
+	if err != nil {
+		return err
+	}
+	if desc.casEnabled {
+		fmt.Printf("# write %s success, cas_version: %d => %d\n", kv.key, kv.casVersion, newCASVersion)
+	} else {
+		fmt.Printf("# write %s success\n", kv.key)
+	}
+	return nil
+}
+
+func patchMessage(desc *tableDesc, origin proto.Message, patch []byte) (proto.Message, error) {
+	document, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(origin)
+	if err != nil {
+		return nil, err
+	}
+
+	patchedDocument, err := applyJSONPatch(document, patch)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := desc.newMessage()
+	if err = unmarshalOptions.Unmarshal(patchedDocument, ret); err != nil {
+		return nil, fmt.Errorf("patched json can not convert to %s: %w", ret.ProtoReflect().Descriptor().FullName(), err)
+	}
+	return ret, nil
+}
+
+func printMessage(msg proto.Message) error {
+	data, err := marshalOptions.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
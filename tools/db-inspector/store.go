@@ -0,0 +1,423 @@
+// Copyright 2025 atframework
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	pu "github.com/atframework/atframe-utils-go/proto_utility"
+	storage "github.com/atframework/atsf4g-go/component/db/storage"
+	cd "github.com/atframework/atsf4g-go/component/dispatcher"
+	private_protocol_config "github.com/atframework/atsf4g-go/component/protocol/private/config/protocol/config"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	errRecordNotFound = errors.New("record not found")
+	errCASCheckFailed = errors.New("record changed since loaded, cas check failed")
+)
+
+// redisConfig 和 logic_redis_cfg 字段一致，可以直接使用部署配置中的 redis.yaml.
+type redisConfig struct {
+	Addrs        []string            `yaml:"addrs"`
+	Password     string              `yaml:"password"`
+	RecordPrefix string              `yaml:"record_prefix"`
+	RandomPrefix bool                `yaml:"random_prefix"`
+	ClusterMode  bool                `yaml:"cluster_mode"`
+	Username     string              `yaml:"username"`
+	Sentinel     redisSentinelConfig `yaml:"sentinel"`
+	TLS          redisTLSConfig      `yaml:"tls"`
+}
+
+// redisSentinelConfig 和 logic_redis_sentinel_cfg 字段一致.
+type redisSentinelConfig struct {
+	MasterName string `yaml:"master_name"`
+	Username   string `yaml:"username"`
+	Password   string `yaml:"password"`
+}
+
+// redisTLSConfig 和 logic_redis_tls_cfg 字段一致.
+type redisTLSConfig struct {
+	Enable             bool   `yaml:"enable"`
+	CaFile             string `yaml:"ca_file"`
+	CertFile           string `yaml:"cert_file"`
+	KeyFile            string `yaml:"key_file"`
+	ServerName         string `yaml:"server_name"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+// toProto 转为 logic_redis_tls_cfg，TLS 配置规则复用 RedisMessageDispatcher.
+func (c *redisTLSConfig) toProto() *private_protocol_config.LogicRedisTlsCfg {
+	return &private_protocol_config.LogicRedisTlsCfg{
+		Enable:             c.Enable,
+		CaFile:             c.CaFile,
+		CertFile:           c.CertFile,
+		KeyFile:            c.KeyFile,
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+}
+
+func loadRedisConfig(path string) (*redisConfig, error) {
+	ret := &redisConfig{ClusterMode: true}
+	if path == "" {
+		return ret, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// 兼容完整配置文件，优先取 redis 节点
+	wrapper := struct {
+		Redis *redisConfig `yaml:"redis"`
+	}{}
+	if err = yaml.Unmarshal(data, &wrapper); err == nil && wrapper.Redis != nil && len(wrapper.Redis.Addrs) > 0 {
+		return wrapper.Redis, nil
+	}
+
+	if err = yaml.Unmarshal(data, ret); err != nil {
+		return nil, fmt.Errorf("parse redis config %s failed: %w", path, err)
+	}
+	return ret, nil
+}
+
+func (c *redisConfig) getRecordPrefix() string {
+	if c.RecordPrefix != "" {
+		return c.RecordPrefix
+	}
+	if c.RandomPrefix {
+		// 和 RedisMessageDispatcher 一致，只有在服务器所在机器上执行才能得到同样的前缀
+		return cd.GetStableHostID()
+	}
+	return "default"
+}
+
+type dbStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+func createDbStore(cfg *redisConfig) (*dbStore, error) {
+	if len(cfg.Addrs) == 0 {
+		return nil, fmt.Errorf("redis addrs is empty")
+	}
+
+	tlsConfig, err := cd.CreateRedisTLSConfig(cfg.TLS.toProto().ToReadonly())
+	if err != nil {
+		return nil, err
+	}
+
+	// 优先级 Sentinel > 集群 > 单节点，和 RedisMessageDispatcher 一致
+	ret := &dbStore{prefix: cfg.getRecordPrefix()}
+	if cfg.Sentinel.MasterName != "" {
+		ret.client = redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       cfg.Sentinel.MasterName,
+			SentinelAddrs:    cfg.Addrs,
+			SentinelUsername: cfg.Sentinel.Username,
+			SentinelPassword: cfg.Sentinel.Password,
+			Username:         cfg.Username,
+			Password:         cfg.Password,
+			TLSConfig:        tlsConfig,
+		})
+	} else if cfg.ClusterMode {
+		ret.client = redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:     cfg.Addrs,
+			Username:  cfg.Username,
+			Password:  cfg.Password,
+			TLSConfig: tlsConfig,
+		})
+	} else {
+		ret.client = redis.NewClient(&redis.Options{
+			Addr:      cfg.Addrs[0],
+			Username:  cfg.Username,
+			Password:  cfg.Password,
+			TLSConfig: tlsConfig,
+		})
+	}
+	return ret, nil
+}
+
+func (s *dbStore) Close() error {
+	return s.client.Close()
+}
+
+// kvRecord KV 表读取结果，raw 用于写回时检查数据是否被修改.
+type kvRecord struct {
+	key        string
+	table      proto.Message
+	casVersion uint64
+	raw        map[string]string
+}
+
+// getTable 记录不存在时返回 nil.
+func (r *kvRecord) getTable() proto.Message {
+	if r == nil {
+		return nil
+	}
+	return r.table
+}
+
+// listRecord KL 表读取结果.
+type listRecord struct {
+	key   string
+	items []pu.RedisListIndexMessage
+	raw   map[string]string
+}
+
+func (s *dbStore) loadKV(ctx context.Context, desc *tableDesc, keys []protoreflect.Value) (*kvRecord, error) {
+	key := desc.recordKey(s.prefix, keys)
+	raw, err := s.client.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("%w: %s", errRecordNotFound, key)
+	}
+
+	table := desc.newMessage()
+	casVersion, err := pu.RedisKVMapToPB(raw, table)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s failed: %w", key, err)
+	}
+	desc.fillKeys(table, keys)
+
+	return &kvRecord{key: key, table: table, casVersion: casVersion, raw: raw}, nil
+}
+
+func (s *dbStore) loadList(ctx context.Context, desc *tableDesc, keys []protoreflect.Value) (*listRecord, error) {
+	key := desc.recordKey(s.prefix, keys)
+	raw, err := s.client.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("%w: %s", errRecordNotFound, key)
+	}
+
+	items, err := pu.RedisKLMapToPB(raw, desc.newMessage)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s failed: %w", key, err)
+	}
+	for _, item := range items {
+		desc.fillKeys(item.Table, keys)
+	}
+
+	return &listRecord{key: key, items: items, raw: raw}, nil
+}
+
+// saveKV 使用 WATCH 做 CAS 写回，读取后数据有任何变化都会失败
+// 开启了 CAS 的表同时递增版本号，服务器上持有旧版本号的进程后续存盘会冲突重试.
+func (s *dbStore) saveKV(ctx context.Context, desc *tableDesc, record *kvRecord, table proto.Message) (uint64, error) {
+	var args []string
+	newCASVersion := record.casVersion
+	if desc.casEnabled {
+		expectVersion := record.casVersion
+		args = storage.FlattenArgs(pu.PBMapToRedisKV(table, &expectVersion, false))
+		// 和 CASLuaScript 一致，第一个字段是版本号
+		newCASVersion = record.casVersion + 1
+		args[1] = strconv.FormatUint(newCASVersion, 10)
+	} else {
+		args = storage.FlattenArgs(pu.PBMapToRedisKV(table, nil, false))
+	}
+
+	newFields := make(map[string]struct{}, len(args)/2)
+	for i := 0; i+1 < len(args); i += 2 {
+		newFields[args[i]] = struct{}{}
+	}
+	removedFields := make([]string, 0)
+	for field := range record.raw {
+		if _, ok := newFields[field]; !ok {
+			removedFields = append(removedFields, field)
+		}
+	}
+
+	values := make([]interface{}, 0, len(args))
+	for _, arg := range args {
+		values = append(values, arg)
+	}
+
+	err := s.client.Watch(ctx, func(tx *redis.Tx) error {
+		current, err := tx.HGetAll(ctx, record.key).Result()
+		if err != nil {
+			return err
+		}
+		if !maps.Equal(current, record.raw) {
+			return errCASCheckFailed
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			if len(removedFields) > 0 {
+				pipe.HDel(ctx, record.key, removedFields...)
+			}
+			pipe.HSet(ctx, record.key, values...)
+			return nil
+		})
+		return err
+	}, record.key)
+	if errors.Is(err, redis.TxFailedErr) {
+		return 0, errCASCheckFailed
+	}
+	if err != nil {
+		return 0, err
+	}
+	return newCASVersion, nil
+}
+
+// saveListItem 写回 KL 表中的一项，同样使用 WATCH 检查这一项没有被修改.
+func (s *dbStore) saveListItem(ctx context.Context, record *listRecord, listIndex uint64, table proto.Message) error {
+	field := strconv.FormatUint(listIndex, 10)
+	oldValue, exists := record.raw[field]
+	if !exists {
+		return fmt.Errorf("list index %d not found in %s", listIndex, record.key)
+	}
+
+	args := storage.FlattenArgs(pu.PBMapToRedisKLUpdate(table, listIndex))
+	values := make([]interface{}, 0, len(args))
+	for _, arg := range args {
+		values = append(values, arg)
+	}
+
+	err := s.client.Watch(ctx, func(tx *redis.Tx) error {
+		current, err := tx.HGet(ctx, record.key, field).Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				return errCASCheckFailed
+			}
+			return err
+		}
+		if current != oldValue {
+			return errCASCheckFailed
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, record.key, values...)
+			return nil
+		})
+		return err
+	}, record.key)
+	if errors.Is(err, redis.TxFailedErr) {
+		return errCASCheckFailed
+	}
+	return err
+}
+
+// checkUserOffline 登入锁有效时玩家数据由服务器持有，不允许离线修改.
+func (s *dbStore) checkUserOffline(ctx context.Context, userId uint64, now time.Time) error {
+	if userId == 0 {
+		return nil
+	}
+
+	loginLockDesc := findTableDesc("login_lock")
+	record, err := s.loadKV(ctx, loginLockDesc, []protoreflect.Value{protoreflect.ValueOfUint64(userId)})
+	if err != nil {
+		if errors.Is(err, errRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	loginLock := record.table.(*private_protocol_pbdesc.DatabaseTableLoginLock)
+	if loginLock.GetRouterServerId() != 0 && now.Unix() < loginLock.GetLoginExpired() {
+		return fmt.Errorf("user %d is online on server 0x%x until %s, kick off the user first",
+			userId, loginLock.GetRouterServerId(), time.Unix(loginLock.GetLoginExpired(), 0).Format(time.DateTime))
+	}
+	return nil
+}
+
+// scanKeys 遍历匹配的 Key，集群模式下需要遍历所有主节点.
+func (s *dbStore) scanKeys(ctx context.Context, pattern string) ([]string, error) {
+	var (
+		mu  sync.Mutex
+		ret []string
+	)
+	scanNode := func(ctx context.Context, client redis.Cmdable) error {
+		var cursor uint64
+		for {
+			keys, next, err := client.Scan(ctx, cursor, pattern, 512).Result()
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			ret = append(ret, keys...)
+			mu.Unlock()
+			if next == 0 {
+				return nil
+			}
+			cursor = next
+		}
+	}
+
+	var err error
+	if cluster, ok := s.client.(*redis.ClusterClient); ok {
+		err = cluster.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+			return scanNode(ctx, client)
+		})
+	} else {
+		err = scanNode(ctx, s.client)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(ret)
+	return ret, nil
+}
+
+// putKV 写入 KV 记录，记录已存在时在原有 CAS 版本上递增.
+func (s *dbStore) putKV(ctx context.Context, desc *tableDesc, keys []protoreflect.Value, table proto.Message) error {
+	record, err := s.loadKV(ctx, desc, keys)
+	if errors.Is(err, errRecordNotFound) {
+		record = &kvRecord{key: desc.recordKey(s.prefix, keys), raw: map[string]string{}}
+	} else if err != nil {
+		return err
+	}
+	_, err = s.saveKV(ctx, desc, record, table)
+	return err
+}
+
+// moveRawHash 原样搬迁整个 Hash，用于主键只有 Key 中不同的 KL 表
+// 源和目标可能不在同一个 slot，无法使用事务，先写目标再删源
+// 上次执行在删源之前中断时目标已包含源的全部字段，这时只删源，所以可以重复执行
+// 目标已存在且内容不同时拒绝覆盖.
+func (s *dbStore) moveRawHash(ctx context.Context, srcKey string, dstKey string) (int, error) {
+	raw, err := s.client.HGetAll(ctx, srcKey).Result()
+	if err != nil {
+		return 0, err
+	}
+	if len(raw) == 0 {
+		return 0, nil
+	}
+
+	dstRaw, err := s.client.HGetAll(ctx, dstKey).Result()
+	if err != nil {
+		return 0, err
+	}
+	if len(dstRaw) > 0 {
+		for field, value := range raw {
+			if dstValue, ok := dstRaw[field]; !ok || dstValue != value {
+				return 0, fmt.Errorf("%s already exists, can not move %s", dstKey, srcKey)
+			}
+		}
+	} else {
+		values := make([]interface{}, 0, len(raw)*2)
+		for field, value := range raw {
+			values = append(values, field, value)
+		}
+		if err = s.client.HSet(ctx, dstKey, values...).Err(); err != nil {
+			return 0, err
+		}
+	}
+	return len(raw), s.client.Del(ctx, srcKey).Err()
+}
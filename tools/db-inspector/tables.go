@@ -0,0 +1,195 @@
+// Copyright 2025 atframework
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	private_protocol_extension "github.com/atframework/atsf4g-go/component/protocol/private/extension/protocol/extension"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// tableDesc 从 pbdesc 中 atframework.database_table 的 index 定义生成，和 db 模板使用同一份定义.
+type tableDesc struct {
+	name       string
+	indexName  string
+	keyFields  []string
+	casEnabled bool
+	listTable  bool
+	sortedSet  bool
+	newMessage func() proto.Message
+}
+
+// tableDescs 启动时从 private_protocol_pbdesc 注册的描述信息中收集，新增表不需要修改这里.
+var tableDescs = collectTableDescs()
+
+func collectTableDescs() []*tableDesc {
+	ret := make([]*tableDesc, 0)
+	packageName := (&private_protocol_pbdesc.DatabaseTableUser{}).ProtoReflect().Descriptor().ParentFile().Package()
+	protoregistry.GlobalFiles.RangeFilesByPackage(packageName, func(fd protoreflect.FileDescriptor) bool {
+		messages := fd.Messages()
+		for i := 0; i < messages.Len(); i++ {
+			ret = append(ret, buildTableDescs(messages.Get(i))...)
+		}
+		return true
+	})
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].name < ret[j].name
+	})
+	return ret
+}
+
+// buildTableDescs 只收集 database_table_ 开头的消息，同名 index 的旧结构(比如 table_user_async_jobs)不会被当成表.
+func buildTableDescs(md protoreflect.MessageDescriptor) []*tableDesc {
+	if !strings.HasPrefix(string(md.Name()), "database_table_") {
+		return nil
+	}
+	options, ok := md.Options().(*descriptorpb.MessageOptions)
+	if !ok || !proto.HasExtension(options, private_protocol_extension.E_DatabaseTable) {
+		return nil
+	}
+	tableOptions, ok := proto.GetExtension(options, private_protocol_extension.E_DatabaseTable).(*private_protocol_extension.DatabaseTableOptions)
+	if !ok {
+		return nil
+	}
+	messageType, err := protoregistry.GlobalTypes.FindMessageByName(md.FullName())
+	if err != nil {
+		return nil
+	}
+
+	ret := make([]*tableDesc, 0, len(tableOptions.GetIndex()))
+	for _, index := range tableOptions.GetIndex() {
+		ret = append(ret, &tableDesc{
+			name:       index.GetName(),
+			indexName:  index.GetName(),
+			keyFields:  index.GetKeyFields(),
+			casEnabled: index.GetEnableCas(),
+			listTable:  index.GetType() == private_protocol_extension.DatabaseIndexType_EN_ATFRAMEWORK_DB_INDEX_TYPE_KL,
+			sortedSet:  index.GetType() == private_protocol_extension.DatabaseIndexType_EN_ATFRAMEWORK_DB_INDEX_TYPE_SORTED_SET,
+			newMessage: func() proto.Message { return messageType.New().Interface() },
+		})
+	}
+	return ret
+}
+
+func findTableDesc(name string) *tableDesc {
+	for _, desc := range tableDescs {
+		if desc.name == name {
+			return desc
+		}
+	}
+	return nil
+}
+
+// tableNames 有序集合表不能用 get/patch 查看，不列出来.
+func tableNames() string {
+	names := make([]string, 0, len(tableDescs))
+	for _, desc := range tableDescs {
+		if desc.sortedSet {
+			continue
+		}
+		names = append(names, desc.name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// parseKeys 按主键字段类型解析命令行参数.
+func (t *tableDesc) parseKeys(args []string) ([]protoreflect.Value, error) {
+	if len(args) != len(t.keyFields) {
+		return nil, fmt.Errorf("table %s requires keys: %s", t.name, strings.Join(t.keyFields, " "))
+	}
+
+	fields := t.newMessage().ProtoReflect().Descriptor().Fields()
+	ret := make([]protoreflect.Value, 0, len(args))
+	for i, fieldName := range t.keyFields {
+		fd := fields.ByName(protoreflect.Name(fieldName))
+		if fd == nil {
+			return nil, fmt.Errorf("key field %s not found in table %s", fieldName, t.name)
+		}
+
+		var value protoreflect.Value
+		switch fd.Kind() {
+		case protoreflect.StringKind:
+			value = protoreflect.ValueOfString(args[i])
+		case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+			v, err := strconv.ParseInt(args[i], 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", fieldName, err)
+			}
+			value = protoreflect.ValueOfInt32(int32(v))
+		case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+			v, err := strconv.ParseInt(args[i], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", fieldName, err)
+			}
+			value = protoreflect.ValueOfInt64(v)
+		case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+			v, err := strconv.ParseUint(args[i], 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", fieldName, err)
+			}
+			value = protoreflect.ValueOfUint32(uint32(v))
+		case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+			v, err := strconv.ParseUint(args[i], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", fieldName, err)
+			}
+			value = protoreflect.ValueOfUint64(v)
+		default:
+			return nil, fmt.Errorf("unsupported key field type %s of %s", fd.Kind(), fieldName)
+		}
+		ret = append(ret, value)
+	}
+	return ret, nil
+}
+
+// recordKey 生成和 db 模板 ${message_name}RedisKeyWith${index_key_name} 一致的 Key.
+func (t *tableDesc) recordKey(prefix string, keys []protoreflect.Value) string {
+	var sb strings.Builder
+	sb.WriteString(prefix)
+	sb.WriteString("-")
+	sb.WriteString(t.indexName)
+	for _, key := range keys {
+		sb.WriteString(".")
+		sb.WriteString(fmt.Sprint(key.Interface()))
+	}
+	return sb.String()
+}
+
+// fillKeys 从 Redis 读取的数据不包含主键，需要补回去.
+func (t *tableDesc) fillKeys(msg proto.Message, keys []protoreflect.Value) {
+	reflect := msg.ProtoReflect()
+	fields := reflect.Descriptor().Fields()
+	for i, fieldName := range t.keyFields {
+		reflect.Set(fields.ByName(protoreflect.Name(fieldName)), keys[i])
+	}
+}
+
+// checkKeys 不允许通过 patch 修改主键.
+func (t *tableDesc) checkKeys(msg proto.Message, keys []protoreflect.Value) error {
+	reflect := msg.ProtoReflect()
+	fields := reflect.Descriptor().Fields()
+	for i, fieldName := range t.keyFields {
+		if !reflect.Get(fields.ByName(protoreflect.Name(fieldName))).Equal(keys[i]) {
+			return fmt.Errorf("key field %s can not be modified", fieldName)
+		}
+	}
+	return nil
+}
+
+// getUserId 获取记录所属玩家，用于检查登入锁，不属于玩家的表返回0.
+func (t *tableDesc) getUserId(msg proto.Message) uint64 {
+	reflect := msg.ProtoReflect()
+	fd := reflect.Descriptor().Fields().ByName("user_id")
+	if fd == nil || fd.Kind() != protoreflect.Uint64Kind {
+		return 0
+	}
+	return reflect.Get(fd).Uint()
+}
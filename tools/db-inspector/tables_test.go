@@ -0,0 +1,73 @@
+// Copyright 2025 atframework
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	db "github.com/atframework/atsf4g-go/component/db"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	test_utility "github.com/atframework/atsf4g-go/component/test_utility"
+)
+
+// TestCollectTableDescs 测试从 atframework.database_table 生成表描述
+// Scenario: 主键、CAS、KL 和有序集合类型都和 proto 中的 index 定义一致.
+func TestCollectTableDescs(t *testing.T) {
+	testCases := []struct {
+		name       string
+		keyFields  []string
+		casEnabled bool
+		listTable  bool
+		sortedSet  bool
+		message    string
+	}{
+		{name: "user", keyFields: []string{"zone_id", "user_id"}, casEnabled: true, message: "database_table_user"},
+		{name: "access", keyFields: []string{"open_id"}, message: "database_table_access"},
+		{name: "async_jobs", keyFields: []string{"job_type", "user_id", "zone_id"}, listTable: true, message: "database_table_user_async_jobs"},
+		{name: "user_snapshot", keyFields: []string{"zone_id", "user_id"}, listTable: true, message: "database_table_user_snapshot"},
+		{name: "uuid_allocator", keyFields: []string{"major_type", "minor_type", "path_type"}, message: "database_table_uuid_allocator"},
+		{name: "rank", keyFields: []string{"id", "type", "version"}, sortedSet: true, message: "database_table_rank"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			desc := findTableDesc(tc.name)
+
+			// Assert
+			if !assert.NotNil(t, desc) {
+				return
+			}
+			assert.Equal(t, tc.name, desc.indexName)
+			assert.Equal(t, tc.keyFields, desc.keyFields)
+			assert.Equal(t, tc.casEnabled, desc.casEnabled)
+			assert.Equal(t, tc.listTable, desc.listTable)
+			assert.Equal(t, tc.sortedSet, desc.sortedSet)
+			assert.Equal(t, protoreflect.Name(tc.message), desc.newMessage().ProtoReflect().Descriptor().Name())
+		})
+	}
+
+	assert.NotContains(t, tableNames(), "rank")
+}
+
+// TestTableDescRecordKey 测试生成的 Key 和 db 组件一致
+// Scenario: 同一个前缀下 recordKey 和生成代码中的 RedisKeyWith 结果相同.
+func TestTableDescRecordKey(t *testing.T) {
+	// Arrange
+	app, _ := test_utility.CreateMemoryStorageApp()
+	ctx := test_utility.NewMockRpcContext(app)
+	prefix := db.GetStorageRecordPrefix(app)
+
+	// Act
+	userKeys, userErr := findTableDesc("user").parseKeys([]string{"3", "10001"})
+	accessKeys, accessErr := findTableDesc("access").parseKeys([]string{"open-id"})
+
+	// Assert
+	assert.NoError(t, userErr)
+	assert.NoError(t, accessErr)
+	assert.Equal(t, db.DatabaseTableUserRedisKeyWithZoneIdUserId(ctx, 3, 10001), findTableDesc("user").recordKey(prefix, userKeys))
+	assert.Equal(t, db.DatabaseTableAccessRedisKeyWithOpenId(ctx, "open-id"), findTableDesc("access").recordKey(prefix, accessKeys))
+	assert.IsType(t, &private_protocol_pbdesc.DatabaseTableUser{}, findTableDesc("user").newMessage())
+}
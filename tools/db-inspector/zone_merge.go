@@ -0,0 +1,905 @@
+// Copyright 2026 atframework
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	private_protocol_common "github.com/atframework/atsf4g-go/component/protocol/private/common/protocol/common"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	uuid "github.com/atframework/atsf4g-go/component/uuid"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// uuidAutoIncField uuid_allocator 表中保存区块 base 的字段.
+const uuidAutoIncField = "auto_inc_id"
+
+// 有序集合表只在合服时使用.
+var (
+	rankKeyDesc                 = findTableDesc("rank")
+	accountDeletionQueueKeyDesc = findTableDesc("account_deletion_queue")
+)
+
+// rankMergePair 原大区排行榜 => 合并后的排行榜.
+type rankMergePair struct {
+	source []protoreflect.Value
+	target []protoreflect.Value
+}
+
+type rankMergeFlag []rankMergePair
+
+func (f *rankMergeFlag) String() string {
+	return fmt.Sprintf("%d rank(s)", len(*f))
+}
+
+func (f *rankMergeFlag) Set(value string) error {
+	parts := strings.Split(value, "=")
+	if len(parts) != 2 {
+		return fmt.Errorf("rank should be <id>.<type>.<version>=<id>.<type>.<version>, got %q", value)
+	}
+	source, err := rankKeyDesc.parseKeys(strings.Split(parts[0], "."))
+	if err != nil {
+		return err
+	}
+	target, err := rankKeyDesc.parseKeys(strings.Split(parts[1], "."))
+	if err != nil {
+		return err
+	}
+	*f = append(*f, rankMergePair{source: source, target: target})
+	return nil
+}
+
+type zoneMergeStats struct {
+	users            int
+	renamedUsers     int
+	rewrittenMails   int
+	asyncJobs        int
+	snapshots        int
+	globalMails      int
+	duplicatedMails  int
+	deletionQueue    int
+	rankMembers      int
+	leftNameMappings int
+	guilds           int
+	renamedGuilds    int
+	chatOffline      int
+}
+
+// globalMailMergePlan 一个邮件类型合并后的目标数据，玩家数据搬迁完后才写入.
+type globalMailMergePlan struct {
+	sourceKey  string
+	targetKeys []protoreflect.Value
+	target     *private_protocol_pbdesc.DatabaseTableGlobalMail
+}
+
+// zoneMerger 把原大区的数据整体并入目标大区
+// 执行前需要停服，所有写入都假设没有服务器进程同时修改这两个大区的数据
+// 中途失败可以直接重新执行，已经搬迁的玩家会被跳过.
+type zoneMerger struct {
+	store        *dbStore
+	sourceZoneId uint32
+	targetZoneId uint32
+	ranks        rankMergeFlag
+	rankPolicy   string
+	redirect     bool
+	dryRun       bool
+	now          time.Time
+
+	// 原大区中和目标大区内容相同的全服邮件ID => 目标大区保留的邮件ID
+	duplicatedMails map[int64]int64
+	globalMailPlans []*globalMailMergePlan
+	maxUserId       uint64
+	maxMailId       int64
+	stats           zoneMergeStats
+}
+
+func runZoneMerge(ctx context.Context, store *dbStore, args []string) error {
+	merger := &zoneMerger{
+		store:           store,
+		now:             time.Now(),
+		duplicatedMails: make(map[int64]int64),
+	}
+
+	flags := flag.NewFlagSet("zone-merge", flag.ContinueOnError)
+	sourceZoneId := flags.Uint("source", 0, "zone id to be merged")
+	targetZoneId := flags.Uint("target", 0, "zone id to merge into")
+	flags.Var(&merger.ranks, "rank", "rank to merge, <id>.<type>.<version>=<id>.<type>.<version>, can be repeated")
+	flags.StringVar(&merger.rankPolicy, "rank-policy", "max", "score of member in both ranks: max or sum")
+	flags.BoolVar(&merger.redirect, "redirect", true, "write user_zone_redirect for moved users")
+	flags.BoolVar(&merger.dryRun, "dry-run", false, "print what will be merged without writing")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *sourceZoneId == 0 || *targetZoneId == 0 || *sourceZoneId == *targetZoneId {
+		return fmt.Errorf("-source and -target must be different non-zero zone ids")
+	}
+	if merger.rankPolicy != "max" && merger.rankPolicy != "sum" {
+		return fmt.Errorf("unknown -rank-policy %q", merger.rankPolicy)
+	}
+	merger.sourceZoneId = uint32(*sourceZoneId)
+	merger.targetZoneId = uint32(*targetZoneId)
+
+	return merger.run(ctx)
+}
+
+func (m *zoneMerger) run(ctx context.Context) error {
+	userIds, err := m.collectUsers(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("# merge zone %d into zone %d, %d user(s)\n", m.sourceZoneId, m.targetZoneId, len(userIds))
+
+	// 有任何玩家在线都不开始，避免只搬了一半
+	for _, userId := range userIds {
+		if err = m.store.checkUserOffline(ctx, userId, m.now); err != nil {
+			return err
+		}
+	}
+
+	// 先算出全服邮件的去重结果，搬迁玩家时要改写玩家身上引用的邮件ID
+	if err = m.planGlobalMails(ctx); err != nil {
+		return err
+	}
+
+	for _, userId := range userIds {
+		if err = m.mergeUser(ctx, userId); err != nil {
+			return fmt.Errorf("merge user %d failed: %w", userId, err)
+		}
+	}
+
+	steps := []func(context.Context) error{
+		m.mergeAsyncJobs,
+		m.mergeUserSnapshots,
+		m.mergeChatOfflineMessages,
+		m.mergeGuilds,
+		m.applyGlobalMails,
+		m.mergeDeletionQueue,
+		m.mergeRanks,
+		m.mergeUUIDAllocators,
+		m.checkLeftNameMappings,
+	}
+	for _, step := range steps {
+		if err = step(ctx); err != nil {
+			return err
+		}
+	}
+
+	if m.dryRun {
+		fmt.Println("# dry run, nothing written")
+	}
+	fmt.Printf("# users: %d, renamed: %d, rewritten mail records: %d\n", m.stats.users, m.stats.renamedUsers, m.stats.rewrittenMails)
+	fmt.Printf("# async_jobs: %d, user_snapshot: %d, account_deletion_queue: %d\n", m.stats.asyncJobs, m.stats.snapshots, m.stats.deletionQueue)
+	fmt.Printf("# global mails: %d, duplicated: %d, rank members: %d\n", m.stats.globalMails, m.stats.duplicatedMails, m.stats.rankMembers)
+	fmt.Printf("# guilds: %d, renamed: %d, chat_offline_message: %d\n", m.stats.guilds, m.stats.renamedGuilds, m.stats.chatOffline)
+	if m.stats.leftNameMappings > 0 {
+		fmt.Printf("# %d name mapping(s) not owned by any moved user are left in zone %d\n", m.stats.leftNameMappings, m.sourceZoneId)
+	}
+	return nil
+}
+
+func (m *zoneMerger) zoneKeyPrefix(indexName string, zoneId uint32) string {
+	return fmt.Sprintf("%s-%s.%d.", m.store.prefix, indexName, zoneId)
+}
+
+func (m *zoneMerger) collectUsers(ctx context.Context) ([]uint64, error) {
+	keyPrefix := m.zoneKeyPrefix("user", m.sourceZoneId)
+	keys, err := m.store.scanKeys(ctx, keyPrefix+"*")
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]uint64, 0, len(keys))
+	for _, key := range keys {
+		userId, err := strconv.ParseUint(strings.TrimPrefix(key, keyPrefix), 10, 64)
+		if err != nil {
+			fmt.Printf("# skip unknown key %s\n", key)
+			continue
+		}
+		ret = append(ret, userId)
+	}
+	return ret, nil
+}
+
+func (m *zoneMerger) loadMailContent(ctx context.Context, mailId int64) (*public_protocol_pbdesc.DMailContent, error) {
+	record, err := m.store.loadKV(ctx, findTableDesc("mail_content"), []protoreflect.Value{protoreflect.ValueOfInt64(mailId)})
+	if err != nil {
+		if errors.Is(err, errRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return record.table.(*private_protocol_pbdesc.DatabaseTableMailContent).GetJobData().GetMailContent(), nil
+}
+
+// mailContentFingerprint 除了邮件ID以外完全相同的全服邮件视为同一封，一般是分别向两个大区发送的同一个运营邮件.
+func mailContentFingerprint(content *public_protocol_pbdesc.DMailContent) string {
+	clone := proto.Clone(content).(*public_protocol_pbdesc.DMailContent)
+	clone.MailId = 0
+	data, _ := proto.MarshalOptions{Deterministic: true}.Marshal(clone)
+	return string(data)
+}
+
+// planGlobalMails 合并后原大区的全服邮件对所有玩家可见，重复的邮件保留目标大区的那一封
+// 被去重的邮件放入待移除队列，由服务器清理邮件内容.
+func (m *zoneMerger) planGlobalMails(ctx context.Context) error {
+	desc := findTableDesc("global_mail")
+	keyPrefix := m.zoneKeyPrefix(desc.indexName, m.sourceZoneId)
+	keys, err := m.store.scanKeys(ctx, keyPrefix+"*")
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		majorType, err := strconv.ParseInt(strings.TrimPrefix(key, keyPrefix), 10, 32)
+		if err != nil {
+			fmt.Printf("# skip unknown key %s\n", key)
+			continue
+		}
+
+		source, err := m.store.loadKV(ctx, desc, []protoreflect.Value{
+			protoreflect.ValueOfUint32(m.sourceZoneId), protoreflect.ValueOfInt32(int32(majorType)),
+		})
+		if err != nil {
+			return err
+		}
+		targetKeys := []protoreflect.Value{protoreflect.ValueOfUint32(m.targetZoneId), protoreflect.ValueOfInt32(int32(majorType))}
+		targetTb := &private_protocol_pbdesc.DatabaseTableGlobalMail{ZoneId: m.targetZoneId, MajorType: int32(majorType)}
+		target, err := m.store.loadKV(ctx, desc, targetKeys)
+		if err == nil {
+			targetTb = target.table.(*private_protocol_pbdesc.DatabaseTableGlobalMail)
+		} else if !errors.Is(err, errRecordNotFound) {
+			return err
+		}
+
+		existing := make(map[int64]struct{})
+		fingerprints := make(map[string]int64)
+		for _, record := range targetTb.GetJobData().GetMailRecords() {
+			existing[record.GetMailId()] = struct{}{}
+			content, err := m.loadMailContent(ctx, record.GetMailId())
+			if err != nil {
+				return err
+			}
+			if content != nil {
+				fingerprints[mailContentFingerprint(content)] = record.GetMailId()
+			}
+		}
+
+		jobData := targetTb.MutableJobData()
+		sourceTb := source.table.(*private_protocol_pbdesc.DatabaseTableGlobalMail)
+		for _, record := range sourceTb.GetJobData().GetMailRecords() {
+			m.maxMailId = max(m.maxMailId, record.GetMailId())
+			if _, ok := existing[record.GetMailId()]; ok {
+				continue
+			}
+
+			content, err := m.loadMailContent(ctx, record.GetMailId())
+			if err != nil {
+				return err
+			}
+			if content != nil {
+				fingerprint := mailContentFingerprint(content)
+				if targetMailId, ok := fingerprints[fingerprint]; ok {
+					m.duplicatedMails[record.GetMailId()] = targetMailId
+					jobData.PendingRemoveList = append(jobData.PendingRemoveList, record)
+					m.stats.duplicatedMails++
+					fmt.Printf("# global mail %d of zone %d is duplicated with %d\n", record.GetMailId(), m.sourceZoneId, targetMailId)
+					continue
+				}
+				fingerprints[fingerprint] = record.GetMailId()
+			}
+
+			existing[record.GetMailId()] = struct{}{}
+			jobData.MailRecords = append(jobData.MailRecords, record)
+			m.stats.globalMails++
+		}
+		jobData.PendingRemoveList = append(jobData.PendingRemoveList, sourceTb.GetJobData().GetPendingRemoveList()...)
+
+		m.globalMailPlans = append(m.globalMailPlans, &globalMailMergePlan{
+			sourceKey:  source.key,
+			targetKeys: targetKeys,
+			target:     targetTb,
+		})
+	}
+	return nil
+}
+
+func (m *zoneMerger) applyGlobalMails(ctx context.Context) error {
+	if m.dryRun {
+		return nil
+	}
+	desc := findTableDesc("global_mail")
+	for _, plan := range m.globalMailPlans {
+		if err := m.store.putKV(ctx, desc, plan.targetKeys, plan.target); err != nil {
+			return err
+		}
+		if err := m.store.client.Del(ctx, plan.sourceKey).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewriteUserMails 被去重的全服邮件改为引用目标大区保留的那一封，避免玩家重复领取.
+func (m *zoneMerger) rewriteUserMails(userTb *private_protocol_pbdesc.DatabaseTableUser) {
+	mailData := userTb.GetMailData()
+	for _, records := range [][]*public_protocol_pbdesc.DMailRecord{
+		mailData.GetMailBox(), mailData.GetPendingRemoveList(), mailData.GetReceivedGlobalMails(),
+	} {
+		for _, record := range records {
+			m.maxMailId = max(m.maxMailId, record.GetMailId())
+			if !record.GetIsGlobalMail() {
+				continue
+			}
+			if targetMailId, ok := m.duplicatedMails[record.GetMailId()]; ok {
+				record.MailId = targetMailId
+				m.stats.rewrittenMails++
+			}
+		}
+	}
+}
+
+func (m *zoneMerger) mergeUser(ctx context.Context, userId uint64) error {
+	userDesc := findTableDesc("user")
+	sourceKeys := []protoreflect.Value{protoreflect.ValueOfUint32(m.sourceZoneId), protoreflect.ValueOfUint64(userId)}
+	record, err := m.store.loadKV(ctx, userDesc, sourceKeys)
+	if err != nil {
+		if errors.Is(err, errRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	m.maxUserId = max(m.maxUserId, userId)
+	userTb := record.table.(*private_protocol_pbdesc.DatabaseTableUser)
+	userTb.ZoneId = m.targetZoneId
+	// 公会随大区一起搬迁
+	if userTb.GetGuildData().GetGuildId() != 0 && userTb.GetGuildData().GetGuildZoneId() == m.sourceZoneId {
+		userTb.GetGuildData().GuildZoneId = m.targetZoneId
+	}
+	m.rewriteUserMails(userTb)
+	if err = m.mergeNickName(ctx, userTb); err != nil {
+		return err
+	}
+	m.stats.users++
+	if m.dryRun {
+		return nil
+	}
+
+	targetKeys := []protoreflect.Value{protoreflect.ValueOfUint32(m.targetZoneId), protoreflect.ValueOfUint64(userId)}
+	if err = m.store.putKV(ctx, userDesc, targetKeys, userTb); err != nil {
+		return err
+	}
+
+	openIdKeys := []protoreflect.Value{protoreflect.ValueOfString(userTb.GetOpenId())}
+	access, err := m.store.loadKV(ctx, findTableDesc("access"), openIdKeys)
+	if err != nil && !errors.Is(err, errRecordNotFound) {
+		return err
+	}
+	// 保留登入码，开启合服模式的 lobbysvr 可以直接接受原大区ID的登入
+	if accessTb, ok := access.getTable().(*private_protocol_pbdesc.DatabaseTableAccess); ok &&
+		accessTb.GetUserId() == userId && accessTb.GetZoneId() == m.sourceZoneId {
+		accessTb.ZoneId = m.targetZoneId
+		if _, err = m.store.saveKV(ctx, findTableDesc("access"), access, accessTb); err != nil {
+			return err
+		}
+	}
+
+	deletionDesc := findTableDesc("account_deletion")
+	deletion, err := m.store.loadKV(ctx, deletionDesc, openIdKeys)
+	if err != nil && !errors.Is(err, errRecordNotFound) {
+		return err
+	}
+	if deletionTb, ok := deletion.getTable().(*private_protocol_pbdesc.DatabaseTableAccountDeletion); ok &&
+		deletionTb.GetUserId() == userId && deletionTb.GetZoneId() == m.sourceZoneId {
+		deletionTb.ZoneId = m.targetZoneId
+		if _, err = m.store.saveKV(ctx, deletionDesc, deletion, deletionTb); err != nil {
+			return err
+		}
+	}
+
+	if m.redirect {
+		if err = m.store.putKV(ctx, findTableDesc("user_zone_redirect"), sourceKeys, &private_protocol_pbdesc.DatabaseTableUserZoneRedirect{
+			ZoneId:       m.sourceZoneId,
+			UserId:       userId,
+			TargetZoneId: m.targetZoneId,
+			TransferTime: m.now.Unix(),
+			Operator:     "zone-merge",
+		}); err != nil {
+			return err
+		}
+	}
+
+	// 最后删除原数据，重新执行时以原大区数据为准
+	return m.store.client.Del(ctx, record.key).Err()
+}
+
+// mergeNickName 昵称在目标大区已被占用时清空昵称，玩家可以免费重新起名，登入后补发补偿邮件.
+func (m *zoneMerger) mergeNickName(ctx context.Context, userTb *private_protocol_pbdesc.DatabaseTableUser) error {
+	nickName := userTb.GetAccountData().GetProfile().GetNickName()
+	if nickName == "" {
+		return nil
+	}
+
+	desc := findTableDesc("name_mapping")
+	typeId := uint32(private_protocol_common.NameMappingType_EN_NAME_MAPPING_TYPE_USER_NICKNAME)
+	targetKeys := []protoreflect.Value{
+		protoreflect.ValueOfUint32(typeId), protoreflect.ValueOfUint32(m.targetZoneId), protoreflect.ValueOfString(nickName),
+	}
+	target, err := m.store.loadKV(ctx, desc, targetKeys)
+	switch {
+	case err == nil:
+		// 重新执行时昵称可能已经是自己的
+		owner := target.table.(*private_protocol_pbdesc.DatabaseTableNameMapping).GetMappingData().GetUserMapping().GetUserId()
+		if owner != userTb.GetUserId() {
+			userTb.MutableAccountData().MutableProfile().NickName = ""
+			mergeData := userTb.MutableZoneMergeData()
+			mergeData.SourceZoneId = m.sourceZoneId
+			mergeData.MergeTime = m.now.Unix()
+			mergeData.OriginalNickName = nickName
+			mergeData.PendingRenameCompensation = true
+			m.stats.renamedUsers++
+			fmt.Printf("# nick name %q of user %d conflicts with user %d, will be cleared\n", nickName, userTb.GetUserId(), owner)
+		}
+	case errors.Is(err, errRecordNotFound):
+		if !m.dryRun {
+			err = m.store.putKV(ctx, desc, targetKeys, &private_protocol_pbdesc.DatabaseTableNameMapping{
+				TypeId: typeId,
+				ZoneId: m.targetZoneId,
+				Name:   nickName,
+				MappingData: &private_protocol_pbdesc.DatabaseNameMappingBlobData{
+					MappingType: &private_protocol_pbdesc.DatabaseNameMappingBlobData_UserMapping{
+						UserMapping: &private_protocol_pbdesc.UserMappingData{
+							UserId: userTb.GetUserId(),
+							ZoneId: m.targetZoneId,
+						},
+					},
+				},
+			})
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		return err
+	}
+
+	if m.dryRun {
+		return nil
+	}
+	source, err := m.store.loadKV(ctx, desc, []protoreflect.Value{
+		protoreflect.ValueOfUint32(typeId), protoreflect.ValueOfUint32(m.sourceZoneId), protoreflect.ValueOfString(nickName),
+	})
+	if err != nil {
+		if errors.Is(err, errRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if source.table.(*private_protocol_pbdesc.DatabaseTableNameMapping).GetMappingData().GetUserMapping().GetUserId() != userTb.GetUserId() {
+		return nil
+	}
+	return m.store.client.Del(ctx, source.key).Err()
+}
+
+// mergeAsyncJobs Key 为 async_jobs.<job_type>.<user_id>.<zone_id>，大区在最后.
+func (m *zoneMerger) mergeAsyncJobs(ctx context.Context) error {
+	keyPrefix := fmt.Sprintf("%s-async_jobs.", m.store.prefix)
+	keySuffix := fmt.Sprintf(".%d", m.sourceZoneId)
+	keys, err := m.store.scanKeys(ctx, keyPrefix+"*"+keySuffix)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if len(strings.Split(strings.TrimPrefix(key, keyPrefix), ".")) != 3 {
+			continue
+		}
+		if m.dryRun {
+			m.stats.asyncJobs++
+			continue
+		}
+		count, err := m.store.moveRawHash(ctx, key, fmt.Sprintf("%s.%d", strings.TrimSuffix(key, keySuffix), m.targetZoneId))
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			m.stats.asyncJobs++
+		}
+	}
+	return nil
+}
+
+// mergeUserSnapshots 快照和快照的时间索引都只有 Key 中的 zone_id 不同，原样搬迁.
+func (m *zoneMerger) mergeUserSnapshots(ctx context.Context) error {
+	for _, indexName := range []string{"user_snapshot", "user_snapshot_index"} {
+		sourcePrefix := m.zoneKeyPrefix(indexName, m.sourceZoneId)
+		targetPrefix := m.zoneKeyPrefix(indexName, m.targetZoneId)
+		keys, err := m.store.scanKeys(ctx, sourcePrefix+"*")
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if m.dryRun {
+				if indexName == "user_snapshot" {
+					m.stats.snapshots++
+				}
+				continue
+			}
+			count, err := m.store.moveRawHash(ctx, key, targetPrefix+strings.TrimPrefix(key, sourcePrefix))
+			if err != nil {
+				return err
+			}
+			if count > 0 && indexName == "user_snapshot" {
+				m.stats.snapshots++
+			}
+		}
+	}
+	return nil
+}
+
+// mergeChatOfflineMessages Key 为 chat_offline_message.<zone_id>.<user_id>
+// 先在原大区改写接收方大区再整体搬迁，中断后重新执行时已经改写的消息会被跳过.
+func (m *zoneMerger) mergeChatOfflineMessages(ctx context.Context) error {
+	desc := findTableDesc("chat_offline_message")
+	sourcePrefix := m.zoneKeyPrefix(desc.indexName, m.sourceZoneId)
+	targetPrefix := m.zoneKeyPrefix(desc.indexName, m.targetZoneId)
+	keys, err := m.store.scanKeys(ctx, sourcePrefix+"*")
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		userId, err := strconv.ParseUint(strings.TrimPrefix(key, sourcePrefix), 10, 64)
+		if err != nil {
+			fmt.Printf("# skip unknown key %s\n", key)
+			continue
+		}
+		if m.dryRun {
+			m.stats.chatOffline++
+			continue
+		}
+
+		record, err := m.store.loadList(ctx, desc, []protoreflect.Value{
+			protoreflect.ValueOfUint32(m.sourceZoneId), protoreflect.ValueOfUint64(userId),
+		})
+		if err != nil {
+			if errors.Is(err, errRecordNotFound) {
+				continue
+			}
+			return err
+		}
+		for _, item := range record.items {
+			message := item.Table.(*private_protocol_pbdesc.DatabaseTableChatOfflineMessage).GetMessage()
+			if message == nil || message.GetToZoneId() != m.sourceZoneId {
+				continue
+			}
+			message.ToZoneId = m.targetZoneId
+			if err = m.store.saveListItem(ctx, record, item.ListIndex, item.Table); err != nil {
+				return err
+			}
+		}
+
+		count, err := m.store.moveRawHash(ctx, key, targetPrefix+strings.TrimPrefix(key, sourcePrefix))
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			m.stats.chatOffline++
+		}
+	}
+	return nil
+}
+
+// mergeGuilds 公会整体搬迁到目标大区，成员和申请中原大区的玩家改为目标大区
+// 合服需要停服，这里直接释放路由租约，由目标大区的进程重新接管.
+func (m *zoneMerger) mergeGuilds(ctx context.Context) error {
+	desc := findTableDesc("guild")
+	keyPrefix := m.zoneKeyPrefix(desc.indexName, m.sourceZoneId)
+	keys, err := m.store.scanKeys(ctx, keyPrefix+"*")
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		guildId, err := strconv.ParseUint(strings.TrimPrefix(key, keyPrefix), 10, 64)
+		if err != nil {
+			fmt.Printf("# skip unknown key %s\n", key)
+			continue
+		}
+		if err = m.mergeGuild(ctx, desc, guildId); err != nil {
+			return fmt.Errorf("merge guild %d failed: %w", guildId, err)
+		}
+	}
+	return nil
+}
+
+func (m *zoneMerger) mergeGuild(ctx context.Context, desc *tableDesc, guildId uint64) error {
+	record, err := m.store.loadKV(ctx, desc, []protoreflect.Value{
+		protoreflect.ValueOfUint32(m.sourceZoneId), protoreflect.ValueOfUint64(guildId),
+	})
+	if err != nil {
+		if errors.Is(err, errRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	guildTb := record.table.(*private_protocol_pbdesc.DatabaseTableGuild)
+	guildTb.ZoneId = m.targetZoneId
+	guildTb.RouterServerId = 0
+	guildTb.RouterExpired = 0
+	guild := guildTb.GetGuild()
+	if guild != nil {
+		guild.ZoneId = m.targetZoneId
+		for _, member := range guild.GetMembers() {
+			if member.GetZoneId() == m.sourceZoneId {
+				member.ZoneId = m.targetZoneId
+			}
+		}
+		for _, application := range guild.GetApplications() {
+			if application.GetZoneId() == m.sourceZoneId {
+				application.ZoneId = m.targetZoneId
+			}
+		}
+		if err = m.mergeGuildName(ctx, guildTb); err != nil {
+			return err
+		}
+	}
+	m.stats.guilds++
+	if m.dryRun {
+		return nil
+	}
+
+	targetKeys := []protoreflect.Value{protoreflect.ValueOfUint32(m.targetZoneId), protoreflect.ValueOfUint64(guildId)}
+	if err = m.store.putKV(ctx, desc, targetKeys, guildTb); err != nil {
+		return err
+	}
+	return m.store.client.Del(ctx, record.key).Err()
+}
+
+// mergeGuildName 公会名在目标大区已被占用时改为 <公会名>.<原大区ID>，改名后仍然冲突需要人工处理.
+func (m *zoneMerger) mergeGuildName(ctx context.Context, guildTb *private_protocol_pbdesc.DatabaseTableGuild) error {
+	guild := guildTb.GetGuild()
+	originalName := guild.GetName()
+	if originalName == "" {
+		return nil
+	}
+
+	desc := findTableDesc("name_mapping")
+	typeId := uint32(private_protocol_common.NameMappingType_EN_NAME_MAPPING_TYPE_GUILD_NAME)
+	for _, name := range []string{originalName, fmt.Sprintf("%s.%d", originalName, m.sourceZoneId)} {
+		targetKeys := []protoreflect.Value{
+			protoreflect.ValueOfUint32(typeId), protoreflect.ValueOfUint32(m.targetZoneId), protoreflect.ValueOfString(name),
+		}
+		target, err := m.store.loadKV(ctx, desc, targetKeys)
+		if err == nil {
+			// 重新执行时公会名可能已经是自己的
+			owner := target.table.(*private_protocol_pbdesc.DatabaseTableNameMapping).GetMappingData().GetGuildMapping().GetGuildId()
+			if owner != guildTb.GetGuildId() {
+				fmt.Printf("# guild name %q of guild %d conflicts with guild %d\n", name, guildTb.GetGuildId(), owner)
+				continue
+			}
+		} else if !errors.Is(err, errRecordNotFound) {
+			return err
+		} else if !m.dryRun {
+			err = m.store.putKV(ctx, desc, targetKeys, &private_protocol_pbdesc.DatabaseTableNameMapping{
+				TypeId: typeId,
+				ZoneId: m.targetZoneId,
+				Name:   name,
+				MappingData: &private_protocol_pbdesc.DatabaseNameMappingBlobData{
+					MappingType: &private_protocol_pbdesc.DatabaseNameMappingBlobData_GuildMapping{
+						GuildMapping: &private_protocol_pbdesc.GuildMappingData{
+							ZoneId:  m.targetZoneId,
+							GuildId: guildTb.GetGuildId(),
+						},
+					},
+				},
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if name != originalName {
+			guild.Name = name
+			m.stats.renamedGuilds++
+		}
+		if m.dryRun {
+			return nil
+		}
+		return m.deleteSourceGuildName(ctx, desc, typeId, originalName, guildTb.GetGuildId())
+	}
+	return fmt.Errorf("guild name %q of guild %d conflicts in zone %d, rename it before merge", originalName, guildTb.GetGuildId(), m.targetZoneId)
+}
+
+func (m *zoneMerger) deleteSourceGuildName(ctx context.Context, desc *tableDesc, typeId uint32, name string, guildId uint64) error {
+	source, err := m.store.loadKV(ctx, desc, []protoreflect.Value{
+		protoreflect.ValueOfUint32(typeId), protoreflect.ValueOfUint32(m.sourceZoneId), protoreflect.ValueOfString(name),
+	})
+	if err != nil {
+		if errors.Is(err, errRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if source.table.(*private_protocol_pbdesc.DatabaseTableNameMapping).GetMappingData().GetGuildMapping().GetGuildId() != guildId {
+		return nil
+	}
+	return m.store.client.Del(ctx, source.key).Err()
+}
+
+func (m *zoneMerger) mergeDeletionQueue(ctx context.Context) error {
+	sourceKey := accountDeletionQueueKeyDesc.recordKey(m.store.prefix, []protoreflect.Value{protoreflect.ValueOfUint32(m.sourceZoneId)})
+	targetKey := accountDeletionQueueKeyDesc.recordKey(m.store.prefix, []protoreflect.Value{protoreflect.ValueOfUint32(m.targetZoneId)})
+	members, err := m.store.client.ZRangeWithScores(ctx, sourceKey, 0, -1).Result()
+	if err != nil {
+		return err
+	}
+	m.stats.deletionQueue = len(members)
+	if len(members) == 0 || m.dryRun {
+		return nil
+	}
+	if err = m.store.client.ZAdd(ctx, targetKey, members...).Err(); err != nil {
+		return err
+	}
+	return m.store.client.Del(ctx, sourceKey).Err()
+}
+
+// mergeRanks 成员按 -rank-policy 重新计分，max 取两边较高的分数，sum 累加.
+func (m *zoneMerger) mergeRanks(ctx context.Context) error {
+	for _, pair := range m.ranks {
+		sourceKey := rankKeyDesc.recordKey(m.store.prefix, pair.source)
+		targetKey := rankKeyDesc.recordKey(m.store.prefix, pair.target)
+		if sourceKey == targetKey {
+			continue
+		}
+
+		members, err := m.store.client.ZRangeWithScores(ctx, sourceKey, 0, -1).Result()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("# rank %s => %s, %d member(s)\n", sourceKey, targetKey, len(members))
+		m.stats.rankMembers += len(members)
+		if m.dryRun {
+			continue
+		}
+
+		if m.rankPolicy == "sum" {
+			err = m.sumRank(ctx, sourceKey, targetKey, members)
+		} else if len(members) > 0 {
+			// GT 重复执行结果不变
+			err = m.store.client.ZAddArgs(ctx, targetKey, redis.ZAddArgs{GT: true, Members: members}).Err()
+		}
+		if err != nil {
+			return err
+		}
+		if err = m.store.client.Del(ctx, sourceKey).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rankMergePlanKey 保存 sum 合并后的最终分数，写入排行榜并删除原排行榜后再删除.
+func (m *zoneMerger) rankMergePlanKey(sourceKey string) string {
+	return fmt.Sprintf("%s-zone_merge_rank_plan.%s", m.store.prefix, sourceKey)
+}
+
+// sumRank 累加不能重复执行，先把累加后的分数一次性写入计划，再按计划覆盖目标排行榜
+// 两个排行榜可能不在同一个 slot，无法使用 ZUNIONSTORE 和 RENAME
+// 中断后重新执行时计划已存在，直接使用计划中的分数，不会再次累加.
+func (m *zoneMerger) sumRank(ctx context.Context, sourceKey string, targetKey string, members []redis.Z) error {
+	planKey := m.rankMergePlanKey(sourceKey)
+	plan, err := m.store.client.HGetAll(ctx, planKey).Result()
+	if err != nil {
+		return err
+	}
+
+	if len(plan) == 0 {
+		if len(members) == 0 {
+			return nil
+		}
+		names := make([]string, 0, len(members))
+		for _, member := range members {
+			names = append(names, fmt.Sprint(member.Member))
+		}
+		// 不在目标排行榜中的成员返回 0
+		scores, err := m.store.client.ZMScore(ctx, targetKey, names...).Result()
+		if err != nil {
+			return err
+		}
+		values := make([]interface{}, 0, len(members)*2)
+		plan = make(map[string]string, len(members))
+		for i, member := range members {
+			score := strconv.FormatFloat(member.Score+scores[i], 'g', -1, 64)
+			values = append(values, names[i], score)
+			plan[names[i]] = score
+		}
+		if err = m.store.client.HSet(ctx, planKey, values...).Err(); err != nil {
+			return err
+		}
+	}
+
+	sumMembers := make([]redis.Z, 0, len(plan))
+	for member, value := range plan {
+		score, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("parse %s of %s failed: %w", member, planKey, err)
+		}
+		sumMembers = append(sumMembers, redis.Z{Score: score, Member: member})
+	}
+	if err = m.store.client.ZAdd(ctx, targetKey, sumMembers...).Err(); err != nil {
+		return err
+	}
+	if err = m.store.client.Del(ctx, sourceKey).Err(); err != nil {
+		return err
+	}
+	return m.store.client.Del(ctx, planKey).Err()
+}
+
+// mergeUUIDAllocators 分配器的 Key 不区分大区，正常情况下两个大区本来就共用同一组分配器
+// 原大区如果是从其他环境导入的，其中的ID可能超出当前分配器的范围，需要把分配器推进到最大ID之后.
+func (m *zoneMerger) mergeUUIDAllocators(ctx context.Context) error {
+	if err := m.bumpUUIDAllocator(ctx, "account_id",
+		private_protocol_pbdesc.EnGlobalUUIDMajorType_EN_GLOBAL_UUID_MAT_ACCOUNT_ID,
+		private_protocol_pbdesc.EnGlobalUUIDMinorType_EN_GLOBAL_UUID_MIT_LIMIT_GLOBAL,
+		m.maxUserId); err != nil {
+		return err
+	}
+
+	if m.maxMailId <= 0 {
+		return nil
+	}
+	return m.bumpUUIDAllocator(ctx, "mail",
+		private_protocol_pbdesc.EnGlobalUUIDMajorType_EN_GLOBAL_UUID_MAT_MAIL,
+		private_protocol_pbdesc.EnGlobalUUIDMinorType_EN_GLOBAL_UUID_MIT_DEFAULT,
+		uint64(m.maxMailId))
+}
+
+// bumpUUIDAllocator 区块划分和 component/uuid 一致.
+func (m *zoneMerger) bumpUUIDAllocator(ctx context.Context, name string,
+	majorType private_protocol_pbdesc.EnGlobalUUIDMajorType, minorType private_protocol_pbdesc.EnGlobalUUIDMinorType, maxId uint64,
+) error {
+	if maxId < uuid.GlobalUniqueIDOffset {
+		return nil
+	}
+
+	key := findTableDesc("uuid_allocator").recordKey(m.store.prefix, []protoreflect.Value{
+		protoreflect.ValueOfUint32(uint32(majorType)),
+		protoreflect.ValueOfUint32(uint32(minorType)),
+		protoreflect.ValueOfUint32(uint32(private_protocol_pbdesc.EnGlobalUUIDPatchType_EN_GLOBAL_UUID_PT_DEFAULT)),
+	})
+	current, err := m.store.client.HGet(ctx, key, uuidAutoIncField).Uint64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return err
+	}
+	required := (maxId - uuid.GlobalUniqueIDOffset) >> uuid.GlobalUniqueIDBitsOffForMajor(majorType)
+	if current >= required {
+		return nil
+	}
+
+	fmt.Printf("# uuid allocator %s %s is behind max id %d, bump %d => %d\n", name, key, maxId, current, required)
+	if m.dryRun {
+		return nil
+	}
+	return m.store.client.HIncrBy(ctx, key, uuidAutoIncField, int64(required-current)).Err()
+}
+
+// checkLeftNameMappings 玩家搬迁时已经处理了自己的昵称，剩下的是残留数据，只做提示不处理.
+func (m *zoneMerger) checkLeftNameMappings(ctx context.Context) error {
+	if m.dryRun {
+		return nil
+	}
+	keys, err := m.store.scanKeys(ctx, fmt.Sprintf("%s-name_mapping.*.%d.*", m.store.prefix, m.sourceZoneId))
+	if err != nil {
+		return err
+	}
+	m.stats.leftNameMappings = len(keys)
+	return nil
+}
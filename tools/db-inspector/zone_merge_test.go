@@ -0,0 +1,376 @@
+// Copyright 2026 atframework
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	pu "github.com/atframework/atframe-utils-go/proto_utility"
+	storage "github.com/atframework/atsf4g-go/component/db/storage"
+	private_protocol_common "github.com/atframework/atsf4g-go/component/protocol/private/common/protocol/common"
+	private_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/private/pbdesc/protocol/pbdesc"
+	public_protocol_pbdesc "github.com/atframework/atsf4g-go/component/protocol/public/pbdesc/protocol/pbdesc"
+	uuid "github.com/atframework/atsf4g-go/component/uuid"
+)
+
+const (
+	testMergeSourceZoneId uint32 = 1
+	testMergeTargetZoneId uint32 = 2
+)
+
+// createTestDbStore 使用内存中的 Redis.
+func createTestDbStore(t *testing.T) *dbStore {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return &dbStore{client: client, prefix: "test"}
+}
+
+func nickNameMappingKeys(zoneId uint32, nickName string) []protoreflect.Value {
+	return []protoreflect.Value{
+		protoreflect.ValueOfUint32(uint32(private_protocol_common.NameMappingType_EN_NAME_MAPPING_TYPE_USER_NICKNAME)),
+		protoreflect.ValueOfUint32(zoneId),
+		protoreflect.ValueOfString(nickName),
+	}
+}
+
+func putTestUser(t *testing.T, ctx context.Context, store *dbStore, zoneId uint32, userId uint64, nickName string) {
+	assert.NoError(t, store.putKV(ctx, findTableDesc("user"),
+		[]protoreflect.Value{protoreflect.ValueOfUint32(zoneId), protoreflect.ValueOfUint64(userId)},
+		&private_protocol_pbdesc.DatabaseTableUser{
+			ZoneId: zoneId,
+			UserId: userId,
+			OpenId: nickName + "-open-id",
+			AccountData: &private_protocol_pbdesc.AccountInformation{
+				Profile: &public_protocol_pbdesc.DUserProfile{UserId: userId, NickName: nickName},
+			},
+		}))
+	putTestNickNameMapping(t, ctx, store, zoneId, nickName, userId)
+}
+
+func putTestNickNameMapping(t *testing.T, ctx context.Context, store *dbStore, zoneId uint32, nickName string, userId uint64) {
+	assert.NoError(t, store.putKV(ctx, findTableDesc("name_mapping"), nickNameMappingKeys(zoneId, nickName),
+		&private_protocol_pbdesc.DatabaseTableNameMapping{
+			TypeId: uint32(private_protocol_common.NameMappingType_EN_NAME_MAPPING_TYPE_USER_NICKNAME),
+			ZoneId: zoneId,
+			Name:   nickName,
+			MappingData: &private_protocol_pbdesc.DatabaseNameMappingBlobData{
+				MappingType: &private_protocol_pbdesc.DatabaseNameMappingBlobData_UserMapping{
+					UserMapping: &private_protocol_pbdesc.UserMappingData{UserId: userId, ZoneId: zoneId},
+				},
+			},
+		}))
+}
+
+func loadTestUser(t *testing.T, ctx context.Context, store *dbStore, zoneId uint32, userId uint64) *private_protocol_pbdesc.DatabaseTableUser {
+	record, err := store.loadKV(ctx, findTableDesc("user"),
+		[]protoreflect.Value{protoreflect.ValueOfUint32(zoneId), protoreflect.ValueOfUint64(userId)})
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	return record.table.(*private_protocol_pbdesc.DatabaseTableUser)
+}
+
+func loadTestNickNameOwner(t *testing.T, ctx context.Context, store *dbStore, zoneId uint32, nickName string) uint64 {
+	record, err := store.loadKV(ctx, findTableDesc("name_mapping"), nickNameMappingKeys(zoneId, nickName))
+	if !assert.NoError(t, err) {
+		return 0
+	}
+	return record.table.(*private_protocol_pbdesc.DatabaseTableNameMapping).GetMappingData().GetUserMapping().GetUserId()
+}
+
+// TestZoneMergeUsers 测试合服搬迁玩家
+// Scenario: 玩家搬到目标大区并写入跳转记录，昵称冲突的玩家清空昵称等待补偿，不冲突的昵称映射搬到目标大区，
+// uuid 分配器推进到原大区最大的玩家ID之后.
+func TestZoneMergeUsers(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := createTestDbStore(t)
+	now := time.Unix(1700000000, 0)
+	accountBitsOff := uuid.GlobalUniqueIDBitsOffForMajor(private_protocol_pbdesc.EnGlobalUUIDMajorType_EN_GLOBAL_UUID_MAT_ACCOUNT_ID)
+	conflictUserId := uint64(uuid.GlobalUniqueIDOffset) + (300 << accountBitsOff) + 1
+	normalUserId := uint64(uuid.GlobalUniqueIDOffset) + (12 << accountBitsOff) + 3
+	ownerUserId := uint64(uuid.GlobalUniqueIDOffset) + 7
+
+	putTestUser(t, ctx, store, testMergeSourceZoneId, conflictUserId, "alice")
+	putTestUser(t, ctx, store, testMergeSourceZoneId, normalUserId, "bob")
+	putTestUser(t, ctx, store, testMergeTargetZoneId, ownerUserId, "alice")
+
+	allocatorKey := findTableDesc("uuid_allocator").recordKey(store.prefix, []protoreflect.Value{
+		protoreflect.ValueOfUint32(uint32(private_protocol_pbdesc.EnGlobalUUIDMajorType_EN_GLOBAL_UUID_MAT_ACCOUNT_ID)),
+		protoreflect.ValueOfUint32(uint32(private_protocol_pbdesc.EnGlobalUUIDMinorType_EN_GLOBAL_UUID_MIT_LIMIT_GLOBAL)),
+		protoreflect.ValueOfUint32(uint32(private_protocol_pbdesc.EnGlobalUUIDPatchType_EN_GLOBAL_UUID_PT_DEFAULT)),
+	})
+	assert.NoError(t, store.client.HSet(ctx, allocatorKey, uuidAutoIncField, 10).Err())
+
+	merger := &zoneMerger{
+		store:           store,
+		sourceZoneId:    testMergeSourceZoneId,
+		targetZoneId:    testMergeTargetZoneId,
+		rankPolicy:      "max",
+		redirect:        true,
+		now:             now,
+		duplicatedMails: make(map[int64]int64),
+	}
+
+	// Act
+	err := merger.run(ctx)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 2, merger.stats.users)
+	assert.Equal(t, 1, merger.stats.renamedUsers)
+	assert.Equal(t, 0, merger.stats.leftNameMappings)
+
+	conflictUser := loadTestUser(t, ctx, store, testMergeTargetZoneId, conflictUserId)
+	assert.Equal(t, testMergeTargetZoneId, conflictUser.GetZoneId())
+	assert.Empty(t, conflictUser.GetAccountData().GetProfile().GetNickName())
+	assert.Equal(t, "alice", conflictUser.GetZoneMergeData().GetOriginalNickName())
+	assert.Equal(t, testMergeSourceZoneId, conflictUser.GetZoneMergeData().GetSourceZoneId())
+	assert.True(t, conflictUser.GetZoneMergeData().GetPendingRenameCompensation())
+	assert.Equal(t, ownerUserId, loadTestNickNameOwner(t, ctx, store, testMergeTargetZoneId, "alice"))
+
+	normalUser := loadTestUser(t, ctx, store, testMergeTargetZoneId, normalUserId)
+	assert.Equal(t, "bob", normalUser.GetAccountData().GetProfile().GetNickName())
+	assert.Equal(t, normalUserId, loadTestNickNameOwner(t, ctx, store, testMergeTargetZoneId, "bob"))
+
+	for _, userId := range []uint64{conflictUserId, normalUserId} {
+		sourceKeys := []protoreflect.Value{protoreflect.ValueOfUint32(testMergeSourceZoneId), protoreflect.ValueOfUint64(userId)}
+		_, err = store.loadKV(ctx, findTableDesc("user"), sourceKeys)
+		assert.ErrorIs(t, err, errRecordNotFound)
+		redirect, err := store.loadKV(ctx, findTableDesc("user_zone_redirect"), sourceKeys)
+		if assert.NoError(t, err) {
+			assert.Equal(t, testMergeTargetZoneId, redirect.table.(*private_protocol_pbdesc.DatabaseTableUserZoneRedirect).GetTargetZoneId())
+		}
+	}
+	for _, nickName := range []string{"alice", "bob"} {
+		_, err = store.loadKV(ctx, findTableDesc("name_mapping"), nickNameMappingKeys(testMergeSourceZoneId, nickName))
+		assert.ErrorIs(t, err, errRecordNotFound)
+	}
+
+	allocatorBase, err := store.client.HGet(ctx, allocatorKey, uuidAutoIncField).Uint64()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(300), allocatorBase)
+}
+
+// TestZoneMergeRerun 测试合服重复执行
+// Scenario: 第二次执行时原大区已经没有玩家，目标大区的数据保持不变.
+func TestZoneMergeRerun(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := createTestDbStore(t)
+	putTestUser(t, ctx, store, testMergeSourceZoneId, 1000001, "carol")
+	newMerger := func() *zoneMerger {
+		return &zoneMerger{
+			store:           store,
+			sourceZoneId:    testMergeSourceZoneId,
+			targetZoneId:    testMergeTargetZoneId,
+			rankPolicy:      "max",
+			redirect:        true,
+			now:             time.Unix(1700000000, 0),
+			duplicatedMails: make(map[int64]int64),
+		}
+	}
+	assert.NoError(t, newMerger().run(ctx))
+
+	// Act
+	merger := newMerger()
+	err := merger.run(ctx)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 0, merger.stats.users)
+	assert.Equal(t, "carol", loadTestUser(t, ctx, store, testMergeTargetZoneId, 1000001).GetAccountData().GetProfile().GetNickName())
+	assert.Equal(t, uint64(1000001), loadTestNickNameOwner(t, ctx, store, testMergeTargetZoneId, "carol"))
+}
+
+// TestMoveRawHash 测试原样搬迁 Hash
+// Scenario: 目标不存在时搬迁；上次中断在删源之前时只删源；目标内容不同时拒绝覆盖.
+func TestMoveRawHash(t *testing.T) {
+	testCases := []struct {
+		name        string
+		source      map[string]string
+		target      map[string]string
+		expectCount int
+		expectError bool
+	}{
+		{name: "target not exists", source: map[string]string{"1": "a", "2": "b"}, expectCount: 2},
+		{name: "source not exists", target: map[string]string{"1": "a"}, expectCount: 0},
+		{name: "resume after interrupted", source: map[string]string{"1": "a", "2": "b"}, target: map[string]string{"1": "a", "2": "b"}, expectCount: 2},
+		{name: "target conflicts", source: map[string]string{"1": "a"}, target: map[string]string{"1": "c"}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			store := createTestDbStore(t)
+			if len(tc.source) > 0 {
+				assert.NoError(t, store.client.HSet(ctx, "src", tc.source).Err())
+			}
+			if len(tc.target) > 0 {
+				assert.NoError(t, store.client.HSet(ctx, "dst", tc.target).Err())
+			}
+
+			// Act
+			count, err := store.moveRawHash(ctx, "src", "dst")
+
+			// Assert
+			srcRaw, _ := store.client.HGetAll(ctx, "src").Result()
+			dstRaw, _ := store.client.HGetAll(ctx, "dst").Result()
+			if tc.expectError {
+				assert.Error(t, err)
+				assert.Equal(t, tc.source, srcRaw)
+				assert.Equal(t, tc.target, dstRaw)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectCount, count)
+			assert.Empty(t, srcRaw)
+			if len(tc.source) > 0 {
+				assert.Equal(t, tc.source, dstRaw)
+			}
+		})
+	}
+}
+
+// TestZoneMergeRankSumResume 测试 sum 合并排行榜中断后重新执行
+// Scenario: 上次执行已经按计划写入目标排行榜但没有删除原排行榜，重新执行时使用计划中的分数，不会再次累加.
+func TestZoneMergeRankSumResume(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := createTestDbStore(t)
+	var ranks rankMergeFlag
+	assert.NoError(t, ranks.Set("1.1.1=2.1.1"))
+	sourceKey := rankKeyDesc.recordKey(store.prefix, ranks[0].source)
+	targetKey := rankKeyDesc.recordKey(store.prefix, ranks[0].target)
+	merger := &zoneMerger{store: store, ranks: ranks, rankPolicy: "sum"}
+	// 原排行榜 a=10 b=5，目标排行榜 a=20 c=1，上次执行写入计划和目标排行榜后中断
+	assert.NoError(t, store.client.ZAdd(ctx, sourceKey, redis.Z{Score: 10, Member: "a"}, redis.Z{Score: 5, Member: "b"}).Err())
+	assert.NoError(t, store.client.ZAdd(ctx, targetKey,
+		redis.Z{Score: 30, Member: "a"}, redis.Z{Score: 5, Member: "b"}, redis.Z{Score: 1, Member: "c"}).Err())
+	assert.NoError(t, store.client.HSet(ctx, merger.rankMergePlanKey(sourceKey), "a", "30", "b", "5").Err())
+
+	// Act
+	err := merger.mergeRanks(ctx)
+
+	// Assert
+	assert.NoError(t, err)
+	result, _ := store.client.ZRangeWithScores(ctx, targetKey, 0, -1).Result()
+	assert.Equal(t, []redis.Z{{Score: 1, Member: "c"}, {Score: 5, Member: "b"}, {Score: 30, Member: "a"}}, result)
+	assert.Zero(t, store.client.Exists(ctx, sourceKey, merger.rankMergePlanKey(sourceKey)).Val())
+}
+
+// TestZoneMergeGuildsAndChat 测试合服搬迁公会和离线私聊
+// Scenario: 公会搬到目标大区，重名的公会改名，成员和玩家身上的公会大区改为目标大区；离线私聊搬到目标大区并修正接收方大区.
+func TestZoneMergeGuildsAndChat(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := createTestDbStore(t)
+	const guildId, ownerGuildId, userId uint64 = 5001, 5002, 1000001
+	guildNameKeys := func(zoneId uint32, name string) []protoreflect.Value {
+		return []protoreflect.Value{
+			protoreflect.ValueOfUint32(uint32(private_protocol_common.NameMappingType_EN_NAME_MAPPING_TYPE_GUILD_NAME)),
+			protoreflect.ValueOfUint32(zoneId),
+			protoreflect.ValueOfString(name),
+		}
+	}
+	putGuild := func(zoneId uint32, id uint64, memberZoneId uint32) {
+		assert.NoError(t, store.putKV(ctx, findTableDesc("guild"),
+			[]protoreflect.Value{protoreflect.ValueOfUint32(zoneId), protoreflect.ValueOfUint64(id)},
+			&private_protocol_pbdesc.DatabaseTableGuild{
+				ZoneId:         zoneId,
+				GuildId:        id,
+				RouterServerId: 100,
+				Guild: &public_protocol_pbdesc.DGuildData{
+					GuildId: id, ZoneId: zoneId, Name: "knights",
+					Members: []*public_protocol_pbdesc.DGuildMember{{UserId: userId, ZoneId: memberZoneId}},
+				},
+			}))
+		assert.NoError(t, store.putKV(ctx, findTableDesc("name_mapping"), guildNameKeys(zoneId, "knights"),
+			&private_protocol_pbdesc.DatabaseTableNameMapping{
+				TypeId: uint32(private_protocol_common.NameMappingType_EN_NAME_MAPPING_TYPE_GUILD_NAME),
+				ZoneId: zoneId,
+				Name:   "knights",
+				MappingData: &private_protocol_pbdesc.DatabaseNameMappingBlobData{
+					MappingType: &private_protocol_pbdesc.DatabaseNameMappingBlobData_GuildMapping{
+						GuildMapping: &private_protocol_pbdesc.GuildMappingData{GuildId: id, ZoneId: zoneId},
+					},
+				},
+			}))
+	}
+	putGuild(testMergeSourceZoneId, guildId, testMergeSourceZoneId)
+	putGuild(testMergeTargetZoneId, ownerGuildId, testMergeTargetZoneId)
+	putTestUser(t, ctx, store, testMergeSourceZoneId, userId, "dave")
+	userRecord, err := store.loadKV(ctx, findTableDesc("user"),
+		[]protoreflect.Value{protoreflect.ValueOfUint32(testMergeSourceZoneId), protoreflect.ValueOfUint64(userId)})
+	if !assert.NoError(t, err) {
+		return
+	}
+	userTb := userRecord.table.(*private_protocol_pbdesc.DatabaseTableUser)
+	userTb.GuildData = &public_protocol_pbdesc.DUserGuildData{GuildId: guildId, GuildZoneId: testMergeSourceZoneId}
+	_, err = store.saveKV(ctx, findTableDesc("user"), userRecord, userTb)
+	assert.NoError(t, err)
+
+	chatKey := findTableDesc("chat_offline_message").recordKey(store.prefix,
+		[]protoreflect.Value{protoreflect.ValueOfUint32(testMergeSourceZoneId), protoreflect.ValueOfUint64(userId)})
+	chatArgs := storage.FlattenArgs(pu.PBMapToRedisKLUpdate(&private_protocol_pbdesc.DatabaseTableChatOfflineMessage{
+		ZoneId:  testMergeSourceZoneId,
+		UserId:  userId,
+		Message: &public_protocol_pbdesc.DChatMessage{MessageId: 1, ToZoneId: testMergeSourceZoneId},
+	}, 1))
+	chatValues := make([]interface{}, 0, len(chatArgs))
+	for _, arg := range chatArgs {
+		chatValues = append(chatValues, arg)
+	}
+	assert.NoError(t, store.client.HSet(ctx, chatKey, chatValues...).Err())
+
+	merger := &zoneMerger{
+		store:           store,
+		sourceZoneId:    testMergeSourceZoneId,
+		targetZoneId:    testMergeTargetZoneId,
+		rankPolicy:      "max",
+		now:             time.Unix(1700000000, 0),
+		duplicatedMails: make(map[int64]int64),
+	}
+
+	// Act
+	err = merger.run(ctx)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 1, merger.stats.guilds)
+	assert.Equal(t, 1, merger.stats.renamedGuilds)
+	assert.Equal(t, 1, merger.stats.chatOffline)
+
+	guildRecord, err := store.loadKV(ctx, findTableDesc("guild"),
+		[]protoreflect.Value{protoreflect.ValueOfUint32(testMergeTargetZoneId), protoreflect.ValueOfUint64(guildId)})
+	if assert.NoError(t, err) {
+		guildTb := guildRecord.table.(*private_protocol_pbdesc.DatabaseTableGuild)
+		assert.Zero(t, guildTb.GetRouterServerId())
+		assert.Equal(t, testMergeTargetZoneId, guildTb.GetGuild().GetZoneId())
+		assert.Equal(t, "knights.1", guildTb.GetGuild().GetName())
+		assert.Equal(t, testMergeTargetZoneId, guildTb.GetGuild().GetMembers()[0].GetZoneId())
+	}
+	renamed, err := store.loadKV(ctx, findTableDesc("name_mapping"), guildNameKeys(testMergeTargetZoneId, "knights.1"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, guildId, renamed.table.(*private_protocol_pbdesc.DatabaseTableNameMapping).GetMappingData().GetGuildMapping().GetGuildId())
+	}
+	_, err = store.loadKV(ctx, findTableDesc("name_mapping"), guildNameKeys(testMergeSourceZoneId, "knights"))
+	assert.ErrorIs(t, err, errRecordNotFound)
+	assert.Equal(t, testMergeTargetZoneId, loadTestUser(t, ctx, store, testMergeTargetZoneId, userId).GetGuildData().GetGuildZoneId())
+
+	chat, err := store.loadList(ctx, findTableDesc("chat_offline_message"),
+		[]protoreflect.Value{protoreflect.ValueOfUint32(testMergeTargetZoneId), protoreflect.ValueOfUint64(userId)})
+	if assert.NoError(t, err) && assert.Len(t, chat.items, 1) {
+		message := chat.items[0].Table.(*private_protocol_pbdesc.DatabaseTableChatOfflineMessage).GetMessage()
+		assert.Equal(t, testMergeTargetZoneId, message.GetToZoneId())
+	}
+	assert.Zero(t, store.client.Exists(ctx, chatKey).Val())
+}